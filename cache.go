@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -52,6 +53,9 @@ func NewCompilationCache() CompilationCache {
 // The contents written into dirname are wazero-version specific, meaning different versions of
 // wazero will duplicate entries for the same input wasm.
 //
+// The directory grows without bound. To evict entries, combine
+// NewCompilationCacheDirStore with NewEvictingCompilationCacheStore instead.
+//
 // Note: The embedder must safeguard this directory from external changes.
 func NewCompilationCacheWithDir(dirname string) (CompilationCache, error) {
 	c := &cache{}
@@ -59,6 +63,102 @@ func NewCompilationCacheWithDir(dirname string) (CompilationCache, error) {
 	return c, err
 }
 
+// CompilationCacheStore persists the native code compiled from wasm modules,
+// for use by a CompilationCache returned by NewCompilationCacheWithStore.
+//
+// Unlike CompilationCache, this interface may be implemented by third
+// parties, for example to share compiled code in a remote store. Built-in
+// implementations include NewCompilationCacheDirStore and
+// NewCompilationCacheMemoryStore, which can be bounded by
+// NewEvictingCompilationCacheStore.
+//
+// # Notes
+//
+//   - Implementations must be safe for concurrent use.
+//   - Content is trusted: it is not validated like a wasm binary is when
+//     compiled. Implementations must safeguard it from external changes.
+type CompilationCacheStore interface {
+	// Get returns the content previously added for the key, with ok=true. If
+	// there is no entry for the key, this returns ok=false and a nil error.
+	//
+	// Note: The caller closes the content when done.
+	Get(key [32]byte) (content io.ReadCloser, ok bool, err error)
+
+	// Add stores the content for the key, which must be returned as-is by
+	// subsequent calls to Get.
+	Add(key [32]byte, content io.Reader) (err error)
+
+	// Delete removes the entry for the key, if present. This is called when
+	// an entry returned by Get is no longer usable, for example when it was
+	// written by a different version of wazero.
+	Delete(key [32]byte) (err error)
+}
+
+// compile-time check to ensure CompilationCacheStore is interchangeable with filecache.Cache
+var _ filecache.Cache = CompilationCacheStore(nil)
+
+// NewCompilationCacheWithStore is like wazero.NewCompilationCache except
+// compiled code is also written to, and read from, the given store.
+//
+// Here's an example of bounding the in-memory cache to 64MB:
+//
+//	store := wazero.NewEvictingCompilationCacheStore(
+//		wazero.NewCompilationCacheMemoryStore(),
+//		wazero.CompilationCacheEvictionPolicy{MaxBytes: 64 << 20})
+//	cache := wazero.NewCompilationCacheWithStore(store)
+//	defer cache.Close(ctx)
+func NewCompilationCacheWithStore(store CompilationCacheStore) CompilationCache {
+	return &cache{fileCache: store}
+}
+
+// NewCompilationCacheDirStore returns the CompilationCacheStore which
+// NewCompilationCacheWithDir uses to write into the directory `dirname`.
+//
+// If the dirname doesn't exist, this creates it or returns an error.
+func NewCompilationCacheDirStore(dirname string) (CompilationCacheStore, error) {
+	c := &cache{}
+	if err := c.ensuresFileCache(dirname, version.GetWazeroVersion()); err != nil {
+		return nil, err
+	}
+	return c.fileCache, nil
+}
+
+// NewCompilationCacheMemoryStore returns a CompilationCacheStore which holds
+// entries in memory, without bound. Use NewEvictingCompilationCacheStore to
+// limit its size.
+func NewCompilationCacheMemoryStore() CompilationCacheStore {
+	return filecache.NewMemory()
+}
+
+// CompilationCacheEvictionPolicy configures NewEvictingCompilationCacheStore.
+// The zero value never evicts entries.
+type CompilationCacheEvictionPolicy = filecache.Policy
+
+// CompilationCacheStats are the counters of an EvictingCompilationCacheStore.
+type CompilationCacheStats = filecache.Stats
+
+// EvictingCompilationCacheStore is a CompilationCacheStore returned by
+// NewEvictingCompilationCacheStore.
+type EvictingCompilationCacheStore interface {
+	CompilationCacheStore
+
+	// Stats returns a snapshot of the hit, miss and eviction counters, as
+	// well as the entries currently tracked.
+	Stats() CompilationCacheStats
+}
+
+// NewEvictingCompilationCacheStore returns a CompilationCacheStore which
+// delegates to another, deleting its entries according to the policy: the
+// least recently used entries beyond CompilationCacheEvictionPolicy.MaxBytes,
+// and those unused for longer than CompilationCacheEvictionPolicy.TTL.
+//
+// Note: Only entries added, or read, through the result are accounted for.
+// For example, entries written in a directory by a previous process are
+// tracked once read.
+func NewEvictingCompilationCacheStore(store CompilationCacheStore, policy CompilationCacheEvictionPolicy) EvictingCompilationCacheStore {
+	return filecache.NewEvicting(store, policy)
+}
+
 // cache implements Cache interface.
 type cache struct {
 	// eng is the engine for this cache. If the cache is configured, the engine is shared across multiple instances of
@@ -39,6 +39,28 @@ func Example_compileCache() {
 	//
 }
 
+// This is an example of bounding the size of a compilation cache with
+// wazero.NewEvictingCompilationCacheStore.
+func Example_compileCacheWithStore() {
+	ctx := context.Background()
+
+	// Keep at most 64MB of compiled code in memory, evicting the least
+	// recently used modules first.
+	store := wazero.NewEvictingCompilationCacheStore(
+		wazero.NewCompilationCacheMemoryStore(),
+		wazero.CompilationCacheEvictionPolicy{MaxBytes: 64 << 20})
+
+	// Each cache shares the store, even if the cache itself is closed.
+	for i := 0; i < 2; i++ {
+		cache := wazero.NewCompilationCacheWithStore(store)
+		newRuntimeCompileClose(ctx, wazero.NewRuntimeConfig().WithCompilationCache(cache))
+		cache.Close(ctx)
+	}
+
+	// Output:
+	//
+}
+
 func newCompilationCacheWithDir(cacheDir string) wazero.CompilationCache {
 	cache, err := wazero.NewCompilationCacheWithDir(cacheDir)
 	if err != nil {
@@ -1,9 +1,11 @@
 package wazero
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	goruntime "runtime"
@@ -133,6 +135,78 @@ func TestCompilationCache(t *testing.T) {
 	})
 }
 
+func TestNewCompilationCacheWithStore(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	ctx := context.Background()
+	store := NewEvictingCompilationCacheStore(NewCompilationCacheMemoryStore(), CompilationCacheEvictionPolicy{})
+
+	compileClose := func() {
+		cache := NewCompilationCacheWithStore(store)
+		defer cache.Close(ctx)
+		r := NewRuntimeWithConfig(ctx, NewRuntimeConfig().WithCompilationCache(cache))
+		defer r.Close(ctx)
+		_, err := r.CompileModule(ctx, facWasm)
+		require.NoError(t, err)
+	}
+
+	// The first compilation misses, then adds to the store.
+	compileClose()
+	stats := store.Stats()
+	require.Equal(t, uint64(0), stats.Hits)
+	require.Equal(t, uint64(1), stats.Misses)
+	require.Equal(t, 1, stats.Entries)
+	require.True(t, stats.Bytes > 0)
+
+	// A new cache reads the compiled code from the store.
+	compileClose()
+	stats = store.Stats()
+	require.Equal(t, uint64(1), stats.Hits)
+	require.Equal(t, uint64(1), stats.Misses)
+
+	t.Run("max bytes", func(t *testing.T) {
+		store := NewEvictingCompilationCacheStore(NewCompilationCacheMemoryStore(),
+			CompilationCacheEvictionPolicy{MaxBytes: 1})
+		cache := NewCompilationCacheWithStore(store)
+		defer cache.Close(ctx)
+		r := NewRuntimeWithConfig(ctx, NewRuntimeConfig().WithCompilationCache(cache))
+		defer r.Close(ctx)
+
+		_, err := r.CompileModule(ctx, facWasm)
+		require.NoError(t, err)
+		// The compiled code is larger than the limit, so is evicted.
+		require.Equal(t, uint64(1), store.Stats().Evictions)
+		require.Equal(t, 0, store.Stats().Entries)
+	})
+}
+
+func TestNewCompilationCacheDirStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewCompilationCacheDirStore(dir)
+	require.NoError(t, err)
+
+	key := [32]byte{1, 2, 3}
+	require.NoError(t, store.Add(key, bytes.NewReader([]byte{4, 5, 6})))
+	content, ok, err := store.Get(key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	b, err := io.ReadAll(content)
+	require.NoError(t, err)
+	require.NoError(t, content.Close())
+	require.Equal(t, []byte{4, 5, 6}, b)
+
+	// Entries are written in the same version-specific subdirectory as
+	// NewCompilationCacheWithDir.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	require.True(t, entries[0].IsDir())
+
+	_, err = NewCompilationCacheDirStore(path.Join(dir, entries[0].Name(), "0102030000000000000000000000000000000000000000000000000000000000"))
+	require.Contains(t, err.Error(), "is not dir")
+}
+
 func getCacheSharedRuntimes(ctx context.Context, t *testing.T) (foo, bar *runtime) {
 	// Creates new cache instance and pass it to the config.
 	c := NewCompilationCache()
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+// inspection is what the inspect command prints about a WebAssembly binary, which is also its JSON output.
+type inspection struct {
+	Sections  []inspectSection  `json:"sections"`
+	Imports   []inspectImport   `json:"imports"`
+	Exports   []inspectExport   `json:"exports"`
+	Memories  []inspectMemory   `json:"memories"`
+	Tables    []inspectTable    `json:"tables"`
+	Globals   []inspectGlobal   `json:"globals"`
+	Functions []inspectFunction `json:"functions"`
+
+	// Features are the features beyond WebAssembly 1.0 the binary uses, such as "simd".
+	Features []string `json:"features"`
+
+	// DWARF is true if the binary has DWARF debug sections, such as ".debug_info".
+	DWARF bool `json:"dwarf"`
+}
+
+type inspectSection struct {
+	// ID is the name of the section ID, such as "code".
+	ID string `json:"id"`
+	// Name is the name of a custom section, such as "producers".
+	Name string `json:"name,omitempty"`
+	// Offset is the offset of the section in the binary, and Size the size of its content in bytes.
+	Offset int `json:"offset"`
+	Size   int `json:"size"`
+}
+
+type inspectImport struct {
+	Module string `json:"module"`
+	Name   string `json:"name"`
+	// Type is the name of the extern type, such as "func".
+	Type string `json:"type"`
+	// Desc is the signature of a function, or the type of other imports, such as "(param i32) (result i32)".
+	Desc string `json:"desc"`
+}
+
+type inspectExport struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Index uint32 `json:"index"`
+	// Desc is the signature of a function, or empty for other exports.
+	Desc string `json:"desc,omitempty"`
+}
+
+type inspectMemory struct {
+	Index    uint32 `json:"index"`
+	Imported bool   `json:"imported,omitempty"`
+	// Min and Max are in pages of 64KB. Max is nil if unbounded.
+	Min    uint32  `json:"min"`
+	Max    *uint32 `json:"max,omitempty"`
+	Shared bool    `json:"shared,omitempty"`
+}
+
+type inspectTable struct {
+	Index    uint32  `json:"index"`
+	Imported bool    `json:"imported,omitempty"`
+	Type     string  `json:"type"`
+	Min      uint32  `json:"min"`
+	Max      *uint32 `json:"max,omitempty"`
+}
+
+type inspectGlobal struct {
+	Index    uint32 `json:"index"`
+	Imported bool   `json:"imported,omitempty"`
+	Type     string `json:"type"`
+	Mutable  bool   `json:"mutable,omitempty"`
+}
+
+type inspectFunction struct {
+	Index uint32 `json:"index"`
+	// Name is the name in the name section, or empty.
+	Name string `json:"name,omitempty"`
+	Desc string `json:"desc"`
+	// Size is the size in bytes of the function in the code section, including its locals.
+	Size int `json:"size"`
+}
+
+func doInspect(args []string, stdOut io.Writer, stdErr io.Writer) int {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	flags.SetOutput(stdErr)
+
+	var help bool
+	flags.BoolVar(&help, "h", false, "Prints usage.")
+
+	var asJSON bool
+	flags.BoolVar(&asJSON, "json", false, "Prints the inspection as JSON.")
+
+	_ = flags.Parse(args)
+
+	if help {
+		printInspectUsage(stdErr, flags)
+		return 0
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stdErr, "missing path to wasm file")
+		printInspectUsage(stdErr, flags)
+		return 1
+	}
+
+	rc, bin := readWasm(flags.Arg(0), stdErr)
+	if rc != 0 {
+		return rc
+	}
+
+	i, err := inspect(bin)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error inspecting wasm binary: %v\n", err)
+		return 1
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(stdOut)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(i)
+	} else {
+		printInspection(stdOut, i)
+	}
+	return 0
+}
+
+// inspect decodes the binary, which is valid with all features supported by wazero.
+func inspect(bin []byte) (*inspection, error) {
+	m, err := binary.DecodeModule(bin, watFeatures, wasm.MemoryLimitPages, false, false, false)
+	if err == nil {
+		err = m.Validate(watFeatures)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	i := &inspection{Features: detectFeatures(bin)}
+	var codeSizes []int
+	if i.Sections, codeSizes, err = inspectSections(bin); err != nil {
+		return nil, err
+	}
+	for _, s := range i.Sections {
+		i.DWARF = i.DWARF || strings.HasPrefix(s.Name, ".debug_")
+	}
+
+	var funcTypes []wasm.Index
+	var memIdx, tableIdx, globalIdx uint32
+	for _, imp := range m.ImportSection {
+		ii := inspectImport{Module: imp.Module, Name: imp.Name, Type: wasm.ExternTypeName(imp.Type)}
+		switch imp.Type {
+		case wasm.ExternTypeFunc:
+			funcTypes = append(funcTypes, imp.DescFunc)
+			ii.Desc = signature(&m.TypeSection[imp.DescFunc])
+		case wasm.ExternTypeMemory:
+			mem := memory(memIdx, imp.DescMem)
+			mem.Imported = true
+			i.Memories = append(i.Memories, mem)
+			ii.Desc = mem.desc()
+			memIdx++
+		case wasm.ExternTypeTable:
+			table := table(tableIdx, &imp.DescTable)
+			table.Imported = true
+			i.Tables = append(i.Tables, table)
+			ii.Desc = table.desc()
+			tableIdx++
+		case wasm.ExternTypeGlobal:
+			g := inspectGlobal{Index: globalIdx, Imported: true, Type: wasm.ValueTypeName(imp.DescGlobal.ValType), Mutable: imp.DescGlobal.Mutable}
+			i.Globals = append(i.Globals, g)
+			ii.Desc = g.desc()
+			globalIdx++
+		case wasm.ExternTypeTag:
+			ii.Desc = signature(&m.TypeSection[imp.DescTag])
+		}
+		i.Imports = append(i.Imports, ii)
+	}
+
+	if m.MemorySection != nil {
+		i.Memories = append(i.Memories, memory(memIdx, m.MemorySection))
+	}
+	for j := range m.TableSection {
+		i.Tables = append(i.Tables, table(tableIdx, &m.TableSection[j]))
+		tableIdx++
+	}
+	for _, g := range m.GlobalSection {
+		i.Globals = append(i.Globals, inspectGlobal{Index: globalIdx, Type: wasm.ValueTypeName(g.Type.ValType), Mutable: g.Type.Mutable})
+		globalIdx++
+	}
+
+	funcTypes = append(funcTypes, m.FunctionSection...)
+	for j, typeIdx := range m.FunctionSection {
+		idx := m.ImportFunctionCount + wasm.Index(j)
+		f := inspectFunction{Index: idx, Desc: signature(&m.TypeSection[typeIdx])}
+		if j < len(codeSizes) {
+			f.Size = codeSizes[j]
+		}
+		if m.NameSection != nil {
+			f.Name = nameOf(m.NameSection.FunctionNames, idx)
+		}
+		i.Functions = append(i.Functions, f)
+	}
+
+	for _, exp := range m.ExportSection {
+		e := inspectExport{Name: exp.Name, Type: wasm.ExternTypeName(exp.Type), Index: exp.Index}
+		if exp.Type == wasm.ExternTypeFunc {
+			e.Desc = signature(&m.TypeSection[funcTypes[exp.Index]])
+		}
+		i.Exports = append(i.Exports, e)
+	}
+	return i, nil
+}
+
+// inspectSections returns the sections of the binary, and the size of each function in the code section.
+func inspectSections(bin []byte) (sections []inspectSection, codeSizes []int, err error) {
+	errMalformed := errors.New("malformed section")
+	for pos := 8; pos < len(bin); {
+		s := inspectSection{ID: wasm.SectionIDName(bin[pos]), Offset: pos}
+		size, n, err := leb128.LoadUint32(bin[pos+1:])
+		if err != nil {
+			return nil, nil, errMalformed
+		}
+		start, end := pos+1+int(n), pos+1+int(n)+int(size)
+		if end > len(bin) {
+			return nil, nil, errMalformed
+		}
+		s.Size = int(size)
+		content := bin[start:end]
+
+		switch bin[pos] {
+		case wasm.SectionIDCustom:
+			nameLen, n, err := leb128.LoadUint32(content)
+			if err != nil || int(n)+int(nameLen) > len(content) {
+				return nil, nil, errMalformed
+			}
+			s.Name = string(content[n : int(n)+int(nameLen)])
+		case wasm.SectionIDCode:
+			count, n, err := leb128.LoadUint32(content)
+			if err != nil {
+				return nil, nil, errMalformed
+			}
+			for offset := int(n); uint32(len(codeSizes)) < count; {
+				bodySize, n, err := leb128.LoadUint32(content[offset:])
+				if err != nil {
+					return nil, nil, errMalformed
+				}
+				codeSizes = append(codeSizes, int(n)+int(bodySize))
+				offset += int(n) + int(bodySize)
+			}
+		}
+		sections = append(sections, s)
+		pos = end
+	}
+	return
+}
+
+// detectFeatures returns the features beyond WebAssembly 1.0 the binary uses, which are those it isn't valid without.
+func detectFeatures(bin []byte) (features []string) {
+	for f := api.CoreFeatures(1); f <= watFeatures; f <<= 1 {
+		if f&watFeatures == 0 || f == api.CoreFeatureMutableGlobal {
+			continue
+		}
+		enabled := watFeatures &^ f
+		m, err := binary.DecodeModule(bin, enabled, wasm.MemoryLimitPages, false, false, false)
+		if err == nil {
+			err = m.Validate(enabled)
+		}
+		if err != nil {
+			features = append(features, f.String())
+		}
+	}
+	return
+}
+
+func memory(idx uint32, mem *wasm.Memory) inspectMemory {
+	m := inspectMemory{Index: idx, Min: mem.Min, Shared: mem.IsShared}
+	if mem.IsMaxEncoded {
+		max := mem.Max
+		m.Max = &max
+	}
+	return m
+}
+
+func table(idx uint32, t *wasm.Table) inspectTable {
+	return inspectTable{Index: idx, Type: wasm.ValueTypeName(t.Type), Min: t.Min, Max: t.Max}
+}
+
+func (m *inspectMemory) desc() string {
+	if m.Shared {
+		return limits(m.Min, m.Max) + " shared"
+	}
+	return limits(m.Min, m.Max)
+}
+
+func (t *inspectTable) desc() string {
+	return limits(t.Min, t.Max) + " " + t.Type
+}
+
+func (g *inspectGlobal) desc() string {
+	if g.Mutable {
+		return "(mut " + g.Type + ")"
+	}
+	return g.Type
+}
+
+// signature formats a function type in the text format, such as "(param i32 i32) (result i32)".
+func signature(ft *wasm.FunctionType) string {
+	var parts []string
+	if len(ft.Params) > 0 {
+		parts = append(parts, "(param "+valueTypeNames(ft.Params)+")")
+	}
+	if len(ft.Results) > 0 {
+		parts = append(parts, "(result "+valueTypeNames(ft.Results)+")")
+	}
+	return strings.Join(parts, " ")
+}
+
+func valueTypeNames(types []wasm.ValueType) string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, wasm.ValueTypeName(t))
+	}
+	return strings.Join(names, " ")
+}
+
+func nameOf(names wasm.NameMap, idx wasm.Index) string {
+	for _, n := range names {
+		if n.Index == idx {
+			return n.Name
+		}
+	}
+	return ""
+}
+
+// limits formats the limits of a memory or table, such as "1 2".
+func limits(min uint32, max *uint32) string {
+	if max == nil {
+		return fmt.Sprint(min)
+	}
+	return fmt.Sprintf("%d %d", min, *max)
+}
+
+func printInspection(w io.Writer, i *inspection) {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "Sections:")
+	for _, s := range i.Sections {
+		id := s.ID
+		if s.Name != "" {
+			id += fmt.Sprintf(" %q", s.Name)
+		}
+		row(tw, id, fmt.Sprintf("offset=%#x", s.Offset), fmt.Sprintf("size=%d", s.Size))
+	}
+
+	fmt.Fprintln(tw, "Imports:")
+	for _, imp := range i.Imports {
+		row(tw, imp.Type, imp.Module+"."+imp.Name, imp.Desc)
+	}
+
+	fmt.Fprintln(tw, "Exports:")
+	for _, e := range i.Exports {
+		row(tw, e.Type, e.Name, fmt.Sprintf("%s[%d]", e.Type, e.Index), e.Desc)
+	}
+
+	fmt.Fprintln(tw, "Memories:")
+	for _, m := range i.Memories {
+		row(tw, fmt.Sprintf("memory[%d]", m.Index), m.desc(), imported(m.Imported))
+	}
+
+	fmt.Fprintln(tw, "Tables:")
+	for _, t := range i.Tables {
+		row(tw, fmt.Sprintf("table[%d]", t.Index), t.desc(), imported(t.Imported))
+	}
+
+	fmt.Fprintln(tw, "Globals:")
+	for _, g := range i.Globals {
+		row(tw, fmt.Sprintf("global[%d]", g.Index), g.desc(), imported(g.Imported))
+	}
+
+	fmt.Fprintln(tw, "Functions:")
+	for _, f := range i.Functions {
+		name := ""
+		if f.Name != "" {
+			name = "$" + f.Name
+		}
+		row(tw, fmt.Sprintf("func[%d]", f.Index), name, fmt.Sprintf("size=%d", f.Size), f.Desc)
+	}
+
+	features := "none"
+	if len(i.Features) > 0 {
+		features = strings.Join(i.Features, ", ")
+	}
+	fmt.Fprintf(tw, "Features: %s\n", features)
+	fmt.Fprintf(tw, "DWARF: %t\n", i.DWARF)
+}
+
+// row writes an indented row of cells, without trailing empty cells which would be padded with spaces.
+func row(w io.Writer, cells ...string) {
+	for len(cells) > 0 && cells[len(cells)-1] == "" {
+		cells = cells[:len(cells)-1]
+	}
+	fmt.Fprintf(w, "  %s\n", strings.Join(cells, "\t"))
+}
+
+func imported(b bool) string {
+	if b {
+		return "imported"
+	}
+	return ""
+}
+
+func printInspectUsage(stdErr io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(stdErr, "wazero CLI")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Usage:\n  wazero inspect <options> <path to wasm or wat file>")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Options:")
+	flags.PrintDefaults()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// valueTypeV128 is the api.ValueType of a v128, which functions defined in WebAssembly can have.
+const valueTypeV128 = api.ValueType(wasm.ValueTypeV128)
+
+// parseParams parses the arguments of the invoked function according to the types of its parameters. A v128 takes
+// two parameters, and is parsed from lanes in the form of <shape>:<lane>,..., such as "i32x4:1,2,3,4".
+func parseParams(def api.FunctionDefinition, args []string) ([]uint64, error) {
+	types := def.ParamTypes()
+	if len(args) != len(types) {
+		return nil, fmt.Errorf("expected %d arguments, but %d were given", len(types), len(args))
+	}
+	params := make([]uint64, 0, len(types))
+	for i, t := range types {
+		arg := args[i]
+		var err error
+		var v uint64
+		switch t {
+		case api.ValueTypeI32:
+			v, err = parseInt(arg, 32)
+		case api.ValueTypeI64:
+			v, err = parseInt(arg, 64)
+		case api.ValueTypeF32:
+			var f float64
+			if f, err = strconv.ParseFloat(arg, 32); err == nil {
+				v = api.EncodeF32(float32(f))
+			}
+		case api.ValueTypeF64:
+			var f float64
+			if f, err = strconv.ParseFloat(arg, 64); err == nil {
+				v = api.EncodeF64(f)
+			}
+		case valueTypeV128:
+			var lo, hi uint64
+			if lo, hi, err = parseV128(arg); err == nil {
+				params = append(params, lo, hi)
+				continue
+			}
+		default: // a reference, such as externref
+			if arg != "null" {
+				v, err = strconv.ParseUint(arg, 0, 64)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s argument %q", wasm.ValueTypeName(wasm.ValueType(t)), arg)
+		}
+		params = append(params, v)
+	}
+	return params, nil
+}
+
+// parseInt parses a signed or unsigned integer, such as -1 or 0xffffffff.
+func parseInt(s string, bitSize int) (uint64, error) {
+	if v, err := strconv.ParseInt(s, 0, bitSize); err == nil {
+		if bitSize == 32 {
+			return uint64(uint32(v)), nil
+		}
+		return uint64(v), nil
+	}
+	return strconv.ParseUint(s, 0, bitSize)
+}
+
+// parseV128 parses a v128 in the form of <shape>:<lane>,..., such as "i32x4:1,2,3,4" or "f64x2:1.5,-2".
+func parseV128(s string) (lo, hi uint64, err error) {
+	shape, lanesStr, _ := strings.Cut(s, ":")
+	var laneBits, lanes int
+	switch shape {
+	case "i8x16":
+		laneBits, lanes = 8, 16
+	case "i16x8":
+		laneBits, lanes = 16, 8
+	case "i32x4", "f32x4":
+		laneBits, lanes = 32, 4
+	case "i64x2", "f64x2":
+		laneBits, lanes = 64, 2
+	default:
+		return 0, 0, fmt.Errorf("invalid shape %q", shape)
+	}
+	fields := strings.Split(lanesStr, ",")
+	if len(fields) != lanes {
+		return 0, 0, fmt.Errorf("expected %d lanes", lanes)
+	}
+
+	var b [16]byte
+	for i, f := range fields {
+		var v uint64
+		switch shape[0] {
+		case 'f':
+			var fl float64
+			if fl, err = strconv.ParseFloat(f, laneBits); err == nil {
+				if v = math.Float64bits(fl); laneBits == 32 {
+					v = uint64(math.Float32bits(float32(fl)))
+				}
+			}
+		default:
+			v, err = parseInt(f, laneBits)
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+		for j := 0; j < laneBits/8; j++ {
+			b[i*laneBits/8+j] = byte(v >> (8 * j))
+		}
+	}
+	return binary.LittleEndian.Uint64(b[:8]), binary.LittleEndian.Uint64(b[8:]), nil
+}
+
+// formatResults formats the results of the invoked function according to their types, such as "-1" for an i32. A
+// v128 is formatted as two 64-bit lanes, such as "i64x2:0x1,0x2", and a null reference as "null".
+func formatResults(def api.FunctionDefinition, results []uint64) []string {
+	var ret []string
+	for _, t := range def.ResultTypes() {
+		v := results[0]
+		results = results[1:]
+		switch t {
+		case api.ValueTypeI32:
+			ret = append(ret, strconv.FormatInt(int64(api.DecodeI32(v)), 10))
+		case api.ValueTypeI64:
+			ret = append(ret, strconv.FormatInt(int64(v), 10))
+		case api.ValueTypeF32:
+			ret = append(ret, strconv.FormatFloat(float64(api.DecodeF32(v)), 'g', -1, 32))
+		case api.ValueTypeF64:
+			ret = append(ret, strconv.FormatFloat(api.DecodeF64(v), 'g', -1, 64))
+		case valueTypeV128:
+			ret = append(ret, fmt.Sprintf("i64x2:%#x,%#x", v, results[0]))
+			results = results[1:]
+		default:
+			if v == 0 {
+				ret = append(ret, "null")
+			} else {
+				ret = append(ret, strconv.FormatUint(v, 10))
+			}
+		}
+	}
+	return ret
+}
@@ -24,6 +24,11 @@ import (
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/version"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binary"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/wast"
+	"github.com/tetratelabs/wazero/internal/wat"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -55,8 +60,16 @@ func doMain(stdOut io.Writer, stdErr logging.Writer) int {
 	switch subCmd {
 	case "compile":
 		return doCompile(flag.Args()[1:], stdErr)
+	case "inspect":
+		return doInspect(flag.Args()[1:], stdOut, stdErr)
 	case "run":
 		return doRun(flag.Args()[1:], stdOut, stdErr)
+	case "wizer":
+		return doWizer(flag.Args()[1:], stdOut, stdErr)
+	case "spectest":
+		return doSpectest(flag.Args()[1:], stdOut, stdErr)
+	case "wat2wasm":
+		return doWat2Wasm(flag.Args()[1:], stdErr)
 	case "version":
 		fmt.Fprintln(stdOut, version.GetWazeroVersion())
 		return 0
@@ -90,8 +103,14 @@ func doCompile(args []string, stdErr io.Writer) int {
 			"Enables memory profiling and writes the profile at the given path.")
 	}
 
+	var output string
+	flags.StringVar(&output, "o", "",
+		"Writes the compiled module to the given path, for use with the run command. "+
+			"The result is only runnable by the same version of wazero on a compatible host.")
+
 	cacheDir := cacheDirFlag(flags)
 	workers := workersFlag(flags)
+	rtFlags := runtimeConfigFlags(flags)
 
 	_ = flags.Parse(args)
 
@@ -117,13 +136,15 @@ func doCompile(args []string, stdErr io.Writer) int {
 
 	wasmPath := flags.Arg(0)
 
-	wasm, err := os.ReadFile(wasmPath)
-	if err != nil {
-		fmt.Fprintf(stdErr, "error reading wasm binary: %v\n", err)
-		return 1
+	rc, wasm := readWasm(wasmPath, stdErr)
+	if rc != 0 {
+		return rc
 	}
 
-	c := wazero.NewRuntimeConfig()
+	rc, c := rtFlags.apply(wazero.NewRuntimeConfig(), stdErr)
+	if rc != 0 {
+		return rc
+	}
 	if rc, cache := maybeUseCacheDir(cacheDir, stdErr); rc != 0 {
 		return rc
 	} else if cache != nil {
@@ -148,6 +169,11 @@ func doCompile(args []string, stdErr io.Writer) int {
 			fmt.Fprintf(stdErr, "error compiling wasm binary: %v\n", err)
 			return 1
 		}
+		if output != "" && count == 1 {
+			if rc := writeCompiledModule(compiledModule, output, stdErr); rc != 0 {
+				return rc
+			}
+		}
 		if err := compiledModule.Close(ctx); err != nil {
 			fmt.Fprintf(stdErr, "error releasing compiled module: %v\n", err)
 			return 1
@@ -157,6 +183,19 @@ func doCompile(args []string, stdErr io.Writer) int {
 	return 0
 }
 
+func writeCompiledModule(compiledModule wazero.CompiledModule, path string, stdErr io.Writer) int {
+	data, err := compiledModule.MarshalBinary()
+	if err != nil {
+		fmt.Fprintf(stdErr, "error serializing compiled module: %v\n", err)
+		return 1
+	}
+	if err = os.WriteFile(path, data, 0o644); err != nil {
+		fmt.Fprintf(stdErr, "error writing compiled module: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
 func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 	flags := flag.NewFlagSet("run", flag.ExitOnError)
 	flags.SetOutput(stdErr)
@@ -183,8 +222,13 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 			"This may be specified multiple times. When <wasm path> is unset, <path> is used. "+
 			"For example, -mount=/:/ or c:\\:/ makes the entire host volume writeable by wasm. "+
 			"For read-only mounts, append the suffix ':ro'. "+
-			"Note that the volume mount inherently allows the guest to escape the volume via relative path lookups like '../../'. "+
-			"If that is not desired, use wazero as a library and implement a custom fs.FS.")
+			"Note that the volume mount inherently allows the guest to escape the volume via relative path lookups like '../../' "+
+			"or symbolic links, unless -confine-mounts is set.")
+
+	var confineMounts bool
+	flags.BoolVar(&confineMounts, "confine-mounts", false,
+		"Prevents the guest from escaping mounted directories via relative path lookups like '../../' or symbolic links. "+
+			"On Linux, paths are resolved by the kernel with openat2 and RESOLVE_BENEATH.")
 
 	var listens sliceFlag
 	flags.Var(&listens, "listen",
@@ -205,6 +249,17 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 		"A comma-separated list of host function scopes to log to stderr. "+
 			"This may be specified multiple times. Supported values: all,clock,filesystem,memory,proc,poll,random,sock")
 
+	var invoke string
+	flags.StringVar(&invoke, "invoke", "",
+		"Name of the exported function to call instead of _start. Arguments after the path to the wasm file are "+
+			"parsed according to the parameter types of the function, and its results are printed to stdout, "+
+			"one per line. A v128 argument is of the form <shape>:<lanes>, such as i32x4:1,2,3,4.")
+
+	var initFunc string
+	flags.StringVar(&initFunc, "init-func", "",
+		"Name of the exported function which initializes the module before the <invoke> function is called. "+
+			"When unset, _initialize is called if exported.")
+
 	var cpuProfile string
 	var memProfile string
 	if version.GetWazeroVersion() == version.Default {
@@ -217,6 +272,7 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 
 	cacheDir := cacheDirFlag(flags)
 	workers := workersFlag(flags)
+	rtFlags := runtimeConfigFlags(flags)
 
 	_ = flags.Parse(args)
 
@@ -231,6 +287,12 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 		return 1
 	}
 
+	if initFunc != "" && invoke == "" {
+		fmt.Fprintln(stdErr, "init-func requires invoke")
+		printRunUsage(stdErr, flags)
+		return 1
+	}
+
 	if memProfile != "" {
 		defer writeHeapProfile(stdErr, memProfile)
 	}
@@ -263,15 +325,14 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 		env = append(env, fields[0], fields[1])
 	}
 
-	rc, _, fsConfig := validateMounts(mounts, stdErr)
+	rc, _, fsConfig := validateMounts(mounts, confineMounts, stdErr)
 	if rc != 0 {
 		return rc
 	}
 
-	wasm, err := os.ReadFile(wasmPath)
-	if err != nil {
-		fmt.Fprintf(stdErr, "error reading wasm binary: %v\n", err)
-		return 1
+	rc, wasm := readWasm(wasmPath, stdErr)
+	if rc != 0 {
+		return rc
 	}
 
 	wasmExe := filepath.Base(wasmPath)
@@ -282,6 +343,9 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 	} else {
 		rtc = wazero.NewRuntimeConfig()
 	}
+	if rc, rtc = rtFlags.apply(rtc, stdErr); rc != 0 {
+		return rc
+	}
 
 	ctx := maybeHostLogging(context.Background(), logging.LogScopes(hostlogging), stdErr)
 
@@ -333,22 +397,57 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 		WithFSConfig(fsConfig).
 		WithSysNanosleep().
 		WithSysNanotime().
-		WithSysWalltime().
-		WithArgs(append([]string{wasmExe}, wasmArgs...)...)
+		WithSysWalltime()
+	if invoke == "" {
+		conf = conf.WithArgs(append([]string{wasmExe}, wasmArgs...)...)
+	} else {
+		// The arguments are those of the invoked function, not of the program.
+		conf = conf.WithArgs(wasmExe)
+	}
 	for i := 0; i < len(env); i += 2 {
 		conf = conf.WithEnv(env[i], env[i+1])
 	}
 
-	guest, err := rt.CompileModule(compilationCtx, wasm)
-	if err != nil {
+	var guest wazero.CompiledModule
+	var err error
+	if wazero.IsCompiledModule(wasm) {
+		if guest, err = rt.LoadCompiledModule(compilationCtx, wasm); err != nil {
+			fmt.Fprintf(stdErr, "error loading compiled module: %v\n", err)
+			return 1
+		}
+	} else if guest, err = rt.CompileModule(compilationCtx, wasm); err != nil {
 		fmt.Fprintf(stdErr, "error compiling wasm binary: %v\n", err)
 		return 1
 	}
 
+	var invokeDef api.FunctionDefinition
+	var params []uint64
+	if invoke != "" {
+		exported := guest.ExportedFunctions()
+		if invokeDef = exported[invoke]; invokeDef == nil {
+			fmt.Fprintf(stdErr, "function %q is not exported\n", invoke)
+			return 1
+		}
+		if params, err = parseParams(invokeDef, wasmArgs); err != nil {
+			fmt.Fprintf(stdErr, "error parsing arguments of %s: %v\n", invoke, err)
+			return 1
+		}
+		// Run the initializer, if any, instead of _start while instantiating.
+		if initFunc == "" {
+			conf = conf.WithStartFunctions("_initialize")
+		} else if exported[initFunc] == nil {
+			fmt.Fprintf(stdErr, "function %q is not exported\n", initFunc)
+			return 1
+		} else {
+			conf = conf.WithStartFunctions(initFunc)
+		}
+	}
+
+	var mod api.Module
 	switch detectImports(guest.ImportedFunctions()) {
 	case modeWasi:
 		wasi_snapshot_preview1.MustInstantiate(ctx, rt)
-		_, err = rt.InstantiateModule(ctx, guest, conf)
+		mod, err = rt.InstantiateModule(ctx, guest, conf)
 	case modeWasiUnstable:
 		// Instantiate the current WASI functions under the wasi_unstable
 		// instead of wasi_snapshot_preview1.
@@ -357,10 +456,24 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 		_, err = wasiBuilder.Instantiate(ctx)
 		if err == nil {
 			// Instantiate our binary, but using the old import names.
-			_, err = rt.InstantiateModule(ctx, guest, conf)
+			mod, err = rt.InstantiateModule(ctx, guest, conf)
 		}
 	case modeDefault:
-		_, err = rt.InstantiateModule(ctx, guest, conf)
+		mod, err = rt.InstantiateModule(ctx, guest, conf)
+	}
+
+	var results []uint64
+	if err == nil && invokeDef != nil {
+		if results, err = mod.ExportedFunction(invoke).Call(ctx, params...); err == nil {
+			for _, r := range formatResults(invokeDef, results) {
+				fmt.Fprintln(stdOut, r)
+			}
+			return 0
+		}
+		if _, ok := err.(*sys.ExitError); !ok {
+			fmt.Fprintf(stdErr, "error invoking %s: %v\n", invoke, err)
+			return 1
+		}
 	}
 
 	if err != nil {
@@ -379,7 +492,327 @@ func doRun(args []string, stdOut io.Writer, stdErr logging.Writer) int {
 	return 0
 }
 
-func validateMounts(mounts sliceFlag, stdErr logging.Writer) (rc int, rootPath string, config wazero.FSConfig) {
+func doWizer(args []string, stdOut io.Writer, stdErr logging.Writer) int {
+	flags := flag.NewFlagSet("wizer", flag.ExitOnError)
+	flags.SetOutput(stdErr)
+
+	var help bool
+	flags.BoolVar(&help, "h", false, "Prints usage.")
+
+	var output string
+	flags.StringVar(&output, "o", "", "Writes the pre-initialized WebAssembly binary to the given path.")
+
+	var initFunc string
+	flags.StringVar(&initFunc, "init-func", "wizer.initialize",
+		"Name of the exported function which initializes the module.")
+
+	var keepInitFunc bool
+	flags.BoolVar(&keepInitFunc, "keep-init-func", false,
+		"Keeps the export of the init function, which is otherwise removed.")
+
+	var envs sliceFlag
+	flags.Var(&envs, "env", "key=value pair of environment variable to expose to the binary. "+
+		"Can be specified multiple times.")
+
+	var mounts sliceFlag
+	flags.Var(&mounts, "mount",
+		"Filesystem path to expose to the binary in the form of <path>[:<wasm path>][:ro]. "+
+			"This may be specified multiple times. When <wasm path> is unset, <path> is used.")
+
+	rtFlags := runtimeConfigFlags(flags)
+
+	_ = flags.Parse(args)
+
+	if help {
+		printWizerUsage(stdErr, flags)
+		return 0
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stdErr, "missing path to wasm file")
+		printWizerUsage(stdErr, flags)
+		return 1
+	}
+
+	if output == "" {
+		fmt.Fprintln(stdErr, "missing output path")
+		printWizerUsage(stdErr, flags)
+		return 1
+	}
+
+	wasmPath := flags.Arg(0)
+	wasmArgs := flags.Args()[1:]
+	if len(wasmArgs) > 0 && wasmArgs[0] == "--" {
+		wasmArgs = wasmArgs[1:]
+	}
+
+	rc, _, fsConfig := validateMounts(mounts, false, stdErr)
+	if rc != 0 {
+		return rc
+	}
+
+	rc, rtc := rtFlags.apply(wazero.NewRuntimeConfig(), stdErr)
+	if rc != 0 {
+		return rc
+	}
+
+	wasmBin, err := os.ReadFile(wasmPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error reading wasm binary: %v\n", err)
+		return 1
+	}
+
+	// The module is decoded separately from the one compiled by the runtime,
+	// so that rewriting it cannot affect the running instance.
+	memoryLimitPages := wasm.MemoryLimitPages
+	if rtFlags.memoryLimitPages > 0 {
+		memoryLimitPages = uint32(rtFlags.memoryLimitPages)
+	}
+	source, err := binary.DecodeModule(wasmBin, api.CoreFeatures(rtFlags.features), memoryLimitPages,
+		rtFlags.memoryCapacityFromMax, false, true)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error compiling wasm binary: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	rt := wazero.NewRuntimeWithConfig(ctx, rtc)
+	defer rt.Close(ctx)
+
+	guest, err := rt.CompileModule(ctx, wasmBin)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error compiling wasm binary: %v\n", err)
+		return 1
+	}
+
+	// The init function is called instead of any start function, such as
+	// "_start". The start section of the module still runs.
+	conf := wazero.NewModuleConfig().
+		WithStdout(stdOut).
+		WithStderr(stdErr).
+		WithFSConfig(fsConfig).
+		WithStartFunctions().
+		WithArgs(append([]string{filepath.Base(wasmPath)}, wasmArgs...)...)
+	for _, e := range envs {
+		fields := strings.SplitN(e, "=", 2)
+		if len(fields) != 2 {
+			fmt.Fprintf(stdErr, "invalid environment variable: %s\n", e)
+			return 1
+		}
+		conf = conf.WithEnv(fields[0], fields[1])
+	}
+
+	switch detectImports(guest.ImportedFunctions()) {
+	case modeWasi:
+		wasi_snapshot_preview1.MustInstantiate(ctx, rt)
+	case modeWasiUnstable:
+		wasiBuilder := rt.NewHostModuleBuilder("wasi_unstable")
+		wasi_snapshot_preview1.NewFunctionExporter().ExportFunctions(wasiBuilder)
+		if _, err = wasiBuilder.Instantiate(ctx); err != nil {
+			fmt.Fprintf(stdErr, "error instantiating wasm binary: %v\n", err)
+			return 1
+		}
+	}
+
+	mod, err := rt.InstantiateModule(ctx, guest, conf)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error instantiating wasm binary: %v\n", err)
+		return 1
+	}
+
+	fn := mod.ExportedFunction(initFunc)
+	if fn == nil {
+		fmt.Fprintf(stdErr, "error initializing wasm binary: function %q is not exported\n", initFunc)
+		return 1
+	}
+	if _, err = fn.Call(ctx); err != nil {
+		fmt.Fprintf(stdErr, "error initializing wasm binary: %v\n", err)
+		return 1
+	}
+
+	if err = mod.(*wasm.ModuleInstance).PreInitialize(source); err != nil {
+		fmt.Fprintf(stdErr, "error pre-initializing wasm binary: %v\n", err)
+		return 1
+	}
+	if !keepInitFunc {
+		exports := source.ExportSection[:0]
+		for _, e := range source.ExportSection {
+			if e.Type != wasm.ExternTypeFunc || e.Name != initFunc {
+				exports = append(exports, e)
+			}
+		}
+		source.ExportSection = exports
+	}
+
+	if err = os.WriteFile(output, binaryencoding.EncodeModule(source), 0o644); err != nil {
+		fmt.Fprintf(stdErr, "error writing wasm binary: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func doWat2Wasm(args []string, stdErr io.Writer) int {
+	flags := flag.NewFlagSet("wat2wasm", flag.ExitOnError)
+	flags.SetOutput(stdErr)
+
+	var help bool
+	flags.BoolVar(&help, "h", false, "Prints usage.")
+
+	var output string
+	flags.StringVar(&output, "o", "",
+		"Writes the WebAssembly binary to the given path. Defaults to the path of the text file with a .wasm extension.")
+
+	var noCheck bool
+	flags.BoolVar(&noCheck, "no-check", false,
+		"Skips validating the WebAssembly binary, for example to produce invalid binaries for tests.")
+
+	_ = flags.Parse(args)
+
+	if help {
+		printWat2WasmUsage(stdErr, flags)
+		return 0
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stdErr, "missing path to wat file")
+		printWat2WasmUsage(stdErr, flags)
+		return 1
+	}
+
+	watPath := flags.Arg(0)
+	if output == "" {
+		output = strings.TrimSuffix(watPath, filepath.Ext(watPath)) + ".wasm"
+	}
+
+	source, err := os.ReadFile(watPath)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error reading wat file: %v\n", err)
+		return 1
+	}
+
+	bin, err := wat.Compile(source)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error parsing wat file: %s:%v\n", watPath, err)
+		return 1
+	}
+
+	if !noCheck {
+		m, err := binary.DecodeModule(bin, watFeatures, wasm.MemoryLimitPages, false, false, false)
+		if err == nil {
+			err = m.Validate(watFeatures)
+		}
+		if err != nil {
+			fmt.Fprintf(stdErr, "error validating wasm binary: %v\n", err)
+			return 1
+		}
+	}
+
+	if err = os.WriteFile(output, bin, 0o644); err != nil {
+		fmt.Fprintf(stdErr, "error writing wasm binary: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func doSpectest(args []string, stdOut io.Writer, stdErr io.Writer) int {
+	flags := flag.NewFlagSet("spectest", flag.ExitOnError)
+	flags.SetOutput(stdErr)
+
+	var help bool
+	flags.BoolVar(&help, "h", false, "Prints usage.")
+
+	var useInterpreter bool
+	flags.BoolVar(&useInterpreter, "interpreter", false,
+		"Interprets WebAssembly modules instead of compiling them into native code.")
+
+	var quiet bool
+	flags.BoolVar(&quiet, "q", false, "Only prints failed commands and the summary of each script.")
+
+	_ = flags.Parse(args)
+
+	if help {
+		printSpectestUsage(stdErr, flags)
+		return 0
+	}
+
+	if flags.NArg() < 1 {
+		fmt.Fprintln(stdErr, "missing path to wast file")
+		printSpectestUsage(stdErr, flags)
+		return 1
+	}
+
+	var rtc wazero.RuntimeConfig
+	if useInterpreter {
+		rtc = wazero.NewRuntimeConfigInterpreter()
+	} else {
+		rtc = wazero.NewRuntimeConfig()
+	}
+	rtc = rtc.WithCoreFeatures(watFeatures)
+
+	ctx := context.Background()
+	rc := 0
+	for _, path := range flags.Args() {
+		source, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stdErr, "error reading wast file: %v\n", err)
+			rc = 1
+			continue
+		}
+		script, err := wat.ParseScript(source)
+		if err != nil {
+			fmt.Fprintf(stdErr, "error parsing wast file: %s:%v\n", path, err)
+			rc = 1
+			continue
+		}
+
+		passed, failed := 0, 0
+		err = wast.Run(ctx, rtc, script, func(r *wast.Result) {
+			if r.Err != nil {
+				failed++
+			} else {
+				passed++
+			}
+			if !quiet || r.Err != nil {
+				fmt.Fprintf(stdOut, "%s:%s\n", path, r)
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(stdErr, "error running wast file: %s: %v\n", path, err)
+			rc = 1
+			continue
+		}
+		fmt.Fprintf(stdOut, "%s: %d passed, %d failed\n", path, passed, failed)
+		if failed > 0 {
+			rc = 1
+		}
+	}
+	return rc
+}
+
+// watFeatures are the features text modules and scripts are validated with, which include all features wazero
+// supports, as the text format doesn't declare the features a module uses.
+const watFeatures = api.CoreFeaturesV2 | experimental.CoreFeaturesThreads | experimental.CoreFeaturesTailCall |
+	experimental.CoreFeaturesExtendedConst | experimental.CoreFeaturesExceptionHandling |
+	experimental.CoreFeaturesTypedFunctionReferences
+
+// readWasm reads the WebAssembly binary or compiled module at path, converting it from the text format when the
+// path has the .wat extension.
+func readWasm(path string, stdErr io.Writer) (rc int, bin []byte) {
+	bin, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(stdErr, "error reading wasm binary: %v\n", err)
+		return 1, nil
+	}
+	if filepath.Ext(path) == ".wat" {
+		if bin, err = wat.Compile(bin); err != nil {
+			fmt.Fprintf(stdErr, "error parsing wat file: %s:%v\n", path, err)
+			return 1, nil
+		}
+	}
+	return 0, bin
+}
+
+func validateMounts(mounts sliceFlag, confine bool, stdErr logging.Writer) (rc int, rootPath string, config wazero.FSConfig) {
 	config = wazero.NewFSConfig()
 	for _, mount := range mounts {
 		if len(mount) == 0 {
@@ -418,6 +851,9 @@ func validateMounts(mounts sliceFlag, stdErr logging.Writer) (rc int, rootPath s
 		}
 
 		root := sysfs.DirFS(dir)
+		if confine {
+			root = sysfs.ConfinedDirFS(dir)
+		}
 		if readOnly {
 			root = &sysfs.ReadFS{FS: root}
 		}
@@ -490,6 +926,48 @@ func workersFlag(flags *flag.FlagSet) *int {
 		"Increasing this value may improve compilation speed at the cost of higher memory usage.")
 }
 
+// runtimeFlags are the flags which configure the wazero.RuntimeConfig of run and compile.
+type runtimeFlags struct {
+	features              featuresFlag
+	memoryLimitPages      uint
+	memoryCapacityFromMax bool
+	debugInfo             bool
+	closeOnContextDone    bool
+}
+
+func runtimeConfigFlags(flags *flag.FlagSet) *runtimeFlags {
+	f := &runtimeFlags{features: featuresFlag(api.CoreFeaturesV2)}
+	flags.Var(&f.features, "features",
+		"A comma-separated list of WebAssembly features to enable in addition to those of WebAssembly 2.0. "+
+			"This may be specified multiple times. Supported values: all,"+strings.ReplaceAll((watFeatures&^api.CoreFeaturesV2).String(), "|", ","))
+	flags.UintVar(&f.memoryLimitPages, "memory-limit", 0,
+		"Maximum number of 64KiB pages a memory can grow to. The default is the maximum of 65536 (4GiB).")
+	flags.BoolVar(&f.memoryCapacityFromMax, "memory-capacity-from-max", false,
+		"Allocates memory up to its maximum size when instantiated, instead of growing it on demand.")
+	flags.BoolVar(&f.debugInfo, "debuginfo", true,
+		"Includes source locations from DWARF custom sections in stack traces.")
+	flags.BoolVar(&f.closeOnContextDone, "close-on-context-done", false,
+		"Compiles functions to check for the termination of the module, such as by -timeout. "+
+			"This is implied by -timeout when running, but must be set when compiling for it.")
+	return f
+}
+
+// apply returns the configuration with the flags applied, or a non-zero exit code if they are invalid.
+func (f *runtimeFlags) apply(rtc wazero.RuntimeConfig, stdErr io.Writer) (int, wazero.RuntimeConfig) {
+	if f.memoryLimitPages > uint(wasm.MemoryLimitPages) {
+		fmt.Fprintf(stdErr, "invalid memory-limit: %d pages exceeds the maximum of %d\n", f.memoryLimitPages, wasm.MemoryLimitPages)
+		return 1, rtc
+	}
+	if f.memoryLimitPages > 0 {
+		rtc = rtc.WithMemoryLimitPages(uint32(f.memoryLimitPages))
+	}
+	rtc = rtc.WithCoreFeatures(api.CoreFeatures(f.features)).
+		WithMemoryCapacityFromMax(f.memoryCapacityFromMax).
+		WithDebugInfoEnabled(f.debugInfo).
+		WithCloseOnContextDone(f.closeOnContextDone)
+	return 0, rtc
+}
+
 func maybeUseCacheDir(cacheDir *string, stdErr io.Writer) (int, wazero.CompilationCache) {
 	if dir := *cacheDir; dir != "" {
 		if cache, err := wazero.NewCompilationCacheWithDir(dir); err != nil {
@@ -509,14 +987,18 @@ func printUsage(stdErr io.Writer) {
 	fmt.Fprintln(stdErr)
 	fmt.Fprintln(stdErr, "Commands:")
 	fmt.Fprintln(stdErr, "  compile\tPre-compiles a WebAssembly binary")
+	fmt.Fprintln(stdErr, "  inspect\tPrints the contents of a WebAssembly binary")
 	fmt.Fprintln(stdErr, "  run\t\tRuns a WebAssembly binary")
+	fmt.Fprintln(stdErr, "  spectest\tRuns WebAssembly scripts of the specification tests")
 	fmt.Fprintln(stdErr, "  version\tDisplays the version of wazero CLI")
+	fmt.Fprintln(stdErr, "  wat2wasm\tConverts a WebAssembly text file to a binary")
+	fmt.Fprintln(stdErr, "  wizer\t\tPre-initializes a WebAssembly binary")
 }
 
 func printCompileUsage(stdErr io.Writer, flags *flag.FlagSet) {
 	fmt.Fprintln(stdErr, "wazero CLI")
 	fmt.Fprintln(stdErr)
-	fmt.Fprintln(stdErr, "Usage:\n  wazero compile <options> <path to wasm file>")
+	fmt.Fprintln(stdErr, "Usage:\n  wazero compile <options> <path to wasm or wat file>")
 	fmt.Fprintln(stdErr)
 	fmt.Fprintln(stdErr, "Options:")
 	flags.PrintDefaults()
@@ -525,7 +1007,34 @@ func printCompileUsage(stdErr io.Writer, flags *flag.FlagSet) {
 func printRunUsage(stdErr io.Writer, flags *flag.FlagSet) {
 	fmt.Fprintln(stdErr, "wazero CLI")
 	fmt.Fprintln(stdErr)
-	fmt.Fprintln(stdErr, "Usage:\n  wazero run <options> <path to wasm file> [--] <wasm args>")
+	fmt.Fprintln(stdErr, "Usage:\n  wazero run <options> <path to wasm, wat or compiled module file> [--] <wasm args>")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Options:")
+	flags.PrintDefaults()
+}
+
+func printWizerUsage(stdErr io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(stdErr, "wazero CLI")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Usage:\n  wazero wizer <options> -o <output path> <path to wasm file> [--] <wasm args>")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Options:")
+	flags.PrintDefaults()
+}
+
+func printSpectestUsage(stdErr io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(stdErr, "wazero CLI")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Usage:\n  wazero spectest <options> <path to wast file>...")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Options:")
+	flags.PrintDefaults()
+}
+
+func printWat2WasmUsage(stdErr io.Writer, flags *flag.FlagSet) {
+	fmt.Fprintln(stdErr, "wazero CLI")
+	fmt.Fprintln(stdErr)
+	fmt.Fprintln(stdErr, "Usage:\n  wazero wat2wasm <options> <path to wat file>")
 	fmt.Fprintln(stdErr)
 	fmt.Fprintln(stdErr, "Options:")
 	flags.PrintDefaults()
@@ -574,6 +1083,41 @@ func (f *sliceFlag) Set(s string) error {
 	return nil
 }
 
+type featuresFlag api.CoreFeatures
+
+func (f *featuresFlag) String() string {
+	return api.CoreFeatures(*f).String()
+}
+
+// Set enables the features of the comma-separated names, which are those of api.CoreFeatures.String.
+func (f *featuresFlag) Set(input string) error {
+	for _, s := range strings.Split(input, ",") {
+		switch s {
+		case "":
+			continue
+		case "all":
+			*f |= featuresFlag(watFeatures)
+			continue
+		}
+		feature := featureByName(s)
+		if feature == 0 {
+			return errors.New("not a feature")
+		}
+		*f |= featuresFlag(feature)
+	}
+	return nil
+}
+
+// featureByName returns the feature supported by wazero with the given name, or zero if there is none.
+func featureByName(name string) api.CoreFeatures {
+	for f := api.CoreFeatures(1); f <= watFeatures; f <<= 1 {
+		if f&watFeatures != 0 && f.String() == name {
+			return f
+		}
+	}
+	return 0
+}
+
 type logScopesFlag logging.LogScopes
 
 func (f *logScopesFlag) String() string {
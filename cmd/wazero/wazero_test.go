@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -14,13 +16,17 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/experimental/logging"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	"github.com/tetratelabs/wazero/internal/platform"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/version"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -157,6 +163,42 @@ func requireChdirToTemp(t *testing.T) (string, string) {
 	return tmpDir, oldwd
 }
 
+func TestCompile_Output(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	tmpDir := t.TempDir()
+
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, wasmWasiArg, 0o600))
+	outPath := filepath.Join(tmpDir, "test.wzc")
+
+	exitCode, stdout, stderr := runMain(t, "", []string{"compile", "-o", outPath, wasmPath})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Zero(t, stdout)
+
+	// The compiled module can be run in lieu of the wasm binary.
+	exitCode, stdout, stderr = runMain(t, "", []string{"run", outPath, "hello world"})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Equal(t, "test.wzc\x00hello world\x00", stdout)
+
+	// The compiled module is incompatible with the interpreter.
+	exitCode, _, stderr = runMain(t, "", []string{"run", "-interpreter", outPath})
+	require.Equal(t, 1, exitCode)
+	require.Contains(t, stderr, "error loading compiled module: compiled module: loading requires the compiler")
+
+	// The compiled module can only be run with -timeout if compiled with -close-on-context-done.
+	exitCode, _, stderr = runMain(t, "", []string{"run", "-timeout=10s", outPath})
+	require.Equal(t, 1, exitCode)
+	require.Contains(t, stderr, "produced with close on context done false, but runtime has true")
+
+	exitCode, _, stderr = runMain(t, "", []string{"compile", "-close-on-context-done", "-o", outPath, wasmPath})
+	require.Equal(t, 0, exitCode, stderr)
+	exitCode, stdout, stderr = runMain(t, "", []string{"run", "-timeout=10s", outPath, "hello world"})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Equal(t, "test.wzc\x00hello world\x00", stdout)
+}
+
 func TestCompile_Errors(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -186,6 +228,10 @@ func TestCompile_Errors(t *testing.T) {
 			message: "invalid cachedir",
 			args:    []string{"--cachedir", notWasmPath, wasmPath},
 		},
+		{
+			message: "invalid memory-limit: 65537 pages exceeds the maximum of 65536",
+			args:    []string{"--memory-limit=65537", wasmPath},
+		},
 	}
 
 	for _, tc := range tests {
@@ -482,6 +528,530 @@ func TestRun(t *testing.T) {
 	}
 }
 
+// wasmWizer increments a global in its start function, and on
+// "wizer.initialize" adds 7 to it and stores 42 in memory at offset 8. "get"
+// returns the sum of both, and "call" calls "get" via a table.
+var wasmWizer = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection:     []wasm.FunctionType{{}, {Results: []wasm.ValueType{wasm.ValueTypeI32}}},
+	FunctionSection: []wasm.Index{0, 0, 1, 1},
+	CodeSection: []wasm.Code{
+		{Body: []byte{
+			wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 1, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 0,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{
+			wasm.OpcodeI32Const, 8, wasm.OpcodeI32Const, 42, wasm.OpcodeI32Store, 0x2, 0x0,
+			wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 7, wasm.OpcodeI32Add, wasm.OpcodeGlobalSet, 0,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{
+			wasm.OpcodeGlobalGet, 0, wasm.OpcodeI32Const, 8, wasm.OpcodeI32Load, 0x2, 0x0, wasm.OpcodeI32Add,
+			wasm.OpcodeEnd,
+		}},
+		{Body: []byte{wasm.OpcodeI32Const, 0, wasm.OpcodeCallIndirect, 1, 0, wasm.OpcodeEnd}},
+	},
+	MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 1},
+	GlobalSection: []wasm.Global{{
+		Type: wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+		Init: wasm.NewConstantExpressionFromI32(0),
+	}},
+	TableSection: []wasm.Table{{Type: wasm.RefTypeFuncref, Min: 1}},
+	ElementSection: []wasm.ElementSegment{{
+		OffsetExpr: wasm.NewConstantExpressionFromI32(0),
+		Init:       []wasm.ConstantExpression{wasm.NewConstantExpressionFromOpcode(wasm.OpcodeRefFunc, []byte{2})},
+		Type:       wasm.RefTypeFuncref,
+	}},
+	DataSection:  []wasm.DataSegment{{OffsetExpression: wasm.NewConstantExpressionFromI32(0), Init: []byte("hi")}},
+	StartSection: func() *wasm.Index { idx := wasm.Index(0); return &idx }(),
+	ExportSection: []wasm.Export{
+		{Name: "wizer.initialize", Type: wasm.ExternTypeFunc, Index: 1},
+		{Name: "get", Type: wasm.ExternTypeFunc, Index: 2},
+		{Name: "call", Type: wasm.ExternTypeFunc, Index: 3},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+	},
+})
+
+func TestWizer(t *testing.T) {
+	tmpDir := t.TempDir()
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, wasmWizer, 0o600))
+	outPath := filepath.Join(tmpDir, "out.wasm")
+
+	exitCode, stdout, stderr := runMain(t, "", []string{"wizer", "-o", outPath, wasmPath})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Zero(t, stdout)
+
+	out, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	r := wazero.NewRuntime(ctx)
+	defer r.Close(ctx)
+	mod, err := r.Instantiate(ctx, out)
+	require.NoError(t, err)
+
+	// The start function isn't run again, and the init function is removed.
+	require.Nil(t, mod.ExportedFunction("wizer.initialize"))
+	for _, name := range []string{"get", "call"} {
+		results, err := mod.ExportedFunction(name).Call(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{1 + 7 + 42}, results)
+	}
+	hi, ok := mod.Memory().Read(0, 2)
+	require.True(t, ok)
+	require.Equal(t, "hi", string(hi))
+}
+
+func TestWizer_Features(t *testing.T) {
+	// A shared memory requires the threads feature, which isn't in WebAssembly 2.0.
+	wasmShared := binaryencoding.EncodeModule(&wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0},
+		CodeSection:     []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+		MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: 1, IsMaxEncoded: true, IsShared: true},
+		ExportSection:   []wasm.Export{{Name: "wizer.initialize", Type: wasm.ExternTypeFunc, Index: 0}},
+	})
+	tmpDir := t.TempDir()
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, wasmShared, 0o600))
+	outPath := filepath.Join(tmpDir, "out.wasm")
+
+	exitCode, _, stderr := runMain(t, "", []string{"wizer", "-o", outPath, wasmPath})
+	require.Equal(t, 1, exitCode)
+	require.Contains(t, stderr, "error compiling wasm binary")
+
+	exitCode, _, stderr = runMain(t, "", []string{"wizer", "-features", "threads", "-o", outPath, wasmPath})
+	require.Equal(t, 0, exitCode, stderr)
+
+	out, err := os.ReadFile(outPath)
+	require.NoError(t, err)
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesThreads))
+	defer r.Close(ctx)
+	_, err = r.Instantiate(ctx, out)
+	require.NoError(t, err)
+}
+
+func TestWizer_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	wasmPath := filepath.Join(tmpDir, "test.wasm")
+	require.NoError(t, os.WriteFile(wasmPath, wasmWizer, 0o600))
+	outPath := filepath.Join(tmpDir, "out.wasm")
+
+	notWasmPath := filepath.Join(tmpDir, "bears.wasm")
+	require.NoError(t, os.WriteFile(notWasmPath, []byte("pooh"), 0o600))
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{
+			message: "missing path to wasm file",
+			args:    []string{"-o", outPath},
+		},
+		{
+			message: "missing output path",
+			args:    []string{wasmPath},
+		},
+		{
+			message: "error reading wasm binary",
+			args:    []string{"-o", outPath, "non-existent.wasm"},
+		},
+		{
+			message: "error compiling wasm binary",
+			args:    []string{"-o", outPath, notWasmPath},
+		},
+		{
+			message: "invalid memory-limit",
+			args:    []string{"-o", outPath, "-memory-limit", "65537", wasmPath},
+		},
+		{
+			message: `error initializing wasm binary: function "init" is not exported`,
+			args:    []string{"-o", outPath, "-init-func", "init", wasmPath},
+		},
+	}
+
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"wizer"}, tt.args...))
+
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tt.message)
+		})
+	}
+}
+
+func TestWat2Wasm(t *testing.T) {
+	tmpDir := t.TempDir()
+	outPath := filepath.Join(tmpDir, "out.wasm")
+
+	exitCode, stdout, stderr := runMain(t, "", []string{"wat2wasm", "-o", outPath, "testdata/wasi_arg.wat"})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Zero(t, stdout)
+
+	// The binary runs the same as the one converted with external tools.
+	exitCode, stdout, stderr = runMain(t, "", []string{"run", outPath, "hello world"})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Equal(t, "out.wasm\x00hello world\x00", stdout)
+}
+
+func TestWat2Wasm_DefaultOutput(t *testing.T) {
+	tmpDir := t.TempDir()
+	watPath := filepath.Join(tmpDir, "test.wat")
+	require.NoError(t, os.WriteFile(watPath, []byte(`(module (func (export "f")))`), 0o600))
+
+	exitCode, _, stderr := runMain(t, "", []string{"wat2wasm", watPath})
+	require.Equal(t, 0, exitCode, stderr)
+	require.NoError(t, exist(filepath.Join(tmpDir, "test.wasm")))
+}
+
+func TestWat2Wasm_Errors(t *testing.T) {
+	tmpDir := t.TempDir()
+	outPath := filepath.Join(tmpDir, "out.wasm")
+
+	notWatPath := filepath.Join(tmpDir, "bears.wat")
+	require.NoError(t, os.WriteFile(notWatPath, []byte("(module\n  (func (local.get $pooh)))"), 0o600))
+
+	invalidPath := filepath.Join(tmpDir, "invalid.wat")
+	require.NoError(t, os.WriteFile(invalidPath, []byte("(module (func (result i32)))"), 0o600))
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{
+			message: "missing path to wat file",
+			args:    []string{"-o", outPath},
+		},
+		{
+			message: "error reading wat file",
+			args:    []string{"-o", outPath, "non-existent.wat"},
+		},
+		{
+			message: "bears.wat:2:20: unknown local $pooh",
+			args:    []string{"-o", outPath, notWatPath},
+		},
+		{
+			message: "error validating wasm binary",
+			args:    []string{"-o", outPath, invalidPath},
+		},
+		{
+			message: "error writing wasm binary",
+			args:    []string{"-o", tmpDir, "-no-check", invalidPath},
+		},
+	}
+
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"wat2wasm"}, tt.args...))
+
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tt.message)
+		})
+	}
+}
+
+func TestRun_Wat(t *testing.T) {
+	exitCode, stdout, stderr := runMain(t, "", []string{"run", "testdata/wasi_arg.wat", "hello world"})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Equal(t, "wasi_arg.wat\x00hello world\x00", stdout)
+}
+
+func TestRun_ConfineMounts(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on windows")
+	}
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "secret.txt"), []byte("secret\n"), 0o600))
+	mountDir := filepath.Join(tmpDir, "animals")
+	require.NoError(t, os.Mkdir(mountDir, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(mountDir, "bear.txt"), []byte("pooh\n"), 0o600))
+	require.NoError(t, os.Symlink("../secret.txt", filepath.Join(mountDir, "secret.txt")))
+
+	mount := fmt.Sprintf("--mount=%s:/animals:ro", mountDir)
+
+	// Without confinement, the symbolic link escapes the mount.
+	exitCode, stdout, stderr := runMain(t, "", []string{"run", mount, "testdata/cat/cat-tinygo.wasm", "/animals/secret.txt"})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Equal(t, "secret\n", stdout)
+
+	exitCode, stdout, stderr = runMain(t, "", []string{"run", "--confine-mounts", mount, "testdata/cat/cat-tinygo.wasm", "/animals/bear.txt"})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Equal(t, "pooh\n", stdout)
+
+	exitCode, stdout, _ = runMain(t, "", []string{"run", "--confine-mounts", mount, "testdata/cat/cat-tinygo.wasm", "/animals/secret.txt"})
+	require.Equal(t, 1, exitCode)
+	require.Zero(t, stdout)
+}
+
+func TestRun_Invoke(t *testing.T) {
+	watPath := filepath.Join(t.TempDir(), "test.wat")
+	require.NoError(t, os.WriteFile(watPath, []byte(`(module
+  (global $base (mut i32) (i32.const 0))
+  (func (export "_initialize") (global.set $base (i32.const 100)))
+  (func (export "init") (global.set $base (i32.const 1000)))
+  (func (export "add") (param i32 i32) (result i32) (i32.add (global.get $base) (i32.add (local.get 0) (local.get 1))))
+  (func (export "sub") (param i64 i64) (result i64) (i64.sub (local.get 0) (local.get 1)))
+  (func (export "mul") (param f32 f64) (result f32 f64) (f32.mul (local.get 0) (f32.const 2)) (f64.mul (local.get 1) (f64.const 2)))
+  (func (export "swap") (param v128) (result v128) (i8x16.shuffle 8 9 10 11 12 13 14 15 0 1 2 3 4 5 6 7 (local.get 0) (local.get 0)))
+  (func (export "ref") (param externref) (result externref) (local.get 0))
+  (func (export "none")))`), 0o600))
+
+	tests := []struct {
+		name           string
+		args           []string
+		expectedStdout string
+	}{
+		{name: "_initialize", args: []string{"-invoke=add", watPath, "1", "-2"}, expectedStdout: "99\n"},
+		{name: "init-func", args: []string{"-invoke=add", "-init-func=init", watPath, "--", "1", "2"}, expectedStdout: "1003\n"},
+		{name: "i64", args: []string{"-invoke=sub", watPath, "0x10", "18446744073709551615"}, expectedStdout: "17\n"},
+		{name: "floats", args: []string{"-invoke=mul", watPath, "1.5", "-2.25e3"}, expectedStdout: "3\n-4500\n"},
+		{name: "v128", args: []string{"-invoke=swap", watPath, "i32x4:1,2,3,4"}, expectedStdout: "i64x2:0x400000003,0x200000001\n"},
+		{name: "externref", args: []string{"-invoke=ref", watPath, "42"}, expectedStdout: "42\n"},
+		{name: "null externref", args: []string{"-invoke=ref", watPath, "null"}, expectedStdout: "null\n"},
+		{name: "no results", args: []string{"-invoke=none", watPath}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			exitCode, stdout, stderr := runMain(t, "", append([]string{"run"}, tc.args...))
+			require.Equal(t, 0, exitCode, stderr)
+			require.Equal(t, tc.expectedStdout, stdout)
+		})
+	}
+}
+
+func TestRun_Invoke_Errors(t *testing.T) {
+	watPath := filepath.Join(t.TempDir(), "test.wat")
+	require.NoError(t, os.WriteFile(watPath, []byte(`(module
+  (func (export "div") (param i32 i32) (result i32) (i32.div_s (local.get 0) (local.get 1)))
+  (func (export "v") (param v128)))`), 0o600))
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{message: "init-func requires invoke", args: []string{"-init-func=init", watPath}},
+		{message: `function "mul" is not exported`, args: []string{"-invoke=mul", watPath}},
+		{message: `function "init" is not exported`, args: []string{"-invoke=div", "-init-func=init", watPath, "1", "1"}},
+		{message: "error parsing arguments of div: expected 2 arguments, but 1 were given", args: []string{"-invoke=div", watPath, "1"}},
+		{message: `invalid i32 argument "4294967296"`, args: []string{"-invoke=div", watPath, "1", "4294967296"}},
+		{message: `invalid v128 argument "i32x4:1,2"`, args: []string{"-invoke=v", watPath, "i32x4:1,2"}},
+		{message: "error invoking div: wasm error: integer divide by zero", args: []string{"-invoke=div", watPath, "1", "0"}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"run"}, tc.args...))
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tc.message)
+		})
+	}
+}
+
+func TestRun_Features(t *testing.T) {
+	watPath := filepath.Join(t.TempDir(), "test.wat")
+	require.NoError(t, os.WriteFile(watPath, []byte(`(module
+  (memory 2)
+  (func $size (result i32) (memory.size))
+  (func (export "size") (result i32) (return_call $size)))`), 0o600))
+
+	tests := []struct {
+		name, expectedStdout, expectedStderr string
+		args                                 []string
+	}{
+		{
+			name:           "disabled",
+			args:           []string{"-invoke=size", watPath},
+			expectedStderr: `return_call invalid as feature "tail-call" is disabled`,
+		},
+		{
+			name:           "enabled",
+			args:           []string{"-invoke=size", "-features=tail-call", watPath},
+			expectedStdout: "2\n",
+		},
+		{
+			name:           "all",
+			args:           []string{"-invoke=size", "-features=all", watPath},
+			expectedStdout: "2\n",
+		},
+		{
+			name:           "memory-limit",
+			args:           []string{"-invoke=size", "-features=tail-call", "-memory-limit=1", watPath},
+			expectedStderr: "error compiling wasm binary",
+		},
+		{
+			name:           "invalid memory-limit",
+			args:           []string{"-memory-limit=65537", watPath},
+			expectedStderr: "invalid memory-limit: 65537 pages exceeds the maximum of 65536",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			exitCode, stdout, stderr := runMain(t, "", append([]string{"run"}, tc.args...))
+			if tc.expectedStderr != "" {
+				require.Equal(t, 1, exitCode)
+				require.Contains(t, stderr, tc.expectedStderr)
+			} else {
+				require.Equal(t, 0, exitCode, stderr)
+			}
+			require.Equal(t, tc.expectedStdout, stdout)
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	watPath := filepath.Join(t.TempDir(), "test.wat")
+	require.NoError(t, os.WriteFile(watPath, []byte(`(module
+  (import "env" "mem" (memory 1 2 shared))
+  (import "env" "g" (global (mut i32)))
+  (table 1 funcref)
+  (func $f (export "f") (param v128) (result v128 i32) (local.get 0) (i32.atomic.load (i32.const 0)))
+  (func (result v128 i32) (return_call $f (v128.const i64x2 0 0))))`), 0o600))
+
+	exitCode, stdout, stderr := runMain(t, "", []string{"inspect", watPath})
+	require.Equal(t, 0, exitCode, stderr)
+	require.Equal(t, `Sections:
+  type           offset=0x8   size=12
+  import         offset=0x16  size=22
+  function       offset=0x2e  size=3
+  table          offset=0x33  size=4
+  export         offset=0x39  size=5
+  code           offset=0x40  size=35
+  custom "name"  offset=0x65  size=11
+Imports:
+  memory  env.mem  1 2 shared
+  global  env.g    (mut i32)
+Exports:
+  func  f  func[0]  (param v128) (result v128 i32)
+Memories:
+  memory[0]  1 2 shared  imported
+Tables:
+  table[0]  1 funcref
+Globals:
+  global[0]  (mut i32)  imported
+Functions:
+  func[0]  $f  size=11  (param v128) (result v128 i32)
+  func[1]      size=23  (result v128 i32)
+Features: multi-value, simd, threads, tail-call
+DWARF: false
+`, stdout)
+
+	exitCode, stdout, stderr = runMain(t, "", []string{"inspect", "-json", "testdata/wasi_arg.wasm"})
+	require.Equal(t, 0, exitCode, stderr)
+	var i inspection
+	require.NoError(t, json.Unmarshal([]byte(stdout), &i))
+	require.Equal(t, []inspectImport{
+		{Module: "wasi_snapshot_preview1", Name: "args_get", Type: "func", Desc: "(param i32 i32) (result i32)"},
+		{Module: "wasi_snapshot_preview1", Name: "args_sizes_get", Type: "func", Desc: "(param i32 i32) (result i32)"},
+		{Module: "wasi_snapshot_preview1", Name: "fd_write", Type: "func", Desc: "(param i32 i32 i32 i32) (result i32)"},
+	}, i.Imports)
+	require.Equal(t, []inspectExport{
+		{Name: "memory", Type: "memory", Index: 0},
+		{Name: "_start", Type: "func", Index: 3},
+	}, i.Exports)
+	require.Equal(t, []inspectFunction{{Index: 3, Size: 31}}, i.Functions)
+	require.False(t, i.DWARF)
+}
+
+func TestInspect_Errors(t *testing.T) {
+	notWasmPath := filepath.Join(t.TempDir(), "bears.wasm")
+	require.NoError(t, os.WriteFile(notWasmPath, []byte("pooh"), 0o600))
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{
+			message: "missing path to wasm file",
+			args:    []string{},
+		},
+		{
+			message: "error reading wasm binary",
+			args:    []string{"non-existent.wasm"},
+		},
+		{
+			message: "error inspecting wasm binary",
+			args:    []string{notWasmPath},
+		},
+	}
+
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"inspect"}, tt.args...))
+
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tt.message)
+		})
+	}
+}
+
+func TestSpectest(t *testing.T) {
+	tmpDir := t.TempDir()
+	wastPath := filepath.Join(tmpDir, "add.wast")
+	require.NoError(t, os.WriteFile(wastPath, []byte(`(module
+  (func (export "add") (param i32 i32) (result i32) (i32.add (local.get 0) (local.get 1))))
+(assert_return (invoke "add" (i32.const 1) (i32.const 2)) (i32.const 3))
+(assert_return (invoke "add" (i32.const 1) (i32.const 2)) (i32.const 4))
+`), 0o600))
+
+	exitCode, stdout, stderr := runMain(t, "", []string{"spectest", wastPath})
+	require.Equal(t, 1, exitCode, stderr)
+	require.Equal(t, wastPath+`:1: module: ok
+`+wastPath+`:3: assert_return (invoke "add"): ok
+`+wastPath+`:4: assert_return (invoke "add"): FAIL: expected (i32.const 4), but was (i32.const 3)
+`+wastPath+`: 2 passed, 1 failed
+`, stdout)
+
+	// Only failures are printed when quiet.
+	exitCode, stdout, stderr = runMain(t, "", []string{"spectest", "-q", "-interpreter", wastPath})
+	require.Equal(t, 1, exitCode, stderr)
+	require.Equal(t, wastPath+`:4: assert_return (invoke "add"): FAIL: expected (i32.const 4), but was (i32.const 3)
+`+wastPath+`: 2 passed, 1 failed
+`, stdout)
+}
+
+func TestSpectest_Errors(t *testing.T) {
+	notWastPath := filepath.Join(t.TempDir(), "bears.wast")
+	require.NoError(t, os.WriteFile(notWastPath, []byte("(assert_pooh)"), 0o600))
+
+	tests := []struct {
+		message string
+		args    []string
+	}{
+		{
+			message: "missing path to wast file",
+			args:    []string{},
+		},
+		{
+			message: "error reading wast file",
+			args:    []string{"non-existent.wast"},
+		},
+		{
+			message: "bears.wast:1:1: unknown command (assert_pooh ...)",
+			args:    []string{notWastPath},
+		},
+	}
+
+	for _, tc := range tests {
+		tt := tc
+		t.Run(tt.message, func(t *testing.T) {
+			exitCode, _, stderr := runMain(t, "", append([]string{"spectest"}, tt.args...))
+
+			require.Equal(t, 1, exitCode)
+			require.Contains(t, stderr, tt.message)
+		})
+	}
+}
+
 func TestVersion(t *testing.T) {
 	exitCode, stdout, stderr := runMain(t, "", []string{"version"})
 	require.Equal(t, 0, exitCode)
@@ -676,6 +1246,55 @@ func Test_logScopesFlag(t *testing.T) {
 	}
 }
 
+func Test_featuresFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		values   []string
+		expected api.CoreFeatures
+	}{
+		{
+			name:     "empty",
+			expected: api.CoreFeaturesV2,
+		},
+		{
+			name:     "one",
+			values:   []string{"threads"},
+			expected: api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+		},
+		{
+			name:     "comma-separated",
+			values:   []string{"tail-call,extended-const"},
+			expected: api.CoreFeaturesV2 | experimental.CoreFeaturesTailCall | experimental.CoreFeaturesExtendedConst,
+		},
+		{
+			name:     "multiple",
+			values:   []string{"exception-handling", "typed-function-references"},
+			expected: api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling | experimental.CoreFeaturesTypedFunctionReferences,
+		},
+		{
+			name:     "all",
+			values:   []string{"all"},
+			expected: watFeatures,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			f := featuresFlag(api.CoreFeaturesV2)
+			for _, v := range tc.values {
+				require.NoError(t, f.Set(v))
+			}
+			require.Equal(t, tc.expected, api.CoreFeatures(f))
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		f := featuresFlag(api.CoreFeaturesV2)
+		require.EqualError(t, f.Set("threads,gc"), "not a feature")
+	})
+}
+
 func TestHelp(t *testing.T) {
 	exitCode, _, stderr := runMain(t, "", []string{"-h"})
 	require.Equal(t, 0, exitCode)
@@ -687,8 +1306,12 @@ Usage:
 
 Commands:
   compile	Pre-compiles a WebAssembly binary
+  inspect	Prints the contents of a WebAssembly binary
   run		Runs a WebAssembly binary
+  spectest	Runs WebAssembly scripts of the specification tests
   version	Displays the version of wazero CLI
+  wat2wasm	Converts a WebAssembly text file to a binary
+  wizer		Pre-initializes a WebAssembly binary
 `, stderr)
 }
 
@@ -0,0 +1,266 @@
+package wazero
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	goruntime "runtime"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/version"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+)
+
+// compiledModuleMagic prefixes the result of CompiledModule.MarshalBinary,
+// similar to how "\0asm" prefixes a wasm binary.
+var compiledModuleMagic = []byte{0x00, 'w', 'z', 'c'}
+
+// compiledModuleFormatVersion is incremented on any incompatible change to
+// the layout written by CompiledModule.MarshalBinary.
+const compiledModuleFormatVersion = 1
+
+const (
+	compiledModuleFlagEnsureTermination = 1 << iota
+	compiledModuleFlagCanonicalizeNaN
+)
+
+// compilerCpuFeatures returns the CPU features of this host which the compiler
+// generates native code for. Other features don't affect the native code, so
+// aren't required of the host loading it.
+func compilerCpuFeatures() uint64 {
+	var used platform.CpuFeatureFlags
+	switch goruntime.GOARCH {
+	case "amd64":
+		used = platform.CpuFeatureAmd64SSE4_1 | platform.CpuFeatureAmd64BMI1 | platform.CpuFeatureAmd64ABM
+	case "arm64":
+		used = platform.CpuFeatureArm64Atomic
+	}
+	return platform.CpuFeatures.Raw() & used.Raw()
+}
+
+// IsCompiledModule returns true if data starts with the header written by
+// CompiledModule.MarshalBinary, as opposed to a WebAssembly binary.
+//
+// Note: This only inspects the header, so a true result does not imply
+// Runtime.LoadCompiledModule will succeed.
+func IsCompiledModule(data []byte) bool {
+	return bytes.HasPrefix(data, compiledModuleMagic)
+}
+
+// compiledModuleHeader is the self-describing part of a serialized
+// CompiledModule, which determines if it is loadable on this host.
+type compiledModuleHeader struct {
+	wazeroVersion     string
+	goos, goarch      string
+	cpuFeatures       uint64
+	enabledFeatures   api.CoreFeatures
+	ensureTermination bool
+	canonicalizeNaN   bool
+}
+
+// MarshalBinary implements CompiledModule.MarshalBinary
+//
+// The layout, with all integers in little-endian byte order, is:
+//   - compiledModuleMagic
+//   - compiledModuleFormatVersion (1 byte)
+//   - wazero version, GOOS and GOARCH, each as a length (1 byte) and string
+//   - CPU features used by the compiler (8 bytes) and api.CoreFeatures (8 bytes)
+//   - flags (1 byte)
+//   - the wasm binary encoded from the module, as a length (4 bytes) and bytes
+//   - the native code serialized by the engine, as a length (8 bytes) and bytes
+//   - the SHA-256 checksum of everything above (32 bytes)
+func (c *compiledModule) MarshalBinary() ([]byte, error) {
+	serializer, ok := c.compiledEngine.(wasm.CompiledModuleSerializer)
+	if !ok {
+		return nil, errors.New("compiled module serialization requires the compiler")
+	}
+	native, err := serializer.SerializeCompiledModule(c.module)
+	if err != nil {
+		return nil, err
+	}
+
+	header := &compiledModuleHeader{
+		wazeroVersion:     version.GetWazeroVersion(),
+		goos:              goruntime.GOOS,
+		goarch:            goruntime.GOARCH,
+		cpuFeatures:       compilerCpuFeatures(),
+		enabledFeatures:   c.enabledFeatures,
+		ensureTermination: c.ensureTermination,
+		canonicalizeNaN:   c.canonicalizeNaN,
+	}
+	// Re-encode the wasm binary from the decoded module, instead of keeping a
+	// copy of it for the lifetime of every compiled module.
+	return encodeCompiledModule(header, binaryencoding.EncodeModule(c.module), native), nil
+}
+
+// encodeCompiledModule is the inverse of decodeCompiledModule.
+func encodeCompiledModule(header *compiledModuleHeader, source, native []byte) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(compiledModuleMagic)
+	buf.WriteByte(compiledModuleFormatVersion)
+	writeShortString(buf, header.wazeroVersion)
+	writeShortString(buf, header.goos)
+	writeShortString(buf, header.goarch)
+	buf.Write(binary.LittleEndian.AppendUint64(nil, header.cpuFeatures))
+	buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(header.enabledFeatures)))
+	var flags byte
+	if header.ensureTermination {
+		flags |= compiledModuleFlagEnsureTermination
+	}
+	if header.canonicalizeNaN {
+		flags |= compiledModuleFlagCanonicalizeNaN
+	}
+	buf.WriteByte(flags)
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(source))))
+	buf.Write(source)
+	buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(native))))
+	buf.Write(native)
+	checksum := sha256.Sum256(buf.Bytes())
+	buf.Write(checksum[:])
+	return buf.Bytes()
+}
+
+func writeShortString(buf *bytes.Buffer, s string) {
+	buf.WriteByte(byte(len(s)))
+	buf.WriteString(s)
+}
+
+// LoadCompiledModule implements Runtime.LoadCompiledModule
+func (r *runtime) LoadCompiledModule(ctx context.Context, data []byte) (CompiledModule, error) {
+	if err := r.failIfClosed(); err != nil {
+		return nil, err
+	}
+
+	header, source, native, err := decodeCompiledModule(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.checkCompiledModuleHeader(header); err != nil {
+		return nil, err
+	}
+	serializer, ok := r.store.Engine.(wasm.CompiledModuleSerializer)
+	if !ok {
+		return nil, errors.New("compiled module: loading requires the compiler")
+	}
+
+	c, listeners, err := r.decodeModule(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	if err = serializer.DeserializeCompiledModule(c.module, listeners, r.ensureTermination, native); err != nil {
+		return nil, fmt.Errorf("compiled module: %w", err)
+	}
+	return c, nil
+}
+
+// checkCompiledModuleHeader returns an error if the native code described by
+// header cannot be used by this runtime.
+func (r *runtime) checkCompiledModuleHeader(header *compiledModuleHeader) error {
+	if v := version.GetWazeroVersion(); header.wazeroVersion != v {
+		return fmt.Errorf("compiled module: produced by wazero %s, but this is %s", header.wazeroVersion, v)
+	}
+	if header.goos != goruntime.GOOS || header.goarch != goruntime.GOARCH {
+		return fmt.Errorf("compiled module: produced for %s/%s, but this is %s/%s",
+			header.goos, header.goarch, goruntime.GOOS, goruntime.GOARCH)
+	}
+	if missing := header.cpuFeatures &^ platform.CpuFeatures.Raw(); missing != 0 {
+		return fmt.Errorf("compiled module: requires CPU features %#x not present on this host", missing)
+	}
+	if missing := header.enabledFeatures &^ r.enabledFeatures; missing != 0 {
+		return fmt.Errorf("compiled module: requires disabled features: %s", missing)
+	}
+	if header.ensureTermination != r.ensureTermination {
+		return fmt.Errorf("compiled module: produced with close on context done %v, but runtime has %v",
+			header.ensureTermination, r.ensureTermination)
+	}
+	if header.canonicalizeNaN != r.deterministic {
+		return fmt.Errorf("compiled module: produced with deterministic %v, but runtime has %v",
+			header.canonicalizeNaN, r.deterministic)
+	}
+	return nil
+}
+
+// decodeCompiledModule splits data written by compiledModule.MarshalBinary
+// into its header, the wasm binary and the native code serialized by the
+// engine.
+func decodeCompiledModule(data []byte) (header *compiledModuleHeader, source, native []byte, err error) {
+	if !IsCompiledModule(data) {
+		return nil, nil, nil, errors.New("compiled module: invalid magic number")
+	}
+	if len(data) < len(compiledModuleMagic)+1+sha256.Size {
+		return nil, nil, nil, errors.New("compiled module: unexpected end of data")
+	}
+	body, checksum := data[:len(data)-sha256.Size], data[len(data)-sha256.Size:]
+	if expected := sha256.Sum256(body); !bytes.Equal(expected[:], checksum) {
+		return nil, nil, nil, errors.New("compiled module: checksum mismatch")
+	}
+
+	d := compiledModuleDecoder{b: body[len(compiledModuleMagic):]}
+	if v := d.byte(); v != compiledModuleFormatVersion {
+		return nil, nil, nil, fmt.Errorf("compiled module: unsupported format version %d", v)
+	}
+	header = &compiledModuleHeader{}
+	header.wazeroVersion = d.shortString()
+	header.goos = d.shortString()
+	header.goarch = d.shortString()
+	header.cpuFeatures = d.uint64()
+	header.enabledFeatures = api.CoreFeatures(d.uint64())
+	flags := d.byte()
+	header.ensureTermination = flags&compiledModuleFlagEnsureTermination != 0
+	header.canonicalizeNaN = flags&compiledModuleFlagCanonicalizeNaN != 0
+	source = d.bytes(uint64(d.uint32()))
+	native = d.bytes(d.uint64())
+	if d.err != nil {
+		return nil, nil, nil, d.err
+	} else if len(d.b) != 0 {
+		return nil, nil, nil, errors.New("compiled module: unexpected trailing data")
+	}
+	return
+}
+
+// compiledModuleDecoder reads fields from b, recording the first error.
+type compiledModuleDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *compiledModuleDecoder) bytes(n uint64) (ret []byte) {
+	if d.err != nil {
+		return nil
+	} else if uint64(len(d.b)) < n {
+		d.err = errors.New("compiled module: unexpected end of data")
+		return nil
+	}
+	ret, d.b = d.b[:n], d.b[n:]
+	return
+}
+
+func (d *compiledModuleDecoder) byte() byte {
+	if b := d.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *compiledModuleDecoder) shortString() string {
+	return string(d.bytes(uint64(d.byte())))
+}
+
+func (d *compiledModuleDecoder) uint32() uint32 {
+	if b := d.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *compiledModuleDecoder) uint64() uint64 {
+	if b := d.bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
@@ -0,0 +1,210 @@
+package wazero
+
+import (
+	"context"
+	goruntime "runtime"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/experimental/logging"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/version"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+)
+
+func TestCompiledModule_MarshalBinary(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	ctx := context.Background()
+
+	r := NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	compiled, err := r.CompileModule(ctx, facWasm)
+	require.NoError(t, err)
+
+	data, err := compiled.MarshalBinary()
+	require.NoError(t, err)
+	require.True(t, IsCompiledModule(data))
+	require.False(t, IsCompiledModule(facWasm))
+
+	header, source, native, err := decodeCompiledModule(data)
+	require.NoError(t, err)
+	require.Equal(t, version.GetWazeroVersion(), header.wazeroVersion)
+	require.Equal(t, compilerCpuFeatures(), header.cpuFeatures)
+	require.Equal(t, api.CoreFeaturesV2, header.enabledFeatures)
+	require.False(t, header.ensureTermination)
+	require.False(t, header.canonicalizeNaN)
+	// The source is encoded from the module, not kept from facWasm.
+	require.Equal(t, binaryencoding.EncodeModule(compiled.(*compiledModule).module), source)
+	require.True(t, len(native) > 0)
+
+	// Load into a separate runtime, which never compiled the module.
+	loader := NewRuntime(ctx)
+	defer loader.Close(ctx)
+
+	loaded, err := loader.LoadCompiledModule(ctx, data)
+	require.NoError(t, err)
+	require.Equal(t, compiled.ExportedFunctions()["fac-ssa"].ParamTypes(), loaded.ExportedFunctions()["fac-ssa"].ParamTypes())
+
+	mod, err := loader.InstantiateModule(ctx, loaded, NewModuleConfig())
+	require.NoError(t, err)
+	results, err := mod.ExportedFunction("fac-ssa").Call(ctx, 5)
+	require.NoError(t, err)
+	require.Equal(t, uint64(120), results[0])
+
+	// A loaded module can be serialized again.
+	reserialized, err := loaded.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, data, reserialized)
+}
+
+func TestCompiledModule_MarshalBinary_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("interpreter", func(t *testing.T) {
+		r := NewRuntimeWithConfig(ctx, NewRuntimeConfigInterpreter())
+		defer r.Close(ctx)
+
+		compiled, err := r.CompileModule(ctx, facWasm)
+		require.NoError(t, err)
+
+		_, err = compiled.MarshalBinary()
+		require.EqualError(t, err, "compiled module serialization requires the compiler")
+	})
+
+	if !platform.CompilerSupported() {
+		return
+	}
+
+	t.Run("function listeners", func(t *testing.T) {
+		r := NewRuntime(ctx)
+		defer r.Close(ctx)
+
+		lctx := experimental.WithFunctionListenerFactory(ctx, logging.NewLoggingListenerFactory(nil))
+		compiled, err := r.CompileModule(lctx, facWasm)
+		require.NoError(t, err)
+
+		_, err = compiled.MarshalBinary()
+		require.EqualError(t, err, "modules compiled with function listeners cannot be serialized")
+	})
+}
+
+func TestRuntime_LoadCompiledModule_Errors(t *testing.T) {
+	if !platform.CompilerSupported() {
+		t.Skip()
+	}
+	ctx := context.Background()
+
+	r := NewRuntime(ctx)
+	defer r.Close(ctx)
+
+	compiled, err := r.CompileModule(ctx, facWasm)
+	require.NoError(t, err)
+	data, err := compiled.MarshalBinary()
+	require.NoError(t, err)
+
+	// rewrite mutates the header of a copy of data, then fixes its checksum.
+	rewrite := func(fn func(h *compiledModuleHeader)) []byte {
+		header, source, native, err := decodeCompiledModule(data)
+		require.NoError(t, err)
+		fn(header)
+		return encodeCompiledModule(header, source, native)
+	}
+
+	tests := []struct {
+		name        string
+		config      RuntimeConfig
+		data        []byte
+		expectedErr string
+	}{
+		{
+			name:        "wasm binary",
+			data:        facWasm,
+			expectedErr: "compiled module: invalid magic number",
+		},
+		{
+			name:        "truncated",
+			data:        data[:len(data)-1],
+			expectedErr: "compiled module: checksum mismatch",
+		},
+		{
+			name:        "corrupted",
+			data:        append(append([]byte{}, data[:len(data)-1]...), data[len(data)-1]+1),
+			expectedErr: "compiled module: checksum mismatch",
+		},
+		{
+			name:        "wazero version",
+			data:        rewrite(func(h *compiledModuleHeader) { h.wazeroVersion = "v0.0.1" }),
+			expectedErr: "compiled module: produced by wazero v0.0.1, but this is " + version.GetWazeroVersion(),
+		},
+		{
+			name:        "os",
+			data:        rewrite(func(h *compiledModuleHeader) { h.goos = "plan9" }),
+			expectedErr: "compiled module: produced for plan9/" + goruntime.GOARCH + ", but this is " + goruntime.GOOS + "/" + goruntime.GOARCH,
+		},
+		{
+			name:        "cpu features",
+			data:        rewrite(func(h *compiledModuleHeader) { h.cpuFeatures = 1 << 63 }),
+			expectedErr: "compiled module: requires CPU features 0x8000000000000000 not present on this host",
+		},
+		{
+			name:        "core features",
+			config:      NewRuntimeConfig().WithCoreFeatures(api.CoreFeaturesV1),
+			data:        data,
+			expectedErr: "compiled module: requires disabled features: bulk-memory-operations|multi-value|nontrapping-float-to-int-conversion|reference-types|sign-extension-ops|simd",
+		},
+		{
+			name:        "close on context done",
+			config:      NewRuntimeConfig().WithCloseOnContextDone(true),
+			data:        data,
+			expectedErr: "compiled module: produced with close on context done false, but runtime has true",
+		},
+		{
+			name:        "deterministic",
+			config:      NewRuntimeConfig().WithDeterministic(true),
+			data:        data,
+			expectedErr: "compiled module: produced with deterministic false, but runtime has true",
+		},
+		{
+			name:        "interpreter",
+			config:      NewRuntimeConfigInterpreter(),
+			data:        data,
+			expectedErr: "compiled module: loading requires the compiler",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			config := tc.config
+			if config == nil {
+				config = NewRuntimeConfig()
+			}
+			loader := NewRuntimeWithConfig(ctx, config)
+			defer loader.Close(ctx)
+
+			_, err := loader.LoadCompiledModule(ctx, tc.data)
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestCompilerCpuFeatures(t *testing.T) {
+	features := compilerCpuFeatures()
+	require.Zero(t, features&^platform.CpuFeatures.Raw())
+
+	switch goruntime.GOARCH {
+	case "amd64":
+		require.Zero(t, features&^uint64(platform.CpuFeatureAmd64SSE4_1|platform.CpuFeatureAmd64BMI1|platform.CpuFeatureAmd64ABM))
+		require.Equal(t, platform.CpuFeatures.Has(platform.CpuFeatureAmd64SSE4_1), features&platform.CpuFeatureAmd64SSE4_1 != 0)
+	case "arm64":
+		require.Zero(t, features&^platform.CpuFeatureArm64Atomic.Raw())
+		require.Equal(t, platform.CpuFeatures.Has(platform.CpuFeatureArm64Atomic), features != 0)
+	default:
+		require.Zero(t, features)
+	}
+}
@@ -2,12 +2,14 @@ package wazero
 
 import (
 	"context"
+	crand "crypto/rand"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"math"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
@@ -167,7 +169,36 @@ type RuntimeConfig interface {
 	//
 	// When the invocations of api.Function are closed due to this, sys.ExitError is raised to the callers and
 	// the api.Module from which the functions are derived is made closed.
+	//
+	// WASI host functions which block, such as fd_read of stdin or poll_oneoff, also return sys.ExitError
+	// when the module is closed this way, instead of waiting until they would otherwise return.
 	WithCloseOnContextDone(bool) RuntimeConfig
+
+	// WithDeterministic makes guest modules produce the same results on every
+	// platform and engine, when given the same inputs. Defaults to false.
+	//
+	// When enabled:
+	//
+	//	- NaN results of float and vector (SIMD) instructions are replaced
+	//	  with the canonical NaN of their type. Otherwise, their sign and
+	//	  payload can differ between amd64, arm64 and the interpreter.
+	//	- experimental.CoreFeaturesThreads is disabled, as the results of
+	//	  shared memory depend on thread scheduling.
+	//	- Instantiating a guest module fails if its ModuleConfig uses the
+	//	  host clocks, via WithSysWalltime, WithSysNanotime or
+	//	  WithSysNanosleep, or the host random source, via
+	//	  WithRandSource(crypto/rand.Reader). The default clocks of
+	//	  ModuleConfig step monotonically, and the default random source is
+	//	  seeded, so they are already deterministic. Other sources passed to
+	//	  WithRandSource, or clocks passed to WithWalltime and the like, are
+	//	  up to the caller to make deterministic.
+	//
+	// Like WithCloseOnContextDone, this comes with an extra cost for modules
+	// which use float instructions, so it is disabled by default.
+	//
+	// Note: wazero doesn't implement relaxed SIMD, which has other
+	// implementation-defined results, so there is nothing to disable there.
+	WithDeterministic(bool) RuntimeConfig
 }
 
 // NewRuntimeConfig returns a RuntimeConfig using the compiler if it is supported in this environment,
@@ -190,6 +221,7 @@ type runtimeConfig struct {
 	cache                 CompilationCache
 	storeCustomSections   bool
 	ensureTermination     bool
+	deterministic         bool
 }
 
 // engineLessConfig helps avoid copy/pasting the wrong defaults.
@@ -265,6 +297,13 @@ func (c *runtimeConfig) WithCloseOnContextDone(ensure bool) RuntimeConfig {
 	return ret
 }
 
+// WithDeterministic implements RuntimeConfig.WithDeterministic
+func (c *runtimeConfig) WithDeterministic(deterministic bool) RuntimeConfig {
+	ret := c.clone()
+	ret.deterministic = deterministic
+	return ret
+}
+
 // WithMemoryLimitPages implements RuntimeConfig.WithMemoryLimitPages
 func (c *runtimeConfig) WithMemoryLimitPages(memoryLimitPages uint32) RuntimeConfig {
 	ret := c.clone()
@@ -350,6 +389,25 @@ type CompiledModule interface {
 	// (api.CustomSection) in this module keyed on the section name.
 	CustomSections() []api.CustomSection
 
+	// MarshalBinary returns a portable representation of this module, which
+	// includes its native code, for use with Runtime.LoadCompiledModule.
+	//
+	// The result is self-describing: it records the wazero version, target
+	// operating system and architecture, and the CPU features its native
+	// code uses. It is only loadable on a compatible host by the same version
+	// of wazero.
+	//
+	// # Notes
+	//
+	//   - This implements encoding.BinaryMarshaler.
+	//   - This errs if the module was not compiled to native code, for
+	//     example when using NewRuntimeConfigInterpreter, or when compiled
+	//     with a function listener.
+	//   - The wasm binary included is encoded from the decoded module, so it
+	//     can differ from the one compiled. For example, custom sections are
+	//     only included when RuntimeConfig.WithCustomSections is enabled.
+	MarshalBinary() ([]byte, error)
+
 	// Close releases all the allocated resources for this CompiledModule.
 	//
 	// Note: It is safe to call Close while having outstanding calls from an
@@ -367,6 +425,13 @@ type compiledModule struct {
 	// closeWithModule prevents leaking compiled code when a module is compiled implicitly.
 	closeWithModule bool
 	typeIDs         []wasm.FunctionTypeID
+
+	// The followings are used by MarshalBinary.
+
+	enabledFeatures   api.CoreFeatures
+	ensureTermination bool
+	// canonicalizeNaN is true when compiled by a deterministic runtime.
+	canonicalizeNaN bool
 }
 
 // Name implements CompiledModule.Name
@@ -536,6 +601,8 @@ type ModuleConfig interface {
 	// # Notes
 	//
 	//   - The caller is responsible to close any io.Writer they supply: It is not closed on api.Module Close.
+	//   - Except, a sysfs.FileIO from the experimental sysfs package is used by the guest as a sys.File, such as a
+	//     pipe it can poll, and is closed on api.Module Close. See sysfs.NewPipe and sysfs.NewPTY.
 	//   - This does not default to os.Stderr as that both violates sandboxing and prevents concurrent modules.
 	//
 	// See https://linux.die.net/man/3/stderr
@@ -549,6 +616,8 @@ type ModuleConfig interface {
 	// # Notes
 	//
 	//   - The caller is responsible to close any io.Reader they supply: It is not closed on api.Module Close.
+	//   - Except, a sysfs.FileIO from the experimental sysfs package is used by the guest as a sys.File, such as a
+	//     pipe it can poll, and is closed on api.Module Close. See sysfs.NewPipe and sysfs.NewPTY.
 	//   - This does not default to os.Stdin as that both violates sandboxing and prevents concurrent modules.
 	//
 	// See https://linux.die.net/man/3/stdin
@@ -562,6 +631,8 @@ type ModuleConfig interface {
 	// # Notes
 	//
 	//   - The caller is responsible to close any io.Writer they supply: It is not closed on api.Module Close.
+	//   - Except, a sysfs.FileIO from the experimental sysfs package is used by the guest as a sys.File, such as a
+	//     pipe it can poll, and is closed on api.Module Close. See sysfs.NewPipe and sysfs.NewPTY.
 	//   - This does not default to os.Stdout as that both violates sandboxing and prevents concurrent modules.
 	//
 	// See https://linux.die.net/man/3/stdout
@@ -683,6 +754,9 @@ type moduleConfig struct {
 	fsConfig FSConfig
 	// sockConfig is the network listener configuration for ABI like WASI.
 	sockConfig *internalsock.Config
+	// sysWalltime, sysNanotime and sysNanosleep are true when the host clocks
+	// are used, which fails instantiation when the runtime is deterministic.
+	sysWalltime, sysNanotime, sysNanosleep bool
 }
 
 // NewModuleConfig returns a ModuleConfig that can be used for configuring module instantiation.
@@ -791,6 +865,7 @@ func (c *moduleConfig) WithWalltime(walltime sys.Walltime, resolution sys.ClockR
 	ret := c.clone()
 	ret.walltime = walltime
 	ret.walltimeResolution = resolution
+	ret.sysWalltime = false
 	return ret
 }
 
@@ -800,7 +875,9 @@ func (c *moduleConfig) WithWalltime(walltime sys.Walltime, resolution sys.ClockR
 
 // WithSysWalltime implements ModuleConfig.WithSysWalltime
 func (c *moduleConfig) WithSysWalltime() ModuleConfig {
-	return c.WithWalltime(platform.Walltime, sys.ClockResolution(time.Microsecond.Nanoseconds()))
+	ret := c.WithWalltime(platform.Walltime, sys.ClockResolution(time.Microsecond.Nanoseconds())).(*moduleConfig)
+	ret.sysWalltime = true
+	return ret
 }
 
 // WithNanotime implements ModuleConfig.WithNanotime
@@ -808,18 +885,22 @@ func (c *moduleConfig) WithNanotime(nanotime sys.Nanotime, resolution sys.ClockR
 	ret := c.clone()
 	ret.nanotime = nanotime
 	ret.nanotimeResolution = resolution
+	ret.sysNanotime = false
 	return ret
 }
 
 // WithSysNanotime implements ModuleConfig.WithSysNanotime
 func (c *moduleConfig) WithSysNanotime() ModuleConfig {
-	return c.WithNanotime(platform.Nanotime, sys.ClockResolution(1))
+	ret := c.WithNanotime(platform.Nanotime, sys.ClockResolution(1)).(*moduleConfig)
+	ret.sysNanotime = true
+	return ret
 }
 
 // WithNanosleep implements ModuleConfig.WithNanosleep
 func (c *moduleConfig) WithNanosleep(nanosleep sys.Nanosleep) ModuleConfig {
 	ret := *c // copy
 	ret.nanosleep = nanosleep
+	ret.sysNanosleep = false
 	return &ret
 }
 
@@ -832,7 +913,9 @@ func (c *moduleConfig) WithOsyield(osyield sys.Osyield) ModuleConfig {
 
 // WithSysNanosleep implements ModuleConfig.WithSysNanosleep
 func (c *moduleConfig) WithSysNanosleep() ModuleConfig {
-	return c.WithNanosleep(platform.Nanosleep)
+	ret := c.WithNanosleep(platform.Nanosleep).(*moduleConfig)
+	ret.sysNanosleep = true
+	return ret
 }
 
 // WithRandSource implements ModuleConfig.WithRandSource
@@ -842,6 +925,28 @@ func (c *moduleConfig) WithRandSource(source io.Reader) ModuleConfig {
 	return ret
 }
 
+// requireDeterministic returns an error if the configuration uses the host
+// clocks or random source. See RuntimeConfig.WithDeterministic
+func (c *moduleConfig) requireDeterministic() error {
+	var options []string
+	if c.sysWalltime {
+		options = append(options, "WithSysWalltime")
+	}
+	if c.sysNanotime {
+		options = append(options, "WithSysNanotime")
+	}
+	if c.sysNanosleep {
+		options = append(options, "WithSysNanosleep")
+	}
+	if c.randSource == crand.Reader {
+		options = append(options, "WithRandSource(crypto/rand.Reader)")
+	}
+	if len(options) > 0 {
+		return fmt.Errorf("deterministic runtime doesn't support ModuleConfig.%s", strings.Join(options, ", ModuleConfig."))
+	}
+	return nil
+}
+
 // toSysContext creates a baseline wasm.Context configured by ModuleConfig.
 func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 	var environ [][]byte // Intentionally doesn't pre-allocate to reduce logic to default to nil.
@@ -871,8 +976,10 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 
 	var fs []experimentalsys.FS
 	var guestPaths []string
+	var maxOpenFiles int
 	if f, ok := c.fsConfig.(*fsConfig); ok {
 		fs, guestPaths = f.preopens()
+		maxOpenFiles = f.maxOpenFiles
 	}
 
 	var listeners []*net.TCPListener
@@ -882,7 +989,7 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 		}
 	}
 
-	return internalsys.NewContext(
+	if sysCtx, err = internalsys.NewContext(
 		math.MaxUint32,
 		c.args,
 		environ,
@@ -895,5 +1002,9 @@ func (c *moduleConfig) toSysContext() (sysCtx *internalsys.Context, err error) {
 		c.nanosleep, c.osyield,
 		fs, guestPaths,
 		listeners,
-	)
+	); err != nil {
+		return
+	}
+	sysCtx.FS().SetMaxOpenFiles(maxOpenFiles)
+	return
 }
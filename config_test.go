@@ -72,6 +72,11 @@ func TestRuntimeConfig(t *testing.T) {
 			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithCloseOnContextDone(true) },
 			expected: &runtimeConfig{ensureTermination: true},
 		},
+		{
+			name:     "WithDeterministic",
+			with:     func(c RuntimeConfig) RuntimeConfig { return c.WithDeterministic(true) },
+			expected: &runtimeConfig{deterministic: true},
+		},
 	}
 
 	for _, tt := range tests {
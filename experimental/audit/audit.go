@@ -0,0 +1,50 @@
+// Package audit records the filesystem, socket and process operations of
+// guest modules as structured events, for example to keep an audit trail of
+// the files a guest read or wrote.
+//
+// Each event records one WASI function call, such as path_open or fd_write,
+// with the module name, the guest and host paths, the open flags, the count
+// of bytes transferred and the errno returned to the guest. Events are
+// delivered to a Go function, or written as JSON lines:
+//
+//	f, _ := os.Create("audit.jsonl")
+//	ctx = audit.WithJSONLines(ctx, f)
+//	mod, err := r.InstantiateModule(ctx, compiled, config)
+//
+// This is independent of experimental/logging, which formats every host
+// call as text for debugging. When no audit is configured, the cost is one
+// check per call.
+//
+// # Notes
+//
+//   - This is an experimental API and subject to change.
+//   - Only guest modules are audited, not host modules, and only calls to
+//     WASI functions whose names start with "fd_", "path_", "sock_" or
+//     "proc_".
+//   - Events are delivered synchronously, before the function returns to
+//     the guest, so a slow listener slows down the guest.
+package audit
+
+import (
+	"context"
+	"io"
+
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+)
+
+// Event is an operation of a guest, such as opening a file.
+type Event = internalsys.AuditEvent
+
+// WithListener returns a context which calls fn with the events of guest
+// modules instantiated with it. fn must be safe for concurrent use, when
+// guests run concurrently.
+func WithListener(ctx context.Context, fn func(Event)) context.Context {
+	return context.WithValue(ctx, internalsys.AuditKey{}, internalsys.NewAudit(fn))
+}
+
+// WithJSONLines returns a context which writes the events of guest modules
+// instantiated with it to w, as one line of JSON each. Errors writing to w
+// are ignored.
+func WithJSONLines(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, internalsys.AuditKey{}, internalsys.NewJSONAudit(w))
+}
@@ -0,0 +1,115 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/audit"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/testing/proxy"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+func TestWithListener(t *testing.T) {
+	dir := t.TempDir()
+
+	var events []audit.Event
+	ctx := audit.WithListener(testCtx, func(e audit.Event) { events = append(events, e) })
+	mod := instantiate(t, ctx, wazero.NewModuleConfig().WithName("guest").
+		WithFSConfig(wazero.NewFSConfig().WithDirMount(dir, "/data")))
+
+	call := func(name string, params ...uint64) {
+		_, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		require.NoError(t, err)
+	}
+
+	// Open "a.txt" for writing, write "wazero" and read it back.
+	require.True(t, mod.Memory().WriteString(0, "a.txt"))
+	require.True(t, mod.Memory().WriteString(16, "wazero"))
+	require.True(t, mod.Memory().WriteUint32Le(32, 16)) // iovec.buf
+	require.True(t, mod.Memory().WriteUint32Le(36, 6))  // iovec.buf_len
+	call(wasip1.PathOpenName, 3, 0, 0, 5, uint64(wasip1.O_CREAT), uint64(wasip1.RIGHT_FD_READ|wasip1.RIGHT_FD_WRITE), 0, 0, 64)
+	call(wasip1.FdWriteName, 4, 32, 1, 64)
+	call(wasip1.FdPreadName, 4, 32, 1, 0, 64)
+	call(wasip1.FdCloseName, 4)
+	call(wasip1.PathUnlinkFileName, 3, 0, 5)
+	call(wasip1.PathUnlinkFileName, 3, 0, 5)
+
+	// Clock functions aren't audited.
+	call(wasip1.ClockTimeGetName, uint64(wasip1.ClockIDMonotonic), 0, 0)
+
+	// The signal of proc_raise isn't mistaken for a file descriptor.
+	call(wasip1.ProcRaiseName, 3)
+
+	hostPath := filepath.Join(dir, "a.txt")
+	for i := range events {
+		require.False(t, events[i].Time.IsZero())
+		events[i].Time = time.Time{}
+	}
+	require.Equal(t, []audit.Event{
+		{
+			Module: "guest", Op: wasip1.PathOpenName, Fd: 4, Path: "/data/a.txt", HostPath: hostPath,
+			Flags: experimentalsys.O_NOFOLLOW | experimentalsys.O_CREAT | experimentalsys.O_RDWR,
+		},
+		{Module: "guest", Op: wasip1.FdWriteName, Fd: 4, Path: "/data/a.txt", HostPath: hostPath, Bytes: 6},
+		{Module: "guest", Op: wasip1.FdPreadName, Fd: 4, Path: "/data/a.txt", HostPath: hostPath, Bytes: 6},
+		{Module: "guest", Op: wasip1.FdCloseName, Fd: 4, Path: "/data/a.txt", HostPath: hostPath},
+		{Module: "guest", Op: wasip1.PathUnlinkFileName, Fd: 3, Path: "/data/a.txt", HostPath: hostPath},
+		{
+			Module: "guest", Op: wasip1.PathUnlinkFileName, Fd: 3, Path: "/data/a.txt", HostPath: hostPath,
+			Errno: experimentalsys.ENOENT,
+		},
+		{Module: "guest", Op: wasip1.ProcRaiseName, Errno: experimentalsys.ENOSYS},
+	}, events)
+}
+
+func TestWithJSONLines(t *testing.T) {
+	var out bytes.Buffer
+	ctx := audit.WithJSONLines(testCtx, &out)
+	mod := instantiate(t, ctx, wazero.NewModuleConfig().WithName("guest"))
+
+	_, err := mod.ExportedFunction(wasip1.FdWriteName).Call(testCtx, 12345, 0, 0, 0)
+	require.NoError(t, err)
+
+	// proc_exit is audited before the module closes.
+	_, err = mod.ExportedFunction(wasip1.ProcExitName).Call(testCtx, 2)
+	require.Error(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Equal(t, 2, len(lines))
+
+	var e audit.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &e))
+	e.Time = time.Time{}
+	require.Equal(t, audit.Event{Module: "guest", Op: wasip1.FdWriteName, Fd: 12345, Errno: experimentalsys.EBADF}, e)
+	require.True(t, strings.HasPrefix(lines[1], `{"time":`))
+	require.True(t, strings.HasSuffix(lines[1], `"module":"guest","op":"proc_exit","fd":0,"exit_code":2}`), lines[1])
+}
+
+func instantiate(t *testing.T, ctx context.Context, config wazero.ModuleConfig) api.Module {
+	r := wazero.NewRuntime(testCtx)
+	t.Cleanup(func() { _ = r.Close(testCtx) })
+
+	wasiCompiled, err := wasi_snapshot_preview1.NewBuilder(r).Compile(testCtx)
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, wasiCompiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+
+	proxyCompiled, err := r.CompileModule(testCtx, proxy.NewModuleBinary(wasi_snapshot_preview1.ModuleName, wasiCompiled))
+	require.NoError(t, err)
+	mod, err := r.InstantiateModule(ctx, proxyCompiled, config)
+	require.NoError(t, err)
+	return mod
+}
@@ -0,0 +1,135 @@
+// Package checkpoint persists the state of an idle module instance, to resume
+// it in a new instance of the same module, possibly in another process.
+//
+// This differs from experimental.Snapshotter, which captures the call stack to
+// unwind within a single function call, and is not persistent.
+//
+// Here's an example of pre-initializing a module once, then resuming it:
+//
+//	mod, _ := r.InstantiateModule(ctx, compiled, config.WithStartFunctions("_initialize"))
+//	var buf bytes.Buffer
+//	_ = checkpoint.Save(ctx, mod, &buf)
+//
+//	// later, perhaps in another process with the same config
+//	mod, _ = r.InstantiateModule(ctx, compiled, config.WithStartFunctions())
+//	_ = checkpoint.Restore(ctx, mod, &buf)
+package checkpoint
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// magic prefixes the result of Save.
+var magic = []byte{0x00, 'w', 'z', 'i'}
+
+// formatVersion is incremented on any incompatible change to the layout
+// written by Save.
+const formatVersion = 1
+
+// Save writes the state of mod to w, which Restore can apply to another
+// instance of the same module.
+//
+// The state includes the memory, globals and tables defined by mod, as well as
+// files it opened via WASI. Stdio and the pre-opened file systems themselves
+// are not included: they are taken from the configuration of the restored
+// instance.
+//
+// # Errors
+//
+// Save fails if mod holds state which cannot be persisted:
+//   - a reference to a function of another module, or a non-null externref,
+//     in a global or table
+//   - an open socket
+//
+// # Notes
+//
+//   - mod must not be executing any function.
+//   - State outside mod is not saved, such as imported memories, globals or
+//     tables, or anything held by host functions.
+//   - The result is only valid for the same wazero version and runtime
+//     configuration, as it is verified against the ID of the compiled module.
+func Save(_ context.Context, mod api.Module, w io.Writer) error {
+	m, ok := mod.(*wasm.ModuleInstance)
+	if !ok {
+		return fmt.Errorf("unsupported module type %T", mod)
+	} else if m.IsClosed() {
+		return errors.New("module closed")
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.Write(magic)
+	bw.WriteByte(formatVersion)
+	bw.Write(m.Source.ID[:])
+	snapshot := m.Snapshot()
+	defer snapshot.Close()
+	if err := m.WriteSnapshot(bw, snapshot); err != nil {
+		return err
+	}
+	if m.Sys != nil {
+		bw.WriteByte(1)
+		if err := m.Sys.FS().WriteSnapshot(bw); err != nil {
+			return err
+		}
+	} else {
+		bw.WriteByte(0)
+	}
+	return bw.Flush()
+}
+
+// Restore reads state written by Save from r, and applies it to mod, which
+// must be an instance of the same compiled module.
+//
+// mod should be instantiated without start functions, with the same file
+// system configuration as the saved module. Files it opened are closed, and
+// those open when saving are reopened at the same file descriptors.
+//
+// # Notes
+//
+//   - mod must not be executing any function.
+//   - On error, mod may be partially restored, so should be closed.
+func Restore(_ context.Context, mod api.Module, r io.Reader) error {
+	m, ok := mod.(*wasm.ModuleInstance)
+	if !ok {
+		return fmt.Errorf("unsupported module type %T", mod)
+	} else if m.IsClosed() {
+		return errors.New("module closed")
+	}
+
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic)+1+len(m.Source.ID))
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	if !bytes.HasPrefix(header, magic) {
+		return errors.New("invalid magic number")
+	} else if v := header[len(magic)]; v != formatVersion {
+		return fmt.Errorf("unsupported format version %d", v)
+	} else if !bytes.Equal(header[len(magic)+1:], m.Source.ID[:]) {
+		return errors.New("saved from a different module or runtime configuration")
+	}
+
+	s, err := m.ReadSnapshot(br)
+	if err != nil {
+		return err
+	}
+	if err = m.Restore(s); err != nil {
+		return err
+	}
+
+	hasSys, err := br.ReadByte()
+	if err != nil {
+		return fmt.Errorf("failed to read files: %w", err)
+	}
+	if hasSys == 1 && m.Sys != nil {
+		return m.Sys.FS().RestoreSnapshot(br)
+	}
+	return nil
+}
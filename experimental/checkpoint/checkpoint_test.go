@@ -0,0 +1,173 @@
+package checkpoint_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/checkpoint"
+	"github.com/tetratelabs/wazero/experimental/table"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+)
+
+var testCtx = context.Background()
+
+// counterWasm exports "inc", which increments a global and stores it to
+// memory at offset zero. A funcref table holds "inc" at offset 1.
+var counterWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection:     []wasm.FunctionType{{}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeGlobalGet, 0,
+		wasm.OpcodeI32Const, 1,
+		wasm.OpcodeI32Add,
+		wasm.OpcodeGlobalSet, 0,
+		wasm.OpcodeI32Const, 0,
+		wasm.OpcodeGlobalGet, 0,
+		wasm.OpcodeI32Store, 0x2, 0x0,
+		wasm.OpcodeEnd,
+	}}},
+	MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 3, IsMaxEncoded: true},
+	GlobalSection: []wasm.Global{{
+		Type: wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+		Init: wasm.NewConstantExpressionFromI32(0),
+	}},
+	TableSection: []wasm.Table{{Type: wasm.RefTypeFuncref, Min: 2}},
+	ElementSection: []wasm.ElementSegment{{
+		OffsetExpr: wasm.NewConstantExpressionFromI32(1),
+		Init:       []wasm.ConstantExpression{wasm.NewConstantExpressionFromOpcode(wasm.OpcodeRefFunc, []byte{0})},
+		Type:       wasm.RefTypeFuncref,
+	}},
+	ExportSection: []wasm.Export{
+		{Name: "inc", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		{Name: "counter", Type: wasm.ExternTypeGlobal, Index: 0},
+	},
+})
+
+func TestSaveRestore(t *testing.T) {
+	t.Run("interpreter", func(t *testing.T) {
+		testSaveRestore(t, wazero.NewRuntimeConfigInterpreter())
+	})
+	if platform.CompilerSupported() {
+		t.Run("compiler", func(t *testing.T) {
+			testSaveRestore(t, wazero.NewRuntimeConfigCompiler())
+		})
+	}
+}
+
+func testSaveRestore(t *testing.T, config wazero.RuntimeConfig) {
+	r := wazero.NewRuntimeWithConfig(testCtx, config)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, counterWasm)
+	require.NoError(t, err)
+
+	saved, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName("saved"))
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = saved.ExportedFunction("inc").Call(testCtx)
+		require.NoError(t, err)
+	}
+	_, ok := saved.Memory().Grow(1)
+	require.True(t, ok)
+
+	var buf bytes.Buffer
+	require.NoError(t, checkpoint.Save(testCtx, saved, &buf))
+
+	restored, err := r.InstantiateModule(testCtx, compiled, wazero.NewModuleConfig().WithName("restored"))
+	require.NoError(t, err)
+	require.NoError(t, checkpoint.Restore(testCtx, restored, &buf))
+
+	require.Equal(t, uint64(3), restored.ExportedGlobal("counter").Get())
+	require.Equal(t, uint32(2*wasm.MemoryPageSize), restored.Memory().Size())
+	v, ok := restored.Memory().ReadUint32Le(0)
+	require.True(t, ok)
+	require.Equal(t, uint32(3), v)
+
+	// The table reference must resolve to the function of the restored module.
+	_, err = table.LookupFunction(restored, 0, 1, nil, nil).Call(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), restored.ExportedGlobal("counter").Get())
+	require.Equal(t, uint64(3), saved.ExportedGlobal("counter").Get())
+}
+
+func TestSave_Errors(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	host, err := r.NewHostModuleBuilder("host").
+		NewFunctionBuilder().WithFunc(func() {}).Export("f").
+		Instantiate(testCtx)
+	require.NoError(t, err)
+	require.EqualError(t, checkpoint.Save(testCtx, host, &bytes.Buffer{}), "unsupported module type wazero.hostModuleInstance")
+
+	mod, err := r.Instantiate(testCtx, counterWasm)
+	require.NoError(t, err)
+	require.NoError(t, mod.Close(testCtx))
+	require.EqualError(t, checkpoint.Save(testCtx, mod, &bytes.Buffer{}), "module closed")
+}
+
+func TestRestore_Errors(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, counterWasm)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, checkpoint.Save(testCtx, mod, &buf))
+	saved := buf.Bytes()
+
+	other, err := r.Instantiate(testCtx, binaryencoding.EncodeModule(&wasm.Module{}))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		mod         api.Module
+		input       []byte
+		expectedErr string
+	}{
+		{
+			name:        "empty",
+			mod:         mod,
+			expectedErr: "failed to read header: EOF",
+		},
+		{
+			name:        "invalid magic",
+			mod:         mod,
+			input:       append([]byte{'w'}, saved[1:]...),
+			expectedErr: "invalid magic number",
+		},
+		{
+			name:        "unsupported version",
+			mod:         mod,
+			input:       append(append([]byte(nil), saved[:4]...), append([]byte{0}, saved[5:]...)...),
+			expectedErr: "unsupported format version 0",
+		},
+		{
+			name:        "different module",
+			mod:         other,
+			input:       saved,
+			expectedErr: "saved from a different module or runtime configuration",
+		},
+		{
+			name:        "truncated",
+			mod:         mod,
+			input:       saved[:40],
+			expectedErr: "failed to read integer: EOF",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkpoint.Restore(testCtx, tc.mod, bytes.NewReader(tc.input))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package replay records the nondeterministic inputs a guest receives from
+// WASI host functions, so that a run can later be reproduced exactly, for
+// example to debug a problem which only happened once.
+//
+// The inputs recorded are:
+//   - args and environment variables
+//   - clock_time_get, and clocks read by poll_oneoff
+//   - random_get
+//   - fd_read and fd_pread, including stdin
+//   - sock_recv
+//   - which subscriptions of poll_oneoff are ready
+//
+// Here's an example of recording a run, then replaying it:
+//
+//	var trace bytes.Buffer
+//	mod, err := r.InstantiateModule(replay.WithRecording(ctx, &trace), compiled, config)
+//
+//	// later, perhaps in another process without the same stdin
+//	mod, err = r.InstantiateModule(replay.WithReplay(ctx, &trace), compiled, config)
+//
+// A trace is JSON, with one line per input. When the guest requests an input
+// which isn't the next one in the trace, such as reading from a different file
+// descriptor, the call fails with a *DivergenceError.
+//
+// # Notes
+//
+//   - This is an experimental API and subject to change.
+//   - Only guest modules are traced, not host modules.
+//   - Other results, such as file metadata and sock_accept, come from the
+//     host as usual. Replay with the same configuration and files if the
+//     guest depends on them.
+//   - When replaying, reads don't wait for data, and poll_oneoff only waits
+//     when all subscriptions are clocks.
+package replay
+
+import (
+	"context"
+	"io"
+
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+)
+
+// Event is an input recorded in a trace.
+type Event = internalsys.TraceEvent
+
+// DivergenceError is returned by a function call when replaying, if the guest
+// requests a different input than the one recorded.
+type DivergenceError = internalsys.TraceDivergenceError
+
+// WithRecording returns a context which records the inputs of guest modules
+// instantiated with it to w.
+//
+// Note: Inputs are recorded in the order they are requested, so instantiate
+// one module at a time with each context.
+func WithRecording(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, internalsys.TraceKey{}, internalsys.NewTraceRecorder(w))
+}
+
+// WithReplay returns a context which replays the inputs recorded by
+// WithRecording to guest modules instantiated with it.
+func WithReplay(ctx context.Context, r io.Reader) context.Context {
+	return context.WithValue(ctx, internalsys.TraceKey{}, internalsys.NewTraceReplayer(r))
+}
@@ -0,0 +1,94 @@
+package replay_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/replay"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/testing/proxy"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+func TestRecordReplay(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	wasiCompiled, err := wasi_snapshot_preview1.NewBuilder(r).Compile(testCtx)
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, wasiCompiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+	proxyCompiled, err := r.CompileModule(testCtx, proxy.NewModuleBinary(wasi_snapshot_preview1.ModuleName, wasiCompiled))
+	require.NoError(t, err)
+
+	instantiate := func(ctx context.Context, config wazero.ModuleConfig) api.Module {
+		mod, err := r.InstantiateModule(ctx, proxyCompiled, config.WithName("").WithSysNanotime().WithRandSource(rand.Reader))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = mod.Close(testCtx) })
+		return mod
+	}
+
+	call := func(mod api.Module, name string, params ...uint64) error {
+		_, err := mod.ExportedFunction(name).Call(testCtx, params...)
+		return err
+	}
+
+	// run calls functions which return nondeterministic inputs, and returns
+	// the memory they were written to.
+	run := func(mod api.Module) []byte {
+		require.True(t, mod.Memory().Write(32, []byte{48, 0, 0, 0, 8, 0, 0, 0})) // iovec of 8 bytes at 48
+		require.NoError(t, call(mod, wasip1.ClockTimeGetName, wasip1.ClockIDMonotonic, 0, 0))
+		require.NoError(t, call(mod, wasip1.RandomGetName, 8, 16))
+		require.NoError(t, call(mod, wasip1.FdReadName, 0, 32, 1, 40))
+		require.NoError(t, call(mod, wasip1.ArgsSizesGetName, 56, 60))
+		buf, ok := mod.Memory().Read(0, 64)
+		require.True(t, ok)
+		return bytes.Clone(buf)
+	}
+
+	var trace bytes.Buffer
+	mod := instantiate(replay.WithRecording(testCtx, &trace),
+		wazero.NewModuleConfig().WithStdin(strings.NewReader("wazero")).WithArgs("a", "b"))
+	recorded := run(mod)
+	require.NoError(t, mod.Close(testCtx))
+	require.Equal(t, "wazero", string(recorded[48:54]))
+
+	// Replay without the same stdin or args, and with different clocks and
+	// random bytes.
+	mod = instantiate(replay.WithReplay(testCtx, bytes.NewReader(trace.Bytes())), wazero.NewModuleConfig())
+	require.Equal(t, recorded, run(mod))
+	require.NoError(t, mod.Close(testCtx))
+
+	t.Run("diverges", func(t *testing.T) {
+		mod := instantiate(replay.WithReplay(testCtx, bytes.NewReader(trace.Bytes())), wazero.NewModuleConfig())
+
+		// The guest reads random bytes instead of the clock.
+		err := call(mod, wasip1.RandomGetName, 8, 16)
+		var divergence *replay.DivergenceError
+		require.True(t, errors.As(err, &divergence))
+		require.Equal(t, 2, divergence.Index)
+		require.Equal(t, "nanotime", divergence.Expected.Kind)
+		require.Equal(t, replay.Event{Kind: "random", Size: 16}, divergence.Actual)
+		require.Contains(t, err.Error(), "replay diverged at event 2: trace has nanotime, but guest requested random size=16")
+	})
+
+	t.Run("trace ended", func(t *testing.T) {
+		mod := instantiate(replay.WithReplay(testCtx, bytes.NewReader(trace.Bytes())), wazero.NewModuleConfig())
+		run(mod)
+
+		err := call(mod, wasip1.ClockTimeGetName, wasip1.ClockIDMonotonic, 0, 0)
+		var divergence *replay.DivergenceError
+		require.True(t, errors.As(err, &divergence))
+		require.Nil(t, divergence.Expected)
+	})
+}
@@ -41,10 +41,18 @@ const (
 	ENOTSUP
 	EPERM
 	EROFS
+	EXDEV
+	EDQUOT
+	EMFILE
+	ENOSPC
 
-	// NOTE ENOTCAPABLE is defined in wasip1, but not in POSIX. wasi-libc
+	// ENOTCAPABLE is defined in wasip1, but not in POSIX. It is only returned
+	// when WASI rights are enforced, such as by a sysfs.RightsFS. wasi-libc
 	// converts it to EBADF, ESPIPE or EINVAL depending on the call site.
-	// It isn't known if compilers who don't use ENOTCAPABLE would crash on it.
+	ENOTCAPABLE
+
+	// EPIPE is returned when writing to a pipe whose read end is closed.
+	EPIPE
 )
 
 // Error implements error
@@ -92,6 +100,18 @@ func (e Errno) Error() string {
 		return "operation not permitted"
 	case EROFS:
 		return "read-only file system"
+	case EXDEV:
+		return "cross-device link"
+	case EDQUOT:
+		return "disk quota exceeded"
+	case EMFILE:
+		return "too many open files"
+	case ENOSPC:
+		return "no space left on device"
+	case ENOTCAPABLE:
+		return "capabilities insufficient"
+	case EPIPE:
+		return "broken pipe"
 	default:
 		return "Errno(" + strconv.Itoa(int(e)) + ")"
 	}
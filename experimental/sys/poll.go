@@ -17,6 +17,10 @@ const (
 
 	// POLLOUT is a write event.
 	POLLOUT
+
+	// POLLHUP is set in results when the peer hung up, such as the write end
+	// of a pipe closing. It is ignored when waiting for events.
+	POLLHUP
 )
 
 // Pollable is implemented by custom readers that support polling for
@@ -46,3 +50,17 @@ const (
 type Pollable interface {
 	Poll(flag Pflag, timeoutMillis int32) (ready bool, errno Errno)
 }
+
+// PollNotifier is implemented by virtual files, such as in-memory pipes,
+// which have no host file descriptor. This allows poll_oneoff to wait on them
+// at the same time as other files, instead of polling each in turn.
+type PollNotifier interface {
+	Pollable
+
+	// PollNotify returns a channel which is closed when any event in flag is
+	// ready, or is already closed if one is ready now.
+	//
+	// A nil channel means the file can't notify, for example when it wraps
+	// another file, so the caller falls back to Pollable.Poll.
+	PollNotify(flag Pflag) <-chan struct{}
+}
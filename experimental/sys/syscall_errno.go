@@ -52,6 +52,16 @@ func syscallToErrno(err error) (Errno, bool) {
 		return EPERM, true
 	case syscall.EROFS:
 		return EROFS, true
+	case syscall.EXDEV:
+		return EXDEV, true
+	case syscall.EDQUOT:
+		return EDQUOT, true
+	case syscall.EMFILE:
+		return EMFILE, true
+	case syscall.ENOSPC:
+		return ENOSPC, true
+	case syscall.EPIPE:
+		return EPIPE, true
 	default:
 		return EIO, true
 	}
@@ -100,6 +110,18 @@ func (e Errno) Unwrap() error {
 		return syscall.EPERM
 	case EROFS:
 		return syscall.EROFS
+	case EXDEV:
+		return syscall.EXDEV
+	case EDQUOT:
+		return syscall.EDQUOT
+	case EMFILE:
+		return syscall.EMFILE
+	case ENOSPC:
+		return syscall.ENOSPC
+	case ENOTCAPABLE:
+		return syscall.EPERM // There's no POSIX equivalent.
+	case EPIPE:
+		return syscall.EPIPE
 	default:
 		return syscall.EIO
 	}
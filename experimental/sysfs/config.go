@@ -13,4 +13,14 @@ type FSConfig interface {
 	//
 	// This is an alternative to WithFSMount, allowing more features.
 	WithSysFSMount(fs experimentalsys.FS, guestPath string) wazero.FSConfig
+
+	// WithMaxOpenFiles limits the count of file descriptors a module can have
+	// open at the same time, after which opening a file or accepting a
+	// connection fails with sys.EMFILE. Zero, the default, means no limit.
+	//
+	// All descriptors count, including stdio, pre-opens, sockets, and files
+	// of any mount. For example, with one mount, 4 are open before the guest
+	// opens a file. To limit only the files opened via a mount, see
+	// Quota.MaxOpenFilesInFS.
+	WithMaxOpenFiles(max int) wazero.FSConfig
 }
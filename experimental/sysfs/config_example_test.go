@@ -1,7 +1,10 @@
 package sysfs_test
 
 import (
+	"fmt"
 	"io/fs"
+	"log"
+	"os"
 	"testing/fstest"
 
 	"github.com/tetratelabs/wazero"
@@ -34,6 +37,66 @@ func ExampleDirFS() {
 	// Output:
 }
 
+// This example shows how to configure a sysfs.ConfinedDirFS
+func ExampleConfinedDirFS() {
+	root := sysfs.ConfinedDirFS(".")
+
+	moduleConfig = wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(root, "/"))
+
+	// Output:
+}
+
+// This example shows how to configure a sysfs.NewMemFS
+func ExampleNewMemFS() {
+	root := sysfs.NewMemFS()
+
+	moduleConfig = wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(root, "/tmp"))
+
+	// Output:
+}
+
+// This example shows how to configure a sysfs.NewOverlayFS
+func ExampleNewOverlayFS() {
+	base := &sysfs.AdaptFS{FS: fstest.MapFS{
+		"etc/motd": &fstest.MapFile{Data: []byte("hello\n"), Mode: 0o644},
+	}}
+	root := sysfs.NewOverlayFS(sysfs.NewMemFS(), base)
+
+	moduleConfig = wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(root, "/"))
+
+	// After the guest runs, inspect what it changed.
+	changes, _ := root.Changes()
+	fmt.Println(len(changes))
+
+	// Output:
+	// 0
+}
+
+// This example shows how to limit what a guest can write with a
+// sysfs.QuotaFS
+func ExampleNewQuotaFS() {
+	root := sysfs.NewQuotaFS(sysfs.NewMemFS(), sysfs.Quota{
+		MaxBytesWritten:  1 << 20,
+		MaxFiles:         100,
+		MaxOpenFilesInFS: 10,
+	})
+
+	// Limit the file descriptors of the module, too, including stdio.
+	fsConfig := wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(root, "/")
+	fsConfig = fsConfig.(sysfs.FSConfig).WithMaxOpenFiles(32)
+
+	moduleConfig = wazero.NewModuleConfig().WithFSConfig(fsConfig)
+
+	// After the guest runs, inspect what it used.
+	fmt.Println(root.Usage().BytesWritten)
+
+	// Output:
+	// 0
+}
+
 // This example shows how to configure a sysfs.ReadFS
 func ExampleReadFS() {
 	root := sysfs.DirFS(".")
@@ -44,3 +107,87 @@ func ExampleReadFS() {
 
 	// Output:
 }
+
+// This example shows how to configure a sysfs.NewRightsFS, to only allow
+// reading the files of a writable directory.
+func ExampleNewRightsFS() {
+	root := sysfs.NewRightsFS(sysfs.DirFS("."), sysfs.RightsReadOnly, sysfs.RightsReadOnly)
+
+	moduleConfig = wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(root, "/"))
+
+	// Output:
+}
+
+// This example shows how to configure a sysfs.NewPolicyFS, so that the guest
+// can read JSON files under "data", but only create files under "tmp".
+func ExampleNewPolicyFS() {
+	root := sysfs.NewPolicyFS(sysfs.DirFS("."), sysfs.GlobPolicy(
+		sysfs.PolicyRule{Pattern: ".", Access: sysfs.AccessRead},
+		sysfs.PolicyRule{Pattern: "data", Access: sysfs.AccessRead},
+		sysfs.PolicyRule{Pattern: "data/*.json", Access: sysfs.AccessRead},
+		sysfs.PolicyRule{Pattern: "tmp/**", Access: sysfs.AccessRead | sysfs.AccessWrite | sysfs.AccessCreate | sysfs.AccessDelete},
+	))
+
+	moduleConfig = wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(root, "/"))
+
+	// Output:
+}
+
+// This example shows how to configure a sysfs.NewTarFS, to mount an archive
+// of assets without unpacking it.
+func ExampleNewTarFS() {
+	f, err := os.Open("assets.tar")
+	if err != nil {
+		return // the example has no archive
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		log.Panicln(err)
+	}
+	root, err := sysfs.NewTarFS(f, st.Size())
+	if err != nil {
+		log.Panicln(err)
+	}
+
+	moduleConfig = wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(root, "/assets"))
+
+	// Output:
+}
+
+// This example shows how to configure stdin and stdout as sysfs.NewPipe, so
+// that the guest can poll them.
+func ExampleNewPipe() {
+	stdin, hostStdin := sysfs.NewPipe()
+	hostStdout, stdout := sysfs.NewPipe()
+
+	moduleConfig = wazero.NewModuleConfig().
+		WithStdin(&sysfs.FileIO{File: stdin}).
+		WithStdout(&sysfs.FileIO{File: stdout})
+
+	// The host writes to the guest, then closes its end so the guest reads EOF.
+	_, _ = hostStdin.Write([]byte("hello\n"))
+	_ = hostStdin.Close()
+
+	// ... instantiate the guest, and read its output via hostStdout
+	_ = hostStdout.Close()
+
+	// Output:
+}
+
+// This example shows how to configure stdio as a sysfs.NewPTY, so that the
+// guest sees a terminal.
+func ExampleNewPTY() {
+	host, guest := sysfs.NewPTY()
+	defer host.Close()
+
+	tty := &sysfs.FileIO{File: guest}
+	moduleConfig = wazero.NewModuleConfig().
+		WithStdin(tty).WithStdout(tty).WithStderr(tty)
+
+	// Output:
+}
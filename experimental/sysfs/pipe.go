@@ -0,0 +1,58 @@
+package sysfs
+
+import (
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+)
+
+// NewPipe returns the read and write ends of an in-memory pipe, like
+// os.Pipe. Pass one end to the guest as stdio via FileIO, and use the other
+// from the host.
+//
+// Unlike an io.Reader or io.Writer passed to ModuleConfig.WithStdin or
+// WithStdout, the guest can poll either end with poll_oneoff, and use it in
+// non-blocking mode via fd_fdstat_set_flags.
+//
+// Reads block until data is written, and return EOF once the write end is
+// closed. Writes block while the pipe is full, and fail with sys.EPIPE once
+// the read end is closed. poll_oneoff reports a hang up once the other end is
+// closed. The guest closes its end when the module closes, so the host sees
+// EOF after the guest exits.
+//
+// # Notes
+//
+//   - This is an experimental API and subject to change.
+//   - Each end is closed once, when all handles of it are closed. When the
+//     same end is configured for stdout and stderr, each stream has its own
+//     handle, like dup in POSIX.
+func NewPipe() (r, w experimentalsys.File) {
+	return sysfs.NewPipe()
+}
+
+// NewPTY returns the ends of an in-memory pseudo-terminal. Pass guest via
+// FileIO to any of ModuleConfig.WithStdin, WithStdout and WithStderr, and use
+// host to write what the user types and read what the guest prints.
+//
+// Both ends are character devices which can't seek, so fd_fdstat_get
+// reports a terminal to the guest, and isatty returns true. Otherwise, each
+// direction behaves like NewPipe.
+//
+// # Notes
+//
+//   - This is an experimental API and subject to change.
+//   - There is no line discipline: input isn't echoed or buffered by line,
+//     which is left to the host end, or to the guest in raw mode.
+//   - To present a host terminal instead, pass its *os.File, such as
+//     os.Stdin or the replica of a host pseudo-terminal, which is already a
+//     character device.
+func NewPTY() (host, guest experimentalsys.File) {
+	return sysfs.NewPTY()
+}
+
+// FileIO adapts a sys.File, such as an end of NewPipe, to io.Reader,
+// io.Writer and io.Closer. Read returns io.EOF when the file returns zero
+// bytes.
+//
+// When passed to ModuleConfig.WithStdin, WithStdout or WithStderr, the guest
+// uses File as-is, and closes it when the module is closed.
+type FileIO = sysfs.FileIO
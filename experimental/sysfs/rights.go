@@ -0,0 +1,78 @@
+package sysfs
+
+import (
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+)
+
+// NewRightsFS returns a sys.FS which makes WASI enforce rights on the
+// descriptors of fs, returning sys.ENOTCAPABLE when one is missing. For
+// example, this allows read-only descriptors inside a writable mount.
+//
+// base are the rights of the pre-opened directory, and inheriting are the
+// maximum rights of the files and directories opened from it. path_open
+// limits the rights the guest asks for to the inheriting rights of the
+// directory, and fd_fdstat_set_rights can remove rights, but not add them.
+//
+// # Notes
+//
+//   - This is an experimental API and subject to change.
+//   - Rights are only enforced by WASI host functions, not by fs.
+//   - The result can be wrapped by ReadFS, NewQuotaFS or NewPolicyFS before
+//     it is mounted. Other wrappers, such as NewOverlayFS, hide the rights.
+//   - Rights were removed from later versions of WASI, so guests may not
+//     handle sys.ENOTCAPABLE well.
+func NewRightsFS(fs experimentalsys.FS, base, inheriting Rights) *RightsFS {
+	return sysfs.NewRightsFS(fs, uint32(base), uint32(inheriting))
+}
+
+// RightsFS is the sys.FS returned by NewRightsFS.
+type RightsFS = sysfs.RightsFS
+
+// Rights are WASI rights, which are combined with bitwise OR.
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-rights-flagsu64
+type Rights uint64
+
+// The WASI rights, named after the functions they allow.
+const (
+	RightFdDatasync           = Rights(wasip1.RIGHT_FD_DATASYNC)
+	RightFdRead               = Rights(wasip1.RIGHT_FD_READ)
+	RightFdSeek               = Rights(wasip1.RIGHT_FD_SEEK)
+	RightFdFdstatSetFlags     = Rights(wasip1.RIGHT_FDSTAT_SET_FLAGS)
+	RightFdSync               = Rights(wasip1.RIGHT_FD_SYNC)
+	RightFdTell               = Rights(wasip1.RIGHT_FD_TELL)
+	RightFdWrite              = Rights(wasip1.RIGHT_FD_WRITE)
+	RightFdAdvise             = Rights(wasip1.RIGHT_FD_ADVISE)
+	RightFdAllocate           = Rights(wasip1.RIGHT_FD_ALLOCATE)
+	RightPathCreateDirectory  = Rights(wasip1.RIGHT_PATH_CREATE_DIRECTORY)
+	RightPathCreateFile       = Rights(wasip1.RIGHT_PATH_CREATE_FILE)
+	RightPathLinkSource       = Rights(wasip1.RIGHT_PATH_LINK_SOURCE)
+	RightPathLinkTarget       = Rights(wasip1.RIGHT_PATH_LINK_TARGET)
+	RightPathOpen             = Rights(wasip1.RIGHT_PATH_OPEN)
+	RightFdReaddir            = Rights(wasip1.RIGHT_FD_READDIR)
+	RightPathReadlink         = Rights(wasip1.RIGHT_PATH_READLINK)
+	RightPathRenameSource     = Rights(wasip1.RIGHT_PATH_RENAME_SOURCE)
+	RightPathRenameTarget     = Rights(wasip1.RIGHT_PATH_RENAME_TARGET)
+	RightPathFilestatGet      = Rights(wasip1.RIGHT_PATH_FILESTAT_GET)
+	RightPathFilestatSetSize  = Rights(wasip1.RIGHT_PATH_FILESTAT_SET_SIZE)
+	RightPathFilestatSetTimes = Rights(wasip1.RIGHT_PATH_FILESTAT_SET_TIMES)
+	RightFdFilestatGet        = Rights(wasip1.RIGHT_FD_FILESTAT_GET)
+	RightFdFilestatSetSize    = Rights(wasip1.RIGHT_FD_FILESTAT_SET_SIZE)
+	RightFdFilestatSetTimes   = Rights(wasip1.RIGHT_FD_FILESTAT_SET_TIMES)
+	RightPathSymlink          = Rights(wasip1.RIGHT_PATH_SYMLINK)
+	RightPathRemoveDirectory  = Rights(wasip1.RIGHT_PATH_REMOVE_DIRECTORY)
+	RightPathUnlinkFile       = Rights(wasip1.RIGHT_PATH_UNLINK_FILE)
+	RightPollFdReadwrite      = Rights(wasip1.RIGHT_POLL_FD_READWRITE)
+	RightSockShutdown         = Rights(wasip1.RIGHT_SOCK_SHUTDOWN)
+
+	// RightsAll are all the rights.
+	RightsAll = RightSockShutdown<<1 - 1
+
+	// RightsReadOnly are the rights to read files and directories, without
+	// changing them.
+	RightsReadOnly = RightFdRead | RightFdSeek | RightFdTell | RightFdAdvise |
+		RightPathOpen | RightFdReaddir | RightPathReadlink |
+		RightPathFilestatGet | RightFdFilestatGet | RightPollFdReadwrite
+)
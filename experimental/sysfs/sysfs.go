@@ -8,6 +8,8 @@
 package sysfs
 
 import (
+	"io"
+
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/sysfs"
 )
@@ -26,6 +28,171 @@ func DirFS(dir string) experimentalsys.FS {
 	return sysfs.DirFS(dir)
 }
 
+// ConfinedDirFS is like DirFS, except the guest can't escape dir via relative
+// path lookups like "../../" or symbolic links. Paths which would escape
+// return sys.EPERM.
+//
+// On Linux 5.6+, each path is resolved by the kernel relative to dir, using
+// openat2 with RESOLVE_BENEATH. On other platforms, each path component is
+// resolved in Go, which doesn't guard against concurrent changes to dir on the
+// host, such as a directory replaced by a symbolic link while in use.
+//
+// The result implements io.Closer, to release the file descriptor of dir held
+// on Linux once no module uses it. Otherwise, it is released when the result
+// is garbage collected.
+func ConfinedDirFS(dir string) experimentalsys.FS {
+	return sysfs.ConfinedDirFS(dir)
+}
+
+// NewMemFS returns a writable sys.FS held in memory, which starts with an
+// empty root directory. Nothing is read from or written to the host, so this
+// is useful for scratch space, or to isolate a guest from the host.
+//
+// This supports regular files, directories, symbolic links and hard links.
+// Permission bits are stored, but not enforced, like for a process running
+// as root. Absolute symbolic links resolve from the root of the FS.
+func NewMemFS() experimentalsys.FS {
+	return sysfs.NewMemFS()
+}
+
+// NewTarFS returns a read-only sys.FS backed by the tar archive in r, which
+// is size bytes, for example an *os.File of guest assets, instead of
+// unpacking it to a directory.
+//
+// The archive is indexed once, reading only the headers, then file contents
+// are read from r as the guest reads them. Modes, timestamps, symbolic
+// links and hard links are preserved, and inode numbers are stable for the
+// same archive. Compressed archives, such as .tar.gz, must be decompressed
+// first, as they can't be read at an offset.
+func NewTarFS(r io.ReaderAt, size int64) (experimentalsys.FS, error) {
+	return sysfs.NewTarFS(r, size)
+}
+
+// NewZipFS returns a read-only sys.FS backed by the zip archive in r, which
+// is size bytes, like NewTarFS.
+//
+// Files stored without compression are read at an offset of r. Compressed
+// files are decompressed as they are read, so reading them out of order is
+// slower.
+func NewZipFS(r io.ReaderAt, size int64) (experimentalsys.FS, error) {
+	return sysfs.NewZipFS(r, size)
+}
+
+// NewOverlayFS returns a copy-on-write sys.FS, which presents the read-only
+// lowers, higher priority first, with changes written to upper instead, like
+// Linux overlayfs. For example, upper can be NewMemFS and the lower an
+// AdaptFS of an embed.FS.
+//
+// After the guest runs, OverlayFS.Changes returns what it changed, and
+// OverlayFS.Discard removes those changes from upper. See OverlayFS for
+// details, such as how deleted files are recorded in upper.
+func NewOverlayFS(upper experimentalsys.FS, lowers ...experimentalsys.FS) *OverlayFS {
+	return sysfs.NewOverlayFS(upper, lowers...)
+}
+
+// OverlayFS is the sys.FS returned by NewOverlayFS.
+type OverlayFS = sysfs.OverlayFS
+
+// OverlayChange is a change returned by OverlayFS.Changes.
+type OverlayChange = sysfs.OverlayChange
+
+// ChangeKind is the kind of an OverlayChange.
+type ChangeKind = sysfs.ChangeKind
+
+const (
+	// ChangeAdded is a file which doesn't exist in a lower layer.
+	ChangeAdded = sysfs.ChangeAdded
+	// ChangeModified is a file which replaces one in a lower layer.
+	ChangeModified = sysfs.ChangeModified
+	// ChangeDeleted is a file deleted from a lower layer.
+	ChangeDeleted = sysfs.ChangeDeleted
+)
+
+// NewQuotaFS returns a sys.FS which limits what the guest can do to fs, such
+// as the total bytes written, returning sys.ENOSPC, sys.EDQUOT or sys.EMFILE
+// when a limit is reached. QuotaFS.Usage returns what was used so far.
+func NewQuotaFS(fs experimentalsys.FS, quota Quota) *QuotaFS {
+	return sysfs.NewQuotaFS(fs, quota)
+}
+
+// QuotaFS is the sys.FS returned by NewQuotaFS.
+type QuotaFS = sysfs.QuotaFS
+
+// Quota are the limits of a QuotaFS. A zero field means no limit.
+type Quota = sysfs.Quota
+
+// QuotaUsage is the usage returned by QuotaFS.Usage.
+type QuotaUsage = sysfs.QuotaUsage
+
+// NewPolicyFS returns a sys.FS which asks policy before each operation which
+// opens or changes a path of fs: OpenFile, Mkdir, Rmdir, Rename, Unlink,
+// Link, Symlink, Chmod and Utimens. When the policy returns a non-zero
+// sys.Errno, the operation fails with it.
+//
+// GlobPolicy returns a Policy for common cases, such as allowing a guest to
+// read "data/*.json", but only create files under "tmp/**".
+//
+// Note: Paths are checked as given, without resolving symbolic links. See
+// PolicyFS for details.
+func NewPolicyFS(fs experimentalsys.FS, policy Policy) *PolicyFS {
+	return sysfs.NewPolicyFS(fs, policy)
+}
+
+// PolicyFS is the sys.FS returned by NewPolicyFS.
+type PolicyFS = sysfs.PolicyFS
+
+// Policy decides if an operation of a PolicyFS is allowed. See
+// sysfs.Policy for the meaning of its parameters.
+type Policy = sysfs.Policy
+
+// PolicyOp is an operation checked by a Policy.
+type PolicyOp = sysfs.PolicyOp
+
+// The operations checked by a Policy, named after the sys.FS methods.
+const (
+	PolicyOpOpen    = sysfs.PolicyOpOpen
+	PolicyOpMkdir   = sysfs.PolicyOpMkdir
+	PolicyOpRmdir   = sysfs.PolicyOpRmdir
+	PolicyOpRename  = sysfs.PolicyOpRename
+	PolicyOpUnlink  = sysfs.PolicyOpUnlink
+	PolicyOpLink    = sysfs.PolicyOpLink
+	PolicyOpSymlink = sysfs.PolicyOpSymlink
+	PolicyOpChmod   = sysfs.PolicyOpChmod
+	PolicyOpUtimens = sysfs.PolicyOpUtimens
+)
+
+// GlobPolicy returns a Policy which allows an operation if the first rule
+// whose pattern matches the path allows the access it needs. Otherwise, the
+// operation fails with sys.EACCES.
+//
+// Patterns are relative to the root of the PolicyFS, not to the root of the
+// guest: when it is mounted at "/mnt", match "data/*.json" for the guest path
+// "/mnt/data/a.json". Opening a directory, including the root ".", needs
+// AccessRead, so allow it for directories the guest lists or resolves paths
+// from.
+func GlobPolicy(rules ...PolicyRule) Policy {
+	return sysfs.GlobPolicy(rules...)
+}
+
+// PolicyRule is a rule of GlobPolicy.
+type PolicyRule = sysfs.PolicyRule
+
+// Access is what a PolicyRule allows.
+type Access = sysfs.Access
+
+const (
+	// AccessRead allows opening files and directories for reading.
+	AccessRead = sysfs.AccessRead
+	// AccessWrite allows opening files for writing, and changing their
+	// metadata.
+	AccessWrite = sysfs.AccessWrite
+	// AccessCreate allows creating files, directories and links.
+	AccessCreate = sysfs.AccessCreate
+	// AccessDelete allows removing or renaming files and directories, and
+	// renaming onto an existing path, which replaces it.
+	AccessDelete = sysfs.AccessDelete
+)
+
 // ReadFS is used to mask an existing sys.FS for reads. Notably, this allows
 // the CLI to do read-only mounts of directories the host user can write, but
 // doesn't want the guest wasm to. For example, Python libraries shouldn't be
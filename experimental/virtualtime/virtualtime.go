@@ -0,0 +1,104 @@
+// Package virtualtime provides a virtual clock for the clocks and sleeps of
+// guest modules, for example to simulate a program that runs for hours in a
+// test that completes in milliseconds.
+//
+// A Clock drives the walltime, nanotime and nanosleep of guests
+// instantiated with WithClock, consistently: time only passes when the clock
+// advances, and sleeps, including poll_oneoff timeouts, end when the clock
+// reaches their deadline instead of after real time elapses.
+//
+// Here's an example of running guests until they are all sleeping, then
+// advancing time:
+//
+//	clock := virtualtime.NewClock(time.Unix(0, 0))
+//	ctx = virtualtime.WithClock(ctx, clock)
+//	go r.InstantiateModule(ctx, compiled, config) // sleeps for an hour
+//
+//	clock.BlockUntil(1)
+//	clock.Advance(time.Hour)
+//
+// # Notes
+//
+//   - This is an experimental API and subject to change.
+//   - Only guest modules use the clock, not host modules. This overrides
+//     the clocks configured with wazero.ModuleConfig.
+//   - When a poll_oneoff timeout elapses in virtual time, ready file
+//     descriptors are still polled in real time.
+package virtualtime
+
+import (
+	"context"
+	"time"
+
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
+)
+
+// Clock is a virtual clock, which only advances when Advance is called, or
+// when all guests using it are sleeping, if auto-advance is enabled.
+//
+// A Clock is safe for concurrent use, and can be shared by many guests.
+type Clock struct {
+	c *internalsys.VirtualClock
+}
+
+// NewClock returns a clock which starts at the given time, and which only
+// advances when Advance is called.
+func NewClock(start time.Time) *Clock {
+	return &Clock{c: internalsys.NewVirtualClock(start)}
+}
+
+// SetAutoAdvance enables or disables advancing the clock to the earliest
+// deadline of a sleep, once all guests using the clock are sleeping.
+//
+// A guest uses the clock from instantiation until it is closed. A guest
+// which is blocked on something other than the clock, such as reading
+// stdin, prevents the clock from advancing automatically. So does a guest
+// which is idle after its function returned, so close guests when done.
+func (c *Clock) SetAutoAdvance(autoAdvance bool) {
+	c.c.SetAutoAdvance(autoAdvance)
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	return time.Unix(0, c.c.Now())
+}
+
+// Advance advances the clock by d, ending any sleeps whose deadline is
+// reached.
+func (c *Clock) Advance(d time.Duration) {
+	c.c.Advance(d)
+}
+
+// Sleepers returns the count of sleeps in progress.
+func (c *Clock) Sleepers() int {
+	return c.c.Sleepers()
+}
+
+// BlockUntil blocks until at least n sleeps are in progress. This allows a
+// test to advance the clock only after guests started sleeping.
+func (c *Clock) BlockUntil(n int) {
+	c.c.BlockUntil(n)
+}
+
+// Walltime implements sys.Walltime, returning the time of the clock.
+func (c *Clock) Walltime() (sec int64, nsec int32) {
+	return c.c.Walltime()
+}
+
+// Nanotime implements sys.Nanotime, returning the time elapsed since the
+// clock was created.
+func (c *Clock) Nanotime() int64 {
+	return c.c.Nanotime()
+}
+
+// Nanosleep implements sys.Nanosleep, blocking until the clock advances by
+// ns.
+func (c *Clock) Nanosleep(ns int64) {
+	c.c.Nanosleep(ns)
+}
+
+// WithClock returns a context which makes guest modules instantiated with
+// it use the clock.
+func WithClock(ctx context.Context, clock *Clock) context.Context {
+	return context.WithValue(ctx, internalsys.VirtualClockKey{}, clock.c)
+}
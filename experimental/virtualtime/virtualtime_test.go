@@ -0,0 +1,146 @@
+package virtualtime_test
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/virtualtime"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/internal/testing/proxy"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+)
+
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
+var start = time.Unix(1640995200, 0) // 2022-01-01
+
+func TestClock(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	wasiCompiled, err := wasi_snapshot_preview1.NewBuilder(r).Compile(testCtx)
+	require.NoError(t, err)
+	_, err = r.InstantiateModule(testCtx, wasiCompiled, wazero.NewModuleConfig())
+	require.NoError(t, err)
+	proxyCompiled, err := r.CompileModule(testCtx, proxy.NewModuleBinary(wasi_snapshot_preview1.ModuleName, wasiCompiled))
+	require.NoError(t, err)
+
+	instantiate := func(t *testing.T, clock *virtualtime.Clock, config wazero.ModuleConfig) api.Module {
+		// The clocks of the config are overridden.
+		config = config.WithName("").WithSysWalltime().WithSysNanotime().WithSysNanosleep()
+		mod, err := r.InstantiateModule(virtualtime.WithClock(testCtx, clock), proxyCompiled, config)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = mod.Close(testCtx) })
+		return mod
+	}
+
+	now := func(t *testing.T, mod api.Module, id uint64) time.Duration {
+		_, err := mod.ExportedFunction(wasip1.ClockTimeGetName).Call(testCtx, id, 0, 0)
+		require.NoError(t, err)
+		ns, ok := mod.Memory().ReadUint64Le(0)
+		require.True(t, ok)
+		return time.Duration(ns)
+	}
+
+	// poll calls poll_oneoff with a relative timeout, and optionally a
+	// subscription to read fd. This returns the count of events.
+	poll := func(t *testing.T, mod api.Module, timeout time.Duration, fd int32) uint32 {
+		in := make([]byte, 96)
+		in[8] = wasip1.EventTypeClock
+		binary.LittleEndian.PutUint32(in[16:], wasip1.ClockIDMonotonic)
+		binary.LittleEndian.PutUint64(in[24:], uint64(timeout))
+		nsubscriptions := uint64(1)
+		if fd >= 0 {
+			in[48+8] = wasip1.EventTypeFdRead
+			binary.LittleEndian.PutUint32(in[48+16:], uint32(fd))
+			nsubscriptions++
+		}
+		require.True(t, mod.Memory().Write(64, in))
+		_, err := mod.ExportedFunction(wasip1.PollOneoffName).Call(testCtx, 64, 256, nsubscriptions, 8)
+		require.NoError(t, err)
+		nevents, ok := mod.Memory().ReadUint32Le(8)
+		require.True(t, ok)
+		return nevents
+	}
+
+	t.Run("Advance", func(t *testing.T) {
+		clock := virtualtime.NewClock(start)
+		mod := instantiate(t, clock, wazero.NewModuleConfig())
+		require.Equal(t, time.Duration(0), now(t, mod, wasip1.ClockIDMonotonic))
+		require.Equal(t, time.Duration(start.UnixNano()), now(t, mod, wasip1.ClockIDRealtime))
+
+		slept := make(chan struct{})
+		go func() {
+			defer close(slept)
+			poll(t, mod, time.Hour, -1)
+		}()
+
+		clock.BlockUntil(1)
+		clock.Advance(time.Minute)
+		require.Equal(t, 1, clock.Sleepers())
+
+		clock.Advance(59 * time.Minute)
+		<-slept
+		require.Equal(t, time.Hour, now(t, mod, wasip1.ClockIDMonotonic))
+		require.Equal(t, start.Add(time.Hour), clock.Now())
+	})
+
+	t.Run("SetAutoAdvance", func(t *testing.T) {
+		clock := virtualtime.NewClock(start)
+		clock.SetAutoAdvance(true)
+		mod1 := instantiate(t, clock, wazero.NewModuleConfig())
+		mod2 := instantiate(t, clock, wazero.NewModuleConfig())
+
+		// The clock doesn't advance until both guests are sleeping.
+		slept1, slept2 := make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(slept1)
+			poll(t, mod1, time.Hour, -1)
+		}()
+		clock.BlockUntil(1)
+		require.Equal(t, start, clock.Now())
+
+		go func() {
+			defer close(slept2)
+			poll(t, mod2, 2*time.Hour, -1)
+		}()
+		<-slept1
+		require.Equal(t, start.Add(time.Hour), clock.Now())
+
+		// The first guest is no longer sleeping, so the clock doesn't
+		// advance until it is closed.
+		require.Equal(t, 1, clock.Sleepers())
+		require.NoError(t, mod1.Close(testCtx))
+		<-slept2
+		require.Equal(t, start.Add(2*time.Hour), clock.Now())
+	})
+
+	t.Run("poll_oneoff with fd", func(t *testing.T) {
+		stdin, w, err := os.Pipe()
+		require.NoError(t, err)
+		defer stdin.Close()
+		defer w.Close()
+
+		clock := virtualtime.NewClock(start)
+		clock.SetAutoAdvance(true)
+		mod := instantiate(t, clock, wazero.NewModuleConfig().WithStdin(stdin))
+
+		// stdin isn't ready, so the timeout elapses in virtual time.
+		require.Equal(t, uint32(1), poll(t, mod, time.Hour, 0))
+		require.Equal(t, start.Add(time.Hour), clock.Now())
+
+		// Once stdin is ready, the clock doesn't advance, so only the stdin
+		// event is written.
+		_, err = w.Write([]byte("wazero"))
+		require.NoError(t, err)
+		require.Equal(t, uint32(1), poll(t, mod, time.Hour, 0))
+		require.Equal(t, start.Add(time.Hour), clock.Now())
+	})
+}
@@ -0,0 +1,261 @@
+package wasmbinary
+
+import (
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ValueType is the type of a value, such as a parameter, local or global.
+//
+// Numeric types and abstract reference types have the same value as their
+// binary encoding, as in api.ValueType. Other reference types are created
+// with NewRefType or ValueType.NonNullable.
+type ValueType uint64
+
+const (
+	ValueTypeI32       = ValueType(wasm.ValueTypeI32)
+	ValueTypeI64       = ValueType(wasm.ValueTypeI64)
+	ValueTypeF32       = ValueType(wasm.ValueTypeF32)
+	ValueTypeF64       = ValueType(wasm.ValueTypeF64)
+	ValueTypeV128      = ValueType(wasm.ValueTypeV128)
+	ValueTypeFuncref   = ValueType(wasm.ValueTypeFuncref)
+	ValueTypeExternref = ValueType(wasm.ValueTypeExternref)
+	ValueTypeExnref    = ValueType(wasm.ValueTypeExnref)
+)
+
+// NewRefType returns the reference type of the function type at typeIndex,
+// defined by the function-references proposal.
+func NewRefType(typeIndex uint32, nullable bool) ValueType {
+	return ValueType(wasm.ValueTypeConcreteRef(typeIndex, nullable))
+}
+
+// NonNullable returns the non-nullable variant of a reference type.
+func (v ValueType) NonNullable() ValueType {
+	return ValueType(wasm.ValueType(v).AsNonNullable())
+}
+
+// String implements fmt.Stringer, returning the name of the type in the
+// WebAssembly text format, such as "i32" or "(ref null 1)".
+func (v ValueType) String() string {
+	return wasm.ValueTypeName(wasm.ValueType(v))
+}
+
+// ExternTypeTag is the api.ExternType of a tag, defined by the
+// exception-handling proposal.
+const ExternTypeTag api.ExternType = wasm.ExternTypeTag
+
+// Module is a decoded WebAssembly module.
+//
+// Fields are named after the sections they are decoded from, and the index
+// of an entry in a section is its index in the module, after any imports of
+// the same kind. For example, if a module imports two functions, the first
+// entry of Functions is function index 2.
+//
+// Instructions and constant expressions, such as Global.Init, are kept in
+// their binary encoding, including the trailing end opcode (0x0b).
+type Module struct {
+	// Types are the function types of the module.
+	Types []FunctionType
+
+	// Imports are the functions, tables, memories, globals and tags imported
+	// by the module.
+	Imports []Import
+
+	// Functions are the type indexes of the functions defined by the module,
+	// index-correlated with Code.
+	Functions []uint32
+
+	// Tables are the tables defined by the module.
+	Tables []Table
+
+	// Memory is the memory defined by the module, or nil if there isn't one.
+	Memory *Memory
+
+	// Tags are the type indexes of the tags defined by the module.
+	Tags []uint32
+
+	// Globals are the globals defined by the module.
+	Globals []Global
+
+	// Exports are the exports of the module, in their order of definition.
+	Exports []Export
+
+	// Start is the index of the function called on instantiation, or nil.
+	Start *uint32
+
+	// Elements are the element segments of the module.
+	Elements []ElementSegment
+
+	// DataCount is the number of data segments, which must be set if code
+	// uses memory.init or data.drop, or nil.
+	DataCount *uint32
+
+	// Code are the bodies of the functions defined by the module,
+	// index-correlated with Functions.
+	Code []Code
+
+	// Data are the data segments of the module.
+	Data []DataSegment
+
+	// Names are the contents of the "name" custom section, or nil.
+	Names *Names
+
+	// CustomSections are the custom sections other than "name". They are
+	// encoded after all other sections, so their original position isn't
+	// retained.
+	CustomSections []CustomSection
+}
+
+// FunctionType is the signature of a function.
+type FunctionType struct {
+	Params, Results []ValueType
+
+	// RecGroupSize is the number of types in the recursion group of this
+	// type, defined by the GC proposal, or zero or one if not in a group.
+	// Types of a group are consecutive in Module.Types.
+	RecGroupSize int
+
+	// RecGroupPosition is the position of this type in its recursion group.
+	RecGroupPosition int
+}
+
+// Import is an import of a module. The field corresponding to Type is set.
+type Import struct {
+	Type api.ExternType
+
+	// Module and Name are the names this is imported by.
+	Module, Name string
+
+	// FuncType is the type index of an imported function.
+	FuncType uint32
+
+	// Table is the type of an imported table.
+	Table Table
+
+	// Memory is the type of an imported memory.
+	Memory Memory
+
+	// Global is the type of an imported global.
+	Global GlobalType
+
+	// TagType is the type index of an imported tag.
+	TagType uint32
+}
+
+// Table is a table of references.
+type Table struct {
+	Type ValueType
+	Min  uint32
+	// Max is the maximum number of elements, or nil if unbounded.
+	Max *uint32
+	// Init is the constant expression which initializes each element, or nil
+	// for null. Tables of a non-nullable type require one.
+	Init []byte
+}
+
+// Memory is a linear memory.
+type Memory struct {
+	// Min is the initial number of pages.
+	Min uint32
+	// Max is the maximum number of pages, or nil if unbounded.
+	Max *uint32
+	// Shared is true for a memory shared between threads.
+	Shared bool
+}
+
+// GlobalType is the type of a global.
+type GlobalType struct {
+	ValType ValueType
+	Mutable bool
+}
+
+// Global is a global defined by the module.
+type Global struct {
+	Type GlobalType
+	// Init is the constant expression which initializes the global.
+	Init []byte
+}
+
+// Export is an export of a module.
+type Export struct {
+	Type api.ExternType
+	Name string
+	// Index is the index of the exported item in the index space of Type.
+	Index uint32
+}
+
+// ElementMode is the mode of an ElementSegment.
+type ElementMode = wasm.ElementMode
+
+const (
+	// ElementModeActive segments initialize a table on instantiation.
+	ElementModeActive = wasm.ElementModeActive
+	// ElementModePassive segments are used by table.init.
+	ElementModePassive = wasm.ElementModePassive
+	// ElementModeDeclarative segments declare functions referenced by
+	// ref.func.
+	ElementModeDeclarative = wasm.ElementModeDeclarative
+)
+
+// ElementSegment is a vector of references.
+type ElementSegment struct {
+	Mode ElementMode
+	// Table is the index of the table initialized by an active segment.
+	Table uint32
+	// Offset is the constant expression of the offset in Table initialized
+	// by an active segment.
+	Offset []byte
+	// Type is the type of the references.
+	Type ValueType
+	// Init are the constant expressions of each reference.
+	Init [][]byte
+}
+
+// Code is the body of a function.
+type Code struct {
+	// Locals are the types of local variables, after the parameters.
+	Locals []ValueType
+	// Body are the instructions of the function, ending with the end opcode.
+	Body []byte
+}
+
+// DataSegment is a sequence of bytes.
+type DataSegment struct {
+	// Passive is true for segments used by memory.init, rather than
+	// initializing memory on instantiation.
+	Passive bool
+	// Offset is the constant expression of the offset in memory initialized
+	// by an active segment.
+	Offset []byte
+	Init   []byte
+}
+
+// Names are symbolic names of a module, used for debugging.
+type Names struct {
+	// Module is the name of the module.
+	Module string
+	// Functions are the names of functions, in ascending order of index.
+	Functions []NameAssoc
+	// Locals are the names of parameters and locals, in ascending order of
+	// function index.
+	Locals []IndirectNameAssoc
+}
+
+// NameAssoc associates an index with a name.
+type NameAssoc struct {
+	Index uint32
+	Name  string
+}
+
+// IndirectNameAssoc associates the index of a function with the names of
+// its parameters and locals.
+type IndirectNameAssoc struct {
+	Index uint32
+	Names []NameAssoc
+}
+
+// CustomSection is a custom section other than "name".
+type CustomSection struct {
+	Name string
+	Data []byte
+}
@@ -0,0 +1,336 @@
+// Package wasmbinary decodes WebAssembly binaries into a Module, which can be
+// edited and encoded back into a binary.
+//
+// This allows tools to rewrite modules before compiling them, for example to
+// strip custom sections or rename imports:
+//
+//	m, _ := wasmbinary.Decode(bin, api.CoreFeaturesV2)
+//	m.CustomSections = nil
+//	for i := range m.Imports {
+//		if m.Imports[i].Module == "env" {
+//			m.Imports[i].Module = "shim"
+//		}
+//	}
+//	bin = m.Encode()
+//
+// DecodeText parses modules in the WebAssembly text format instead, so that
+// tools can build modules from source, such as "(module (func (export \"f\")))".
+//
+// Adding or removing imports or definitions changes the index of items after
+// them, which this package doesn't rewrite in code or other sections.
+//
+// Decode, Encode and Validate use the same decoder, encoder and validation as
+// wazero.Runtime, so a module valid here compiles with the same features.
+package wasmbinary
+
+import (
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binary"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/wat"
+)
+
+// Decode decodes a WebAssembly binary, using the given features.
+//
+// The result isn't validated: decoding only fails on a malformed binary,
+// with the same error as wazero.Runtime CompileModule. Use Module.Validate
+// to check the rest.
+func Decode(bin []byte, features api.CoreFeatures) (*Module, error) {
+	m, err := binary.DecodeModule(bin, features, wasm.MemoryLimitPages, false, false, true)
+	if err != nil {
+		return nil, err
+	}
+	return fromInternal(m), nil
+}
+
+// DecodeText parses a module in the WebAssembly text format (.wat), such as
+// produced by wasm2wat. The module keyword can be omitted.
+//
+// Like Decode, the result isn't validated: parsing only fails on a syntax
+// error or an unknown identifier, with an error prefixed by its line and
+// column, such as "3:5: unknown local $x".
+func DecodeText(source []byte) (*Module, error) {
+	m, err := wat.ParseModule(source)
+	if err != nil {
+		return nil, err
+	}
+	return fromInternal(m), nil
+}
+
+// Encode encodes the module in the WebAssembly binary format.
+//
+// Encode doesn't validate the module, so the result may fail to compile.
+func (m *Module) Encode() []byte {
+	return binaryencoding.EncodeModule(m.toInternal())
+}
+
+// Validate returns the error wazero.Runtime CompileModule would return for
+// the encoded module, using the given features and the default memory limit,
+// or nil if it is valid.
+func (m *Module) Validate(features api.CoreFeatures) error {
+	internal, err := binary.DecodeModule(m.Encode(), features, wasm.MemoryLimitPages, false, false, false)
+	if err != nil {
+		return err
+	}
+	return internal.Validate(features)
+}
+
+func fromInternal(in *wasm.Module) *Module {
+	m := &Module{
+		Functions: in.FunctionSection,
+		Start:     in.StartSection,
+		DataCount: in.DataCountSection,
+	}
+
+	for i := range in.TypeSection {
+		t := &in.TypeSection[i]
+		m.Types = append(m.Types, FunctionType{
+			Params:           fromValueTypes(t.Params),
+			Results:          fromValueTypes(t.Results),
+			RecGroupSize:     t.RecGroupSize,
+			RecGroupPosition: t.RecGroupPosition,
+		})
+	}
+
+	for i := range in.ImportSection {
+		imp := &in.ImportSection[i]
+		o := Import{Type: imp.Type, Module: imp.Module, Name: imp.Name}
+		switch imp.Type {
+		case wasm.ExternTypeFunc:
+			o.FuncType = imp.DescFunc
+		case wasm.ExternTypeTable:
+			o.Table = fromTable(&imp.DescTable)
+		case wasm.ExternTypeMemory:
+			o.Memory = *fromMemory(imp.DescMem)
+		case wasm.ExternTypeGlobal:
+			o.Global = GlobalType{ValType: ValueType(imp.DescGlobal.ValType), Mutable: imp.DescGlobal.Mutable}
+		case wasm.ExternTypeTag:
+			o.TagType = imp.DescTag
+		}
+		m.Imports = append(m.Imports, o)
+	}
+
+	for i := range in.TableSection {
+		m.Tables = append(m.Tables, fromTable(&in.TableSection[i]))
+	}
+	if in.MemorySection != nil {
+		m.Memory = fromMemory(in.MemorySection)
+	}
+	for _, t := range in.TagSection {
+		m.Tags = append(m.Tags, t.Type)
+	}
+
+	for i := range in.GlobalSection {
+		g := &in.GlobalSection[i]
+		m.Globals = append(m.Globals, Global{
+			Type: GlobalType{ValType: ValueType(g.Type.ValType), Mutable: g.Type.Mutable},
+			Init: g.Init.Data,
+		})
+	}
+
+	for i := range in.ExportSection {
+		e := &in.ExportSection[i]
+		m.Exports = append(m.Exports, Export{Type: e.Type, Name: e.Name, Index: e.Index})
+	}
+
+	for i := range in.ElementSection {
+		e := &in.ElementSection[i]
+		o := ElementSegment{Mode: e.Mode, Table: e.TableIndex, Offset: e.OffsetExpr.Data, Type: ValueType(e.Type)}
+		for _, init := range e.Init {
+			o.Init = append(o.Init, init.Data)
+		}
+		m.Elements = append(m.Elements, o)
+	}
+
+	for i := range in.CodeSection {
+		c := &in.CodeSection[i]
+		m.Code = append(m.Code, Code{Locals: fromValueTypes(c.LocalTypes), Body: c.Body})
+	}
+
+	for i := range in.DataSection {
+		d := &in.DataSection[i]
+		m.Data = append(m.Data, DataSegment{Passive: d.Passive, Offset: d.OffsetExpression.Data, Init: d.Init})
+	}
+
+	if n := in.NameSection; n != nil {
+		m.Names = &Names{Module: n.ModuleName, Functions: fromNameMap(n.FunctionNames)}
+		for _, l := range n.LocalNames {
+			m.Names.Locals = append(m.Names.Locals, IndirectNameAssoc{Index: l.Index, Names: fromNameMap(l.NameMap)})
+		}
+	}
+
+	for _, c := range in.CustomSections {
+		m.CustomSections = append(m.CustomSections, CustomSection{Name: c.Name, Data: c.Data})
+	}
+	return m
+}
+
+func (m *Module) toInternal() *wasm.Module {
+	out := &wasm.Module{
+		FunctionSection:  m.Functions,
+		StartSection:     m.Start,
+		DataCountSection: m.DataCount,
+	}
+
+	for i := range m.Types {
+		t := &m.Types[i]
+		out.TypeSection = append(out.TypeSection, wasm.FunctionType{
+			Params:           toValueTypes(t.Params),
+			Results:          toValueTypes(t.Results),
+			RecGroupSize:     t.RecGroupSize,
+			RecGroupPosition: t.RecGroupPosition,
+		})
+	}
+
+	for i := range m.Imports {
+		imp := &m.Imports[i]
+		o := wasm.Import{Type: imp.Type, Module: imp.Module, Name: imp.Name}
+		switch imp.Type {
+		case wasm.ExternTypeFunc:
+			o.DescFunc = imp.FuncType
+		case wasm.ExternTypeTable:
+			o.DescTable = imp.Table.toInternal()
+		case wasm.ExternTypeMemory:
+			o.DescMem = imp.Memory.toInternal()
+		case wasm.ExternTypeGlobal:
+			o.DescGlobal = wasm.GlobalType{ValType: wasm.ValueType(imp.Global.ValType), Mutable: imp.Global.Mutable}
+		case wasm.ExternTypeTag:
+			o.DescTag = imp.TagType
+		}
+		out.ImportSection = append(out.ImportSection, o)
+	}
+
+	for i := range m.Tables {
+		out.TableSection = append(out.TableSection, m.Tables[i].toInternal())
+	}
+	if m.Memory != nil {
+		out.MemorySection = m.Memory.toInternal()
+	}
+	for _, t := range m.Tags {
+		out.TagSection = append(out.TagSection, wasm.Tag{Type: t})
+	}
+
+	for i := range m.Globals {
+		g := &m.Globals[i]
+		out.GlobalSection = append(out.GlobalSection, wasm.Global{
+			Type: wasm.GlobalType{ValType: wasm.ValueType(g.Type.ValType), Mutable: g.Type.Mutable},
+			Init: wasm.ConstantExpression{Data: g.Init},
+		})
+	}
+
+	for i := range m.Exports {
+		e := &m.Exports[i]
+		out.ExportSection = append(out.ExportSection, wasm.Export{Type: e.Type, Name: e.Name, Index: e.Index})
+	}
+
+	for i := range m.Elements {
+		e := &m.Elements[i]
+		o := wasm.ElementSegment{
+			Mode:       e.Mode,
+			TableIndex: e.Table,
+			OffsetExpr: wasm.ConstantExpression{Data: e.Offset},
+			Type:       wasm.RefType(e.Type),
+		}
+		for _, init := range e.Init {
+			o.Init = append(o.Init, wasm.ConstantExpression{Data: init})
+		}
+		out.ElementSection = append(out.ElementSection, o)
+	}
+
+	for i := range m.Code {
+		c := &m.Code[i]
+		out.CodeSection = append(out.CodeSection, wasm.Code{LocalTypes: toValueTypes(c.Locals), Body: c.Body})
+	}
+
+	for i := range m.Data {
+		d := &m.Data[i]
+		out.DataSection = append(out.DataSection, wasm.DataSegment{
+			Passive:          d.Passive,
+			OffsetExpression: wasm.ConstantExpression{Data: d.Offset},
+			Init:             d.Init,
+		})
+	}
+
+	if n := m.Names; n != nil {
+		out.NameSection = &wasm.NameSection{ModuleName: n.Module, FunctionNames: toNameMap(n.Functions)}
+		for _, l := range n.Locals {
+			out.NameSection.LocalNames = append(out.NameSection.LocalNames, wasm.NameMapAssoc{Index: l.Index, NameMap: toNameMap(l.Names)})
+		}
+	}
+
+	for i := range m.CustomSections {
+		c := &m.CustomSections[i]
+		out.CustomSections = append(out.CustomSections, &wasm.CustomSection{Name: c.Name, Data: c.Data})
+	}
+	return out
+}
+
+func fromTable(t *wasm.Table) Table {
+	o := Table{Type: ValueType(t.Type), Min: t.Min, Max: t.Max}
+	if t.InitExpr != nil {
+		o.Init = t.InitExpr.Data
+	}
+	return o
+}
+
+func (t *Table) toInternal() wasm.Table {
+	o := wasm.Table{Type: wasm.RefType(t.Type), Min: t.Min, Max: t.Max}
+	if t.Init != nil {
+		o.InitExpr = &wasm.ConstantExpression{Data: t.Init}
+	}
+	return o
+}
+
+func fromMemory(mem *wasm.Memory) *Memory {
+	o := &Memory{Min: mem.Min, Shared: mem.IsShared}
+	if mem.IsMaxEncoded {
+		max := mem.Max
+		o.Max = &max
+	}
+	return o
+}
+
+func (mem *Memory) toInternal() *wasm.Memory {
+	o := &wasm.Memory{Min: mem.Min, Cap: mem.Min, Max: wasm.MemoryLimitPages, IsShared: mem.Shared}
+	if mem.Max != nil {
+		o.Max, o.IsMaxEncoded = *mem.Max, true
+	}
+	return o
+}
+
+func fromValueTypes(vts []wasm.ValueType) []ValueType {
+	if vts == nil {
+		return nil
+	}
+	o := make([]ValueType, len(vts))
+	for i, vt := range vts {
+		o[i] = ValueType(vt)
+	}
+	return o
+}
+
+func toValueTypes(vts []ValueType) []wasm.ValueType {
+	if vts == nil {
+		return nil
+	}
+	o := make([]wasm.ValueType, len(vts))
+	for i, vt := range vts {
+		o[i] = wasm.ValueType(vt)
+	}
+	return o
+}
+
+func fromNameMap(nm wasm.NameMap) (o []NameAssoc) {
+	for _, na := range nm {
+		o = append(o, NameAssoc{Index: na.Index, Name: na.Name})
+	}
+	return
+}
+
+func toNameMap(nas []NameAssoc) (o wasm.NameMap) {
+	for _, na := range nas {
+		o = append(o, wasm.NameAssoc{Index: na.Index, Name: na.Name})
+	}
+	return
+}
@@ -0,0 +1,180 @@
+package wasmbinary_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental/wasmbinary"
+	"github.com/tetratelabs/wazero/internal/testing/dwarftestdata"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+var testCtx = context.Background()
+
+func TestDecode_RoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		bin  []byte
+	}{
+		{name: "tinygo", bin: dwarftestdata.TinyGoWasm},
+		{name: "zig", bin: dwarftestdata.ZigWasm},
+		{name: "module", bin: (&testModule).Encode()},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := wasmbinary.Decode(tc.bin, api.CoreFeaturesV2)
+			require.NoError(t, err)
+			require.NoError(t, m.Validate(api.CoreFeaturesV2))
+
+			decoded, err := wasmbinary.Decode(m.Encode(), api.CoreFeaturesV2)
+			require.NoError(t, err)
+			require.Equal(t, m, decoded)
+		})
+	}
+}
+
+// testModule exercises each field of wasmbinary.Module.
+var testModule = wasmbinary.Module{
+	Types: []wasmbinary.FunctionType{
+		{},
+		{Params: []wasmbinary.ValueType{wasmbinary.ValueTypeI32}, Results: []wasmbinary.ValueType{wasmbinary.ValueTypeI64}},
+	},
+	Imports: []wasmbinary.Import{
+		{Type: api.ExternTypeFunc, Module: "env", Name: "f", FuncType: 1},
+		{Type: api.ExternTypeGlobal, Module: "env", Name: "g", Global: wasmbinary.GlobalType{ValType: wasmbinary.ValueTypeI32}},
+	},
+	Functions: []uint32{0},
+	Tables:    []wasmbinary.Table{{Type: wasmbinary.ValueTypeFuncref, Min: 1, Max: ptrOfUint32(2)}},
+	Memory:    &wasmbinary.Memory{Min: 1, Max: ptrOfUint32(3)},
+	Globals: []wasmbinary.Global{{
+		Type: wasmbinary.GlobalType{ValType: wasmbinary.ValueTypeF64, Mutable: true},
+		Init: []byte{0x44, 0, 0, 0, 0, 0, 0, 0xf0, 0x3f, 0x0b}, // f64.const 1
+	}},
+	Exports: []wasmbinary.Export{{Type: api.ExternTypeFunc, Name: "run", Index: 1}},
+	Start:   ptrOfUint32(1),
+	Elements: []wasmbinary.ElementSegment{
+		{Mode: wasmbinary.ElementModeActive, Offset: []byte{0x41, 0, 0x0b}, Type: wasmbinary.ValueTypeFuncref.NonNullable(), Init: [][]byte{{0xd2, 1, 0x0b}}},
+		{Mode: wasmbinary.ElementModePassive, Type: wasmbinary.ValueTypeFuncref, Init: [][]byte{{0xd0, 0x70, 0x0b}}},
+	},
+	DataCount: ptrOfUint32(2),
+	Code:      []wasmbinary.Code{{Locals: []wasmbinary.ValueType{wasmbinary.ValueTypeI32, wasmbinary.ValueTypeI32}, Body: []byte{0x0b}}},
+	Data: []wasmbinary.DataSegment{
+		{Offset: []byte{0x23, 0, 0x0b}, Init: []byte("hello")}, // global.get 0
+		{Passive: true, Init: []byte("world")},
+	},
+	Names: &wasmbinary.Names{
+		Module:    "test",
+		Functions: []wasmbinary.NameAssoc{{Index: 1, Name: "run"}},
+		Locals:    []wasmbinary.IndirectNameAssoc{{Index: 1, Names: []wasmbinary.NameAssoc{{Index: 0, Name: "x"}}}},
+	},
+	CustomSections: []wasmbinary.CustomSection{{Name: "producers", Data: []byte{0}}},
+}
+
+func TestModule_Edit(t *testing.T) {
+	m, err := wasmbinary.Decode(dwarftestdata.TinyGoWasm, api.CoreFeaturesV2)
+	require.NoError(t, err)
+	require.NotEqual(t, 0, len(m.CustomSections))
+
+	m.CustomSections = nil
+	m.Names = nil
+	for i := range m.Imports {
+		if m.Imports[i].Module == "wasi_snapshot_preview1" {
+			m.Imports[i].Module = "shim"
+		}
+	}
+
+	r := wazero.NewRuntimeWithConfig(testCtx, wazero.NewRuntimeConfig().WithCustomSections(true))
+	defer r.Close(testCtx)
+	compiled, err := r.CompileModule(testCtx, m.Encode())
+	require.NoError(t, err)
+	require.Equal(t, 0, len(compiled.CustomSections()))
+	for _, f := range compiled.ImportedFunctions() {
+		moduleName, _, _ := f.Import()
+		require.Equal(t, "shim", moduleName)
+	}
+}
+
+func TestModule_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		module wasmbinary.Module
+	}{
+		{
+			name: "type mismatch",
+			module: wasmbinary.Module{
+				Types:     []wasmbinary.FunctionType{{Results: []wasmbinary.ValueType{wasmbinary.ValueTypeI32}}},
+				Functions: []uint32{0},
+				Code:      []wasmbinary.Code{{Body: []byte{0x42, 0, 0x0b}}}, // i64.const 0
+			},
+		},
+		{
+			name:   "unknown export",
+			module: wasmbinary.Module{Exports: []wasmbinary.Export{{Type: api.ExternTypeFunc, Name: "f"}}},
+		},
+		{
+			name:   "unknown start",
+			module: wasmbinary.Module{Start: ptrOfUint32(0)},
+		},
+		{
+			name:   "memory over limit",
+			module: wasmbinary.Module{Memory: &wasmbinary.Memory{Min: 70000}},
+		},
+		{
+			name: "function and code mismatch",
+			module: wasmbinary.Module{
+				Types:     []wasmbinary.FunctionType{{}},
+				Functions: []uint32{0},
+			},
+		},
+	}
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.module.Validate(api.CoreFeaturesV2)
+			_, expectedErr := r.CompileModule(testCtx, tc.module.Encode())
+			require.Error(t, expectedErr)
+			require.EqualError(t, err, expectedErr.Error())
+		})
+	}
+}
+
+func TestDecode_Error(t *testing.T) {
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	_, err := wasmbinary.Decode([]byte("pooh"), api.CoreFeaturesV2)
+	_, expectedErr := r.CompileModule(testCtx, []byte("pooh"))
+	require.EqualError(t, err, expectedErr.Error())
+}
+
+func TestDecodeText(t *testing.T) {
+	m, err := wasmbinary.DecodeText([]byte(`(module
+  (func $add (export "add") (param i32 i32) (result i32)
+    (i32.add (local.get 0) (local.get 1))))`))
+	require.NoError(t, err)
+	require.NoError(t, m.Validate(api.CoreFeaturesV2))
+
+	r := wazero.NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	mod, err := r.Instantiate(testCtx, m.Encode())
+	require.NoError(t, err)
+	results, err := mod.ExportedFunction("add").Call(testCtx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{3}, results)
+}
+
+func TestDecodeText_Error(t *testing.T) {
+	_, err := wasmbinary.DecodeText([]byte("(module\n  (func (local.get $x)))"))
+	require.EqualError(t, err, "2:20: unknown local $x")
+}
+
+func ptrOfUint32(v uint32) *uint32 {
+	return &v
+}
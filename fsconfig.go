@@ -80,6 +80,9 @@ type FSConfig interface {
 	// such as creating or deleting files, limited to any host level access
 	// controls.
 	//
+	// To prevent escaping the directory, mount sysfs.ConfinedDirFS from the
+	// experimental/sysfs package with sysfs.FSConfig WithSysFSMount instead.
+	//
 	// # os.DirFS
 	//
 	// This configuration optimizes for WASI compatibility which is sometimes
@@ -140,6 +143,9 @@ type fsConfig struct {
 	// guestPathToFS are the normalized paths to the currently configured
 	// filesystems, used for de-duplicating.
 	guestPathToFS map[string]int
+	// maxOpenFiles is the count of file descriptors a module can have open,
+	// or zero for no limit.
+	maxOpenFiles int
 }
 
 // NewFSConfig returns a FSConfig that can be used for configuring module instantiation.
@@ -198,6 +204,13 @@ func (c *fsConfig) WithSysFSMount(fs experimentalsys.FS, guestPath string) FSCon
 	return ret
 }
 
+// WithMaxOpenFiles implements sysfs.FSConfig
+func (c *fsConfig) WithMaxOpenFiles(max int) FSConfig {
+	ret := c.clone()
+	ret.maxOpenFiles = max
+	return ret
+}
+
 // preopens returns the possible nil index-correlated preopened filesystems
 // with guest paths.
 func (c *fsConfig) preopens() ([]experimentalsys.FS, []string) {
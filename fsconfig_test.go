@@ -67,6 +67,26 @@ func TestFSConfig(t *testing.T) {
 	}
 }
 
+func TestFSConfig_WithMaxOpenFiles(t *testing.T) {
+	base := NewFSConfig()
+	fc := base.(*fsConfig).WithMaxOpenFiles(10)
+
+	require.Equal(t, 10, fc.(*fsConfig).maxOpenFiles)
+	require.Zero(t, base.(*fsConfig).maxOpenFiles) // base isn't changed
+
+	sysCtx, err := NewModuleConfig().WithFSConfig(fc).(*moduleConfig).toSysContext()
+	require.NoError(t, err)
+	defer sysCtx.FS().Close()
+
+	// stdio is open, so only 7 more can be.
+	for i := 0; i < 7; i++ {
+		_, errno := sysCtx.FS().OpenFile(sysfs.DirFS("."), ".", sys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+	}
+	_, errno := sysCtx.FS().OpenFile(sysfs.DirFS("."), ".", sys.O_RDONLY, 0)
+	require.EqualErrno(t, sys.EMFILE, errno)
+}
+
 func TestFSConfig_clone(t *testing.T) {
 	fc := NewFSConfig().(*fsConfig)
 	fc.guestPathToFS["/"] = 0
@@ -0,0 +1,171 @@
+package wasi_snapshot_preview1
+
+import (
+	"context"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/wasip1"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// isAudited returns true if the WASI function named name is a filesystem,
+// socket or process operation, which is recorded when the guest has a
+// sys.Audit. proc_exit records itself, as it doesn't return.
+func isAudited(name string) bool {
+	for _, prefix := range []string{"fd_", "path_", "sock_", "proc_"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditFunc is a wasiFunc which records a sys.AuditEvent when the guest has
+// a sys.Audit. Otherwise, it only calls the function.
+type auditFunc struct {
+	name string
+	fn   wasiFunc
+}
+
+// Call implements the same method as documented on api.GoModuleFunction.
+func (f *auditFunc) Call(ctx context.Context, mod api.Module, stack []uint64) {
+	a := mod.(*wasm.ModuleInstance).Sys.Audit()
+	if a == nil {
+		f.fn.Call(ctx, mod, stack)
+		return
+	}
+
+	// Copy the params, as the result overwrites the stack.
+	params := make([]uint64, len(stack))
+	copy(params, stack)
+
+	e := sys.AuditEvent{Module: mod.Name(), Op: f.name}
+	auditBefore(mod, params, &e)
+	errno := f.fn(ctx, mod, stack)
+	if errno != 0 {
+		stack[0] = uint64(wasip1.ToErrno(errno))
+	} else {
+		stack[0] = 0
+		auditAfter(mod, params, &e)
+	}
+	e.Errno = errno
+	a.Record(&e)
+}
+
+// auditBefore sets the fields of the event known before the function is
+// called, such as the path to remove.
+func auditBefore(mod api.Module, params []uint64, e *sys.AuditEvent) {
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	mem := mod.Memory()
+
+	switch e.Op {
+	case wasip1.PathCreateDirectoryName, wasip1.PathReadlinkName,
+		wasip1.PathRemoveDirectoryName, wasip1.PathUnlinkFileName:
+		e.Fd = int32(params[0])
+		e.Path, e.HostPath = auditAtPath(fsc, mem, e.Fd, params[1], params[2])
+	case wasip1.PathFilestatGetName, wasip1.PathFilestatSetTimesName:
+		e.Fd = int32(params[0])
+		e.Path, e.HostPath = auditAtPath(fsc, mem, e.Fd, params[2], params[3])
+	case wasip1.PathOpenName:
+		e.Fd = int32(params[0])
+		e.Path, e.HostPath = auditAtPath(fsc, mem, e.Fd, params[2], params[3])
+		e.Flags = openFlags(uint16(params[1]), uint16(params[4]), uint16(params[7]), uint32(params[5]))
+	case wasip1.PathLinkName:
+		e.Fd = int32(params[0])
+		e.Path, e.HostPath = auditAtPath(fsc, mem, e.Fd, params[2], params[3])
+		e.NewPath, e.NewHostPath = auditAtPath(fsc, mem, int32(params[4]), params[5], params[6])
+	case wasip1.PathRenameName:
+		e.Fd = int32(params[0])
+		e.Path, e.HostPath = auditAtPath(fsc, mem, e.Fd, params[1], params[2])
+		e.NewPath, e.NewHostPath = auditAtPath(fsc, mem, int32(params[3]), params[4], params[5])
+	case wasip1.PathSymlinkName:
+		e.Fd = int32(params[2])
+		e.Path, e.HostPath = auditAtPath(fsc, mem, e.Fd, params[3], params[4])
+		if b, ok := mem.Read(uint32(params[0]), uint32(params[1])); ok {
+			e.NewPath = string(b)
+		}
+	case wasip1.ProcExitName, wasip1.ProcRaiseName:
+		// The parameter is an exit code or a signal, not a file descriptor.
+		// proc_exit records its exit code itself.
+	default: // fd_* and sock_* functions, whose first parameter is a file descriptor
+		e.Fd = int32(params[0])
+		if f, ok := fsc.LookupFile(e.Fd); ok {
+			e.Path, e.HostPath = auditFilePaths(fsc, f)
+		}
+	}
+}
+
+// auditAfter sets the fields of the event known after the function
+// succeeded, such as the count of bytes read.
+func auditAfter(mod api.Module, params []uint64, e *sys.AuditEvent) {
+	mem := mod.Memory()
+
+	var result uint32
+	switch e.Op {
+	case wasip1.FdReadName, wasip1.FdWriteName:
+		result = uint32(params[3])
+	case wasip1.FdPreadName, wasip1.FdPwriteName, wasip1.FdReaddirName,
+		wasip1.SockRecvName, wasip1.SockSendName:
+		result = uint32(params[4])
+	case wasip1.PathOpenName:
+		if fd, ok := mem.ReadUint32Le(uint32(params[8])); ok {
+			e.Fd = int32(fd)
+		}
+		return
+	case wasip1.SockAcceptName:
+		if fd, ok := mem.ReadUint32Le(uint32(params[2])); ok {
+			e.Fd = int32(fd)
+		}
+		return
+	default:
+		return
+	}
+	if n, ok := mem.ReadUint32Le(result); ok {
+		e.Bytes = int64(n)
+	}
+}
+
+// auditAtPath returns the guest and host path of a path relative to the
+// directory dirFD, or only the path if it isn't valid.
+func auditAtPath(fsc *sys.FSContext, mem api.Memory, dirFD int32, p, pathLen uint64) (guestPath, hostPath string) {
+	fs, pathName, errno := atPath(fsc, mem, dirFD, uint32(p), uint32(pathLen), 0)
+	if errno != 0 {
+		if b, ok := mem.Read(uint32(p), uint32(pathLen)); ok {
+			return string(b), ""
+		}
+		return "", ""
+	}
+	return auditGuestPath(fsc, fs, pathName), sysfs.HostPath(fs, pathName)
+}
+
+// auditFilePaths returns the guest and host path of an open file.
+func auditFilePaths(fsc *sys.FSContext, f *sys.FileEntry) (guestPath, hostPath string) {
+	if f.FS == nil || f.IsPreopen { // e.g. stdio or a pre-opened directory
+		return f.Name, sysfs.HostPath(f.FS, ".")
+	}
+	return auditGuestPath(fsc, f.FS, f.Name), sysfs.HostPath(f.FS, f.Name)
+}
+
+// auditGuestPath returns the guest path of pathName in fs, by joining it
+// with the guest path of the pre-open of fs.
+func auditGuestPath(fsc *sys.FSContext, fs experimentalsys.FS, pathName string) (guestPath string) {
+	guestPath = pathName
+	if fs == nil || !reflect.TypeOf(fs).Comparable() {
+		return
+	}
+	for fd := int32(3); ; fd++ {
+		f, ok := fsc.LookupFile(fd)
+		if !ok || !f.IsPreopen {
+			return
+		}
+		if f.FS == fs {
+			return path.Join(f.Name, pathName)
+		}
+	}
+}
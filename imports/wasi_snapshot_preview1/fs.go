@@ -35,9 +35,11 @@ func fdAdviseFn(_ context.Context, mod api.Module, params []uint64) experimental
 	advice := byte(params[3])
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 
-	_, ok := fsc.LookupFile(fd)
+	f, ok := fsc.LookupFile(fd)
 	if !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, wasip1.RIGHT_FD_ADVISE); errno != 0 {
+		return errno
 	}
 
 	switch advice {
@@ -80,6 +82,8 @@ func fdAllocateFn(_ context.Context, mod api.Module, params []uint64) experiment
 	f, ok := fsc.LookupFile(fd)
 	if !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, wasip1.RIGHT_FD_ALLOCATE); errno != 0 {
+		return errno
 	}
 
 	tail := int64(offset + length)
@@ -137,6 +141,8 @@ func fdDatasyncFn(_ context.Context, mod api.Module, params []uint64) experiment
 	// Check to see if the file descriptor is available
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, wasip1.RIGHT_FD_DATASYNC); errno != 0 {
+		return errno
 	} else {
 		return f.File.Datasync()
 	}
@@ -160,8 +166,8 @@ func fdDatasyncFn(_ context.Context, mod api.Module, params []uint64) experiment
 //   - fs_filetype 1 byte: the file type
 //   - fs_flags 2 bytes: the file descriptor flag
 //   - 5 pad bytes
-//   - fs_right_base 8 bytes: ignored as rights were removed from WASI.
-//   - fs_right_inheriting 8 bytes: ignored as rights were removed from WASI.
+//   - fs_right_base 8 bytes: the rights of the file descriptor
+//   - fs_right_inheriting 8 bytes: the maximum rights of files opened from it
 //
 // For example, with a file corresponding with `fd` was a directory (=3) opened
 // with `fd_read` right (=1) and no fs_flags (=0), parameter resultFdstat=1,
@@ -229,6 +235,11 @@ func fdFdstatGetFn(_ context.Context, mod api.Module, params []uint64) experimen
 		fsRightsBase = fileRightsBase
 	}
 
+	if f.Rights != nil {
+		fsRightsBase &= f.Rights.Base
+		fsRightsInheriting &= f.Rights.Inheriting
+	}
+
 	writeFdstat(buf, fileType, fdflags, fsRightsBase, fsRightsInheriting)
 	return 0
 }
@@ -309,6 +320,8 @@ func fdFdstatSetFlagsFn(_ context.Context, mod api.Module, params []uint64) expe
 
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, wasip1.RIGHT_FDSTAT_SET_FLAGS); errno != 0 {
+		return errno
 	} else {
 		nonblock := wasip1.FD_NONBLOCK&wasiFlag != 0
 		if pf, ok := f.File.(experimentalsys.PollableFile); ok {
@@ -330,15 +343,94 @@ func fdFdstatSetFlagsFn(_ context.Context, mod api.Module, params []uint64) expe
 	return 0
 }
 
-// fdFdstatSetRights will not be implemented as rights were removed from WASI.
+// fdFdstatSetRights is the WASI function named FdFdstatSetRightsName which
+// adjusts the rights associated with a file descriptor.
+//
+// # Parameters
+//
+//   - fd: file descriptor to adjust the rights of
+//   - fs_rights_base: the rights of the file descriptor
+//   - fs_rights_inheriting: the maximum rights of files opened from it
 //
-// See https://github.com/bytecodealliance/wasmtime/pull/4666
-var fdFdstatSetRights = stubFunction(
-	wasip1.FdFdstatSetRightsName,
+// Result (Errno)
+//
+// The return value is 0 except the following error conditions:
+//   - sys.EBADF: `fd` is invalid
+//   - sys.ENOTCAPABLE: the rights would be extended
+//
+// # Notes
+//   - Rights can only be removed. Once removed, a right is enforced by the
+//     host functions of the file descriptor, even if it wasn't pre-opened
+//     with sysfs.NewRightsFS.
+//   - Rights were removed from later versions of WASI.
+//     See https://github.com/bytecodealliance/wasmtime/pull/4666
+//
+// See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-fd_fdstat_set_rightsfd-fd-fs_rights_base-rights-fs_rights_inheriting-rights---errno
+var fdFdstatSetRights = newHostFunc(
+	wasip1.FdFdstatSetRightsName, fdFdstatSetRightsFn,
 	[]wasm.ValueType{i32, i64, i64},
 	"fd", "fs_rights_base", "fs_rights_inheriting",
 )
 
+func fdFdstatSetRightsFn(_ context.Context, mod api.Module, params []uint64) experimentalsys.Errno {
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	fd := int32(params[0])
+	base, inheriting := params[1], params[2]
+
+	f, ok := fsc.LookupFile(fd)
+	if !ok {
+		return experimentalsys.EBADF
+	}
+
+	// Files without rights have all of them.
+	current := sys.Rights{Base: allRights, Inheriting: allRights}
+	if f.Rights != nil {
+		current = *f.Rights
+	}
+	if base&^uint64(current.Base) != 0 || inheriting&^uint64(current.Inheriting) != 0 {
+		return experimentalsys.ENOTCAPABLE
+	}
+	f.Rights = &sys.Rights{Base: uint32(base), Inheriting: uint32(inheriting)}
+	return 0
+}
+
+// allRights are all the rights defined by WASI.
+const allRights = wasip1.RIGHT_SOCK_SHUTDOWN<<1 - 1
+
+// requireRights returns sys.ENOTCAPABLE if the rights of the file are
+// enforced, and don't include all the given rights.
+func requireRights(f *sys.FileEntry, rights uint32) experimentalsys.Errno {
+	if f.Rights != nil && f.Rights.Base&rights != rights {
+		return experimentalsys.ENOTCAPABLE
+	}
+	return 0
+}
+
+// readRights returns the rights needed by fd_read or fd_pread.
+func readRights(isPread bool) uint32 {
+	if isPread {
+		return wasip1.RIGHT_FD_READ | wasip1.RIGHT_FD_SEEK
+	}
+	return wasip1.RIGHT_FD_READ
+}
+
+// writeRights returns the rights needed by fd_write or fd_pwrite.
+func writeRights(isPwrite bool) uint32 {
+	if isPwrite {
+		return wasip1.RIGHT_FD_WRITE | wasip1.RIGHT_FD_SEEK
+	}
+	return wasip1.RIGHT_FD_WRITE
+}
+
+// seekRights returns the rights needed by fd_seek: only RIGHT_FD_TELL when
+// the offset doesn't change, as used by fd_tell.
+func seekRights(offset uint64, whence uint32) uint32 {
+	if offset == 0 && whence == io.SeekCurrent {
+		return wasip1.RIGHT_FD_TELL
+	}
+	return wasip1.RIGHT_FD_SEEK
+}
+
 // fdFilestatGet is the WASI function named FdFilestatGetName which returns
 // the stat attributes of an open file.
 //
@@ -406,6 +498,8 @@ func fdFilestatGetFunc(mod api.Module, fd int32, resultBuf uint32) experimentals
 	f, ok := fsc.LookupFile(fd)
 	if !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, wasip1.RIGHT_FD_FILESTAT_GET); errno != 0 {
+		return errno
 	}
 
 	st, errno := f.File.Stat()
@@ -476,6 +570,8 @@ func fdFilestatSetSizeFn(_ context.Context, mod api.Module, params []uint64) exp
 	// Check to see if the file descriptor is available
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, wasip1.RIGHT_FD_FILESTAT_SET_SIZE); errno != 0 {
+		return errno
 	} else {
 		return f.File.Truncate(size)
 	}
@@ -503,6 +599,8 @@ func fdFilestatSetTimesFn(_ context.Context, mod api.Module, params []uint64) ex
 	f, ok := fsc.LookupFile(fd)
 	if !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, wasip1.RIGHT_FD_FILESTAT_SET_TIMES); errno != 0 {
+		return errno
 	}
 
 	atim, mtim, errno := toTimes(sys.WalltimeNanos, atim, mtim, fstFlags)
@@ -773,7 +871,8 @@ func fdReadFn(_ context.Context, mod api.Module, params []uint64) experimentalsy
 
 func fdReadOrPread(mod api.Module, params []uint64, isPread bool) experimentalsys.Errno {
 	mem := mod.Memory()
-	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	fsc := sysCtx.FS()
 
 	fd := int32(params[0])
 	iovs := uint32(params[1])
@@ -783,13 +882,25 @@ func fdReadOrPread(mod api.Module, params []uint64, isPread bool) experimentalsy
 	var reader func(buf []byte) (n int, errno experimentalsys.Errno)
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, readRights(isPread)); errno != 0 {
+		return errno
 	} else if isPread {
 		offset := int64(params[3])
 		reader = (&preader{f: f.File, offset: offset}).Read
+		reader = sysCtx.TraceReader(fd, f.File, sys.TracePread, reader)
 		resultNread = uint32(params[4])
 	} else {
 		reader = f.File.Read
 		resultNread = uint32(params[3])
+		// Don't wait when replaying, as the data read is in the trace.
+		if mayBlock(fd, f.File) && !sysCtx.Replaying() {
+			m := mod.(*wasm.ModuleInstance)
+			awaitReady(m, f.File, experimentalsys.POLLIN)
+			if stdin, ok := f.File.(*sys.StdinFile); ok && readerMayBlock(stdin.Reader) {
+				reader = readOrDone(m, reader)
+			}
+		}
+		reader = sysCtx.TraceReader(fd, f.File, sys.TraceRead, reader)
 	}
 
 	nread, errno := readv(mem, iovs, iovsCount, reader)
@@ -803,6 +914,63 @@ func fdReadOrPread(mod api.Module, params []uint64, isPread bool) experimentalsy
 	}
 }
 
+// mayBlock returns true if reading the file could block indefinitely, such as
+// stdin or a socket in blocking mode.
+func mayBlock(fd int32, f experimentalsys.File) bool {
+	if isNonblock(f) {
+		return false
+	} else if fd == sys.FdStdin {
+		return true
+	}
+	_, ok := f.(socketapi.TCPConn)
+	return ok
+}
+
+// readerMayBlock returns true if stdin configured with the reader may block
+// in a way that can't be interrupted when the module is closed. Pollable
+// readers were already waited on with awaitReady, and in-memory readers, such
+// as bytes.Reader, strings.Reader and bytes.Buffer, which report the count of
+// bytes remaining with Len, never block.
+func readerMayBlock(r io.Reader) bool {
+	switch r.(type) {
+	case experimentalsys.Pollable, interface{ Len() int }:
+		return false
+	}
+	return true
+}
+
+// readOrDone returns a reader which returns promptly when the module is
+// closed, by reading in the background. This is used for readers which can't
+// be polled, such as stdin configured with an arbitrary io.Reader.
+//
+// Note: When the module is closed during a read, it continues in the
+// background, and what it reads is discarded.
+func readOrDone(m *wasm.ModuleInstance, reader func(buf []byte) (int, experimentalsys.Errno)) func(buf []byte) (int, experimentalsys.Errno) {
+	type result struct {
+		n     int
+		errno experimentalsys.Errno
+	}
+	return func(buf []byte) (int, experimentalsys.Errno) {
+		// Read into a copy, as buf is memory of the module, which must not
+		// be written after returning.
+		tmp := make([]byte, len(buf))
+		ch := make(chan result, 1)
+		go func() {
+			n, errno := reader(tmp)
+			ch <- result{n: n, errno: errno}
+		}()
+
+		select {
+		case r := <-ch:
+			copy(buf, tmp[:r.n])
+			return r.n, r.errno
+		case <-m.Done():
+			failIfClosed(m)
+			return 0, experimentalsys.EINTR
+		}
+	}
+}
+
 func readv(mem api.Memory, iovs uint32, iovsCount uint32, reader func(buf []byte) (nread int, errno experimentalsys.Errno)) (uint32, experimentalsys.Errno) {
 	var nread uint32
 	iovsStop := iovsCount << 3 // iovsCount * 8
@@ -1040,6 +1208,8 @@ func writeDirent(buf []byte, dNext uint64, ino sysapi.Inode, dNamlen uint32, dTy
 func direntCache(fsc *sys.FSContext, fd int32) (*sys.DirentCache, experimentalsys.Errno) {
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return nil, experimentalsys.EBADF
+	} else if errno := requireRights(f, wasip1.RIGHT_FD_READDIR); errno != 0 {
+		return nil, errno
 	} else if dir, errno := f.DirentCache(); errno == 0 {
 		return dir, 0
 	} else if errno == experimentalsys.ENOTDIR {
@@ -1126,6 +1296,8 @@ func fdSeekFn(_ context.Context, mod api.Module, params []uint64) experimentalsy
 
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, seekRights(offset, whence)); errno != 0 {
+		return errno
 	} else if isDir, _ := f.File.IsDir(); isDir {
 		return experimentalsys.EISDIR // POSIX doesn't forbid seeking a directory, but wasi-testsuite does.
 	} else if newOffset, errno := f.File.Seek(int64(offset), int(whence)); errno != 0 {
@@ -1149,6 +1321,8 @@ func fdSyncFn(_ context.Context, mod api.Module, params []uint64) experimentalsy
 	// Check to see if the file descriptor is available
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, wasip1.RIGHT_FD_SYNC); errno != 0 {
+		return errno
 	} else {
 		return f.File.Sync()
 	}
@@ -1267,6 +1441,8 @@ func fdWriteOrPwrite(mod api.Module, params []uint64, isPwrite bool) experimenta
 	var writer func(buf []byte) (n int, errno experimentalsys.Errno)
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return experimentalsys.EBADF
+	} else if errno := requireRights(f, writeRights(isPwrite)); errno != 0 {
+		return errno
 	} else if isPwrite {
 		offset := int64(params[3])
 		writer = (&pwriter{f: f.File, offset: offset}).Write
@@ -1348,7 +1524,7 @@ func pathCreateDirectoryFn(_ context.Context, mod api.Module, params []uint64) e
 	path := uint32(params[1])
 	pathLen := uint32(params[2])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_CREATE_DIRECTORY)
 	if errno != 0 {
 		return errno
 	}
@@ -1402,7 +1578,7 @@ func pathFilestatGetFn(_ context.Context, mod api.Module, params []uint64) exper
 	path := uint32(params[2])
 	pathLen := uint32(params[3])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_FILESTAT_GET)
 	if errno != 0 {
 		return errno
 	}
@@ -1457,7 +1633,7 @@ func pathFilestatSetTimesFn(_ context.Context, mod api.Module, params []uint64)
 		return errno
 	}
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_FILESTAT_SET_TIMES)
 	if errno != 0 {
 		return errno
 	}
@@ -1495,7 +1671,7 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) experimental
 	oldPath := uint32(params[2])
 	oldPathLen := uint32(params[3])
 
-	oldFS, oldName, errno := atPath(fsc, mem, oldFD, oldPath, oldPathLen)
+	oldFS, oldName, errno := atPath(fsc, mem, oldFD, oldPath, oldPathLen, wasip1.RIGHT_PATH_LINK_SOURCE)
 	if errno != 0 {
 		return errno
 	}
@@ -1504,7 +1680,7 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) experimental
 	newPath := uint32(params[5])
 	newPathLen := uint32(params[6])
 
-	newFS, newName, errno := atPath(fsc, mem, newFD, newPath, newPathLen)
+	newFS, newName, errno := atPath(fsc, mem, newFD, newPath, newPathLen, wasip1.RIGHT_PATH_LINK_TARGET)
 	if errno != 0 {
 		return errno
 	}
@@ -1526,9 +1702,10 @@ func pathLinkFn(_ context.Context, mod api.Module, params []uint64) experimental
 //   - path: offset in api.Memory to read the path string from
 //   - pathLen: length of `path`
 //   - oFlags: open flags to indicate the method by which to open the file
-//   - fsRightsBase: interpret RIGHT_FD_WRITE to set O_RDWR
-//   - fsRightsInheriting: ignored as rights were removed from WASI.
-//     created file descriptor for `path`
+//   - fsRightsBase: interpret RIGHT_FD_WRITE to set O_RDWR. When `fd` has
+//     rights, this is limited to the rights it inherits.
+//   - fsRightsInheriting: the rights of files opened from the created file
+//     descriptor, when `fd` has rights. Otherwise, this is ignored.
 //   - fdFlags: file descriptor flags
 //   - resultOpenedFD: offset in api.Memory to write the newly created file
 //     descriptor to.
@@ -1590,13 +1767,12 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) experimental
 	oflags := uint16(params[4])
 
 	rights := uint32(params[5])
-	// inherited rights aren't used
-	_ = params[6]
+	inheriting := uint32(params[6])
 
 	fdflags := uint16(params[7])
 	resultOpenedFD := uint32(params[8])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), preopenFD, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), preopenFD, path, pathLen, pathOpenRights(oflags))
 	if errno != 0 {
 		return errno
 	}
@@ -1605,6 +1781,14 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) experimental
 		return experimentalsys.EINVAL
 	}
 
+	// When the directory has rights, the file can't have more than it
+	// inherits.
+	dir, _ := fsc.LookupFile(preopenFD)
+	if dir.Rights != nil {
+		rights &= dir.Rights.Inheriting
+		inheriting &= dir.Rights.Inheriting
+	}
+
 	fileOpenFlags := openFlags(dirflags, oflags, fdflags, rights)
 	isDir := fileOpenFlags&experimentalsys.O_DIRECTORY != 0
 
@@ -1617,6 +1801,12 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) experimental
 		return errno
 	}
 
+	if dir.Rights != nil {
+		if f, ok := fsc.LookupFile(newFD); ok {
+			f.Rights = &sys.Rights{Base: rights, Inheriting: inheriting}
+		}
+	}
+
 	// Check any flags that require the file to evaluate.
 	if isDir {
 		if f, ok := fsc.LookupFile(newFD); !ok {
@@ -1637,7 +1827,20 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) experimental
 	return 0
 }
 
-// atPath returns the pre-open specific path after verifying it is a directory.
+// pathOpenRights returns the rights path_open needs on the directory.
+func pathOpenRights(oflags uint16) uint32 {
+	rights := wasip1.RIGHT_PATH_OPEN
+	if oflags&wasip1.O_CREAT != 0 {
+		rights |= wasip1.RIGHT_PATH_CREATE_FILE
+	}
+	if oflags&wasip1.O_TRUNC != 0 {
+		rights |= wasip1.RIGHT_PATH_FILESTAT_SET_SIZE
+	}
+	return rights
+}
+
+// atPath returns the pre-open specific path after verifying it is a directory
+// with the given rights.
 //
 // # Notes
 //
@@ -1653,7 +1856,7 @@ func pathOpenFn(_ context.Context, mod api.Module, params []uint64) experimental
 //
 // See https://github.com/WebAssembly/wasi-libc/blob/659ff414560721b1660a19685110e484a081c3d4/libc-bottom-half/sources/at_fdcwd.c
 // See https://linux.die.net/man/2/openat
-func atPath(fsc *sys.FSContext, mem api.Memory, fd int32, p, pathLen uint32) (experimentalsys.FS, string, experimentalsys.Errno) {
+func atPath(fsc *sys.FSContext, mem api.Memory, fd int32, p, pathLen, rights uint32) (experimentalsys.FS, string, experimentalsys.Errno) {
 	b, ok := mem.Read(p, pathLen)
 	if !ok {
 		return nil, "", experimentalsys.EFAULT
@@ -1681,6 +1884,8 @@ func atPath(fsc *sys.FSContext, mem api.Memory, fd int32, p, pathLen uint32) (ex
 
 	if f, ok := fsc.LookupFile(fd); !ok {
 		return nil, "", experimentalsys.EBADF // closed or invalid
+	} else if errno := requireRights(f, rights); errno != 0 {
+		return nil, "", errno
 	} else if isDir, errno := f.File.IsDir(); errno != 0 {
 		return nil, "", errno
 	} else if !isDir {
@@ -1716,7 +1921,7 @@ func openFlags(dirflags, oflags, fdflags uint16, rights uint32) (openFlags exper
 	} else if oflags&wasip1.O_EXCL != 0 {
 		openFlags |= experimentalsys.O_EXCL
 	}
-	// Because we don't implement rights, we partially rely on the open flags
+	// Unless rights are enforced, we partially rely on the open flags
 	// to determine the mode in which the file will be opened. This will create
 	// divergent behavior compared to WASI runtimes which have a more strict
 	// interpretation of the WASI capabilities model; for example, a program
@@ -1794,7 +1999,7 @@ func pathReadlinkFn(_ context.Context, mod api.Module, params []uint64) experime
 	}
 
 	mem := mod.Memory()
-	preopen, p, errno := atPath(fsc, mem, fd, path, pathLen)
+	preopen, p, errno := atPath(fsc, mem, fd, path, pathLen, wasip1.RIGHT_PATH_READLINK)
 	if errno != 0 {
 		return errno
 	}
@@ -1853,7 +2058,7 @@ func pathRemoveDirectoryFn(_ context.Context, mod api.Module, params []uint64) e
 	path := uint32(params[1])
 	pathLen := uint32(params[2])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_REMOVE_DIRECTORY)
 	if errno != 0 {
 		return errno
 	}
@@ -1903,12 +2108,12 @@ func pathRenameFn(_ context.Context, mod api.Module, params []uint64) experiment
 	newPath := uint32(params[4])
 	newPathLen := uint32(params[5])
 
-	oldFS, oldPathName, errno := atPath(fsc, mod.Memory(), fd, oldPath, oldPathLen)
+	oldFS, oldPathName, errno := atPath(fsc, mod.Memory(), fd, oldPath, oldPathLen, wasip1.RIGHT_PATH_RENAME_SOURCE)
 	if errno != 0 {
 		return errno
 	}
 
-	newFS, newPathName, errno := atPath(fsc, mod.Memory(), newFD, newPath, newPathLen)
+	newFS, newPathName, errno := atPath(fsc, mod.Memory(), newFD, newPath, newPathLen, wasip1.RIGHT_PATH_RENAME_TARGET)
 	if errno != 0 {
 		return errno
 	}
@@ -1959,7 +2164,7 @@ func pathSymlinkFn(_ context.Context, mod api.Module, params []uint64) experimen
 		return experimentalsys.EFAULT
 	}
 
-	_, newPathName, errno := atPath(fsc, mod.Memory(), fd, newPath, newPathLen)
+	_, newPathName, errno := atPath(fsc, mod.Memory(), fd, newPath, newPathLen, wasip1.RIGHT_PATH_SYMLINK)
 	if errno != 0 {
 		return errno
 	}
@@ -2006,7 +2211,7 @@ func pathUnlinkFileFn(_ context.Context, mod api.Module, params []uint64) experi
 	path := uint32(params[1])
 	pathLen := uint32(params[2])
 
-	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen)
+	preopen, pathName, errno := atPath(fsc, mod.Memory(), fd, path, pathLen, wasip1.RIGHT_PATH_UNLINK_FILE)
 	if errno != 0 {
 		return errno
 	}
@@ -10,7 +10,6 @@ import (
 	"os"
 	"path"
 	"testing"
-	gofstest "testing/fstest"
 	"time"
 
 	"github.com/tetratelabs/wazero"
@@ -244,15 +243,9 @@ func Test_fdFdstatGet(t *testing.T) {
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 	preopen := getPreopen(t, fsc)
 
-	// replace stdin with a fake TTY file.
-	// TODO: Make this easier once we have in-memory sys.File
+	// replace stdin with an in-memory TTY.
 	stdin, _ := fsc.LookupFile(sys.FdStdin)
-	stdinFile, errno := (&sysfs.AdaptFS{FS: &gofstest.MapFS{"stdin": &gofstest.MapFile{
-		Mode: fs.ModeDevice | fs.ModeCharDevice | 0o600,
-	}}}).OpenFile("stdin", 0, 0)
-	require.EqualErrno(t, 0, errno)
-
-	stdin.File = stdinFile
+	_, stdin.File = sysfs.NewPTY()
 
 	// Make this file writeable, to ensure flags read-back correctly.
 	fileFD, errno := fsc.OpenFile(preopen, file, experimentalsys.O_RDWR, 0)
@@ -651,13 +644,105 @@ func Test_fdFdstatSetFlags(t *testing.T) {
 	})
 }
 
-// Test_fdFdstatSetRights only tests it is stubbed for GrainLang per #271
 func Test_fdFdstatSetRights(t *testing.T) {
-	log := requireErrnoNosys(t, wasip1.FdFdstatSetRightsName, 0, 0, 0)
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithFS(fstest.FS))
+	defer r.Close(testCtx)
+
+	// Without rights, a file has all of them, so they can only be narrowed.
+	fileFD := uint64(requireOpenFD(t, mod, "animals.txt"))
+	requireErrnoResult(t, 0, mod, wasip1.FdFdstatSetRightsName, fileFD, uint64(wasip1.RIGHT_FD_READ), 0)
+	requireErrnoResult(t, 0, mod, wasip1.FdReadName, fileFD, 0, 0, 0)
+	requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.FdSeekName, fileFD, 1, io.SeekStart, 0)
+	requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.FdFdstatSetRightsName, fileFD, uint64(wasip1.RIGHT_FD_READ|wasip1.RIGHT_FD_SEEK), 0)
+	requireErrnoResult(t, wasip1.ErrnoBadf, mod, wasip1.FdFdstatSetRightsName, uint64(12345), 0, 0)
 	require.Equal(t, `
-==> wasi_snapshot_preview1.fd_fdstat_set_rights(fd=0,fs_rights_base=,fs_rights_inheriting=)
-<== errno=ENOSYS
-`, log)
+==> wasi_snapshot_preview1.fd_fdstat_set_rights(fd=4,fs_rights_base=FD_READ,fs_rights_inheriting=)
+<== errno=ESUCCESS
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=0,iovs_len=0)
+<== (nread=0,errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_seek(fd=4,offset=1,whence=0)
+<== (newoffset=,errno=ENOTCAPABLE)
+==> wasi_snapshot_preview1.fd_fdstat_set_rights(fd=4,fs_rights_base=FD_READ|FD_SEEK,fs_rights_inheriting=)
+<== errno=ENOTCAPABLE
+==> wasi_snapshot_preview1.fd_fdstat_set_rights(fd=12345,fs_rights_base=,fs_rights_inheriting=)
+<== errno=EBADF
+`, "\n"+log.String())
+}
+
+func Test_rightsFS(t *testing.T) {
+	memFS := sysfs.NewMemFS()
+	f, errno := memFS.OpenFile("animals.txt", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o644)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// The directory can open files, but they are read-only.
+	readOnly := wasip1.RIGHT_FD_READ | wasip1.RIGHT_FD_SEEK | wasip1.RIGHT_FD_TELL | wasip1.RIGHT_FD_FILESTAT_GET
+	rightsFS := sysfs.NewRightsFS(memFS, wasip1.RIGHT_PATH_OPEN|wasip1.RIGHT_FD_READDIR, readOnly)
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().
+		WithFSConfig(wazero.NewFSConfig().(interface {
+			WithSysFSMount(experimentalsys.FS, string) wazero.FSConfig
+		}).WithSysFSMount(rightsFS, "/")))
+	defer r.Close(testCtx)
+
+	preopenFD, fileFD := uint64(3), uint64(4)
+	requirePathOpen(t, mod, "animals.txt", math.MaxUint32)
+	log.Reset()
+
+	t.Run("directory", func(t *testing.T) {
+		ok := mod.Memory().WriteString(0, "sub")
+		require.True(t, ok)
+		requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.PathCreateDirectoryName, preopenFD, 0, 3)
+		requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.PathUnlinkFileName, preopenFD, 0, 3)
+		requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.PathOpenName, preopenFD, 0, 0, 3, uint64(wasip1.O_CREAT), 0, 0, 0, 0)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		requireErrnoResult(t, 0, mod, wasip1.FdReadName, fileFD, 0, 0, 0)
+		requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.FdWriteName, fileFD, 0, 0, 0)
+		requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.FdFilestatSetSizeName, fileFD, 0)
+		requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.FdFdstatSetRightsName, fileFD, uint64(wasip1.RIGHT_FD_WRITE), 0)
+
+		// The rights requested by path_open are limited to the ones inherited.
+		requireErrnoResult(t, 0, mod, wasip1.FdFdstatGetName, fileFD, 0)
+		base, ok := mod.Memory().ReadUint64Le(8)
+		require.True(t, ok)
+		require.Equal(t, uint64(readOnly), base)
+
+		// The rights move with the file descriptor.
+		requireErrnoResult(t, 0, mod, wasip1.FdRenumberName, fileFD, 10)
+		requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.FdWriteName, 10, 0, 0, 0)
+	})
+
+	require.Equal(t, `
+==> wasi_snapshot_preview1.path_create_directory(fd=3,path=sub)
+<== errno=ENOTCAPABLE
+==> wasi_snapshot_preview1.path_unlink_file(fd=3,path=sub)
+<== errno=ENOTCAPABLE
+==> wasi_snapshot_preview1.path_open(fd=3,dirflags=,path=sub,oflags=CREAT,fs_rights_base=,fs_rights_inheriting=,fdflags=)
+<== (opened_fd=,errno=ENOTCAPABLE)
+==> wasi_snapshot_preview1.fd_read(fd=4,iovs=0,iovs_len=0)
+<== (nread=0,errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_write(fd=4,iovs=0,iovs_len=0)
+<== (nwritten=,errno=ENOTCAPABLE)
+==> wasi_snapshot_preview1.fd_filestat_set_size(fd=4,size=0)
+<== errno=ENOTCAPABLE
+==> wasi_snapshot_preview1.fd_fdstat_set_rights(fd=4,fs_rights_base=FD_WRITE,fs_rights_inheriting=)
+<== errno=ENOTCAPABLE
+==> wasi_snapshot_preview1.fd_fdstat_get(fd=4)
+<== (stat={filetype=REGULAR_FILE,fdflags=,fs_rights_base=FD_READ|FD_SEEK|FD_TELL,fs_rights_inheriting=},errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_renumber(fd=4,to=10)
+<== errno=ESUCCESS
+==> wasi_snapshot_preview1.fd_write(fd=10,iovs=0,iovs_len=0)
+<== (nwritten=,errno=ENOTCAPABLE)
+`, "\n"+log.String())
+}
+
+// requirePathOpen opens the path relative to the pre-open using path_open,
+// asking for the given rights.
+func requirePathOpen(t *testing.T, mod api.Module, path string, rights uint32) {
+	ok := mod.Memory().WriteString(0, path)
+	require.True(t, ok)
+	requireErrnoResult(t, 0, mod, wasip1.PathOpenName, 3, 0, 0, uint64(len(path)), 0, uint64(rights), uint64(rights), 0, 64)
 }
 
 func Test_fdFilestatGet(t *testing.T) {
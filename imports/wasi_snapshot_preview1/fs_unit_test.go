@@ -1,7 +1,10 @@
 package wasi_snapshot_preview1
 
 import (
+	"bytes"
+	"io"
 	"os"
+	"strings"
 	"testing"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
@@ -351,3 +354,33 @@ func Test_isPreopenedStdio(t *testing.T) {
 		})
 	}
 }
+
+func Test_readerMayBlock(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	defer pr.Close()
+	defer pw.Close()
+
+	tests := []struct {
+		name     string
+		reader   io.Reader
+		expected bool
+	}{
+		{name: "bytes.Reader", reader: bytes.NewReader(nil)},
+		{name: "strings.Reader", reader: strings.NewReader("wazero")},
+		{name: "bytes.Buffer", reader: &bytes.Buffer{}},
+		{name: "pollable", reader: struct {
+			io.Reader
+			experimentalsys.Pollable
+		}{Reader: pr}},
+		{name: "os.Pipe", reader: pr, expected: true},
+		{name: "io.Reader", reader: struct{ io.Reader }{strings.NewReader("")}, expected: true},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, readerMayBlock(tc.reader))
+		})
+	}
+}
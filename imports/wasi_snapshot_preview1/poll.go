@@ -2,11 +2,15 @@ package wasi_snapshot_preview1
 
 import (
 	"context"
+	"io"
+	"io/fs"
+	"math"
 	"time"
 
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental/sys"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
 )
@@ -24,8 +28,7 @@ import (
 // Result (Errno)
 //
 // The return value is 0 except the following error conditions:
-//   - sys.EINVAL: the parameters are invalid
-//   - sys.ENOTSUP: a parameters is valid, but not yet supported.
+//   - sys.EINVAL: the parameters are invalid, such as clock flags
 //   - sys.EFAULT: there is not enough memory to read the subscriptions or
 //     write results.
 //
@@ -33,6 +36,8 @@ import (
 //
 //   - Since the `out` pointer nests Errno, the result is always 0.
 //   - This is similar to `poll` in POSIX.
+//   - fd_read and fd_write subscriptions are waited on at the same time, and
+//     clock subscriptions bound how long. See sysfs.PollFiles for details.
 //
 // See https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#poll_oneoff
 // See https://linux.die.net/man/3/poll
@@ -46,6 +51,8 @@ type event struct {
 	eventType byte
 	userData  []byte
 	errno     wasip1.Errno
+	nbytes    uint64
+	flags     uint16
 }
 
 func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
@@ -82,18 +89,25 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 	// Loop through all subscriptions and write their output.
 
 	// Extract FS context, used in the body of the for loop for FS access.
-	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
-	// blockingPollSubs are fd read subscriptions processed after the loop via polling.
-	var blockingPollSubs []struct {
-		evt  *event
-		file sys.File
-	}
-	// The timeout is initialized at max Duration, the loop will find the minimum.
-	var timeout time.Duration = 1<<63 - 1
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	fsc := sysCtx.FS()
+	// fdEvents are fd subscriptions processed after the loop by polling all
+	// of their files at once. pollEvents are the corresponding files.
+	var fdEvents []*event
+	var pollEvents []sysfs.PollEvent
+	// clockEvents are clock subscriptions, written back after polling if
+	// their timeout elapsed, which are in clockTimeouts.
+	var clockEvents []*event
+	var clockTimeouts []time.Duration
+	// The timeout is negative until a clock subscription is found, as then
+	// polling waits indefinitely. Otherwise, the loop will find the minimum.
+	var timeout time.Duration = -1
 	// Count of all the subscriptions that have been already written back to outBuf.
 	// nevents*32 returns at all times the offset where the next event should be written:
 	// this way we ensure that there are no gaps between records.
 	nevents := uint32(0)
+	// Count of fd subscriptions already written back, due to a bad fd.
+	badfds := 0
 
 	// Layout is subscription_u: Union
 	// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#subscription_u
@@ -114,98 +128,109 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 
 		switch eventType {
 		case wasip1.EventTypeClock: // handle later
-			newTimeout, err := processClockEvent(argBuf)
+			newTimeout, err := processClockEvent(sysCtx, argBuf)
 			if err != 0 {
 				return err
 			}
 			// Min timeout.
-			if newTimeout < timeout {
+			if timeout < 0 || newTimeout < timeout {
 				timeout = newTimeout
 			}
-			// Ack the clock event to the outBuf.
-			writeEvent(outBuf[outOffset:], evt)
-			nevents++
-		case wasip1.EventTypeFdRead:
+			clockEvents = append(clockEvents, evt)
+			clockTimeouts = append(clockTimeouts, newTimeout)
+		case wasip1.EventTypeFdRead, wasip1.EventTypeFdWrite:
 			fd := int32(le.Uint32(argBuf))
 			if fd < 0 {
 				return sys.EBADF
 			}
+			flag, rights := sys.POLLIN, uint32(wasip1.RIGHT_POLL_FD_READWRITE|wasip1.RIGHT_FD_READ)
+			if eventType == wasip1.EventTypeFdWrite {
+				flag, rights = sys.POLLOUT, wasip1.RIGHT_POLL_FD_READWRITE|wasip1.RIGHT_FD_WRITE
+			}
 			if file, ok := fsc.LookupFile(fd); !ok {
 				evt.errno = wasip1.ErrnoBadf
 				writeEvent(outBuf[outOffset:], evt)
 				nevents++
-			} else if fd != internalsys.FdStdin && isNonblock(file.File) {
+				badfds++
+			} else if errno := requireRights(file, rights); errno != 0 {
+				evt.errno = wasip1.ToErrno(errno)
 				writeEvent(outBuf[outOffset:], evt)
 				nevents++
+				badfds++
 			} else {
-				// The fd is in blocking mode; do not ack yet, append
-				// to a slice for deferred polling evaluation.
-				blockingPollSubs = append(blockingPollSubs, struct {
-					evt  *event
-					file sys.File
-				}{evt, file.File})
+				// Defer until all subscriptions are known, so that their
+				// files can be polled at the same time.
+				fdEvents = append(fdEvents, evt)
+				pollEvents = append(pollEvents, sysfs.PollEvent{File: file.File, Flag: flag})
 			}
-		case wasip1.EventTypeFdWrite:
-			fd := int32(le.Uint32(argBuf))
-			if fd < 0 {
-				return sys.EBADF
-			}
-			if _, ok := fsc.LookupFile(fd); ok {
-				evt.errno = wasip1.ErrnoNotsup
-			} else {
-				evt.errno = wasip1.ErrnoBadf
-			}
-			nevents++
-			writeEvent(outBuf[outOffset:], evt)
 		default:
 			return sys.EINVAL
 		}
 	}
 
-	sysCtx := mod.(*wasm.ModuleInstance).Sys
-	if nevents == nsubscriptions {
-		// We already wrote back all the results. We already wrote this number
-		// earlier to offset `resultNevents`.
-		// We only need to observe the timeout (nonzero if there are clock subscriptions)
-		// and return.
-		if timeout > 0 {
-			sysCtx.Nanosleep(int64(timeout))
+	m := mod.(*wasm.ModuleInstance)
+	// elapsed is how long was waited for the timeout, which is zero unless
+	// nothing else was ready.
+	var elapsed time.Duration
+	if len(pollEvents) == 0 {
+		// We only need to observe the timeout when there are only clock
+		// subscriptions. Don't wait when a bad fd was already written back.
+		if timeout > 0 && badfds == 0 {
+			if !sysCtx.NanosleepOrDone(int64(timeout), m.Done()) {
+				failIfClosed(m)
+			}
+			elapsed = timeout
 		}
-		return 0
-	}
-
-	// Wait for the timeout to expire, or for data to become available on
-	// each blocking fd subscriber. The remaining time budget is tracked
-	// across iterations so total wall time never exceeds the timeout.
-	remaining := timeout
-	for _, sub := range blockingPollSubs {
-		p, ok := sub.file.(sys.Pollable)
-		if !ok {
-			sub.evt.errno = wasip1.ErrnoNotsup
-			writeEvent(outBuf[nevents*32:], sub.evt)
-			nevents++
-			continue
+	} else {
+		// Wait for the timeout to expire, or for any fd subscription to
+		// become ready. Don't wait when a bad fd was already written back.
+		timeoutMillis := durationToMillis(timeout)
+		if badfds > 0 {
+			timeoutMillis = 0
 		}
-		start := time.Now()
-		ready, errno := p.Poll(sys.POLLIN, int32(remaining.Milliseconds()))
-		switch errno {
-		case 0:
-			if ready {
-				sub.evt.errno = 0
-				writeEvent(outBuf[nevents*32:], sub.evt)
-				nevents++
+		poll := func() (int, sys.Errno) { return sysfs.PollFiles(m.Done(), pollEvents, timeoutMillis) }
+		if vclock := sysCtx.VirtualClock(); vclock != nil && timeoutMillis > 0 {
+			// The timeout elapses in virtual time.
+			poll = func() (int, sys.Errno) { return vclock.PollFiles(m.Done(), pollEvents, int64(timeout)) }
+		}
+		if errno := sysCtx.TracePoll(pollEvents, func() sys.Errno {
+			_, errno := poll()
+			if errno == sys.EINTR {
+				failIfClosed(m)
 			}
-		case sys.ENOSYS, sys.ENOTSUP:
-			sub.evt.errno = wasip1.ErrnoNotsup
-			writeEvent(outBuf[nevents*32:], sub.evt)
-			nevents++
-		default:
 			return errno
+		}); errno != 0 {
+			return errno
+		}
+		if badfds == 0 && !anyPollEvent(pollEvents) {
+			elapsed = timeout
 		}
-		if elapsed := time.Since(start); elapsed < remaining {
-			remaining -= elapsed
+	}
+
+	for i, pe := range pollEvents {
+		evt := fdEvents[i]
+		if pe.Errno != 0 {
+			evt.errno = wasip1.ToErrno(pe.Errno)
+		} else if pe.Revents == 0 {
+			continue
 		} else {
-			remaining = 0
+			if pe.Revents&sys.POLLHUP != 0 {
+				evt.flags = wasip1.EventRwFlagsHangup
+			}
+			if evt.eventType == wasip1.EventTypeFdRead {
+				evt.nbytes = readableBytes(pe.File)
+			}
+		}
+		writeEvent(outBuf[nevents*32:], evt)
+		nevents++
+	}
+
+	// Only write back clock subscriptions whose timeout elapsed, as an fd
+	// which is ready may have returned before.
+	for i, evt := range clockEvents {
+		if clockTimeouts[i] <= elapsed {
+			writeEvent(outBuf[nevents*32:], evt)
+			nevents++
 		}
 	}
 
@@ -218,33 +243,88 @@ func pollOneoffFn(_ context.Context, mod api.Module, params []uint64) sys.Errno
 	return 0
 }
 
-// processClockEvent supports only relative name events, as that's what's used
-// to implement sleep in various compilers including Rust, Zig and TinyGo.
-func processClockEvent(inBuf []byte) (time.Duration, sys.Errno) {
-	_ /* ID */ = le.Uint32(inBuf[0:8])          // See below
+// anyPollEvent returns true if any of the events is ready or failed.
+func anyPollEvent(events []sysfs.PollEvent) bool {
+	for _, pe := range events {
+		if pe.Revents != 0 || pe.Errno != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// durationToMillis returns the duration in milliseconds for sysfs.PollFiles,
+// rounded up so that polling doesn't return before the timeout.
+func durationToMillis(timeout time.Duration) int32 {
+	if timeout < 0 {
+		return -1
+	}
+	millis := (timeout + time.Millisecond - 1) / time.Millisecond
+	if millis > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(millis)
+}
+
+// readableBytes returns the count of bytes remaining to read in a regular
+// file, or buffered in a pipe, or zero if unknown.
+func readableBytes(f sys.File) uint64 {
+	st, errno := f.Stat()
+	if errno != 0 {
+		return 0
+	} else if st.Mode&fs.ModeNamedPipe != 0 {
+		return uint64(st.Size)
+	} else if !st.Mode.IsRegular() {
+		return 0
+	}
+	offset, errno := f.Seek(0, io.SeekCurrent)
+	if errno != 0 || offset >= st.Size {
+		return 0
+	}
+	return uint64(st.Size - offset)
+}
+
+// processClockEvent returns the timeout of a clock subscription. Absolute
+// timeouts are converted to relative ones using the clock named by the
+// subscription.
+func processClockEvent(sysCtx *internalsys.Context, inBuf []byte) (time.Duration, sys.Errno) {
+	id := le.Uint32(inBuf[0:8])
 	timeout := le.Uint64(inBuf[8:16])           // nanos if relative
 	_ /* precision */ = le.Uint64(inBuf[16:24]) // Unused
 	flags := le.Uint16(inBuf[24:32])
 
-	var err sys.Errno
-	// subclockflags has only one flag defined:  subscription_clock_abstime
+	// subclockflags has only one flag defined: subscription_clock_abstime
 	switch flags {
 	case 0: // relative time
-	case 1: // subscription_clock_abstime
-		err = sys.ENOTSUP
-	default: // subclockflags has only one flag defined.
-		err = sys.EINVAL
-	}
-
-	if err != 0 {
-		return 0, err
-	} else {
 		// https://linux.die.net/man/3/clock_settime says relative timers are
-		// unaffected. Since this function only supports relative timeout, we can
-		// skip name ID validation and use a single sleep function.
+		// unaffected, so we can skip name ID validation.
+		return clampDuration(timeout), 0
+	case wasip1.SubclockflagsAbstime:
+		var now int64
+		switch id {
+		case wasip1.ClockIDRealtime:
+			now = sysCtx.WalltimeNanos()
+		case wasip1.ClockIDMonotonic:
+			now = sysCtx.Nanotime()
+		default:
+			return 0, sys.EINVAL
+		}
+		if deadline := clampDuration(timeout); int64(deadline) > now {
+			return deadline - time.Duration(now), 0
+		}
+		return 0, 0 // already elapsed
+	default:
+		return 0, sys.EINVAL
+	}
+}
 
-		return time.Duration(timeout), 0
+// clampDuration converts nanoseconds to a time.Duration, clamping values
+// which would overflow it.
+func clampDuration(ns uint64) time.Duration {
+	if ns > math.MaxInt64 {
+		return math.MaxInt64
 	}
+	return time.Duration(ns)
 }
 
 // isNonblock returns true if the file implements PollableFile and is in
@@ -256,6 +336,31 @@ func isNonblock(f sys.File) bool {
 	return false
 }
 
+// awaitReady blocks until the file is ready for the flag, or the module is
+// closed, such as when the context of the call is done and the module was
+// configured with WithCloseOnContextDone. In the latter case, this panics
+// with the exit error, like other host functions do on close.
+//
+// This returns without waiting when the file can't be polled, so that the
+// caller falls back to blocking.
+func awaitReady(m *wasm.ModuleInstance, f sys.File, flag sys.Pflag) {
+	events := []sysfs.PollEvent{{File: f, Flag: flag}}
+	// Check first without waiting, as that doesn't need to watch m.Done.
+	if n, errno := sysfs.PollFiles(nil, events, 0); n > 0 || errno != 0 {
+		return
+	}
+	if _, errno := sysfs.PollFiles(m.Done(), events, -1); errno == sys.EINTR {
+		failIfClosed(m)
+	}
+}
+
+// failIfClosed panics with the exit error if the module is closed.
+func failIfClosed(m *wasm.ModuleInstance) {
+	if err := m.FailIfClosed(); err != nil {
+		panic(err)
+	}
+}
+
 // writeEvent writes the event corresponding to the processed subscription.
 // https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-event-struct
 func writeEvent(outBuf []byte, evt *event) {
@@ -263,5 +368,7 @@ func writeEvent(outBuf []byte, evt *event) {
 	outBuf[8] = byte(evt.errno) // uint16, but safe as < 255
 	outBuf[9] = 0
 	le.PutUint32(outBuf[10:], uint32(evt.eventType))
-	// TODO: When FD events are supported, write outOffset+16
+	// fd_readwrite is only meaningful for fd events, and zero otherwise.
+	le.PutUint64(outBuf[16:], evt.nbytes)
+	le.PutUint16(outBuf[24:], evt.flags)
 }
@@ -3,6 +3,7 @@ package wasi_snapshot_preview1_test
 import (
 	"io/fs"
 	"os"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/tetratelabs/wazero/api"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -192,9 +194,9 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 `,
 		},
 		{
-			name:            "20ms timeout, fdread on tty (buffer ready): both events are written",
+			name:            "20ms timeout, fdread on tty (buffer ready): only fd event is written",
 			nsubscriptions:  2,
-			expectedNevents: 2,
+			expectedNevents: 1,
 			stdin:           &ttyStdinFile{StdinFile: sys.StdinFile{Reader: strings.NewReader("test")}},
 			mem: concat(
 				clockNsSub(20*1000*1000),
@@ -206,23 +208,22 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			expectedMem: []byte{
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
-				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				// 32 empty bytes
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-				0x0, 0x0,
 
 				'?', // stopped after encoding
 			},
 			expectedLog: `
 ==> wasi_snapshot_preview1.poll_oneoff(in=0,out=128,nsubscriptions=2)
-<== (nevents=2,errno=ESUCCESS)
+<== (nevents=1,errno=ESUCCESS)
 `,
 		},
 		{
@@ -231,7 +232,7 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			expectedNevents: 2,
 			stdin:           &ttyStdinFile{StdinFile: sys.StdinFile{Reader: strings.NewReader("test")}},
 			mem: concat(
-				clockNsSub(20*1000*1000),
+				clockNsSub(0),
 				fdReadSub,
 			),
 			expectedErrno: wasip1.ErrnoSuccess,
@@ -240,14 +241,14 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			expectedMem: []byte{
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
@@ -265,7 +266,7 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			expectedNevents: 2,
 			stdin:           &sys.StdinFile{Reader: strings.NewReader("test")},
 			mem: concat(
-				clockNsSub(20*1000*1000),
+				clockNsSub(0),
 				fdReadSub,
 			),
 			expectedErrno: wasip1.ErrnoSuccess,
@@ -274,14 +275,14 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			expectedMem: []byte{
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
@@ -294,12 +295,12 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 `,
 		},
 		{
-			name:            "1ns timeout, fdread on regular file: both events are written",
+			name:            "1ns timeout, fdread on regular file: only fd event is written",
 			nsubscriptions:  2,
-			expectedNevents: 2,
+			expectedNevents: 1,
 			stdin:           &sys.StdinFile{Reader: strings.NewReader("test")},
 			mem: concat(
-				clockNsSub(20*1000*1000),
+				clockNsSub(1),
 				fdReadSub,
 			),
 			expectedErrno: wasip1.ErrnoSuccess,
@@ -308,23 +309,22 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			expectedMem: []byte{
 				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
+				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
-				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, // pad to 32
+				// 32 empty bytes
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-				0x0, 0x0,
 
 				'?', // stopped after encoding
 			},
 			expectedLog: `
 ==> wasi_snapshot_preview1.poll_oneoff(in=0,out=128,nsubscriptions=2)
-<== (nevents=2,errno=ESUCCESS)
+<== (nevents=1,errno=ESUCCESS)
 `,
 		},
 		{
@@ -364,7 +364,7 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 		{
 			name:            "pollable pipe, multiple subs, events returned out of order",
 			nsubscriptions:  3,
-			expectedNevents: 3,
+			expectedNevents: 2,
 			mem: concat(
 				fdReadSub,
 				clockNsSub(20*1000*1000),
@@ -375,15 +375,7 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 			out:           128, // past in
 			resultNevents: 512, // past out
 			expectedMem: []byte{
-				// Clock is acknowledged first.
-				0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-				byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-				wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-				0x0, 0x0,
-
-				// Then an illegal file with custom user data.
+				// An illegal file with custom user data is written back first.
 				0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, // userdata
 				byte(wasip1.ErrnoBadf), 0x0, // errno is 16 bit
 				wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
@@ -400,11 +392,17 @@ func Test_pollOneoff_Stdin(t *testing.T) {
 				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 				0x0, 0x0,
 
+				// The clock didn't fire, as stdin was ready first.
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+				0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+
 				'?', // stopped after encoding
 			},
 			expectedLog: `
 ==> wasi_snapshot_preview1.poll_oneoff(in=0,out=128,nsubscriptions=3)
-<== (nevents=3,errno=ESUCCESS)
+<== (nevents=2,errno=ESUCCESS)
 `,
 		},
 	}
@@ -471,13 +469,8 @@ func Test_pollOneoff_Zero(t *testing.T) {
 		),
 	)
 
+	// The clock didn't fire, as the fd was ready first.
 	expectedMem := []byte{
-		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
-		byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
-		wasip1.EventTypeClock, 0x0, 0x0, 0x0, // 4 bytes for type enum
-		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
-
 		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // userdata
 		byte(wasip1.ErrnoSuccess), 0x0, // errno is 16 bit
 		wasip1.EventTypeFdRead, 0x0, 0x0, 0x0, // 4 bytes for type enum
@@ -485,6 +478,11 @@ func Test_pollOneoff_Zero(t *testing.T) {
 		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
 		0x0, 0x0,
 
+		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+		0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0,
+
 		'?', // stopped after encoding
 	}
 
@@ -498,7 +496,7 @@ func Test_pollOneoff_Zero(t *testing.T) {
 	// Events should be written on success regardless of nested failure.
 	nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
 	require.True(t, ok)
-	require.Equal(t, uint32(2), nevents)
+	require.Equal(t, uint32(1), nevents)
 
 	// second run: simulate no more data on the fd
 	poller.ready = false
@@ -685,7 +683,9 @@ func Test_pollOneoff_NonStdinPollableNotReady(t *testing.T) {
 ==> wasi_snapshot_preview1.poll_oneoff(in=0,out=128,nsubscriptions=2)
 <== (nevents=1,errno=ESUCCESS)
 `, "\n"+log.String())
-	require.Equal(t, []int32{20}, poller.timeouts)
+	// The poller is checked for a slice of the timeout, so that closing the
+	// module is noticed. As it returns without waiting, it isn't retried.
+	require.Equal(t, []int32{10}, poller.timeouts)
 
 	outMem, ok := mod.Memory().Read(out, 64)
 	require.True(t, ok)
@@ -757,6 +757,279 @@ func Test_pollOneoff_NonStdinPollableTimeoutBudget(t *testing.T) {
 	require.Equal(t, uint32(1), nevents)
 }
 
+func Test_pollOneoff_FdWrite(t *testing.T) {
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithStdout(&strings.Builder{}))
+	defer r.Close(testCtx)
+	defer log.Reset()
+
+	maskMemory(t, mod, 1024)
+
+	out := uint32(128)
+	resultNevents := uint32(512)
+	mod.Memory().Write(0, fdWriteSubFd(byte(sys.FdStdout)))
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName,
+		uint64(0), uint64(out), uint64(1), uint64(resultNevents))
+
+	// Writes to stdout don't block, so it is ready.
+	outMem, ok := mod.Memory().Read(out, 32)
+	require.True(t, ok)
+	require.Equal(t, byte(wasip1.ErrnoSuccess), outMem[8])
+	require.Equal(t, byte(wasip1.EventTypeFdWrite), outMem[10])
+
+	nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
+	require.True(t, ok)
+	require.Equal(t, uint32(1), nevents)
+}
+
+func Test_pollOneoff_FdRights(t *testing.T) {
+	out := uint32(128)
+	resultNevents := uint32(512)
+	tests := []struct {
+		name          string
+		rights        uint32
+		sub           []byte
+		expectedErrno wasip1.Errno
+	}{
+		{
+			name:          "write",
+			rights:        wasip1.RIGHT_POLL_FD_READWRITE | wasip1.RIGHT_FD_WRITE,
+			sub:           fdWriteSubFd(byte(sys.FdStdout)),
+			expectedErrno: wasip1.ErrnoSuccess,
+		},
+		{
+			name:          "write without FD_WRITE",
+			rights:        wasip1.RIGHT_POLL_FD_READWRITE | wasip1.RIGHT_FD_READ,
+			sub:           fdWriteSubFd(byte(sys.FdStdout)),
+			expectedErrno: wasip1.ErrnoNotcapable,
+		},
+		{
+			name:          "read without FD_READ",
+			rights:        wasip1.RIGHT_POLL_FD_READWRITE,
+			sub:           fdReadSubFd(byte(sys.FdStdout)),
+			expectedErrno: wasip1.ErrnoNotcapable,
+		},
+		{
+			name:          "write without POLL_FD_READWRITE",
+			rights:        wasip1.RIGHT_FD_WRITE,
+			sub:           fdWriteSubFd(byte(sys.FdStdout)),
+			expectedErrno: wasip1.ErrnoNotcapable,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			// Rights can only be narrowed, so use a new module for each case.
+			mod, r, _ := requireProxyModule(t, wazero.NewModuleConfig().WithStdout(&strings.Builder{}))
+			defer r.Close(testCtx)
+
+			requireErrnoResult(t, 0, mod, wasip1.FdFdstatSetRightsName, uint64(sys.FdStdout), uint64(tc.rights), 0)
+			maskMemory(t, mod, 1024)
+			mod.Memory().Write(0, tc.sub)
+
+			// The subscription fails, not poll_oneoff, so others can succeed.
+			requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName,
+				uint64(0), uint64(out), uint64(1), uint64(resultNevents))
+			outMem, ok := mod.Memory().Read(out, 32)
+			require.True(t, ok)
+			require.Equal(t, byte(tc.expectedErrno), outMem[8])
+
+			nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
+			require.True(t, ok)
+			require.Equal(t, uint32(1), nevents)
+		})
+	}
+}
+
+func Test_pollOneoff_FdReadNbytes(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(tmpDir+"/test.txt", []byte("wazero"), 0o600))
+
+	cfg := wazero.NewModuleConfig().WithFSConfig(
+		wazero.NewFSConfig().WithDirMount(tmpDir, "/"),
+	)
+	mod, r, log := requireProxyModule(t, cfg)
+	defer r.Close(testCtx)
+	defer log.Reset()
+
+	fd := requirePollOpenFile(t, mod, "test.txt")
+	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	entry, ok := fsc.LookupFile(fd)
+	require.True(t, ok)
+	_, errno := entry.File.Seek(2, 0)
+	require.EqualErrno(t, 0, errno)
+
+	maskMemory(t, mod, 1024)
+
+	out := uint32(128)
+	resultNevents := uint32(512)
+	mod.Memory().Write(0, fdReadSubFd(byte(fd)))
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName,
+		uint64(0), uint64(out), uint64(1), uint64(resultNevents))
+
+	// A regular file is ready, and nbytes are the bytes left to read.
+	outMem, ok := mod.Memory().Read(out, 32)
+	require.True(t, ok)
+	require.Equal(t, byte(wasip1.ErrnoSuccess), outMem[8])
+	require.Equal(t, byte(wasip1.EventTypeFdRead), outMem[10])
+	require.Equal(t, []byte{4, 0, 0, 0, 0, 0, 0, 0}, outMem[16:24]) // nbytes
+	require.Equal(t, []byte{0, 0}, outMem[24:26])                   // flags
+}
+
+func Test_pollOneoff_FdReadHangup(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("hangup is not reported on " + runtime.GOOS)
+	}
+
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	defer pr.Close()
+	require.NoError(t, pw.Close())
+
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithStdin(pr))
+	defer r.Close(testCtx)
+	defer log.Reset()
+
+	maskMemory(t, mod, 1024)
+
+	out := uint32(128)
+	resultNevents := uint32(512)
+	mod.Memory().Write(0, fdReadSub)
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName,
+		uint64(0), uint64(out), uint64(1), uint64(resultNevents))
+
+	// The write end is closed, so reading is ready and the flags say why.
+	outMem, ok := mod.Memory().Read(out, 32)
+	require.True(t, ok)
+	require.Equal(t, byte(wasip1.ErrnoSuccess), outMem[8])
+	require.Equal(t, byte(wasip1.EventTypeFdRead), outMem[10])
+	require.Equal(t, []byte{wasip1.EventRwFlagsHangup, 0}, outMem[24:26])
+}
+
+func Test_pollOneoff_Pipe(t *testing.T) {
+	stdin, hostStdin := sysfs.NewPipe()
+	defer hostStdin.Close()
+
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithStdin(&sysfs.FileIO{File: stdin}))
+	defer r.Close(testCtx)
+	defer log.Reset()
+
+	maskMemory(t, mod, 1024)
+
+	out := uint32(128)
+	resultNevents := uint32(512)
+	mod.Memory().Write(0, concat(clockNsSub(1), fdReadSub))
+
+	// Nothing was written, so only the clock event is ready.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName,
+		uint64(0), uint64(out), uint64(2), uint64(resultNevents))
+	nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
+	require.True(t, ok)
+	require.Equal(t, uint32(1), nevents)
+
+	// Reading is ready once written, with the count of bytes buffered.
+	_, errno := hostStdin.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	mod.Memory().Write(0, fdReadSub)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName,
+		uint64(0), uint64(out), uint64(1), uint64(resultNevents))
+	outMem, ok := mod.Memory().Read(out, 32)
+	require.True(t, ok)
+	require.Equal(t, byte(wasip1.EventTypeFdRead), outMem[10])
+	require.Equal(t, []byte{6, 0, 0, 0, 0, 0, 0, 0}, outMem[16:24])
+	require.Equal(t, []byte{0, 0}, outMem[24:26])
+
+	// The flags say the write end closed.
+	require.EqualErrno(t, 0, hostStdin.Close())
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName,
+		uint64(0), uint64(out), uint64(1), uint64(resultNevents))
+	outMem, ok = mod.Memory().Read(out, 32)
+	require.True(t, ok)
+	require.Equal(t, []byte{wasip1.EventRwFlagsHangup, 0}, outMem[24:26])
+}
+
+func Test_pollOneoff_FdReadyBeforeClock(t *testing.T) {
+	stdin, hostStdin := sysfs.NewPipe()
+	defer hostStdin.Close()
+	_, errno := hostStdin.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+
+	mod, r, log := requireProxyModule(t, wazero.NewModuleConfig().WithStdin(&sysfs.FileIO{File: stdin}))
+	defer r.Close(testCtx)
+	defer log.Reset()
+
+	maskMemory(t, mod, 1024)
+
+	out := uint32(128)
+	resultNevents := uint32(512)
+	mod.Memory().Write(0, concat(clockNsSub(uint64(time.Second)), fdReadSub))
+
+	// Stdin is ready, so poll_oneoff returns before the clock fires.
+	start := time.Now()
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.PollOneoffName,
+		uint64(0), uint64(out), uint64(2), uint64(resultNevents))
+	require.True(t, time.Since(start) < time.Second)
+
+	nevents, ok := mod.Memory().ReadUint32Le(resultNevents)
+	require.True(t, ok)
+	require.Equal(t, uint32(1), nevents)
+	outMem, ok := mod.Memory().Read(out, 64)
+	require.True(t, ok)
+	require.Equal(t, byte(wasip1.EventTypeFdRead), outMem[10])
+	require.Equal(t, make([]byte, 32), outMem[32:64])
+}
+
+func Test_pollOneoff_ClockAbstime(t *testing.T) {
+	tests := []struct {
+		name          string
+		clockID       byte
+		timeout       uint64
+		expectedErrno wasip1.Errno
+	}{
+		{
+			name:          "realtime in the past",
+			clockID:       wasip1.ClockIDRealtime,
+			timeout:       1,
+			expectedErrno: wasip1.ErrnoSuccess,
+		},
+		{
+			name:          "monotonic in the past",
+			clockID:       wasip1.ClockIDMonotonic,
+			timeout:       1,
+			expectedErrno: wasip1.ErrnoSuccess,
+		},
+		{
+			name:          "invalid clock",
+			clockID:       100,
+			timeout:       1,
+			expectedErrno: wasip1.ErrnoInval,
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			mod, r, log := requireProxyModule(t, wazero.NewModuleConfig())
+			defer r.Close(testCtx)
+			defer log.Reset()
+
+			maskMemory(t, mod, 1024)
+
+			sub := clockNsSub(tc.timeout)
+			sub[16] = tc.clockID
+			sub[40] = wasip1.SubclockflagsAbstime
+			mod.Memory().Write(0, sub)
+
+			// A deadline which already passed doesn't wait.
+			requireErrnoResult(t, tc.expectedErrno, mod, wasip1.PollOneoffName,
+				uint64(0), uint64(128), uint64(1), uint64(512))
+		})
+	}
+}
+
 func requirePollOpenFile(t *testing.T, mod api.Module, name string) int32 {
 	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
 	preopen, ok := fsc.LookupFile(sys.FdPreopen)
@@ -800,6 +1073,13 @@ func fdReadSubFd(fd byte) []byte {
 	}
 }
 
+// subscription for an EventTypeFdWrite on a given fd
+func fdWriteSubFd(fd byte) []byte {
+	sub := fdReadSubFd(fd)
+	sub[8] = wasip1.EventTypeFdWrite
+	return sub
+}
+
 func fdReadSubFdWithUserData(fd byte, userdata []byte) []byte {
 	return concat(
 		userdata,
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/tetratelabs/wazero/api"
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
 	"github.com/tetratelabs/wazero/sys"
@@ -29,6 +31,10 @@ var procExit = &wasm.HostFunc{
 func procExitFn(ctx context.Context, mod api.Module, params []uint64) {
 	exitCode := uint32(params[0])
 
+	if a := mod.(*wasm.ModuleInstance).Sys.Audit(); a != nil {
+		a.Record(&internalsys.AuditEvent{Module: mod.Name(), Op: wasip1.ProcExitName, ExitCode: exitCode})
+	}
+
 	// Ensure other callers see the exit code.
 	_ = mod.CloseWithExitCode(ctx, exitCode)
 
@@ -38,7 +44,12 @@ func procExitFn(ctx context.Context, mod api.Module, params []uint64) {
 	panic(sys.NewExitError(exitCode))
 }
 
-// procRaise is stubbed and will never be supported, as it was removed.
+// procRaise is stubbed and will never be supported, as it was removed. Calls
+// are still audited, as a process operation.
 //
 // See https://github.com/WebAssembly/WASI/pull/136
-var procRaise = stubFunction(wasip1.ProcRaiseName, []wasm.ValueType{i32}, "sig")
+var procRaise = newHostFunc(wasip1.ProcRaiseName, procRaiseFn, []wasm.ValueType{i32}, "sig")
+
+func procRaiseFn(context.Context, api.Module, []uint64) experimentalsys.Errno {
+	return experimentalsys.ENOSYS
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental/sys"
 	socketapi "github.com/tetratelabs/wazero/internal/sock"
+	internalsys "github.com/tetratelabs/wazero/internal/sys"
 	"github.com/tetratelabs/wazero/internal/sysfs"
 	"github.com/tetratelabs/wazero/internal/wasip1"
 	"github.com/tetratelabs/wazero/internal/wasm"
@@ -32,6 +33,12 @@ func sockAcceptFn(_ context.Context, mod api.Module, params []uint64) (errno sys
 	resultFd := uint32(params[2])
 	nonblock := flags&uint32(wasip1.FD_NONBLOCK) != 0
 
+	// Wait for a connection unless the listener is non-blocking, so that
+	// closing the module interrupts it. Errors are left to SockAccept.
+	if e, ok := fsc.LookupFile(fd); ok && !isNonblock(e.File) {
+		awaitReady(mod.(*wasm.ModuleInstance), e.File, sys.POLLIN)
+	}
+
 	var connFD int32
 	if connFD, errno = fsc.SockAccept(fd, nonblock); errno == 0 {
 		mem.WriteUint32Le(resultFd, uint32(connFD))
@@ -52,7 +59,8 @@ var sockRecv = newHostFunc(
 
 func sockRecvFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
 	mem := mod.Memory()
-	fsc := mod.(*wasm.ModuleInstance).Sys.FS()
+	sysCtx := mod.(*wasm.ModuleInstance).Sys
+	fsc := sysCtx.FS()
 
 	fd := int32(params[0])
 	riData := uint32(params[1])
@@ -66,12 +74,18 @@ func sockRecvFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
 		return sys.EBADF // Not open
 	} else if conn, ok = e.File.(socketapi.TCPConn); !ok {
 		return sys.EBADF // Not a conn
+	} else if errno := requireRights(e, wasip1.RIGHT_FD_READ); errno != 0 {
+		return errno
 	}
 
 	if riFlags & ^(wasip1.RI_RECV_PEEK|wasip1.RI_RECV_WAITALL) != 0 {
 		return sys.ENOTSUP
 	}
 
+	if !isNonblock(conn) && !sysCtx.Replaying() {
+		awaitReady(mod.(*wasm.ModuleInstance), conn, sys.POLLIN)
+	}
+
 	if riFlags&wasip1.RI_RECV_PEEK != 0 {
 		// Each record in riData is of the form:
 		// type iovec struct { buf *uint8; bufLen uint32 }
@@ -89,7 +103,8 @@ func sockRecvFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
 		if !ok {
 			return sys.EINVAL
 		}
-		n, err := conn.Recvfrom(firstIovecBuf, sysfs.MSG_PEEK)
+		peek := func(buf []byte) (int, sys.Errno) { return conn.Recvfrom(buf, sysfs.MSG_PEEK) }
+		n, err := sysCtx.TraceReader(fd, conn, internalsys.TracePeek, peek)(firstIovecBuf)
 		if err != 0 {
 			return err
 		}
@@ -102,7 +117,8 @@ func sockRecvFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
 	// do a blocking operation until all data has been retrieved;
 	// otherwise we are able to return earlier.
 	// For simplicity, we currently wait all regardless the flag.
-	bufSize, errno := readv(mem, riData, riDataCount, conn.Read)
+	reader := sysCtx.TraceReader(fd, conn, internalsys.TraceRead, conn.Read)
+	bufSize, errno := readv(mem, riData, riDataCount, reader)
 	if errno != 0 {
 		return errno
 	}
@@ -141,6 +157,8 @@ func sockSendFn(_ context.Context, mod api.Module, params []uint64) sys.Errno {
 		return sys.EBADF // Not open
 	} else if conn, ok = e.File.(socketapi.TCPConn); !ok {
 		return sys.EBADF // Not a conn
+	} else if errno := requireRights(e, wasip1.RIGHT_FD_WRITE); errno != 0 {
+		return errno
 	}
 
 	bufSize, errno := writev(mem, siData, siDataCount, conn.Write)
@@ -168,6 +186,8 @@ func sockShutdownFn(_ context.Context, mod api.Module, params []uint64) sys.Errn
 		return sys.EBADF // Not open
 	} else if conn, ok = e.File.(socketapi.TCPConn); !ok {
 		return sys.EBADF // Not a conn
+	} else if errno := requireRights(e, wasip1.RIGHT_SOCK_SHUTDOWN); errno != 0 {
+		return errno
 	}
 
 	sysHow := 0
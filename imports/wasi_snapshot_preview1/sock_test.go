@@ -121,6 +121,47 @@ func Test_sockShutdown(t *testing.T) {
 	}
 }
 
+func Test_sockRights(t *testing.T) {
+	ctx := experimentalsock.WithConfig(testCtx, experimentalsock.NewConfig().WithTCPListener("127.0.0.1", 0))
+
+	mod, r, log := requireProxyModuleWithContext(ctx, t, wazero.NewModuleConfig())
+	defer r.Close(testCtx)
+
+	// Dial the socket so that a call to accept doesn't hang.
+	tcpAddr := requireTCPListenerAddr(t, mod)
+	tcp, err := net.DialTCP("tcp", nil, tcpAddr)
+	require.NoError(t, err)
+	defer tcp.Close() //nolint
+
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockAcceptName, uint64(sys.FdPreopen), uint64(0), 128)
+	connFd, _ := mod.Memory().ReadUint32Le(128)
+
+	// Narrow the rights of the connection, so it can only be written.
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatSetRightsName, uint64(connFd), uint64(wasip1.RIGHT_FD_WRITE), 0)
+	requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.SockRecvName, uint64(connFd), 0, 0, 0, 0, 0)
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.SockSendName, uint64(connFd), 0, 0, 0, 0)
+	requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.SockShutdownName, uint64(connFd), uint64(wasip1.SD_WR))
+	requireErrnoResult(t, wasip1.ErrnoSuccess, mod, wasip1.FdFdstatSetRightsName, uint64(connFd), 0, 0)
+	requireErrnoResult(t, wasip1.ErrnoNotcapable, mod, wasip1.SockSendName, uint64(connFd), 0, 0, 0, 0)
+
+	require.Equal(t, `
+==> wasi_snapshot_preview1.sock_accept(fd=3,flags=)
+<== (fd=4,errno=ESUCCESS)
+==> wasi_snapshot_preview1.fd_fdstat_set_rights(fd=4,fs_rights_base=FD_WRITE,fs_rights_inheriting=)
+<== errno=ESUCCESS
+==> wasi_snapshot_preview1.sock_recv(fd=4,ri_data=0,ri_data_len=0,ri_flags=)
+<== (ro_datalen=,ro_flags=,errno=ENOTCAPABLE)
+==> wasi_snapshot_preview1.sock_send(fd=4,si_data=0,si_data_len=0,si_flags=)
+<== (so_datalen=0,errno=ESUCCESS)
+==> wasi_snapshot_preview1.sock_shutdown(fd=4,how=WR)
+<== errno=ENOTCAPABLE
+==> wasi_snapshot_preview1.fd_fdstat_set_rights(fd=4,fs_rights_base=,fs_rights_inheriting=)
+<== errno=ESUCCESS
+==> wasi_snapshot_preview1.sock_send(fd=4,si_data=0,si_data_len=0,si_flags=)
+<== (so_datalen=,errno=ENOTCAPABLE)
+`, "\n"+log.String())
+}
+
 func Test_sockRecv(t *testing.T) {
 	tests := []struct {
 		name           string
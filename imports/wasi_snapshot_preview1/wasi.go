@@ -272,6 +272,10 @@ func newHostFunc(
 	paramTypes []wasm.ValueType,
 	paramNames ...string,
 ) *wasm.HostFunc {
+	var fn api.GoModuleFunction = goFunc
+	if isAudited(name) {
+		fn = &auditFunc{name: name, fn: goFunc}
+	}
 	return &wasm.HostFunc{
 		ExportName:  name,
 		Name:        name,
@@ -279,7 +283,7 @@ func newHostFunc(
 		ParamNames:  paramNames,
 		ResultTypes: []wasm.ValueType{i32},
 		ResultNames: []string{"errno"},
-		Code:        wasm.Code{GoFunc: goFunc},
+		Code:        wasm.Code{GoFunc: fn},
 	}
 }
 
@@ -297,18 +301,3 @@ func (f wasiFunc) Call(ctx context.Context, mod api.Module, stack []uint64) {
 		stack[0] = 0
 	}
 }
-
-// stubFunction stubs for GrainLang per #271.
-func stubFunction(name string, paramTypes []wasm.ValueType, paramNames ...string) *wasm.HostFunc {
-	return &wasm.HostFunc{
-		ExportName:  name,
-		Name:        name,
-		ParamTypes:  paramTypes,
-		ParamNames:  paramNames,
-		ResultTypes: []wasm.ValueType{i32},
-		ResultNames: []string{"errno"},
-		Code: wasm.Code{
-			GoFunc: api.GoModuleFunc(func(_ context.Context, _ api.Module, stack []uint64) { stack[0] = uint64(wasip1.ErrnoNosys) }),
-		},
-	}
-}
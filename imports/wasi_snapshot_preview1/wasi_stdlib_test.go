@@ -5,6 +5,7 @@ import (
 	"context"
 	_ "embed"
 	"io"
+	"io/fs"
 	"net"
 	"net/http"
 	"os"
@@ -21,6 +22,7 @@ import (
 	"github.com/tetratelabs/wazero/api"
 	experimentalsock "github.com/tetratelabs/wazero/experimental/sock"
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/experimental/sysfs"
 	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 	"github.com/tetratelabs/wazero/internal/fstest"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
@@ -78,27 +80,61 @@ func Test_fdReaddir_ls(t *testing.T) {
 		require.NoError(t, os.WriteFile(path.Join(tons, strconv.Itoa(i)), nil, 0o0666))
 	}
 
+	mounts := map[string]func(dir string) wazero.FSConfig{
+		"dir": func(dir string) wazero.FSConfig {
+			return wazero.NewFSConfig().WithReadOnlyDirMount(path.Join(tmpDir, dir), "/")
+		},
+		"memfs": func(dir string) wazero.FSConfig {
+			memFS := sysfs.NewMemFS()
+			copyToFS(t, memFS, path.Join(tmpDir, dir))
+			return wazero.NewFSConfig().(sysfs.FSConfig).WithSysFSMount(memFS, "/")
+		},
+	}
+
 	for toolchain, bin := range toolchains {
 		toolchain := toolchain
 		bin := bin
-		t.Run(toolchain, func(t *testing.T) {
-			var expectDots int
-			if toolchain == "zig-cc" {
-				expectDots = 1
-			}
-			testFdReaddirLs(t, bin, toolchain, tmpDir, expectDots)
-		})
+		for name, mount := range mounts {
+			mount := mount
+			t.Run(toolchain+"/"+name, func(t *testing.T) {
+				var expectDots int
+				if toolchain == "zig-cc" {
+					expectDots = 1
+				}
+				testFdReaddirLs(t, bin, toolchain, mount, expectDots)
+			})
+		}
 	}
 }
 
+// copyToFS copies the files in the host directory to the sys.FS.
+func copyToFS(t *testing.T, dst experimentalsys.FS, dir string) {
+	err := fs.WalkDir(os.DirFS(dir), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == "." {
+			return err
+		}
+		if d.IsDir() {
+			require.EqualErrno(t, 0, dst.Mkdir(p, 0o755))
+			return nil
+		}
+		data, err := os.ReadFile(path.Join(dir, p))
+		require.NoError(t, err)
+		f, errno := dst.OpenFile(p, experimentalsys.O_WRONLY|experimentalsys.O_CREAT, 0o644)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write(data)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 const direntCountTons = 8096
 
-func testFdReaddirLs(t *testing.T, bin []byte, toolchain, rootDir string, expectDots int) {
+func testFdReaddirLs(t *testing.T, bin []byte, toolchain string, mount func(dir string) wazero.FSConfig, expectDots int) {
 	t.Helper()
 
-	moduleConfig := wazero.NewModuleConfig().
-		WithFSConfig(wazero.NewFSConfig().
-			WithReadOnlyDirMount(path.Join(rootDir, "dir"), "/"))
+	moduleConfig := wazero.NewModuleConfig().WithFSConfig(mount("dir"))
 
 	t.Run("empty directory", func(t *testing.T) {
 		console := compileAndRun(t, testCtx, moduleConfig.WithArgs("wasi", "ls", "./a-"), bin)
@@ -141,8 +177,7 @@ ENOTDIR
 
 	t.Run("directory with tons of entries", func(t *testing.T) {
 		moduleConfig = wazero.NewModuleConfig().
-			WithFSConfig(wazero.NewFSConfig().
-				WithReadOnlyDirMount(path.Join(rootDir, "tons"), "/")).
+			WithFSConfig(mount("tons")).
 			WithArgs("wasi", "ls", ".")
 
 		console := compileAndRun(t, testCtx, moduleConfig, bin)
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
+	"io"
+	"os"
 	"testing"
 	"time"
 
@@ -79,6 +81,79 @@ func TestNewFunctionExporter(t *testing.T) {
 	})
 }
 
+func TestBlockingCallsInterrupted(t *testing.T) {
+	osStdin, osStdinW, err := os.Pipe()
+	require.NoError(t, err)
+	defer osStdin.Close()
+	defer osStdinW.Close()
+
+	stdin, stdinW := io.Pipe()
+	defer stdinW.Close()
+
+	iovs := []byte{
+		16, 0, 0, 0, // iovs[0].offset
+		8, 0, 0, 0, // iovs[0].length
+	}
+
+	tests := []struct {
+		name     string
+		config   wazero.ModuleConfig
+		mem      []byte
+		funcName string
+		params   []uint64
+	}{
+		{
+			name:     "poll_oneoff clock",
+			config:   wazero.NewModuleConfig().WithSysNanosleep(),
+			mem:      clockNsSub(uint64(time.Hour)),
+			funcName: wasip1.PollOneoffName,
+			params:   []uint64{0, 128, 1, 512},
+		},
+		{
+			name:     "poll_oneoff stdin",
+			config:   wazero.NewModuleConfig().WithStdin(osStdin),
+			mem:      fdReadSubFd(0),
+			funcName: wasip1.PollOneoffName,
+			params:   []uint64{0, 128, 1, 512},
+		},
+		{
+			name:     "fd_read stdin",
+			config:   wazero.NewModuleConfig().WithStdin(osStdin),
+			mem:      iovs,
+			funcName: wasip1.FdReadName,
+			params:   []uint64{0, 0, 1, 64},
+		},
+		{
+			name:     "fd_read stdin reader",
+			config:   wazero.NewModuleConfig().WithStdin(stdin),
+			mem:      iovs,
+			funcName: wasip1.FdReadName,
+			params:   []uint64{0, 0, 1, 64},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			rConfig := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+			mod, r, _ := requireProxyModuleWithRuntimeConfig(testCtx, t, rConfig, tc.config)
+			defer r.Close(testCtx)
+
+			require.True(t, mod.Memory().Write(0, tc.mem))
+
+			ctx, cancel := context.WithCancel(testCtx)
+			defer cancel()
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				cancel()
+			}()
+
+			_, err := mod.ExportedFunction(tc.funcName).Call(ctx, tc.params...)
+			require.Equal(t, sys.NewExitError(sys.ExitCodeContextCanceled), err)
+		})
+	}
+}
+
 // maskMemory sets the first memory in the store to '?' * size, so tests can see what's written.
 func maskMemory(t *testing.T, mod api.Module, size int) {
 	for i := uint32(0); i < uint32(size); i++ {
@@ -91,13 +166,17 @@ func requireProxyModule(t *testing.T, config wazero.ModuleConfig) (api.Module, a
 }
 
 func requireProxyModuleWithContext(ctx context.Context, t *testing.T, config wazero.ModuleConfig) (api.Module, api.Closer, *bytes.Buffer) {
+	return requireProxyModuleWithRuntimeConfig(ctx, t, wazero.NewRuntimeConfig(), config)
+}
+
+func requireProxyModuleWithRuntimeConfig(ctx context.Context, t *testing.T, rConfig wazero.RuntimeConfig, config wazero.ModuleConfig) (api.Module, api.Closer, *bytes.Buffer) {
 	var log bytes.Buffer
 
 	// Set context to one that has an experimental listener
 	ctx = experimental.WithFunctionListenerFactory(ctx,
 		proxy.NewLoggingListenerFactory(&log, logging.LogScopeAll))
 
-	r := wazero.NewRuntime(ctx)
+	r := wazero.NewRuntimeWithConfig(ctx, rConfig)
 
 	wasiModuleCompiled, err := wasi_snapshot_preview1.NewBuilder(r).Compile(ctx)
 	require.NoError(t, err)
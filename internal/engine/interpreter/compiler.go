@@ -3636,6 +3636,16 @@ operatorSwitch:
 		return fmt.Errorf("unsupported instruction in interpreterir: 0x%x", op)
 	}
 
+	if c.module.CanonicalizeNaN {
+		var vecOp wasm.OpcodeVec
+		if op == wasm.OpcodeVecPrefix {
+			vecOp = c.body[c.currentOpPC+1]
+		}
+		if shape := wasm.NaNResultShape(op, vecOp); shape != wasm.NaNShapeNone {
+			c.emit(newOperationCanonicalizeNaN(shape))
+		}
+	}
+
 	// Move the program counter to point to the next instruction.
 	c.pc++
 	return nil
@@ -687,6 +687,21 @@ func (e *moduleEngine) FunctionInstanceReference(funcIndex wasm.Index) wasm.Refe
 	return uintptr(unsafe.Pointer(&e.functions[funcIndex]))
 }
 
+// FunctionIndex implements the same method as documented on wasm.ModuleEngine.
+func (e *moduleEngine) FunctionIndex(ref wasm.Reference) (wasm.Index, bool) {
+	if len(e.functions) == 0 {
+		return 0, false
+	}
+	base, size := uintptr(unsafe.Pointer(&e.functions[0])), unsafe.Sizeof(function{})
+	if ref < base || (ref-base)%size != 0 {
+		return 0, false
+	}
+	if i := (ref - base) / size; i < uintptr(len(e.functions)) {
+		return wasm.Index(i), true
+	}
+	return 0, false
+}
+
 // NewFunction implements the same method as documented on wasm.ModuleEngine.
 func (e *moduleEngine) NewFunction(index wasm.Index) (ce api.Function) {
 	// Note: The input parameters are pre-validated, so a compiled function is only absent on close. Updates to
@@ -4664,6 +4679,23 @@ func (ce *callEngine) callNativeFunc(ctx context.Context, m *wasm.ModuleInstance
 				frame.pc = op.U2
 			}
 
+		case operationKindCanonicalizeNaN:
+			switch wasm.NaNShape(op.B1) {
+			case wasm.NaNShapeF32:
+				ce.pushValue(canonicalizeNaNF32x2(ce.popValue()))
+			case wasm.NaNShapeF64:
+				ce.pushValue(canonicalizeNaNF64(ce.popValue()))
+			case wasm.NaNShapeF32x4:
+				hi, lo := ce.popValue(), ce.popValue()
+				ce.pushValue(canonicalizeNaNF32x2(lo))
+				ce.pushValue(canonicalizeNaNF32x2(hi))
+			case wasm.NaNShapeF64x2:
+				hi, lo := ce.popValue(), ce.popValue()
+				ce.pushValue(canonicalizeNaNF64(lo))
+				ce.pushValue(canonicalizeNaNF64(hi))
+			}
+			frame.pc++
+
 		default:
 			frame.pc++
 		}
@@ -4961,3 +4993,25 @@ func v128Dot(x1Hi, x1Lo, x2Hi, x2Lo uint64) (uint64, uint64) {
 	r8 := int32(int16(x1Hi>>48)) * int32(int16(x2Hi>>48))
 	return uint64(uint32(r1+r2)) | (uint64(uint32(r3+r4)) << 32), uint64(uint32(r5+r6)) | (uint64(uint32(r7+r8)) << 32)
 }
+
+// canonicalizeNaNF32x2 replaces each float32 in v which is NaN with
+// wasm.CanonicalNaNF32. A scalar float32 is in the lower bits, and the upper
+// bits are zero, so they are left as is.
+func canonicalizeNaNF32x2(v uint64) uint64 {
+	lo, hi := uint32(v), uint32(v>>32)
+	if f := math.Float32frombits(lo); f != f {
+		lo = wasm.CanonicalNaNF32
+	}
+	if f := math.Float32frombits(hi); f != f {
+		hi = wasm.CanonicalNaNF32
+	}
+	return uint64(hi)<<32 | uint64(lo)
+}
+
+// canonicalizeNaNF64 returns wasm.CanonicalNaNF64 if v is NaN, or v otherwise.
+func canonicalizeNaNF64(v uint64) uint64 {
+	if math.IsNaN(math.Float64frombits(v)) {
+		return wasm.CanonicalNaNF64
+	}
+	return v
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"math"
 	"strings"
+
+	"github.com/tetratelabs/wazero/internal/wasm"
 )
 
 // unsignedInt represents unsigned 32-bit or 64-bit integers.
@@ -463,6 +465,8 @@ func (o operationKind) String() (ret string) {
 		ret = "operationKindBrOnNull"
 	case operationKindBrOnNonNull:
 		ret = "operationKindBrOnNonNull"
+	case operationKindCanonicalizeNaN:
+		ret = "operationKindCanonicalizeNaN"
 	default:
 		panic(fmt.Errorf("unknown operation %d", o))
 	}
@@ -806,6 +810,8 @@ const (
 	operationKindBrOnNull
 	// operationKindBrOnNonNull is the Kind for br_on_non_null instruction.
 	operationKindBrOnNonNull
+	// operationKindCanonicalizeNaN is the Kind for newOperationCanonicalizeNaN.
+	operationKindCanonicalizeNaN
 
 	// operationKindEnd is always placed at the bottom of this iota definition to be used in the test.
 	operationKindEnd
@@ -1163,6 +1169,9 @@ func (o unionOperation) String() string {
 	case operationKindBrOnNonNull:
 		return fmt.Sprintf("%s %s %s", o.Kind, label(o.U1).String(), label(o.U2).String())
 
+	case operationKindCanonicalizeNaN:
+		return fmt.Sprintf("%s %d", o.Kind, o.B1)
+
 	default:
 		panic(fmt.Sprintf("TODO: %v", o.Kind))
 	}
@@ -2945,6 +2954,13 @@ func newOperationBrOnNonNull(thenTarget, elseTarget label, thenDrop inclusiveRan
 	}
 }
 
+// newOperationCanonicalizeNaN is a constructor for operationKindCanonicalizeNaN.
+// It replaces the value on top of the stack, or each of its lanes, with the
+// canonical NaN if it is NaN. B1 is the wasm.NaNShape of the value.
+func newOperationCanonicalizeNaN(shape wasm.NaNShape) unionOperation {
+	return unionOperation{Kind: operationKindCanonicalizeNaN, B1: byte(shape)}
+}
+
 // exceptionTableEntry represents one try_table's exception handling scope.
 // Built at compile time and stored per compiledFunction.
 type exceptionTableEntry struct {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"hash/crc32"
 	"io"
@@ -56,34 +57,72 @@ func (e *engine) getCompiledModule(module *wasm.Module, listeners []experimental
 	}
 	cm, ok, err = e.getCompiledModuleFromCache(module)
 	if ok {
-		cm.parent = e
-		cm.module = module
-		cm.sharedFunctions = e.sharedFunctions
-		cm.ensureTermination = ensureTermination
-		cm.offsets = wazevoapi.NewModuleContextOffsetData(module, len(listeners) > 0)
-		if len(listeners) > 0 {
-			cm.listeners = listeners
-			cm.listenerBeforeTrampolines = make([]*byte, len(module.TypeSection))
-			cm.listenerAfterTrampolines = make([]*byte, len(module.TypeSection))
-			for i := range module.TypeSection {
-				typ := &module.TypeSection[i]
-				before, after := e.getListenerTrampolineForType(typ)
-				cm.listenerBeforeTrampolines[i] = before
-				cm.listenerAfterTrampolines[i] = after
-			}
-		}
-		e.addCompiledModuleToMemory(module, cm)
-		ssaBuilder := ssa.NewBuilder()
-		machine := newMachine()
-		be := backend.NewCompiler(context.Background(), machine, ssaBuilder)
-		cm.executables.compileEntryPreambles(module, machine, be)
-
-		// Set the finalizer.
-		e.setFinalizer(cm.executables, executablesFinalizer)
+		e.addDeserializedCompiledModule(module, cm, listeners, ensureTermination)
 	}
 	return
 }
 
+// addDeserializedCompiledModule completes the compiledModule read by deserializeCompiledModule, so that it can be
+// instantiated, and adds it to the in-memory cache.
+func (e *engine) addDeserializedCompiledModule(module *wasm.Module, cm *compiledModule, listeners []experimental.FunctionListener, ensureTermination bool) {
+	cm.parent = e
+	cm.module = module
+	cm.sharedFunctions = e.sharedFunctions
+	cm.ensureTermination = ensureTermination
+	cm.offsets = wazevoapi.NewModuleContextOffsetData(module, len(listeners) > 0)
+	if len(listeners) > 0 {
+		cm.listeners = listeners
+		cm.listenerBeforeTrampolines = make([]*byte, len(module.TypeSection))
+		cm.listenerAfterTrampolines = make([]*byte, len(module.TypeSection))
+		for i := range module.TypeSection {
+			typ := &module.TypeSection[i]
+			before, after := e.getListenerTrampolineForType(typ)
+			cm.listenerBeforeTrampolines[i] = before
+			cm.listenerAfterTrampolines[i] = after
+		}
+	}
+	e.addCompiledModuleToMemory(module, cm)
+	ssaBuilder := ssa.NewBuilder()
+	machine := newMachine()
+	be := backend.NewCompiler(context.Background(), machine, ssaBuilder)
+	cm.executables.compileEntryPreambles(module, machine, be)
+
+	// Set the finalizer.
+	e.setFinalizer(cm.executables, executablesFinalizer)
+}
+
+// SerializeCompiledModule implements wasm.CompiledModuleSerializer.
+func (e *engine) SerializeCompiledModule(module *wasm.Module) ([]byte, error) {
+	if module.IsHostModule {
+		return nil, errors.New("host modules cannot be serialized")
+	}
+	cm, ok := e.getCompiledModuleFromMemory(module, false)
+	if !ok {
+		return nil, errors.New("source module must be compiled before serialization")
+	} else if len(cm.listeners) > 0 {
+		return nil, errors.New("modules compiled with function listeners cannot be serialized")
+	}
+	return io.ReadAll(serializeCompiledModule(e.wazeroVersion, cm))
+}
+
+// DeserializeCompiledModule implements wasm.CompiledModuleSerializer.
+func (e *engine) DeserializeCompiledModule(module *wasm.Module, listeners []experimental.FunctionListener, ensureTermination bool, data []byte) error {
+	if len(listeners) > 0 {
+		return errors.New("function listeners are not supported with serialized modules")
+	}
+	if _, ok := e.getCompiledModuleFromMemory(module, true); ok {
+		return nil
+	}
+	cm, staleCache, err := deserializeCompiledModule(e.wazeroVersion, io.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		return err
+	} else if staleCache {
+		return fmt.Errorf("compiled module was not serialized by wazero %s", e.wazeroVersion)
+	}
+	e.addDeserializedCompiledModule(module, cm, listeners, ensureTermination)
+	return nil
+}
+
 func (e *engine) addCompiledModuleToMemory(m *wasm.Module, cm *compiledModule) *compiledModule {
 	e.mux.Lock()
 	defer e.mux.Unlock()
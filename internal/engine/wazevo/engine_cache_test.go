@@ -2,6 +2,7 @@ package wazevo
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"hash/crc32"
 	"io"
@@ -326,3 +327,45 @@ func Test_fileCacheKey(t *testing.T) {
 	require.Equal(t, original, m.ID)
 	require.NotEqual(t, original, result)
 }
+
+func TestEngine_SerializeCompiledModule(t *testing.T) {
+	ctx := context.Background()
+	m := &wasm.Module{
+		TypeSection:     []wasm.FunctionType{{}},
+		FunctionSection: []wasm.Index{0, 0},
+		CodeSection: []wasm.Code{
+			{Body: []byte{wasm.OpcodeEnd}},
+			{Body: []byte{wasm.OpcodeEnd}},
+		},
+		ID: wasm.ModuleID{1},
+	}
+
+	e := NewEngine(ctx, 0, nil).(*engine)
+	_, err := e.SerializeCompiledModule(m)
+	require.EqualError(t, err, "source module must be compiled before serialization")
+
+	require.NoError(t, e.CompileModule(ctx, m, nil, false))
+	data, err := e.SerializeCompiledModule(m)
+	require.NoError(t, err)
+
+	// Deserialize into a different engine, and ensure the result is the same.
+	other := NewEngine(ctx, 0, nil).(*engine)
+	require.NoError(t, other.DeserializeCompiledModule(m, nil, false, data))
+	require.Equal(t, uint32(1), other.CompiledModuleCount())
+	expected, _ := e.getCompiledModuleFromMemory(m, false)
+	actual, ok := other.getCompiledModuleFromMemory(m, false)
+	require.True(t, ok)
+	require.Equal(t, expected.functionOffsets, actual.functionOffsets)
+	require.Equal(t, expected.executable, actual.executable)
+
+	t.Run("stale", func(t *testing.T) {
+		stale := NewEngine(ctx, 0, nil).(*engine)
+		stale.wazeroVersion = "0.0.0"
+		err := stale.DeserializeCompiledModule(m, nil, false, data)
+		require.EqualError(t, err, "compiled module was not serialized by wazero 0.0.0")
+	})
+	t.Run("host module", func(t *testing.T) {
+		_, err := e.SerializeCompiledModule(&wasm.Module{IsHostModule: true})
+		require.EqualError(t, err, "host modules cannot be serialized")
+	})
+}
@@ -163,7 +163,11 @@ func (c *Compiler) lowerBody(entryBlk ssa.BasicBlock) {
 
 	for c.loweringState.pc < len(c.wasmFunctionBody) {
 		blkBeforeLowering := c.ssaBuilder.CurrentBlock()
+		pc := c.loweringState.pc
 		c.lowerCurrentOpcode()
+		if c.m.CanonicalizeNaN {
+			c.canonicalizeNaN(pc)
+		}
 		blkAfterLowering := c.ssaBuilder.CurrentBlock()
 		if blkBeforeLowering != blkAfterLowering {
 			// In Wasm, once a block exits, that means we've done compiling the block.
@@ -179,6 +183,54 @@ func (c *Compiler) state() *loweringState {
 	return &c.loweringState
 }
 
+// canonicalizeNaN replaces the result of the instruction at pc with the
+// canonical NaN of its type if it is NaN, as the bits of NaN results differ
+// by platform. See wasm.NaNResultShape.
+func (c *Compiler) canonicalizeNaN(pc int) {
+	state := c.state()
+	if state.unreachable {
+		return
+	}
+
+	op := c.wasmFunctionBody[pc]
+	var vecOp wasm.OpcodeVec
+	if op == wasm.OpcodeVecPrefix {
+		vecOp = c.wasmFunctionBody[pc+1]
+	}
+	shape := wasm.NaNResultShape(op, vecOp)
+	if shape == wasm.NaNShapeNone {
+		return
+	}
+
+	builder := c.ssaBuilder
+	v := state.pop()
+	switch shape {
+	case wasm.NaNShapeF32, wasm.NaNShapeF64:
+		var canonical ssa.Value
+		if shape == wasm.NaNShapeF32 {
+			canonical = builder.AllocateInstruction().
+				AsF32const(math.Float32frombits(wasm.CanonicalNaNF32)).Insert(builder).Return()
+		} else {
+			canonical = builder.AllocateInstruction().
+				AsF64const(math.Float64frombits(wasm.CanonicalNaNF64)).Insert(builder).Return()
+		}
+		// Only NaN isn't equal to itself.
+		isNotNaN := builder.AllocateInstruction()
+		isNotNaN.AsFcmp(v, v, ssa.FloatCmpCondEqual)
+		builder.InsertInstruction(isNotNaN)
+		v = builder.AllocateInstruction().AsSelect(isNotNaN.Return(), v, canonical).Insert(builder).Return()
+	case wasm.NaNShapeF32x4, wasm.NaNShapeF64x2:
+		lane, canonical := ssa.VecLaneF32x4, uint64(wasm.CanonicalNaNF32)<<32|uint64(wasm.CanonicalNaNF32)
+		if shape == wasm.NaNShapeF64x2 {
+			lane, canonical = ssa.VecLaneF64x2, wasm.CanonicalNaNF64
+		}
+		canonicals := builder.AllocateInstruction().AsVconst(canonical, canonical).Insert(builder).Return()
+		isNaN := builder.AllocateInstruction().AsVFcmp(v, v, ssa.FloatCmpCondNotEqual, lane).Insert(builder).Return()
+		v = builder.AllocateInstruction().AsVbitselect(isNaN, canonicals, v).Insert(builder).Return()
+	}
+	state.push(v)
+}
+
 func (c *Compiler) lowerCurrentOpcode() {
 	op := c.wasmFunctionBody[c.loweringState.pc]
 
@@ -339,6 +339,35 @@ func (m *moduleEngine) FunctionInstanceReference(funcIndex wasm.Index) wasm.Refe
 	return uintptr(unsafe.Pointer(lf))
 }
 
+// FunctionIndex implements wasm.ModuleEngine.
+func (m *moduleEngine) FunctionIndex(ref wasm.Reference) (wasm.Index, bool) {
+	if ref == 0 {
+		return 0, false
+	}
+	src := m.module.Source
+	for i := wasm.Index(0); i < src.ImportFunctionCount; i++ {
+		if begin, _, _ := m.parent.offsets.ImportedFunctionOffset(i); ref == uintptr(unsafe.Pointer(&m.opaque[begin])) {
+			return i, true
+		}
+	}
+
+	// Otherwise, ref is either a functionInstance or the imported function slot of another module's opaque buffer,
+	// which is laid out the same way. Compare the executable to rule out the latter.
+	tf := wazevoapi.PtrFromUintptr[functionInstance](ref)
+	if tf.moduleContextOpaquePtr != m.opaquePtr || tf.indexInModule < src.ImportFunctionCount {
+		return 0, false
+	}
+	localIndex := tf.indexInModule - src.ImportFunctionCount
+	if int(localIndex) >= len(src.FunctionSection) {
+		return 0, false
+	}
+	p := m.parent
+	if tf.executable != &p.executable[p.functionOffsets[localIndex]] {
+		return 0, false
+	}
+	return tf.indexInModule, true
+}
+
 // LookupFunction implements wasm.ModuleEngine.
 func (m *moduleEngine) LookupFunction(t *wasm.TableInstance, typeId wasm.FunctionTypeID, tableOffset wasm.Index) (*wasm.ModuleInstance, wasm.Index) {
 	if tableOffset >= uint32(len(t.References)) || t.Type != wasm.RefTypeFuncref {
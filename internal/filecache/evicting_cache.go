@@ -0,0 +1,223 @@
+package filecache
+
+import (
+	"container/list"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// Policy configures when NewEvicting removes entries from the underlying Cache.
+type Policy struct {
+	// MaxBytes bounds the total size of the content of all entries. When
+	// exceeded, the least recently used entries are deleted. Zero is unbounded.
+	MaxBytes int64
+
+	// TTL is the duration after which an entry not used by Get or Add is
+	// deleted. Zero never expires entries.
+	TTL time.Duration
+}
+
+// Stats are counters maintained by a Cache returned by NewEvicting.
+type Stats struct {
+	// Hits is the count of Get calls which found an entry.
+	Hits uint64
+	// Misses is the count of Get calls which found no entry, including
+	// expired ones.
+	Misses uint64
+	// Evictions is the count of entries deleted due to the Policy.
+	Evictions uint64
+	// Entries is the count of entries currently tracked.
+	Entries int
+	// Bytes is the total size of the content of entries currently tracked.
+	Bytes int64
+}
+
+// NewEvicting returns a Cache which deletes entries from the given one
+// according to the Policy.
+//
+// Only entries added or read through the returned Cache are tracked. For
+// example, entries persisted by a previous process are only accounted for
+// once read. The size of an entry read is its Stat or Size, such as for an
+// *os.File, otherwise the count of bytes read until it is closed.
+func NewEvicting(cache Cache, policy Policy) *Evicting {
+	return &Evicting{
+		cache:   cache,
+		policy:  policy,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: map[Key]*list.Element{},
+	}
+}
+
+// Evicting implements Cache by delegating to another, enforcing a Policy.
+type Evicting struct {
+	cache  Cache
+	policy Policy
+	// now is time.Now, but overridable for tests.
+	now func() time.Time
+
+	mux sync.Mutex
+	// lru is ordered from the most recently used *evictingEntry to the least.
+	lru     *list.List
+	entries map[Key]*list.Element
+	stats   Stats
+}
+
+type evictingEntry struct {
+	key      Key
+	size     int64
+	accessed time.Time
+}
+
+// Stats returns a snapshot of the counters of this cache.
+func (e *Evicting) Stats() Stats {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	ret := e.stats
+	ret.Entries = len(e.entries)
+	return ret
+}
+
+// Get implements Cache.Get
+func (e *Evicting) Get(key Key) (content io.ReadCloser, ok bool, err error) {
+	e.mux.Lock()
+	if elem, tracked := e.entries[key]; tracked && e.expired(elem.Value.(*evictingEntry)) {
+		err = e.evict(elem)
+		e.stats.Misses++
+		e.mux.Unlock()
+		return nil, false, err
+	}
+	e.mux.Unlock()
+
+	if content, ok, err = e.cache.Get(key); err != nil {
+		return
+	}
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	elem, tracked := e.entries[key]
+	switch {
+	case !ok:
+		e.stats.Misses++
+		if tracked { // deleted externally
+			e.remove(elem)
+		}
+	case tracked:
+		e.stats.Hits++
+		e.touch(elem)
+	default:
+		e.stats.Hits++
+		if size, known := contentSize(content); known {
+			if err = e.trackLocked(key, size); err != nil {
+				_ = content.Close()
+				return nil, false, err
+			}
+		} else { // Track the entry once its size is known.
+			content = &countingReadCloser{ReadCloser: content, onClose: func(n int64) { _ = e.track(key, n) }}
+		}
+	}
+	return
+}
+
+// contentSize returns the size of content, if known without reading it.
+func contentSize(content io.Reader) (int64, bool) {
+	switch c := content.(type) {
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if info, err := c.Stat(); err == nil {
+			return info.Size(), true
+		}
+	case interface{ Size() int64 }:
+		return c.Size(), true
+	}
+	return 0, false
+}
+
+// Add implements Cache.Add
+func (e *Evicting) Add(key Key, content io.Reader) (err error) {
+	counter := &countingReadCloser{ReadCloser: io.NopCloser(content)}
+	if err = e.cache.Add(key, counter); err != nil {
+		return
+	}
+	return e.track(key, counter.n)
+}
+
+// Delete implements Cache.Delete
+func (e *Evicting) Delete(key Key) (err error) {
+	e.mux.Lock()
+	if elem, ok := e.entries[key]; ok {
+		e.remove(elem)
+	}
+	e.mux.Unlock()
+	return e.cache.Delete(key)
+}
+
+// track records the entry as most recently used, then enforces the Policy.
+func (e *Evicting) track(key Key, size int64) (err error) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	return e.trackLocked(key, size)
+}
+
+// trackLocked is track, when mux is already locked.
+func (e *Evicting) trackLocked(key Key, size int64) (err error) {
+	if elem, ok := e.entries[key]; ok {
+		e.remove(elem)
+	}
+	e.entries[key] = e.lru.PushFront(&evictingEntry{key: key, size: size, accessed: e.now()})
+	e.stats.Bytes += size
+
+	for back := e.lru.Back(); back != nil; back = e.lru.Back() {
+		entry := back.Value.(*evictingEntry)
+		if !e.expired(entry) && (e.policy.MaxBytes == 0 || e.stats.Bytes <= e.policy.MaxBytes) {
+			break
+		}
+		if err = e.evict(back); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (e *Evicting) expired(entry *evictingEntry) bool {
+	return e.policy.TTL > 0 && e.now().Sub(entry.accessed) > e.policy.TTL
+}
+
+func (e *Evicting) touch(elem *list.Element) {
+	elem.Value.(*evictingEntry).accessed = e.now()
+	e.lru.MoveToFront(elem)
+}
+
+func (e *Evicting) evict(elem *list.Element) error {
+	e.remove(elem)
+	e.stats.Evictions++
+	return e.cache.Delete(elem.Value.(*evictingEntry).key)
+}
+
+func (e *Evicting) remove(elem *list.Element) {
+	entry := e.lru.Remove(elem).(*evictingEntry)
+	delete(e.entries, entry.key)
+	e.stats.Bytes -= entry.size
+}
+
+// countingReadCloser counts the bytes read, and passes the count to onClose.
+type countingReadCloser struct {
+	io.ReadCloser
+	n       int64
+	onClose func(n int64)
+}
+
+func (c *countingReadCloser) Read(p []byte) (n int, err error) {
+	n, err = c.ReadCloser.Read(p)
+	c.n += int64(n)
+	return
+}
+
+func (c *countingReadCloser) Close() error {
+	if c.onClose != nil {
+		c.onClose(c.n)
+		c.onClose = nil
+	}
+	return c.ReadCloser.Close()
+}
@@ -0,0 +1,124 @@
+package filecache
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestEvicting_MaxBytes(t *testing.T) {
+	mc := newMemoryCache()
+	e := NewEvicting(mc, Policy{MaxBytes: 10})
+
+	require.NoError(t, e.Add(Key{1}, bytes.NewReader(make([]byte, 4))))
+	require.NoError(t, e.Add(Key{2}, bytes.NewReader(make([]byte, 4))))
+	require.Equal(t, Stats{Entries: 2, Bytes: 8}, e.Stats())
+
+	// Use the first entry, so that the second is the least recently used.
+	requireGet(t, e, Key{1}, 4)
+
+	// Adding a third entry exceeds MaxBytes, so evicts the second.
+	require.NoError(t, e.Add(Key{3}, bytes.NewReader(make([]byte, 4))))
+	require.Equal(t, Stats{Hits: 1, Evictions: 1, Entries: 2, Bytes: 8}, e.Stats())
+	_, ok, err := mc.Get(Key{2})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, ok, err = e.Get(Key{2})
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, Stats{Hits: 1, Misses: 1, Evictions: 1, Entries: 2, Bytes: 8}, e.Stats())
+
+	// An entry larger than MaxBytes is not retained.
+	require.NoError(t, e.Add(Key{4}, bytes.NewReader(make([]byte, 11))))
+	require.Equal(t, Stats{Hits: 1, Misses: 1, Evictions: 4, Entries: 0, Bytes: 0}, e.Stats())
+}
+
+func TestEvicting_TTL(t *testing.T) {
+	mc := newMemoryCache()
+	e := NewEvicting(mc, Policy{TTL: time.Minute})
+	now := time.Unix(0, 0)
+	e.now = func() time.Time { return now }
+
+	require.NoError(t, e.Add(Key{1}, bytes.NewReader(make([]byte, 4))))
+	now = now.Add(30 * time.Second)
+	require.NoError(t, e.Add(Key{2}, bytes.NewReader(make([]byte, 4))))
+
+	// Using an entry extends its lifetime.
+	now = now.Add(40 * time.Second)
+	requireGet(t, e, Key{2}, 4)
+
+	// The first entry expired.
+	_, ok, err := e.Get(Key{1})
+	require.NoError(t, err)
+	require.False(t, ok)
+	_, ok, err = mc.Get(Key{1})
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, Stats{Hits: 1, Misses: 1, Evictions: 1, Entries: 1, Bytes: 4}, e.Stats())
+
+	// Expired entries are swept when adding others.
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, e.Add(Key{3}, bytes.NewReader(make([]byte, 2))))
+	require.Equal(t, Stats{Hits: 1, Misses: 1, Evictions: 2, Entries: 1, Bytes: 2}, e.Stats())
+}
+
+func TestEvicting_untracked(t *testing.T) {
+	// Add an entry before the evicting cache exists, like a previous process.
+	mc := newMemoryCache()
+	require.NoError(t, mc.Add(Key{1}, bytes.NewReader(make([]byte, 4))))
+
+	e := NewEvicting(mc, Policy{MaxBytes: 6})
+	require.Equal(t, Stats{}, e.Stats())
+
+	// Reading the entry tracks it.
+	requireGet(t, e, Key{1}, 4)
+	require.Equal(t, Stats{Hits: 1, Entries: 1, Bytes: 4}, e.Stats())
+
+	// Deleting it untracks it.
+	require.NoError(t, e.Delete(Key{1}))
+	require.Equal(t, Stats{Hits: 1}, e.Stats())
+}
+
+func TestEvicting_untrackedPartialRead(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		cache Cache
+	}{
+		{name: "memory", cache: newMemoryCache()},
+		{name: "file", cache: newFileCache(t.TempDir())},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.NoError(t, tc.cache.Add(Key{1}, bytes.NewReader(make([]byte, 4))))
+			require.NoError(t, tc.cache.Add(Key{2}, bytes.NewReader(make([]byte, 4))))
+
+			e := NewEvicting(tc.cache, Policy{MaxBytes: 6})
+
+			// The size is known on Get, even if the entry isn't read.
+			for _, key := range []Key{{1}, {2}} {
+				content, ok, err := e.Get(key)
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.NoError(t, content.Close())
+			}
+			require.Equal(t, Stats{Hits: 2, Evictions: 1, Entries: 1, Bytes: 4}, e.Stats())
+
+			_, ok, err := tc.cache.Get(Key{1})
+			require.NoError(t, err)
+			require.False(t, ok)
+		})
+	}
+}
+
+func requireGet(t *testing.T, c Cache, key Key, expectedLen int) {
+	content, ok, err := c.Get(key)
+	require.NoError(t, err)
+	require.True(t, ok)
+	b, err := io.ReadAll(content)
+	require.NoError(t, err)
+	require.NoError(t, content.Close())
+	require.Equal(t, expectedLen, len(b))
+}
@@ -0,0 +1,60 @@
+package filecache
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// NewMemory returns a new Cache which holds entries in memory, without bound.
+//
+// This is usually combined with NewEvicting, to bound its size.
+func NewMemory() Cache {
+	return newMemoryCache()
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: map[Key][]byte{}}
+}
+
+// memoryCache holds the content of each entry in a map.
+type memoryCache struct {
+	mux     sync.RWMutex
+	entries map[Key][]byte
+}
+
+func (mc *memoryCache) Get(key Key) (content io.ReadCloser, ok bool, err error) {
+	mc.mux.RLock()
+	defer mc.mux.RUnlock()
+	if b, ok := mc.entries[key]; ok {
+		return memoryContent{bytes.NewReader(b)}, true, nil
+	}
+	return nil, false, nil
+}
+
+func (mc *memoryCache) Add(key Key, content io.Reader) (err error) {
+	b, err := io.ReadAll(content)
+	if err != nil {
+		return
+	}
+	mc.mux.Lock()
+	defer mc.mux.Unlock()
+	mc.entries[key] = b
+	return
+}
+
+func (mc *memoryCache) Delete(key Key) (err error) {
+	mc.mux.Lock()
+	defer mc.mux.Unlock()
+	delete(mc.entries, key)
+	return
+}
+
+// memoryContent is the content of an entry, which is a bytes.Reader so that
+// its size is known without reading it.
+type memoryContent struct {
+	*bytes.Reader
+}
+
+// Close implements io.Closer
+func (memoryContent) Close() error { return nil }
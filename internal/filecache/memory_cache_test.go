@@ -0,0 +1,37 @@
+package filecache
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	mc := newMemoryCache()
+	id := Key{1, 2, 3}
+
+	_, ok, err := mc.Get(id)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	content := []byte{1, 2, 3, 4, 5}
+	require.NoError(t, mc.Add(id, bytes.NewReader(content)))
+
+	result, ok, err := mc.Get(id)
+	require.NoError(t, err)
+	require.True(t, ok)
+	actual, err := io.ReadAll(result)
+	require.NoError(t, err)
+	require.NoError(t, result.Close())
+	require.Equal(t, content, actual)
+
+	require.NoError(t, mc.Delete(id))
+	_, ok, err = mc.Get(id)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Deleting a non-existent entry is not an error.
+	require.NoError(t, mc.Delete(id))
+}
@@ -23,6 +23,8 @@ import (
 	"runtime"
 	"testing/fstest"
 	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 )
 
 var files = []struct {
@@ -110,6 +112,17 @@ func WriteTestFiles(tmpDir string) (err error) {
 	return
 }
 
+// WriteTestFilesFS writes files defined in FS to the given sys.FS. This is
+// used for implementations not backed by a directory, like a memory FS.
+func WriteTestFilesFS(testFS experimentalsys.FS) error {
+	for _, nf := range files {
+		if errno := writeTestFileFS(testFS, nf.name, nf.file); errno != 0 {
+			return errno
+		}
+	}
+	return nil
+}
+
 // TestFS runs fstest.TestFS on the given input which is either FS or includes
 // files written by WriteTestFiles.
 func TestFS(testfs fs.FS) error {
@@ -142,3 +155,31 @@ func writeTestFile(tmpDir, name string, file *fstest.MapFile) (err error) {
 	}
 	return os.Chtimes(fullPath, mtim, mtim)
 }
+
+func writeTestFileFS(testFS experimentalsys.FS, name string, file *fstest.MapFile) experimentalsys.Errno {
+	if mode := file.Mode; mode&fs.ModeDir != 0 {
+		if name != "." {
+			if errno := testFS.Mkdir(name, mode); errno != 0 {
+				return errno
+			}
+		}
+	} else {
+		f, errno := testFS.OpenFile(name, experimentalsys.O_WRONLY|experimentalsys.O_CREAT|experimentalsys.O_TRUNC, mode)
+		if errno != 0 {
+			return errno
+		}
+		_, errno = f.Write(file.Data)
+		if closeErrno := f.Close(); errno == 0 {
+			errno = closeErrno
+		}
+		if errno != 0 {
+			return errno
+		}
+	}
+
+	mtim := file.ModTime
+	if mtim.Unix() == 0 {
+		mtim = defaultTime
+	}
+	return testFS.Utimens(name, mtim.UnixNano(), mtim.UnixNano())
+}
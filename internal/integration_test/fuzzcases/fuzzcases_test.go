@@ -11,10 +11,11 @@ import (
 	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/internal/platform"
-	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/nodiff"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
 var ctx = context.Background()
@@ -349,15 +350,17 @@ func Test733(t *testing.T) {
 
 func Test873(t *testing.T) {
 	run(t, func(t *testing.T, r wazero.Runtime) {
+		// The element segment is out of bounds, which traps instead of panicking.
 		_, err := r.Instantiate(ctx, getWasmBinary(t, "873"))
-		require.NoError(t, err)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
 	})
 }
 
 func Test874(t *testing.T) {
 	run(t, func(t *testing.T, r wazero.Runtime) {
+		// The element segment is out of bounds, which traps instead of panicking.
 		_, err := r.Instantiate(ctx, getWasmBinary(t, "874"))
-		require.NoError(t, err)
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
 	})
 }
 
@@ -0,0 +1,29 @@
+package platform
+
+import "os"
+
+// MemoryImage is an immutable copy of a linear memory, which MapMemoryImage
+// maps copy-on-write, so that restoring it costs page table updates instead
+// of copying its content.
+type MemoryImage struct {
+	f *os.File
+	// len is the length of the content, before padding to a page size.
+	len int
+	// size is the length of the file, a multiple of the page size.
+	size int
+}
+
+// Len returns the length of the content of the image.
+func (i *MemoryImage) Len() int {
+	return i.len
+}
+
+// ReadAt implements io.ReaderAt, to read the content of the image.
+func (i *MemoryImage) ReadAt(p []byte, off int64) (int, error) {
+	return i.f.ReadAt(p, off)
+}
+
+// Close releases the image. Memory mapped from it is unaffected.
+func (i *MemoryImage) Close() error {
+	return i.f.Close()
+}
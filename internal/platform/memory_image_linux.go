@@ -0,0 +1,68 @@
+package platform
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// MemoryImageSupported is true when MmapLinearMemory, NewMemoryImage and
+// MapMemoryImage are implemented. This requires a 64-bit address space, as
+// linear memories are reserved at their maximum size.
+const MemoryImageSupported = unsafe.Sizeof(uintptr(0)) == 8
+
+// MmapLinearMemory reserves size bytes of zeroed memory, which doesn't consume
+// physical memory until written.
+func MmapLinearMemory(size int) ([]byte, error) {
+	return unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE,
+		unix.MAP_ANON|unix.MAP_PRIVATE|unix.MAP_NORESERVE)
+}
+
+// MunmapLinearMemory releases memory returned by MmapLinearMemory.
+func MunmapLinearMemory(b []byte) error {
+	return unix.Munmap(b)
+}
+
+// NewMemoryImage returns an image with the content of b, held in an anonymous
+// memory-backed file.
+func NewMemoryImage(b []byte) (*MemoryImage, error) {
+	fd, err := unix.MemfdCreate("wazero-memory", unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	f := os.NewFile(uintptr(fd), "wazero-memory")
+	pageSize := os.Getpagesize()
+	size := (len(b) + pageSize - 1) &^ (pageSize - 1)
+	if err = f.Truncate(int64(size)); err == nil {
+		_, err = f.WriteAt(b, 0)
+	}
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &MemoryImage{f: f, len: len(b), size: size}, nil
+}
+
+// MapMemoryImage replaces the content of b with a copy-on-write mapping of the
+// image, followed by zeros. Pages of b written since are discarded, and pages
+// of the image are only copied when written again.
+//
+// Note: b must be a page aligned part of memory returned by MmapLinearMemory,
+// and at least as long as the image.
+func MapMemoryImage(b []byte, image *MemoryImage) error {
+	const prot = unix.PROT_READ | unix.PROT_WRITE
+	if image.size > 0 {
+		if _, err := unix.MmapPtr(int(image.f.Fd()), 0, unsafe.Pointer(&b[0]), uintptr(image.size),
+			prot, unix.MAP_PRIVATE|unix.MAP_FIXED); err != nil {
+			return err
+		}
+	}
+	if rest := b[image.size:]; len(rest) > 0 {
+		if _, err := unix.MmapPtr(-1, 0, unsafe.Pointer(&rest[0]), uintptr(len(rest)),
+			prot, unix.MAP_ANON|unix.MAP_PRIVATE|unix.MAP_FIXED|unix.MAP_NORESERVE); err != nil {
+			return err
+		}
+	}
+	return nil
+}
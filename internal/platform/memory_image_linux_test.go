@@ -0,0 +1,46 @@
+package platform
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestMapMemoryImage(t *testing.T) {
+	if !MemoryImageSupported {
+		t.Skip()
+	}
+	pageSize := os.Getpagesize()
+
+	mem, err := MmapLinearMemory(pageSize * 4)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, MunmapLinearMemory(mem))
+	}()
+
+	image, err := NewMemoryImage([]byte("hello"))
+	require.NoError(t, err)
+	defer image.Close()
+	require.Equal(t, 5, image.Len())
+
+	content, err := io.ReadAll(io.NewSectionReader(image, 0, int64(image.Len())))
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), content)
+
+	// Dirty the pages of the image and beyond.
+	for i := range mem {
+		mem[i] = 0xff
+	}
+
+	require.NoError(t, MapMemoryImage(mem[:pageSize*3], image))
+	require.Equal(t, []byte("hello"), mem[:5])
+	require.Equal(t, make([]byte, pageSize*3-5), mem[5:pageSize*3])
+	require.Equal(t, byte(0xff), mem[pageSize*3], "beyond b must be unaffected")
+
+	// Writes are private, so don't change the image.
+	copy(mem, "jello")
+	require.NoError(t, MapMemoryImage(mem[:pageSize], image))
+	require.Equal(t, []byte("hello"), mem[:5])
+}
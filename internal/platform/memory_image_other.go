@@ -0,0 +1,34 @@
+//go:build !linux
+
+package platform
+
+import (
+	"errors"
+	"runtime"
+)
+
+// MemoryImageSupported is true when MmapLinearMemory, NewMemoryImage and
+// MapMemoryImage are implemented.
+const MemoryImageSupported = false
+
+var errMemoryImageUnsupported = errors.New("memory images unsupported on GOOS=" + runtime.GOOS)
+
+// MmapLinearMemory is not implemented on this platform.
+func MmapLinearMemory(int) ([]byte, error) {
+	return nil, errMemoryImageUnsupported
+}
+
+// MunmapLinearMemory is not implemented on this platform.
+func MunmapLinearMemory([]byte) error {
+	return errMemoryImageUnsupported
+}
+
+// NewMemoryImage is not implemented on this platform.
+func NewMemoryImage([]byte) (*MemoryImage, error) {
+	return nil, errMemoryImageUnsupported
+}
+
+// MapMemoryImage is not implemented on this platform.
+func MapMemoryImage([]byte, *MemoryImage) error {
+	return errMemoryImageUnsupported
+}
@@ -0,0 +1,103 @@
+package sys
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// AuditKey is a context.Context key for an *Audit, which receives an
+// AuditEvent for each filesystem, socket and process operation of guest
+// modules instantiated with it.
+type AuditKey struct{}
+
+// AuditEvent is an operation of a guest, such as opening a file.
+type AuditEvent struct {
+	// Time is when the operation completed, according to the host.
+	Time time.Time `json:"time"`
+
+	// Module is the name of the guest module.
+	Module string `json:"module"`
+
+	// Op is the name of the host function, such as "path_open".
+	Op string `json:"op"`
+
+	// Fd is the file descriptor operated on, or the directory a path is
+	// relative to. For example, this is the file descriptor opened by
+	// path_open, if it succeeded.
+	Fd int32 `json:"fd"`
+
+	// Path is the guest path of the file operated on, if known.
+	Path string `json:"path,omitempty"`
+
+	// HostPath is the path of Path on the host, if it is in a directory of
+	// the host.
+	HostPath string `json:"host_path,omitempty"`
+
+	// NewPath is the second guest path of operations such as path_rename,
+	// or the contents of the link for path_symlink.
+	NewPath string `json:"new_path,omitempty"`
+
+	// NewHostPath is the path of NewPath on the host, if it is in a
+	// directory of the host.
+	NewHostPath string `json:"new_host_path,omitempty"`
+
+	// Flags are the open flags of path_open.
+	Flags experimentalsys.Oflag `json:"flags,omitempty"`
+
+	// Bytes is the count of bytes transferred, such as read or written.
+	Bytes int64 `json:"bytes,omitempty"`
+
+	// ExitCode is the exit code of proc_exit.
+	ExitCode uint32 `json:"exit_code,omitempty"`
+
+	// Errno is the error returned to the guest, or zero on success.
+	Errno experimentalsys.Errno `json:"errno,omitempty"`
+}
+
+// Audit receives the AuditEvent of guests. It is safe for concurrent use.
+type Audit struct {
+	fn func(AuditEvent)
+}
+
+// NewAudit returns an Audit which calls fn with each event. fn must be safe
+// for concurrent use when guests run concurrently.
+func NewAudit(fn func(AuditEvent)) *Audit {
+	return &Audit{fn: fn}
+}
+
+// NewJSONAudit returns an Audit which writes each event to w as one line of
+// JSON. Errors writing are ignored.
+func NewJSONAudit(w io.Writer) *Audit {
+	var mu sync.Mutex
+	enc := json.NewEncoder(w)
+	return NewAudit(func(e AuditEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = enc.Encode(&e)
+	})
+}
+
+// Record calls the function of the audit with the event, after setting its
+// time.
+func (a *Audit) Record(e *AuditEvent) {
+	e.Time = time.Now()
+	a.fn(*e)
+}
+
+// SetAudit makes operations of this context be recorded to the audit.
+func (c *Context) SetAudit(a *Audit) {
+	c.audit = a
+}
+
+// Audit returns the audit set by SetAudit, or nil. This is nil safe, as a
+// host module can be called without a Context.
+func (c *Context) Audit() *Audit {
+	if c == nil {
+		return nil
+	}
+	return c.audit
+}
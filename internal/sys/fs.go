@@ -52,10 +52,27 @@ type FileEntry struct {
 	// File is always non-nil.
 	File sys.File
 
+	// Flag are the flags File was opened with by FSContext.OpenFile, which
+	// allow it to be reopened.
+	Flag sys.Oflag
+
+	// Rights are the WASI rights of the file, or nil if they aren't enforced.
+	// See sysfs.RightsFS
+	Rights *Rights
+
 	// direntCache is nil until DirentCache was called.
 	direntCache *DirentCache
 }
 
+// Rights are the WASI rights of a FileEntry, such as wasip1.RIGHT_FD_READ.
+type Rights struct {
+	// Base are the rights of the file itself.
+	Base uint32
+
+	// Inheriting are the maximum rights of files opened from this directory.
+	Inheriting uint32
+}
+
 // DirentCache gets or creates a DirentCache for this file or returns an error.
 //
 // # Errors
@@ -254,6 +271,27 @@ type FSContext struct {
 	// (or directories) and defaults to empty.
 	// TODO: This is unguarded, so not goroutine-safe!
 	openedFiles FileTable
+
+	// maxOpenFiles is the count of file descriptors that can be open, after
+	// which opening another fails with sys.EMFILE, or zero for no limit.
+	maxOpenFiles int
+}
+
+// SetMaxOpenFiles limits the count of file descriptors that can be open,
+// including stdio, pre-opens and sockets, or removes the limit if zero.
+//
+// Descriptors already open are kept, even if over the limit.
+func (c *FSContext) SetMaxOpenFiles(max int) {
+	c.maxOpenFiles = max
+}
+
+// checkOpenFiles returns sys.EMFILE if another file descriptor can't be
+// opened.
+func (c *FSContext) checkOpenFiles() sys.Errno {
+	if max := c.maxOpenFiles; max > 0 && c.openedFiles.Len() >= max {
+		return sys.EMFILE
+	}
+	return 0
 }
 
 // FileTable is a specialization of the descriptor.Table type used to map file
@@ -268,10 +306,13 @@ func (c *FSContext) LookupFile(fd int32) (*FileEntry, bool) {
 // OpenFile opens the file into the table and returns its file descriptor.
 // The result must be closed by CloseFile or Close.
 func (c *FSContext) OpenFile(fs sys.FS, path string, flag sys.Oflag, perm fs.FileMode) (int32, sys.Errno) {
+	if errno := c.checkOpenFiles(); errno != 0 {
+		return 0, errno
+	}
 	if f, errno := fs.OpenFile(path, flag, perm); errno != 0 {
 		return 0, errno
 	} else {
-		fe := &FileEntry{FS: fs, File: f}
+		fe := &FileEntry{FS: fs, File: f, Flag: flag}
 		if path == "/" || path == "." {
 			fe.Name = ""
 		} else {
@@ -323,6 +364,10 @@ func (c *FSContext) SockAccept(sockFD int32, nonblock bool) (int32, sys.Errno) {
 		return 0, sys.EBADF // Not a sock
 	}
 
+	// Check before accepting, so the connection stays pending.
+	if errno := c.checkOpenFiles(); errno != 0 {
+		return 0, errno
+	}
 	conn, errno := sock.Accept()
 	if errno != 0 {
 		return 0, errno
@@ -396,6 +441,7 @@ func (c *Context) InitFSContext(
 		return err
 	}
 	c.fsc.openedFiles.Insert(errWriter)
+	dupSharedStdio(inFile, outWriter, errWriter)
 
 	for i, f := range fs {
 		guestPath := guestPaths[i]
@@ -404,12 +450,16 @@ func (c *Context) InitFSContext(
 			// Default to bind to '/' when guestPath is effectively empty.
 			guestPath = "/"
 		}
-		c.fsc.openedFiles.Insert(&FileEntry{
+		fe := &FileEntry{
 			FS:        f,
 			Name:      guestPath,
 			IsPreopen: true,
 			File:      &lazyDir{fs: f},
-		})
+		}
+		if r := sysfs.FindRightsFS(f); r != nil {
+			fe.Rights = &Rights{Base: r.Base, Inheriting: r.Inheriting}
+		}
+		c.fsc.openedFiles.Insert(fe)
 	}
 
 	for _, tl := range tcpListeners {
@@ -0,0 +1,251 @@
+package sys
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// reopenFlagMask are the flags which only have an effect when a file is first
+// opened, so are cleared when reopening it.
+const reopenFlagMask = sys.O_CREAT | sys.O_EXCL | sys.O_TRUNC
+
+// maxPathLen bounds the length of a path read by RestoreSnapshot, which is
+// longer than PATH_MAX on common hosts.
+const maxPathLen = 1 << 16
+
+// fileSnapshot is the state of a file descriptor, encoded by
+// FSContext.WriteSnapshot.
+type fileSnapshot struct {
+	fd        int32
+	isPreopen bool
+	name      string
+	// preopen is the descriptor of the pre-open whose file system holds the
+	// file, unless isPreopen.
+	preopen  int32
+	flag     sys.Oflag
+	offset   int64
+	append   bool
+	nonblock bool
+}
+
+// WriteSnapshot encodes the files opened in this context, so that
+// RestoreSnapshot can reopen them in another context with the same pre-opens.
+//
+// Only descriptors from FdPreopen are encoded: stdio is left as configured.
+// Directory positions are not retained, so reading one restarts at its
+// beginning. Sockets cannot be encoded, so result in an error.
+//
+// The layout is a sequence of unsigned LEB128 integers: the count of files,
+// then for each its descriptor, whether it is a pre-open, the length of its
+// name then the name. Other files continue with the descriptor of their
+// pre-open, their open flags, their offset, and whether they are in append or
+// non-blocking mode.
+func (c *FSContext) WriteSnapshot(w io.Writer) error {
+	var files []*fileSnapshot
+	var err error
+	c.openedFiles.Range(func(fd int32, f *FileEntry) bool {
+		if fd < FdPreopen {
+			return true
+		}
+		var s *fileSnapshot
+		if s, err = c.snapshotFile(fd, f); err == nil {
+			files = append(files, s)
+		}
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+
+	b := binary.AppendUvarint(nil, uint64(len(files)))
+	for _, f := range files {
+		b = binary.AppendUvarint(b, uint64(f.fd))
+		b = appendBool(b, f.isPreopen)
+		b = binary.AppendUvarint(b, uint64(len(f.name)))
+		b = append(b, f.name...)
+		if !f.isPreopen {
+			b = binary.AppendUvarint(b, uint64(f.preopen))
+			b = binary.AppendUvarint(b, uint64(f.flag))
+			b = binary.AppendUvarint(b, uint64(f.offset))
+			b = appendBool(b, f.append)
+			b = appendBool(b, f.nonblock)
+		}
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+func (c *FSContext) snapshotFile(fd int32, f *FileEntry) (*fileSnapshot, error) {
+	if f.FS == nil {
+		return nil, fmt.Errorf("fd %d: sockets cannot be snapshotted", fd)
+	}
+	s := &fileSnapshot{fd: fd, isPreopen: f.IsPreopen, name: f.Name}
+	if f.IsPreopen {
+		return s, nil
+	}
+
+	var ok bool
+	if s.preopen, ok = c.preopenOf(f.FS); !ok {
+		return nil, fmt.Errorf("fd %d: file system is not pre-opened", fd)
+	}
+	s.flag = f.Flag &^ reopenFlagMask
+	s.append = f.File.IsAppend()
+	if pf, ok := f.File.(sys.PollableFile); ok {
+		s.nonblock = pf.IsNonblock()
+	}
+	if isDir, errno := f.File.IsDir(); errno != 0 {
+		return nil, fmt.Errorf("fd %d: %w", fd, errno)
+	} else if !isDir {
+		if s.offset, errno = f.File.Seek(0, io.SeekCurrent); errno != 0 {
+			return nil, fmt.Errorf("fd %d: %w", fd, errno)
+		}
+	}
+	return s, nil
+}
+
+// preopenOf returns the descriptor of the first pre-open of fs.
+func (c *FSContext) preopenOf(fs sys.FS) (fd int32, ok bool) {
+	// Guard comparison, as a sys.FS may be an uncomparable value type.
+	if !reflect.TypeOf(fs).Comparable() {
+		return 0, false
+	}
+	c.openedFiles.Range(func(preopen int32, f *FileEntry) bool {
+		if f.IsPreopen && f.FS == fs {
+			fd, ok = preopen, true
+		}
+		return !ok
+	})
+	return
+}
+
+// RestoreSnapshot reopens files encoded by WriteSnapshot, at the same file
+// descriptors. Any files opened in this context since pre-opening are closed
+// first, as well as pre-opens closed when the snapshot was taken.
+//
+// This returns an error if a pre-open in the snapshot isn't at the same file
+// descriptor and guest path in this context, or a file cannot be reopened.
+func (c *FSContext) RestoreSnapshot(r *bufio.Reader) error {
+	files, err := readFileSnapshots(r)
+	if err != nil {
+		return err
+	}
+
+	preopens := map[int32]struct{}{}
+	for _, s := range files {
+		if !s.isPreopen {
+			continue
+		}
+		if f, ok := c.openedFiles.Lookup(s.fd); !ok || !f.IsPreopen || f.Name != s.name {
+			return fmt.Errorf("fd %d: expected pre-open %q", s.fd, s.name)
+		}
+		preopens[s.fd] = struct{}{}
+	}
+
+	var toClose []int32
+	c.openedFiles.Range(func(fd int32, f *FileEntry) bool {
+		if _, ok := preopens[fd]; fd >= FdPreopen && !ok {
+			toClose = append(toClose, fd)
+		}
+		return true
+	})
+	for _, fd := range toClose {
+		if errno := c.CloseFile(fd); errno != 0 {
+			return fmt.Errorf("fd %d: %w", fd, errno)
+		}
+	}
+
+	for _, s := range files {
+		if !s.isPreopen {
+			if err = c.reopenFile(s); err != nil {
+				return fmt.Errorf("fd %d: %w", s.fd, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *FSContext) reopenFile(s *fileSnapshot) error {
+	preopen, ok := c.openedFiles.Lookup(s.preopen)
+	if !ok || !preopen.IsPreopen || preopen.FS == nil {
+		return fmt.Errorf("missing pre-open %d", s.preopen)
+	}
+	path := s.name
+	if path == "" {
+		path = "."
+	}
+	f, errno := preopen.FS.OpenFile(path, s.flag, 0)
+	if errno != 0 {
+		return errno
+	}
+	fe := &FileEntry{FS: preopen.FS, Name: s.name, File: f, Flag: s.flag}
+	if !c.openedFiles.InsertAt(fe, s.fd) {
+		_ = f.Close()
+		return sys.EBADF
+	}
+	if s.offset != 0 {
+		if _, errno = f.Seek(s.offset, io.SeekStart); errno != 0 {
+			return errno
+		}
+	}
+	if s.append != f.IsAppend() {
+		if errno = f.SetAppend(s.append); errno != 0 {
+			return errno
+		}
+	}
+	if pf, ok := f.(sys.PollableFile); ok && s.nonblock != pf.IsNonblock() {
+		if errno = pf.SetNonblock(s.nonblock); errno != 0 {
+			return errno
+		}
+	}
+	return nil
+}
+
+func readFileSnapshots(r *bufio.Reader) (files []*fileSnapshot, err error) {
+	uvarint := func() uint64 {
+		var v uint64
+		if err == nil {
+			v, err = binary.ReadUvarint(r)
+		}
+		return v
+	}
+	count := uvarint()
+	for i := uint64(0); i < count && err == nil; i++ {
+		s := &fileSnapshot{fd: int32(uvarint())}
+		s.isPreopen = uvarint() != 0
+		if n := uvarint(); n > maxPathLen {
+			err = fmt.Errorf("invalid name length %d", n)
+		} else if err == nil {
+			name := make([]byte, n)
+			_, err = io.ReadFull(r, name)
+			s.name = string(name)
+		}
+		if !s.isPreopen {
+			s.preopen = int32(uvarint())
+			s.flag = sys.Oflag(uvarint())
+			s.offset = int64(uvarint())
+			s.append = uvarint() != 0
+			s.nonblock = uvarint() != 0
+		}
+		if err == nil && (s.fd < FdPreopen || s.preopen < 0) {
+			err = errors.New("invalid file descriptor")
+		}
+		files = append(files, s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read files: %w", err)
+	}
+	return
+}
@@ -0,0 +1,120 @@
+package sys
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFSContext_Snapshot(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(path.Join(tmpDir, "file"), []byte("wazero"), 0o600))
+	require.NoError(t, os.Mkdir(path.Join(tmpDir, "dir"), 0o700))
+	dirFS := sysfs.DirFS(tmpDir)
+
+	c := Context{}
+	require.NoError(t, c.InitFSContext(nil, nil, nil, []sys.FS{dirFS}, []string{"/"}, nil))
+	fsc := c.fsc
+	defer fsc.Close()
+
+	fileFD, errno := fsc.OpenFile(dirFS, "file", sys.O_RDWR|sys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+	f, _ := fsc.LookupFile(fileFD)
+	_, errno = f.File.Seek(2, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.File.SetAppend(true))
+
+	dirFD, errno := fsc.OpenFile(dirFS, "dir", sys.O_RDONLY|sys.O_DIRECTORY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, fsc.Renumber(dirFD, 10))
+
+	var buf bytes.Buffer
+	require.NoError(t, fsc.WriteSnapshot(&buf))
+
+	restored := Context{}
+	require.NoError(t, restored.InitFSContext(nil, nil, nil, []sys.FS{dirFS}, []string{"/"}, nil))
+	rfsc := restored.fsc
+	defer rfsc.Close()
+
+	// A file opened before restoring is closed.
+	openedFD, errno := rfsc.OpenFile(dirFS, "file", sys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+
+	require.NoError(t, rfsc.RestoreSnapshot(bufio.NewReader(&buf)))
+
+	rf, ok := rfsc.LookupFile(fileFD)
+	require.True(t, ok)
+	require.Equal(t, "file", rf.Name)
+	require.Equal(t, sys.O_RDWR, rf.Flag)
+	require.True(t, rf.File.IsAppend())
+	offset, errno := rf.File.Seek(0, io.SeekCurrent)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(2), offset)
+
+	rd, ok := rfsc.LookupFile(10)
+	require.True(t, ok)
+	isDir, errno := rd.File.IsDir()
+	require.EqualErrno(t, 0, errno)
+	require.True(t, isDir)
+
+	if openedFD != fileFD {
+		_, ok = rfsc.LookupFile(openedFD)
+		require.False(t, ok)
+	}
+}
+
+func TestFSContext_RestoreSnapshot_Errors(t *testing.T) {
+	dirFS := sysfs.DirFS(t.TempDir())
+
+	c := Context{}
+	require.NoError(t, c.InitFSContext(nil, nil, nil, []sys.FS{dirFS}, []string{"/"}, nil))
+	defer c.fsc.Close()
+	var buf bytes.Buffer
+	require.NoError(t, c.fsc.WriteSnapshot(&buf))
+	snapshot := buf.Bytes()
+
+	tests := []struct {
+		name        string
+		guestPath   string
+		input       []byte
+		expectedErr string
+	}{
+		{
+			name:        "different pre-open",
+			guestPath:   "/tmp",
+			input:       snapshot,
+			expectedErr: `fd 3: expected pre-open "/"`,
+		},
+		{
+			name:        "truncated",
+			guestPath:   "/",
+			input:       snapshot[:len(snapshot)-1],
+			expectedErr: "failed to read files: EOF",
+		},
+		{
+			name:        "invalid file descriptor",
+			guestPath:   "/",
+			input:       []byte{1, 0, 1, 0},
+			expectedErr: "failed to read files: invalid file descriptor",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			restored := Context{}
+			require.NoError(t, restored.InitFSContext(nil, nil, nil, []sys.FS{dirFS}, []string{tc.guestPath}, nil))
+			defer restored.fsc.Close()
+
+			err := restored.fsc.RestoreSnapshot(bufio.NewReader(bytes.NewReader(tc.input)))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
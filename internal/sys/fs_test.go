@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"net"
 	"os"
 	"path"
 	"testing"
@@ -129,6 +130,49 @@ func TestFSContext_CloseFile(t *testing.T) {
 	})
 }
 
+func TestFSContext_SetMaxOpenFiles(t *testing.T) {
+	embedFS, err := fs.Sub(testdata, "testdata")
+	require.NoError(t, err)
+	testFS := &sysfs.AdaptFS{FS: embedFS}
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listen.Close()
+
+	c := Context{}
+	err = c.InitFSContext(nil, nil, nil, []sys.FS{testFS}, []string{"/"}, []*net.TCPListener{listen.(*net.TCPListener)})
+	require.NoError(t, err)
+	fsc := c.fsc
+	defer fsc.Close()
+
+	// stdio, the pre-open and the listener count.
+	fsc.SetMaxOpenFiles(6)
+	fd, errno := fsc.OpenFile(testFS, "empty.txt", sys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	_, errno = fsc.OpenFile(testFS, "test.txt", sys.O_RDONLY, 0)
+	require.EqualErrno(t, sys.EMFILE, errno)
+
+	// Accepting a connection fails the same way, but leaves it pending.
+	conn, err := net.Dial("tcp", listen.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, errno = fsc.SockAccept(FdPreopen+1, false)
+	require.EqualErrno(t, sys.EMFILE, errno)
+
+	// Closing a file makes room for another.
+	require.EqualErrno(t, 0, fsc.CloseFile(fd))
+	connFD, errno := fsc.SockAccept(FdPreopen+1, false)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, fsc.CloseFile(connFD))
+
+	// Zero removes the limit.
+	fsc.SetMaxOpenFiles(0)
+	for i := 0; i < 2; i++ {
+		_, errno = fsc.OpenFile(testFS, "test.txt", sys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+	}
+}
+
 func TestFSContext_noPreopens(t *testing.T) {
 	c := Context{}
 	err := c.InitFSContext(nil, nil, nil, nil, nil, nil)
@@ -3,6 +3,7 @@ package sys
 import (
 	"io"
 	"os"
+	"reflect"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	"github.com/tetratelabs/wazero/internal/sysfs"
@@ -48,6 +49,14 @@ func (f *writerFile) Write(buf []byte) (int, experimentalsys.Errno) {
 	return n, experimentalsys.UnwrapOSError(err)
 }
 
+// Poll implements the same method as documented on experimentalsys.Pollable
+func (f *writerFile) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if p, ok := f.w.(experimentalsys.Pollable); ok {
+		return p.Poll(flag, timeoutMillis)
+	}
+	return f.noopStdoutFile.Poll(flag, timeoutMillis)
+}
+
 // noopStdinFile is a fs.ModeDevice file for use implementing FdStdin. This is
 // safer than reading from os.DevNull as it can never overrun operating system
 // file descriptors.
@@ -79,6 +88,14 @@ func (noopStdoutFile) Write(buf []byte) (int, experimentalsys.Errno) {
 	return len(buf), 0 // same as io.Discard
 }
 
+// Poll implements the same method as documented on experimentalsys.Pollable
+func (noopStdoutFile) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if flag != experimentalsys.POLLOUT {
+		return false, experimentalsys.ENOTSUP
+	}
+	return true, 0 // always ready to write, as writes don't block
+}
+
 type noopStdioFile struct {
 	experimentalsys.UnimplementedFile
 }
@@ -99,6 +116,8 @@ func (noopStdioFile) Close() (errno experimentalsys.Errno) { return }
 func stdinFileEntry(r io.Reader) (*FileEntry, error) {
 	if r == nil {
 		return &FileEntry{Name: "stdin", IsPreopen: true, File: &noopStdinFile{}}, nil
+	} else if fio, ok := r.(*sysfs.FileIO); ok {
+		return &FileEntry{Name: "stdin", IsPreopen: true, File: fio.File}, nil
 	} else if f, ok := r.(*os.File); ok {
 		if f, err := sysfs.NewStdioFile(true, f); err != nil {
 			return nil, err
@@ -113,6 +132,8 @@ func stdinFileEntry(r io.Reader) (*FileEntry, error) {
 func stdioWriterFileEntry(name string, w io.Writer) (*FileEntry, error) {
 	if w == nil {
 		return &FileEntry{Name: name, IsPreopen: true, File: &noopStdoutFile{}}, nil
+	} else if fio, ok := w.(*sysfs.FileIO); ok {
+		return &FileEntry{Name: name, IsPreopen: true, File: fio.File}, nil
 	} else if f, ok := w.(*os.File); ok {
 		if f, err := sysfs.NewStdioFile(false, f); err != nil {
 			return nil, err
@@ -123,3 +144,23 @@ func stdioWriterFileEntry(name string, w io.Writer) (*FileEntry, error) {
 		return &FileEntry{Name: name, IsPreopen: true, File: &writerFile{w: w}}, nil
 	}
 }
+
+// dupSharedStdio replaces a file configured for more than one stdio stream,
+// such as a pseudo-terminal, with a duplicate if it supports that. This
+// allows the guest to close one stream without closing the others.
+func dupSharedStdio(entries ...*FileEntry) {
+	files := make([]experimentalsys.File, len(entries))
+	for i, e := range entries {
+		files[i] = e.File
+		d, ok := e.File.(interface{ Dup() experimentalsys.File })
+		if !ok || !reflect.TypeOf(e.File).Comparable() {
+			continue
+		}
+		for _, f := range files[:i] {
+			if f == e.File {
+				e.File = d.Dup()
+				break
+			}
+		}
+	}
+}
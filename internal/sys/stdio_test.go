@@ -1,12 +1,14 @@
 package sys
 
 import (
+	"io"
 	"io/fs"
 	"os"
 	"strings"
 	"testing"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 )
 
@@ -65,6 +67,37 @@ func TestStdinFilePoll_NonPollable(t *testing.T) {
 	require.False(t, ready)
 }
 
+func TestWriterFilePoll(t *testing.T) {
+	timeout := int32(0) // return immediately
+
+	// Writes to an io.Writer don't block, so it is always ready.
+	f := &writerFile{w: &strings.Builder{}}
+	ready, errno := f.Poll(experimentalsys.POLLOUT, timeout)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	// POLLIN is not supported, as the file can't be read.
+	ready, errno = f.Poll(experimentalsys.POLLIN, timeout)
+	require.EqualErrno(t, experimentalsys.ENOTSUP, errno)
+	require.False(t, ready)
+
+	// When the writer implements Pollable, Poll delegates to it.
+	f = &writerFile{w: &pollableWriter{}}
+	ready, errno = f.Poll(experimentalsys.POLLOUT, timeout)
+	require.EqualErrno(t, 0, errno)
+	require.False(t, ready)
+}
+
+// pollableWriter is a mock io.Writer that implements experimentalsys.Pollable,
+// and is never ready.
+type pollableWriter struct {
+	strings.Builder
+}
+
+func (w *pollableWriter) Poll(experimentalsys.Pflag, int32) (bool, experimentalsys.Errno) {
+	return false, 0
+}
+
 func TestStdio(t *testing.T) {
 	// simulate regular file attached to stdin
 	f, err := os.CreateTemp(t.TempDir(), "somefile")
@@ -193,3 +226,31 @@ func TestStdio(t *testing.T) {
 		}
 	}
 }
+
+func TestStdio_FileIO(t *testing.T) {
+	host, guest := sysfs.NewPTY()
+	defer host.Close()
+
+	tty := &sysfs.FileIO{File: guest}
+	c := Context{}
+	require.NoError(t, c.InitFSContext(tty, tty, tty, nil, nil, nil))
+	fsc := c.FS()
+
+	// The guest uses the file as-is.
+	stdin, ok := fsc.LookupFile(FdStdin)
+	require.True(t, ok)
+	require.Equal(t, guest, stdin.File)
+
+	// Each stream has its own handle, so closing one doesn't close the others.
+	require.EqualErrno(t, 0, fsc.CloseFile(FdStdout))
+	stderr, ok := fsc.LookupFile(FdStderr)
+	require.True(t, ok)
+	_, errno := stderr.File.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+
+	// The host reads EOF after the guest closes.
+	require.NoError(t, c.FS().Close())
+	b, err := io.ReadAll(&sysfs.FileIO{File: host})
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+}
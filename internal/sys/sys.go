@@ -26,6 +26,9 @@ type Context struct {
 	osyield            sys.Osyield
 	randSource         io.Reader
 	fsc                FSContext
+	trace              *Trace
+	vclock             *VirtualClock
+	audit              *Audit
 }
 
 // Args is like os.Args and defaults to nil.
@@ -64,6 +67,13 @@ func (c *Context) EnvironSize() uint32 {
 
 // Walltime implements platform.Walltime.
 func (c *Context) Walltime() (sec int64, nsec int32) {
+	if c.trace != nil {
+		ns := c.trace.traceTime(TraceWalltime, func() int64 {
+			sec, nsec := c.walltime()
+			return (sec * time.Second.Nanoseconds()) + int64(nsec)
+		})
+		return ns / time.Second.Nanoseconds(), int32(ns % time.Second.Nanoseconds())
+	}
 	return c.walltime()
 }
 
@@ -80,6 +90,9 @@ func (c *Context) WalltimeResolution() sys.ClockResolution {
 
 // Nanotime implements sys.Nanotime.
 func (c *Context) Nanotime() int64 {
+	if c.trace != nil {
+		return c.trace.traceTime(TraceNanotime, c.nanotime)
+	}
 	return c.nanotime()
 }
 
@@ -93,6 +106,33 @@ func (c *Context) Nanosleep(ns int64) {
 	c.nanosleep(ns)
 }
 
+// NanosleepOrDone is like Nanosleep, except it returns false if done is
+// closed before ns have elapsed.
+//
+// Note: A sys.Nanosleep can't be interrupted, so it continues in the
+// background until ns have elapsed. This isn't the case with a VirtualClock.
+func (c *Context) NanosleepOrDone(ns int64, done <-chan struct{}) bool {
+	if c.vclock != nil {
+		return c.vclock.Sleep(ns, done)
+	} else if done == nil {
+		c.nanosleep(ns)
+		return true
+	}
+
+	slept := make(chan struct{})
+	go func() {
+		c.nanosleep(ns)
+		close(slept)
+	}()
+
+	select {
+	case <-slept:
+		return true
+	case <-done:
+		return false
+	}
+}
+
 // Osyield implements sys.Osyield.
 func (c *Context) Osyield() {
 	c.osyield()
@@ -106,6 +146,9 @@ func (c *Context) FS() *FSContext {
 // RandSource is a source of random bytes and defaults to a deterministic source.
 // see wazero.ModuleConfig WithRandSource
 func (c *Context) RandSource() io.Reader {
+	if c.trace != nil {
+		return &traceRandSource{t: c.trace, r: c.randSource}
+	}
 	return c.randSource
 }
 
@@ -265,6 +265,25 @@ func TestNewContext_Nanosleep(t *testing.T) {
 	require.Equal(t, aNs, sysCtx.nanosleep)
 }
 
+func TestContext_NanosleepOrDone(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	var ns sys.Nanosleep = func(n int64) {
+		if n < 0 {
+			<-block // sleeps until the test ends
+		}
+	}
+	sysCtx, err := NewContext(0, nil, nil, nil, nil, nil, nil, nil, 0, nil, 0, ns, nil, nil, nil, nil)
+	require.Nil(t, err)
+
+	require.True(t, sysCtx.NanosleepOrDone(1, nil))
+	require.True(t, sysCtx.NanosleepOrDone(1, make(chan struct{})))
+
+	done := make(chan struct{})
+	close(done)
+	require.False(t, sysCtx.NanosleepOrDone(-1, done))
+}
+
 func TestNewContext_Osyield(t *testing.T) {
 	var oy sys.Osyield = func() {}
 	sysCtx, err := NewContext(0, nil, nil, nil, nil, nil, nil, nil, 0, nil, 0, nil, oy, nil, nil, nil)
@@ -0,0 +1,279 @@
+package sys
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+)
+
+// TraceKey is a context.Context key for a *Trace, which records or replays
+// the nondeterministic inputs of guest modules instantiated with it.
+type TraceKey struct{}
+
+// Kinds of TraceEvent.
+const (
+	TraceArgs     = "args"
+	TraceEnviron  = "environ"
+	TraceWalltime = "walltime"
+	TraceNanotime = "nanotime"
+	TraceRandom   = "random"
+	TraceRead     = "read"
+	TracePread    = "pread"
+	TracePeek     = "peek"
+	TracePoll     = "poll"
+)
+
+// TraceEvent is a nondeterministic input returned to the guest, encoded as
+// one line of JSON in a trace.
+type TraceEvent struct {
+	// Kind is the kind of input, such as TraceNanotime.
+	Kind string `json:"kind"`
+
+	// Fd is the file descriptor read or polled, if any.
+	Fd int32 `json:"fd,omitempty"`
+
+	// Size is the count of bytes the guest requested, or the count of
+	// subscriptions for TracePoll. A different size when replaying is a
+	// divergence.
+	Size int `json:"size,omitempty"`
+
+	// Value is the time for TraceWalltime and TraceNanotime.
+	Value int64 `json:"value,omitempty"`
+
+	// Data are the bytes read.
+	Data []byte `json:"data,omitempty"`
+
+	// Values are the values of TraceArgs and TraceEnviron.
+	Values [][]byte `json:"values,omitempty"`
+
+	// Polls are the results of each event of TracePoll.
+	Polls []TracePollResult `json:"polls,omitempty"`
+
+	// Errno is the error returned with the input.
+	Errno experimentalsys.Errno `json:"errno,omitempty"`
+}
+
+// TracePollResult is the result of one sysfs.PollEvent in a TraceEvent.
+type TracePollResult struct {
+	Revents experimentalsys.Pflag `json:"revents,omitempty"`
+	Errno   experimentalsys.Errno `json:"errno,omitempty"`
+}
+
+// TraceDivergenceError is returned when replaying a trace, and the guest
+// requests a different input than the one recorded.
+type TraceDivergenceError struct {
+	// Index is the zero-based index of the event in the trace.
+	Index int
+
+	// Expected is the event recorded, or nil if the trace ended.
+	Expected *TraceEvent
+
+	// Actual describes the input requested by the guest. Only Kind, Fd and
+	// Size are set.
+	Actual TraceEvent
+}
+
+// Error implements error.
+func (e *TraceDivergenceError) Error() string {
+	if e.Expected == nil {
+		return fmt.Sprintf("replay diverged at event %d: trace ended, but guest requested %s", e.Index, e.Actual.describe())
+	}
+	return fmt.Sprintf("replay diverged at event %d: trace has %s, but guest requested %s",
+		e.Index, e.Expected.describe(), e.Actual.describe())
+}
+
+func (e *TraceEvent) describe() string {
+	s := e.Kind
+	if e.Fd != 0 {
+		s += fmt.Sprintf(" fd=%d", e.Fd)
+	}
+	if e.Size != 0 {
+		s += fmt.Sprintf(" size=%d", e.Size)
+	}
+	return s
+}
+
+// Trace records the nondeterministic inputs of a guest, or replays them from
+// a previous recording. Events are ordered, so a Trace should only be used
+// with one module at a time.
+type Trace struct {
+	mu    sync.Mutex
+	enc   *json.Encoder // non-nil when recording
+	dec   *json.Decoder // non-nil when replaying
+	index int
+}
+
+// NewTraceRecorder returns a Trace which records events to w.
+func NewTraceRecorder(w io.Writer) *Trace {
+	return &Trace{enc: json.NewEncoder(w)}
+}
+
+// NewTraceReplayer returns a Trace which replays the events recorded in r.
+func NewTraceReplayer(r io.Reader) *Trace {
+	return &Trace{dec: json.NewDecoder(r)}
+}
+
+// Replaying returns true if the trace replays inputs, so that the host
+// shouldn't use or wait on the real ones.
+func (t *Trace) Replaying() bool {
+	return t != nil && t.dec != nil
+}
+
+// event records the event completed by fn, or replays the next one, which
+// must have the same Kind, Fd and Size as actual.
+func (t *Trace) event(actual TraceEvent, fn func(*TraceEvent)) (TraceEvent, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	index := t.index
+	t.index++
+
+	if t.enc != nil {
+		fn(&actual)
+		if err := t.enc.Encode(&actual); err != nil {
+			return actual, fmt.Errorf("failed to record event %d: %w", index, err)
+		}
+		return actual, nil
+	}
+
+	var expected TraceEvent
+	if err := t.dec.Decode(&expected); errors.Is(err, io.EOF) {
+		return actual, &TraceDivergenceError{Index: index, Actual: actual}
+	} else if err != nil {
+		return actual, fmt.Errorf("failed to replay event %d: %w", index, err)
+	}
+	if expected.Kind != actual.Kind || expected.Fd != actual.Fd || expected.Size != actual.Size {
+		return actual, &TraceDivergenceError{Index: index, Expected: &expected, Actual: actual}
+	}
+	return expected, nil
+}
+
+// mustEvent is like event, except it panics on error. This is used in
+// functions called by host functions, which return the panic as an error.
+func (t *Trace) mustEvent(actual TraceEvent, fn func(*TraceEvent)) TraceEvent {
+	e, err := t.event(actual, fn)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// SetTrace records the args and environ of the context to the trace, or
+// replaces them with the ones replayed, then records or replays inputs
+// returned to the guest.
+func (c *Context) SetTrace(t *Trace) (err error) {
+	var e TraceEvent
+	if e, err = t.event(TraceEvent{Kind: TraceArgs}, func(e *TraceEvent) { e.Values = c.args }); err != nil {
+		return
+	}
+	c.args = e.Values
+	if c.argsSize, err = nullTerminatedByteCount(math.MaxUint32, c.args); err != nil {
+		return fmt.Errorf("args invalid: %w", err)
+	}
+
+	if e, err = t.event(TraceEvent{Kind: TraceEnviron}, func(e *TraceEvent) { e.Values = c.environ }); err != nil {
+		return
+	}
+	c.environ = e.Values
+	if c.environSize, err = nullTerminatedByteCount(math.MaxUint32, c.environ); err != nil {
+		return fmt.Errorf("environ invalid: %w", err)
+	}
+
+	c.trace = t
+	return nil
+}
+
+// Replaying returns true if inputs are replayed from a trace.
+func (c *Context) Replaying() bool {
+	return c.trace.Replaying()
+}
+
+// TraceReader returns a reader which records or replays the data read from
+// the file, or reader itself if there is no trace. kind is TraceRead,
+// TracePread or TracePeek, where only TraceRead changes the offset of the
+// file.
+//
+// When replaying, the file isn't read. Instead, the offset is advanced by
+// the count of bytes replayed, when the file supports it.
+func (c *Context) TraceReader(fd int32, f experimentalsys.File, kind string,
+	reader func([]byte) (int, experimentalsys.Errno),
+) func([]byte) (int, experimentalsys.Errno) {
+	t := c.trace
+	if t == nil {
+		return reader
+	}
+	return func(buf []byte) (int, experimentalsys.Errno) {
+		e := t.mustEvent(TraceEvent{Kind: kind, Fd: fd, Size: len(buf)}, func(e *TraceEvent) {
+			n, errno := reader(buf)
+			e.Data, e.Errno = buf[:n], errno
+		})
+		if t.Replaying() {
+			copy(buf, e.Data)
+			if kind == TraceRead && len(e.Data) > 0 {
+				_, _ = f.Seek(int64(len(e.Data)), io.SeekCurrent)
+			}
+		}
+		return len(e.Data), e.Errno
+	}
+}
+
+// TracePoll records or replays the results of poll, which polls the events.
+func (c *Context) TracePoll(events []sysfs.PollEvent, poll func() experimentalsys.Errno) experimentalsys.Errno {
+	t := c.trace
+	if t == nil {
+		return poll()
+	}
+	e := t.mustEvent(TraceEvent{Kind: TracePoll, Size: len(events)}, func(e *TraceEvent) {
+		if e.Errno = poll(); e.Errno == 0 {
+			e.Polls = make([]TracePollResult, len(events))
+			for i := range events {
+				e.Polls[i] = TracePollResult{Revents: events[i].Revents, Errno: events[i].Errno}
+			}
+		}
+	})
+	if t.Replaying() && e.Errno == 0 {
+		if len(e.Polls) != len(events) {
+			panic(fmt.Errorf("invalid poll event: expected %d results, but have %d", len(events), len(e.Polls)))
+		}
+		for i, p := range e.Polls {
+			events[i].Revents, events[i].Errno = p.Revents, p.Errno
+		}
+	}
+	return e.Errno
+}
+
+// traceTime records or replays the time returned by now.
+func (t *Trace) traceTime(kind string, now func() int64) int64 {
+	return t.mustEvent(TraceEvent{Kind: kind}, func(e *TraceEvent) { e.Value = now() }).Value
+}
+
+// traceRandSource records or replays the bytes read from an io.Reader.
+type traceRandSource struct {
+	t *Trace
+	r io.Reader
+}
+
+// Read implements io.Reader
+func (r *traceRandSource) Read(buf []byte) (int, error) {
+	var err error
+	e := r.t.mustEvent(TraceEvent{Kind: TraceRandom, Size: len(buf)}, func(e *TraceEvent) {
+		var n int
+		if n, err = r.r.Read(buf); err != nil {
+			e.Errno = experimentalsys.EIO
+		}
+		e.Data = buf[:n]
+	})
+	if r.t.Replaying() {
+		copy(buf, e.Data)
+		if e.Errno != 0 {
+			err = e.Errno
+		}
+	}
+	return len(e.Data), err
+}
@@ -0,0 +1,73 @@
+package sys
+
+import (
+	"bytes"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestContext_TracePoll(t *testing.T) {
+	var trace bytes.Buffer
+	sysCtx := DefaultContext(nil)
+	require.NoError(t, sysCtx.SetTrace(NewTraceRecorder(&trace)))
+
+	events := []sysfs.PollEvent{{Flag: experimentalsys.POLLIN}, {Flag: experimentalsys.POLLOUT}}
+	errno := sysCtx.TracePoll(events, func() experimentalsys.Errno {
+		events[0].Revents = experimentalsys.POLLIN
+		events[1].Errno = experimentalsys.EBADF
+		return 0
+	})
+	require.EqualErrno(t, 0, errno)
+
+	sysCtx = DefaultContext(nil)
+	require.NoError(t, sysCtx.SetTrace(NewTraceReplayer(&trace)))
+	require.True(t, sysCtx.Replaying())
+
+	// Polling isn't done when replaying.
+	replayed := []sysfs.PollEvent{{Flag: experimentalsys.POLLIN}, {Flag: experimentalsys.POLLOUT}}
+	errno = sysCtx.TracePoll(replayed, func() experimentalsys.Errno {
+		t.Fatal("unexpected poll")
+		return 0
+	})
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, events, replayed)
+}
+
+func TestContext_TraceReader(t *testing.T) {
+	testFS := sysfs.NewMemFS()
+	f, errno := testFS.OpenFile("file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	var trace bytes.Buffer
+	sysCtx := DefaultContext(nil)
+	require.NoError(t, sysCtx.SetTrace(NewTraceRecorder(&trace)))
+
+	buf := make([]byte, 4)
+	reader := sysCtx.TraceReader(3, f, TraceRead, func(buf []byte) (int, experimentalsys.Errno) {
+		return copy(buf, "waz"), 0
+	})
+	n, errno := reader(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 3, n)
+
+	sysCtx = DefaultContext(nil)
+	require.NoError(t, sysCtx.SetTrace(NewTraceReplayer(&trace)))
+
+	buf = make([]byte, 4)
+	n, errno = sysCtx.TraceReader(3, f, TraceRead, f.Read)(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "waz", string(buf[:n]))
+
+	// The offset is advanced as if the file was read.
+	offset, errno := f.Seek(0, 1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(3), offset)
+
+	// Reading a different file diverges.
+	err := require.CapturePanic(func() { _, _ = sysCtx.TraceReader(4, f, TraceRead, f.Read)(buf) })
+	require.EqualError(t, err, "replay diverged at event 3: trace ended, but guest requested read fd=4 size=4")
+}
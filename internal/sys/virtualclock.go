@@ -0,0 +1,255 @@
+package sys
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/sysfs"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// VirtualClockKey is a context.Context key for a *VirtualClock, which drives
+// the clocks and sleeps of guest modules instantiated with it.
+type VirtualClockKey struct{}
+
+// VirtualClock is a clock which only advances when told to, or when all
+// attached guests are sleeping, if auto-advance is enabled.
+//
+// Walltime and Nanotime advance together, and sleeps end when the clock
+// reaches their deadline instead of after real time elapses.
+type VirtualClock struct {
+	mu    sync.Mutex
+	start int64 // epoch nanoseconds when the clock was created
+	now   int64 // epoch nanoseconds
+
+	autoAdvance bool
+
+	// guests is the count of attached guests which aren't closed.
+	guests int
+
+	// sleepers are waiting for the clock to reach their deadline.
+	sleepers []*sleeper
+
+	// changed is closed and replaced when sleepers change.
+	changed chan struct{}
+}
+
+type sleeper struct {
+	deadline int64
+	woken    chan struct{}
+}
+
+// NewVirtualClock returns a clock which starts at the given time.
+func NewVirtualClock(start time.Time) *VirtualClock {
+	now := start.UnixNano()
+	return &VirtualClock{start: now, now: now, changed: make(chan struct{})}
+}
+
+// SetAutoAdvance enables or disables advancing the clock to the next
+// deadline once all attached guests are sleeping.
+func (c *VirtualClock) SetAutoAdvance(autoAdvance bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.autoAdvance = autoAdvance
+	c.maybeAutoAdvance()
+}
+
+// Now returns the current time as epoch nanoseconds.
+func (c *VirtualClock) Now() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Walltime implements sys.Walltime.
+func (c *VirtualClock) Walltime() (sec int64, nsec int32) {
+	now := c.Now()
+	return now / time.Second.Nanoseconds(), int32(now % time.Second.Nanoseconds())
+}
+
+// Nanotime implements sys.Nanotime.
+func (c *VirtualClock) Nanotime() int64 {
+	return c.Now() - c.start
+}
+
+// Nanosleep implements sys.Nanosleep.
+func (c *VirtualClock) Nanosleep(ns int64) {
+	c.Sleep(ns, nil)
+}
+
+// Sleep blocks until the clock advances by ns, and returns true, or returns
+// false if done is closed first.
+func (c *VirtualClock) Sleep(ns int64, done <-chan struct{}) bool {
+	if ns <= 0 {
+		return true
+	}
+	s := c.addSleeper(ns)
+	select {
+	case <-s.woken:
+		return true
+	case <-done:
+		c.removeSleeper(s)
+		return false
+	}
+}
+
+// Advance advances the clock by d, ending any sleeps whose deadline is
+// reached.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d > 0 {
+		c.advanceTo(addSaturated(c.now, int64(d)))
+	}
+}
+
+// Sleepers returns the count of sleeps in progress.
+func (c *VirtualClock) Sleepers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sleepers)
+}
+
+// BlockUntil blocks until at least n sleeps are in progress.
+func (c *VirtualClock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		if len(c.sleepers) >= n {
+			c.mu.Unlock()
+			return
+		}
+		changed := c.changed
+		c.mu.Unlock()
+		<-changed
+	}
+}
+
+// Attach counts a guest as running until done is closed. When auto-advance
+// is enabled, the clock only advances once all running guests are sleeping.
+func (c *VirtualClock) Attach(done <-chan struct{}) {
+	c.mu.Lock()
+	c.guests++
+	c.mu.Unlock()
+
+	go func() {
+		<-done
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.guests--
+		c.maybeAutoAdvance()
+	}()
+}
+
+// PollFiles is like sysfs.PollFiles, except the timeout elapses when the
+// clock advances by timeout nanoseconds, instead of in real time.
+func (c *VirtualClock) PollFiles(done <-chan struct{}, events []sysfs.PollEvent, timeout int64) (int, experimentalsys.Errno) {
+	if n, errno := sysfs.PollFiles(done, events, 0); n > 0 || errno != 0 {
+		return n, errno
+	}
+
+	s := c.addSleeper(timeout)
+	defer c.removeSleeper(s)
+
+	// Stop polling when either the sleep ends or done is closed.
+	interrupt, stop := make(chan struct{}), make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-done:
+		case <-s.woken:
+		case <-stop:
+		}
+		close(interrupt)
+	}()
+
+	n, errno := sysfs.PollFiles(interrupt, events, -1)
+	if errno == experimentalsys.EINTR {
+		select {
+		case <-done:
+		default:
+			return 0, 0 // timed out
+		}
+	}
+	return n, errno
+}
+
+func (c *VirtualClock) addSleeper(ns int64) *sleeper {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := &sleeper{deadline: addSaturated(c.now, ns), woken: make(chan struct{})}
+	c.sleepers = append(c.sleepers, s)
+	c.notifyChanged()
+	c.maybeAutoAdvance()
+	return s
+}
+
+func (c *VirtualClock) removeSleeper(s *sleeper) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, other := range c.sleepers {
+		if other == s {
+			c.sleepers = append(c.sleepers[:i], c.sleepers[i+1:]...)
+			c.notifyChanged()
+			return
+		}
+	}
+}
+
+// advanceTo sets the time to now and wakes sleepers whose deadline is
+// reached. This must be called with the lock held.
+func (c *VirtualClock) advanceTo(now int64) {
+	c.now = now
+	sleepers := c.sleepers[:0]
+	for _, s := range c.sleepers {
+		if s.deadline <= now {
+			close(s.woken)
+		} else {
+			sleepers = append(sleepers, s)
+		}
+	}
+	clear(c.sleepers[len(sleepers):])
+	c.sleepers = sleepers
+	c.notifyChanged()
+}
+
+// maybeAutoAdvance advances to the earliest deadline if all guests are
+// sleeping. This must be called with the lock held.
+func (c *VirtualClock) maybeAutoAdvance() {
+	if !c.autoAdvance || len(c.sleepers) == 0 || len(c.sleepers) < c.guests {
+		return
+	}
+	next := c.sleepers[0].deadline
+	for _, s := range c.sleepers[1:] {
+		next = min(next, s.deadline)
+	}
+	c.advanceTo(next)
+}
+
+// notifyChanged wakes BlockUntil. This must be called with the lock held.
+func (c *VirtualClock) notifyChanged() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// SetVirtualClock makes the clocks and sleeps of this context use the
+// virtual clock, overriding the ones configured.
+func (c *Context) SetVirtualClock(vc *VirtualClock) {
+	c.walltime, c.walltimeResolution = vc.Walltime, sys.ClockResolution(1)
+	c.nanotime, c.nanotimeResolution = vc.Nanotime, sys.ClockResolution(1)
+	c.nanosleep = vc.Nanosleep
+	c.vclock = vc
+}
+
+// VirtualClock returns the clock set by SetVirtualClock, or nil.
+func (c *Context) VirtualClock() *VirtualClock {
+	return c.vclock
+}
+
+func addSaturated(now, ns int64) int64 {
+	if ns > math.MaxInt64-now {
+		return math.MaxInt64
+	}
+	return now + ns
+}
@@ -0,0 +1,70 @@
+package sys
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestVirtualClock(t *testing.T) {
+	start := time.Unix(1640995200, 5)
+	c := NewVirtualClock(start)
+
+	sec, nsec := c.Walltime()
+	require.Equal(t, int64(1640995200), sec)
+	require.Equal(t, int32(5), nsec)
+	require.Equal(t, int64(0), c.Nanotime())
+
+	// Sleeping for zero doesn't block.
+	require.True(t, c.Sleep(0, nil))
+
+	slept := make(chan bool)
+	go func() { slept <- c.Sleep(int64(time.Second), nil) }()
+	c.BlockUntil(1)
+
+	c.Advance(time.Second - 1)
+	require.Equal(t, 1, c.Sleepers())
+	c.Advance(1)
+	require.True(t, <-slept)
+	require.Equal(t, 0, c.Sleepers())
+	require.Equal(t, time.Second.Nanoseconds(), c.Nanotime())
+
+	t.Run("done", func(t *testing.T) {
+		done := make(chan struct{})
+		go func() { slept <- c.Sleep(int64(time.Second), done) }()
+		c.BlockUntil(1)
+		close(done)
+		require.False(t, <-slept)
+		require.Equal(t, 0, c.Sleepers())
+	})
+
+	t.Run("auto-advance", func(t *testing.T) {
+		c := NewVirtualClock(start)
+		c.SetAutoAdvance(true)
+
+		done := make(chan struct{})
+		c.Attach(done)
+		require.True(t, c.Sleep(math.MaxInt64, nil))
+		require.Equal(t, int64(math.MaxInt64), c.Now()) // saturated
+
+		c = NewVirtualClock(start)
+		c.Attach(done)
+		c.Attach(done)
+		go func() { slept <- c.Sleep(int64(time.Minute), nil) }()
+		c.BlockUntil(1)
+
+		// Auto-advance starts once enabled, when all guests are sleeping.
+		go func() { slept <- c.Sleep(int64(time.Hour), nil) }()
+		c.BlockUntil(2)
+		c.SetAutoAdvance(true)
+		require.True(t, <-slept)
+		require.Equal(t, time.Minute.Nanoseconds(), c.Nanotime())
+
+		// Closing the guests advances to the remaining sleep.
+		close(done)
+		require.True(t, <-slept)
+		require.Equal(t, time.Hour.Nanoseconds(), c.Nanotime())
+	})
+}
@@ -0,0 +1,301 @@
+package sysfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// NewTarFS returns a read-only sys.FS with the contents of the tar archive
+// in r, which is size bytes.
+//
+// The headers are read once, to index the archive in memory. The contents of
+// files are read from r when the guest reads them, except sparse files,
+// which are read into memory. Device files and FIFOs are skipped.
+func NewTarFS(r io.ReaderAt, size int64) (experimentalsys.FS, error) {
+	sr := io.NewSectionReader(r, 0, size)
+	tr := tar.NewReader(sr)
+	b := newArchiveBuilder()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil && !errors.Is(err, tar.ErrInsecurePath) {
+			return nil, err
+		}
+
+		var n *memNode
+		perm := hdr.FileInfo().Mode().Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			n = b.m.newNode(fs.ModeDir | perm)
+		case tar.TypeReg, tar.TypeGNUSparse:
+			n = b.m.newNode(perm)
+			if isSparse(hdr) {
+				if n.data, err = io.ReadAll(tr); err != nil {
+					return nil, err
+				}
+			} else {
+				// The reader is at the start of the contents of the file.
+				offset, _ := sr.Seek(0, io.SeekCurrent)
+				n.src, n.srcSize = io.NewSectionReader(r, offset, hdr.Size), hdr.Size
+			}
+		case tar.TypeSymlink:
+			n = b.m.newNode(fs.ModeSymlink | perm)
+			n.target = hdr.Linkname
+		case tar.TypeLink:
+			if err = b.link(hdr.Name, hdr.Linkname); err != nil {
+				return nil, err
+			}
+			continue
+		default:
+			continue
+		}
+
+		atim, ctim := hdr.AccessTime, hdr.ChangeTime
+		if atim.IsZero() {
+			atim = hdr.ModTime
+		}
+		if ctim.IsZero() {
+			ctim = hdr.ModTime
+		}
+		if err = b.add(hdr.Name, n, atim, hdr.ModTime, ctim); err != nil {
+			return nil, err
+		}
+	}
+	return b.finish(), nil
+}
+
+// isSparse returns true if the contents of a file aren't stored contiguously
+// in the archive.
+func isSparse(hdr *tar.Header) bool {
+	if hdr.Typeflag == tar.TypeGNUSparse {
+		return true
+	}
+	for k := range hdr.PAXRecords {
+		if strings.HasPrefix(k, "GNU.sparse.") {
+			return true
+		}
+	}
+	return false
+}
+
+// NewZipFS returns a read-only sys.FS with the contents of the zip archive in
+// r, which is size bytes.
+//
+// The central directory is read once, to index the archive in memory. The
+// contents of files are read from r when the guest reads them. Reading
+// a compressed file at an offset before the last one read decompresses it
+// again from the start.
+func NewZipFS(r io.ReaderAt, size int64) (experimentalsys.FS, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil && !errors.Is(err, zip.ErrInsecurePath) {
+		return nil, err
+	}
+	b := newArchiveBuilder()
+	for _, f := range zr.File {
+		var n *memNode
+		mode := f.Mode()
+		switch mode.Type() {
+		case fs.ModeDir:
+			n = b.m.newNode(fs.ModeDir | mode.Perm())
+		case fs.ModeSymlink:
+			n = b.m.newNode(mode)
+			if n.target, err = readZipLink(f); err != nil {
+				return nil, err
+			}
+		case 0:
+			n = b.m.newNode(mode.Perm())
+			if n.src, err = zipReaderAt(r, f); err != nil {
+				return nil, err
+			}
+			n.srcSize = int64(f.UncompressedSize64)
+		default:
+			continue
+		}
+		if err = b.add(f.Name, n, f.Modified, f.Modified, f.Modified); err != nil {
+			return nil, err
+		}
+	}
+	return b.finish(), nil
+}
+
+// readZipLink returns the target of a symbolic link, which is the contents
+// of the file.
+func readZipLink(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	target, err := io.ReadAll(rc)
+	return string(target), err
+}
+
+// zipReaderAt returns an io.ReaderAt of the contents of the file.
+func zipReaderAt(r io.ReaderAt, f *zip.File) (io.ReaderAt, error) {
+	if f.Method == zip.Store {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		return io.NewSectionReader(r, offset, int64(f.UncompressedSize64)), nil
+	}
+	return &zipFileReader{f: f}, nil
+}
+
+// zipFileReader is an io.ReaderAt of a compressed file, which decompresses
+// it sequentially.
+type zipFileReader struct {
+	f *zip.File
+
+	mu     sync.Mutex
+	rc     io.ReadCloser
+	offset int64
+}
+
+// ReadAt implements io.ReaderAt
+func (z *zipFileReader) ReadAt(buf []byte, off int64) (int, error) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if z.rc == nil || off < z.offset {
+		if z.rc != nil {
+			_ = z.rc.Close()
+		}
+		rc, err := z.f.Open()
+		if err != nil {
+			z.rc = nil
+			return 0, err
+		}
+		z.rc, z.offset = rc, 0
+	}
+	if off > z.offset {
+		n, err := io.CopyN(io.Discard, z.rc, off-z.offset)
+		z.offset += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := io.ReadFull(z.rc, buf)
+	z.offset += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// archiveBuilder adds the entries of an archive to a memFS.
+type archiveBuilder struct {
+	m *memFS
+
+	// times are the timestamps of entries, set once all are added, as adding
+	// an entry to a directory changes its timestamps.
+	times map[*memNode][3]int64
+}
+
+func newArchiveBuilder() *archiveBuilder {
+	m := newMemFS()
+	m.now = func() int64 { return 0 }
+	m.root.atim, m.root.mtim, m.root.ctim = 0, 0, 0
+	m.root.mode = fs.ModeDir | 0o755
+	return &archiveBuilder{m: m, times: map[*memNode][3]int64{}}
+}
+
+// add adds the node at the path of an entry, replacing any existing entry,
+// except a directory replaced by a directory, which is updated instead.
+func (b *archiveBuilder) add(name string, n *memNode, atim, mtim, ctim time.Time) error {
+	dir, base, err := b.parent(name)
+	if err != nil {
+		return err
+	}
+	if base == "." {
+		if n.isDir() {
+			dir.mode = n.mode
+			b.times[dir] = [3]int64{atim.UnixNano(), mtim.UnixNano(), ctim.UnixNano()}
+		}
+		return nil
+	}
+	if existing := dir.entries[base]; existing != nil {
+		if existing.isDir() && n.isDir() {
+			existing.mode = n.mode
+			b.times[existing] = [3]int64{atim.UnixNano(), mtim.UnixNano(), ctim.UnixNano()}
+			return nil
+		}
+		b.m.unlink(dir, base)
+	}
+	b.m.link(dir, base, n)
+	b.times[n] = [3]int64{atim.UnixNano(), mtim.UnixNano(), ctim.UnixNano()}
+	return nil
+}
+
+// link adds a hard link at the path of an entry to the file at target.
+func (b *archiveBuilder) link(name, target string) error {
+	_, _, n, errno := b.m.lookup(cleanArchivePath(target), false)
+	if errno != 0 || n == nil || n.isDir() {
+		return fmt.Errorf("invalid hard link %s to %s", name, target)
+	}
+	dir, base, err := b.parent(name)
+	if err != nil {
+		return err
+	} else if base == "." {
+		return fmt.Errorf("invalid hard link %s to %s", name, target)
+	}
+	if existing := dir.entries[base]; existing != nil {
+		if existing == n {
+			return nil
+		}
+		b.m.unlink(dir, base)
+	}
+	b.m.link(dir, base, n)
+	return nil
+}
+
+// parent returns the directory of the path of an entry, creating any missing
+// directories, and its base name.
+func (b *archiveBuilder) parent(name string) (*memNode, string, error) {
+	p := cleanArchivePath(name)
+	if !fs.ValidPath(p) {
+		return nil, "", fmt.Errorf("invalid path in archive: %s", name)
+	} else if p == "." {
+		return b.m.root, ".", nil
+	}
+
+	dir := b.m.root
+	elems := strings.Split(p, "/")
+	for _, elem := range elems[:len(elems)-1] {
+		next := dir.entries[elem]
+		if next == nil {
+			next = b.m.newNode(fs.ModeDir | 0o755)
+			b.m.link(dir, elem, next)
+		} else if !next.isDir() {
+			return nil, "", fmt.Errorf("invalid path in archive: %s", name)
+		}
+		dir = next
+	}
+	return dir, elems[len(elems)-1], nil
+}
+
+// cleanArchivePath returns the path of an entry relative to the root, such
+// as "a/b" for "./a/b/" or "/a/b".
+func cleanArchivePath(name string) string {
+	return path.Clean(strings.TrimLeft(name, "/"))
+}
+
+// finish sets the timestamps of entries, and returns the read-only FS.
+func (b *archiveBuilder) finish() experimentalsys.FS {
+	for n, t := range b.times {
+		n.atim, n.mtim, n.ctim = t[0], t[1], t[2]
+	}
+	b.m.now = func() int64 { return time.Now().UnixNano() }
+	return &ReadFS{FS: b.m}
+}
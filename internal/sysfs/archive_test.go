@@ -0,0 +1,184 @@
+package sysfs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestTarFS_Open_Read(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.AddFS(fstest.FS))
+	require.NoError(t, tw.Close())
+
+	testFS, err := NewTarFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	testOpen_Read(t, testFS, true, true)
+}
+
+func TestZipFS_Open_Read(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	require.NoError(t, zw.AddFS(fstest.FS))
+	require.NoError(t, zw.Close())
+
+	testFS, err := NewZipFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	testOpen_Read(t, testFS, true, true)
+}
+
+func TestTarFS(t *testing.T) {
+	mtim := time.Unix(1667482413, 0)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, hdr := range []*tar.Header{
+		{Name: "./", Typeflag: tar.TypeDir, Mode: 0o700, ModTime: mtim},
+		{Name: "a/b/file.txt", Typeflag: tar.TypeReg, Mode: 0o640, Size: 6, ModTime: mtim},
+		{Name: "a/link.txt", Typeflag: tar.TypeSymlink, Linkname: "b/file.txt", Mode: 0o777, ModTime: mtim},
+		{Name: "hardlink.txt", Typeflag: tar.TypeLink, Linkname: "a/b/file.txt"},
+		{Name: "fifo", Typeflag: tar.TypeFifo, Mode: 0o600},
+	} {
+		require.NoError(t, tw.WriteHeader(hdr))
+		if hdr.Size > 0 {
+			_, err := tw.Write([]byte("wazero"))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tw.Close())
+
+	testFS, err := NewTarFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	st, errno := testFS.Stat(".")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeDir|0o700, st.Mode)
+
+	// Directories which aren't in the archive are created.
+	st, errno = testFS.Stat("a/b")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeDir|0o755, st.Mode)
+
+	file, errno := testFS.Stat("a/b/file.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.FileMode(0o640), file.Mode)
+	require.Equal(t, int64(6), file.Size)
+	require.Equal(t, mtim.UnixNano(), file.Mtim)
+	require.Equal(t, uint64(2), file.Nlink)
+
+	// Hard links and symbolic links resolve to the same file.
+	st, errno = testFS.Stat("hardlink.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, file, st)
+	st, errno = testFS.Stat("a/link.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, file, st)
+	st, errno = testFS.Lstat("a/link.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeSymlink|0o777, st.Mode)
+	target, errno := testFS.Readlink("a/link.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "b/file.txt", target)
+
+	// FIFOs aren't supported.
+	_, errno = testFS.Stat("fifo")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+
+	f, errno := testFS.OpenFile("a/link.txt", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	b := make([]byte, 10)
+	n, errno := f.Pread(b, 2)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "zero", string(b[:n]))
+
+	// The archive is read-only.
+	require.EqualErrno(t, experimentalsys.EROFS, testFS.Mkdir("dir", 0o755))
+	_, errno = f.Write([]byte("a"))
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+}
+
+func TestZipFS(t *testing.T) {
+	mtim := time.Date(2022, 11, 3, 13, 33, 32, 0, time.UTC)
+	content := bytes.Repeat([]byte("wazero"), 1000)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		hdr  zip.FileHeader
+		mode fs.FileMode
+		data []byte
+	}{
+		{hdr: zip.FileHeader{Name: "dir/", Modified: mtim}, mode: fs.ModeDir | 0o700},
+		{hdr: zip.FileHeader{Name: "dir/deflated", Method: zip.Deflate, Modified: mtim}, mode: 0o640, data: content},
+		{hdr: zip.FileHeader{Name: "dir/stored", Method: zip.Store, Modified: mtim}, mode: 0o600, data: content},
+		{hdr: zip.FileHeader{Name: "link", Modified: mtim}, mode: fs.ModeSymlink | 0o777, data: []byte("dir/deflated")},
+	} {
+		hdr := f.hdr
+		hdr.SetMode(f.mode)
+		w, err := zw.CreateHeader(&hdr)
+		require.NoError(t, err)
+		_, err = w.Write(f.data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	testFS, err := NewZipFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	st, errno := testFS.Stat("dir")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeDir|0o700, st.Mode)
+	require.Equal(t, mtim.UnixNano(), st.Mtim)
+
+	st, errno = testFS.Lstat("link")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeSymlink|0o777, st.Mode)
+
+	for _, name := range []string{"dir/deflated", "dir/stored", "link"} {
+		t.Run(name, func(t *testing.T) {
+			f, errno := testFS.OpenFile(name, experimentalsys.O_RDONLY, 0)
+			require.EqualErrno(t, 0, errno)
+			defer f.Close()
+
+			st, errno := f.Stat()
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, int64(len(content)), st.Size)
+
+			// Read after a later offset was read.
+			b := make([]byte, 6)
+			n, errno := f.Pread(b, 3000)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, "wazero", string(b[:n]))
+			n, errno = f.Pread(b, 3)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, "erowaz", string(b[:n]))
+
+			all, err := io.ReadAll(&FileIO{File: f})
+			require.NoError(t, err)
+			require.Equal(t, content, all)
+		})
+	}
+}
+
+func TestNewTarFS_Errors(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "../escape", Typeflag: tar.TypeReg}))
+	require.NoError(t, tw.Close())
+
+	_, err := NewTarFS(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.EqualError(t, err, "invalid path in archive: ../escape")
+
+	_, err = NewTarFS(bytes.NewReader([]byte("not a tar")), 9)
+	require.Error(t, err)
+}
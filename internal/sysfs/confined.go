@@ -0,0 +1,256 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// maxSymlinks is the maximum number of symbolic links followed resolving a
+// path, after which ELOOP is returned. This is the same as Linux.
+const maxSymlinks = 40
+
+// ConfinedDirFS is like DirFS, except paths can't escape dir, whether via ".."
+// or symbolic links. For example, opening "../etc/passwd", or a symbolic link
+// to "/etc/passwd", returns sys.EPERM.
+//
+// On Linux 5.6+, each path is resolved by the kernel with openat2 and
+// RESOLVE_BENEATH relative to dir, which is not subject to races with
+// concurrent changes on the host. Otherwise, each path component is resolved
+// in Go, rejecting symbolic links which escape dir.
+//
+// The result implements io.Closer, which releases the file descriptor of dir
+// held on Linux.
+func ConfinedDirFS(dir string) experimentalsys.FS {
+	return newConfinedDirFS(dir)
+}
+
+// newWalkDirFS returns a ConfinedDirFS which resolves paths by walking each
+// component, so works on any platform.
+func newWalkDirFS(dir string) experimentalsys.FS {
+	return &walkDirFS{dirFS: DirFS(dir).(*dirFS)}
+}
+
+// walkDirFS is a ConfinedDirFS which resolves each path component in Go,
+// following symbolic links itself, then delegates to dirFS with the resolved
+// path, which neither contains ".." nor symbolic links.
+//
+// Unlike openat2DirFS, this is subject to races with concurrent changes to
+// the directory on the host, such as a directory replaced with a symbolic
+// link after it was resolved.
+type walkDirFS struct {
+	experimentalsys.UnimplementedFS
+	dirFS *dirFS
+}
+
+// String implements fmt.Stringer
+func (d *walkDirFS) String() string {
+	return d.dirFS.dir
+}
+
+// hostDir implements hostDirFS
+func (d *walkDirFS) hostDir() string {
+	return d.dirFS.dir
+}
+
+// Close implements io.Closer, but is a no-op, as nothing is held open.
+func (d *walkDirFS) Close() error {
+	return nil
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (d *walkDirFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	resolved, errno := d.resolve(path, flag&experimentalsys.O_NOFOLLOW == 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	f, errno := d.dirFS.OpenFile(resolved, flag, perm)
+	if errno != 0 {
+		return nil, errno
+	}
+	// Resolve the path again when re-opening the file, as it may have been
+	// replaced by a symbolic link which escapes the root since it was opened.
+	if of, ok := f.(*osFile); ok {
+		of.openAgain = func(flag experimentalsys.Oflag) (*os.File, experimentalsys.Errno) {
+			resolved, errno := d.resolve(path, flag&experimentalsys.O_NOFOLLOW == 0)
+			if errno != 0 {
+				return nil, errno
+			}
+			return OpenFile(d.dirFS.join(resolved), flag, perm)
+		}
+	}
+	return f, 0
+}
+
+// Lstat implements the same method as documented on sys.FS
+func (d *walkDirFS) Lstat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	path, errno := d.resolve(path, false)
+	if errno != 0 {
+		return sys.Stat_t{}, errno
+	}
+	return d.dirFS.Lstat(path)
+}
+
+// Stat implements the same method as documented on sys.FS
+func (d *walkDirFS) Stat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	path, errno := d.resolve(path, true)
+	if errno != 0 {
+		return sys.Stat_t{}, errno
+	}
+	return d.dirFS.Stat(path)
+}
+
+// Mkdir implements the same method as documented on sys.FS
+func (d *walkDirFS) Mkdir(path string, perm fs.FileMode) experimentalsys.Errno {
+	path, errno := d.resolve(path, false)
+	if errno != 0 {
+		return errno
+	}
+	return d.dirFS.Mkdir(path, perm)
+}
+
+// Chmod implements the same method as documented on sys.FS
+func (d *walkDirFS) Chmod(path string, perm fs.FileMode) experimentalsys.Errno {
+	path, errno := d.resolve(path, true)
+	if errno != 0 {
+		return errno
+	}
+	return d.dirFS.Chmod(path, perm)
+}
+
+// Rename implements the same method as documented on sys.FS
+func (d *walkDirFS) Rename(from, to string) experimentalsys.Errno {
+	from, errno := d.resolve(from, false)
+	if errno != 0 {
+		return errno
+	}
+	if to, errno = d.resolve(to, false); errno != 0 {
+		return errno
+	}
+	return d.dirFS.Rename(from, to)
+}
+
+// Rmdir implements the same method as documented on sys.FS
+func (d *walkDirFS) Rmdir(path string) experimentalsys.Errno {
+	path, errno := d.resolve(path, false)
+	if errno != 0 {
+		return errno
+	}
+	return d.dirFS.Rmdir(path)
+}
+
+// Unlink implements the same method as documented on sys.FS
+func (d *walkDirFS) Unlink(path string) experimentalsys.Errno {
+	path, errno := d.resolve(path, false)
+	if errno != 0 {
+		return errno
+	}
+	return d.dirFS.Unlink(path)
+}
+
+// Link implements the same method as documented on sys.FS
+func (d *walkDirFS) Link(oldName, newName string) experimentalsys.Errno {
+	oldName, errno := d.resolve(oldName, false)
+	if errno != 0 {
+		return errno
+	}
+	if newName, errno = d.resolve(newName, false); errno != 0 {
+		return errno
+	}
+	return d.dirFS.Link(oldName, newName)
+}
+
+// Symlink implements the same method as documented on sys.FS
+func (d *walkDirFS) Symlink(oldName, link string) experimentalsys.Errno {
+	// oldName isn't resolved, as it is only the content of the link. Any
+	// escape is prevented when the link is followed.
+	link, errno := d.resolve(link, false)
+	if errno != 0 {
+		return errno
+	}
+	return d.dirFS.Symlink(oldName, link)
+}
+
+// Readlink implements the same method as documented on sys.FS
+func (d *walkDirFS) Readlink(path string) (string, experimentalsys.Errno) {
+	path, errno := d.resolve(path, false)
+	if errno != 0 {
+		return "", errno
+	}
+	return d.dirFS.Readlink(path)
+}
+
+// Utimens implements the same method as documented on sys.FS
+func (d *walkDirFS) Utimens(path string, atim, mtim int64) experimentalsys.Errno {
+	path, errno := d.resolve(path, true)
+	if errno != 0 {
+		return errno
+	}
+	return d.dirFS.Utimens(path, atim, mtim)
+}
+
+// resolve returns the path relative to the root which p refers to, with any
+// "." or ".." components removed and symbolic links followed, except the last
+// one unless followLast. This returns sys.EPERM if the path would escape the
+// root, including via an absolute symbolic link.
+//
+// Components which don't exist are kept, so that the operation using the
+// result returns the appropriate error, such as sys.ENOENT.
+func (d *walkDirFS) resolve(p string, followLast bool) (string, experimentalsys.Errno) {
+	// A trailing slash requires the last component to be a directory, so it
+	// is followed, like POSIX.
+	trailingSlash := strings.HasSuffix(p, "/")
+	followLast = followLast || trailingSlash
+
+	var resolved []string
+	pending := strings.Split(p, "/")
+	for links := 0; len(pending) > 0; {
+		name := pending[0]
+		pending = pending[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return "", experimentalsys.EPERM
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		resolved = append(resolved, name)
+		if len(pending) == 0 && !followLast {
+			break
+		}
+		target, err := os.Readlink(d.dirFS.join(strings.Join(resolved, "/")))
+		if err != nil {
+			continue // not a symbolic link, or doesn't exist.
+		}
+		if links++; links > maxSymlinks {
+			return "", experimentalsys.ELOOP
+		}
+		if filepath.IsAbs(target) || filepath.VolumeName(target) != "" {
+			return "", experimentalsys.EPERM
+		}
+		target = platform.ToPosixPath(target)
+		if path.IsAbs(target) {
+			return "", experimentalsys.EPERM
+		}
+		// Replace the link with its target, which is relative to the
+		// directory containing it.
+		resolved = resolved[:len(resolved)-1]
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+
+	ret := strings.Join(resolved, "/")
+	if trailingSlash && ret != "" {
+		ret += "/"
+	}
+	return ret, 0
+}
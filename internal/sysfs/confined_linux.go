@@ -0,0 +1,327 @@
+package sysfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/sys/unix"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// openat2Supported returns true unless the kernel is older than Linux 5.6,
+// which added openat2.
+var openat2Supported = sync.OnceValue(func() bool {
+	fd, err := unix.Openat2(unix.AT_FDCWD, ".", &unix.OpenHow{Flags: unix.O_PATH | unix.O_CLOEXEC})
+	if err == nil {
+		_ = unix.Close(fd)
+	}
+	return !errors.Is(err, unix.ENOSYS)
+})
+
+func newConfinedDirFS(dir string) experimentalsys.FS {
+	if !openat2Supported() {
+		return newWalkDirFS(dir)
+	}
+	d := &openat2DirFS{dirFS: DirFS(dir).(*dirFS)}
+	// Open the root once, instead of on each operation. If this fails, such
+	// as dir doesn't exist, each operation returns the error instead.
+	fd, err := unix.Open(dir, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		d.rootErrno = experimentalsys.UnwrapOSError(err)
+	} else {
+		d.root = os.NewFile(uintptr(fd), dir)
+	}
+	return d
+}
+
+// openat2DirFS is a ConfinedDirFS which resolves each path relative to a
+// file descriptor of the root with openat2 and RESOLVE_BENEATH, so the kernel
+// prevents escapes. The root is opened once, so the FS keeps referring to the
+// same directory, even if it is renamed on the host.
+//
+// Operations which don't follow a symbolic link in the last component, such
+// as Unlink, resolve its parent directory this way, then use the *at variant
+// of the syscall with the last component. Those which do, such as Chmod,
+// resolve the path to a file descriptor, and use it via /proc/self/fd.
+type openat2DirFS struct {
+	experimentalsys.UnimplementedFS
+	dirFS *dirFS
+
+	// root is the directory opened with O_PATH, or nil if that failed with
+	// rootErrno.
+	root      *os.File
+	rootErrno experimentalsys.Errno
+}
+
+// String implements fmt.Stringer
+func (d *openat2DirFS) String() string {
+	return d.dirFS.dir
+}
+
+// hostDir implements hostDirFS
+func (d *openat2DirFS) hostDir() string {
+	return d.dirFS.dir
+}
+
+// Close implements io.Closer by closing the file descriptor of the root, after
+// which each operation returns sys.EBADF. The descriptor is otherwise closed
+// when the FS is garbage collected.
+func (d *openat2DirFS) Close() error {
+	if d.root == nil {
+		return nil
+	}
+	return d.root.Close()
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (d *openat2DirFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	fd, errno := d.open(path, toOsOpenFlag(flag), syscallMode(perm))
+	if errno != 0 {
+		return nil, errno
+	}
+	name := d.dirFS.join(path)
+	f := newOsFile(name, flag, perm, os.NewFile(uintptr(fd), name)).(*osFile)
+	// Re-open the file via procfs, as its path may have been replaced by a
+	// symbolic link which escapes the root since it was opened. This isn't a
+	// symbolic link followed by O_NOFOLLOW, so clear it.
+	f.openAgain = func(flag experimentalsys.Oflag) (*os.File, experimentalsys.Errno) {
+		return OpenFile(procPath(int(f.fd)), flag&^experimentalsys.O_NOFOLLOW, perm)
+	}
+	return f, 0
+}
+
+// Lstat implements the same method as documented on sys.FS
+func (d *openat2DirFS) Lstat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	return d.stat(path, unix.O_NOFOLLOW)
+}
+
+// Stat implements the same method as documented on sys.FS
+func (d *openat2DirFS) Stat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	return d.stat(path, 0)
+}
+
+func (d *openat2DirFS) stat(path string, flag int) (sys.Stat_t, experimentalsys.Errno) {
+	fd, errno := d.open(path, unix.O_PATH|flag, 0)
+	if errno != 0 {
+		return sys.Stat_t{}, errno
+	}
+	f := os.NewFile(uintptr(fd), path)
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return sys.Stat_t{}, experimentalsys.UnwrapOSError(err)
+	}
+	return sys.NewStat_t(info), 0
+}
+
+// Mkdir implements the same method as documented on sys.FS
+func (d *openat2DirFS) Mkdir(path string, perm fs.FileMode) experimentalsys.Errno {
+	dirfd, name, errno := d.openParent(path)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(dirfd)
+	err := unix.Mkdirat(dirfd, name, syscallMode(perm))
+	if errno = experimentalsys.UnwrapOSError(err); errno == experimentalsys.ENOTDIR {
+		errno = experimentalsys.ENOENT
+	}
+	return errno
+}
+
+// Chmod implements the same method as documented on sys.FS
+func (d *openat2DirFS) Chmod(path string, perm fs.FileMode) experimentalsys.Errno {
+	fd, errno := d.open(path, unix.O_PATH, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(fd)
+	// fchmod doesn't support O_PATH, so use the file via procfs instead.
+	return experimentalsys.UnwrapOSError(unix.Chmod(procPath(fd), syscallMode(perm)))
+}
+
+// Rename implements the same method as documented on sys.FS
+func (d *openat2DirFS) Rename(from, to string) experimentalsys.Errno {
+	if from == to {
+		return 0
+	}
+	fromDirfd, fromName, errno := d.openParent(from)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(fromDirfd)
+	toDirfd, toName, errno := d.openParent(to)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(toDirfd)
+	return experimentalsys.UnwrapOSError(unix.Renameat(fromDirfd, fromName, toDirfd, toName))
+}
+
+// Rmdir implements the same method as documented on sys.FS
+func (d *openat2DirFS) Rmdir(path string) experimentalsys.Errno {
+	dirfd, name, errno := d.openParent(path)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(dirfd)
+	return experimentalsys.UnwrapOSError(unix.Unlinkat(dirfd, name, unix.AT_REMOVEDIR))
+}
+
+// Unlink implements the same method as documented on sys.FS
+func (d *openat2DirFS) Unlink(path string) experimentalsys.Errno {
+	dirfd, name, errno := d.openParent(path)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(dirfd)
+	if errno = experimentalsys.UnwrapOSError(unix.Unlinkat(dirfd, name, 0)); errno == experimentalsys.EPERM {
+		errno = experimentalsys.EISDIR
+	}
+	return errno
+}
+
+// Link implements the same method as documented on sys.FS
+func (d *openat2DirFS) Link(oldName, newName string) experimentalsys.Errno {
+	oldDirfd, oldBase, errno := d.openParent(oldName)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(oldDirfd)
+	newDirfd, newBase, errno := d.openParent(newName)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(newDirfd)
+	return experimentalsys.UnwrapOSError(unix.Linkat(oldDirfd, oldBase, newDirfd, newBase, 0))
+}
+
+// Symlink implements the same method as documented on sys.FS
+func (d *openat2DirFS) Symlink(oldName, link string) experimentalsys.Errno {
+	// See dirFS.Symlink
+	if path.IsAbs(oldName) {
+		return experimentalsys.EPERM
+	}
+	dirfd, name, errno := d.openParent(link)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(dirfd)
+	return experimentalsys.UnwrapOSError(unix.Symlinkat(oldName, dirfd, name))
+}
+
+// Readlink implements the same method as documented on sys.FS
+func (d *openat2DirFS) Readlink(path string) (string, experimentalsys.Errno) {
+	dirfd, name, errno := d.openParent(path)
+	if errno != 0 {
+		return "", errno
+	}
+	defer unix.Close(dirfd)
+	for size := 128; ; size *= 2 {
+		buf := make([]byte, size)
+		n, err := unix.Readlinkat(dirfd, name, buf)
+		if err != nil {
+			return "", experimentalsys.UnwrapOSError(err)
+		}
+		if n < size {
+			return string(buf[:n]), 0
+		}
+	}
+}
+
+// Utimens implements the same method as documented on sys.FS
+func (d *openat2DirFS) Utimens(path string, atim, mtim int64) experimentalsys.Errno {
+	fd, errno := d.open(path, unix.O_PATH, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer unix.Close(fd)
+	// utimensat doesn't support O_PATH, so use the file via procfs instead.
+	return utimens(procPath(fd), atim, mtim)
+}
+
+// open opens the path beneath the root, returning sys.EPERM if it would
+// escape it.
+func (d *openat2DirFS) open(path string, flag int, mode uint32) (int, experimentalsys.Errno) {
+	if d.root == nil {
+		return -1, d.rootErrno
+	}
+	// SyscallConn, unlike Fd, fails once the root is closed, and prevents it
+	// from being closed concurrently.
+	rc, err := d.root.SyscallConn()
+	if err != nil {
+		return -1, experimentalsys.EBADF
+	}
+	fd, errno := -1, experimentalsys.Errno(0)
+	if err = rc.Control(func(root uintptr) {
+		fd, errno = openat2(int(root), path, flag, mode)
+	}); err != nil {
+		return -1, experimentalsys.EBADF
+	}
+	return fd, errno
+}
+
+// openat2 opens the path beneath the root file descriptor.
+func openat2(root int, path string, flag int, mode uint32) (int, experimentalsys.Errno) {
+	if path = strings.TrimLeft(path, "/"); path == "" {
+		path = "."
+	}
+	how := &unix.OpenHow{
+		Flags:   uint64(flag | unix.O_CLOEXEC),
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+	}
+	if flag&unix.O_CREAT != 0 { // otherwise, openat2 requires no mode.
+		how.Mode = uint64(mode)
+	}
+	for {
+		fd, err := unix.Openat2(root, path, how)
+		switch {
+		case err == nil:
+			return fd, 0
+		case errors.Is(err, unix.EAGAIN):
+			continue // a concurrent rename or mount, so retry.
+		case errors.Is(err, unix.EXDEV):
+			return -1, experimentalsys.EPERM // escapes the root
+		}
+		return -1, experimentalsys.UnwrapOSError(err)
+	}
+}
+
+// openParent opens the directory containing the last component of the path
+// beneath the root, returning its file descriptor and the last component.
+func (d *openat2DirFS) openParent(p string) (dirfd int, name string, errno experimentalsys.Errno) {
+	dir, name := path.Split(strings.TrimRight(p, "/"))
+	if name == "" {
+		name = "."
+	}
+	dirfd, errno = d.open(dir, unix.O_PATH|unix.O_DIRECTORY, 0)
+	return
+}
+
+// procPath returns the path of the file descriptor in procfs, which resolves
+// to the same file, even if it was opened with O_PATH.
+func procPath(fd int) string {
+	return "/proc/self/fd/" + strconv.Itoa(fd)
+}
+
+// syscallMode returns the mode bits passed to syscalls for the perm, like
+// os.Chmod.
+func syscallMode(perm fs.FileMode) (mode uint32) {
+	mode = uint32(perm.Perm())
+	if perm&fs.ModeSetuid != 0 {
+		mode |= unix.S_ISUID
+	}
+	if perm&fs.ModeSetgid != 0 {
+		mode |= unix.S_ISGID
+	}
+	if perm&fs.ModeSticky != 0 {
+		mode |= unix.S_ISVTX
+	}
+	return
+}
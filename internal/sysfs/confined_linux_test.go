@@ -0,0 +1,41 @@
+package sysfs
+
+import (
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestOpenat2DirFS_Root(t *testing.T) {
+	if !openat2Supported() {
+		t.Skip("openat2 is not supported")
+	}
+	tmpDir := t.TempDir()
+	dir := path.Join(tmpDir, "dir")
+	require.NoError(t, os.Mkdir(dir, 0o700))
+	require.NoError(t, os.WriteFile(path.Join(dir, "file"), nil, 0o600))
+
+	testFS := ConfinedDirFS(dir)
+	_, errno := testFS.Stat("file")
+	require.EqualErrno(t, 0, errno)
+
+	// The root is opened once, so it is still resolved after being renamed.
+	require.NoError(t, os.Rename(dir, path.Join(tmpDir, "renamed")))
+	_, errno = testFS.Stat("file")
+	require.EqualErrno(t, 0, errno)
+
+	require.NoError(t, testFS.(io.Closer).Close())
+	_, errno = testFS.Stat("file")
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+
+	t.Run("not exist", func(t *testing.T) {
+		testFS := ConfinedDirFS(dir)
+		_, errno := testFS.Stat("file")
+		require.EqualErrno(t, experimentalsys.ENOENT, errno)
+		require.NoError(t, testFS.(io.Closer).Close())
+	})
+}
@@ -0,0 +1,222 @@
+package sysfs
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// confinedDirFSes returns each implementation of ConfinedDirFS supported on
+// this platform, by name.
+func confinedDirFSes(dir string) map[string]experimentalsys.FS {
+	ret := map[string]experimentalsys.FS{"walk": newWalkDirFS(dir)}
+	if _, ok := ConfinedDirFS(dir).(*walkDirFS); !ok {
+		ret[runtime.GOOS] = ConfinedDirFS(dir)
+	}
+	return ret
+}
+
+func TestConfinedDirFS(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(tmpDir))
+
+	for name, testFS := range confinedDirFSes(tmpDir) {
+		t.Run(name, func(t *testing.T) {
+			require.Equal(t, tmpDir, testFS.(interface{ String() string }).String())
+
+			testOpen_Read(t, testFS, true, true)
+			testStat(t, testFS)
+
+			t.Run("Mkdir", func(t *testing.T) {
+				require.EqualErrno(t, 0, testFS.Mkdir(name, fs.ModeDir))
+				require.EqualErrno(t, experimentalsys.EEXIST, testFS.Mkdir(name, fs.ModeDir))
+				require.EqualErrno(t, experimentalsys.ENOENT, testFS.Mkdir("non-existing-dir/foo", fs.ModeDir))
+				require.EqualErrno(t, 0, testFS.Rmdir(name))
+			})
+
+			if runtime.GOOS != "windows" {
+				t.Run("Chmod", func(t *testing.T) {
+					require.NoError(t, os.WriteFile(path.Join(tmpDir, name), nil, 0o444))
+					defer os.Remove(path.Join(tmpDir, name))
+					testChmod(t, testFS, name)
+				})
+			}
+
+			t.Run("Rename and Unlink", func(t *testing.T) {
+				require.NoError(t, os.WriteFile(path.Join(tmpDir, name), nil, 0o600))
+				require.EqualErrno(t, 0, testFS.Rename(name, "sub/"+name))
+				require.EqualErrno(t, 0, testFS.Link("sub/"+name, name))
+				require.EqualErrno(t, 0, testFS.Unlink("sub/"+name))
+				require.EqualErrno(t, experimentalsys.EISDIR, testFS.Unlink("sub"))
+				require.EqualErrno(t, 0, testFS.Unlink(name))
+				require.EqualErrno(t, experimentalsys.ENOENT, testFS.Unlink(name))
+			})
+
+			t.Run("Symlink", func(t *testing.T) {
+				require.EqualErrno(t, experimentalsys.EPERM, testFS.Symlink("/test.txt", name))
+				require.EqualErrno(t, 0, testFS.Symlink("sub/test.txt", name))
+				defer testFS.Unlink(name)
+
+				dst, errno := testFS.Readlink(name)
+				require.EqualErrno(t, 0, errno)
+				require.Equal(t, "sub/test.txt", dst)
+
+				st, errno := testFS.Lstat(name)
+				require.EqualErrno(t, 0, errno)
+				require.Equal(t, fs.ModeSymlink, st.Mode.Type())
+
+				st, errno = testFS.Stat(name)
+				require.EqualErrno(t, 0, errno)
+				require.Equal(t, int64(14), st.Size)
+			})
+		})
+	}
+}
+
+func TestConfinedDirFS_Escape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on windows")
+	}
+
+	tmpDir := t.TempDir()
+	secret := path.Join(tmpDir, "secret")
+	require.NoError(t, os.WriteFile(secret, []byte("secret"), 0o600))
+
+	root := path.Join(tmpDir, "root")
+	require.NoError(t, os.MkdirAll(path.Join(root, "sub"), 0o700))
+	require.NoError(t, os.WriteFile(path.Join(root, "file"), []byte("file"), 0o600))
+	for link, target := range map[string]string{
+		"abs":         secret,
+		"up":          "../secret",
+		"dir-up":      "..",
+		"sub/up":      "../../secret",
+		"inside":      "sub/back",
+		"sub/back":    "../file",
+		"loop":        "loop",
+		"dangling":    "created",
+		"sub/sub-abs": root,
+	} {
+		require.NoError(t, os.Symlink(target, path.Join(root, link)))
+	}
+
+	for name, testFS := range confinedDirFSes(root) {
+		t.Run(name, func(t *testing.T) {
+			for _, p := range []string{"../secret", "sub/../../secret", "abs", "up", "dir-up/secret", "sub/up", "sub/sub-abs/file"} {
+				_, errno := testFS.OpenFile(p, experimentalsys.O_RDONLY, 0)
+				require.EqualErrno(t, experimentalsys.EPERM, errno, p)
+				_, errno = testFS.Stat(p)
+				require.EqualErrno(t, experimentalsys.EPERM, errno, p)
+				require.EqualErrno(t, experimentalsys.EPERM, testFS.Chmod(p, 0o777), p)
+				require.EqualErrno(t, experimentalsys.EPERM, testFS.Utimens(p, 0, 0), p)
+			}
+
+			_, errno := testFS.OpenFile("loop", experimentalsys.O_RDONLY, 0)
+			require.EqualErrno(t, experimentalsys.ELOOP, errno)
+
+			// Links are only followed beneath the root.
+			for _, p := range []string{"inside", "sub/back", "sub/../file", "/file"} {
+				f, errno := testFS.OpenFile(p, experimentalsys.O_RDONLY, 0)
+				require.EqualErrno(t, 0, errno, p)
+				buf := make([]byte, 4)
+				_, errno = f.Read(buf)
+				require.EqualErrno(t, 0, errno)
+				require.Equal(t, "file", string(buf))
+				require.EqualErrno(t, 0, f.Close())
+			}
+
+			// A link can be created with any content, and read back, even if it
+			// can't be followed.
+			require.EqualErrno(t, 0, testFS.Symlink("../secret", "new-up"))
+			dst, errno := testFS.Readlink("new-up")
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, "../secret", dst)
+			_, errno = testFS.Stat("new-up")
+			require.EqualErrno(t, experimentalsys.EPERM, errno)
+			require.EqualErrno(t, 0, testFS.Unlink("new-up"))
+
+			// The last component isn't followed by these, so only a parent
+			// can escape.
+			_, errno = testFS.Lstat("up")
+			require.EqualErrno(t, 0, errno)
+			_, errno = testFS.Lstat("dir-up/secret")
+			require.EqualErrno(t, experimentalsys.EPERM, errno)
+			_, errno = testFS.Readlink("dir-up/secret")
+			require.EqualErrno(t, experimentalsys.EPERM, errno)
+			require.EqualErrno(t, experimentalsys.EPERM, testFS.Mkdir("dir-up/new", 0o700))
+			require.EqualErrno(t, experimentalsys.EPERM, testFS.Rmdir("dir-up/root"))
+			require.EqualErrno(t, experimentalsys.EPERM, testFS.Unlink("dir-up/secret"))
+			require.EqualErrno(t, experimentalsys.EPERM, testFS.Rename("file", "dir-up/moved"))
+			require.EqualErrno(t, experimentalsys.EPERM, testFS.Rename("dir-up/secret", "moved"))
+			require.EqualErrno(t, experimentalsys.EPERM, testFS.Link("dir-up/secret", "linked"))
+			require.EqualErrno(t, experimentalsys.EPERM, testFS.Link("file", "dir-up/linked"))
+			require.EqualErrno(t, experimentalsys.EPERM, testFS.Symlink("file", "dir-up/linked"))
+
+			// Creating via a link creates its target beneath the root.
+			f, errno := testFS.OpenFile("dangling", experimentalsys.O_CREAT|experimentalsys.O_WRONLY, 0o600)
+			require.EqualErrno(t, 0, errno)
+			require.EqualErrno(t, 0, f.Close())
+			require.EqualErrno(t, 0, testFS.Unlink("created"))
+
+			// Nothing outside the root changed.
+			entries, err := os.ReadDir(tmpDir)
+			require.NoError(t, err)
+			require.Equal(t, 2, len(entries))
+			b, err := os.ReadFile(secret)
+			require.NoError(t, err)
+			require.Equal(t, "secret", string(b))
+		})
+	}
+}
+
+func TestConfinedDirFS_EscapeReopen(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links require privileges on windows")
+	}
+
+	tmpDir := t.TempDir()
+	outside := path.Join(tmpDir, "outside")
+	require.NoError(t, os.Mkdir(outside, 0o700))
+	require.NoError(t, os.WriteFile(path.Join(outside, "file"), []byte("secret"), 0o600))
+
+	for name := range confinedDirFSes(tmpDir) {
+		t.Run(name, func(t *testing.T) {
+			root := path.Join(tmpDir, name)
+			require.NoError(t, os.MkdirAll(path.Join(root, "sub"), 0o700))
+			require.NoError(t, os.WriteFile(path.Join(root, "sub", "file"), []byte("file"), 0o600))
+			testFS := confinedDirFSes(root)[name]
+
+			f, errno := testFS.OpenFile("sub/file", experimentalsys.O_RDWR, 0)
+			require.EqualErrno(t, 0, errno)
+			defer f.Close()
+
+			// Replace a parent of the open file with a link outside the root,
+			// then re-open it by setting the append flag.
+			require.NoError(t, os.Rename(path.Join(root, "sub"), path.Join(root, "moved")))
+			require.NoError(t, os.Symlink(outside, path.Join(root, "sub")))
+
+			errno = f.SetAppend(true)
+			if name == "walk" {
+				// The path is resolved again, which now escapes the root.
+				require.EqualErrno(t, experimentalsys.EPERM, errno)
+			} else {
+				// The file was re-opened without resolving its path.
+				require.EqualErrno(t, 0, errno)
+				_, errno = f.Write([]byte("!"))
+				require.EqualErrno(t, 0, errno)
+				b, err := os.ReadFile(path.Join(root, "moved", "file"))
+				require.NoError(t, err)
+				require.Equal(t, "file!", string(b))
+			}
+
+			b, err := os.ReadFile(path.Join(outside, "file"))
+			require.NoError(t, err)
+			require.Equal(t, "secret", string(b))
+		})
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package sysfs
+
+import experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+
+func newConfinedDirFS(dir string) experimentalsys.FS {
+	return newWalkDirFS(dir)
+}
@@ -40,6 +40,11 @@ func (d *dirFS) String() string {
 	return d.dir
 }
 
+// hostDir implements hostDirFS
+func (d *dirFS) hostDir() string {
+	return d.dir
+}
+
 // OpenFile implements the same method as documented on sys.FS
 func (d *dirFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
 	return OpenOSFile(d.join(path), flag, perm)
@@ -36,6 +36,45 @@ func NewStdioFile(stdin bool, f fs.File) (experimentalsys.File, error) {
 	return &stdioFile{File: file, st: sys.Stat_t{Mode: mode, Nlink: 1}}, nil
 }
 
+// FileIO adapts a sys.File to io.Reader, io.Writer and io.Closer, for the
+// host to use the other end of a pipe, or to configure stdio with a sys.File.
+//
+// When passed to ModuleConfig.WithStdin, WithStdout or WithStderr, the guest
+// uses File as-is, instead of via the adapter, and closes it when the module
+// is closed.
+type FileIO struct {
+	File experimentalsys.File
+}
+
+// Read implements io.Reader, returning io.EOF when the file returns zero
+// bytes.
+func (f *FileIO) Read(buf []byte) (int, error) {
+	n, errno := f.File.Read(buf)
+	if errno != 0 {
+		return n, errno
+	} else if n == 0 && len(buf) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+// Write implements io.Writer
+func (f *FileIO) Write(buf []byte) (int, error) {
+	n, errno := f.File.Write(buf)
+	if errno != 0 {
+		return n, errno
+	}
+	return n, nil
+}
+
+// Close implements io.Closer
+func (f *FileIO) Close() error {
+	if errno := f.File.Close(); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
 func OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (*os.File, experimentalsys.Errno) {
 	return openFile(path, flag, perm)
 }
@@ -93,6 +132,11 @@ func (f *stdioFile) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (ready
 	return false, experimentalsys.ENOSYS
 }
 
+// hostFd implements the same method as documented on hostFile
+func (f *stdioFile) hostFd() (uintptr, bool) {
+	return hostFdOf(f.File)
+}
+
 // SetAppend implements File.SetAppend
 func (f *stdioFile) SetAppend(bool) experimentalsys.Errno {
 	// Ignore for stdio.
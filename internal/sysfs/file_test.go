@@ -401,10 +401,26 @@ func TestFilePoll_POLLOUT(t *testing.T) {
 	require.NoError(t, err)
 	timeout := int32(0) // return immediately
 
-	// We don't yet implement write blocking.
 	ready, errno := wF.(experimentalsys.Pollable).Poll(pflag, timeout)
-	require.EqualErrno(t, experimentalsys.ENOTSUP, errno)
-	require.False(t, ready)
+	if runtime.GOOS == "windows" {
+		// We don't yet implement write polling on Windows.
+		require.EqualErrno(t, experimentalsys.ENOTSUP, errno)
+		require.False(t, ready)
+	} else {
+		// An empty pipe can be written to.
+		require.EqualErrno(t, 0, errno)
+		require.True(t, ready)
+	}
+
+	// We can wait for either event.
+	ready, errno = wF.(experimentalsys.Pollable).Poll(experimentalsys.POLLIN|pflag, timeout)
+	if runtime.GOOS == "windows" {
+		require.EqualErrno(t, experimentalsys.ENOTSUP, errno)
+		require.False(t, ready)
+	} else {
+		require.EqualErrno(t, 0, errno)
+		require.True(t, ready)
+	}
 }
 
 // pollableFsFile is a mock fs.File that also implements experimentalsys.Pollable.
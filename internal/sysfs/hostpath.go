@@ -0,0 +1,33 @@
+package sysfs
+
+import (
+	"path/filepath"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// hostDirFS is implemented by a sys.FS which is a directory of the host.
+type hostDirFS interface {
+	hostDir() string
+}
+
+// HostPath returns the path on the host of path p of fs, or an empty string
+// if fs isn't a directory of the host, such as a MemFS.
+func HostPath(fs experimentalsys.FS, p string) string {
+	for {
+		switch f := fs.(type) {
+		case hostDirFS:
+			return filepath.Join(f.hostDir(), filepath.FromSlash(p))
+		case *ReadFS:
+			fs = f.FS
+		case *QuotaFS:
+			fs = f.FS
+		case *PolicyFS:
+			fs = f.FS
+		case *RightsFS:
+			fs = f.FS
+		default:
+			return ""
+		}
+	}
+}
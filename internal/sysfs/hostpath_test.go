@@ -0,0 +1,34 @@
+package sysfs
+
+import (
+	"path/filepath"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestHostPath(t *testing.T) {
+	dir := t.TempDir()
+	expected := filepath.Join(dir, "a", "b.txt")
+
+	tests := []struct {
+		name     string
+		fs       experimentalsys.FS
+		expected string
+	}{
+		{name: "DirFS", fs: DirFS(dir), expected: expected},
+		{name: "ConfinedDirFS", fs: ConfinedDirFS(dir), expected: expected},
+		{name: "ReadFS", fs: &ReadFS{FS: DirFS(dir)}, expected: expected},
+		{name: "QuotaFS", fs: NewQuotaFS(NewRightsFS(DirFS(dir), 0, 0), Quota{}), expected: expected},
+		{name: "MemFS", fs: NewMemFS()},
+		{name: "nil"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, HostPath(tc.fs, "a/b.txt"))
+		})
+	}
+}
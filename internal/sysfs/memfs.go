@@ -0,0 +1,795 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// memFSDev is incremented for each memFS, so that each has a unique device ID.
+var memFSDev atomic.Uint64
+
+// NewMemFS returns a writable sys.FS held in memory, which starts with an empty
+// root directory. Nothing is read from or written to the host.
+//
+// This supports regular files, directories, symbolic links and hard links,
+// with inode numbers and timestamps like a POSIX filesystem. Permission bits
+// are stored, but not enforced, like for a process running as root.
+func NewMemFS() experimentalsys.FS {
+	return newMemFS()
+}
+
+func newMemFS() *memFS {
+	m := &memFS{dev: memFSDev.Add(1), now: func() int64 { return time.Now().UnixNano() }}
+	m.root = m.newNode(fs.ModeDir | 0o777)
+	return m
+}
+
+// memFS is a sys.FS where each file is a memNode. All operations, including
+// those on open files, are serialized by mu.
+type memFS struct {
+	experimentalsys.UnimplementedFS
+
+	mu      sync.Mutex
+	dev     uint64
+	lastIno sys.Inode
+	root    *memNode
+
+	// now returns the current time in epoch nanoseconds.
+	now func() int64
+}
+
+// memNode is a file in a memFS, which is referenced by its parent directory
+// and any open files.
+type memNode struct {
+	ino  sys.Inode
+	mode fs.FileMode
+
+	// nlink is the count of directory entries of a file. This is computed
+	// for directories.
+	nlink uint64
+
+	atim, mtim, ctim int64
+
+	// data is the content of a regular file.
+	data []byte
+
+	// src is the content of a regular file read from an archive instead of
+	// data, until it is written. srcSize is its size.
+	src     io.ReaderAt
+	srcSize int64
+
+	// target is the content of a symbolic link.
+	target string
+
+	// entries are the contents of a directory, excluding "." and "..".
+	entries map[string]*memNode
+
+	// parent is the directory containing a directory, or itself for the root.
+	parent *memNode
+}
+
+func (n *memNode) isDir() bool {
+	return n.mode.IsDir()
+}
+
+// size returns the size of a regular file.
+func (n *memNode) size() int64 {
+	if n.src != nil {
+		return n.srcSize
+	}
+	return int64(len(n.data))
+}
+
+// load reads src into data, so that the file can be written.
+func (n *memNode) load() experimentalsys.Errno {
+	if n.src == nil {
+		return 0
+	}
+	data := make([]byte, n.srcSize)
+	if _, err := n.src.ReadAt(data, 0); err != nil && err != io.EOF {
+		return experimentalsys.EIO
+	}
+	n.data, n.src = data, nil
+	return 0
+}
+
+// newNode returns a node with the next inode number, and all timestamps set
+// to now.
+func (m *memFS) newNode(mode fs.FileMode) *memNode {
+	m.lastIno++
+	now := m.now()
+	n := &memNode{ino: m.lastIno, mode: mode, atim: now, mtim: now, ctim: now}
+	if n.isDir() {
+		n.entries = map[string]*memNode{}
+		n.parent = n
+	}
+	return n
+}
+
+// String implements fmt.Stringer
+func (m *memFS) String() string {
+	return "memfs"
+}
+
+// lookup resolves the path to the directory containing its last component,
+// the name of that component, and the node it refers to, or nil if it
+// doesn't exist. When the last component is "." or "..", dir is nil.
+//
+// Symbolic links are followed in all but the last component, unless
+// followLast. Absolute paths, including link targets, resolve from the root,
+// and ".." in the root is the root, so no path escapes the filesystem.
+func (m *memFS) lookup(p string, followLast bool) (dir *memNode, name string, n *memNode, errno experimentalsys.Errno) {
+	if p == "" {
+		return nil, "", nil, experimentalsys.ENOENT
+	}
+
+	// A trailing slash requires the last component to be a directory, so it
+	// is followed, like POSIX.
+	trailingSlash := strings.HasSuffix(p, "/")
+	followLast = followLast || trailingSlash
+	if trimmed := strings.TrimRight(p, "/"); trimmed != "" {
+		p = trimmed
+	}
+
+	n = m.root
+	pending := strings.Split(p, "/")
+	for links := 0; len(pending) > 0; {
+		c := pending[0]
+		pending = pending[1:]
+		if n == nil {
+			return nil, "", nil, experimentalsys.ENOENT
+		} else if !n.isDir() {
+			return nil, "", nil, experimentalsys.ENOTDIR
+		}
+
+		switch c {
+		case "", ".":
+			dir, name = nil, c
+			continue
+		case "..":
+			dir, name, n = nil, c, n.parent
+			continue
+		}
+
+		dir, name, n = n, c, n.entries[c]
+		if n == nil || n.mode.Type() != fs.ModeSymlink || (len(pending) == 0 && !followLast) {
+			continue
+		}
+		if links++; links > maxSymlinks {
+			return nil, "", nil, experimentalsys.ELOOP
+		}
+		// Replace the link with its target, which is relative to the
+		// directory containing it.
+		target := n.target
+		if n = dir; path.IsAbs(target) {
+			n = m.root
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+
+	if trailingSlash && n != nil && !n.isDir() {
+		return nil, "", nil, experimentalsys.ENOTDIR
+	}
+	return
+}
+
+// link adds the node to the directory, updating timestamps.
+func (m *memFS) link(dir *memNode, name string, n *memNode) {
+	dir.entries[name] = n
+	if n.isDir() {
+		n.parent = dir
+	} else {
+		n.nlink++
+	}
+	now := m.now()
+	dir.mtim, dir.ctim, n.ctim = now, now, now
+}
+
+// unlink removes the named node from the directory, updating timestamps.
+func (m *memFS) unlink(dir *memNode, name string) {
+	n := dir.entries[name]
+	delete(dir.entries, name)
+	if !n.isDir() {
+		n.nlink--
+	}
+	now := m.now()
+	dir.mtim, dir.ctim, n.ctim = now, now, now
+}
+
+func (m *memFS) stat(n *memNode) sys.Stat_t {
+	st := sys.Stat_t{
+		Dev:   m.dev,
+		Ino:   n.ino,
+		Mode:  n.mode,
+		Nlink: n.nlink,
+		Atim:  n.atim,
+		Mtim:  n.mtim,
+		Ctim:  n.ctim,
+	}
+	switch n.mode.Type() {
+	case fs.ModeDir:
+		st.Nlink = 2 // its entry in the parent and "."
+		for _, e := range n.entries {
+			if e.isDir() {
+				st.Nlink++ // ".." of the subdirectory
+			}
+		}
+	case fs.ModeSymlink:
+		st.Size = int64(len(n.target))
+	default:
+		st.Size = n.size()
+	}
+	return st
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (m *memFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, name, n, errno := m.lookup(path, flag&experimentalsys.O_NOFOLLOW == 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	writable := flag&(experimentalsys.O_WRONLY|experimentalsys.O_RDWR) != 0
+	switch {
+	case n == nil:
+		if flag&experimentalsys.O_CREAT == 0 {
+			return nil, experimentalsys.ENOENT
+		} else if flag&experimentalsys.O_DIRECTORY != 0 || strings.HasSuffix(path, "/") {
+			return nil, experimentalsys.EISDIR
+		}
+		n = m.newNode(perm.Perm())
+		m.link(dir, name, n)
+	case flag&(experimentalsys.O_CREAT|experimentalsys.O_EXCL) == experimentalsys.O_CREAT|experimentalsys.O_EXCL:
+		return nil, experimentalsys.EEXIST
+	case n.mode.Type() == fs.ModeSymlink: // only when O_NOFOLLOW
+		return nil, experimentalsys.ELOOP
+	case n.isDir():
+		if writable {
+			return nil, experimentalsys.EISDIR
+		}
+	case flag&experimentalsys.O_DIRECTORY != 0:
+		return nil, experimentalsys.ENOTDIR
+	case flag&experimentalsys.O_TRUNC != 0 && writable:
+		n.data, n.src = nil, nil
+		n.mtim = m.now()
+		n.ctim = n.mtim
+	}
+	return &memFile{fs: m, node: n, flag: flag, append: flag&experimentalsys.O_APPEND != 0}, 0
+}
+
+// Lstat implements the same method as documented on sys.FS
+func (m *memFS) Lstat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	return m.statPath(path, false)
+}
+
+// Stat implements the same method as documented on sys.FS
+func (m *memFS) Stat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	return m.statPath(path, true)
+}
+
+func (m *memFS) statPath(path string, followLast bool) (sys.Stat_t, experimentalsys.Errno) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, _, n, errno := m.lookup(path, followLast)
+	if errno != 0 {
+		return sys.Stat_t{}, errno
+	} else if n == nil {
+		return sys.Stat_t{}, experimentalsys.ENOENT
+	}
+	return m.stat(n), 0
+}
+
+// Mkdir implements the same method as documented on sys.FS
+func (m *memFS) Mkdir(path string, perm fs.FileMode) experimentalsys.Errno {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, name, n, errno := m.lookup(path, false)
+	if errno != 0 {
+		return errno
+	} else if n != nil {
+		return experimentalsys.EEXIST
+	}
+	m.link(dir, name, m.newNode(fs.ModeDir|perm.Perm()))
+	return 0
+}
+
+// Chmod implements the same method as documented on sys.FS
+func (m *memFS) Chmod(path string, perm fs.FileMode) experimentalsys.Errno {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, _, n, errno := m.lookup(path, true)
+	if errno != 0 {
+		return errno
+	} else if n == nil {
+		return experimentalsys.ENOENT
+	}
+	n.mode = n.mode.Type() | perm.Perm()
+	n.ctim = m.now()
+	return 0
+}
+
+// Rename implements the same method as documented on sys.FS
+func (m *memFS) Rename(from, to string) experimentalsys.Errno {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fromDir, fromName, fromNode, errno := m.lookup(from, false)
+	if errno != 0 {
+		return errno
+	} else if fromNode == nil {
+		return experimentalsys.ENOENT
+	}
+	toDir, toName, toNode, errno := m.lookup(to, false)
+	if errno != 0 {
+		return errno
+	} else if fromNode == toNode {
+		return 0 // includes hard links to the same file, like POSIX.
+	} else if fromDir == nil || toDir == nil {
+		return experimentalsys.EINVAL // "." or ".."
+	}
+
+	if fromNode.isDir() {
+		if toNode != nil {
+			if !toNode.isDir() {
+				return experimentalsys.ENOTDIR
+			} else if len(toNode.entries) > 0 {
+				return experimentalsys.ENOTEMPTY
+			}
+		}
+		// A directory can't be moved into itself.
+		for d := toDir; ; d = d.parent {
+			if d == fromNode {
+				return experimentalsys.EINVAL
+			} else if d == d.parent {
+				break
+			}
+		}
+	} else if toNode != nil && toNode.isDir() {
+		return experimentalsys.EISDIR
+	}
+
+	if toNode != nil {
+		m.unlink(toDir, toName)
+	}
+	m.unlink(fromDir, fromName)
+	m.link(toDir, toName, fromNode)
+	return 0
+}
+
+// Rmdir implements the same method as documented on sys.FS
+func (m *memFS) Rmdir(path string) experimentalsys.Errno {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, name, n, errno := m.lookup(path, false)
+	switch {
+	case errno != 0:
+		return errno
+	case n == nil:
+		return experimentalsys.ENOENT
+	case !n.isDir():
+		return experimentalsys.ENOTDIR
+	case dir == nil:
+		return experimentalsys.EINVAL // "." or ".."
+	case len(n.entries) > 0:
+		return experimentalsys.ENOTEMPTY
+	}
+	m.unlink(dir, name)
+	return 0
+}
+
+// Unlink implements the same method as documented on sys.FS
+func (m *memFS) Unlink(path string) experimentalsys.Errno {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, name, n, errno := m.lookup(path, false)
+	switch {
+	case errno != 0:
+		return errno
+	case n == nil:
+		return experimentalsys.ENOENT
+	case n.isDir():
+		return experimentalsys.EISDIR
+	}
+	m.unlink(dir, name)
+	return 0
+}
+
+// Link implements the same method as documented on sys.FS
+func (m *memFS) Link(oldPath, newPath string) experimentalsys.Errno {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, _, n, errno := m.lookup(oldPath, false)
+	if errno != 0 {
+		return errno
+	} else if n == nil {
+		return experimentalsys.ENOENT
+	} else if n.isDir() {
+		return experimentalsys.EPERM
+	}
+	dir, name, existing, errno := m.lookup(newPath, false)
+	if errno != 0 {
+		return errno
+	} else if existing != nil {
+		return experimentalsys.EEXIST
+	}
+	m.link(dir, name, n)
+	return 0
+}
+
+// Symlink implements the same method as documented on sys.FS
+//
+// Unlike DirFS, an absolute oldPath is allowed, as it is resolved from the
+// root of this filesystem.
+func (m *memFS) Symlink(oldPath, linkName string) experimentalsys.Errno {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	dir, name, n, errno := m.lookup(linkName, false)
+	if errno != 0 {
+		return errno
+	} else if n != nil {
+		return experimentalsys.EEXIST
+	}
+	n = m.newNode(fs.ModeSymlink | 0o777)
+	n.target = oldPath
+	m.link(dir, name, n)
+	return 0
+}
+
+// Readlink implements the same method as documented on sys.FS
+func (m *memFS) Readlink(path string) (string, experimentalsys.Errno) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, _, n, errno := m.lookup(path, false)
+	if errno != 0 {
+		return "", errno
+	} else if n == nil {
+		return "", experimentalsys.ENOENT
+	} else if n.mode.Type() != fs.ModeSymlink {
+		return "", experimentalsys.EINVAL
+	}
+	return n.target, 0
+}
+
+// Utimens implements the same method as documented on sys.FS
+func (m *memFS) Utimens(path string, atim, mtim int64) experimentalsys.Errno {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, _, n, errno := m.lookup(path, true)
+	if errno != 0 {
+		return errno
+	} else if n == nil {
+		return experimentalsys.ENOENT
+	}
+	m.utimens(n, atim, mtim)
+	return 0
+}
+
+func (m *memFS) utimens(n *memNode, atim, mtim int64) {
+	if atim != experimentalsys.UTIME_OMIT {
+		n.atim = atim
+	}
+	if mtim != experimentalsys.UTIME_OMIT {
+		n.mtim = mtim
+	}
+	n.ctim = m.now()
+}
+
+// compile-time check to ensure memFile implements sys.File.
+var _ experimentalsys.File = (*memFile)(nil)
+
+// memFile is an open memNode. The node remains usable after it is unlinked.
+type memFile struct {
+	experimentalsys.UnimplementedFile
+
+	fs     *memFS
+	node   *memNode
+	flag   experimentalsys.Oflag
+	append bool
+	closed bool
+
+	// offset is the position of Read and Write in a regular file.
+	offset int64
+
+	// dirents are the remaining entries of a directory to return from
+	// Readdir, or nil if not yet read since it was opened or rewound.
+	dirents []experimentalsys.Dirent
+}
+
+func (f *memFile) readable() bool {
+	return f.flag&experimentalsys.O_WRONLY == 0
+}
+
+func (f *memFile) writable() bool {
+	return f.flag&(experimentalsys.O_WRONLY|experimentalsys.O_RDWR) != 0
+}
+
+// Dev implements the same method as documented on sys.File
+func (f *memFile) Dev() (uint64, experimentalsys.Errno) {
+	if f.closed {
+		return 0, experimentalsys.EBADF
+	}
+	return f.fs.dev, 0
+}
+
+// Ino implements the same method as documented on sys.File
+func (f *memFile) Ino() (sys.Inode, experimentalsys.Errno) {
+	if f.closed {
+		return 0, experimentalsys.EBADF
+	}
+	return f.node.ino, 0
+}
+
+// IsDir implements the same method as documented on sys.File
+func (f *memFile) IsDir() (bool, experimentalsys.Errno) {
+	if f.closed {
+		return false, experimentalsys.EBADF
+	}
+	return f.node.isDir(), 0
+}
+
+// IsAppend implements the same method as documented on sys.File
+func (f *memFile) IsAppend() bool {
+	return f.append
+}
+
+// SetAppend implements the same method as documented on sys.File
+func (f *memFile) SetAppend(enable bool) experimentalsys.Errno {
+	if f.closed {
+		return experimentalsys.EBADF
+	}
+	f.append = enable
+	return 0
+}
+
+// Stat implements the same method as documented on sys.File
+func (f *memFile) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	if f.closed {
+		return sys.Stat_t{}, experimentalsys.EBADF
+	}
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+	return f.fs.stat(f.node), 0
+}
+
+// Read implements the same method as documented on sys.File
+func (f *memFile) Read(buf []byte) (n int, errno experimentalsys.Errno) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if n, errno = f.pread(buf, f.offset); errno == 0 {
+		f.offset += int64(n)
+	}
+	return
+}
+
+// Pread implements the same method as documented on sys.File
+func (f *memFile) Pread(buf []byte, off int64) (int, experimentalsys.Errno) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	return f.pread(buf, off)
+}
+
+func (f *memFile) pread(buf []byte, off int64) (int, experimentalsys.Errno) {
+	switch {
+	case f.closed || !f.readable():
+		return 0, experimentalsys.EBADF
+	case f.node.isDir():
+		return 0, experimentalsys.EISDIR
+	case off < 0:
+		return 0, experimentalsys.EINVAL
+	case off >= f.node.size():
+		return 0, 0 // EOF
+	case f.node.src != nil:
+		n, err := f.node.src.ReadAt(buf[:min(int64(len(buf)), f.node.srcSize-off)], off)
+		if err != nil && err != io.EOF {
+			return n, experimentalsys.EIO
+		}
+		return n, 0
+	}
+	return copy(buf, f.node.data[off:]), 0
+}
+
+// Seek implements the same method as documented on sys.File
+func (f *memFile) Seek(offset int64, whence int) (int64, experimentalsys.Errno) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return 0, experimentalsys.EBADF
+	} else if f.node.isDir() {
+		// Seeking to zero rewinds a directory.
+		if offset != 0 || whence != io.SeekStart {
+			return 0, experimentalsys.EISDIR
+		}
+		f.dirents = nil
+		return 0, 0
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.node.size()
+	default:
+		return 0, experimentalsys.EINVAL
+	}
+	if offset < 0 {
+		return 0, experimentalsys.EINVAL
+	}
+	f.offset = offset
+	return offset, 0
+}
+
+// Readdir implements the same method as documented on sys.File
+func (f *memFile) Readdir(n int) (dirents []experimentalsys.Dirent, errno experimentalsys.Errno) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed || !f.node.isDir() {
+		return nil, experimentalsys.EBADF
+	}
+
+	// Snapshot the entries on the first read, so they are consistent even if
+	// the directory changes before it is read completely.
+	if f.dirents == nil {
+		f.dirents = make([]experimentalsys.Dirent, 0, len(f.node.entries))
+		for name, e := range f.node.entries {
+			f.dirents = append(f.dirents, experimentalsys.Dirent{Ino: e.ino, Name: name, Type: e.mode.Type()})
+		}
+		sort.Slice(f.dirents, func(i, j int) bool { return f.dirents[i].Name < f.dirents[j].Name })
+	}
+
+	if n <= 0 || n > len(f.dirents) {
+		n = len(f.dirents)
+	}
+	dirents = f.dirents[:n:n]
+	f.dirents = f.dirents[n:]
+	return dirents, 0
+}
+
+// Write implements the same method as documented on sys.File
+func (f *memFile) Write(buf []byte) (n int, errno experimentalsys.Errno) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed || !f.writable() || f.node.isDir() {
+		return 0, experimentalsys.EBADF
+	} else if errno = f.node.load(); errno != 0 {
+		return 0, errno
+	}
+	if f.append {
+		f.offset = int64(len(f.node.data))
+	}
+	n = f.pwrite(buf, f.offset)
+	f.offset += int64(n)
+	return n, 0
+}
+
+// Pwrite implements the same method as documented on sys.File
+func (f *memFile) Pwrite(buf []byte, off int64) (int, experimentalsys.Errno) {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	switch {
+	case f.closed || !f.writable():
+		return 0, experimentalsys.EBADF
+	case f.node.isDir():
+		return 0, experimentalsys.EISDIR
+	case off < 0:
+		return 0, experimentalsys.EINVAL
+	}
+	if errno := f.node.load(); errno != 0 {
+		return 0, errno
+	}
+	return f.pwrite(buf, off), 0
+}
+
+func (f *memFile) pwrite(buf []byte, off int64) int {
+	if len(buf) == 0 {
+		return 0
+	}
+	if end := off + int64(len(buf)); end > int64(len(f.node.data)) {
+		f.node.data = resize(f.node.data, end)
+	}
+	n := copy(f.node.data[off:], buf)
+	f.node.mtim = f.fs.now()
+	f.node.ctim = f.node.mtim
+	return n
+}
+
+// resize returns data with the size, zero-filling any new bytes.
+func resize(data []byte, size int64) []byte {
+	if size <= int64(cap(data)) {
+		old := len(data)
+		data = data[:size]
+		clear(data[min(int64(old), size):])
+		return data
+	}
+	return append(data, make([]byte, size-int64(len(data)))...)
+}
+
+// Truncate implements the same method as documented on sys.File
+func (f *memFile) Truncate(size int64) experimentalsys.Errno {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	switch {
+	case f.closed || !f.writable():
+		return experimentalsys.EBADF
+	case f.node.isDir():
+		return experimentalsys.EISDIR
+	case size < 0:
+		return experimentalsys.EINVAL
+	}
+	if errno := f.node.load(); errno != 0 {
+		return errno
+	}
+	f.node.data = resize(f.node.data, size)
+	f.node.mtim = f.fs.now()
+	f.node.ctim = f.node.mtim
+	return 0
+}
+
+// Sync implements the same method as documented on sys.File
+func (f *memFile) Sync() experimentalsys.Errno {
+	if f.closed {
+		return experimentalsys.EBADF
+	}
+	return 0 // nothing to flush.
+}
+
+// Datasync implements the same method as documented on sys.File
+func (f *memFile) Datasync() experimentalsys.Errno {
+	return f.Sync()
+}
+
+// Utimens implements the same method as documented on sys.File
+func (f *memFile) Utimens(atim, mtim int64) experimentalsys.Errno {
+	f.fs.mu.Lock()
+	defer f.fs.mu.Unlock()
+
+	if f.closed {
+		return experimentalsys.EBADF
+	}
+	f.fs.utimens(f.node, atim, mtim)
+	return 0
+}
+
+// Poll implements the same method as documented on sys.Pollable
+//
+// Like regular files in POSIX, a memFile is always ready.
+func (f *memFile) Poll(experimentalsys.Pflag, int32) (bool, experimentalsys.Errno) {
+	if f.closed {
+		return false, experimentalsys.EBADF
+	}
+	return true, 0
+}
+
+// Close implements the same method as documented on sys.File
+func (f *memFile) Close() experimentalsys.Errno {
+	f.closed = true
+	return 0
+}
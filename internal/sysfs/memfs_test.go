@@ -0,0 +1,482 @@
+package sysfs
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func newTestMemFS(t *testing.T) *memFS {
+	testFS := newMemFS()
+	require.NoError(t, fstest.WriteTestFilesFS(testFS))
+	return testFS
+}
+
+func TestMemFS_String(t *testing.T) {
+	require.Equal(t, "memfs", NewMemFS().(fmt.Stringer).String())
+}
+
+func TestMemFS_Open_Read(t *testing.T) {
+	testOpen_Read(t, newTestMemFS(t), true, true)
+}
+
+func TestMemFS_Stat(t *testing.T) {
+	testFS := newTestMemFS(t)
+	testStat(t, testFS)
+
+	st, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.FileMode(0o644), st.Mode)
+	require.Equal(t, int64(30), st.Size)
+	require.Equal(t, uint64(1), st.Nlink)
+	require.Equal(t, int64(1667482413000000000), st.Mtim)
+
+	// Each memFS is a different device.
+	st2, errno := newTestMemFS(t).Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.NotEqual(t, st.Dev, st2.Dev)
+
+	// "." and ".." of each subdirectory are hard links to a directory.
+	st, errno = testFS.Stat(".")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, uint64(5), st.Nlink)
+
+	// ".." in the root is the root.
+	st2, errno = testFS.Stat("sub/../..")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, st.Ino, st2.Ino)
+
+	_, errno = testFS.Stat("animals.txt/")
+	require.EqualErrno(t, experimentalsys.ENOTDIR, errno)
+	_, errno = testFS.Stat("animals.txt/cat")
+	require.EqualErrno(t, experimentalsys.ENOTDIR, errno)
+	_, errno = testFS.Stat("")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+}
+
+func TestMemFS_Lstat(t *testing.T) {
+	testFS := newTestMemFS(t)
+	for _, path := range []string{"animals.txt", "sub", "sub-link"} {
+		require.EqualErrno(t, 0, testFS.Symlink(path, path+"-link"))
+	}
+
+	testLstat(t, testFS)
+}
+
+func TestMemFS_Readlink(t *testing.T) {
+	testFS := newTestMemFS(t)
+	testReadlink(t, testFS, testFS)
+}
+
+func TestMemFS_Chmod(t *testing.T) {
+	testFS := newTestMemFS(t)
+	testChmod(t, testFS, "sub/test.txt")
+
+	require.EqualErrno(t, 0, testFS.Chmod("sub", 0o500))
+	requireMode(t, testFS, "sub", 0o500)
+
+	// Permissions aren't enforced.
+	f, errno := testFS.OpenFile("sub/test.txt", experimentalsys.O_RDWR, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+}
+
+func TestMemFS_OpenFile(t *testing.T) {
+	testFS := newTestMemFS(t)
+
+	t.Run("O_CREAT", func(t *testing.T) {
+		f, errno := testFS.OpenFile("new", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		n, errno := f.Write([]byte("wazero"))
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 6, n)
+
+		offset, errno := f.Seek(0, io.SeekStart)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, offset)
+
+		buf := make([]byte, 10)
+		n, errno = f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "wazero", string(buf[:n]))
+
+		// EOF is not an error.
+		n, errno = f.Read(buf)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, n)
+
+		st, errno := f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, fs.FileMode(0o600), st.Mode)
+		require.Equal(t, int64(6), st.Size)
+
+		_, errno = testFS.OpenFile("new", experimentalsys.O_RDWR|experimentalsys.O_CREAT|experimentalsys.O_EXCL, 0o600)
+		require.EqualErrno(t, experimentalsys.EEXIST, errno)
+	})
+
+	t.Run("O_RDONLY", func(t *testing.T) {
+		f, errno := testFS.OpenFile("animals.txt", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		_, errno = f.Pwrite([]byte{1}, 0)
+		require.EqualErrno(t, experimentalsys.EBADF, errno)
+		require.EqualErrno(t, experimentalsys.EBADF, f.Truncate(0))
+	})
+
+	t.Run("O_WRONLY", func(t *testing.T) {
+		f, errno := testFS.OpenFile("animals.txt", experimentalsys.O_WRONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		_, errno = f.Read(make([]byte, 1))
+		require.EqualErrno(t, experimentalsys.EBADF, errno)
+	})
+
+	t.Run("O_TRUNC", func(t *testing.T) {
+		f, errno := testFS.OpenFile("sub/test.txt", experimentalsys.O_RDWR|experimentalsys.O_TRUNC, 0)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		st, errno := testFS.Stat("sub/test.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, st.Size)
+	})
+
+	t.Run("O_APPEND", func(t *testing.T) {
+		f, errno := testFS.OpenFile("animals.txt", experimentalsys.O_RDWR|experimentalsys.O_APPEND, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+		require.True(t, f.IsAppend())
+
+		// Writes append even after seeking.
+		_, errno = f.Seek(0, io.SeekStart)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("dog\n"))
+		require.EqualErrno(t, 0, errno)
+
+		buf := make([]byte, 8)
+		n, errno := f.Pread(buf, 26)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "man\ndog\n", string(buf[:n]))
+
+		// Until append is disabled.
+		require.EqualErrno(t, 0, f.SetAppend(false))
+		_, errno = f.Seek(0, io.SeekStart)
+		require.EqualErrno(t, 0, errno)
+		_, errno = f.Write([]byte("B"))
+		require.EqualErrno(t, 0, errno)
+		n, errno = f.Pread(buf[:4], 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, "Bear", string(buf[:n]))
+	})
+
+	t.Run("O_NOFOLLOW", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Symlink("animals.txt", "nofollow"))
+
+		_, errno := testFS.OpenFile("nofollow", experimentalsys.O_RDONLY|experimentalsys.O_NOFOLLOW, 0)
+		require.EqualErrno(t, experimentalsys.ELOOP, errno)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			name          string
+			path          string
+			flag          experimentalsys.Oflag
+			expectedErrno experimentalsys.Errno
+		}{
+			{name: "parent doesn't exist", path: "nope/new", flag: experimentalsys.O_CREAT, expectedErrno: experimentalsys.ENOENT},
+			{name: "parent is a file", path: "animals.txt/new", flag: experimentalsys.O_CREAT, expectedErrno: experimentalsys.ENOTDIR},
+			{name: "O_DIRECTORY on a file", path: "animals.txt", flag: experimentalsys.O_DIRECTORY, expectedErrno: experimentalsys.ENOTDIR},
+			{name: "directory for writing", path: "sub", flag: experimentalsys.O_WRONLY, expectedErrno: experimentalsys.EISDIR},
+			{name: "create a directory", path: "newdir/", flag: experimentalsys.O_CREAT, expectedErrno: experimentalsys.EISDIR},
+		}
+
+		for _, tt := range tests {
+			tc := tt
+			t.Run(tc.name, func(t *testing.T) {
+				_, errno := testFS.OpenFile(tc.path, tc.flag, 0o600)
+				require.EqualErrno(t, tc.expectedErrno, errno)
+			})
+		}
+	})
+}
+
+func TestMemFS_File(t *testing.T) {
+	testFS := newTestMemFS(t)
+
+	f, errno := testFS.OpenFile("new", experimentalsys.O_RDWR|experimentalsys.O_CREAT, 0o600)
+	require.EqualErrno(t, 0, errno)
+
+	t.Run("Pwrite past the end zero-fills", func(t *testing.T) {
+		n, errno := f.Pwrite([]byte{1}, 3)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, n)
+
+		buf := make([]byte, 5)
+		n, errno = f.Pread(buf, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []byte{0, 0, 0, 1}, buf[:n])
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		require.EqualErrno(t, 0, f.Truncate(1))
+		require.EqualErrno(t, 0, f.Truncate(3))
+
+		buf := make([]byte, 5)
+		n, errno := f.Pread(buf, 0)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, []byte{0, 0, 0}, buf[:n])
+
+		require.EqualErrno(t, experimentalsys.EINVAL, f.Truncate(-1))
+	})
+
+	t.Run("Seek", func(t *testing.T) {
+		offset, errno := f.Seek(-1, io.SeekEnd)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(2), offset)
+
+		offset, errno = f.Seek(1, io.SeekCurrent)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(3), offset)
+
+		_, errno = f.Seek(-4, io.SeekCurrent)
+		require.EqualErrno(t, experimentalsys.EINVAL, errno)
+		_, errno = f.Seek(0, 3)
+		require.EqualErrno(t, experimentalsys.EINVAL, errno)
+	})
+
+	t.Run("Utimens", func(t *testing.T) {
+		require.EqualErrno(t, 0, f.Utimens(1, experimentalsys.UTIME_OMIT))
+		require.EqualErrno(t, 0, testFS.Utimens("new", experimentalsys.UTIME_OMIT, 2))
+
+		st, errno := f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(1), st.Atim)
+		require.Equal(t, int64(2), st.Mtim)
+	})
+
+	t.Run("unlinked file is still usable", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Unlink("new"))
+
+		_, errno := f.Write([]byte{2})
+		require.EqualErrno(t, 0, errno)
+
+		st, errno := f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, st.Nlink)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		require.EqualErrno(t, 0, f.Close())
+		require.EqualErrno(t, 0, f.Close())
+
+		_, errno := f.Stat()
+		require.EqualErrno(t, experimentalsys.EBADF, errno)
+		_, errno = f.Read(make([]byte, 1))
+		require.EqualErrno(t, experimentalsys.EBADF, errno)
+		_, errno = f.Write([]byte{1})
+		require.EqualErrno(t, experimentalsys.EBADF, errno)
+		require.EqualErrno(t, experimentalsys.EBADF, f.Sync())
+	})
+}
+
+func TestMemFS_Readdir(t *testing.T) {
+	testFS := newTestMemFS(t)
+
+	f, errno := testFS.OpenFile("dir", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	dirents, errno := f.Readdir(2)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 2, len(dirents))
+
+	// Changes after the first read aren't visible until rewound.
+	require.EqualErrno(t, 0, testFS.Mkdir("dir/b-", 0o700))
+	dirents, errno = f.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, len(dirents))
+	require.Equal(t, "ab-", dirents[0].Name)
+
+	_, errno = f.Seek(1, io.SeekStart)
+	require.EqualErrno(t, experimentalsys.EISDIR, errno)
+	_, errno = f.Seek(0, io.SeekStart)
+	require.EqualErrno(t, 0, errno)
+
+	dirents = requireReaddir(t, f, -1, true)
+	for i := range dirents {
+		dirents[i].Ino = 0
+	}
+	require.Equal(t, []experimentalsys.Dirent{
+		{Name: "-", Type: 0},
+		{Name: "a-", Type: fs.ModeDir},
+		{Name: "ab-", Type: 0},
+		{Name: "b-", Type: fs.ModeDir},
+	}, dirents)
+
+	_, errno = f.Read(make([]byte, 1))
+	require.EqualErrno(t, experimentalsys.EISDIR, errno)
+
+	file, errno := testFS.OpenFile("animals.txt", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer file.Close()
+	_, errno = file.Readdir(-1)
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+}
+
+func TestMemFS_Mkdir(t *testing.T) {
+	testFS := newTestMemFS(t)
+
+	require.EqualErrno(t, 0, testFS.Mkdir("new/", 0o700))
+	requireMode(t, testFS, "new", 0o700)
+
+	require.EqualErrno(t, experimentalsys.EEXIST, testFS.Mkdir("new", 0o700))
+	require.EqualErrno(t, experimentalsys.EEXIST, testFS.Mkdir("animals.txt", 0o700))
+	require.EqualErrno(t, experimentalsys.EEXIST, testFS.Mkdir(".", 0o700))
+	require.EqualErrno(t, experimentalsys.ENOENT, testFS.Mkdir("nope/new", 0o700))
+}
+
+func TestMemFS_Rename(t *testing.T) {
+	tests := []struct {
+		name          string
+		from, to      string
+		expectedErrno experimentalsys.Errno
+	}{
+		{name: "file", from: "animals.txt", to: "sub/zoo.txt"},
+		{name: "file over file", from: "animals.txt", to: "sub/test.txt"},
+		{name: "file to itself", from: "animals.txt", to: "animals.txt"},
+		{name: "dir", from: "dir", to: "sub/dir"},
+		{name: "dir over empty dir", from: "dir", to: "emptydir"},
+		{name: "from doesn't exist", from: "nope", to: "new", expectedErrno: experimentalsys.ENOENT},
+		{name: "to parent doesn't exist", from: "animals.txt", to: "nope/new", expectedErrno: experimentalsys.ENOENT},
+		{name: "file over dir", from: "animals.txt", to: "emptydir", expectedErrno: experimentalsys.EISDIR},
+		{name: "dir over file", from: "sub", to: "animals.txt", expectedErrno: experimentalsys.ENOTDIR},
+		{name: "dir over non-empty dir", from: "emptydir", to: "sub", expectedErrno: experimentalsys.ENOTEMPTY},
+		{name: "dir into itself", from: "dir", to: "dir/a-/dir", expectedErrno: experimentalsys.EINVAL},
+		{name: "dot", from: ".", to: "new", expectedErrno: experimentalsys.EINVAL},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			testFS := newTestMemFS(t)
+			before, errno := testFS.Lstat(tc.from)
+			if errno != 0 {
+				require.EqualErrno(t, tc.expectedErrno, errno)
+			}
+
+			require.EqualErrno(t, tc.expectedErrno, testFS.Rename(tc.from, tc.to))
+			if tc.expectedErrno != 0 || tc.from == tc.to {
+				return
+			}
+
+			_, errno = testFS.Lstat(tc.from)
+			require.EqualErrno(t, experimentalsys.ENOENT, errno)
+			after, errno := testFS.Lstat(tc.to)
+			require.EqualErrno(t, 0, errno)
+			require.Equal(t, before.Ino, after.Ino)
+		})
+	}
+
+	t.Run("dir parent", func(t *testing.T) {
+		testFS := newTestMemFS(t)
+		require.EqualErrno(t, 0, testFS.Rename("dir", "sub/dir"))
+
+		st, errno := testFS.Stat("sub/dir/a-/../../test.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(14), st.Size)
+	})
+}
+
+func TestMemFS_Rmdir(t *testing.T) {
+	testFS := newTestMemFS(t)
+
+	require.EqualErrno(t, 0, testFS.Rmdir("emptydir"))
+	require.EqualErrno(t, experimentalsys.ENOENT, testFS.Rmdir("emptydir"))
+	require.EqualErrno(t, experimentalsys.ENOTEMPTY, testFS.Rmdir("sub"))
+	require.EqualErrno(t, experimentalsys.ENOTDIR, testFS.Rmdir("animals.txt"))
+	require.EqualErrno(t, experimentalsys.EINVAL, testFS.Rmdir("dir/a-/."))
+}
+
+func TestMemFS_Link(t *testing.T) {
+	testFS := newTestMemFS(t)
+
+	require.EqualErrno(t, 0, testFS.Link("animals.txt", "sub/animals.txt"))
+	require.EqualErrno(t, experimentalsys.EEXIST, testFS.Link("animals.txt", "sub/test.txt"))
+	require.EqualErrno(t, experimentalsys.EPERM, testFS.Link("sub", "sub2"))
+	require.EqualErrno(t, experimentalsys.ENOENT, testFS.Link("nope", "sub2"))
+
+	st1, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	st2, errno := testFS.Stat("sub/animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, st1.Ino, st2.Ino)
+	require.Equal(t, uint64(2), st2.Nlink)
+
+	// A write via one link is visible via the other.
+	f, errno := testFS.OpenFile("sub/animals.txt", experimentalsys.O_WRONLY|experimentalsys.O_TRUNC, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	require.EqualErrno(t, 0, testFS.Unlink("animals.txt"))
+	st2, errno = testFS.Stat("sub/animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, uint64(1), st2.Nlink)
+	require.Zero(t, st2.Size)
+}
+
+func TestMemFS_Unlink(t *testing.T) {
+	testFS := newTestMemFS(t)
+
+	require.EqualErrno(t, 0, testFS.Symlink("sub", "sub-link"))
+	require.EqualErrno(t, 0, testFS.Unlink("sub-link"))
+	_, errno := testFS.Stat("sub")
+	require.EqualErrno(t, 0, errno)
+
+	require.EqualErrno(t, 0, testFS.Unlink("animals.txt"))
+	require.EqualErrno(t, experimentalsys.ENOENT, testFS.Unlink("animals.txt"))
+	require.EqualErrno(t, experimentalsys.EISDIR, testFS.Unlink("sub"))
+}
+
+func TestMemFS_Symlink(t *testing.T) {
+	testFS := newTestMemFS(t)
+
+	require.EqualErrno(t, 0, testFS.Symlink("/sub/test.txt", "sub/abs"))
+	require.EqualErrno(t, 0, testFS.Symlink("../../../animals.txt", "sub/up"))
+	require.EqualErrno(t, 0, testFS.Symlink("sub/", "dir-link"))
+	require.EqualErrno(t, 0, testFS.Symlink("loop", "loop"))
+	require.EqualErrno(t, 0, testFS.Symlink("created", "dangling"))
+	require.EqualErrno(t, experimentalsys.EEXIST, testFS.Symlink("animals.txt", "sub/test.txt"))
+
+	for p, size := range map[string]int64{
+		"sub/abs":               14,
+		"sub/up":                30,
+		"dir-link/abs":          14,
+		"dir-link/../empty.txt": 0,
+	} {
+		st, errno := testFS.Stat(p)
+		require.EqualErrno(t, 0, errno, p)
+		require.Equal(t, size, st.Size, p)
+	}
+
+	st, errno := testFS.Lstat("dir-link/")
+	require.EqualErrno(t, 0, errno)
+	require.True(t, st.Mode.IsDir())
+
+	_, errno = testFS.Stat("loop")
+	require.EqualErrno(t, experimentalsys.ELOOP, errno)
+
+	// Creating via a dangling link creates its target.
+	f, errno := testFS.OpenFile("dangling", experimentalsys.O_CREAT|experimentalsys.O_WRONLY, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, 0, testFS.Unlink("created"))
+}
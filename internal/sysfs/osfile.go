@@ -43,6 +43,11 @@ type osFile struct {
 
 	// cachedStat includes fields that won't change while a file is open.
 	cachedSt *cachedStat
+
+	// openAgain, when set, is used by reopen instead of opening path, which
+	// may no longer resolve to the same file, or even one inside a confined
+	// directory.
+	openAgain func(flag experimentalsys.Oflag) (*os.File, experimentalsys.Errno)
 }
 
 // cachedStat returns the cacheable parts of sys.Stat_t or an error if they
@@ -113,12 +118,18 @@ func (f *osFile) reopen() (errno experimentalsys.Errno) {
 
 	// Clear any create or trunc flag, as we are re-opening, not re-creating.
 	flag := f.flag &^ (experimentalsys.O_CREAT | experimentalsys.O_TRUNC)
-	file, errno := OpenFile(f.path, flag, f.perm)
+	var file *os.File
+	if f.openAgain != nil {
+		file, errno = f.openAgain(flag)
+	} else {
+		file, errno = OpenFile(f.path, flag, f.perm)
+	}
 	if errno != 0 {
 		return errno
 	}
 	errno = f.checkSameFile(file)
 	if errno != 0 {
+		_ = file.Close()
 		return errno
 	}
 
@@ -233,6 +244,11 @@ func (f *osFile) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (ready bo
 	return poll(f.fd, flag, timeoutMillis)
 }
 
+// hostFd implements the same method as documented on hostFile
+func (f *osFile) hostFd() (uintptr, bool) {
+	return f.fd, !f.closed
+}
+
 // Readdir implements File.Readdir. Notably, this uses "Readdir", not
 // "ReadDir", from os.File.
 func (f *osFile) Readdir(n int) (dirents []experimentalsys.Dirent, errno experimentalsys.Errno) {
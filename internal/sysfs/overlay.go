@@ -0,0 +1,934 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	// whiteoutPrefix is the prefix of a file in the upper layer, which hides
+	// the file named by the rest of its name in lower layers. This is the
+	// same convention as OCI image layers.
+	whiteoutPrefix = ".wh."
+
+	// opaqueWhiteout is a file in a directory of the upper layer, which hides
+	// the contents of the same directory in lower layers.
+	opaqueWhiteout = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// ChangeKind is the kind of an OverlayChange.
+type ChangeKind uint8
+
+const (
+	// ChangeAdded is a file which doesn't exist in a lower layer.
+	ChangeAdded ChangeKind = iota + 1
+	// ChangeModified is a file which was copied up from a lower layer, or
+	// replaces one.
+	ChangeModified
+	// ChangeDeleted is a file deleted from a lower layer.
+	ChangeDeleted
+)
+
+// String implements fmt.Stringer
+func (k ChangeKind) String() string {
+	switch k {
+	case ChangeAdded:
+		return "A"
+	case ChangeModified:
+		return "C"
+	case ChangeDeleted:
+		return "D"
+	}
+	return "?"
+}
+
+// OverlayChange is a path in the upper layer of an OverlayFS, as returned by
+// OverlayFS.Changes.
+type OverlayChange struct {
+	// Path is relative to the root, such as "sub/test.txt".
+	Path string
+	Kind ChangeKind
+}
+
+// NewOverlayFS returns an OverlayFS which writes to upper, over the read-only
+// lowers, which have a higher priority first.
+func NewOverlayFS(upper experimentalsys.FS, lowers ...experimentalsys.FS) *OverlayFS {
+	layers := []experimentalsys.FS{upper}
+	for _, lower := range lowers {
+		layers = append(layers, &ReadFS{FS: lower})
+	}
+	return &OverlayFS{layers: layers}
+}
+
+// OverlayFS is a copy-on-write sys.FS, like Linux overlayfs: lower layers are
+// never written, and changes are written to the upper layer instead.
+//
+//   - A directory which exists in multiple layers has the entries of all of
+//     them, unless hidden by an upper one.
+//   - A file in a lower layer is copied up to the upper layer before it is
+//     opened for writing, or its metadata is changed.
+//   - A file deleted from a lower layer is hidden by a whiteout in the upper
+//     layer: an empty file with the same name prefixed by ".wh.". Guests can't
+//     create or see files named like this.
+//
+// Like overlayfs, renaming a directory which exists in a lower layer fails
+// with sys.EXDEV. Symbolic links in a lower layer which are followed resolve
+// in the overlay, not in that layer.
+//
+// Note: Directories copied up are writable by their owner, so that their
+// entries can be copied up.
+type OverlayFS struct {
+	experimentalsys.UnimplementedFS
+
+	// mu serializes operations, which can take multiple steps, such as
+	// copy-up.
+	mu sync.Mutex
+
+	// layers are the upper layer followed by the lower layers.
+	layers []experimentalsys.FS
+}
+
+// Upper returns the layer changes are written to.
+func (o *OverlayFS) Upper() experimentalsys.FS {
+	return o.layers[0]
+}
+
+// overlayEntry is a path looked up in an OverlayFS.
+type overlayEntry struct {
+	// path is relative to the root, without symbolic links, "." or "..".
+	path string
+
+	exists bool
+
+	// st is the status of the entry in the topmost layer which has it.
+	st sys.Stat_t
+
+	// layers are the indexes of the layers which have the entry, topmost
+	// first. Only a directory exists in more than one layer.
+	layers []int
+
+	// inLower is true when any lower layer has the entry, even if hidden by
+	// the upper one.
+	inLower bool
+}
+
+func (e *overlayEntry) isDir() bool {
+	return e.st.Mode.IsDir()
+}
+
+// inUpper returns true if the entry exists in the upper layer.
+func (e *overlayEntry) inUpper() bool {
+	return e.exists && e.layers[0] == 0
+}
+
+// lookup returns the entry at the path, following symbolic links in all but
+// the last component, unless followLast.
+func (o *OverlayFS) lookup(p string, followLast bool) (overlayEntry, experimentalsys.Errno) {
+	if p == "" {
+		return overlayEntry{}, experimentalsys.ENOENT
+	}
+
+	// A trailing slash requires the last component to be a directory, so it
+	// is followed, like POSIX.
+	trailingSlash := strings.HasSuffix(p, "/")
+	followLast = followLast || trailingSlash
+	if trimmed := strings.TrimRight(p, "/"); trimmed != "" {
+		p = trimmed
+	}
+
+	all := make([]int, len(o.layers))
+	for i := range all {
+		all[i] = i
+	}
+	root, errno := o.stat(".", all)
+	if errno != 0 {
+		return overlayEntry{}, errno
+	}
+
+	// entries are the entries resolved so far, so ".." pops the last one.
+	entries := []overlayEntry{root}
+	pending := strings.Split(p, "/")
+	for links := 0; len(pending) > 0; {
+		c := pending[0]
+		pending = pending[1:]
+		parent := &entries[len(entries)-1]
+		if !parent.exists {
+			return overlayEntry{}, experimentalsys.ENOENT
+		} else if !parent.isDir() {
+			return overlayEntry{}, experimentalsys.ENOTDIR
+		}
+
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			if len(entries) > 1 {
+				entries = entries[:len(entries)-1]
+			}
+			continue
+		}
+
+		e, errno := o.stat(path.Join(parent.path, c), parent.layers)
+		if errno != 0 {
+			return overlayEntry{}, errno
+		}
+		if !e.exists || e.st.Mode.Type() != fs.ModeSymlink || (len(pending) == 0 && !followLast) {
+			entries = append(entries, e)
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return overlayEntry{}, experimentalsys.ELOOP
+		}
+		target, errno := o.layers[e.layers[0]].Readlink(e.path)
+		if errno != 0 {
+			return overlayEntry{}, errno
+		}
+		if path.IsAbs(target) {
+			entries = entries[:1]
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+
+	e := entries[len(entries)-1]
+	if trailingSlash && e.exists && !e.isDir() {
+		return overlayEntry{}, experimentalsys.ENOTDIR
+	}
+	return e, 0
+}
+
+// stat returns the entry at the path in the layers, which are those that
+// have its parent directory.
+func (o *OverlayFS) stat(p string, layers []int) (e overlayEntry, errno experimentalsys.Errno) {
+	e.path = p
+	if strings.HasPrefix(path.Base(p), whiteoutPrefix) {
+		return // whiteouts are never visible.
+	}
+
+	merge := true
+	for _, i := range layers {
+		st, errno := o.layers[i].Lstat(p)
+		if errno == experimentalsys.ENOENT {
+			if i == 0 && o.exists(whiteoutPath(p)) {
+				break // hidden in all lower layers.
+			}
+			continue
+		} else if errno != 0 {
+			return overlayEntry{}, errno
+		}
+
+		if i > 0 {
+			e.inLower = true
+		}
+		switch {
+		case !e.exists:
+			e.exists, e.st, e.layers = true, st, []int{i}
+			// An opaque directory isn't merged with lower layers.
+			merge = st.Mode.IsDir() && !(i == 0 && o.exists(path.Join(p, opaqueWhiteout)))
+		case merge && st.Mode.IsDir():
+			e.layers = append(e.layers, i)
+		default:
+			merge = false // a lower directory is hidden by a non-directory.
+		}
+	}
+	return
+}
+
+// exists returns true if the path exists in the upper layer.
+func (o *OverlayFS) exists(p string) bool {
+	_, errno := o.layers[0].Lstat(p)
+	return errno == 0
+}
+
+// whiteoutPath returns the path of the whiteout which hides p.
+func whiteoutPath(p string) string {
+	dir, name := path.Split(p)
+	return dir + whiteoutPrefix + name
+}
+
+// whiteout hides the path in lower layers.
+func (o *OverlayFS) whiteout(p string) experimentalsys.Errno {
+	return o.touch(whiteoutPath(p))
+}
+
+// opaque hides the contents of the directory in lower layers.
+func (o *OverlayFS) opaque(dir string) experimentalsys.Errno {
+	return o.touch(path.Join(dir, opaqueWhiteout))
+}
+
+// touch creates an empty file in the upper layer.
+func (o *OverlayFS) touch(p string) experimentalsys.Errno {
+	f, errno := o.layers[0].OpenFile(p, experimentalsys.O_WRONLY|experimentalsys.O_CREAT|experimentalsys.O_TRUNC, 0o600)
+	if errno != 0 {
+		return errno
+	}
+	return f.Close()
+}
+
+// prepareCreate prepares the upper layer to create the path, which doesn't
+// exist in the overlay, by copying up its parent and removing any whiteout.
+// This returns true if a whiteout was removed, in which case a directory
+// created at the path must be opaque.
+func (o *OverlayFS) prepareCreate(p string) (bool, experimentalsys.Errno) {
+	if strings.HasPrefix(path.Base(p), whiteoutPrefix) {
+		return false, experimentalsys.EPERM
+	}
+	if errno := o.copyUpDir(path.Dir(p)); errno != 0 {
+		return false, errno
+	}
+	switch errno := o.layers[0].Unlink(whiteoutPath(p)); errno {
+	case 0:
+		return true, 0
+	case experimentalsys.ENOENT:
+		return false, 0
+	default:
+		return false, errno
+	}
+}
+
+// copyUpDir ensures the directory, which exists in the overlay, exists in
+// the upper layer, copying it and its parents from the lower layers.
+func (o *OverlayFS) copyUpDir(dir string) experimentalsys.Errno {
+	if o.exists(dir) {
+		return 0
+	}
+	if errno := o.copyUpDir(path.Dir(dir)); errno != 0 {
+		return errno
+	}
+	for _, lower := range o.layers[1:] {
+		st, errno := lower.Lstat(dir)
+		if errno != 0 || !st.Mode.IsDir() {
+			continue
+		}
+		if errno = o.layers[0].Mkdir(dir, st.Mode.Perm()|0o700); errno != 0 {
+			return errno
+		}
+		return o.layers[0].Utimens(dir, st.Atim, st.Mtim)
+	}
+	return experimentalsys.ENOENT
+}
+
+// copyUp ensures the entry exists in the upper layer, copying it from the
+// topmost lower layer which has it.
+func (o *OverlayFS) copyUp(e *overlayEntry) experimentalsys.Errno {
+	if e.inUpper() {
+		return 0
+	}
+	if e.isDir() {
+		return o.copyUpDir(e.path)
+	}
+	if errno := o.copyUpDir(path.Dir(e.path)); errno != 0 {
+		return errno
+	}
+
+	lower, upper := o.layers[e.layers[0]], o.layers[0]
+	if e.st.Mode.Type() == fs.ModeSymlink {
+		target, errno := lower.Readlink(e.path)
+		if errno != 0 {
+			return errno
+		}
+		if errno = upper.Symlink(target, e.path); errno != 0 {
+			return errno
+		}
+	} else {
+		if errno := copyFile(lower, upper, e.path); errno != 0 {
+			return errno
+		}
+		if errno := upper.Chmod(e.path, e.st.Mode.Perm()); errno != 0 {
+			return errno
+		}
+		if errno := upper.Utimens(e.path, e.st.Atim, e.st.Mtim); errno != 0 {
+			return errno
+		}
+	}
+	e.layers = []int{0}
+	return 0
+}
+
+// copyFile copies the content of the regular file at the path.
+func copyFile(from, to experimentalsys.FS, p string) experimentalsys.Errno {
+	src, errno := from.OpenFile(p, experimentalsys.O_RDONLY, 0)
+	if errno != 0 {
+		return errno
+	}
+	defer src.Close()
+	dst, errno := to.OpenFile(p, experimentalsys.O_WRONLY|experimentalsys.O_CREAT|experimentalsys.O_TRUNC, 0o600)
+	if errno != 0 {
+		return errno
+	}
+	defer dst.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, errno := src.Read(buf)
+		if errno != 0 {
+			return errno
+		} else if n == 0 {
+			return 0 // EOF
+		}
+		if _, errno = dst.Write(buf[:n]); errno != 0 {
+			return errno
+		}
+	}
+}
+
+// readdir returns the entries of the directory in all its layers, except
+// whiteouts and those they hide, in name order.
+func (o *OverlayFS) readdir(e *overlayEntry) ([]experimentalsys.Dirent, experimentalsys.Errno) {
+	seen := map[string]bool{}
+	var dirents []experimentalsys.Dirent
+	for _, i := range e.layers {
+		f, errno := o.layers[i].OpenFile(e.path, experimentalsys.O_RDONLY|experimentalsys.O_DIRECTORY, 0)
+		if errno != 0 {
+			return nil, errno
+		}
+		layerDirents, errno := f.Readdir(-1)
+		f.Close()
+		if errno != 0 {
+			return nil, errno
+		}
+		for _, d := range layerDirents {
+			if seen[d.Name] {
+				continue
+			}
+			seen[d.Name] = true
+			if name, ok := strings.CutPrefix(d.Name, whiteoutPrefix); ok {
+				seen[name] = true
+				continue
+			}
+			dirents = append(dirents, d)
+		}
+	}
+	sort.Slice(dirents, func(i, j int) bool { return dirents[i].Name < dirents[j].Name })
+	return dirents, 0
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (o *OverlayFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, errno := o.lookup(path, flag&experimentalsys.O_NOFOLLOW == 0)
+	if errno != 0 {
+		return nil, errno
+	}
+
+	writable := flag&(experimentalsys.O_WRONLY|experimentalsys.O_RDWR) != 0
+	switch {
+	case !e.exists:
+		if flag&experimentalsys.O_CREAT == 0 {
+			return nil, experimentalsys.ENOENT
+		}
+		if _, errno = o.prepareCreate(e.path); errno != 0 {
+			return nil, errno
+		}
+		return o.layers[0].OpenFile(e.path, flag, perm)
+	case flag&(experimentalsys.O_CREAT|experimentalsys.O_EXCL) == experimentalsys.O_CREAT|experimentalsys.O_EXCL:
+		return nil, experimentalsys.EEXIST
+	case e.isDir():
+		if writable {
+			return nil, experimentalsys.EISDIR
+		}
+		f, errno := o.layers[e.layers[0]].OpenFile(e.path, flag, perm)
+		if errno != 0 {
+			return nil, errno
+		}
+		return &overlayDir{fs: o, entry: e, f: f}, 0
+	case writable:
+		if errno = o.copyUp(&e); errno != 0 {
+			return nil, errno
+		}
+	default:
+		flag &^= experimentalsys.O_TRUNC // which must not change a lower layer.
+	}
+	return o.layers[e.layers[0]].OpenFile(e.path, flag&^experimentalsys.O_CREAT, perm)
+}
+
+// Lstat implements the same method as documented on sys.FS
+func (o *OverlayFS) Lstat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	return o.statPath(path, false)
+}
+
+// Stat implements the same method as documented on sys.FS
+func (o *OverlayFS) Stat(path string) (sys.Stat_t, experimentalsys.Errno) {
+	return o.statPath(path, true)
+}
+
+func (o *OverlayFS) statPath(path string, followLast bool) (sys.Stat_t, experimentalsys.Errno) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, errno := o.lookup(path, followLast)
+	if errno != 0 {
+		return sys.Stat_t{}, errno
+	} else if !e.exists {
+		return sys.Stat_t{}, experimentalsys.ENOENT
+	}
+	return e.st, 0
+}
+
+// Mkdir implements the same method as documented on sys.FS
+func (o *OverlayFS) Mkdir(path string, perm fs.FileMode) experimentalsys.Errno {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, errno := o.lookup(path, false)
+	if errno != 0 {
+		return errno
+	} else if e.exists {
+		return experimentalsys.EEXIST
+	}
+	whiteout, errno := o.prepareCreate(e.path)
+	if errno != 0 {
+		return errno
+	}
+	if errno = o.layers[0].Mkdir(e.path, perm); errno != 0 || !whiteout {
+		return errno
+	}
+	// Don't merge the new directory with a deleted one in a lower layer.
+	return o.opaque(e.path)
+}
+
+// Chmod implements the same method as documented on sys.FS
+func (o *OverlayFS) Chmod(path string, perm fs.FileMode) experimentalsys.Errno {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, errno := o.lookupUpper(path)
+	if errno != 0 {
+		return errno
+	}
+	return o.layers[0].Chmod(e.path, perm)
+}
+
+// Utimens implements the same method as documented on sys.FS
+func (o *OverlayFS) Utimens(path string, atim, mtim int64) experimentalsys.Errno {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, errno := o.lookupUpper(path)
+	if errno != 0 {
+		return errno
+	}
+	return o.layers[0].Utimens(e.path, atim, mtim)
+}
+
+// lookupUpper returns the existing entry at the path, following symbolic
+// links, after copying it up.
+func (o *OverlayFS) lookupUpper(path string) (overlayEntry, experimentalsys.Errno) {
+	e, errno := o.lookup(path, true)
+	if errno != 0 {
+		return overlayEntry{}, errno
+	} else if !e.exists {
+		return overlayEntry{}, experimentalsys.ENOENT
+	}
+	return e, o.copyUp(&e)
+}
+
+// Rename implements the same method as documented on sys.FS
+func (o *OverlayFS) Rename(from, to string) experimentalsys.Errno {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	src, errno := o.lookup(from, false)
+	if errno != 0 {
+		return errno
+	} else if !src.exists {
+		return experimentalsys.ENOENT
+	}
+	dst, errno := o.lookup(to, false)
+	if errno != 0 {
+		return errno
+	} else if src.path == dst.path {
+		return 0
+	} else if src.path == "." || dst.path == "." {
+		return experimentalsys.EINVAL
+	}
+
+	if src.isDir() {
+		if src.inLower {
+			return experimentalsys.EXDEV // like overlayfs without redirect_dir
+		} else if strings.HasPrefix(dst.path, src.path+"/") {
+			return experimentalsys.EINVAL // a directory can't be moved into itself.
+		}
+		if dst.exists {
+			if !dst.isDir() {
+				return experimentalsys.ENOTDIR
+			} else if errno = o.removableDir(&dst); errno != 0 {
+				return errno
+			}
+		}
+	} else if dst.exists && dst.isDir() {
+		return experimentalsys.EISDIR
+	}
+
+	if errno = o.copyUp(&src); errno != 0 {
+		return errno
+	}
+	whiteout, errno := o.prepareCreate(dst.path)
+	if errno != 0 {
+		return errno
+	}
+	if dst.inUpper() && dst.isDir() {
+		if errno = o.clearDir(dst.path); errno != 0 {
+			return errno
+		}
+	}
+	if errno = o.layers[0].Rename(src.path, dst.path); errno != 0 {
+		return errno
+	}
+	if src.isDir() && (whiteout || dst.inLower) {
+		if errno = o.opaque(dst.path); errno != 0 {
+			return errno
+		}
+	}
+	if src.inLower {
+		return o.whiteout(src.path)
+	}
+	return 0
+}
+
+// removableDir returns zero if the directory is empty in the overlay, so can
+// be removed, or replaced by another one.
+func (o *OverlayFS) removableDir(e *overlayEntry) experimentalsys.Errno {
+	if e.path == "." {
+		return experimentalsys.EINVAL
+	}
+	dirents, errno := o.readdir(e)
+	if errno != 0 {
+		return errno
+	} else if len(dirents) > 0 {
+		return experimentalsys.ENOTEMPTY
+	}
+	return 0
+}
+
+// clearDir removes the whiteouts in the directory in the upper layer, which
+// is empty in the overlay, so that it can be removed.
+func (o *OverlayFS) clearDir(dir string) experimentalsys.Errno {
+	f, errno := o.layers[0].OpenFile(dir, experimentalsys.O_RDONLY|experimentalsys.O_DIRECTORY, 0)
+	if errno != 0 {
+		return errno
+	}
+	dirents, errno := f.Readdir(-1)
+	f.Close()
+	if errno != 0 {
+		return errno
+	}
+	for _, d := range dirents {
+		if errno = o.layers[0].Unlink(path.Join(dir, d.Name)); errno != 0 {
+			return errno
+		}
+	}
+	return 0
+}
+
+// Rmdir implements the same method as documented on sys.FS
+func (o *OverlayFS) Rmdir(path string) experimentalsys.Errno {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, errno := o.lookup(path, false)
+	switch {
+	case errno != 0:
+		return errno
+	case !e.exists:
+		return experimentalsys.ENOENT
+	case !e.isDir():
+		return experimentalsys.ENOTDIR
+	}
+	if errno = o.removableDir(&e); errno != 0 {
+		return errno
+	}
+	if e.inUpper() {
+		if errno = o.clearDir(e.path); errno != 0 {
+			return errno
+		}
+		if errno = o.layers[0].Rmdir(e.path); errno != 0 {
+			return errno
+		}
+	}
+	return o.hide(&e)
+}
+
+// Unlink implements the same method as documented on sys.FS
+func (o *OverlayFS) Unlink(path string) experimentalsys.Errno {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, errno := o.lookup(path, false)
+	switch {
+	case errno != 0:
+		return errno
+	case !e.exists:
+		return experimentalsys.ENOENT
+	case e.isDir():
+		return experimentalsys.EISDIR
+	}
+	if e.inUpper() {
+		if errno = o.layers[0].Unlink(e.path); errno != 0 {
+			return errno
+		}
+	}
+	return o.hide(&e)
+}
+
+// hide adds a whiteout for the removed entry if it exists in a lower layer.
+func (o *OverlayFS) hide(e *overlayEntry) experimentalsys.Errno {
+	if !e.inLower {
+		return 0
+	}
+	if errno := o.copyUpDir(path.Dir(e.path)); errno != 0 {
+		return errno
+	}
+	return o.whiteout(e.path)
+}
+
+// Link implements the same method as documented on sys.FS
+func (o *OverlayFS) Link(oldPath, newPath string) experimentalsys.Errno {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	src, errno := o.lookup(oldPath, false)
+	if errno != 0 {
+		return errno
+	} else if !src.exists {
+		return experimentalsys.ENOENT
+	} else if src.isDir() {
+		return experimentalsys.EPERM
+	}
+	dst, errno := o.lookup(newPath, false)
+	if errno != 0 {
+		return errno
+	} else if dst.exists {
+		return experimentalsys.EEXIST
+	}
+	if errno = o.copyUp(&src); errno != 0 {
+		return errno
+	}
+	if _, errno = o.prepareCreate(dst.path); errno != 0 {
+		return errno
+	}
+	return o.layers[0].Link(src.path, dst.path)
+}
+
+// Symlink implements the same method as documented on sys.FS
+func (o *OverlayFS) Symlink(oldPath, linkName string) experimentalsys.Errno {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, errno := o.lookup(linkName, false)
+	if errno != 0 {
+		return errno
+	} else if e.exists {
+		return experimentalsys.EEXIST
+	}
+	if _, errno = o.prepareCreate(e.path); errno != 0 {
+		return errno
+	}
+	return o.layers[0].Symlink(oldPath, e.path)
+}
+
+// Readlink implements the same method as documented on sys.FS
+func (o *OverlayFS) Readlink(path string) (string, experimentalsys.Errno) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, errno := o.lookup(path, false)
+	if errno != 0 {
+		return "", errno
+	} else if !e.exists {
+		return "", experimentalsys.ENOENT
+	}
+	return o.layers[e.layers[0]].Readlink(e.path)
+}
+
+// Changes returns the changes in the upper layer relative to the lower
+// layers, in path order. For example, after a guest deleted "a.txt" from a
+// lower layer and wrote "b.txt", this returns a ChangeDeleted for "a.txt" and
+// a ChangeAdded or ChangeModified for "b.txt".
+func (o *OverlayFS) Changes() ([]OverlayChange, experimentalsys.Errno) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var changes []OverlayChange
+	if errno := o.changes(".", &changes); errno != 0 {
+		return nil, errno
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, 0
+}
+
+func (o *OverlayFS) changes(dir string, changes *[]OverlayChange) experimentalsys.Errno {
+	f, errno := o.layers[0].OpenFile(dir, experimentalsys.O_RDONLY|experimentalsys.O_DIRECTORY, 0)
+	if errno != 0 {
+		return errno
+	}
+	dirents, errno := f.Readdir(-1)
+	f.Close()
+	if errno != 0 {
+		return errno
+	}
+
+	for _, d := range dirents {
+		p := path.Join(dir, d.Name)
+		if d.Name == opaqueWhiteout {
+			continue
+		} else if name, ok := strings.CutPrefix(d.Name, whiteoutPrefix); ok {
+			*changes = append(*changes, OverlayChange{Path: path.Join(dir, name), Kind: ChangeDeleted})
+			continue
+		}
+
+		kind := ChangeAdded
+		for _, lower := range o.layers[1:] {
+			if _, errno = lower.Lstat(p); errno == 0 {
+				kind = ChangeModified
+				break
+			}
+		}
+		*changes = append(*changes, OverlayChange{Path: p, Kind: kind})
+
+		if d.IsDir() {
+			if errno = o.changes(p, changes); errno != 0 {
+				return errno
+			}
+		}
+	}
+	return 0
+}
+
+// Discard removes all changes from the upper layer, so that the overlay is
+// the same as the lower layers. Files already open are unaffected.
+func (o *OverlayFS) Discard() experimentalsys.Errno {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.removeAll(".")
+}
+
+// removeAll removes the contents of the directory in the upper layer.
+func (o *OverlayFS) removeAll(dir string) experimentalsys.Errno {
+	f, errno := o.layers[0].OpenFile(dir, experimentalsys.O_RDONLY|experimentalsys.O_DIRECTORY, 0)
+	if errno != 0 {
+		return errno
+	}
+	dirents, errno := f.Readdir(-1)
+	f.Close()
+	if errno != 0 {
+		return errno
+	}
+
+	for _, d := range dirents {
+		p := path.Join(dir, d.Name)
+		if !d.IsDir() {
+			errno = o.layers[0].Unlink(p)
+		} else if errno = o.removeAll(p); errno == 0 {
+			errno = o.layers[0].Rmdir(p)
+		}
+		if errno != 0 {
+			return errno
+		}
+	}
+	return 0
+}
+
+// compile-time check to ensure overlayDir implements sys.File.
+var _ experimentalsys.File = (*overlayDir)(nil)
+
+// overlayDir is an open directory in an OverlayFS, which lists the entries
+// of all its layers.
+type overlayDir struct {
+	experimentalsys.DirFile
+
+	fs    *OverlayFS
+	entry overlayEntry
+
+	// f is the directory in the topmost layer.
+	f experimentalsys.File
+
+	// dirents are the remaining entries to return from Readdir, or nil if not
+	// yet read since the directory was opened or rewound.
+	dirents []experimentalsys.Dirent
+	closed  bool
+}
+
+// Dev implements the same method as documented on sys.File
+func (d *overlayDir) Dev() (uint64, experimentalsys.Errno) {
+	return d.f.Dev()
+}
+
+// Ino implements the same method as documented on sys.File
+func (d *overlayDir) Ino() (sys.Inode, experimentalsys.Errno) {
+	return d.f.Ino()
+}
+
+// Stat implements the same method as documented on sys.File
+func (d *overlayDir) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	return d.f.Stat()
+}
+
+// Seek implements the same method as documented on sys.File
+func (d *overlayDir) Seek(offset int64, whence int) (int64, experimentalsys.Errno) {
+	if d.closed {
+		return 0, experimentalsys.EBADF
+	} else if offset != 0 || whence != io.SeekStart {
+		return 0, experimentalsys.EISDIR
+	}
+	d.dirents = nil // rewind
+	return 0, 0
+}
+
+// Readdir implements the same method as documented on sys.File
+func (d *overlayDir) Readdir(n int) (dirents []experimentalsys.Dirent, errno experimentalsys.Errno) {
+	if d.closed {
+		return nil, experimentalsys.EBADF
+	}
+	if d.dirents == nil {
+		d.fs.mu.Lock()
+		d.dirents, errno = d.fs.readdir(&d.entry)
+		d.fs.mu.Unlock()
+		if errno != 0 {
+			return nil, errno
+		} else if d.dirents == nil {
+			d.dirents = []experimentalsys.Dirent{}
+		}
+	}
+
+	if n <= 0 || n > len(d.dirents) {
+		n = len(d.dirents)
+	}
+	dirents = d.dirents[:n:n]
+	d.dirents = d.dirents[n:]
+	return dirents, 0
+}
+
+// Sync implements the same method as documented on sys.File
+func (d *overlayDir) Sync() experimentalsys.Errno {
+	return d.f.Sync()
+}
+
+// Datasync implements the same method as documented on sys.File
+func (d *overlayDir) Datasync() experimentalsys.Errno {
+	return d.f.Datasync()
+}
+
+// Utimens implements the same method as documented on sys.File
+func (d *overlayDir) Utimens(atim, mtim int64) experimentalsys.Errno {
+	return d.f.Utimens(atim, mtim)
+}
+
+// Close implements the same method as documented on sys.File
+func (d *overlayDir) Close() experimentalsys.Errno {
+	if d.closed {
+		return 0
+	}
+	d.closed = true
+	return d.f.Close()
+}
@@ -0,0 +1,353 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/fstest"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+// newTestOverlayFS returns an OverlayFS with an empty upper layer over the
+// test files.
+func newTestOverlayFS(t *testing.T) (*OverlayFS, experimentalsys.FS) {
+	lower := newTestMemFS(t)
+	return NewOverlayFS(newMemFS(), lower), lower
+}
+
+func TestOverlayFS_Open_Read(t *testing.T) {
+	testFS, _ := newTestOverlayFS(t)
+	testOpen_Read(t, testFS, true, true)
+}
+
+func TestOverlayFS_Stat(t *testing.T) {
+	testFS, _ := newTestOverlayFS(t)
+	testStat(t, testFS)
+}
+
+func TestOverlayFS_Lstat(t *testing.T) {
+	testFS, _ := newTestOverlayFS(t)
+	for _, path := range []string{"animals.txt", "sub", "sub-link"} {
+		require.EqualErrno(t, 0, testFS.Symlink(path, path+"-link"))
+	}
+
+	testLstat(t, testFS)
+}
+
+func TestOverlayFS_Readlink(t *testing.T) {
+	testFS, _ := newTestOverlayFS(t)
+	testReadlink(t, testFS, testFS)
+}
+
+func TestOverlayFS_Chmod(t *testing.T) {
+	testFS, lower := newTestOverlayFS(t)
+	testChmod(t, testFS, "sub/test.txt")
+
+	// The lower layer didn't change.
+	requireMode(t, lower, "sub/test.txt", 0o444)
+}
+
+func TestOverlayFS_CopyUp(t *testing.T) {
+	testFS, lower := newTestOverlayFS(t)
+
+	f, errno := testFS.OpenFile("sub/test.txt", experimentalsys.O_WRONLY|experimentalsys.O_APPEND, 0)
+	require.EqualErrno(t, 0, errno)
+	_, errno = f.Write([]byte("bye\n"))
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	require.Equal(t, "greet sub dir\nbye\n", readTestFile(t, testFS, "sub/test.txt"))
+	require.Equal(t, "greet sub dir\n", readTestFile(t, lower, "sub/test.txt"))
+
+	// Metadata is copied up with the content.
+	st, errno := testFS.Upper().Stat("sub/test.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.FileMode(0o444), st.Mode)
+	require.Equal(t, int64(1672531200000000000), st.Atim)
+	st, errno = testFS.Upper().Stat("sub")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeDir|0o755, st.Mode)
+
+	// The directory still has the entries of the lower layer.
+	requireOverlayDir(t, testFS, "dir", "-", "a-", "ab-")
+
+	t.Run("O_TRUNC on a read-only file doesn't change the lower layer", func(t *testing.T) {
+		f, errno := testFS.OpenFile("animals.txt", experimentalsys.O_RDONLY|experimentalsys.O_TRUNC, 0)
+		require.EqualErrno(t, 0, errno)
+		require.EqualErrno(t, 0, f.Close())
+
+		st, errno := lower.Stat("animals.txt")
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, int64(30), st.Size)
+	})
+}
+
+func TestOverlayFS_Readdir(t *testing.T) {
+	lower1 := newMemFS()
+	require.EqualErrno(t, 0, lower1.Mkdir("dir", 0o755))
+	for _, name := range []string{"dir/a", "dir/b", "c"} {
+		require.EqualErrno(t, 0, writeTestFile(lower1, name, name))
+	}
+	lower2 := newMemFS()
+	require.EqualErrno(t, 0, lower2.Mkdir("dir", 0o755))
+	require.EqualErrno(t, 0, lower2.Mkdir("c", 0o755))
+	for _, name := range []string{"dir/b", "dir/d", "c/hidden"} {
+		require.EqualErrno(t, 0, writeTestFile(lower2, name, "lower2"))
+	}
+	testFS := NewOverlayFS(newMemFS(), lower1, lower2)
+
+	// A higher layer has priority.
+	requireOverlayDir(t, testFS, "dir", "a", "b", "d")
+	require.Equal(t, "dir/b", readTestFile(t, testFS, "dir/b"))
+
+	// A directory isn't merged with one in a lower layer hidden by a file.
+	_, errno := testFS.Stat("c/hidden")
+	require.EqualErrno(t, experimentalsys.ENOTDIR, errno)
+
+	// Whiteouts and what they hide aren't listed.
+	require.EqualErrno(t, 0, writeTestFile(testFS, "dir/e", "upper"))
+	require.EqualErrno(t, 0, testFS.Unlink("dir/b"))
+	requireOverlayDir(t, testFS, "dir", "a", "d", "e")
+	_, errno = testFS.Stat("dir/b")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+
+	t.Run("rewind", func(t *testing.T) {
+		f, errno := testFS.OpenFile("dir", experimentalsys.O_RDONLY, 0)
+		require.EqualErrno(t, 0, errno)
+		defer f.Close()
+
+		dirents, errno := f.Readdir(2)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 2, len(dirents))
+		dirents, errno = f.Readdir(2)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 1, len(dirents))
+		dirents, errno = f.Readdir(2)
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, len(dirents))
+
+		_, errno = f.Seek(0, io.SeekStart)
+		require.EqualErrno(t, 0, errno)
+		dirents, errno = f.Readdir(-1)
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, 3, len(dirents))
+	})
+}
+
+func TestOverlayFS_Unlink(t *testing.T) {
+	testFS, lower := newTestOverlayFS(t)
+
+	require.EqualErrno(t, 0, testFS.Unlink("animals.txt"))
+	require.EqualErrno(t, experimentalsys.ENOENT, testFS.Unlink("animals.txt"))
+	require.EqualErrno(t, experimentalsys.EISDIR, testFS.Unlink("sub"))
+	_, errno := lower.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+
+	// A whiteout can't be seen or created by the guest.
+	_, errno = testFS.Stat(".wh.animals.txt")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+	_, errno = testFS.OpenFile(".wh.cat", experimentalsys.O_CREAT|experimentalsys.O_WRONLY, 0o600)
+	require.EqualErrno(t, experimentalsys.EPERM, errno)
+
+	// A file created at the same path replaces the whiteout.
+	require.EqualErrno(t, 0, writeTestFile(testFS, "animals.txt", "cat\n"))
+	require.Equal(t, "cat\n", readTestFile(t, testFS, "animals.txt"))
+	_, errno = testFS.Upper().Stat(".wh.animals.txt")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+}
+
+func TestOverlayFS_Rmdir(t *testing.T) {
+	testFS, _ := newTestOverlayFS(t)
+
+	require.EqualErrno(t, experimentalsys.ENOTEMPTY, testFS.Rmdir("sub"))
+	require.EqualErrno(t, 0, testFS.Unlink("sub/test.txt"))
+	require.EqualErrno(t, 0, testFS.Rmdir("sub"))
+	require.EqualErrno(t, experimentalsys.ENOENT, testFS.Rmdir("sub"))
+	require.EqualErrno(t, experimentalsys.ENOTDIR, testFS.Rmdir("animals.txt"))
+
+	// A directory created at the same path doesn't have the deleted entries.
+	require.EqualErrno(t, 0, testFS.Mkdir("sub", 0o755))
+	requireOverlayDir(t, testFS, "sub")
+	_, errno := testFS.Stat("sub/test.txt")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+
+	require.EqualErrno(t, 0, testFS.Rmdir("emptydir"))
+	requireOverlayDir(t, testFS, ".", "animals.txt", "dir", "empty.txt", "sub")
+}
+
+func TestOverlayFS_Rename(t *testing.T) {
+	tests := []struct {
+		name          string
+		from, to      string
+		expectedErrno experimentalsys.Errno
+	}{
+		{name: "lower file", from: "animals.txt", to: "sub/zoo.txt"},
+		{name: "lower file over lower file", from: "animals.txt", to: "sub/test.txt"},
+		{name: "lower file to itself", from: "animals.txt", to: "animals.txt"},
+		{name: "upper dir", from: "new", to: "sub/new"},
+		{name: "upper dir over lower empty dir", from: "new", to: "emptydir"},
+		{name: "lower dir", from: "dir", to: "sub/dir", expectedErrno: experimentalsys.EXDEV},
+		{name: "from doesn't exist", from: "nope", to: "new", expectedErrno: experimentalsys.ENOENT},
+		{name: "file over dir", from: "animals.txt", to: "emptydir", expectedErrno: experimentalsys.EISDIR},
+		{name: "dir over file", from: "new", to: "animals.txt", expectedErrno: experimentalsys.ENOTDIR},
+		{name: "dir over non-empty dir", from: "new", to: "sub", expectedErrno: experimentalsys.ENOTEMPTY},
+		{name: "dir into itself", from: "new", to: "new/a/new", expectedErrno: experimentalsys.EINVAL},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			testFS, lower := newTestOverlayFS(t)
+			require.EqualErrno(t, 0, testFS.Mkdir("new", 0o755))
+			require.EqualErrno(t, 0, testFS.Mkdir("new/a", 0o755))
+
+			require.EqualErrno(t, tc.expectedErrno, testFS.Rename(tc.from, tc.to))
+			if tc.expectedErrno != 0 || tc.from == tc.to {
+				return
+			}
+
+			_, errno := testFS.Lstat(tc.from)
+			require.EqualErrno(t, experimentalsys.ENOENT, errno)
+			_, errno = testFS.Lstat(tc.to)
+			require.EqualErrno(t, 0, errno)
+
+			// The lower layer didn't change.
+			_, errno = lower.Lstat("animals.txt")
+			require.EqualErrno(t, 0, errno)
+		})
+	}
+
+	t.Run("dir over lower empty dir is opaque", func(t *testing.T) {
+		testFS, _ := newTestOverlayFS(t)
+		require.EqualErrno(t, 0, testFS.Mkdir("new", 0o755))
+		require.EqualErrno(t, 0, testFS.Rename("new", "emptydir"))
+		requireOverlayDir(t, testFS, "emptydir")
+	})
+}
+
+func TestOverlayFS_Link(t *testing.T) {
+	testFS, _ := newTestOverlayFS(t)
+
+	require.EqualErrno(t, 0, testFS.Link("animals.txt", "sub/animals.txt"))
+	require.EqualErrno(t, experimentalsys.EEXIST, testFS.Link("animals.txt", "sub/test.txt"))
+	require.EqualErrno(t, experimentalsys.EPERM, testFS.Link("sub", "sub2"))
+
+	st1, errno := testFS.Stat("animals.txt")
+	require.EqualErrno(t, 0, errno)
+	st2, errno := testFS.Stat("sub/animals.txt")
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, st1.Ino, st2.Ino)
+}
+
+func TestOverlayFS_Symlink(t *testing.T) {
+	lower := newTestMemFS(t)
+	require.EqualErrno(t, 0, lower.Symlink("/sub/test.txt", "link"))
+	testFS := NewOverlayFS(newMemFS(), lower)
+
+	// A link in a lower layer resolves in the overlay.
+	require.EqualErrno(t, 0, testFS.Unlink("sub/test.txt"))
+	_, errno := testFS.Stat("link")
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+
+	// Even if it was created in the lower layer.
+	require.EqualErrno(t, 0, writeTestFile(testFS, "link", "created"))
+	require.Equal(t, "created", readTestFile(t, testFS, "sub/test.txt"))
+	require.Equal(t, "greet sub dir\n", readTestFile(t, lower, "sub/test.txt"))
+}
+
+func TestOverlayFS_DirFS(t *testing.T) {
+	lowerDir, upperDir := t.TempDir(), t.TempDir()
+	require.NoError(t, fstest.WriteTestFiles(lowerDir))
+	testFS := NewOverlayFS(DirFS(upperDir), DirFS(lowerDir))
+
+	require.EqualErrno(t, 0, writeTestFile(testFS, "dir/a-/new", "new"))
+	require.EqualErrno(t, 0, testFS.Unlink("dir/-"))
+
+	b, err := os.ReadFile(path.Join(upperDir, "dir", "a-", "new"))
+	require.NoError(t, err)
+	require.Equal(t, "new", string(b))
+	_, err = os.Stat(path.Join(upperDir, "dir", ".wh.-"))
+	require.NoError(t, err)
+	_, err = os.Stat(path.Join(lowerDir, "dir", "-"))
+	require.NoError(t, err)
+
+	requireOverlayDir(t, testFS, "dir", "a-", "ab-")
+}
+
+func TestOverlayFS_Changes(t *testing.T) {
+	testFS, _ := newTestOverlayFS(t)
+
+	changes, errno := testFS.Changes()
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, len(changes))
+
+	require.EqualErrno(t, 0, testFS.Unlink("dir/-"))
+	require.EqualErrno(t, 0, writeTestFile(testFS, "dir/a-/new", "new"))
+	require.EqualErrno(t, 0, testFS.Chmod("animals.txt", 0o600))
+	require.EqualErrno(t, 0, testFS.Rmdir("emptydir"))
+	require.EqualErrno(t, 0, testFS.Mkdir("emptydir", 0o700))
+
+	changes, errno = testFS.Changes()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, []OverlayChange{
+		{Path: "animals.txt", Kind: ChangeModified},
+		{Path: "dir", Kind: ChangeModified},
+		{Path: "dir/-", Kind: ChangeDeleted},
+		{Path: "dir/a-", Kind: ChangeModified},
+		{Path: "dir/a-/new", Kind: ChangeAdded},
+		{Path: "emptydir", Kind: ChangeModified},
+	}, changes)
+
+	t.Run("Discard", func(t *testing.T) {
+		require.EqualErrno(t, 0, testFS.Discard())
+
+		changes, errno = testFS.Changes()
+		require.EqualErrno(t, 0, errno)
+		require.Zero(t, len(changes))
+
+		requireOverlayDir(t, testFS, "dir", "-", "a-", "ab-")
+		requireMode(t, testFS, "animals.txt", 0o644)
+	})
+}
+
+// requireOverlayDir requires the directory to have the entries.
+func requireOverlayDir(t *testing.T, testFS experimentalsys.FS, dir string, expected ...string) {
+	f, errno := testFS.OpenFile(dir, experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	dirents, errno := f.Readdir(-1)
+	require.EqualErrno(t, 0, errno)
+	names := []string{}
+	for _, d := range dirents {
+		names = append(names, d.Name)
+	}
+	sort.Strings(names)
+	require.Equal(t, append([]string{}, expected...), names)
+}
+
+func readTestFile(t *testing.T, testFS experimentalsys.FS, name string) string {
+	f, errno := testFS.OpenFile(name, experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	buf := make([]byte, 64)
+	n, errno := f.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	return string(buf[:n])
+}
+
+func writeTestFile(testFS experimentalsys.FS, name, data string) experimentalsys.Errno {
+	f, errno := testFS.OpenFile(name, experimentalsys.O_WRONLY|experimentalsys.O_CREAT|experimentalsys.O_TRUNC, 0o600)
+	if errno != 0 {
+		return errno
+	}
+	defer f.Close()
+	_, errno = f.Write([]byte(data))
+	return errno
+}
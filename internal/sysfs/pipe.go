@@ -0,0 +1,379 @@
+package sysfs
+
+import (
+	"io/fs"
+	"sync"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+// pipeBufSize is the count of bytes a pipe holds before writes block, the
+// same as the default on Linux.
+const pipeBufSize = 64 * 1024
+
+// NewPipe returns the read and write ends of an in-memory pipe, like
+// os.Pipe, except nothing is allocated on the host.
+//
+// Reads block until data is written, and return EOF once every handle to the
+// write end is closed. Writes block while the pipe is full, and return
+// sys.EPIPE once every handle to the read end is closed. Both ends support
+// non-blocking mode and polling, including sys.POLLHUP.
+func NewPipe() (r, w experimentalsys.File) {
+	p := &pipe{readers: 1, writers: 1, changed: make(chan struct{}), readable: make(chan struct{}), writable: closedChan}
+	return &pipeEnd{p: p, read: true}, &pipeEnd{p: p}
+}
+
+// NewPTY returns the ends of an in-memory pseudo-terminal. Bytes written to
+// one end are read from the other, and both ends are character devices, so
+// that a guest using guest as stdio sees a terminal, like isatty does.
+//
+// Unlike a host pseudo-terminal, there is no line discipline: input isn't
+// echoed or edited by line, which is left to the host end.
+func NewPTY() (host, guest experimentalsys.File) {
+	hostR, guestW := NewPipe()
+	guestR, hostW := NewPipe()
+	host = &ptyEnd{r: hostR.(*pipeEnd), w: hostW.(*pipeEnd)}
+	guest = &ptyEnd{r: guestR.(*pipeEnd), w: guestW.(*pipeEnd)}
+	return
+}
+
+// pipe is the state shared by the ends of a pipe.
+type pipe struct {
+	mu  sync.Mutex
+	buf []byte
+
+	// readers and writers are the count of open handles to each end.
+	readers, writers int
+
+	// changed is closed and replaced when any of the above changes.
+	changed chan struct{}
+
+	// readable and writable are closed while the read or write end is ready,
+	// for sys.PollNotifier.
+	readable, writable chan struct{}
+}
+
+// notify wakes up anything waiting for a change. This must be called with mu
+// locked.
+func (p *pipe) notify() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+	setReady(&p.readable, len(p.buf) > 0 || p.writers == 0)
+	setReady(&p.writable, len(p.buf) < pipeBufSize || p.readers == 0)
+}
+
+// setReady closes ch if ready, or replaces it if it was closed and isn't.
+func setReady(ch *chan struct{}, ready bool) {
+	select {
+	case <-*ch:
+		if !ready {
+			*ch = make(chan struct{})
+		}
+	default:
+		if ready {
+			close(*ch)
+		}
+	}
+}
+
+// wait unlocks mu until the next change. This must be called with mu locked.
+func (p *pipe) wait() {
+	changed := p.changed
+	p.mu.Unlock()
+	<-changed
+	p.mu.Lock()
+}
+
+// pipeEnd is a handle to one end of a pipe.
+type pipeEnd struct {
+	experimentalsys.UnimplementedFile
+
+	p        *pipe
+	read     bool
+	closed   bool
+	nonblock bool
+}
+
+// Dup returns another handle to the same end of the pipe. The end is only
+// closed once all handles are closed.
+func (f *pipeEnd) Dup() experimentalsys.File {
+	f.p.mu.Lock()
+	defer f.p.mu.Unlock()
+
+	if f.closed {
+		return &pipeEnd{p: f.p, read: f.read, closed: true}
+	} else if f.read {
+		f.p.readers++
+	} else {
+		f.p.writers++
+	}
+	return &pipeEnd{p: f.p, read: f.read, closed: f.closed, nonblock: f.nonblock}
+}
+
+// IsDir implements the same method as documented on sys.File
+func (f *pipeEnd) IsDir() (bool, experimentalsys.Errno) {
+	return false, 0
+}
+
+// Stat implements the same method as documented on sys.File
+func (f *pipeEnd) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	f.p.mu.Lock()
+	defer f.p.mu.Unlock()
+
+	if f.closed {
+		return sys.Stat_t{}, experimentalsys.EBADF
+	}
+	return sys.Stat_t{Mode: fs.ModeNamedPipe | 0o600, Nlink: 1, Size: int64(len(f.p.buf))}, 0
+}
+
+// Read implements the same method as documented on sys.File
+func (f *pipeEnd) Read(buf []byte) (int, experimentalsys.Errno) {
+	if !f.read {
+		return 0, experimentalsys.EBADF
+	} else if len(buf) == 0 {
+		return 0, 0 // less overhead on zero-length reads.
+	}
+
+	p := f.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		if f.closed {
+			return 0, experimentalsys.EBADF
+		} else if len(p.buf) > 0 {
+			n := copy(buf, p.buf)
+			p.buf = p.buf[n:]
+			p.notify()
+			return n, 0
+		} else if p.writers == 0 {
+			return 0, 0 // EOF
+		} else if f.nonblock {
+			return 0, experimentalsys.EAGAIN
+		}
+		p.wait()
+	}
+}
+
+// Write implements the same method as documented on sys.File
+func (f *pipeEnd) Write(buf []byte) (n int, errno experimentalsys.Errno) {
+	if f.read {
+		return 0, experimentalsys.EBADF
+	}
+
+	p := f.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(buf) > 0 {
+		if f.closed {
+			return n, experimentalsys.EBADF
+		} else if p.readers == 0 {
+			return n, experimentalsys.EPIPE
+		} else if free := pipeBufSize - len(p.buf); free > 0 {
+			written := min(free, len(buf))
+			p.buf = append(p.buf, buf[:written]...)
+			buf = buf[written:]
+			n += written
+			p.notify()
+		} else if f.nonblock {
+			if n == 0 {
+				errno = experimentalsys.EAGAIN
+			}
+			return
+		} else {
+			p.wait()
+		}
+	}
+	return
+}
+
+// hangup implements the same method as documented on hangupFile
+func (f *pipeEnd) hangup() bool {
+	f.p.mu.Lock()
+	defer f.p.mu.Unlock()
+
+	if f.read {
+		return f.p.writers == 0
+	}
+	return f.p.readers == 0
+}
+
+// Poll implements the same method as documented on sys.Pollable
+func (f *pipeEnd) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	ch := f.PollNotify(flag)
+	if ch == nil {
+		return false, experimentalsys.ENOTSUP
+	}
+	return awaitNotify(ch, timeoutMillis), 0
+}
+
+// PollNotify implements the same method as documented on sys.PollNotifier
+func (f *pipeEnd) PollNotify(flag experimentalsys.Pflag) <-chan struct{} {
+	if (f.read && flag != experimentalsys.POLLIN) || (!f.read && flag != experimentalsys.POLLOUT) {
+		return nil
+	}
+
+	p := f.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if f.closed {
+		return closedChan // operations fail with EBADF
+	} else if f.read {
+		return p.readable
+	}
+	return p.writable
+}
+
+// IsNonblock implements the same method as documented on sys.PollableFile
+func (f *pipeEnd) IsNonblock() bool {
+	f.p.mu.Lock()
+	defer f.p.mu.Unlock()
+
+	return f.nonblock
+}
+
+// SetNonblock implements the same method as documented on sys.PollableFile
+func (f *pipeEnd) SetNonblock(enable bool) experimentalsys.Errno {
+	f.p.mu.Lock()
+	defer f.p.mu.Unlock()
+
+	f.nonblock = enable
+	return 0
+}
+
+// Close implements the same method as documented on sys.File
+func (f *pipeEnd) Close() experimentalsys.Errno {
+	p := f.p
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if f.closed {
+		return 0
+	}
+	f.closed = true
+	if f.read {
+		if p.readers--; p.readers == 0 {
+			p.buf = nil // Nothing can read it anymore.
+		}
+	} else {
+		p.writers--
+	}
+	p.notify()
+	return 0
+}
+
+// ptyEnd is a handle to one end of a pseudo-terminal, which reads from one
+// pipe and writes to another.
+type ptyEnd struct {
+	experimentalsys.UnimplementedFile
+
+	r, w *pipeEnd
+}
+
+// Dup returns another handle to the same end of the pseudo-terminal.
+func (f *ptyEnd) Dup() experimentalsys.File {
+	return &ptyEnd{r: f.r.Dup().(*pipeEnd), w: f.w.Dup().(*pipeEnd)}
+}
+
+// IsDir implements the same method as documented on sys.File
+func (f *ptyEnd) IsDir() (bool, experimentalsys.Errno) {
+	return false, 0
+}
+
+// Stat implements the same method as documented on sys.File
+func (f *ptyEnd) Stat() (sys.Stat_t, experimentalsys.Errno) {
+	if _, errno := f.r.Stat(); errno != 0 {
+		return sys.Stat_t{}, errno
+	}
+	return sys.Stat_t{Mode: fs.ModeDevice | fs.ModeCharDevice | 0o620, Nlink: 1}, 0
+}
+
+// Read implements the same method as documented on sys.File
+func (f *ptyEnd) Read(buf []byte) (int, experimentalsys.Errno) {
+	return f.r.Read(buf)
+}
+
+// Write implements the same method as documented on sys.File
+func (f *ptyEnd) Write(buf []byte) (int, experimentalsys.Errno) {
+	return f.w.Write(buf)
+}
+
+// hangup implements the same method as documented on hangupFile
+func (f *ptyEnd) hangup() bool {
+	return f.r.hangup()
+}
+
+// Poll implements the same method as documented on sys.Pollable
+func (f *ptyEnd) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if flag == experimentalsys.POLLIN|experimentalsys.POLLOUT {
+		if ready, errno = f.w.Poll(experimentalsys.POLLOUT, 0); ready || errno != 0 {
+			return
+		}
+		return f.r.Poll(experimentalsys.POLLIN, timeoutMillis)
+	}
+	return f.end(flag).Poll(flag, timeoutMillis)
+}
+
+// PollNotify implements the same method as documented on sys.PollNotifier
+func (f *ptyEnd) PollNotify(flag experimentalsys.Pflag) <-chan struct{} {
+	return f.end(flag).PollNotify(flag)
+}
+
+// end returns the pipe which is polled for flag, or the read end when flag
+// is more than one event, which isn't supported by pipeEnd.PollNotify.
+func (f *ptyEnd) end(flag experimentalsys.Pflag) *pipeEnd {
+	if flag == experimentalsys.POLLOUT {
+		return f.w
+	}
+	return f.r
+}
+
+// IsNonblock implements the same method as documented on sys.PollableFile
+func (f *ptyEnd) IsNonblock() bool {
+	return f.r.IsNonblock()
+}
+
+// SetNonblock implements the same method as documented on sys.PollableFile
+func (f *ptyEnd) SetNonblock(enable bool) experimentalsys.Errno {
+	_ = f.r.SetNonblock(enable)
+	return f.w.SetNonblock(enable)
+}
+
+// Close implements the same method as documented on sys.File
+func (f *ptyEnd) Close() experimentalsys.Errno {
+	_ = f.r.Close()
+	return f.w.Close()
+}
+
+// closedChan is returned by PollNotify when a file is ready now.
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// awaitNotify waits up to timeoutMillis for ch to close, and returns true
+// if it did.
+func awaitNotify(ch <-chan struct{}, timeoutMillis int32) bool {
+	if timeoutMillis == 0 {
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	} else if timeoutMillis < 0 {
+		<-ch
+		return true
+	}
+	timer := time.NewTimer(time.Duration(timeoutMillis) * time.Millisecond)
+	defer timer.Stop()
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
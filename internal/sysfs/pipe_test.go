@@ -0,0 +1,208 @@
+package sysfs
+
+import (
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestPipe(t *testing.T) {
+	r, w := NewPipe()
+	defer r.Close()
+	defer w.Close()
+
+	st, errno := r.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, fs.ModeNamedPipe|0o600, st.Mode)
+
+	// Each end only supports its direction.
+	_, errno = r.Write([]byte("a"))
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+	_, errno = w.Read(make([]byte, 1))
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+
+	n, errno := w.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+
+	st, errno = r.Stat()
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(6), st.Size)
+
+	buf := make([]byte, 4)
+	n, errno = r.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "waze", string(buf[:n]))
+	n, errno = r.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "ro", string(buf[:n]))
+
+	// A blocking read waits for a write.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte("!"))
+	}()
+	n, errno = r.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "!", string(buf[:n]))
+}
+
+func TestPipe_EOF(t *testing.T) {
+	r, w := NewPipe()
+	defer r.Close()
+
+	_, errno := w.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+
+	// Data written before the write end closed is read before EOF.
+	w2 := w.(*pipeEnd).Dup()
+	require.EqualErrno(t, 0, w.Close())
+	require.EqualErrno(t, 0, w2.Close())
+	b, err := io.ReadAll(&FileIO{File: r})
+	require.NoError(t, err)
+	require.Equal(t, "wazero", string(b))
+
+	// Writes after closing fail.
+	_, errno = w.Write([]byte("a"))
+	require.EqualErrno(t, experimentalsys.EBADF, errno)
+}
+
+func TestPipe_EPIPE(t *testing.T) {
+	r, w := NewPipe()
+	defer w.Close()
+
+	// A blocking write waits for a read, then fails when the read end closes.
+	n, errno := w.Write(make([]byte, pipeBufSize))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, pipeBufSize, n)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = r.Read(make([]byte, 10))
+		time.Sleep(10 * time.Millisecond)
+		_ = r.Close()
+	}()
+	n, errno = w.Write(make([]byte, 20))
+	require.EqualErrno(t, experimentalsys.EPIPE, errno)
+	require.Equal(t, 10, n)
+}
+
+func TestPipe_Nonblock(t *testing.T) {
+	r, w := NewPipe()
+	defer r.Close()
+	defer w.Close()
+
+	for _, f := range []experimentalsys.File{r, w} {
+		pf := f.(experimentalsys.PollableFile)
+		require.False(t, pf.IsNonblock())
+		require.EqualErrno(t, 0, pf.SetNonblock(true))
+		require.True(t, pf.IsNonblock())
+	}
+
+	_, errno := r.Read(make([]byte, 1))
+	require.EqualErrno(t, experimentalsys.EAGAIN, errno)
+
+	// Writes are partial when the pipe fills up.
+	n, errno := w.Write(make([]byte, pipeBufSize-1))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, pipeBufSize-1, n)
+	n, errno = w.Write(make([]byte, 2))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	_, errno = w.Write(make([]byte, 1))
+	require.EqualErrno(t, experimentalsys.EAGAIN, errno)
+}
+
+func TestPipe_PollFiles(t *testing.T) {
+	r, w := NewPipe()
+	defer r.Close()
+	defer w.Close()
+
+	events := []PollEvent{
+		{File: r, Flag: experimentalsys.POLLIN},
+		{File: w, Flag: experimentalsys.POLLOUT},
+	}
+
+	// The pipe is empty, so it can be written to, but not read from.
+	n, errno := PollFiles(nil, events, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.Equal(t, experimentalsys.Pflag(0), events[0].Revents)
+	require.Equal(t, experimentalsys.POLLOUT, events[1].Revents)
+
+	// A write wakes up a reader waiting in poll.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w.Write([]byte("wazero"))
+	}()
+	n, errno = PollFiles(nil, events[:1], -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.Equal(t, experimentalsys.POLLIN, events[0].Revents)
+
+	// Poll doesn't consume data.
+	ready, errno := r.(experimentalsys.Pollable).Poll(experimentalsys.POLLIN, 0)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	// Closing the write end is reported as a hang up.
+	require.EqualErrno(t, 0, w.Close())
+	n, errno = PollFiles(nil, events[:1], 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.Equal(t, experimentalsys.POLLIN|experimentalsys.POLLHUP, events[0].Revents)
+
+	// Only the direction of each end is supported.
+	_, errno = r.(experimentalsys.Pollable).Poll(experimentalsys.POLLOUT, 0)
+	require.EqualErrno(t, experimentalsys.ENOTSUP, errno)
+}
+
+func TestPTY(t *testing.T) {
+	host, guest := NewPTY()
+	defer host.Close()
+
+	for _, f := range []experimentalsys.File{host, guest} {
+		st, errno := f.Stat()
+		require.EqualErrno(t, 0, errno)
+		require.Equal(t, fs.ModeDevice|fs.ModeCharDevice|0o620, st.Mode)
+	}
+
+	// Each end reads what the other writes.
+	_, errno := host.Write([]byte("ls\n"))
+	require.EqualErrno(t, 0, errno)
+	_, errno = guest.Write([]byte("a.txt\n"))
+	require.EqualErrno(t, 0, errno)
+
+	buf := make([]byte, 10)
+	n, errno := guest.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "ls\n", string(buf[:n]))
+	n, errno = host.Read(buf)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "a.txt\n", string(buf[:n]))
+
+	// Polling for either event returns when one is ready.
+	ready, errno := guest.(experimentalsys.Pollable).Poll(experimentalsys.POLLIN|experimentalsys.POLLOUT, 0)
+	require.EqualErrno(t, 0, errno)
+	require.True(t, ready)
+
+	// The host sees a hang up once all handles to the guest end are closed.
+	dup := guest.(*ptyEnd).Dup()
+	require.EqualErrno(t, 0, guest.Close())
+	events := []PollEvent{{File: host, Flag: experimentalsys.POLLIN}}
+	n, errno = PollFiles(nil, events, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 0, n)
+
+	require.EqualErrno(t, 0, dup.Close())
+	n, errno = PollFiles(nil, events, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.Equal(t, experimentalsys.POLLIN|experimentalsys.POLLHUP, events[0].Revents)
+
+	_, errno = host.Write([]byte("exit\n"))
+	require.EqualErrno(t, experimentalsys.EPIPE, errno)
+}
@@ -0,0 +1,274 @@
+package sysfs
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// PolicyOp is an operation checked by a Policy.
+type PolicyOp uint8
+
+const (
+	// PolicyOpOpen is sys.FS OpenFile.
+	PolicyOpOpen PolicyOp = iota
+	// PolicyOpMkdir is sys.FS Mkdir.
+	PolicyOpMkdir
+	// PolicyOpRmdir is sys.FS Rmdir.
+	PolicyOpRmdir
+	// PolicyOpRename is sys.FS Rename, checked for both paths.
+	PolicyOpRename
+	// PolicyOpUnlink is sys.FS Unlink.
+	PolicyOpUnlink
+	// PolicyOpLink is sys.FS Link, checked for both paths.
+	PolicyOpLink
+	// PolicyOpSymlink is sys.FS Symlink, checked for the link name.
+	PolicyOpSymlink
+	// PolicyOpChmod is sys.FS Chmod.
+	PolicyOpChmod
+	// PolicyOpUtimens is sys.FS Utimens.
+	PolicyOpUtimens
+)
+
+var policyOpNames = [...]string{
+	"open", "mkdir", "rmdir", "rename", "unlink", "link", "symlink", "chmod", "utimens",
+}
+
+// String implements fmt.Stringer
+func (o PolicyOp) String() string {
+	if int(o) < len(policyOpNames) {
+		return policyOpNames[o]
+	}
+	return "unknown"
+}
+
+// Policy decides if an operation on a path is allowed, returning zero, or
+// the errno to return to the guest otherwise.
+//
+// The path is relative to the root of the PolicyFS, such as "data/a.json",
+// not the path the guest uses: when the PolicyFS is mounted at "/mnt", the
+// guest path "/mnt/data/a.json" is checked as "data/a.json".
+//
+// The flag is the one passed to OpenFile for PolicyOpOpen. For other
+// operations, it is sys.O_CREAT when the path is created, such as the new
+// path of a rename, and zero otherwise. When the new path of a rename or link
+// already exists, it is sys.O_CREAT|sys.O_TRUNC, as a rename replaces it.
+type Policy func(op PolicyOp, path string, flag experimentalsys.Oflag) experimentalsys.Errno
+
+// NewPolicyFS returns a PolicyFS which checks operations on fs with policy.
+func NewPolicyFS(fs experimentalsys.FS, policy Policy) *PolicyFS {
+	return &PolicyFS{FS: fs, policy: policy}
+}
+
+// PolicyFS is a sys.FS which asks a Policy before each operation which opens
+// or changes a path.
+//
+// Note: Paths are checked as given, without resolving symbolic links. Deny
+// PolicyOpSymlink unless links created by the guest can't be used to reach
+// paths the policy denies.
+type PolicyFS struct {
+	experimentalsys.FS
+
+	policy Policy
+}
+
+// String implements fmt.Stringer
+func (p *PolicyFS) String() string {
+	if s, ok := p.FS.(interface{ String() string }); ok {
+		return s.String()
+	}
+	return "policy"
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (p *PolicyFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	if errno := p.policy(PolicyOpOpen, path, flag); errno != 0 {
+		return nil, errno
+	}
+	return p.FS.OpenFile(path, flag, perm)
+}
+
+// Mkdir implements the same method as documented on sys.FS
+func (p *PolicyFS) Mkdir(path string, perm fs.FileMode) experimentalsys.Errno {
+	if errno := p.policy(PolicyOpMkdir, path, experimentalsys.O_CREAT); errno != 0 {
+		return errno
+	}
+	return p.FS.Mkdir(path, perm)
+}
+
+// Chmod implements the same method as documented on sys.FS
+func (p *PolicyFS) Chmod(path string, perm fs.FileMode) experimentalsys.Errno {
+	if errno := p.policy(PolicyOpChmod, path, 0); errno != 0 {
+		return errno
+	}
+	return p.FS.Chmod(path, perm)
+}
+
+// Rename implements the same method as documented on sys.FS
+func (p *PolicyFS) Rename(from, to string) experimentalsys.Errno {
+	if errno := p.policy(PolicyOpRename, from, 0); errno != 0 {
+		return errno
+	}
+	if errno := p.policy(PolicyOpRename, to, p.newPathFlag(to)); errno != 0 {
+		return errno
+	}
+	return p.FS.Rename(from, to)
+}
+
+// Rmdir implements the same method as documented on sys.FS
+func (p *PolicyFS) Rmdir(path string) experimentalsys.Errno {
+	if errno := p.policy(PolicyOpRmdir, path, 0); errno != 0 {
+		return errno
+	}
+	return p.FS.Rmdir(path)
+}
+
+// Unlink implements the same method as documented on sys.FS
+func (p *PolicyFS) Unlink(path string) experimentalsys.Errno {
+	if errno := p.policy(PolicyOpUnlink, path, 0); errno != 0 {
+		return errno
+	}
+	return p.FS.Unlink(path)
+}
+
+// Link implements the same method as documented on sys.FS
+func (p *PolicyFS) Link(oldPath, newPath string) experimentalsys.Errno {
+	if errno := p.policy(PolicyOpLink, oldPath, 0); errno != 0 {
+		return errno
+	}
+	if errno := p.policy(PolicyOpLink, newPath, p.newPathFlag(newPath)); errno != 0 {
+		return errno
+	}
+	return p.FS.Link(oldPath, newPath)
+}
+
+// newPathFlag returns the flag checked for the new path of a rename or link,
+// which includes sys.O_TRUNC if the path exists, as it would be replaced.
+func (p *PolicyFS) newPathFlag(path string) experimentalsys.Oflag {
+	if _, errno := p.FS.Lstat(path); errno == 0 {
+		return experimentalsys.O_CREAT | experimentalsys.O_TRUNC
+	}
+	return experimentalsys.O_CREAT
+}
+
+// Symlink implements the same method as documented on sys.FS
+func (p *PolicyFS) Symlink(oldPath, linkName string) experimentalsys.Errno {
+	if errno := p.policy(PolicyOpSymlink, linkName, experimentalsys.O_CREAT); errno != 0 {
+		return errno
+	}
+	return p.FS.Symlink(oldPath, linkName)
+}
+
+// Utimens implements the same method as documented on sys.FS
+func (p *PolicyFS) Utimens(path string, atim, mtim int64) experimentalsys.Errno {
+	if errno := p.policy(PolicyOpUtimens, path, 0); errno != 0 {
+		return errno
+	}
+	return p.FS.Utimens(path, atim, mtim)
+}
+
+// Access is what a PolicyRule allows, combined with bitwise OR.
+type Access uint8
+
+const (
+	// AccessRead allows opening files and directories for reading.
+	AccessRead Access = 1 << iota
+	// AccessWrite allows opening files for writing, and changing their
+	// metadata with Chmod and Utimens. It is also needed to hard link a file.
+	AccessWrite
+	// AccessCreate allows creating files, directories and links, including
+	// the new path of a rename.
+	AccessCreate
+	// AccessDelete allows removing files and directories, including the old
+	// path of a rename, and an existing new path, which a rename replaces.
+	AccessDelete
+)
+
+// PolicyRule allows access to the paths which match Pattern.
+//
+// Pattern has the syntax of path.Match, relative to the root of the
+// PolicyFS, such as "data/*.json". A pattern ending in "/**" matches the
+// directory before it, and anything under it, such as "tmp/**".
+type PolicyRule struct {
+	Pattern string
+	Access  Access
+}
+
+// GlobPolicy returns a Policy which allows an operation if the first rule
+// whose pattern matches the path allows the access it needs. Otherwise, it
+// returns sys.EACCES.
+func GlobPolicy(rules ...PolicyRule) Policy {
+	return func(op PolicyOp, p string, flag experimentalsys.Oflag) experimentalsys.Errno {
+		p = path.Clean(p)
+		for _, r := range rules {
+			if !matchPattern(r.Pattern, p) {
+				continue
+			}
+			if need := policyAccess(op, flag); r.Access&need == need {
+				return 0
+			}
+			return experimentalsys.EACCES
+		}
+		return experimentalsys.EACCES
+	}
+}
+
+// policyAccess returns the access an operation needs.
+func policyAccess(op PolicyOp, flag experimentalsys.Oflag) (access Access) {
+	if flag&experimentalsys.O_CREAT != 0 {
+		access |= AccessCreate
+	}
+	switch op {
+	case PolicyOpOpen:
+		switch flag & (experimentalsys.O_RDONLY | experimentalsys.O_WRONLY | experimentalsys.O_RDWR) {
+		case experimentalsys.O_WRONLY:
+			access |= AccessWrite
+		case experimentalsys.O_RDWR:
+			access |= AccessRead | AccessWrite
+		default:
+			access |= AccessRead
+		}
+		if flag&(experimentalsys.O_TRUNC|experimentalsys.O_APPEND) != 0 {
+			access |= AccessWrite
+		}
+	case PolicyOpRmdir, PolicyOpUnlink:
+		access |= AccessDelete
+	case PolicyOpRename, PolicyOpLink:
+		if access == 0 { // the old path
+			if op == PolicyOpRename {
+				access = AccessDelete
+			} else {
+				access = AccessWrite
+			}
+		} else if flag&experimentalsys.O_TRUNC != 0 { // replaces the new path
+			access |= AccessDelete
+		}
+	case PolicyOpChmod, PolicyOpUtimens:
+		access |= AccessWrite
+	}
+	return
+}
+
+// matchPattern returns true if the cleaned path matches the pattern of a
+// PolicyRule.
+func matchPattern(pattern, p string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		if dir == "" || dir == "." {
+			return true
+		}
+		if matched, _ := path.Match(dir, p); matched {
+			return true
+		}
+		// Match any parent of the path against the directory pattern.
+		for parent := path.Dir(p); parent != "."; parent = path.Dir(parent) {
+			if matched, _ := path.Match(dir, parent); matched {
+				return true
+			}
+		}
+		return false
+	}
+	matched, _ := path.Match(pattern, p)
+	return matched
+}
@@ -0,0 +1,113 @@
+package sysfs
+
+import (
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestPolicyFS_Open_Read(t *testing.T) {
+	allowAll := func(PolicyOp, string, experimentalsys.Oflag) experimentalsys.Errno { return 0 }
+	testOpen_Read(t, NewPolicyFS(newTestMemFS(t), allowAll), true, true)
+}
+
+func TestPolicyFS_String(t *testing.T) {
+	require.Equal(t, "memfs", NewPolicyFS(newMemFS(), GlobPolicy()).String())
+}
+
+func TestPolicyFS(t *testing.T) {
+	type call struct {
+		op   PolicyOp
+		path string
+		flag experimentalsys.Oflag
+	}
+	var calls []call
+	testFS := NewPolicyFS(newMemFS(), func(op PolicyOp, path string, flag experimentalsys.Oflag) experimentalsys.Errno {
+		calls = append(calls, call{op, path, flag})
+		if path == "denied" {
+			return experimentalsys.EPERM
+		}
+		return 0
+	})
+
+	f, errno := testFS.OpenFile("file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	require.EqualErrno(t, 0, testFS.Chmod("file", 0o400))
+	require.EqualErrno(t, 0, testFS.Utimens("file", 1, 1))
+	require.EqualErrno(t, 0, testFS.Link("file", "dir/link"))
+	require.EqualErrno(t, 0, testFS.Symlink("file", "symlink"))
+	require.EqualErrno(t, 0, testFS.Rename("dir/link", "link"))
+	require.EqualErrno(t, 0, testFS.Rename("symlink", "link")) // replaces it
+	require.EqualErrno(t, 0, testFS.Unlink("link"))
+	require.EqualErrno(t, 0, testFS.Rmdir("dir"))
+
+	// The errno of the policy is returned, and nothing changes.
+	require.EqualErrno(t, experimentalsys.EPERM, testFS.Rename("file", "denied"))
+	_, errno = testFS.Stat("file")
+	require.EqualErrno(t, 0, errno)
+
+	require.Equal(t, []call{
+		{PolicyOpOpen, "file", experimentalsys.O_CREAT | experimentalsys.O_RDWR},
+		{PolicyOpMkdir, "dir", experimentalsys.O_CREAT},
+		{PolicyOpChmod, "file", 0},
+		{PolicyOpUtimens, "file", 0},
+		{PolicyOpLink, "file", 0},
+		{PolicyOpLink, "dir/link", experimentalsys.O_CREAT},
+		{PolicyOpSymlink, "symlink", experimentalsys.O_CREAT},
+		{PolicyOpRename, "dir/link", 0},
+		{PolicyOpRename, "link", experimentalsys.O_CREAT},
+		{PolicyOpRename, "symlink", 0},
+		{PolicyOpRename, "link", experimentalsys.O_CREAT | experimentalsys.O_TRUNC},
+		{PolicyOpUnlink, "link", 0},
+		{PolicyOpRmdir, "dir", 0},
+		{PolicyOpRename, "file", 0},
+		{PolicyOpRename, "denied", experimentalsys.O_CREAT},
+	}, calls)
+}
+
+func TestGlobPolicy(t *testing.T) {
+	policy := GlobPolicy(
+		PolicyRule{Pattern: ".", Access: AccessRead},
+		PolicyRule{Pattern: "data/secret.json"},
+		PolicyRule{Pattern: "data/*.json", Access: AccessRead},
+		PolicyRule{Pattern: "tmp/**", Access: AccessRead | AccessWrite | AccessCreate | AccessDelete},
+		PolicyRule{Pattern: "out/*", Access: AccessCreate},
+	)
+
+	tests := []struct {
+		name          string
+		op            PolicyOp
+		path          string
+		flag          experimentalsys.Oflag
+		expectedErrno experimentalsys.Errno
+	}{
+		{name: "read root", op: PolicyOpOpen, path: "."},
+		{name: "read json", op: PolicyOpOpen, path: "data/a.json"},
+		{name: "read unclean json", op: PolicyOpOpen, path: "data/../data/a.json"},
+		{name: "first rule wins", op: PolicyOpOpen, path: "data/secret.json", expectedErrno: experimentalsys.EACCES},
+		{name: "no rule", op: PolicyOpOpen, path: "data/a.txt", expectedErrno: experimentalsys.EACCES},
+		{name: "write json", op: PolicyOpOpen, path: "data/a.json", flag: experimentalsys.O_WRONLY, expectedErrno: experimentalsys.EACCES},
+		{name: "truncate json", op: PolicyOpOpen, path: "data/a.json", flag: experimentalsys.O_TRUNC, expectedErrno: experimentalsys.EACCES},
+		{name: "create json", op: PolicyOpOpen, path: "data/b.json", flag: experimentalsys.O_CREAT, expectedErrno: experimentalsys.EACCES},
+		{name: "chmod json", op: PolicyOpChmod, path: "data/a.json", expectedErrno: experimentalsys.EACCES},
+		{name: "rename from json", op: PolicyOpRename, path: "data/a.json", expectedErrno: experimentalsys.EACCES},
+		{name: "link json", op: PolicyOpLink, path: "data/a.json", expectedErrno: experimentalsys.EACCES},
+		{name: "tmp", op: PolicyOpMkdir, path: "tmp", flag: experimentalsys.O_CREAT},
+		{name: "create under tmp", op: PolicyOpOpen, path: "tmp/a/b", flag: experimentalsys.O_CREAT | experimentalsys.O_RDWR},
+		{name: "rename to tmp", op: PolicyOpRename, path: "tmp/a", flag: experimentalsys.O_CREAT},
+		{name: "rename to out", op: PolicyOpRename, path: "out/a", flag: experimentalsys.O_CREAT},
+		{name: "rename onto out", op: PolicyOpRename, path: "out/a", flag: experimentalsys.O_CREAT | experimentalsys.O_TRUNC, expectedErrno: experimentalsys.EACCES},
+		{name: "unlink under tmp", op: PolicyOpUnlink, path: "tmp/a"},
+		{name: "tmp prefix", op: PolicyOpMkdir, path: "tmpfoo", flag: experimentalsys.O_CREAT, expectedErrno: experimentalsys.EACCES},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.EqualErrno(t, tc.expectedErrno, policy(tc.op, tc.path, tc.flag))
+		})
+	}
+}
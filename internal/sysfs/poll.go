@@ -8,10 +8,11 @@ import (
 
 // poll implements `Poll` as documented on sys.File via a file descriptor.
 func poll(fd uintptr, flag sys.Pflag, timeoutMillis int32) (ready bool, errno sys.Errno) {
-	if flag != sys.POLLIN {
-		return false, sys.ENOTSUP
+	events, errno := pollEvents(flag)
+	if errno != 0 {
+		return false, errno
 	}
-	fds := []pollFd{newPollFd(fd, _POLLIN, 0)}
+	fds := []pollFd{newPollFd(fd, events, 0)}
 	count, errno := _poll(fds, timeoutMillis)
 	return count > 0, errno
 }
@@ -28,11 +28,13 @@ const _POLLIN = 0x0001
 
 // _poll implements poll on Linux via ppoll.
 func _poll(fds []pollFd, timeoutMillis int32) (n int, errno sys.Errno) {
-	var ts syscall.Timespec
+	// A nil timespec blocks indefinitely.
+	var ts *syscall.Timespec
 	if timeoutMillis >= 0 {
-		ts = syscall.NsecToTimespec(int64(time.Duration(timeoutMillis) * time.Millisecond))
+		t := syscall.NsecToTimespec(int64(time.Duration(timeoutMillis) * time.Millisecond))
+		ts = &t
 	}
-	return ppoll(fds, &ts)
+	return ppoll(fds, ts)
 }
 
 // ppoll is a poll variant that allows to subscribe to a mask of signals.
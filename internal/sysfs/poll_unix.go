@@ -0,0 +1,126 @@
+//go:build linux || darwin
+
+package sysfs
+
+import (
+	"sync"
+	"syscall"
+
+	"github.com/tetratelabs/wazero/experimental/sys"
+)
+
+const (
+	// _POLLOUT subscribes a notification when data can be written.
+	_POLLOUT = 0x0004
+	// _POLLERR is returned when the file descriptor has an error.
+	_POLLERR = 0x0008
+	// _POLLHUP is returned when the peer hung up.
+	_POLLHUP = 0x0010
+	// _POLLNVAL is returned when the file descriptor isn't open.
+	_POLLNVAL = 0x0020
+)
+
+// pollHostsSupported is true when pollHosts can wait on several file
+// descriptors at once.
+const pollHostsSupported = true
+
+// pollEvents returns the poll events corresponding to the flag.
+func pollEvents(flag sys.Pflag) (events int16, errno sys.Errno) {
+	if flag&sys.POLLIN != 0 {
+		events |= _POLLIN
+	}
+	if flag&sys.POLLOUT != 0 {
+		events |= _POLLOUT
+	}
+	if events == 0 {
+		return 0, sys.ENOTSUP
+	}
+	return
+}
+
+// pollHosts waits on the file descriptor of each event at once, setting
+// their Revents or Errno, and returns the count of events set. This returns
+// sys.EINTR if done is closed while waiting.
+func pollHosts(done <-chan struct{}, events []*PollEvent, fds []uintptr, timeoutMillis int32) (n int, errno sys.Errno) {
+	pfds := make([]pollFd, len(fds), len(fds)+1)
+	for i, fd := range fds {
+		e, errno := pollEvents(events[i].Flag)
+		if errno != 0 {
+			return 0, errno
+		}
+		pfds[i] = newPollFd(fd, e, 0)
+	}
+
+	if done != nil && timeoutMillis != 0 {
+		wake, stop, errno := wakeOnDone(done)
+		if errno != 0 {
+			return 0, errno
+		}
+		defer stop()
+		pfds = append(pfds, newPollFd(wake, _POLLIN, 0))
+	}
+
+	if _, errno = _poll(pfds, timeoutMillis); errno != 0 {
+		return 0, errno
+	}
+	if len(pfds) > len(fds) && pfds[len(fds)].revents != 0 {
+		return 0, sys.EINTR
+	}
+	for i, pfd := range pfds[:len(fds)] {
+		e := events[i]
+		if pfd.revents&_POLLNVAL != 0 {
+			e.Errno = sys.EBADF
+		}
+		if pfd.revents&_POLLIN != 0 {
+			e.Revents |= sys.POLLIN
+		}
+		if pfd.revents&_POLLOUT != 0 {
+			e.Revents |= sys.POLLOUT
+		}
+		if pfd.revents&_POLLHUP != 0 {
+			// Reads return EOF after a hang up, so they are ready.
+			e.Revents |= sys.POLLHUP | e.Flag&sys.POLLIN
+		}
+		if pfd.revents&_POLLERR != 0 {
+			// Report the event ready, so that the error is returned by the
+			// next read or write.
+			e.Revents |= e.Flag
+		}
+		if e.Revents != 0 || e.Errno != 0 {
+			n++
+		}
+	}
+	return
+}
+
+// wakeOnDone returns the read end of a pipe which becomes readable when done
+// is closed, so that a call to poll including it can be interrupted. stop
+// must be called to release the pipe after polling.
+func wakeOnDone(done <-chan struct{}) (wake uintptr, stop func(), errno sys.Errno) {
+	var p [2]int
+	if err := syscall.Pipe(p[:]); err != nil {
+		return 0, nil, sys.UnwrapOSError(err)
+	}
+	syscall.CloseOnExec(p[0])
+	syscall.CloseOnExec(p[1])
+
+	stopped := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		select {
+		case <-done:
+			_, _ = syscall.Write(p[1], []byte{0})
+		case <-stopped:
+		}
+	}()
+
+	stop = func() {
+		close(stopped)
+		wg.Wait() // Don't close the pipe while it may be written.
+		_ = syscall.Close(p[0])
+		_ = syscall.Close(p[1])
+	}
+	return uintptr(p[0]), stop, 0
+}
@@ -10,3 +10,11 @@ import (
 func poll(uintptr, sys.Pflag, int32) (bool, sys.Errno) {
 	return false, sys.ENOSYS
 }
+
+// pollHostsSupported is false as there is no poll on this platform.
+const pollHostsSupported = false
+
+// pollHosts is not supported on this platform.
+func pollHosts(<-chan struct{}, []*PollEvent, []uintptr, int32) (int, sys.Errno) {
+	return 0, sys.ENOSYS
+}
@@ -22,6 +22,23 @@ const (
 	_POLLIN = (_POLLRDNORM | _POLLRDBAND)
 )
 
+// pollHostsSupported is false because _poll doesn't set revents on Windows,
+// so pollHosts can't tell which file descriptor is ready.
+const pollHostsSupported = false
+
+// pollEvents returns the poll events corresponding to the flag.
+func pollEvents(flag sys.Pflag) (int16, sys.Errno) {
+	if flag != sys.POLLIN {
+		return 0, sys.ENOTSUP
+	}
+	return _POLLIN, 0
+}
+
+// pollHosts is not supported on Windows. See pollHostsSupported.
+func pollHosts(<-chan struct{}, []*PollEvent, []uintptr, int32) (int, sys.Errno) {
+	return 0, sys.ENOSYS
+}
+
 // pollFd is the struct to query for file descriptor events using poll.
 type pollFd struct {
 	// fd is the file descriptor.
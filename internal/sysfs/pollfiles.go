@@ -0,0 +1,267 @@
+package sysfs
+
+import (
+	"math"
+	"reflect"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// PollEvent is an event to wait for on a file, as used by PollFiles.
+type PollEvent struct {
+	// File is the file to poll.
+	File experimentalsys.File
+
+	// Flag are the events to wait for: sys.POLLIN, sys.POLLOUT or both.
+	Flag experimentalsys.Pflag
+
+	// Revents are the events which are ready, set by PollFiles. This may
+	// include sys.POLLHUP, regardless of Flag.
+	Revents experimentalsys.Pflag
+
+	// Errno is set by PollFiles when the file can't be polled. For example,
+	// sys.ENOTSUP when the file doesn't implement sys.Pollable.
+	Errno experimentalsys.Errno
+}
+
+// hostFile is implemented by files backed by a host file descriptor, so that
+// PollFiles can wait on several of them with one call to poll.
+type hostFile interface {
+	// hostFd returns the file descriptor, or false if there is none, such as
+	// when the file is closed.
+	hostFd() (uintptr, bool)
+}
+
+// hangupFile is implemented by virtual files, such as pipes, which report
+// sys.POLLHUP when polled after the other end is closed.
+type hangupFile interface {
+	// hangup returns true if the other end of the file is closed.
+	hangup() bool
+}
+
+// readyEvents returns the Revents of e when its Flag is ready, adding
+// sys.POLLHUP if the file reports a hang up.
+func readyEvents(e *PollEvent) experimentalsys.Pflag {
+	if hf, ok := e.File.(hangupFile); ok && hf.hangup() {
+		return e.Flag | experimentalsys.POLLHUP
+	}
+	return e.Flag
+}
+
+// hostFdOf returns the host file descriptor of the file, if it has one.
+func hostFdOf(f experimentalsys.File) (uintptr, bool) {
+	if hf, ok := f.(hostFile); ok {
+		return hf.hostFd()
+	}
+	return 0, false
+}
+
+// pollSlice is how long PollFiles waits on one kind of file before checking
+// the others, when it can't wait on all of them at once.
+const pollSlice = 10 * time.Millisecond
+
+// PollFiles waits until at least one of the events is ready or the timeout
+// elapses. It returns the count of events with Revents or Errno set.
+//
+// A negative timeoutMillis waits indefinitely, and zero returns immediately.
+// If done is closed while waiting, PollFiles returns sys.EINTR.
+//
+// # Notes
+//
+//   - Files with a host file descriptor, such as sockets, are waited on
+//     together with one call to poll, except on Windows.
+//   - Virtual files which implement sys.PollNotifier are waited on together
+//     via their channels.
+//   - Other sys.Pollable files, or a mix of the above kinds, are checked in
+//     turn, waiting up to pollSlice on one before checking the others. This
+//     is also the most time before noticing done is closed.
+//   - An event which can't be polled, such as a file which doesn't implement
+//     sys.Pollable, returns immediately with Errno set.
+func PollFiles(done <-chan struct{}, events []PollEvent, timeoutMillis int32) (n int, errno experimentalsys.Errno) {
+	s := pollSet{done: done}
+	for i := range events {
+		e := &events[i]
+		e.Revents, e.Errno = 0, 0
+		if fd, ok := hostFdOf(e.File); ok && pollHostsSupported {
+			s.hosts = append(s.hosts, e)
+			s.fds = append(s.fds, fd)
+		} else if pn, ok := e.File.(experimentalsys.PollNotifier); ok && pn.PollNotify(e.Flag) != nil {
+			s.notifiers = append(s.notifiers, e)
+		} else if _, ok = e.File.(experimentalsys.Pollable); ok {
+			s.pollers = append(s.pollers, e)
+		} else {
+			e.Errno = experimentalsys.ENOTSUP
+			n++
+		}
+	}
+	if n > 0 {
+		timeoutMillis = 0 // Return the events which can't be polled.
+	}
+
+	var deadline time.Time
+	if timeoutMillis > 0 {
+		deadline = time.Now().Add(time.Duration(timeoutMillis) * time.Millisecond)
+	}
+
+	// When there's more than one kind of file, check them all before waiting,
+	// as waiting on one kind delays noticing the others.
+	mixed := s.kinds() > 1
+	if mixed && timeoutMillis != 0 {
+		if count, errno := s.poll(0); count != 0 || (errno != 0 && errno != experimentalsys.EINTR) {
+			return count, errno
+		}
+	}
+
+	// sys.Pollable can't be interrupted, so only wait on them for a slice if
+	// they could miss done being closed.
+	sliced := mixed || len(s.pollers) > 1 || (done != nil && len(s.pollers) > 0)
+	for {
+		if isDone(done) {
+			return 0, experimentalsys.EINTR
+		}
+
+		remaining := remainingMillis(timeoutMillis, deadline)
+		wait := remaining
+		if sliced && (wait < 0 || wait > int32(pollSlice/time.Millisecond)) {
+			wait = int32(pollSlice / time.Millisecond)
+		}
+
+		start := time.Now()
+		count, errno := s.poll(wait)
+		if errno == experimentalsys.EINTR {
+			continue // Retry with the remaining time, unless done.
+		} else if errno != 0 {
+			return 0, errno
+		}
+		n += count
+
+		// Only retry when the wait was cut short, as a file which is not
+		// ready returns after waiting the whole time. Don't retry a file
+		// which returned sooner, as it would spin if it ignores the timeout.
+		if n > 0 || wait == remaining || remainingMillis(timeoutMillis, deadline) == 0 ||
+			time.Since(start) < time.Duration(wait)*time.Millisecond {
+			return n, 0
+		}
+	}
+}
+
+// isDone returns true if done is closed.
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// remainingMillis returns the milliseconds remaining until the deadline,
+// rounded up, or timeoutMillis if it is zero or negative.
+func remainingMillis(timeoutMillis int32, deadline time.Time) int32 {
+	if timeoutMillis <= 0 {
+		return timeoutMillis
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0
+	}
+	millis := (remaining + time.Millisecond - 1) / time.Millisecond
+	if millis > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int32(millis)
+}
+
+// pollSet are the events of PollFiles, partitioned by how to wait on them.
+type pollSet struct {
+	done      <-chan struct{}
+	hosts     []*PollEvent
+	fds       []uintptr
+	notifiers []*PollEvent
+	pollers   []*PollEvent
+}
+
+// kinds returns the count of non-empty partitions.
+func (s *pollSet) kinds() (kinds int) {
+	for _, events := range [][]*PollEvent{s.hosts, s.notifiers, s.pollers} {
+		if len(events) > 0 {
+			kinds++
+		}
+	}
+	return
+}
+
+// poll waits up to timeoutMillis on the first kind of file, then checks the
+// others without waiting. It returns the count of events set.
+func (s *pollSet) poll(timeoutMillis int32) (n int, errno experimentalsys.Errno) {
+	if len(s.hosts) > 0 {
+		var count int
+		if count, errno = pollHosts(s.done, s.hosts, s.fds, timeoutMillis); errno != 0 {
+			return
+		}
+		n += count
+		timeoutMillis = 0
+	}
+
+	if len(s.notifiers) > 0 {
+		var count int
+		if count, errno = pollNotifiers(s.done, s.notifiers, timeoutMillis); errno != 0 {
+			return
+		}
+		n += count
+		timeoutMillis = 0
+	}
+
+	for _, e := range s.pollers {
+		ready, errno := e.File.(experimentalsys.Pollable).Poll(e.Flag, timeoutMillis)
+		timeoutMillis = 0
+		switch errno {
+		case 0:
+			if ready {
+				e.Revents = readyEvents(e)
+				n++
+			}
+		case experimentalsys.ENOSYS, experimentalsys.ENOTSUP:
+			e.Errno = experimentalsys.ENOTSUP
+			n++
+		default:
+			return 0, errno
+		}
+	}
+	return
+}
+
+// pollNotifiers waits up to timeoutMillis for any of the channels returned by
+// sys.PollNotifier, and returns the count of events set, or sys.EINTR if done
+// is closed first.
+func pollNotifiers(done <-chan struct{}, events []*PollEvent, timeoutMillis int32) (n int, errno experimentalsys.Errno) {
+	cases := make([]reflect.SelectCase, 0, len(events)+2)
+	for _, e := range events {
+		ch := e.File.(experimentalsys.PollNotifier).PollNotify(e.Flag)
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)})
+	}
+
+	if timeoutMillis != 0 {
+		if timeoutMillis > 0 {
+			timer := time.NewTimer(time.Duration(timeoutMillis) * time.Millisecond)
+			defer timer.Stop()
+			cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(timer.C)})
+		}
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(done)})
+		if chosen, _, _ := reflect.Select(cases); chosen == len(cases)-1 {
+			return 0, experimentalsys.EINTR
+		}
+	}
+
+	// Check all channels, as more than one may be ready.
+	for i, e := range events {
+		select {
+		case <-cases[i].Chan.Interface().(<-chan struct{}):
+			e.Revents = readyEvents(e)
+			n++
+		default:
+		}
+	}
+	return
+}
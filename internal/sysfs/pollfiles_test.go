@@ -0,0 +1,226 @@
+package sysfs
+
+import (
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestPollFiles_Host(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("poll is not multiplexed on " + runtime.GOOS)
+	}
+
+	r1, w1 := requirePipe(t)
+	r2, w2 := requirePipe(t)
+
+	events := []PollEvent{
+		{File: r1, Flag: experimentalsys.POLLIN},
+		{File: r2, Flag: experimentalsys.POLLIN},
+	}
+
+	// Nothing is ready, so this waits for the timeout.
+	n, errno := PollFiles(nil, events, 10)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+
+	// Only the second pipe has data.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = w2.Write([]byte("wazero"))
+	}()
+	n, errno = PollFiles(nil, events, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.Zero(t, events[0].Revents)
+	require.Equal(t, experimentalsys.POLLIN, events[1].Revents)
+
+	// Write readiness is supported.
+	n, errno = PollFiles(nil, []PollEvent{{File: w1, Flag: experimentalsys.POLLOUT}}, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+
+	// Closing the write end hangs up the read end.
+	osR, osW, err := os.Pipe()
+	require.NoError(t, err)
+	defer osR.Close()
+	require.NoError(t, osW.Close())
+	events = []PollEvent{{File: newOsFile("", experimentalsys.O_RDONLY, 0, osR), Flag: experimentalsys.POLLIN}}
+	n, errno = PollFiles(nil, events, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.Equal(t, experimentalsys.POLLIN|experimentalsys.POLLHUP, events[0].Revents)
+}
+
+func TestPollFiles_Notifier(t *testing.T) {
+	f1, f2 := newNotifierFile(), newNotifierFile()
+	events := []PollEvent{
+		{File: f1, Flag: experimentalsys.POLLIN},
+		{File: f2, Flag: experimentalsys.POLLIN},
+	}
+
+	n, errno := PollFiles(nil, events, 10)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		f2.setReady()
+	}()
+	n, errno = PollFiles(nil, events, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.Zero(t, events[0].Revents)
+	require.Equal(t, experimentalsys.POLLIN, events[1].Revents)
+}
+
+func TestPollFiles_Mixed(t *testing.T) {
+	r, _ := requirePipe(t)
+	f := newNotifierFile()
+	events := []PollEvent{
+		{File: r, Flag: experimentalsys.POLLIN},
+		{File: f, Flag: experimentalsys.POLLIN},
+	}
+
+	n, errno := PollFiles(nil, events, 30)
+	require.EqualErrno(t, 0, errno)
+	require.Zero(t, n)
+
+	// The notifier is noticed while waiting on the pipe.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		f.setReady()
+	}()
+	n, errno = PollFiles(nil, events, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.Zero(t, events[0].Revents)
+	require.Equal(t, experimentalsys.POLLIN, events[1].Revents)
+}
+
+func TestPollFiles_NotPollable(t *testing.T) {
+	f := newNotifierFile()
+	events := []PollEvent{
+		{File: experimentalsys.UnimplementedFile{}, Flag: experimentalsys.POLLIN},
+		{File: f, Flag: experimentalsys.POLLIN},
+	}
+
+	// This doesn't wait, as the first event can't be polled.
+	n, errno := PollFiles(nil, events, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.EqualErrno(t, experimentalsys.ENOTSUP, events[0].Errno)
+	require.Zero(t, events[1].Revents)
+}
+
+func TestPollFiles_Done(t *testing.T) {
+	r, _ := requirePipe(t)
+
+	tests := []struct {
+		name  string
+		files []experimentalsys.File
+	}{
+		{name: "host", files: []experimentalsys.File{r}},
+		{name: "notifier", files: []experimentalsys.File{newNotifierFile()}},
+		{name: "pollable", files: []experimentalsys.File{&pollerFile{}}},
+		{name: "mixed", files: []experimentalsys.File{r, newNotifierFile(), &pollerFile{}}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			events := make([]PollEvent, len(tc.files))
+			for i, f := range tc.files {
+				events[i] = PollEvent{File: f, Flag: experimentalsys.POLLIN}
+			}
+
+			// A done channel which is never closed doesn't interrupt.
+			n, errno := PollFiles(make(chan struct{}), events, 10)
+			require.EqualErrno(t, 0, errno)
+			require.Zero(t, n)
+
+			done := make(chan struct{})
+			go func() {
+				time.Sleep(10 * time.Millisecond)
+				close(done)
+			}()
+			_, errno = PollFiles(done, events, -1)
+			require.EqualErrno(t, experimentalsys.EINTR, errno)
+		})
+	}
+}
+
+func TestPollFiles_MemFS(t *testing.T) {
+	f, errno := newTestMemFS(t).OpenFile("animals.txt", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	// Like regular files, memFS files are always ready.
+	events := []PollEvent{{File: f, Flag: experimentalsys.POLLIN}}
+	n, errno := PollFiles(nil, events, -1)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 1, n)
+	require.Equal(t, experimentalsys.POLLIN, events[0].Revents)
+}
+
+func requirePipe(t *testing.T) (r, w experimentalsys.File) {
+	osR, osW, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = osR.Close()
+		_ = osW.Close()
+	})
+	r, err = NewStdioFile(true, osR)
+	require.NoError(t, err)
+	w, err = NewStdioFile(false, osW)
+	require.NoError(t, err)
+	return
+}
+
+// notifierFile is a virtual file which implements experimentalsys.PollNotifier.
+type notifierFile struct {
+	experimentalsys.UnimplementedFile
+	ready chan struct{}
+}
+
+func newNotifierFile() *notifierFile {
+	return &notifierFile{ready: make(chan struct{})}
+}
+
+func (f *notifierFile) setReady() {
+	close(f.ready)
+}
+
+// Poll implements the same method as documented on experimentalsys.Pollable
+func (f *notifierFile) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (bool, experimentalsys.Errno) {
+	select {
+	case <-f.ready:
+		return true, 0
+	default:
+		return false, 0
+	}
+}
+
+// PollNotify implements the same method as documented on
+// experimentalsys.PollNotifier
+func (f *notifierFile) PollNotify(experimentalsys.Pflag) <-chan struct{} {
+	return f.ready
+}
+
+// pollerFile is a virtual file which is never ready, implementing only
+// experimentalsys.Pollable.
+type pollerFile struct {
+	experimentalsys.UnimplementedFile
+}
+
+// Poll implements the same method as documented on experimentalsys.Pollable
+func (f *pollerFile) Poll(_ experimentalsys.Pflag, timeoutMillis int32) (bool, experimentalsys.Errno) {
+	if timeoutMillis > 0 {
+		time.Sleep(time.Duration(timeoutMillis) * time.Millisecond)
+	}
+	return false, 0
+}
@@ -0,0 +1,421 @@
+package sysfs
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+	"sync"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// Quota are the limits of a QuotaFS. A zero field means no limit.
+type Quota struct {
+	// MaxBytesWritten is the total count of bytes that can be written to
+	// files, after which writes fail with sys.ENOSPC. A write which would
+	// exceed it writes as many bytes as remain.
+	MaxBytesWritten int64
+
+	// MaxFiles is the count of files, directories and links that can be
+	// created, less those removed, after which creating one fails with
+	// sys.EDQUOT.
+	MaxFiles int64
+
+	// MaxOpenFilesInFS is the count of files opened via this QuotaFS that can
+	// be open at the same time, after which opening one fails with
+	// sys.EMFILE.
+	//
+	// Note: This doesn't limit the file descriptors of a module, as files on
+	// other mounts, stdio, sockets, and connections from sock_accept aren't
+	// counted. Use FSConfig.WithMaxOpenFiles for that.
+	MaxOpenFilesInFS int64
+
+	// MaxDepth is the count of path components of a directory that can be
+	// created, such as 2 for "a/b", after which creating one fails with
+	// sys.EDQUOT. Symbolic links in the path are followed, so a link to a
+	// deep directory doesn't allow creating deeper ones.
+	MaxDepth int
+}
+
+// QuotaUsage is the usage of a QuotaFS, as returned by QuotaFS.Usage.
+type QuotaUsage struct {
+	// BytesWritten is the total count of bytes written to files.
+	BytesWritten int64
+
+	// Files is the count of files, directories and links created, less
+	// those removed. This doesn't go below zero, so removing files which
+	// existed when mounted doesn't allow creating more than Quota.MaxFiles.
+	Files int64
+
+	// OpenFiles is the count of files opened via this QuotaFS, which are
+	// still open.
+	OpenFiles int64
+}
+
+// NewQuotaFS returns a QuotaFS which limits the usage of fs to the quota.
+func NewQuotaFS(fs experimentalsys.FS, quota Quota) *QuotaFS {
+	return &QuotaFS{FS: fs, quota: quota}
+}
+
+// QuotaFS is a sys.FS which limits the resources a guest can use, such as
+// the count of bytes written, and counts them.
+//
+// Usage is counted per QuotaFS, so to account usage per module, use a
+// separate QuotaFS for each.
+//
+// Note: Only what is done via this QuotaFS is counted. For example, a file
+// which exists when mounted is not counted in QuotaUsage.Files, and
+// extending a file with Truncate doesn't count as bytes written.
+type QuotaFS struct {
+	experimentalsys.FS
+
+	quota Quota
+
+	// mu guards usage, and serializes operations which check a quota before
+	// changing it.
+	mu    sync.Mutex
+	usage QuotaUsage
+}
+
+// Usage returns the current usage.
+func (q *QuotaFS) Usage() QuotaUsage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.usage
+}
+
+// String implements fmt.Stringer
+func (q *QuotaFS) String() string {
+	if s, ok := q.FS.(interface{ String() string }); ok {
+		return s.String()
+	}
+	return "quota"
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (q *QuotaFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	// Reserve the open file, and the file to create if any, so that the lock
+	// isn't held while opening, then give back what wasn't used.
+	q.mu.Lock()
+	if max := q.quota.MaxOpenFilesInFS; max > 0 && q.usage.OpenFiles >= max {
+		q.mu.Unlock()
+		return nil, experimentalsys.EMFILE
+	}
+	q.usage.OpenFiles++
+	create := flag&experimentalsys.O_CREAT != 0 && q.checkCreate() == 0
+	if create {
+		q.usage.Files++
+	}
+	q.mu.Unlock()
+
+	f, created, errno := q.openFile(path, flag, perm, create)
+	if errno != 0 || create && !created {
+		q.mu.Lock()
+		if errno != 0 {
+			q.usage.OpenFiles--
+		}
+		if create && !created {
+			q.usage.Files--
+		}
+		q.mu.Unlock()
+	}
+	if errno != 0 {
+		return nil, errno
+	}
+	qf := &quotaFile{File: f, fs: q}
+	if pf, ok := f.(experimentalsys.PollableFile); ok {
+		return &quotaPollableFile{quotaFile: qf, pf: pf}, 0
+	}
+	return qf, 0
+}
+
+// openFile opens the path, and returns whether it was created, which is only
+// allowed if create. Otherwise, an O_CREAT flag only opens an existing file,
+// and sys.EDQUOT is returned if there is none.
+func (q *QuotaFS) openFile(path string, flag experimentalsys.Oflag, perm fs.FileMode, create bool) (experimentalsys.File, bool, experimentalsys.Errno) {
+	switch {
+	case flag&experimentalsys.O_CREAT == 0:
+		f, errno := q.FS.OpenFile(path, flag, perm)
+		return f, false, errno
+	case !create:
+		if flag&experimentalsys.O_EXCL != 0 {
+			if _, errno := q.FS.Lstat(path); errno == 0 {
+				return nil, false, experimentalsys.EEXIST
+			}
+			return nil, false, experimentalsys.EDQUOT
+		}
+		f, errno := q.FS.OpenFile(path, flag&^experimentalsys.O_CREAT, perm)
+		if errno == experimentalsys.ENOENT {
+			errno = experimentalsys.EDQUOT
+		}
+		return f, false, errno
+	case flag&experimentalsys.O_EXCL != 0:
+		f, errno := q.FS.OpenFile(path, flag, perm)
+		return f, errno == 0, errno
+	}
+
+	// O_EXCL tells whether the file is created, instead of checking whether
+	// it exists first, which races with concurrent changes.
+	f, errno := q.FS.OpenFile(path, flag|experimentalsys.O_EXCL, perm)
+	if errno != experimentalsys.EEXIST {
+		return f, errno == 0, errno
+	}
+	if f, errno = q.FS.OpenFile(path, flag&^experimentalsys.O_CREAT, perm); errno != experimentalsys.ENOENT {
+		return f, false, errno
+	}
+	// The path is a symbolic link to a file which doesn't exist, or it was
+	// removed concurrently, so opening it creates a file.
+	f, errno = q.FS.OpenFile(path, flag, perm)
+	return f, errno == 0, errno
+}
+
+// checkCreate returns sys.EDQUOT if a file can't be created.
+func (q *QuotaFS) checkCreate() experimentalsys.Errno {
+	if max := q.quota.MaxFiles; max > 0 && q.usage.Files >= max {
+		return experimentalsys.EDQUOT
+	}
+	return 0
+}
+
+// Mkdir implements the same method as documented on sys.FS
+func (q *QuotaFS) Mkdir(path string, perm fs.FileMode) experimentalsys.Errno {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if errno := q.checkDepth(path); errno != 0 {
+		return errno
+	}
+	return q.create(func() experimentalsys.Errno { return q.FS.Mkdir(path, perm) })
+}
+
+// checkDepth returns sys.EDQUOT if a directory can't be created at the path.
+func (q *QuotaFS) checkDepth(p string) experimentalsys.Errno {
+	max := q.quota.MaxDepth
+	if max <= 0 {
+		return 0
+	}
+	if depth, errno := q.depth(p); errno != 0 {
+		return errno
+	} else if depth > max {
+		return experimentalsys.EDQUOT
+	}
+	return 0
+}
+
+// depth returns the count of components in the path, such as 2 for "a/b",
+// after following symbolic links, except the last component.
+func (q *QuotaFS) depth(p string) (int, experimentalsys.Errno) {
+	var resolved []string
+	pending := strings.Split(p, "/")
+	for links := 0; len(pending) > 0; {
+		name := pending[0]
+		pending = pending[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) > 0 {
+				resolved = resolved[:len(resolved)-1]
+			}
+			continue
+		}
+
+		resolved = append(resolved, name)
+		if len(pending) == 0 {
+			break
+		}
+		target, errno := q.FS.Readlink(strings.Join(resolved, "/"))
+		if errno != 0 {
+			continue // not a symbolic link, or doesn't exist.
+		}
+		if links++; links > maxSymlinks {
+			return 0, experimentalsys.ELOOP
+		}
+		// Replace the link with its target, which is relative to the
+		// directory containing it, or the root if absolute.
+		resolved = resolved[:len(resolved)-1]
+		if path.IsAbs(target) {
+			resolved = resolved[:0]
+		}
+		pending = append(strings.Split(target, "/"), pending...)
+	}
+	return len(resolved), 0
+}
+
+// create calls fn, which creates a file, if the quota allows it.
+func (q *QuotaFS) create(fn func() experimentalsys.Errno) experimentalsys.Errno {
+	if errno := q.checkCreate(); errno != 0 {
+		return errno
+	}
+	if errno := fn(); errno != 0 {
+		return errno
+	}
+	q.usage.Files++
+	return 0
+}
+
+// removed counts a file removed, unless it would make the count negative, as
+// files which existed when mounted aren't counted.
+func (q *QuotaFS) removed() {
+	if q.usage.Files > 0 {
+		q.usage.Files--
+	}
+}
+
+// Rename implements the same method as documented on sys.FS
+func (q *QuotaFS) Rename(from, to string) experimentalsys.Errno {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	st, errno := q.FS.Lstat(from)
+	if errno == 0 && st.Mode.IsDir() {
+		if errno = q.checkDepth(to); errno != 0 {
+			return errno
+		}
+	}
+	_, errno = q.FS.Lstat(to)
+	replaced := errno == 0
+	if errno = q.FS.Rename(from, to); errno == 0 && replaced {
+		q.removed()
+	}
+	return errno
+}
+
+// Rmdir implements the same method as documented on sys.FS
+func (q *QuotaFS) Rmdir(path string) experimentalsys.Errno {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	errno := q.FS.Rmdir(path)
+	if errno == 0 {
+		q.removed()
+	}
+	return errno
+}
+
+// Unlink implements the same method as documented on sys.FS
+func (q *QuotaFS) Unlink(path string) experimentalsys.Errno {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	errno := q.FS.Unlink(path)
+	if errno == 0 {
+		q.removed()
+	}
+	return errno
+}
+
+// Link implements the same method as documented on sys.FS
+func (q *QuotaFS) Link(oldPath, newPath string) experimentalsys.Errno {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.create(func() experimentalsys.Errno { return q.FS.Link(oldPath, newPath) })
+}
+
+// Symlink implements the same method as documented on sys.FS
+func (q *QuotaFS) Symlink(oldPath, linkName string) experimentalsys.Errno {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.create(func() experimentalsys.Errno { return q.FS.Symlink(oldPath, linkName) })
+}
+
+// reserve returns the count of bytes of n that can be written, or
+// sys.ENOSPC if none can.
+func (q *QuotaFS) reserve(n int) (int, experimentalsys.Errno) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if max := q.quota.MaxBytesWritten; max > 0 {
+		remaining := max - q.usage.BytesWritten
+		if remaining <= 0 && n > 0 {
+			return 0, experimentalsys.ENOSPC
+		} else if int64(n) > remaining {
+			n = int(remaining)
+		}
+	}
+	q.usage.BytesWritten += int64(n)
+	return n, 0
+}
+
+// release returns the bytes reserved, but not written.
+func (q *QuotaFS) release(n int) {
+	if n > 0 {
+		q.mu.Lock()
+		q.usage.BytesWritten -= int64(n)
+		q.mu.Unlock()
+	}
+}
+
+// compile-time check to ensure quotaFile implements sys.File.
+var _ experimentalsys.File = (*quotaFile)(nil)
+
+// quotaFile is a file opened by QuotaFS, which counts bytes written to it.
+type quotaFile struct {
+	experimentalsys.File
+
+	fs     *QuotaFS
+	closed bool
+}
+
+// Write implements the same method as documented on sys.File
+func (f *quotaFile) Write(buf []byte) (int, experimentalsys.Errno) {
+	return f.write(buf, func(buf []byte) (int, experimentalsys.Errno) { return f.File.Write(buf) })
+}
+
+// Pwrite implements the same method as documented on sys.File
+func (f *quotaFile) Pwrite(buf []byte, off int64) (int, experimentalsys.Errno) {
+	return f.write(buf, func(buf []byte) (int, experimentalsys.Errno) { return f.File.Pwrite(buf, off) })
+}
+
+func (f *quotaFile) write(buf []byte, fn func([]byte) (int, experimentalsys.Errno)) (int, experimentalsys.Errno) {
+	reserved, errno := f.fs.reserve(len(buf))
+	if errno != 0 {
+		return 0, errno
+	}
+	n, errno := fn(buf[:reserved])
+	f.fs.release(reserved - n)
+	return n, errno
+}
+
+// Close implements the same method as documented on sys.File
+func (f *quotaFile) Close() experimentalsys.Errno {
+	if !f.closed {
+		f.closed = true
+		f.fs.mu.Lock()
+		f.fs.usage.OpenFiles--
+		f.fs.mu.Unlock()
+	}
+	return f.File.Close()
+}
+
+// hostFd implements the same method as documented on hostFile
+func (f *quotaFile) hostFd() (uintptr, bool) {
+	return hostFdOf(f.File)
+}
+
+// compile-time check to ensure quotaPollableFile implements
+// sys.PollableFile.
+var _ experimentalsys.PollableFile = (*quotaPollableFile)(nil)
+
+// quotaPollableFile is a quotaFile for a sys.PollableFile.
+type quotaPollableFile struct {
+	*quotaFile
+	pf experimentalsys.PollableFile
+}
+
+// Poll implements the same method as documented on sys.PollableFile
+func (f *quotaPollableFile) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (bool, experimentalsys.Errno) {
+	return f.pf.Poll(flag, timeoutMillis)
+}
+
+// IsNonblock implements the same method as documented on sys.PollableFile
+func (f *quotaPollableFile) IsNonblock() bool {
+	return f.pf.IsNonblock()
+}
+
+// SetNonblock implements the same method as documented on sys.PollableFile
+func (f *quotaPollableFile) SetNonblock(enable bool) experimentalsys.Errno {
+	return f.pf.SetNonblock(enable)
+}
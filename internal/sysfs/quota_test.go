@@ -0,0 +1,227 @@
+package sysfs
+
+import (
+	"io/fs"
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestQuotaFS_Open_Read(t *testing.T) {
+	testOpen_Read(t, NewQuotaFS(newTestMemFS(t), Quota{}), true, true)
+}
+
+func TestQuotaFS_String(t *testing.T) {
+	require.Equal(t, "memfs", NewQuotaFS(newMemFS(), Quota{}).String())
+}
+
+func TestQuotaFS_BytesWritten(t *testing.T) {
+	testFS := NewQuotaFS(newMemFS(), Quota{MaxBytesWritten: 10})
+
+	f, errno := testFS.OpenFile("file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+
+	n, errno := f.Write([]byte("wazero"))
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 6, n)
+
+	// Only the remaining bytes are written.
+	n, errno = f.Pwrite([]byte("wazero"), 6)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, 4, n)
+	require.Equal(t, int64(10), testFS.Usage().BytesWritten)
+
+	_, errno = f.Write([]byte("a"))
+	require.EqualErrno(t, experimentalsys.ENOSPC, errno)
+
+	// Writing nothing doesn't fail.
+	_, errno = f.Write(nil)
+	require.EqualErrno(t, 0, errno)
+
+	buf := make([]byte, 20)
+	n, errno = f.Pread(buf, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, "wazerowaze", string(buf[:n]))
+}
+
+func TestQuotaFS_Files(t *testing.T) {
+	testFS := NewQuotaFS(newMemFS(), Quota{MaxFiles: 3})
+
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	f, errno := testFS.OpenFile("dir/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.EqualErrno(t, 0, testFS.Symlink("dir/file", "link"))
+	require.Equal(t, int64(3), testFS.Usage().Files)
+
+	_, errno = testFS.OpenFile("file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, experimentalsys.EDQUOT, errno)
+	require.EqualErrno(t, experimentalsys.EDQUOT, testFS.Mkdir("dir2", 0o700))
+	require.EqualErrno(t, experimentalsys.EDQUOT, testFS.Link("dir/file", "file"))
+	require.EqualErrno(t, experimentalsys.EDQUOT, testFS.Symlink("dir/file", "link2"))
+
+	// Opening an existing file doesn't count.
+	f, errno = testFS.OpenFile("dir/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+
+	// Renaming over a file removes it.
+	require.EqualErrno(t, 0, testFS.Rename("link", "dir/file"))
+	require.Equal(t, int64(2), testFS.Usage().Files)
+
+	require.EqualErrno(t, 0, testFS.Link("dir/file", "file"))
+	require.EqualErrno(t, 0, testFS.Unlink("file"))
+	require.EqualErrno(t, 0, testFS.Unlink("dir/file"))
+	require.EqualErrno(t, 0, testFS.Rmdir("dir"))
+	require.Equal(t, int64(0), testFS.Usage().Files)
+}
+
+func TestQuotaFS_Files_existing(t *testing.T) {
+	memFS := newMemFS()
+	require.EqualErrno(t, 0, memFS.Mkdir("dir", 0o700))
+	testFS := NewQuotaFS(memFS, Quota{MaxFiles: 1})
+
+	// Removing a file which existed when mounted doesn't make room for more.
+	require.EqualErrno(t, 0, testFS.Rmdir("dir"))
+	require.Equal(t, int64(0), testFS.Usage().Files)
+	require.EqualErrno(t, 0, testFS.Mkdir("dir", 0o700))
+	require.EqualErrno(t, experimentalsys.EDQUOT, testFS.Mkdir("dir2", 0o700))
+}
+
+func TestQuotaFS_OpenFile_create(t *testing.T) {
+	testFS := NewQuotaFS(newMemFS(), Quota{MaxFiles: 1})
+
+	f, errno := testFS.OpenFile("file", experimentalsys.O_CREAT|experimentalsys.O_EXCL|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.Equal(t, int64(1), testFS.Usage().Files)
+
+	// Failing to open gives back the reservation.
+	_, errno = testFS.OpenFile("missing/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, experimentalsys.EDQUOT, errno)
+	require.EqualErrno(t, 0, testFS.Unlink("file"))
+	_, errno = testFS.OpenFile("missing/file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, experimentalsys.ENOENT, errno)
+	require.Equal(t, QuotaUsage{}, testFS.Usage())
+
+	// At the limit, O_EXCL fails as if there were no limit on existing files.
+	f, errno = testFS.OpenFile("file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	_, errno = testFS.OpenFile("file", experimentalsys.O_CREAT|experimentalsys.O_EXCL|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, experimentalsys.EEXIST, errno)
+	_, errno = testFS.OpenFile("file2", experimentalsys.O_CREAT|experimentalsys.O_EXCL|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, experimentalsys.EDQUOT, errno)
+	require.Equal(t, QuotaUsage{Files: 1}, testFS.Usage())
+
+	// A dangling symbolic link creates its target.
+	require.EqualErrno(t, 0, testFS.Unlink("file"))
+	require.EqualErrno(t, 0, testFS.FS.Symlink("file", "link"))
+	f, errno = testFS.OpenFile("link", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f.Close())
+	require.Equal(t, QuotaUsage{Files: 1}, testFS.Usage())
+}
+
+// usageFS is a sys.FS which reads the usage of a QuotaFS while opening a file.
+type usageFS struct {
+	experimentalsys.FS
+	q     *QuotaFS
+	usage QuotaUsage
+}
+
+// OpenFile implements the same method as documented on sys.FS
+func (u *usageFS) OpenFile(path string, flag experimentalsys.Oflag, perm fs.FileMode) (experimentalsys.File, experimentalsys.Errno) {
+	u.usage = u.q.Usage()
+	return u.FS.OpenFile(path, flag, perm)
+}
+
+func TestQuotaFS_OpenFile_unlocked(t *testing.T) {
+	u := &usageFS{FS: newMemFS()}
+	u.q = NewQuotaFS(u, Quota{})
+
+	// The lock isn't held while opening, but the file is already counted.
+	f, errno := u.q.OpenFile("file", experimentalsys.O_CREAT|experimentalsys.O_RDWR, 0o600)
+	require.EqualErrno(t, 0, errno)
+	defer f.Close()
+	require.Equal(t, QuotaUsage{Files: 1, OpenFiles: 1}, u.usage)
+}
+
+func TestQuotaFS_OpenFiles(t *testing.T) {
+	testFS := NewQuotaFS(newTestMemFS(t), Quota{MaxOpenFilesInFS: 2})
+
+	f1, errno := testFS.OpenFile("animals.txt", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	f2, errno := testFS.OpenFile(".", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.Equal(t, int64(2), testFS.Usage().OpenFiles)
+
+	_, errno = testFS.OpenFile("animals.txt", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, experimentalsys.EMFILE, errno)
+
+	// Closing twice only counts once.
+	require.EqualErrno(t, 0, f1.Close())
+	require.EqualErrno(t, 0, f1.Close())
+	require.Equal(t, int64(1), testFS.Usage().OpenFiles)
+
+	f1, errno = testFS.OpenFile("animals.txt", experimentalsys.O_RDONLY, 0)
+	require.EqualErrno(t, 0, errno)
+	require.EqualErrno(t, 0, f1.Close())
+	require.EqualErrno(t, 0, f2.Close())
+	require.Equal(t, int64(0), testFS.Usage().OpenFiles)
+}
+
+func TestQuotaFS_Depth(t *testing.T) {
+	testFS := NewQuotaFS(newMemFS(), Quota{MaxDepth: 2})
+
+	require.EqualErrno(t, 0, testFS.Mkdir("a", 0o700))
+	require.EqualErrno(t, 0, testFS.Mkdir("a/b", 0o700))
+	require.EqualErrno(t, experimentalsys.EDQUOT, testFS.Mkdir("a/b/c", 0o700))
+	require.EqualErrno(t, experimentalsys.EDQUOT, testFS.Mkdir("/a/./b/c/", 0o700))
+
+	require.EqualErrno(t, 0, testFS.Mkdir("c", 0o700))
+	require.EqualErrno(t, experimentalsys.EDQUOT, testFS.Rename("c", "a/b/c"))
+	require.EqualErrno(t, 0, testFS.Rename("c", "a/c"))
+
+	// A symbolic link to a directory doesn't bypass the limit.
+	require.EqualErrno(t, 0, testFS.Symlink("a/b", "ab"))
+	require.EqualErrno(t, experimentalsys.EDQUOT, testFS.Mkdir("ab/c", 0o700))
+}
+
+func TestQuotaFS_depth(t *testing.T) {
+	testFS := NewQuotaFS(newMemFS(), Quota{})
+	require.EqualErrno(t, 0, testFS.Mkdir("a", 0o700))
+	require.EqualErrno(t, 0, testFS.Mkdir("a/b", 0o700))
+	require.EqualErrno(t, 0, testFS.Symlink("a/b", "ab"))
+	require.EqualErrno(t, 0, testFS.Symlink("/a/b", "abs"))
+	require.EqualErrno(t, 0, testFS.Symlink("loop", "loop"))
+
+	tests := []struct {
+		path          string
+		expected      int
+		expectedErrno experimentalsys.Errno
+	}{
+		{path: "", expected: 0},
+		{path: ".", expected: 0},
+		{path: "/", expected: 0},
+		{path: "a", expected: 1},
+		{path: "a/b/", expected: 2},
+		{path: "/a/../b/c", expected: 2},
+		{path: "ab", expected: 1}, // the last component isn't followed
+		{path: "ab/c", expected: 3},
+		{path: "abs/c", expected: 3},
+		{path: "ab/../c", expected: 2},
+		{path: "loop/c", expectedErrno: experimentalsys.ELOOP},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.path, func(t *testing.T) {
+			depth, errno := testFS.depth(tc.path)
+			require.EqualErrno(t, tc.expectedErrno, errno)
+			require.Equal(t, tc.expected, depth)
+		})
+	}
+}
@@ -107,6 +107,20 @@ func (r *readFile) Utimens(int64, int64) experimentalsys.Errno {
 	return experimentalsys.EBADF
 }
 
+// Poll implements experimentalsys.Pollable by forwarding to the underlying
+// file if it supports polling.
+func (r *readFile) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
+	if p, ok := r.File.(experimentalsys.Pollable); ok {
+		return p.Poll(flag, timeoutMillis)
+	}
+	return false, experimentalsys.ENOSYS
+}
+
+// hostFd implements the same method as documented on hostFile
+func (r *readFile) hostFd() (uintptr, bool) {
+	return hostFdOf(r.File)
+}
+
 func (r *readFile) writeErr() experimentalsys.Errno {
 	if isDir, errno := r.IsDir(); errno != 0 {
 		return errno
@@ -0,0 +1,53 @@
+package sysfs
+
+import (
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+)
+
+// NewRightsFS returns a RightsFS which pre-opens fs with the given WASI
+// rights.
+func NewRightsFS(fs experimentalsys.FS, base, inheriting uint32) *RightsFS {
+	return &RightsFS{FS: fs, Base: base, Inheriting: inheriting}
+}
+
+// RightsFS is a sys.FS whose pre-opened directory has limited WASI rights,
+// such as wasip1.RIGHT_FD_READ. It doesn't change the behavior of the FS:
+// rights are enforced by the WASI host functions on the descriptors of the
+// pre-open and the files opened from it.
+type RightsFS struct {
+	experimentalsys.FS
+
+	// Base are the rights of the pre-opened directory.
+	Base uint32
+
+	// Inheriting are the maximum rights of files opened from the pre-opened
+	// directory.
+	Inheriting uint32
+}
+
+// String implements fmt.Stringer
+func (r *RightsFS) String() string {
+	if s, ok := r.FS.(interface{ String() string }); ok {
+		return s.String()
+	}
+	return "rights"
+}
+
+// FindRightsFS returns the RightsFS of fs, which is either fs or wrapped by
+// it, such as by a QuotaFS, or nil if there is none.
+func FindRightsFS(fs experimentalsys.FS) *RightsFS {
+	for {
+		switch f := fs.(type) {
+		case *RightsFS:
+			return f
+		case *ReadFS:
+			fs = f.FS
+		case *QuotaFS:
+			fs = f.FS
+		case *PolicyFS:
+			fs = f.FS
+		default:
+			return nil
+		}
+	}
+}
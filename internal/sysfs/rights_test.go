@@ -0,0 +1,32 @@
+package sysfs
+
+import (
+	"testing"
+
+	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestFindRightsFS(t *testing.T) {
+	rightsFS := NewRightsFS(NewMemFS(), 1, 2)
+
+	tests := []struct {
+		name     string
+		fs       experimentalsys.FS
+		expected *RightsFS
+	}{
+		{name: "RightsFS", fs: rightsFS, expected: rightsFS},
+		{name: "ReadFS", fs: &ReadFS{FS: rightsFS}, expected: rightsFS},
+		{name: "QuotaFS", fs: NewQuotaFS(rightsFS, Quota{}), expected: rightsFS},
+		{name: "PolicyFS", fs: NewPolicyFS(&ReadFS{FS: rightsFS}, nil), expected: rightsFS},
+		{name: "MemFS", fs: NewMemFS()},
+		{name: "nil"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, FindRightsFS(tc.fs))
+		})
+	}
+}
@@ -3,6 +3,7 @@ package sysfs
 import (
 	"net"
 	"os"
+	"syscall"
 
 	experimentalsys "github.com/tetratelabs/wazero/experimental/sys"
 	socketapi "github.com/tetratelabs/wazero/internal/sock"
@@ -77,7 +78,12 @@ func (f *tcpListenerFile) IsNonblock() bool {
 
 // Poll implements the same method as documented on experimentalsys.Pollable
 func (f *tcpListenerFile) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
-	return false, experimentalsys.ENOSYS
+	return _pollSock(f.tl, flag, timeoutMillis)
+}
+
+// hostFd implements the same method as documented on hostFile
+func (f *tcpListenerFile) hostFd() (uintptr, bool) {
+	return connFd(f.tl)
 }
 
 var _ socketapi.TCPConn = (*tcpConnFile)(nil)
@@ -182,5 +188,20 @@ func (f *tcpConnFile) IsNonblock() bool {
 
 // Poll implements the same method as documented on experimentalsys.Pollable
 func (f *tcpConnFile) Poll(flag experimentalsys.Pflag, timeoutMillis int32) (ready bool, errno experimentalsys.Errno) {
-	return false, experimentalsys.ENOSYS
+	return _pollSock(f.tc, flag, timeoutMillis)
+}
+
+// hostFd implements the same method as documented on hostFile
+func (f *tcpConnFile) hostFd() (uintptr, bool) {
+	return connFd(f.tc)
+}
+
+// connFd returns the file descriptor of the connection, or false if it is
+// closed or unsupported.
+func connFd(conn syscall.Conn) (fd uintptr, ok bool) {
+	_, errno := syscallConnControl(conn, func(connFd uintptr) (int, experimentalsys.Errno) {
+		fd = connFd
+		return 0, 0
+	})
+	return fd, errno == 0
 }
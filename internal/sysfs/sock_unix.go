@@ -19,7 +19,7 @@ func newTCPListenerFile(tl *net.TCPListener) socketapi.TCPSock {
 
 func _pollSock(conn syscall.Conn, flag sys.Pflag, timeoutMillis int32) (bool, sys.Errno) {
 	n, errno := syscallConnControl(conn, func(fd uintptr) (int, sys.Errno) {
-		if ready, errno := poll(fd, sys.POLLIN, 0); !ready || errno != 0 {
+		if ready, errno := poll(fd, flag, timeoutMillis); !ready || errno != 0 {
 			return -1, errno
 		} else {
 			return 0, errno
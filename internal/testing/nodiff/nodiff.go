@@ -14,9 +14,9 @@ import (
 	"github.com/tetratelabs/wazero/api"
 	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/experimental/logging"
-	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
 )
 
 // We haven't had public APIs for referencing all the imported entries from wazero.CompiledModule,
@@ -50,9 +50,23 @@ func RequireNoDiffT(t *testing.T, wasmBin []byte, checkMemory, loggingCheck bool
 
 // RequireNoDiff ensures that the behavior is the same between the compiler and the interpreter for any given binary.
 func RequireNoDiff(wasmBin []byte, checkMemory, loggingCheck bool, requireNoError func(err error)) {
+	requireNoDiff(wasmBin, false, checkMemory, loggingCheck, requireNoError)
+}
+
+// RequireNoDiffDeterministicT is like RequireNoDiffT, except both runtimes are
+// configured with wazero.RuntimeConfig WithDeterministic. This means results
+// are the same even when they are NaN, so any binary can be compared, as
+// long as it doesn't use threads.
+func RequireNoDiffDeterministicT(t *testing.T, wasmBin []byte, checkMemory, loggingCheck bool) {
+	requireNoDiff(wasmBin, true, checkMemory, loggingCheck, func(err error) { require.NoError(t, err) })
+}
+
+func requireNoDiff(wasmBin []byte, deterministic, checkMemory, loggingCheck bool, requireNoError func(err error)) {
 	const features = api.CoreFeaturesV2 | experimental.CoreFeaturesThreads | experimental.CoreFeaturesTailCall | experimental.CoreFeaturesExtendedConst | experimental.CoreFeaturesExceptionHandling | experimental.CoreFeaturesTypedFunctionReferences
-	compiler := wazero.NewRuntimeWithConfig(context.Background(), wazero.NewRuntimeConfigCompiler().WithCoreFeatures(features))
-	interpreter := wazero.NewRuntimeWithConfig(context.Background(), wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(features))
+	compiler := wazero.NewRuntimeWithConfig(context.Background(),
+		wazero.NewRuntimeConfigCompiler().WithCoreFeatures(features).WithDeterministic(deterministic))
+	interpreter := wazero.NewRuntimeWithConfig(context.Background(),
+		wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(features).WithDeterministic(deterministic))
 	defer compiler.Close(context.Background())
 	defer interpreter.Close(context.Background())
 
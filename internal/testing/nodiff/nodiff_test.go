@@ -1,12 +1,19 @@
 package nodiff
 
 import (
+	"context"
+	"math"
 	"testing"
 
+	"github.com/tetratelabs/wazero"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wat"
 )
 
+// testCtx is an arbitrary, non-default context. Non-nil also prevents linter errors.
+var testCtx = context.WithValue(context.Background(), struct{}{}, "arbitrary")
+
 func Test_ensureMutableGlobalsMatch(t *testing.T) {
 	for _, tc := range []struct {
 		name   string
@@ -203,3 +210,52 @@ func Test_ensureMutableGlobalsMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireNoDiffDeterministicT(t *testing.T) {
+	// Each function returns NaN whose sign or payload differs between amd64,
+	// arm64 and the interpreter, unless canonicalized.
+	bin, err := wat.Compile([]byte(`(module
+	(func (export "f32.div") (result f32) (f32.div (f32.const 0) (f32.const 0)))
+	(func (export "f32.add") (result f32) (f32.add (f32.const -nan:0x1) (f32.const nan:0x2)))
+	(func (export "f32.sqrt") (result f32) (f32.sqrt (f32.const -1)))
+	(func (export "f32.demote_f64") (result f32) (f32.demote_f64 (f64.const -nan:0x1)))
+	(func (export "f64.div") (result f64) (f64.div (f64.const 0) (f64.const 0)))
+	(func (export "f64.min") (result f64) (f64.min (f64.const -nan:0x1) (f64.const 1)))
+	(func (export "f64.promote_f32") (result f64) (f64.promote_f32 (f32.const -nan:0x1)))
+	(func (export "f64.neg") (result f64) (f64.neg (f64.const nan:0x1)))
+	(func (export "f32x4.div") (result v128)
+		(f32x4.div (v128.const f32x4 0 1 -nan:0x1 4) (v128.const f32x4 0 1 2 2)))
+	(func (export "f64x2.sqrt") (result v128) (f64x2.sqrt (v128.const f64x2 -1 4)))
+)`))
+	require.NoError(t, err)
+
+	RequireNoDiffDeterministicT(t, bin, false, false)
+
+	// The results are also the same on each platform the compiler runs.
+	const nan32, nan64 = uint64(wasm.CanonicalNaNF32), wasm.CanonicalNaNF64
+	expected := map[string][]uint64{
+		"f32.div":         {nan32},
+		"f32.add":         {nan32},
+		"f32.sqrt":        {nan32},
+		"f32.demote_f64":  {nan32},
+		"f64.div":         {nan64},
+		"f64.min":         {nan64},
+		"f64.promote_f32": {nan64},
+		"f64.neg":         {0xfff0_0000_0000_0001}, // neg only flips the sign, so isn't canonicalized.
+		"f32x4.div":       {nan32 | uint64(math.Float32bits(1))<<32, nan32 | uint64(math.Float32bits(2))<<32},
+		"f64x2.sqrt":      {nan64, math.Float64bits(2)},
+	}
+
+	for _, config := range []wazero.RuntimeConfig{wazero.NewRuntimeConfigCompiler(), wazero.NewRuntimeConfigInterpreter()} {
+		r := wazero.NewRuntimeWithConfig(testCtx, config.WithDeterministic(true))
+		mod, err := r.Instantiate(testCtx, bin)
+		require.NoError(t, err)
+
+		for name, exp := range expected {
+			results, err := mod.ExportedFunction(name).Call(testCtx)
+			require.NoError(t, err)
+			require.Equal(t, exp, results, name)
+		}
+		require.NoError(t, r.Close(testCtx))
+	}
+}
@@ -174,9 +174,12 @@ const (
 	ErrnoTxtbsy
 	// ErrnoXdev Cross-device link.
 	ErrnoXdev
-
-	// Note: ErrnoNotcapable was removed by WASI maintainers.
+	// ErrnoNotcapable Extension: Capabilities insufficient.
+	//
+	// Note: This was removed from the WASI documentation, but is still
+	// defined by wasi-libc. It is only returned when rights are enforced.
 	// See https://github.com/WebAssembly/wasi-libc/pull/294
+	ErrnoNotcapable
 )
 
 var errnoToString = [...]string{
@@ -308,6 +311,18 @@ func ToErrno(errno sys.Errno) Errno {
 		return ErrnoPerm
 	case sys.EROFS:
 		return ErrnoRofs
+	case sys.EXDEV:
+		return ErrnoXdev
+	case sys.EDQUOT:
+		return ErrnoDquot
+	case sys.EMFILE:
+		return ErrnoMfile
+	case sys.ENOSPC:
+		return ErrnoNospc
+	case sys.ENOTCAPABLE:
+		return ErrnoNotcapable
+	case sys.EPIPE:
+		return ErrnoPipe
 	default:
 		return ErrnoIo
 	}
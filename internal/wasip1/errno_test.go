@@ -111,6 +111,36 @@ func TestToErrno(t *testing.T) {
 			input:    sys.EROFS,
 			expected: ErrnoRofs,
 		},
+		{
+			name:     "sys.EXDEV",
+			input:    sys.EXDEV,
+			expected: ErrnoXdev,
+		},
+		{
+			name:     "sys.EDQUOT",
+			input:    sys.EDQUOT,
+			expected: ErrnoDquot,
+		},
+		{
+			name:     "sys.EMFILE",
+			input:    sys.EMFILE,
+			expected: ErrnoMfile,
+		},
+		{
+			name:     "sys.ENOSPC",
+			input:    sys.ENOSPC,
+			expected: ErrnoNospc,
+		},
+		{
+			name:     "sys.ENOTCAPABLE",
+			input:    sys.ENOTCAPABLE,
+			expected: ErrnoNotcapable,
+		},
+		{
+			name:     "sys.EPIPE",
+			input:    sys.EPIPE,
+			expected: ErrnoPipe,
+		},
 		{
 			name:     "sys.EqualErrno unexpected == ErrnoIo",
 			input:    sys.Errno(0xfe),
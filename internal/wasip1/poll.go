@@ -10,6 +10,15 @@ const (
 	EventTypeFdWrite
 )
 
+// SubclockflagsAbstime means the clock subscription timeout is an absolute
+// time, instead of relative to the current time.
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-subclockflags-recordu16
+const SubclockflagsAbstime = 1
+
+// EventRwFlagsHangup means the peer of this socket or pipe hung up.
+// https://github.com/WebAssembly/WASI/blob/snapshot-01/phases/snapshot/docs.md#-eventrwflags-recordu16
+const EventRwFlagsHangup = 1
+
 const (
 	PollOneoffName = "poll_oneoff"
 )
@@ -3,9 +3,9 @@ package binary
 import (
 	"testing"
 
-	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
 )
 
 func TestEncodeImport(t *testing.T) {
@@ -109,7 +109,7 @@ func TestEncodeImport(t *testing.T) {
 				Type:      wasm.ExternTypeTable,
 				Module:    "my",
 				Name:      "table",
-				DescTable: wasm.Table{Min: 1, Max: ptrOfUint32(2)},
+				DescTable: wasm.Table{Type: wasm.RefTypeFuncref, Min: 1, Max: ptrOfUint32(2)},
 			},
 			expected: []byte{
 				0x02, 'm', 'y',
@@ -0,0 +1,105 @@
+package binaryencoding
+
+import (
+	"bytes"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// The element segment prefixes are explained at https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/binary/modules.html#element-section
+const (
+	elementSegmentPrefixLegacy = iota
+	elementSegmentPrefixPassiveFuncrefValueVector
+	elementSegmentPrefixActiveFuncrefValueVectorWithTableIndex
+	elementSegmentPrefixDeclarativeFuncrefValueVector
+	elementSegmentPrefixActiveFuncrefConstExprVector
+	elementSegmentPrefixPassiveConstExprVector
+	elementSegmentPrefixActiveConstExprVector
+	elementSegmentPrefixDeclarativeConstExprVector
+)
+
+// elemKindFuncRef is the only element kind, used by prefixes with a vector of function indexes.
+const elemKindFuncRef = 0x0
+
+// encodeElement returns the wasm.ElementSegment encoded in WebAssembly 2.0 Binary Format.
+//
+// Function indexes are encoded in the most compact form: when all initializers are a single ref.func of a segment of
+// non-nullable funcref, they are encoded as a vector of indexes, otherwise as a vector of expressions. Segments without a
+// type are encoded the same, as only function indexes exist in WebAssembly 1.0 (20191205).
+//
+// https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/binary/modules.html#element-section
+func encodeElement(e *wasm.ElementSegment) (ret []byte) {
+	typ := e.Type
+	if typ == 0 { // Unset, as only funcref segments exist in WebAssembly 1.0 (20191205).
+		typ = wasm.RefTypeFuncref.AsNonNullable()
+	}
+	funcIndexes, ok := elementFunctionIndexes(typ, e.Init)
+	if ok {
+		switch e.Mode {
+		case wasm.ElementModeActive:
+			if e.TableIndex == 0 {
+				ret = leb128.EncodeUint32(elementSegmentPrefixLegacy)
+				ret = append(ret, encodeConstantExpression(e.OffsetExpr)...)
+			} else {
+				ret = leb128.EncodeUint32(elementSegmentPrefixActiveFuncrefValueVectorWithTableIndex)
+				ret = append(ret, leb128.EncodeUint32(e.TableIndex)...)
+				ret = append(ret, encodeConstantExpression(e.OffsetExpr)...)
+				ret = append(ret, elemKindFuncRef)
+			}
+		case wasm.ElementModePassive:
+			ret = append(leb128.EncodeUint32(elementSegmentPrefixPassiveFuncrefValueVector), elemKindFuncRef)
+		case wasm.ElementModeDeclarative:
+			ret = append(leb128.EncodeUint32(elementSegmentPrefixDeclarativeFuncrefValueVector), elemKindFuncRef)
+		}
+		ret = append(ret, leb128.EncodeUint32(uint32(len(funcIndexes)))...)
+		for _, idx := range funcIndexes {
+			ret = append(ret, leb128.EncodeUint32(idx)...)
+		}
+		return
+	}
+
+	switch e.Mode {
+	case wasm.ElementModeActive:
+		if e.TableIndex == 0 && typ == wasm.RefTypeFuncref {
+			ret = leb128.EncodeUint32(elementSegmentPrefixActiveFuncrefConstExprVector)
+			ret = append(ret, encodeConstantExpression(e.OffsetExpr)...)
+		} else {
+			ret = leb128.EncodeUint32(elementSegmentPrefixActiveConstExprVector)
+			ret = append(ret, leb128.EncodeUint32(e.TableIndex)...)
+			ret = append(ret, encodeConstantExpression(e.OffsetExpr)...)
+			ret = append(ret, EncodeValueType(typ)...)
+		}
+	case wasm.ElementModePassive:
+		ret = leb128.EncodeUint32(elementSegmentPrefixPassiveConstExprVector)
+		ret = append(ret, EncodeValueType(typ)...)
+	case wasm.ElementModeDeclarative:
+		ret = leb128.EncodeUint32(elementSegmentPrefixDeclarativeConstExprVector)
+		ret = append(ret, EncodeValueType(typ)...)
+	}
+	ret = append(ret, leb128.EncodeUint32(uint32(len(e.Init)))...)
+	for _, expr := range e.Init {
+		ret = append(ret, encodeConstantExpression(expr)...)
+	}
+	return
+}
+
+// elementFunctionIndexes returns the function indexes of init, if typ is the non-nullable funcref, which is the type
+// of a vector of function indexes, and each expression is a single ref.func.
+func elementFunctionIndexes(typ wasm.RefType, init []wasm.ConstantExpression) ([]wasm.Index, bool) {
+	if typ != wasm.RefTypeFuncref.AsNonNullable() {
+		return nil, false
+	}
+	indexes := make([]wasm.Index, 0, len(init))
+	for _, expr := range init {
+		if len(expr.Data) < 2 || expr.Data[0] != wasm.OpcodeRefFunc {
+			return nil, false
+		}
+		idx, n, err := leb128.DecodeUint32(bytes.NewReader(expr.Data[1:]))
+		if err != nil || int(1+n) != len(expr.Data)-1 || expr.Data[1+n] != wasm.OpcodeEnd {
+			return nil, false
+		}
+		indexes = append(indexes, idx)
+	}
+	return indexes, true
+}
@@ -0,0 +1,111 @@
+package binaryencoding
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestEncodeElement(t *testing.T) {
+	refFunc1 := wasm.NewConstantExpressionFromOpcode(wasm.OpcodeRefFunc, []byte{1})
+	refNull := wasm.NewConstantExpressionFromOpcode(wasm.OpcodeRefNull, []byte{wasm.RefTypeFuncref.Kind()})
+	offset := wasm.NewConstantExpressionFromI32(2)
+
+	tests := []struct {
+		name     string
+		input    wasm.ElementSegment
+		expected []byte
+	}{
+		{
+			name:  "active legacy",
+			input: wasm.ElementSegment{OffsetExpr: offset, Init: []wasm.ConstantExpression{refFunc1}},
+			expected: []byte{
+				elementSegmentPrefixLegacy,
+				wasm.OpcodeI32Const, 0x02, wasm.OpcodeEnd,
+				0x01, 0x01, // vec(funcidx)
+			},
+		},
+		{
+			name: "active table index",
+			input: wasm.ElementSegment{
+				OffsetExpr: offset, TableIndex: 1, Init: []wasm.ConstantExpression{refFunc1},
+				Type: wasm.RefTypeFuncref.AsNonNullable(),
+			},
+			expected: []byte{
+				elementSegmentPrefixActiveFuncrefValueVectorWithTableIndex, 0x01,
+				wasm.OpcodeI32Const, 0x02, wasm.OpcodeEnd,
+				elemKindFuncRef,
+				0x01, 0x01, // vec(funcidx)
+			},
+		},
+		{
+			name:  "passive",
+			input: wasm.ElementSegment{Mode: wasm.ElementModePassive, Init: []wasm.ConstantExpression{refFunc1}, Type: wasm.RefTypeFuncref.AsNonNullable()},
+			expected: []byte{
+				elementSegmentPrefixPassiveFuncrefValueVector, elemKindFuncRef,
+				0x01, 0x01, // vec(funcidx)
+			},
+		},
+		{
+			name:  "declarative",
+			input: wasm.ElementSegment{Mode: wasm.ElementModeDeclarative, Init: []wasm.ConstantExpression{refFunc1}, Type: wasm.RefTypeFuncref.AsNonNullable()},
+			expected: []byte{
+				elementSegmentPrefixDeclarativeFuncrefValueVector, elemKindFuncRef,
+				0x01, 0x01, // vec(funcidx)
+			},
+		},
+		{
+			name:  "active nullable function indexes",
+			input: wasm.ElementSegment{OffsetExpr: offset, Init: []wasm.ConstantExpression{refFunc1}, Type: wasm.RefTypeFuncref},
+			expected: []byte{
+				elementSegmentPrefixActiveFuncrefConstExprVector,
+				wasm.OpcodeI32Const, 0x02, wasm.OpcodeEnd,
+				0x01, wasm.OpcodeRefFunc, 0x01, wasm.OpcodeEnd,
+			},
+		},
+		{
+			name:  "active expressions",
+			input: wasm.ElementSegment{OffsetExpr: offset, Init: []wasm.ConstantExpression{refNull}, Type: wasm.RefTypeFuncref},
+			expected: []byte{
+				elementSegmentPrefixActiveFuncrefConstExprVector,
+				wasm.OpcodeI32Const, 0x02, wasm.OpcodeEnd,
+				0x01, wasm.OpcodeRefNull, wasm.RefTypeFuncref.Kind(), wasm.OpcodeEnd,
+			},
+		},
+		{
+			name:  "active externref",
+			input: wasm.ElementSegment{OffsetExpr: offset, TableIndex: 1, Init: []wasm.ConstantExpression{refNull}, Type: wasm.RefTypeExternref},
+			expected: []byte{
+				elementSegmentPrefixActiveConstExprVector, 0x01,
+				wasm.OpcodeI32Const, 0x02, wasm.OpcodeEnd,
+				wasm.RefTypeExternref.Kind(),
+				0x01, wasm.OpcodeRefNull, wasm.RefTypeFuncref.Kind(), wasm.OpcodeEnd,
+			},
+		},
+		{
+			name:  "passive expressions",
+			input: wasm.ElementSegment{Mode: wasm.ElementModePassive, Init: []wasm.ConstantExpression{refNull}, Type: wasm.RefTypeFuncref},
+			expected: []byte{
+				elementSegmentPrefixPassiveConstExprVector, wasm.RefTypeFuncref.Kind(),
+				0x01, wasm.OpcodeRefNull, wasm.RefTypeFuncref.Kind(), wasm.OpcodeEnd,
+			},
+		},
+		{
+			name:  "declarative expressions",
+			input: wasm.ElementSegment{Mode: wasm.ElementModeDeclarative, Init: []wasm.ConstantExpression{refNull}, Type: wasm.RefTypeFuncref},
+			expected: []byte{
+				elementSegmentPrefixDeclarativeConstExprVector, wasm.RefTypeFuncref.Kind(),
+				0x01, wasm.OpcodeRefNull, wasm.RefTypeFuncref.Kind(), wasm.OpcodeEnd,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, encodeElement(&tc.input))
+		})
+	}
+}
@@ -18,8 +18,7 @@ func EncodeImport(i *wasm.Import) []byte {
 	case wasm.ExternTypeFunc:
 		data = append(data, leb128.EncodeUint32(i.DescFunc)...)
 	case wasm.ExternTypeTable:
-		data = append(data, wasm.RefTypeFuncref.Kind())
-		data = append(data, EncodeLimitsType(i.DescTable.Min, i.DescTable.Max, false)...)
+		data = append(data, EncodeTable(&i.DescTable)...)
 	case wasm.ExternTypeMemory:
 		maxPtr := &i.DescMem.Max
 		if !i.DescMem.IsMaxEncoded {
@@ -32,7 +31,8 @@ func EncodeImport(i *wasm.Import) []byte {
 		if g.Mutable {
 			mutable = 1
 		}
-		data = append(data, g.ValType.Kind(), mutable)
+		data = append(data, EncodeValueType(g.ValType)...)
+		data = append(data, mutable)
 	case wasm.ExternTypeTag:
 		data = append(data, 0x00) // attribute byte
 		data = append(data, leb128.EncodeUint32(i.DescTag)...)
@@ -17,12 +17,24 @@ func encodeSection(sectionID wasm.SectionID, contents []byte) []byte {
 // See EncodeFunctionType
 // See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#type-section%E2%91%A0
 func encodeTypeSection(types []wasm.FunctionType) []byte {
-	contents := leb128.EncodeUint32(uint32(len(types)))
+	var count uint32
+	var contents []byte
 	for i := range types {
 		t := &types[i]
+		// Types in a rec group of the GC proposal are prefixed by 0x4e and the size of the group, which counts as
+		// one entry of the vector.
+		if t.RecGroupSize > 1 {
+			if t.RecGroupPosition != 0 {
+				contents = append(contents, EncodeFunctionType(t)...)
+				continue
+			}
+			contents = append(contents, 0x4e)
+			contents = append(contents, leb128.EncodeUint32(uint32(t.RecGroupSize))...)
+		}
 		contents = append(contents, EncodeFunctionType(t)...)
+		count++
 	}
-	return encodeSection(wasm.SectionIDType, contents)
+	return encodeSection(wasm.SectionIDType, append(leb128.EncodeUint32(count), contents...))
 }
 
 // encodeImportSection encodes a wasm.SectionIDImport for the given imports in WebAssembly 1.0 (20191205) Binary
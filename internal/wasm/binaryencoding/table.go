@@ -0,0 +1,22 @@
+package binaryencoding
+
+import (
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// EncodeTable returns the wasm.Table encoded in WebAssembly 1.0 (20191205) Binary Format.
+//
+// A table with an initializer expression is encoded as defined by the function-references proposal.
+//
+// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#binary-table
+func EncodeTable(i *wasm.Table) (data []byte) {
+	if i.InitExpr != nil {
+		data = []byte{0x40, 0x00}
+	}
+	data = append(data, EncodeValueType(i.Type)...)
+	data = append(data, EncodeLimitsType(i.Min, i.Max, false)...)
+	if i.InitExpr != nil {
+		data = append(data, encodeConstantExpression(*i.InitExpr)...)
+	}
+	return
+}
@@ -29,10 +29,28 @@ func EncodeValTypes(vt []wasm.ValueType) []byte {
 			return encoded
 		}
 	}
-	// Encode value types as their Kind bytes
 	count := leb128.EncodeUint32(uint32(len(vt)))
 	for _, v := range vt {
-		count = append(count, v.Kind())
+		count = append(count, EncodeValueType(v)...)
 	}
 	return count
 }
+
+// EncodeValueType returns the binary encoding of a value type. Nullable abstract reference types use their short
+// form, such as funcref, while others are encoded with a prefix and their heap type.
+//
+// See https://webassembly.github.io/function-references/core/binary/types.html#reference-types
+func EncodeValueType(v wasm.ValueType) []byte {
+	if !v.IsRef() || (v.IsNullable() && !v.IsConcreteRef()) {
+		return []byte{v.Kind()}
+	}
+	prefix := wasm.RefPrefixNonNullable
+	if v.IsNullable() {
+		prefix = wasm.RefPrefixNullable
+	}
+	if v.IsConcreteRef() {
+		return append([]byte{prefix}, leb128.EncodeInt64(int64(v.TypeIndex()))...)
+	}
+	// The short form of an abstract heap type is the same as its heap type.
+	return []byte{prefix, v.Kind()}
+}
@@ -110,3 +110,25 @@ func TestEncodeValTypes(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeValueType(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    wasm.ValueType
+		expected []byte
+	}{
+		{name: "i32", input: wasm.ValueTypeI32, expected: []byte{wasm.ValueTypeI32.Kind()}},
+		{name: "funcref", input: wasm.ValueTypeFuncref, expected: []byte{wasm.ValueTypeFuncref.Kind()}},
+		{name: "(ref func)", input: wasm.ValueTypeFuncref.AsNonNullable(), expected: []byte{wasm.RefPrefixNonNullable, wasm.ValueTypeFuncref.Kind()}},
+		{name: "(ref null 1)", input: wasm.ValueTypeConcreteRef(1, true), expected: []byte{wasm.RefPrefixNullable, 0x01}},
+		{name: "(ref 64)", input: wasm.ValueTypeConcreteRef(64, false), expected: []byte{wasm.RefPrefixNonNullable, 0xc0, 0x00}},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, EncodeValueType(tc.input))
+		})
+	}
+}
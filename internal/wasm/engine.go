@@ -34,6 +34,17 @@ type Engine interface {
 	NewModuleEngine(module *Module, instance *ModuleInstance) (ModuleEngine, error)
 }
 
+// CompiledModuleSerializer is optionally implemented by an Engine which can export the native code of a compiled
+// module, and later import it in lieu of compiling the module again.
+type CompiledModuleSerializer interface {
+	// SerializeCompiledModule returns the native code of a module previously compiled by Engine.CompileModule.
+	SerializeCompiledModule(module *Module) ([]byte, error)
+
+	// DeserializeCompiledModule has the same effect as Engine.CompileModule, except the native code is read from data,
+	// which must have been produced by SerializeCompiledModule of the same version of the engine.
+	DeserializeCompiledModule(module *Module, listeners []experimental.FunctionListener, ensureTermination bool, data []byte) error
+}
+
 // ModuleEngine implements function calls for a given module.
 type ModuleEngine interface {
 	// DoneInstantiation is called at the end of the instantiation of the module.
@@ -71,6 +82,10 @@ type ModuleEngine interface {
 	// the initialization via ElementSegment.
 	FunctionInstanceReference(funcIndex Index) Reference
 
+	// FunctionIndex is the inverse of FunctionInstanceReference: it returns the Index of the function in this module
+	// the given Reference points to, or false if it is not a function of this module.
+	FunctionIndex(ref Reference) (Index, bool)
+
 	// MemoryGrown notifies the engine that the memory has grown.
 	MemoryGrown()
 }
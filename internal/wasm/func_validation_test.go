@@ -3267,7 +3267,7 @@ func TestModule_funcValidation_SIMD_error(t *testing.T) {
 				OpcodeVecPrefix,
 				OpcodeVecV128i8x16Shuffle,
 			},
-			expectedErr: "16 lane indexes for v128.shuffle not found",
+			expectedErr: "16 lane indexes for i8x16.shuffle not found",
 		},
 		{
 			name: "shuffle lane index not found",
@@ -3278,7 +3278,7 @@ func TestModule_funcValidation_SIMD_error(t *testing.T) {
 				0xff, 0, 0, 0, 0, 0, 0, 0,
 				0, 0, 0, 0, 0, 0, 0, 0,
 			},
-			expectedErr: "invalid lane index[0] 255 >= 32 for v128.shuffle",
+			expectedErr: "invalid lane index[0] 255 >= 32 for i8x16.shuffle",
 		},
 	}
 
@@ -994,7 +994,7 @@ const (
 	OpcodeF32ConvertI32SName    = "f32.convert_i32_s"
 	OpcodeF32ConvertI32UName    = "f32.convert_i32_u"
 	OpcodeF32ConvertI64SName    = "f32.convert_i64_s"
-	OpcodeF32ConvertI64UName    = "f32.convert_i64u"
+	OpcodeF32ConvertI64UName    = "f32.convert_i64_u"
 	OpcodeF32DemoteF64Name      = "f32.demote_f64"
 	OpcodeF64ConvertI32SName    = "f64.convert_i32_s"
 	OpcodeF64ConvertI32UName    = "f64.convert_i32_u"
@@ -1312,7 +1312,7 @@ const (
 	OpcodeVecV128Store32LaneName           = "v128.store32_lane"
 	OpcodeVecV128Store64LaneName           = "v128.store64_lane"
 	OpcodeVecV128ConstName                 = "v128.const"
-	OpcodeVecV128i8x16ShuffleName          = "v128.shuffle"
+	OpcodeVecV128i8x16ShuffleName          = "i8x16.shuffle"
 	OpcodeVecI8x16ExtractLaneSName         = "i8x16.extract_lane_s"
 	OpcodeVecI8x16ExtractLaneUName         = "i8x16.extract_lane_u"
 	OpcodeVecI8x16ReplaceLaneName          = "i8x16.replace_lane"
@@ -1366,10 +1366,10 @@ const (
 	OpcodeVecI32x4GeUName                  = "i32x4.ge_u"
 	OpcodeVecI64x2EqName                   = "i64x2.eq"
 	OpcodeVecI64x2NeName                   = "i64x2.ne"
-	OpcodeVecI64x2LtSName                  = "i64x2.lt"
-	OpcodeVecI64x2GtSName                  = "i64x2.gt"
-	OpcodeVecI64x2LeSName                  = "i64x2.le"
-	OpcodeVecI64x2GeSName                  = "i64x2.ge"
+	OpcodeVecI64x2LtSName                  = "i64x2.lt_s"
+	OpcodeVecI64x2GtSName                  = "i64x2.gt_s"
+	OpcodeVecI64x2LeSName                  = "i64x2.le_s"
+	OpcodeVecI64x2GeSName                  = "i64x2.ge_s"
 	OpcodeVecF32x4EqName                   = "f32x4.eq"
 	OpcodeVecF32x4NeName                   = "f32x4.ne"
 	OpcodeVecF32x4LtName                   = "f32x4.lt"
@@ -1403,8 +1403,8 @@ const (
 	OpcodeVecI8x16AddSatSName              = "i8x16.add_sat_s"
 	OpcodeVecI8x16AddSatUName              = "i8x16.add_sat_u"
 	OpcodeVecI8x16SubName                  = "i8x16.sub"
-	OpcodeVecI8x16SubSatSName              = "i8x16.sub_s"
-	OpcodeVecI8x16SubSatUName              = "i8x16.sub_u"
+	OpcodeVecI8x16SubSatSName              = "i8x16.sub_sat_s"
+	OpcodeVecI8x16SubSatUName              = "i8x16.sub_sat_u"
 	OpcodeVecI8x16MinSName                 = "i8x16.min_s"
 	OpcodeVecI8x16MinUName                 = "i8x16.min_u"
 	OpcodeVecI8x16MaxSName                 = "i8x16.max_s"
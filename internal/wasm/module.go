@@ -183,6 +183,13 @@ type Module struct {
 	// IsHostModule true if this is the host module, false otherwise.
 	IsHostModule bool
 
+	// CanonicalizeNaN is true when NaN results of float operations must be
+	// replaced with the canonical NaN of their type, so that they are the
+	// same on all platforms. See NaNResultShape.
+	//
+	// This is set before AssignModuleID, as it changes the compiled code.
+	CanonicalizeNaN bool
+
 	// functionDefinitionSectionInitOnce guards FunctionDefinitionSection so that it is initialized exactly once.
 	functionDefinitionSectionInitOnce sync.Once
 
@@ -225,6 +232,11 @@ func (m *Module) AssignModuleID(wasm []byte, listeners []experimental.FunctionLi
 	// Write the flag of ensureTermination to the checksum.
 	m.ID[0] = boolToByte(withEnsureTermination)
 	h.Write(m.ID[:1])
+	// Write the flag of CanonicalizeNaN, only when set so that the ID of
+	// other modules is unchanged.
+	if m.CanonicalizeNaN {
+		h.Write([]byte("CanonicalizeNaN"))
+	}
 	// Get checksum by passing the slice underlying m.ID.
 	h.Sum(m.ID[:0])
 }
@@ -139,7 +139,34 @@ const (
 
 func (m *ModuleInstance) setExitCode(exitCode uint32, flag exitCodeFlag) bool {
 	closed := flag | uint64(exitCode)<<32 // Store exitCode as high-order bits.
-	return m.Closed.CompareAndSwap(0, closed)
+	if !m.Closed.CompareAndSwap(0, closed) {
+		return false
+	}
+	m.doneMux.Lock()
+	m.isDone = true
+	if m.done != nil {
+		close(m.done)
+	}
+	m.doneMux.Unlock()
+	return true
+}
+
+// Done returns a channel which is closed when the module is closed. This
+// includes when the context of a call is done and the module is configured
+// to close, as described on CloseModuleOnCanceledOrTimeout.
+//
+// Host functions which block, such as reading stdin, use this to return
+// promptly with the exit error from FailIfClosed.
+func (m *ModuleInstance) Done() <-chan struct{} {
+	m.doneMux.Lock()
+	defer m.doneMux.Unlock()
+	if m.done == nil {
+		m.done = make(chan struct{})
+		if m.isDone {
+			close(m.done)
+		}
+	}
+	return m.done
 }
 
 // ensureResourcesClosed ensures that resources assigned to ModuleInstance is released.
@@ -0,0 +1,197 @@
+package wasm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+)
+
+// preInitDataGapLen is the length of a run of zero bytes in memory, under which it is included in the adjacent
+// data segments rather than splitting them, as each segment costs a few bytes of overhead.
+const preInitDataGapLen = 8
+
+// PreInitialize updates source, which must be decoded from the same binary as this instance, so that instantiating
+// it results in the current state of this instance. This allows a module to run its initialization at build time,
+// like Wizer does.
+//
+// The following are rewritten:
+//   - Active data segments are replaced by the content of memory, and the minimum size of memory is its current size.
+//   - Mutable globals are initialized with their current value.
+//   - Active element segments are replaced by the content of tables, and the minimum size of tables is their current
+//     size. Original segments are kept as declarative, so that their functions can still be referenced by ref.func.
+//   - Passive data and element segments which were dropped become empty.
+//   - The start function is removed, as it already ran.
+//
+// Segment indexes are preserved, so that instructions such as memory.init still refer to the same segments.
+//
+// This returns an error if the module imports a memory or table, as their state is held by another module, or if a
+// global or table holds a non-null externref or a reference to a function of another module.
+//
+// Note: The module must not be executing any function.
+func (m *ModuleInstance) PreInitialize(source *Module) error {
+	if source.ImportMemoryCount > 0 || source.ImportTableCount > 0 {
+		return errors.New("modules importing a memory or table cannot be pre-initialized")
+	}
+	s := m.Snapshot()
+
+	if source.MemorySection != nil {
+		source.MemorySection.Min = memoryBytesNumToPages(uint64(s.memoryLen))
+		if source.MemorySection.Cap < source.MemorySection.Min {
+			source.MemorySection.Cap = source.MemorySection.Min
+		}
+	}
+	source.DataSection = preInitDataSection(source, s)
+	if source.DataCountSection != nil {
+		count := uint32(len(source.DataSection))
+		source.DataCountSection = &count
+	}
+
+	for i := range source.GlobalSection {
+		g := &source.GlobalSection[i]
+		if !g.Type.Mutable {
+			continue
+		}
+		init, err := m.preInitValue(g.Type.ValType, s.globals[i][0], s.globals[i][1])
+		if err != nil {
+			return fmt.Errorf("global[%d]: %w", i, err)
+		}
+		g.Init = init
+	}
+
+	elements, err := m.preInitElementSection(source, s)
+	if err != nil {
+		return err
+	}
+	source.ElementSection = elements
+
+	source.StartSection = nil
+	return nil
+}
+
+// preInitDataSection returns data segments which initialize memory with its content in s.
+func preInitDataSection(source *Module, s *InstanceSnapshot) (data []DataSegment) {
+	// Without a data count section, code cannot refer to data segments, so their indexes needn't be preserved.
+	preserveIndexes := source.DataCountSection != nil
+	for i := range source.DataSection {
+		d := source.DataSection[i]
+		if d.Passive {
+			if s.dataInstances[i] == nil {
+				d.Init = nil
+			}
+		} else if preserveIndexes {
+			// Active segments are dropped once applied, which is the same as an empty passive segment.
+			d = DataSegment{Passive: true}
+		} else {
+			continue
+		}
+		data = append(data, d)
+	}
+
+	mem := s.memory
+	for start := 0; start < len(mem); {
+		if mem[start] == 0 {
+			start++
+			continue
+		}
+		end, zeros := start, 0
+		for i := start; i < len(mem) && zeros < preInitDataGapLen; i++ {
+			if mem[i] == 0 {
+				zeros++
+			} else {
+				end, zeros = i+1, 0
+			}
+		}
+		data = append(data, DataSegment{
+			OffsetExpression: NewConstantExpressionFromI32(int32(start)),
+			Init:             mem[start:end],
+		})
+		start = end
+	}
+	return
+}
+
+// preInitElementSection returns element segments which initialize tables with their content in s.
+func (m *ModuleInstance) preInitElementSection(source *Module, s *InstanceSnapshot) ([]ElementSegment, error) {
+	var elements []ElementSegment
+	for i := range source.ElementSection {
+		e := source.ElementSection[i]
+		switch e.Mode {
+		case ElementModeActive:
+			e.Mode = ElementModeDeclarative
+			e.TableIndex = 0
+			e.OffsetExpr = ConstantExpression{}
+		case ElementModePassive:
+			if e.Type.Kind() == RefTypeFuncref.Kind() && s.elementInstances[i] == nil {
+				e.Init = nil
+			}
+		}
+		elements = append(elements, e)
+	}
+
+	for i := range source.TableSection {
+		t := &source.TableSection[i]
+		refs := s.tables[i]
+		t.Min = uint32(len(refs))
+		// Only runs of non-null references need to be initialized, unless the table is initialized to another
+		// value, in which case all of it is.
+		for start := 0; start < len(refs); {
+			if refs[start] == 0 && t.InitExpr == nil {
+				start++
+				continue
+			}
+			end := start
+			for end < len(refs) && (refs[end] != 0 || t.InitExpr != nil) {
+				end++
+			}
+			e := ElementSegment{
+				OffsetExpr: NewConstantExpressionFromI32(int32(start)),
+				TableIndex: Index(i),
+				Type:       t.Type,
+				Mode:       ElementModeActive,
+			}
+			for _, ref := range refs[start:end] {
+				init, err := m.preInitValue(t.Type, uint64(ref), 0)
+				if err != nil {
+					return nil, fmt.Errorf("table[%d]: %w", i, err)
+				}
+				e.Init = append(e.Init, init)
+			}
+			elements = append(elements, e)
+			start = end
+		}
+	}
+	return elements, nil
+}
+
+// preInitValue returns a constant expression of the given value.
+func (m *ModuleInstance) preInitValue(t ValueType, lo, hi uint64) (ConstantExpression, error) {
+	switch t {
+	case ValueTypeI32:
+		return NewConstantExpressionFromI32(int32(lo)), nil
+	case ValueTypeI64:
+		return NewConstantExpressionFromI64(int64(lo)), nil
+	case ValueTypeF32:
+		return NewConstantExpressionFromOpcode(OpcodeF32Const, binary.LittleEndian.AppendUint32(nil, uint32(lo))), nil
+	case ValueTypeF64:
+		return NewConstantExpressionFromOpcode(OpcodeF64Const, binary.LittleEndian.AppendUint64(nil, lo)), nil
+	case ValueTypeV128:
+		v := binary.LittleEndian.AppendUint64(nil, lo)
+		return NewConstantExpressionFromOpcode(OpcodeVecV128Const, binary.LittleEndian.AppendUint64(v, hi)), nil
+	}
+
+	if lo == 0 {
+		if t.IsConcreteRef() {
+			return NewConstantExpressionFromOpcode(OpcodeRefNull, leb128.EncodeInt64(int64(t.TypeIndex()))), nil
+		}
+		return NewConstantExpressionFromOpcode(OpcodeRefNull, []byte{t.Kind()}), nil
+	} else if t.Kind() != RefTypeFuncref.Kind() {
+		return ConstantExpression{}, fmt.Errorf("cannot encode a non-null %s", ValueTypeName(t))
+	}
+	idx, ok := m.Engine.FunctionIndex(Reference(lo))
+	if !ok {
+		return ConstantExpression{}, errors.New("cannot encode a reference to a function of another module")
+	}
+	return NewConstantExpressionFromOpcode(OpcodeRefFunc, leb128.EncodeUint32(idx)), nil
+}
@@ -0,0 +1,66 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestPreInitDataSection(t *testing.T) {
+	active := DataSegment{OffsetExpression: NewConstantExpressionFromI32(0), Init: []byte{1}}
+	passive := DataSegment{Passive: true, Init: []byte{2}}
+	dataCount := uint32(2)
+
+	tests := []struct {
+		name     string
+		source   *Module
+		snapshot *InstanceSnapshot
+		expected []DataSegment
+	}{
+		{
+			name:     "no memory",
+			source:   &Module{},
+			snapshot: &InstanceSnapshot{},
+		},
+		{
+			name:   "memory split by a long run of zeros",
+			source: &Module{DataSection: []DataSegment{active}},
+			snapshot: &InstanceSnapshot{
+				memory:        []byte{0, 1, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+				dataInstances: []DataInstance{active.Init},
+			},
+			expected: []DataSegment{
+				{OffsetExpression: NewConstantExpressionFromI32(1), Init: []byte{1, 0, 0, 1}},
+				{OffsetExpression: NewConstantExpressionFromI32(13), Init: []byte{1}},
+			},
+		},
+		{
+			name:   "preserves indexes",
+			source: &Module{DataSection: []DataSegment{active, passive}, DataCountSection: &dataCount},
+			snapshot: &InstanceSnapshot{
+				memory:        []byte{1},
+				dataInstances: []DataInstance{active.Init, passive.Init},
+			},
+			expected: []DataSegment{
+				{Passive: true},
+				passive,
+				{OffsetExpression: NewConstantExpressionFromI32(0), Init: []byte{1}},
+			},
+		},
+		{
+			name:   "dropped passive",
+			source: &Module{DataSection: []DataSegment{passive}, DataCountSection: &dataCount},
+			snapshot: &InstanceSnapshot{
+				dataInstances: []DataInstance{nil},
+			},
+			expected: []DataSegment{{Passive: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, preInitDataSection(tc.source, tc.snapshot))
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package wasm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/platform"
+)
+
+// InstanceSnapshot is the state of a ModuleInstance which guest code may mutate, captured by
+// ModuleInstance.Snapshot to be reinstated by ModuleInstance.Restore.
+//
+// Only state defined by the module is captured: imported memories, globals and tables belong to
+// another ModuleInstance.
+type InstanceSnapshot struct {
+	// memory is the content of the locally defined memory, without trailing zero bytes, or nil if
+	// the module doesn't define a memory or memoryImage holds it instead.
+	memory []byte
+	// memoryImage holds the content of memory when it was allocated by SnapshotMemoryAllocator.
+	memoryImage *platform.MemoryImage
+	// memoryLen is the length of the locally defined memory.
+	memoryLen int
+	// globals are the lo and hi values of locally defined globals, index-correlated with Module.GlobalSection.
+	globals [][2]uint64
+	// tables are the references of locally defined tables, index-correlated with Module.TableSection.
+	tables [][]Reference
+	// dataInstances and elementInstances are copies of the same fields on ModuleInstance, to undo
+	// data.drop and elem.drop.
+	dataInstances    []DataInstance
+	elementInstances []ElementInstance
+}
+
+// Snapshot captures the state of this module which guest code may mutate.
+//
+// Note: The module must not be executing any function.
+func (m *ModuleInstance) Snapshot() *InstanceSnapshot {
+	s := &InstanceSnapshot{}
+	module := m.Source
+	if module.MemorySection != nil {
+		buf := m.MemoryInstance.Buffer
+		s.memoryLen = len(buf)
+		content := buf[:nonZeroPrefixLen(buf)]
+		if mem, ok := m.MemoryInstance.expBuffer.(*mappedMemory); ok && mem.mapped {
+			// Fall back to a copy if the image can't be created.
+			if image, err := platform.NewMemoryImage(content); err == nil {
+				s.memoryImage = image
+			}
+		}
+		if s.memoryImage == nil {
+			s.memory = append([]byte(nil), content...)
+		}
+	}
+
+	s.globals = make([][2]uint64, len(module.GlobalSection))
+	for i := range s.globals {
+		lo, hi := m.Globals[module.ImportGlobalCount+Index(i)].Value()
+		s.globals[i] = [2]uint64{lo, hi}
+	}
+
+	s.tables = make([][]Reference, len(module.TableSection))
+	for i := range s.tables {
+		t := m.Tables[module.ImportTableCount+Index(i)]
+		s.tables[i] = append([]Reference(nil), t.References...)
+	}
+
+	s.dataInstances = append([]DataInstance(nil), m.DataInstances...)
+	s.elementInstances = make([]ElementInstance, len(m.ElementInstances))
+	for i, e := range m.ElementInstances {
+		if e != nil {
+			s.elementInstances[i] = append(ElementInstance{}, e...)
+		}
+	}
+	return s
+}
+
+// Restore reinstates the state captured by Snapshot on this module.
+//
+// Memory is restored by copying the non-zero prefix of the snapshot and zeroing the remainder,
+// resizing it if its length differs. This costs time proportional to the length of memory, unless it
+// was allocated by SnapshotMemoryAllocator: then the snapshot is mapped copy-on-write over it, which
+// costs time proportional to the count of pages written since. Tables are resized like memory.
+//
+// Note: The module must not be executing any function, and the snapshot must have been taken from
+// an instance of the same Module.
+func (m *ModuleInstance) Restore(s *InstanceSnapshot) error {
+	module := m.Source
+	if module.MemorySection != nil {
+		mem := m.MemoryInstance
+		if cur := len(mem.Buffer); s.memoryLen > cur {
+			if _, ok := mem.Grow(memoryBytesNumToPages(uint64(s.memoryLen - cur))); !ok {
+				return fmt.Errorf("failed to grow memory to %d bytes", s.memoryLen)
+			}
+		}
+		// Zero any grown pages too, as Grow reuses capacity without clearing it.
+		if s.memoryImage != nil {
+			if err := platform.MapMemoryImage(mem.Buffer, s.memoryImage); err != nil {
+				return fmt.Errorf("failed to map memory: %w", err)
+			}
+		} else {
+			n := copy(mem.Buffer, s.memory)
+			clear(mem.Buffer[n:])
+		}
+		if mem.Shared {
+			mem.Mux.Lock()
+			atomicStoreLength(&mem.Buffer, uintptr(s.memoryLen))
+			mem.Mux.Unlock()
+		} else {
+			mem.Buffer = mem.Buffer[:s.memoryLen]
+		}
+		m.Engine.MemoryGrown()
+	}
+
+	for i, v := range s.globals {
+		m.Globals[module.ImportGlobalCount+Index(i)].SetValue(v[0], v[1])
+	}
+
+	for i, refs := range s.tables {
+		t := m.Tables[module.ImportTableCount+Index(i)]
+		t.References = append(t.References[:0], refs...)
+	}
+
+	// Copy in place, as engines may hold the address of the first element.
+	copy(m.DataInstances, s.dataInstances)
+	for i, e := range s.elementInstances {
+		if e == nil {
+			m.ElementInstances[i] = nil
+		} else {
+			m.ElementInstances[i] = append(m.ElementInstances[i][:0], e...)
+		}
+	}
+	return nil
+}
+
+// Close releases the resources held by the snapshot, after which it must not be restored.
+func (s *InstanceSnapshot) Close() error {
+	if s.memoryImage != nil {
+		return s.memoryImage.Close()
+	}
+	return nil
+}
+
+// memoryContent returns the content of memory captured by the snapshot, without trailing zero bytes.
+func (s *InstanceSnapshot) memoryContent() ([]byte, error) {
+	if s.memoryImage == nil {
+		return s.memory, nil
+	}
+	content := make([]byte, s.memoryImage.Len())
+	_, err := io.ReadFull(io.NewSectionReader(s.memoryImage, 0, int64(len(content))), content)
+	return content, err
+}
+
+// SnapshotMemoryAllocator allocates memories which Restore maps a snapshot over copy-on-write,
+// instead of copying it, when platform.MemoryImageSupported. Memories are reserved at their
+// maximum size, so their address never changes.
+var SnapshotMemoryAllocator experimental.MemoryAllocator = experimental.MemoryAllocatorFunc(allocateMappedMemory)
+
+// mappedMemory is an experimental.LinearMemory allocated by SnapshotMemoryAllocator.
+type mappedMemory struct {
+	buf []byte
+	// mapped is true when buf is from platform.MmapLinearMemory, as opposed to the Go heap.
+	mapped bool
+}
+
+func allocateMappedMemory(_, max uint64) experimental.LinearMemory {
+	if buf, err := platform.MmapLinearMemory(int(max)); err == nil {
+		return &mappedMemory{buf: buf, mapped: true}
+	}
+	return &mappedMemory{buf: make([]byte, max)}
+}
+
+// Reallocate implements experimental.LinearMemory.
+func (m *mappedMemory) Reallocate(size uint64) []byte {
+	if size > uint64(len(m.buf)) {
+		return nil
+	}
+	return m.buf[:size]
+}
+
+// Free implements experimental.LinearMemory.
+func (m *mappedMemory) Free() {
+	if m.mapped {
+		_ = platform.MunmapLinearMemory(m.buf)
+	}
+	m.buf = nil
+}
+
+// nonZeroPrefixLen returns the length of b without trailing zero bytes.
+func nonZeroPrefixLen(b []byte) int {
+	// Skip trailing zero words first, as memory is typically sparse.
+	n := len(b)
+	for n >= 8 && binary.LittleEndian.Uint64(b[n-8:]) == 0 {
+		n -= 8
+	}
+	for n > 0 && b[n-1] == 0 {
+		n--
+	}
+	return n
+}
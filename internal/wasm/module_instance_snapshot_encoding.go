@@ -0,0 +1,216 @@
+package wasm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WriteSnapshot encodes s, which must have been taken from this module, so that ReadSnapshot can decode it for
+// another instance of the same Module, possibly in another process.
+//
+// Function references are encoded as function indexes, so this fails if s holds a reference to a function of
+// another module or a non-null externref.
+//
+// The layout is a sequence of unsigned LEB128 integers, except for the content of memory:
+//   - the length of memory, the length of its content, then its content, if the module defines one
+//   - the lo and hi value of each locally defined global
+//   - the length of each locally defined table, then its references
+//   - whether each data segment was dropped
+//   - the length of each element segment, then its references
+//
+// A reference is zero if null, or otherwise the function index plus one.
+func (m *ModuleInstance) WriteSnapshot(w io.Writer, s *InstanceSnapshot) error {
+	e := &snapshotEncoder{w: w, m: m}
+	if m.Source.MemorySection != nil {
+		content, err := s.memoryContent()
+		if err != nil {
+			return err
+		}
+		e.uvarint(uint64(s.memoryLen))
+		e.uvarint(uint64(len(content)))
+		e.write(content)
+	}
+
+	for i, g := range s.globals {
+		lo := g[0]
+		if t := m.Source.GlobalSection[i].Type.ValType; t == ValueTypeFuncref || t == ValueTypeExternref {
+			lo = e.reference(t, Reference(lo))
+		}
+		e.uvarint(lo)
+		e.uvarint(g[1])
+	}
+
+	for i, refs := range s.tables {
+		e.references(m.Source.TableSection[i].Type, refs)
+	}
+
+	for _, d := range s.dataInstances {
+		if d == nil {
+			e.uvarint(1)
+		} else {
+			e.uvarint(0)
+		}
+	}
+
+	for i, refs := range s.elementInstances {
+		e.references(m.Source.ElementSection[i].Type, refs)
+	}
+	return e.err
+}
+
+// ReadSnapshot decodes a snapshot written by WriteSnapshot, for use with Restore.
+func (m *ModuleInstance) ReadSnapshot(r *bufio.Reader) (*InstanceSnapshot, error) {
+	d := &snapshotDecoder{r: r, m: m}
+	s := &InstanceSnapshot{}
+	module := m.Source
+	if module.MemorySection != nil {
+		s.memoryLen = int(d.uvarint())
+		max := MemoryPagesToBytesNum(m.MemoryInstance.Max)
+		if d.err == nil && (uint64(s.memoryLen) > max || s.memoryLen%int(MemoryPageSize) != 0) {
+			return nil, fmt.Errorf("invalid memory length %d", s.memoryLen)
+		}
+		n := d.uvarint()
+		if d.err == nil && n > uint64(s.memoryLen) {
+			return nil, fmt.Errorf("invalid memory content length %d", n)
+		}
+		s.memory = d.read(n)
+	}
+
+	s.globals = make([][2]uint64, len(module.GlobalSection))
+	for i := range s.globals {
+		lo := d.uvarint()
+		if t := module.GlobalSection[i].Type.ValType; t == ValueTypeFuncref || t == ValueTypeExternref {
+			lo = uint64(d.reference(t, lo))
+		}
+		s.globals[i] = [2]uint64{lo, d.uvarint()}
+	}
+
+	s.tables = make([][]Reference, len(module.TableSection))
+	for i := range s.tables {
+		t := &module.TableSection[i]
+		s.tables[i] = d.references(t.Type)
+		if d.err == nil && t.Max != nil && uint64(len(s.tables[i])) > uint64(*t.Max) {
+			return nil, fmt.Errorf("table[%d] length %d exceeds max %d", i, len(s.tables[i]), *t.Max)
+		}
+	}
+
+	s.dataInstances = make([]DataInstance, len(m.DataInstances))
+	for i := range s.dataInstances {
+		if d.uvarint() == 0 {
+			s.dataInstances[i] = module.DataSection[i].Init
+		}
+	}
+
+	s.elementInstances = make([]ElementInstance, len(m.ElementInstances))
+	for i := range s.elementInstances {
+		s.elementInstances[i] = d.references(module.ElementSection[i].Type)
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	return s, nil
+}
+
+// snapshotEncoder writes fields to w, recording the first error.
+type snapshotEncoder struct {
+	w   io.Writer
+	m   *ModuleInstance
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *snapshotEncoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *snapshotEncoder) uvarint(v uint64) {
+	e.write(binary.AppendUvarint(e.buf[:0], v))
+}
+
+func (e *snapshotEncoder) references(t RefType, refs []Reference) {
+	e.uvarint(uint64(len(refs)))
+	for _, ref := range refs {
+		e.uvarint(e.reference(t, ref))
+	}
+}
+
+func (e *snapshotEncoder) reference(t RefType, ref Reference) uint64 {
+	if ref == 0 {
+		return 0
+	}
+	if t == RefTypeFuncref {
+		if idx, ok := e.m.Engine.FunctionIndex(ref); ok {
+			return uint64(idx) + 1
+		}
+		if e.err == nil {
+			e.err = errors.New("cannot encode a reference to a function of another module")
+		}
+	} else if e.err == nil {
+		e.err = errors.New("cannot encode a non-null externref")
+	}
+	return 0
+}
+
+// snapshotDecoder reads fields from r, recording the first error.
+type snapshotDecoder struct {
+	r   *bufio.Reader
+	m   *ModuleInstance
+	err error
+}
+
+func (d *snapshotDecoder) read(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.err = fmt.Errorf("failed to read %d bytes: %w", n, err)
+		return nil
+	}
+	return b
+}
+
+func (d *snapshotDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.err = fmt.Errorf("failed to read integer: %w", err)
+	}
+	return v
+}
+
+func (d *snapshotDecoder) references(t RefType) []Reference {
+	n := d.uvarint()
+	if d.err != nil {
+		return nil
+	} else if n > math.MaxUint32 {
+		d.err = fmt.Errorf("invalid reference count %d", n)
+		return nil
+	}
+	// Avoid trusting n for allocation until references were read.
+	var refs []Reference
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		refs = append(refs, d.reference(t, d.uvarint()))
+	}
+	return refs
+}
+
+func (d *snapshotDecoder) reference(t RefType, v uint64) Reference {
+	if v == 0 || d.err != nil {
+		return 0
+	}
+	if functionCount := uint64(d.m.Source.ImportFunctionCount) + uint64(len(d.m.Source.FunctionSection)); t != RefTypeFuncref || v > functionCount {
+		d.err = fmt.Errorf("invalid reference %d", v)
+		return 0
+	}
+	return d.m.Engine.FunctionInstanceReference(Index(v - 1))
+}
@@ -0,0 +1,113 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestModuleInstance_SnapshotRestore(t *testing.T) {
+	me := &mockModuleEngine{}
+	imported := &GlobalInstance{Val: 1}
+	m := &ModuleInstance{
+		Source: &Module{
+			ImportGlobalCount: 1,
+			MemorySection:     &Memory{Min: 1, Cap: 2, Max: 2},
+			GlobalSection:     []Global{{Type: GlobalType{ValType: ValueTypeI64, Mutable: true}}},
+			TableSection:      []Table{{Min: 2}},
+		},
+		MemoryInstance:   &MemoryInstance{Buffer: make([]byte, MemoryPageSize, MemoryPageSize*2), Cap: 2, Max: 2},
+		Globals:          []*GlobalInstance{imported, {Val: 2}},
+		Tables:           []*TableInstance{{References: []Reference{1, 2}}},
+		DataInstances:    []DataInstance{[]byte("data")},
+		ElementInstances: []ElementInstance{{3}},
+		Engine:           me,
+	}
+	copy(m.MemoryInstance.Buffer, "hello")
+
+	s := m.Snapshot()
+	require.Equal(t, []byte("hello"), s.memory)
+	require.Equal(t, int(MemoryPageSize), s.memoryLen)
+
+	// Mutate everything captured, and an imported global which isn't.
+	m.MemoryInstance.Buffer = m.MemoryInstance.Buffer[:MemoryPageSize*2]
+	copy(m.MemoryInstance.Buffer, "jello")
+	m.MemoryInstance.Buffer[MemoryPageSize] = 1
+	imported.Val = 10
+	m.Globals[1].Val = 20
+	m.Tables[0].References = append(m.Tables[0].References, 4)
+	m.Tables[0].References[0] = 0
+	m.DataInstances[0] = nil
+	m.ElementInstances[0] = nil
+
+	require.NoError(t, m.Restore(s))
+	mem := m.MemoryInstance
+	require.Equal(t, int(MemoryPageSize), len(mem.Buffer))
+	require.Equal(t, []byte("hello"), mem.Buffer[:5])
+	require.Equal(t, byte(0), mem.Buffer[:MemoryPageSize*2][MemoryPageSize], "grown page must be zeroed")
+	require.Equal(t, 1, me.memoryGrown)
+	require.Equal(t, uint64(10), imported.Val)
+	require.Equal(t, uint64(2), m.Globals[1].Val)
+	require.Equal(t, []Reference{1, 2}, m.Tables[0].References)
+	require.Equal(t, []DataInstance{[]byte("data")}, m.DataInstances)
+	require.Equal(t, []ElementInstance{{3}}, m.ElementInstances)
+}
+
+func TestModuleInstance_SnapshotRestore_MappedMemory(t *testing.T) {
+	if !platform.MemoryImageSupported {
+		t.Skip()
+	}
+	me := &mockModuleEngine{}
+	memSec := &Memory{Min: 1, Cap: 1, Max: 3}
+	m := &ModuleInstance{
+		Source:         &Module{MemorySection: memSec},
+		MemoryInstance: NewMemoryInstance(memSec, SnapshotMemoryAllocator, me),
+		Engine:         me,
+	}
+	defer m.MemoryInstance.expBuffer.Free()
+	copy(m.MemoryInstance.Buffer, "hello")
+
+	s := m.Snapshot()
+	defer s.Close()
+	require.NotNil(t, s.memoryImage)
+	require.Nil(t, s.memory)
+	content, err := s.memoryContent()
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), content)
+
+	mem := m.MemoryInstance
+	_, ok := mem.Grow(2)
+	require.True(t, ok)
+	copy(mem.Buffer, "jello")
+	mem.Buffer[MemoryPageSize*2] = 1
+
+	require.NoError(t, m.Restore(s))
+	require.Equal(t, int(MemoryPageSize), len(mem.Buffer))
+	require.Equal(t, []byte("hello"), mem.Buffer[:5])
+
+	// Growing again must see zeros, not what was written before restoring.
+	_, ok = mem.Grow(2)
+	require.True(t, ok)
+	require.Equal(t, byte(0), mem.Buffer[MemoryPageSize*2])
+}
+
+func TestNonZeroPrefixLen(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []byte
+		expected int
+	}{
+		{name: "nil", expected: 0},
+		{name: "zeros", input: make([]byte, 20), expected: 0},
+		{name: "no trailing zeros", input: []byte{0, 1}, expected: 2},
+		{name: "trailing zero words", input: append([]byte{0, 0, 1}, make([]byte, 17)...), expected: 3},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, nonZeroPrefixLen(tc.input))
+		})
+	}
+}
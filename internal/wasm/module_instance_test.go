@@ -423,6 +423,49 @@ func TestModuleInstance_CloseWithCtxErr(t *testing.T) {
 	})
 }
 
+func TestModuleInstance_Done(t *testing.T) {
+	s := newStore()
+
+	t.Run("closed after", func(t *testing.T) {
+		m := &ModuleInstance{ModuleName: "test", s: s}
+		done := m.Done()
+		requireNotDone(t, done)
+
+		require.NoError(t, m.CloseWithExitCode(testCtx, 2))
+		<-done
+
+		// Subsequent calls return the same channel.
+		require.Equal(t, done, m.Done())
+	})
+
+	t.Run("closed before", func(t *testing.T) {
+		m := &ModuleInstance{ModuleName: "test", s: s}
+		require.NoError(t, m.CloseWithExitCode(testCtx, 2))
+		<-m.Done()
+	})
+
+	t.Run("context canceled", func(t *testing.T) {
+		m := &ModuleInstance{ModuleName: "test", s: s}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer m.CloseModuleOnCanceledOrTimeout(ctx)()
+
+		done := m.Done()
+		requireNotDone(t, done)
+
+		cancel()
+		<-done
+		require.EqualError(t, m.FailIfClosed(), "module closed with context canceled")
+	})
+}
+
+func requireNotDone(t *testing.T, done <-chan struct{}) {
+	select {
+	case <-done:
+		t.Fatal("expected not done")
+	default:
+	}
+}
+
 type mockCloser struct{ called int }
 
 func (m *mockCloser) Close(context.Context) error {
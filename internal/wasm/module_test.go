@@ -1043,8 +1043,8 @@ func TestModule_declaredFunctionIndexes(t *testing.T) {
 }
 
 func TestModule_AssignModuleID(t *testing.T) {
-	getID := func(bin []byte, lsns []experimental.FunctionListener, withEnsureTermination bool) ModuleID {
-		m := Module{}
+	getID := func(bin []byte, lsns []experimental.FunctionListener, withEnsureTermination, canonicalizeNaN bool) ModuleID {
+		m := Module{CanonicalizeNaN: canonicalizeNaN}
 		m.AssignModuleID(bin, lsns, withEnsureTermination)
 		return m.ID
 	}
@@ -1056,6 +1056,7 @@ func TestModule_AssignModuleID(t *testing.T) {
 	for i, tc := range []struct {
 		bin                   []byte
 		withEnsureTermination bool
+		canonicalizeNaN       bool
 		listeners             []experimental.FunctionListener
 	}{
 		{bin: []byte{1, 2, 3}, withEnsureTermination: false},
@@ -1112,8 +1113,10 @@ func TestModule_AssignModuleID(t *testing.T) {
 			listeners:             []experimental.FunctionListener{ml, ml},
 			withEnsureTermination: false,
 		},
+		{bin: []byte{1, 2, 3, 4}, canonicalizeNaN: true},
+		{bin: []byte{1, 2, 3, 4}, withEnsureTermination: true, canonicalizeNaN: true},
 	} {
-		id := getID(tc.bin, tc.listeners, tc.withEnsureTermination)
+		id := getID(tc.bin, tc.listeners, tc.withEnsureTermination, tc.canonicalizeNaN)
 		_, exist := exists[id]
 		require.False(t, exist, i)
 		exists[id] = struct{}{}
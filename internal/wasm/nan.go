@@ -0,0 +1,52 @@
+package wasm
+
+// Canonical NaN bits, which replace the NaN results of float operations when
+// Module.CanonicalizeNaN is set. These are positive quiet NaNs with no payload.
+const (
+	CanonicalNaNF32 uint32 = 0x7fc0_0000
+	CanonicalNaNF64 uint64 = 0x7ff8_0000_0000_0000
+)
+
+// NaNShape is the shape of the result of an instruction which may be NaN.
+type NaNShape byte
+
+const (
+	// NaNShapeNone is returned by NaNResultShape for instructions whose
+	// results are never NaN, or whose NaN results are the same on all
+	// platforms, such as f32.neg.
+	NaNShapeNone NaNShape = iota
+	NaNShapeF32
+	NaNShapeF64
+	NaNShapeF32x4
+	NaNShapeF64x2
+)
+
+// NaNResultShape returns the shape of the result of the instruction, if it
+// is a float operation whose NaN results can differ by platform, such as in
+// their sign or payload. vecOp is only read when op is OpcodeVecPrefix.
+//
+// See https://www.w3.org/TR/2022/WD-wasm-core-2-20220419/exec/numerics.html#nan-propagation
+func NaNResultShape(op Opcode, vecOp OpcodeVec) NaNShape {
+	switch op {
+	case OpcodeF32Ceil, OpcodeF32Floor, OpcodeF32Trunc, OpcodeF32Nearest, OpcodeF32Sqrt,
+		OpcodeF32Add, OpcodeF32Sub, OpcodeF32Mul, OpcodeF32Div, OpcodeF32Min, OpcodeF32Max,
+		OpcodeF32DemoteF64:
+		return NaNShapeF32
+	case OpcodeF64Ceil, OpcodeF64Floor, OpcodeF64Trunc, OpcodeF64Nearest, OpcodeF64Sqrt,
+		OpcodeF64Add, OpcodeF64Sub, OpcodeF64Mul, OpcodeF64Div, OpcodeF64Min, OpcodeF64Max,
+		OpcodeF64PromoteF32:
+		return NaNShapeF64
+	case OpcodeVecPrefix:
+		switch vecOp {
+		case OpcodeVecF32x4Ceil, OpcodeVecF32x4Floor, OpcodeVecF32x4Trunc, OpcodeVecF32x4Nearest, OpcodeVecF32x4Sqrt,
+			OpcodeVecF32x4Add, OpcodeVecF32x4Sub, OpcodeVecF32x4Mul, OpcodeVecF32x4Div, OpcodeVecF32x4Min, OpcodeVecF32x4Max,
+			OpcodeVecF32x4DemoteF64x2Zero:
+			return NaNShapeF32x4
+		case OpcodeVecF64x2Ceil, OpcodeVecF64x2Floor, OpcodeVecF64x2Trunc, OpcodeVecF64x2Nearest, OpcodeVecF64x2Sqrt,
+			OpcodeVecF64x2Add, OpcodeVecF64x2Sub, OpcodeVecF64x2Mul, OpcodeVecF64x2Div, OpcodeVecF64x2Min, OpcodeVecF64x2Max,
+			OpcodeVecF64x2PromoteLowF32x4Zero:
+			return NaNShapeF64x2
+		}
+	}
+	return NaNShapeNone
+}
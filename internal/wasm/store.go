@@ -12,6 +12,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/expctxkeys"
 	"github.com/tetratelabs/wazero/internal/internalapi"
 	internalsys "github.com/tetratelabs/wazero/internal/sys"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -114,6 +115,14 @@ type (
 		// See /RATIONALE.md
 		Closed atomic.Uint64
 
+		// doneMux guards done and isDone. See ModuleInstance.Done.
+		doneMux sync.Mutex
+		// done is lazily created, and closed when the module is closed.
+		done chan struct{}
+		// isDone is true once the module is closed, so that done is created
+		// closed.
+		isDone bool
+
 		// CodeCloser is non-nil when the code should be closed after this module.
 		CodeCloser api.Closer
 
@@ -194,12 +203,12 @@ func (m *ModuleInstance) buildElementInstances(elements []ElementSegment) {
 	}
 }
 
-func (m *ModuleInstance) applyElements(elems []ElementSegment) {
+// applyElements initializes tables with active element segments, in order. An error is returned on the first segment
+// which is out of bounds, leaving the tables initialized by previous segments.
+func (m *ModuleInstance) applyElements(elems []ElementSegment) error {
 	for elemI := range elems {
 		elem := &elems[elemI]
-		if !elem.IsActive() ||
-			// Per https://github.com/WebAssembly/spec/issues/1427 init can be no-op.
-			len(elem.Init) == 0 {
+		if !elem.IsActive() {
 			continue
 		}
 		offsetExprResults := evaluateConstExprInModuleInstance(&elem.OffsetExpr, m)
@@ -208,14 +217,12 @@ func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 		table := m.Tables[elem.TableIndex]
 		references := table.References
 		if int(offset)+len(elem.Init) > len(references) {
-			// ErrElementOffsetOutOfBounds is the error raised when the active element offset exceeds the table length.
-			// Before CoreFeatureReferenceTypes, this was checked statically before instantiation, after the proposal,
-			// this must be raised as runtime error (as in assert_trap in spectest), not even an instantiation error.
+			// Before CoreFeatureReferenceTypes, this was checked statically before instantiation. After the proposal,
+			// this traps, but the tables keep the references of previous segments.
 			// https://github.com/WebAssembly/spec/blob/d39195773112a22b245ffbe864bab6d1182ccb06/test/core/linking.wast#L264-L274
 			//
-			// In wazero, we ignore it since in any way, the instantiated module and engines are fine and can be used
-			// for function invocations.
-			return
+			// The functions of this module stay usable via those references, as the tables retain this module.
+			return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDElement), elemI, wasmruntime.ErrRuntimeInvalidTableAccess)
 		}
 
 		if table.Type == RefTypeExternref {
@@ -229,6 +236,7 @@ func (m *ModuleInstance) applyElements(elems []ElementSegment) {
 			}
 		}
 	}
+	return nil
 }
 
 // validateData ensures that data segments are valid in terms of memory boundary.
@@ -259,7 +267,7 @@ func (m *ModuleInstance) validateData(data []DataSegment) (err error) {
 			offset := int(results[0])
 			ceil := offset + len(d.Init)
 			if offset < 0 || ceil > len(m.MemoryInstance.Buffer) {
-				return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
+				return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDData), i, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 		}
 	}
@@ -278,7 +286,7 @@ func (m *ModuleInstance) applyData(data []DataSegment) error {
 			offsetExprResults := evaluateConstExprInModuleInstance(&d.OffsetExpression, m)
 			offset := int(offsetExprResults[0])
 			if offset < 0 || offset+len(d.Init) > len(m.MemoryInstance.Buffer) {
-				return fmt.Errorf("%s[%d]: out of bounds memory access", SectionIDName(SectionIDData), i)
+				return fmt.Errorf("%s[%d]: %w", SectionIDName(SectionIDData), i, wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess)
 			}
 			copy(m.MemoryInstance.Buffer[offset:], d.Init)
 		}
@@ -391,13 +399,15 @@ func (s *Store) instantiate(
 	// After engine creation, we can create the funcref element instances and initialize funcref type globals.
 	m.buildElementInstances(module.ElementSection)
 
-	// Now all the validation passes, we are safe to mutate memory instances (possibly imported ones).
+	// Now all the validation passes, we are safe to mutate tables and memory instances (possibly imported ones).
+	// Like the specification, element segments are applied before data segments.
+	if err = m.applyElements(module.ElementSection); err != nil {
+		return nil, err
+	}
 	if err = m.applyData(module.DataSection); err != nil {
 		return nil, err
 	}
 
-	m.applyElements(module.ElementSection)
-
 	m.Engine.DoneInstantiation()
 
 	// Execute the start function.
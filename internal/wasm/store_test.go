@@ -17,6 +17,7 @@ import (
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/u32"
 	"github.com/tetratelabs/wazero/internal/u64"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
 )
 
 func TestModuleInstance_Memory(t *testing.T) {
@@ -485,6 +486,16 @@ func (e *mockModuleEngine) FunctionInstanceReference(i Index) Reference {
 	return e.functionRefs[i]
 }
 
+// FunctionIndex implements the same method as documented on wasm.ModuleEngine.
+func (e *mockModuleEngine) FunctionIndex(ref Reference) (Index, bool) {
+	for i, r := range e.functionRefs {
+		if r == ref {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
 // ResolveImportedFunction implements the same method as documented on wasm.ModuleEngine.
 func (e *mockModuleEngine) ResolveImportedFunction(index, _, importedIndex Index, _ ModuleEngine) {
 	e.resolveImportsCalled[index] = importedIndex
@@ -971,18 +982,24 @@ func TestModuleInstance_applyElements(t *testing.T) {
 			m.Tables[0].References[i] = 0xffff // non-null ref.
 		}
 
-		// This shouldn't panic.
-		m.applyElements([]ElementSegment{{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(100)}})
-		m.applyElements([]ElementSegment{
+		// An empty segment is a no-op, but still bounds checked.
+		require.NoError(t, m.applyElements([]ElementSegment{{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(10)}}))
+		err := m.applyElements([]ElementSegment{{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(11)}})
+		require.EqualError(t, err, "element[0]: invalid table access")
+		err = m.applyElements([]ElementSegment{
 			{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(0), Init: make([]ConstantExpression, 3)},
 			{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(100), Init: make([]ConstantExpression, 5)}, // Iteration stops at this point, so the offset:5 below shouldn't be applied.
 			{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(5), Init: make([]ConstantExpression, 5)},
 		})
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+		require.EqualError(t, err, "element[1]: invalid table access")
 		require.Equal(t, []Reference{0, 0, 0, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff},
 			m.Tables[0].References)
-		m.applyElements([]ElementSegment{
+		require.NoError(t, m.applyElements([]ElementSegment{
 			{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(5), Init: make([]ConstantExpression, 5)},
-		})
+		}))
+		require.ErrorIs(t, err, wasmruntime.ErrRuntimeInvalidTableAccess)
+		require.EqualError(t, err, "element[1]: invalid table access")
 		require.Equal(t, []Reference{0, 0, 0, 0xffff, 0xffff, 0, 0, 0, 0, 0}, m.Tables[0].References)
 	})
 	t.Run("funcref", func(t *testing.T) {
@@ -997,13 +1014,13 @@ func TestModuleInstance_applyElements(t *testing.T) {
 			m.Tables[0].References[i] = 0xffff // non-null ref.
 		}
 
-		// This shouldn't panic.
-		m.applyElements([]ElementSegment{{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(100), Init: []ConstantExpression{
+		err = m.applyElements([]ElementSegment{{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(100), Init: []ConstantExpression{
 			NewConstantExpressionFromOpcode(OpcodeRefFunc, leb128.EncodeInt32(1)),
 			NewConstantExpressionFromOpcode(OpcodeRefFunc, leb128.EncodeInt32(2)),
 			NewConstantExpressionFromOpcode(OpcodeRefFunc, leb128.EncodeInt32(3)),
 		}}})
-		m.applyElements([]ElementSegment{
+		require.EqualError(t, err, "element[0]: invalid table access")
+		err = m.applyElements([]ElementSegment{
 			{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(0), Init: []ConstantExpression{
 				NewConstantExpressionFromOpcode(OpcodeRefFunc, leb128.EncodeInt32(0)),
 				NewConstantExpressionFromOpcode(OpcodeRefFunc, leb128.EncodeInt32(1)),
@@ -1015,15 +1032,16 @@ func TestModuleInstance_applyElements(t *testing.T) {
 			{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(100), Init: make([]ConstantExpression, 5)}, // Iteration stops at this point, so the offset:5 below shouldn't be applied.
 			{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(5), Init: make([]ConstantExpression, 5)},
 		})
+		require.EqualError(t, err, "element[2]: invalid table access")
 		require.Equal(t, []Reference{0xa, 0xaa, 0xaaa, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff, 0xffff, 0xabcde},
 			m.Tables[0].References)
-		m.applyElements([]ElementSegment{
+		require.NoError(t, m.applyElements([]ElementSegment{
 			{Mode: ElementModeActive, OffsetExpr: NewConstantExpressionFromI32(5), Init: []ConstantExpression{
 				NewConstantExpressionFromOpcode(OpcodeRefFunc, leb128.EncodeInt32(0)),
 				NewConstantExpressionFromOpcode(OpcodeRefNull, []byte{RefTypeFuncref.Kind()}),
 				NewConstantExpressionFromOpcode(OpcodeRefFunc, leb128.EncodeInt32(2)),
 			}},
-		})
+		}))
 		require.Equal(t, []Reference{0xa, 0xaa, 0xaaa, 0xffff, 0xffff, 0xa, 0x0, 0xaaa, 0xffff, 0xabcde},
 			m.Tables[0].References)
 	})
@@ -0,0 +1,239 @@
+package wast
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/moremath"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wat"
+)
+
+// valueTypeV128 is the api.ValueType of a v128, which isn't defined as the API doesn't support vectors.
+const valueTypeV128 = api.ValueType(wasm.ValueTypeV128)
+
+// args returns the parameters of a function call for the argument of an action. In wazero, an externref is an opaque
+// pointer where zero is null, so (ref.extern 0) is passed as one.
+func args(v wat.Value) []uint64 {
+	switch {
+	case v.Type == wasm.ValueTypeV128:
+		return []uint64{v.Lo, v.Hi}
+	case v.Null:
+		return []uint64{0}
+	case v.Type == wasm.ValueTypeExternref:
+		return []uint64{v.Lo + 1}
+	}
+	return []uint64{v.Lo}
+}
+
+// checkResults returns an error unless the results of an action match the expected values.
+func checkResults(expected []wat.Value, types []api.ValueType, results []uint64) error {
+	if len(expected) != len(types) {
+		return fmt.Errorf("expected %d results, but was %d", len(expected), len(types))
+	}
+	matched := true
+	actualStrs := make([]string, 0, len(types))
+	expectedStrs := make([]string, 0, len(types))
+	for i, t := range types {
+		lo, hi := results[0], uint64(0)
+		if results = results[1:]; t == valueTypeV128 {
+			hi, results = results[0], results[1:]
+		}
+		matched = matched && match(expected[i], lo, hi)
+		actualStrs = append(actualStrs, formatResult(t, lo, hi, expected[i].Shape))
+		expectedStrs = append(expectedStrs, formatValue(expected[i]))
+	}
+	if !matched {
+		return fmt.Errorf("expected %s, but was %s", strings.Join(expectedStrs, " "), strings.Join(actualStrs, " "))
+	}
+	return nil
+}
+
+// match returns true if the bits of a result match the expected value.
+func match(v wat.Value, lo, hi uint64) bool {
+	switch {
+	case v.Either != nil:
+		for _, e := range v.Either {
+			if match(e, lo, hi) {
+				return true
+			}
+		}
+		return false
+	case v.Null:
+		return lo == 0
+	case v.AnyRef:
+		return lo != 0
+	}
+
+	switch v.Type {
+	case wasm.ValueTypeI32:
+		return uint32(lo) == uint32(v.Lo)
+	case wasm.ValueTypeF32:
+		return matchF32(v, 0, uint32(lo))
+	case wasm.ValueTypeF64:
+		return matchF64(v, 0, lo)
+	case wasm.ValueTypeV128:
+		switch v.Shape {
+		case "f32x4":
+			return matchF32(wat.Value{Lo: v.Lo, NaN: v.NaN}, 0, uint32(lo)) &&
+				matchF32(wat.Value{Lo: v.Lo >> 32, NaN: v.NaN}, 1, uint32(lo>>32)) &&
+				matchF32(wat.Value{Lo: v.Hi, NaN: v.NaN}, 2, uint32(hi)) &&
+				matchF32(wat.Value{Lo: v.Hi >> 32, NaN: v.NaN}, 3, uint32(hi>>32))
+		case "f64x2":
+			return matchF64(wat.Value{Lo: v.Lo, NaN: v.NaN}, 0, lo) && matchF64(wat.Value{Lo: v.Hi, NaN: v.NaN}, 1, hi)
+		}
+		return lo == v.Lo && hi == v.Hi
+	case wasm.ValueTypeExternref:
+		return lo == v.Lo+1
+	case wasm.ValueTypeFuncref:
+		return lo != 0 // the address of a function isn't known to the script
+	}
+	return lo == v.Lo
+}
+
+// matchF32 returns true if the float matches the lane of the expected value, which may be a NaN pattern.
+func matchF32(v wat.Value, lane int, actual uint32) bool {
+	switch nan(v, lane) {
+	case wat.NaNCanonical:
+		return actual&moremath.F32CanonicalNaNBitsMask == moremath.F32CanonicalNaNBits
+	case wat.NaNArithmetic:
+		return actual&moremath.F32ExponentMask == moremath.F32ExponentMask &&
+			actual&moremath.F32ArithmeticNaNPayloadMSB == moremath.F32ArithmeticNaNPayloadMSB
+	}
+	return actual == uint32(v.Lo)
+}
+
+// matchF64 returns true if the float matches the lane of the expected value, which may be a NaN pattern.
+func matchF64(v wat.Value, lane int, actual uint64) bool {
+	switch nan(v, lane) {
+	case wat.NaNCanonical:
+		return actual&moremath.F64CanonicalNaNBitsMask == moremath.F64CanonicalNaNBits
+	case wat.NaNArithmetic:
+		return actual&moremath.F64ExponentMask == moremath.F64ExponentMask &&
+			actual&moremath.F64ArithmeticNaNPayloadMSB == moremath.F64ArithmeticNaNPayloadMSB
+	}
+	return actual == v.Lo
+}
+
+// nan returns the NaN pattern of a lane.
+func nan(v wat.Value, lane int) wat.NaN {
+	if lane < len(v.NaN) {
+		return v.NaN[lane]
+	}
+	return wat.NaNNone
+}
+
+// formatValue formats an expected value in the text format, such as "(i32.const 1)".
+func formatValue(v wat.Value) string {
+	switch {
+	case v.Either != nil:
+		alts := make([]string, 0, len(v.Either))
+		for _, e := range v.Either {
+			alts = append(alts, formatValue(e))
+		}
+		return "(either " + strings.Join(alts, " ") + ")"
+	case v.Null:
+		switch v.Type {
+		case wasm.ValueTypeFuncref:
+			return "(ref.null func)"
+		case wasm.ValueTypeExternref:
+			return "(ref.null extern)"
+		case wasm.ValueTypeExnref:
+			return "(ref.null exn)"
+		}
+		return "(ref.null)"
+	case v.AnyRef:
+		return fmt.Sprintf("(%s)", refKeyword(api.ValueType(v.Type.Kind())))
+	}
+
+	switch v.Type {
+	case wasm.ValueTypeI32, wasm.ValueTypeI64, wasm.ValueTypeF32, wasm.ValueTypeF64:
+		return formatScalar(api.ValueType(v.Type.Kind()), v.Lo, nan(v, 0))
+	case wasm.ValueTypeV128:
+		return formatV128(v.Shape, v.Lo, v.Hi, v.NaN)
+	}
+	return fmt.Sprintf("(%s %d)", refKeyword(api.ValueType(v.Type.Kind())), v.Lo)
+}
+
+// formatResult formats a result in the text format, such as "(i32.const 1)". The lanes of a v128 are formatted in
+// the shape of the expected value.
+func formatResult(t api.ValueType, lo, hi uint64, shape string) string {
+	switch t {
+	case api.ValueTypeI32, api.ValueTypeI64, api.ValueTypeF32, api.ValueTypeF64:
+		return formatScalar(t, lo, wat.NaNNone)
+	case valueTypeV128:
+		return formatV128(shape, lo, hi, nil)
+	}
+	if lo == 0 {
+		return "(ref.null)"
+	}
+	if t == api.ValueTypeExternref {
+		return fmt.Sprintf("(ref.extern %d)", lo-1)
+	}
+	return fmt.Sprintf("(%s)", refKeyword(t))
+}
+
+func formatScalar(t api.ValueType, bits uint64, n wat.NaN) string {
+	var v string
+	switch t {
+	case api.ValueTypeI32:
+		v = strconv.FormatInt(int64(int32(bits)), 10)
+	case api.ValueTypeI64:
+		v = strconv.FormatInt(int64(bits), 10)
+	case api.ValueTypeF32:
+		v = formatFloat(uint64(uint32(bits)), 32, n)
+	case api.ValueTypeF64:
+		v = formatFloat(bits, 64, n)
+	}
+	return fmt.Sprintf("(%s.const %s)", api.ValueTypeName(t), v)
+}
+
+func formatV128(shape string, lo, hi uint64, nans []wat.NaN) string {
+	var lanes []string
+	switch shape {
+	case "f32x4":
+		for i, bits := range []uint64{lo & math.MaxUint32, lo >> 32, hi & math.MaxUint32, hi >> 32} {
+			lanes = append(lanes, formatFloat(bits, 32, nan(wat.Value{NaN: nans}, i)))
+		}
+	case "f64x2":
+		for i, bits := range []uint64{lo, hi} {
+			lanes = append(lanes, formatFloat(bits, 64, nan(wat.Value{NaN: nans}, i)))
+		}
+	default:
+		shape = "i64x2"
+		lanes = []string{fmt.Sprintf("%#x", lo), fmt.Sprintf("%#x", hi)}
+	}
+	return fmt.Sprintf("(v128.const %s %s)", shape, strings.Join(lanes, " "))
+}
+
+// formatFloat formats the bits of a float, or a NaN pattern.
+func formatFloat(bits uint64, size int, n wat.NaN) string {
+	switch n {
+	case wat.NaNCanonical:
+		return "nan:canonical"
+	case wat.NaNArithmetic:
+		return "nan:arithmetic"
+	}
+	f, mantissaBits := math.Float64frombits(bits), 52
+	if size == 32 {
+		f, mantissaBits = float64(math.Float32frombits(uint32(bits))), 23
+	}
+	if !math.IsNaN(f) {
+		return strconv.FormatFloat(f, 'g', -1, size)
+	}
+	sign := ""
+	if bits>>(size-1) != 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%snan:%#x", sign, bits&(1<<mantissaBits-1))
+}
+
+func refKeyword(t api.ValueType) string {
+	if t == api.ValueTypeExternref {
+		return "ref.extern"
+	}
+	return "ref.func"
+}
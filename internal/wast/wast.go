@@ -0,0 +1,290 @@
+// Package wast runs WebAssembly scripts, such as the .wast files of the specification tests, on a wazero runtime.
+//
+// Scripts are parsed with wat.ParseScript. Each command is run in order, and its result reported, so that a failure
+// doesn't prevent later commands from running.
+//
+// See https://github.com/WebAssembly/spec/tree/main/interpreter#scripts
+package wast
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasmruntime"
+	"github.com/tetratelabs/wazero/internal/wat"
+)
+
+// spectestWat is the module the specification tests import as "spectest".
+//
+// See https://github.com/WebAssembly/spec/blob/1c5e5d178bd75c79b7a12881c529098beaee2a05/interpreter/script/js.ml#L33-L50
+//
+//go:embed spectest.wat
+var spectestWat []byte
+
+// Result is the result of a command of a script.
+type Result struct {
+	// Command is the command run.
+	Command *wat.Command
+
+	// Err is why the command failed, or nil if it passed.
+	Err error
+}
+
+// String implements fmt.Stringer, such as "12: assert_return (invoke "add"): ok".
+func (r *Result) String() string {
+	c := r.Command
+	s := fmt.Sprintf("%d: %s", c.Line, c.Type)
+	if a := c.Action; a != nil {
+		s += fmt.Sprintf(" (%s %q)", a.Type, a.Field)
+	} else if c.Type == wat.CommandRegister {
+		s += fmt.Sprintf(" %q", c.As)
+	}
+	if r.Err != nil {
+		return s + ": FAIL: " + r.Err.Error()
+	}
+	return s + ": ok"
+}
+
+// Run runs the commands of the script on a new runtime with the given configuration, calling report with the result
+// of each. An error is only returned when the runtime can't be set up.
+func Run(ctx context.Context, config wazero.RuntimeConfig, script *wat.Script, report func(*Result)) error {
+	r := &runner{
+		runtime:    wazero.NewRuntimeWithConfig(ctx, config),
+		modules:    map[string]api.Module{},
+		registered: map[string]api.Module{},
+	}
+	defer r.runtime.Close(ctx)
+
+	// Modules are instantiated anonymously, so that a module can be registered under any name, and even twice.
+	ctx = experimental.WithImportResolver(ctx, func(name string) api.Module {
+		return r.registered[name]
+	})
+
+	bin, err := wat.Compile(spectestWat)
+	if err != nil {
+		return err
+	}
+	if r.registered["spectest"], err = r.instantiate(ctx, bin); err != nil {
+		return fmt.Errorf("error instantiating spectest module: %w", err)
+	}
+
+	for _, c := range script.Commands {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		report(&Result{Command: c, Err: r.run(ctx, c)})
+	}
+	return nil
+}
+
+type runner struct {
+	runtime wazero.Runtime
+
+	// modules are the modules instantiated by their identifier, such as $M.
+	modules map[string]api.Module
+
+	// registered are the modules importable by their registered name.
+	registered map[string]api.Module
+
+	// last is the module last instantiated, or nil if it failed.
+	last api.Module
+}
+
+func (r *runner) run(ctx context.Context, c *wat.Command) error {
+	switch c.Type {
+	case wat.CommandModule:
+		r.last = nil
+		bin, err := c.Module.Encode()
+		if err != nil {
+			return err
+		}
+		if r.last, err = r.instantiate(ctx, bin); err != nil {
+			return err
+		}
+		if c.Module.ID != "" {
+			r.modules[c.Module.ID] = r.last
+		}
+		return nil
+	case wat.CommandRegister:
+		m, err := r.module(c.Name)
+		if err == nil {
+			r.registered[c.As] = m
+		}
+		return err
+	case wat.CommandAction:
+		_, _, err := r.do(ctx, c.Action)
+		return err
+	case wat.CommandAssertReturn:
+		results, types, err := r.do(ctx, c.Action)
+		if err != nil {
+			return err
+		}
+		return checkResults(c.Expected, types, results)
+	case wat.CommandAssertTrap, wat.CommandAssertExhaustion, wat.CommandAssertException:
+		if c.Module != nil {
+			return r.checkUninstantiable(ctx, c)
+		}
+		_, _, err := r.do(ctx, c.Action)
+		return checkTrap(c, err)
+	case wat.CommandAssertMalformed, wat.CommandAssertInvalid:
+		// Like the specification tests of wazero, this instantiates the module, as some validation, such as of
+		// constant expressions, is only done on instantiation.
+		bin, err := c.Module.Encode()
+		if err == nil {
+			_, err = r.instantiate(ctx, bin)
+		}
+		if err == nil {
+			return fmt.Errorf("expected %s module: %s", strings.TrimPrefix(c.Type, "assert_"), c.Text)
+		}
+		return nil
+	case wat.CommandAssertUnlinkable, wat.CommandAssertUninstantiable:
+		return r.checkUninstantiable(ctx, c)
+	default:
+		return fmt.Errorf("unsupported command %s", c.Type)
+	}
+}
+
+// checkUninstantiable returns an error unless the module of an assertion fails to instantiate.
+func (r *runner) checkUninstantiable(ctx context.Context, c *wat.Command) error {
+	bin, err := c.Module.Encode()
+	if err != nil {
+		return err
+	}
+	_, err = r.instantiate(ctx, bin)
+	switch {
+	case err == nil:
+		return fmt.Errorf("expected uninstantiable module: %s", c.Text)
+	case c.Type == wat.CommandAssertUnlinkable:
+		return nil
+	}
+	// Otherwise, instantiation must trap, such as on an out of bounds segment or in the start function.
+	if expected := trapError(c.Text); expected != nil && !errors.Is(err, expected) {
+		return fmt.Errorf("expected %s, but was: %v", expectation(c), err)
+	}
+	return nil
+}
+
+// instantiate compiles and instantiates an anonymous module, whose exports are only importable once registered.
+func (r *runner) instantiate(ctx context.Context, bin []byte) (api.Module, error) {
+	compiled, err := r.runtime.CompileModule(ctx, bin)
+	if err != nil {
+		return nil, err
+	}
+	return r.runtime.InstantiateModule(ctx, compiled, wazero.NewModuleConfig().WithName("").WithStartFunctions())
+}
+
+// module returns the module of the given identifier, or the last module instantiated if empty.
+func (r *runner) module(id string) (api.Module, error) {
+	if id == "" {
+		if r.last == nil {
+			return nil, errors.New("no module instantiated")
+		}
+		return r.last, nil
+	}
+	m, ok := r.modules[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown module %s", id)
+	}
+	return m, nil
+}
+
+// do invokes a function or gets a global, returning its results and their types.
+func (r *runner) do(ctx context.Context, a *wat.Action) ([]uint64, []api.ValueType, error) {
+	m, err := r.module(a.Module)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.Type == "get" {
+		exp, ok := m.(*wasm.ModuleInstance).Exports[a.Field]
+		if !ok || exp.Type != wasm.ExternTypeGlobal {
+			return nil, nil, fmt.Errorf("unknown global %q", a.Field)
+		}
+		// Read the global directly, as api.Global can't get the high bits of a v128.
+		g := m.(*wasm.ModuleInstance).Globals[exp.Index]
+		lo, hi := g.Value()
+		types := []api.ValueType{g.Type.ValType.Kind()}
+		if g.Type.ValType == wasm.ValueTypeV128 {
+			return []uint64{lo, hi}, types, nil
+		}
+		return []uint64{lo}, types, nil
+	}
+
+	fn := m.ExportedFunction(a.Field)
+	if fn == nil {
+		return nil, nil, fmt.Errorf("unknown function %q", a.Field)
+	}
+	var params []uint64
+	for _, v := range a.Args {
+		params = append(params, args(v)...)
+	}
+	results, err := fn.Call(ctx, params...)
+	return results, fn.Definition().ResultTypes(), err
+}
+
+// checkTrap returns an error unless err is the trap expected by an assertion.
+func checkTrap(c *wat.Command, err error) error {
+	if err == nil {
+		return fmt.Errorf("expected %s, but there was no error", expectation(c))
+	}
+	var expected error
+	switch c.Type {
+	case wat.CommandAssertExhaustion:
+		expected = wasmruntime.ErrRuntimeStackOverflow
+	case wat.CommandAssertException:
+		expected = wasmruntime.ErrRuntimeUncaughtException
+	default:
+		expected = trapError(c.Text)
+	}
+	if expected != nil && !errors.Is(err, expected) {
+		return fmt.Errorf("expected %s, but was: %v", expectation(c), err)
+	}
+	return nil
+}
+
+// expectation describes the error expected by an assertion.
+func expectation(c *wat.Command) string {
+	if c.Type == wat.CommandAssertException {
+		return "an exception"
+	}
+	return fmt.Sprintf("%q", c.Text)
+}
+
+// trapError returns the error of a trap described as in the specification tests, such as "integer divide by zero",
+// or nil if any error matches it.
+func trapError(text string) error {
+	switch text {
+	case "expected shared memory":
+		return wasmruntime.ErrRuntimeExpectedSharedMemory
+	case "out of bounds memory access":
+		return wasmruntime.ErrRuntimeOutOfBoundsMemoryAccess
+	case "indirect call type mismatch", "indirect call":
+		return wasmruntime.ErrRuntimeIndirectCallTypeMismatch
+	case "undefined element", "undefined", "out of bounds table access":
+		return wasmruntime.ErrRuntimeInvalidTableAccess
+	case "integer overflow":
+		return wasmruntime.ErrRuntimeIntegerOverflow
+	case "invalid conversion to integer":
+		return wasmruntime.ErrRuntimeInvalidConversionToInteger
+	case "integer divide by zero":
+		return wasmruntime.ErrRuntimeIntegerDivideByZero
+	case "unaligned atomic":
+		return wasmruntime.ErrRuntimeUnalignedAtomic
+	case "unreachable":
+		return wasmruntime.ErrRuntimeUnreachable
+	case "uncaught exception":
+		return wasmruntime.ErrRuntimeUncaughtException
+	case "null", "null reference", "null function reference", "null function":
+		return wasmruntime.ErrRuntimeNullReference
+	}
+	if strings.HasPrefix(text, "uninitialized") {
+		return wasmruntime.ErrRuntimeInvalidTableAccess
+	}
+	return nil
+}
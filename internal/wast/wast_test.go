@@ -0,0 +1,104 @@
+package wast
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wat"
+)
+
+func TestRun(t *testing.T) {
+	script, err := wat.ParseScript([]byte(`(module $M
+  (global (export "g") i64 (i64.const -1))
+  (func (export "add") (param i32 i32) (result i32) (i32.add (local.get 0) (local.get 1)))
+  (func (export "div") (param i32 i32) (result i32) (i32.div_u (local.get 0) (local.get 1)))
+  (func (export "nan") (result f32 v128) (f32.const nan) (v128.const f32x4 1 2 3 -nan:0x600000))
+  (func (export "id") (param externref) (result externref) (local.get 0))
+  (func $loop (export "loop") (call $loop)))
+(register "math" $M)
+(module (import "math" "add" (func $add (param i32 i32) (result i32))) (func (export "inc") (param i32) (result i32)
+  (call $add (local.get 0) (i32.const 1))))
+(assert_return (invoke "inc" (i32.const 1)) (i32.const 2))
+(assert_return (invoke $M "add" (i32.const 1) (i32.const 2)) (i32.const 4))
+(assert_return (get $M "g") (i64.const -1))
+(assert_return (invoke $M "nan") (f32.const nan:canonical) (v128.const f32x4 1 2 3 nan:arithmetic))
+(assert_return (invoke $M "id" (ref.extern 1)) (either (ref.null extern) (ref.extern 1)))
+(assert_return (invoke $M "id" (ref.null extern)) (ref.extern))
+(assert_trap (invoke $M "div" (i32.const 1) (i32.const 0)) "integer divide by zero")
+(assert_trap (invoke $M "div" (i32.const 1) (i32.const 1)) "integer divide by zero")
+(assert_exhaustion (invoke $M "loop") "call stack exhausted")
+(assert_malformed (module quote "(func") "unclosed parenthesis")
+(assert_invalid (module (func (result i32))) "type mismatch")
+(assert_invalid (module (func)) "type mismatch")
+(assert_unlinkable (module (import "math" "sub" (func))) "unknown import")
+(invoke $N "add")
+`))
+	require.NoError(t, err)
+
+	var results []string
+	err = Run(context.Background(), wazero.NewRuntimeConfigInterpreter(), script, func(r *Result) {
+		results = append(results, r.String())
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"1: module: ok",
+		`8: register "math": ok`,
+		"9: module: ok",
+		`11: assert_return (invoke "inc"): ok`,
+		`12: assert_return (invoke "add"): FAIL: expected (i32.const 4), but was (i32.const 3)`,
+		`13: assert_return (get "g"): ok`,
+		`14: assert_return (invoke "nan"): ok`,
+		`15: assert_return (invoke "id"): ok`,
+		`16: assert_return (invoke "id"): FAIL: expected (ref.extern), but was (ref.null)`,
+		`17: assert_trap (invoke "div"): ok`,
+		`18: assert_trap (invoke "div"): FAIL: expected "integer divide by zero", but there was no error`,
+		`19: assert_exhaustion (invoke "loop"): ok`,
+		"20: assert_malformed: ok",
+		"21: assert_invalid: ok",
+		"22: assert_invalid: FAIL: expected invalid module: type mismatch",
+		"23: assert_unlinkable: ok",
+		`24: action (invoke "add"): FAIL: unknown module $N`,
+	}, results)
+}
+
+// TestRun_spectest ensures the scripts of the specification tests pass, like the tests which run them after they are
+// converted to JSON by wast2json.
+func TestRun_spectest(t *testing.T) {
+	features := map[string]api.CoreFeatures{
+		"v1":                        api.CoreFeaturesV1,
+		"v2":                        api.CoreFeaturesV2,
+		"extended-const":            api.CoreFeaturesV2 | experimental.CoreFeaturesExtendedConst,
+		"threads":                   api.CoreFeaturesV2 | experimental.CoreFeaturesThreads,
+		"tail-call":                 api.CoreFeaturesV2 | experimental.CoreFeaturesTailCall,
+		"typed-function-references": api.CoreFeaturesV2 | experimental.CoreFeaturesTypedFunctionReferences | experimental.CoreFeaturesTailCall,
+		"exception-handling": api.CoreFeaturesV2 | experimental.CoreFeaturesExceptionHandling |
+			experimental.CoreFeaturesTailCall | experimental.CoreFeaturesTypedFunctionReferences,
+	}
+
+	files, err := filepath.Glob("../integration_test/spectest/*/testdata/*.wast")
+	require.NoError(t, err)
+	require.NotEqual(t, 0, len(files))
+
+	for _, file := range files {
+		file := file
+		suite := filepath.Base(filepath.Dir(filepath.Dir(file)))
+		t.Run(suite+"/"+filepath.Base(file), func(t *testing.T) {
+			source, err := os.ReadFile(file)
+			require.NoError(t, err)
+			script, err := wat.ParseScript(source)
+			require.NoError(t, err)
+
+			config := wazero.NewRuntimeConfigInterpreter().WithCoreFeatures(features[suite])
+			err = Run(context.Background(), config, script, func(r *Result) {
+				require.NoError(t, r.Err, "line %d", r.Command.Line)
+			})
+			require.NoError(t, err)
+		})
+	}
+}
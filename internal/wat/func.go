@@ -0,0 +1,646 @@
+package wat
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"strings"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+)
+
+// funcParser encodes the instructions of a function body or constant expression. Instructions are either plain,
+// such as "i32.add", folded into S-expressions whose operands precede them, such as "(i32.add (local.get 0) (i32.const
+// 1))", or blocks, which are either structured with "end" or folded.
+type funcParser struct {
+	p *moduleParser
+
+	// locals are the indexes of named parameters and locals.
+	locals map[string]wasm.Index
+
+	// blocks are the enclosing blocks, innermost last.
+	blocks []block
+
+	body []byte
+}
+
+// block is a structured instruction, such as block or if, which may be labeled for branches.
+type block struct {
+	op    wasm.Opcode
+	label string
+	// hasElse is true when an if block has an else branch.
+	hasElse bool
+}
+
+// parseBody encodes the instructions of a function, including the trailing end.
+func (fp *funcParser) parseBody(items []*sexpr, field *sexpr) ([]byte, error) {
+	if err := fp.parseInstrs(items); err != nil {
+		return nil, err
+	}
+	if len(fp.blocks) > 0 {
+		return nil, field.errorf("unclosed %s", wasm.InstructionName(fp.blocks[len(fp.blocks)-1].op))
+	}
+	return append(fp.body, wasm.OpcodeEnd), nil
+}
+
+// parseConstExpr encodes a constant expression, such as the initializer of a global.
+func (p *moduleParser) parseConstExpr(items []*sexpr, field *sexpr) (wasm.ConstantExpression, error) {
+	fp := &funcParser{p: p}
+	body, err := fp.parseBody(items, field)
+	return wasm.ConstantExpression{Data: body}, err
+}
+
+// parseInstrs encodes a sequence of plain, structured and folded instructions.
+func (fp *funcParser) parseInstrs(items []*sexpr) error {
+	for i := 0; i < len(items); {
+		s := items[i]
+		if s.isList {
+			if err := fp.parseFolded(s); err != nil {
+				return err
+			}
+			i++
+			continue
+		}
+		if s.isString || s.isID() {
+			return s.errorf("unexpected %s: expected an instruction", s)
+		}
+
+		var err error
+		switch s.atom {
+		case wasm.OpcodeBlockName, wasm.OpcodeLoopName, wasm.OpcodeIfName, wasm.OpcodeTryTableName:
+			i, err = fp.parseBlockStart(s, items, i+1)
+		case wasm.OpcodeElseName:
+			i, err = fp.parseElse(s, items, i+1)
+		case wasm.OpcodeEndName:
+			i, err = fp.parseEnd(s, items, i+1)
+		default:
+			var code []byte
+			if code, i, err = fp.parsePlain(s, items, i+1); err == nil {
+				fp.body = append(fp.body, code...)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFolded encodes a folded instruction, which is a plain instruction, block, loop, if or try_table, as an
+// S-expression.
+func (fp *funcParser) parseFolded(s *sexpr) error {
+	items := s.list
+	if len(items) == 0 || items[0].isList || items[0].isString {
+		return s.errorf("unexpected %s: expected an instruction", s)
+	}
+	switch op := items[0]; op.atom {
+	case wasm.OpcodeBlockName, wasm.OpcodeLoopName, wasm.OpcodeTryTableName:
+		i, err := fp.parseBlockStart(op, items, 1)
+		if err != nil {
+			return err
+		}
+		if err = fp.parseInstrs(items[i:]); err != nil {
+			return err
+		}
+		return fp.endBlock()
+	case wasm.OpcodeIfName:
+		label, i := optionalID(items, 1)
+		bt, i, err := fp.parseBlockType(items, i)
+		if err != nil {
+			return err
+		}
+		// The condition precedes the then and else branches.
+		for ; i < len(items) && items[i].keyword() != "then"; i++ {
+			if !items[i].isList {
+				return items[i].errorf("unexpected %s: expected a folded instruction or (then ...)", items[i])
+			}
+			if err = fp.parseFolded(items[i]); err != nil {
+				return err
+			}
+		}
+		if i == len(items) {
+			return s.errorf("missing (then ...) in if")
+		}
+		fp.startBlock(wasm.OpcodeIf, label, bt)
+		if err = fp.parseInstrs(items[i].list[1:]); err != nil {
+			return err
+		}
+		if i++; i < len(items) {
+			if items[i].keyword() != "else" || i+1 != len(items) {
+				return items[i].errorf("unexpected %s: expected (else ...)", items[i])
+			}
+			fp.body = append(fp.body, wasm.OpcodeElse)
+			if err = fp.parseInstrs(items[i].list[1:]); err != nil {
+				return err
+			}
+		}
+		return fp.endBlock()
+	default:
+		code, i, err := fp.parsePlain(op, items, 1)
+		if err != nil {
+			return err
+		}
+		for ; i < len(items); i++ {
+			if !items[i].isList {
+				return items[i].errorf("unexpected %s: expected a folded instruction", items[i])
+			}
+			if err = fp.parseFolded(items[i]); err != nil {
+				return err
+			}
+		}
+		fp.body = append(fp.body, code...)
+		return nil
+	}
+}
+
+// parseBlockStart encodes the start of a block, loop, if or try_table, whose label, block type and catch clauses
+// start at items[i].
+func (fp *funcParser) parseBlockStart(op *sexpr, items []*sexpr, i int) (int, error) {
+	label, i := optionalID(items, i)
+	bt, i, err := fp.parseBlockType(items, i)
+	if err != nil {
+		return 0, err
+	}
+	var opcode wasm.Opcode
+	switch op.atom {
+	case wasm.OpcodeBlockName:
+		opcode = wasm.OpcodeBlock
+	case wasm.OpcodeLoopName:
+		opcode = wasm.OpcodeLoop
+	case wasm.OpcodeIfName:
+		opcode = wasm.OpcodeIf
+	case wasm.OpcodeTryTableName:
+		// Labels of catch clauses are resolved outside the try_table block.
+		var catches []byte
+		var count uint32
+		for ; i < len(items) && strings.HasPrefix(items[i].keyword(), "catch"); i++ {
+			c, err := fp.parseCatch(items[i])
+			if err != nil {
+				return 0, err
+			}
+			catches = append(catches, c...)
+			count++
+		}
+		bt = append(bt, leb128.EncodeUint32(count)...)
+		bt = append(bt, catches...)
+		opcode = wasm.OpcodeTryTable
+	}
+	fp.startBlock(opcode, label, bt)
+	return i, nil
+}
+
+// parseCatch encodes a catch clause of try_table: (catch tagidx labelidx), (catch_ref tagidx labelidx),
+// (catch_all labelidx) or (catch_all_ref labelidx).
+func (fp *funcParser) parseCatch(s *sexpr) ([]byte, error) {
+	var kind byte
+	hasTag := true
+	switch s.keyword() {
+	case "catch":
+		kind = 0x00
+	case "catch_ref":
+		kind = 0x01
+	case "catch_all":
+		kind, hasTag = 0x02, false
+	case "catch_all_ref":
+		kind, hasTag = 0x03, false
+	default:
+		return nil, s.errorf("unknown catch clause %s", s)
+	}
+	args := s.list[1:]
+	if (hasTag && len(args) != 2) || (!hasTag && len(args) != 1) {
+		return nil, s.errorf("invalid %s: wrong number of arguments", s.keyword())
+	}
+	c := []byte{kind}
+	if hasTag {
+		tag, err := resolveIndex(fp.p.names[wasm.ExternTypeTag], args[0], "tag")
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, leb128.EncodeUint32(tag)...)
+	}
+	depth, err := fp.labelDepth(args[len(args)-1])
+	if err != nil {
+		return nil, err
+	}
+	return append(c, leb128.EncodeUint32(depth)...), nil
+}
+
+func (fp *funcParser) startBlock(op wasm.Opcode, label string, bt []byte) {
+	fp.body = append(fp.body, op)
+	fp.body = append(fp.body, bt...)
+	fp.blocks = append(fp.blocks, block{op: op, label: label})
+}
+
+func (fp *funcParser) endBlock() error {
+	fp.body = append(fp.body, wasm.OpcodeEnd)
+	fp.blocks = fp.blocks[:len(fp.blocks)-1]
+	return nil
+}
+
+// parseElse encodes the else of a structured if, which may repeat its label.
+func (fp *funcParser) parseElse(s *sexpr, items []*sexpr, i int) (int, error) {
+	if len(fp.blocks) == 0 || fp.blocks[len(fp.blocks)-1].op != wasm.OpcodeIf || fp.blocks[len(fp.blocks)-1].hasElse {
+		return 0, s.errorf("else without a matching if")
+	}
+	b := &fp.blocks[len(fp.blocks)-1]
+	b.hasElse = true
+	i, err := checkLabel(b, items, i)
+	fp.body = append(fp.body, wasm.OpcodeElse)
+	return i, err
+}
+
+// parseEnd encodes the end of a structured block, which may repeat its label.
+func (fp *funcParser) parseEnd(s *sexpr, items []*sexpr, i int) (int, error) {
+	if len(fp.blocks) == 0 {
+		return 0, s.errorf("end without a matching block")
+	}
+	i, err := checkLabel(&fp.blocks[len(fp.blocks)-1], items, i)
+	if err != nil {
+		return 0, err
+	}
+	return i, fp.endBlock()
+}
+
+// checkLabel consumes the optional label following else or end, which must match that of the block.
+func checkLabel(b *block, items []*sexpr, i int) (int, error) {
+	label, next := optionalID(items, i)
+	if label != "" && label != b.label {
+		return 0, items[i].errorf("mismatching label %s", label)
+	}
+	return next, nil
+}
+
+// parseBlockType encodes the type of a block: empty, a single result type, or the index of a function type.
+func (fp *funcParser) parseBlockType(items []*sexpr, i int) ([]byte, int, error) {
+	start := i
+	tu, i, err := fp.p.parseTypeUse(items, i)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, id := range tu.paramIDs {
+		if id != nil {
+			return nil, 0, id.errorf("unexpected identifier in block parameter")
+		}
+	}
+	if !tu.explicit && len(tu.params) == 0 {
+		switch len(tu.results) {
+		case 0:
+			return []byte{0x40}, i, nil
+		case 1:
+			return binaryencoding.EncodeValueType(tu.results[0]), i, nil
+		}
+	}
+	index, err := fp.p.typeUseIndex(tu, items[start])
+	if err != nil {
+		return nil, 0, err
+	}
+	return leb128.EncodeInt64(int64(index)), i, nil
+}
+
+// parsePlain encodes a plain instruction, whose immediates start at items[i], returning the index after them.
+func (fp *funcParser) parsePlain(op *sexpr, items []*sexpr, i int) ([]byte, int, error) {
+	in, ok := instructions[op.atom]
+	if !ok {
+		return nil, 0, op.errorf("unknown instruction %s", op.atom)
+	}
+	code := append([]byte(nil), in.opcode...)
+	p := fp.p
+
+	// arg returns the next immediate, which must be an atom.
+	arg := func(what string) (*sexpr, error) {
+		if i >= len(items) || items[i].isList || items[i].isString {
+			return nil, op.errorf("missing %s of %s", what, op.atom)
+		}
+		i++
+		return items[i-1], nil
+	}
+	// hasIndex returns true if the next item is an optional index.
+	hasIndex := func() bool {
+		return i < len(items) && isIndex(items[i])
+	}
+	index := func(names map[string]wasm.Index, kind string) error {
+		s, err := arg(kind + " index")
+		if err != nil {
+			return err
+		}
+		idx, err := resolveIndex(names, s, kind)
+		code = append(code, leb128.EncodeUint32(idx)...)
+		return err
+	}
+
+	var err error
+	switch in.imm {
+	case immNone:
+	case immLocal:
+		err = index(fp.locals, "local")
+	case immGlobal:
+		err = index(p.names[wasm.ExternTypeGlobal], "global")
+	case immFunc:
+		err = index(p.names[wasm.ExternTypeFunc], "function")
+	case immTag:
+		err = index(p.names[wasm.ExternTypeTag], "tag")
+	case immType:
+		err = index(p.typeNames, "type")
+	case immElem:
+		err = index(p.elemNames, "elem segment")
+	case immData:
+		p.usesDataCount = true
+		err = index(p.dataNames, "data segment")
+	case immTable:
+		if hasIndex() {
+			err = index(p.names[wasm.ExternTypeTable], "table")
+		} else {
+			code = append(code, 0)
+		}
+	case immLabel:
+		var s *sexpr
+		if s, err = arg("label"); err == nil {
+			var depth uint32
+			depth, err = fp.labelDepth(s)
+			code = append(code, leb128.EncodeUint32(depth)...)
+		}
+	case immBrTable:
+		var depths []byte
+		var count uint32
+		for ; hasIndex(); i, count = i+1, count+1 {
+			depth, err := fp.labelDepth(items[i])
+			if err != nil {
+				return nil, 0, err
+			}
+			depths = append(depths, leb128.EncodeUint32(depth)...)
+		}
+		if count == 0 {
+			return nil, 0, op.errorf("missing label of %s", op.atom)
+		}
+		code = append(code, leb128.EncodeUint32(count-1)...)
+		code = append(code, depths...)
+	case immCallIndirect:
+		var table wasm.Index
+		if hasIndex() {
+			if table, err = resolveIndex(p.names[wasm.ExternTypeTable], items[i], "table"); err != nil {
+				return nil, 0, err
+			}
+			i++
+		}
+		var tu *typeUse
+		if tu, i, err = p.parseTypeUse(items, i); err != nil {
+			return nil, 0, err
+		}
+		for _, id := range tu.paramIDs {
+			if id != nil {
+				return nil, 0, id.errorf("unexpected identifier in %s parameter", op.atom)
+			}
+		}
+		var typeIndex wasm.Index
+		if typeIndex, err = p.typeUseIndex(tu, op); err != nil {
+			return nil, 0, err
+		}
+		code = append(code, leb128.EncodeUint32(typeIndex)...)
+		code = append(code, leb128.EncodeUint32(table)...)
+	case immSelect:
+		var results []wasm.ValueType
+		typed := false
+		for ; i < len(items) && items[i].keyword() == "result"; i++ {
+			var types []wasm.ValueType
+			if _, types, err = p.parseValueTypes(items[i]); err != nil {
+				return nil, 0, err
+			}
+			results, typed = append(results, types...), true
+		}
+		if typed {
+			code = []byte{wasm.OpcodeTypedSelect}
+			code = append(code, binaryencoding.EncodeValTypes(results)...)
+		}
+	case immHeapType:
+		var s *sexpr
+		if s, err = arg("heap type"); err == nil {
+			var ht []byte
+			ht, err = p.parseHeapType(s)
+			code = append(code, ht...)
+		}
+	case immMemory:
+		err = fp.memoryIndex(hasIndex, items, &i)
+		code = append(code, 0)
+	case immMemoryCopy:
+		for j := 0; j < 2 && err == nil; j++ {
+			err = fp.memoryIndex(hasIndex, items, &i)
+		}
+		code = append(code, 0, 0)
+	case immMemoryInit:
+		p.usesDataCount = true
+		if i+1 < len(items) && hasIndex() && isIndex(items[i+1]) {
+			if err = fp.memoryIndex(hasIndex, items, &i); err != nil {
+				return nil, 0, err
+			}
+		}
+		err = index(p.dataNames, "data segment")
+		code = append(code, 0)
+	case immTableCopy:
+		if hasIndex() {
+			if err = index(p.names[wasm.ExternTypeTable], "table"); err == nil {
+				err = index(p.names[wasm.ExternTypeTable], "table")
+			}
+		} else {
+			code = append(code, 0, 0)
+		}
+	case immTableInit:
+		var first, second *sexpr
+		if first, err = arg("elem segment index"); err != nil {
+			return nil, 0, err
+		}
+		var table, elem wasm.Index
+		if hasIndex() {
+			second, i = items[i], i+1
+			if table, err = resolveIndex(p.names[wasm.ExternTypeTable], first, "table"); err != nil {
+				return nil, 0, err
+			}
+			elem, err = resolveIndex(p.elemNames, second, "elem segment")
+		} else {
+			elem, err = resolveIndex(p.elemNames, first, "elem segment")
+		}
+		code = append(code, leb128.EncodeUint32(elem)...)
+		code = append(code, leb128.EncodeUint32(table)...)
+	case immZeroByte:
+		code = append(code, 0)
+	case immMemarg, immMemargLane:
+		var memarg []byte
+		if memarg, i, err = parseMemarg(in, items, i); err != nil {
+			return nil, 0, err
+		}
+		code = append(code, memarg...)
+		if in.imm == immMemargLane {
+			err = fp.lane(arg, &code)
+		}
+	case immLane:
+		err = fp.lane(arg, &code)
+	case immI32:
+		var s *sexpr
+		if s, err = arg("value"); err == nil {
+			v, ok := parseInt(s.atom, 32)
+			if !ok {
+				return nil, 0, s.errorf("invalid i32 %s", s.atom)
+			}
+			code = append(code, leb128.EncodeInt32(int32(v))...)
+		}
+	case immI64:
+		var s *sexpr
+		if s, err = arg("value"); err == nil {
+			v, ok := parseInt(s.atom, 64)
+			if !ok {
+				return nil, 0, s.errorf("invalid i64 %s", s.atom)
+			}
+			code = append(code, leb128.EncodeInt64(int64(v))...)
+		}
+	case immF32:
+		var s *sexpr
+		if s, err = arg("value"); err == nil {
+			v, ok := parseF32(s.atom)
+			if !ok {
+				return nil, 0, s.errorf("invalid f32 %s", s.atom)
+			}
+			code = binary.LittleEndian.AppendUint32(code, v)
+		}
+	case immF64:
+		var s *sexpr
+		if s, err = arg("value"); err == nil {
+			v, ok := parseF64(s.atom)
+			if !ok {
+				return nil, 0, s.errorf("invalid f64 %s", s.atom)
+			}
+			code = binary.LittleEndian.AppendUint64(code, v)
+		}
+	case immV128:
+		var v []byte
+		if v, i, err = parseV128(op, items, i); err == nil {
+			code = append(code, v...)
+		}
+	case immShuffle:
+		for j := 0; j < 16 && err == nil; j++ {
+			err = fp.lane(arg, &code)
+		}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return code, i, nil
+}
+
+// memoryIndex consumes an optional memory index, which must be zero as only one memory is supported.
+func (fp *funcParser) memoryIndex(hasIndex func() bool, items []*sexpr, i *int) error {
+	if !hasIndex() {
+		return nil
+	}
+	index, err := resolveIndex(fp.p.names[wasm.ExternTypeMemory], items[*i], "memory")
+	if err != nil {
+		return err
+	} else if index != 0 {
+		return items[*i].errorf("unknown memory %d", index)
+	}
+	*i++
+	return nil
+}
+
+// lane encodes a lane index, which is a byte.
+func (fp *funcParser) lane(arg func(string) (*sexpr, error), code *[]byte) error {
+	s, err := arg("lane index")
+	if err != nil {
+		return err
+	}
+	v, ok := parseUint(s.atom, 8)
+	if !ok {
+		return s.errorf("invalid lane index %s", s.atom)
+	}
+	*code = append(*code, byte(v))
+	return nil
+}
+
+// parseMemarg encodes the optional offset=N and align=N of a memory instruction, defaulting to zero and its natural
+// alignment.
+func parseMemarg(in *instruction, items []*sexpr, i int) ([]byte, int, error) {
+	var offset uint64
+	align := in.align
+	for ; i < len(items) && !items[i].isList && !items[i].isString; i++ {
+		s := items[i]
+		if v, ok := strings.CutPrefix(s.atom, "offset="); ok {
+			if offset, ok = parseUint(v, 32); !ok {
+				return nil, 0, s.errorf("invalid offset %s", v)
+			}
+		} else if v, ok = strings.CutPrefix(s.atom, "align="); ok {
+			a, ok := parseUint(v, 32)
+			if !ok || a == 0 || a&(a-1) != 0 {
+				return nil, 0, s.errorf("invalid alignment %s", v)
+			}
+			align = uint32(bits.TrailingZeros64(a))
+		} else {
+			break
+		}
+	}
+	memarg := leb128.EncodeUint32(align)
+	return append(memarg, leb128.EncodeUint64(offset)...), i, nil
+}
+
+// parseV128 encodes the shape and lanes of v128.const, such as "i32x4 1 2 3 4", starting at items[i].
+func parseV128(op *sexpr, items []*sexpr, i int) ([]byte, int, error) {
+	if i >= len(items) || items[i].isList || items[i].isString {
+		return nil, 0, op.errorf("missing shape of %s", op.atom)
+	}
+	shape := items[i]
+	var lanes, laneBits int
+	switch shape.atom {
+	case "i8x16":
+		lanes, laneBits = 16, 8
+	case "i16x8":
+		lanes, laneBits = 8, 16
+	case "i32x4", "f32x4":
+		lanes, laneBits = 4, 32
+	case "i64x2", "f64x2":
+		lanes, laneBits = 2, 64
+	default:
+		return nil, 0, shape.errorf("unknown vector shape %s", shape.atom)
+	}
+	i++
+	v := make([]byte, 0, 16)
+	for lane := 0; lane < lanes; lane, i = lane+1, i+1 {
+		if i >= len(items) || items[i].isList || items[i].isString {
+			return nil, 0, shape.errorf("expected %d lanes of %s", lanes, shape.atom)
+		}
+		s := items[i]
+		var bits uint64
+		var ok bool
+		switch shape.atom[0] {
+		case 'f':
+			bits, ok = parseFloat(s.atom, laneBits)
+		default:
+			bits, ok = parseInt(s.atom, laneBits)
+		}
+		if !ok {
+			return nil, 0, s.errorf("invalid %s lane %s", shape.atom, s.atom)
+		}
+		for b := 0; b < laneBits/8; b++ {
+			v = append(v, byte(bits>>(8*b)))
+		}
+	}
+	return v, i, nil
+}
+
+// labelDepth resolves a label, by identifier or number, to its relative depth.
+func (fp *funcParser) labelDepth(s *sexpr) (uint32, error) {
+	if s.isID() {
+		for j := len(fp.blocks) - 1; j >= 0; j-- {
+			if fp.blocks[j].label == s.atom {
+				return uint32(len(fp.blocks) - 1 - j), nil
+			}
+		}
+		return 0, s.errorf("unknown label %s", s.atom)
+	}
+	return resolveIndex(nil, s, "label")
+}
+
+// isIndex returns true if s is an index, either an identifier or a number.
+func isIndex(s *sexpr) bool {
+	if s.isList || s.isString || s.atom == "" {
+		return false
+	}
+	return s.isID() || ('0' <= s.atom[0] && s.atom[0] <= '9')
+}
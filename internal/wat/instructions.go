@@ -0,0 +1,215 @@
+package wat
+
+import (
+	"strings"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// immediate is the kind of immediate arguments of a plain instruction, which follow its name in the text format.
+type immediate byte
+
+const (
+	immNone immediate = iota
+	immLocal
+	immGlobal
+	immFunc
+	immTable // optional, defaulting to table zero
+	immLabel
+	immTag
+	immType
+	immElem
+	immData
+	immMemarg
+	immMemargLane
+	immLane
+	immI32
+	immI64
+	immF32
+	immF64
+	immV128
+	immShuffle
+	immBrTable
+	immCallIndirect
+	immSelect
+	immHeapType
+	immMemory // an optional memory index, which can only be zero
+	immMemoryCopy
+	immMemoryInit
+	immTableCopy
+	immTableInit
+	immZeroByte
+)
+
+// instruction is a plain instruction, which isn't a block.
+type instruction struct {
+	// opcode is the encoded opcode, including any prefix.
+	opcode []byte
+	imm    immediate
+	// align is the natural alignment of memory instructions, as the log2 of the size of the access.
+	align uint32
+}
+
+// instructions are the plain instructions by their name in the text format.
+var instructions = map[string]*instruction{}
+
+func init() {
+	for i := 0; i < 256; i++ {
+		op := byte(i)
+		switch op {
+		case wasm.OpcodeBlock, wasm.OpcodeLoop, wasm.OpcodeIf, wasm.OpcodeElse, wasm.OpcodeEnd, wasm.OpcodeTryTable,
+			wasm.OpcodeTypedSelect, wasm.OpcodeMiscPrefix, wasm.OpcodeVecPrefix, wasm.OpcodeAtomicPrefix:
+			continue // blocks and prefixes aren't plain instructions
+		}
+		if name := wasm.InstructionName(op); name != "" {
+			addInstruction(name, []byte{op}, immediateOf(op))
+		}
+	}
+	for _, op := range []wasm.OpcodeTailCall{wasm.OpcodeTailCallReturnCall, wasm.OpcodeTailCallReturnCallIndirect} {
+		addInstruction(wasm.TailCallInstructionName(op), []byte{op}, immediateOf(op))
+	}
+	for i := 0; i < 256; i++ {
+		op := byte(i)
+		if name := wasm.MiscInstructionName(op); name != "" {
+			addInstruction(name, prefixedOpcode(wasm.OpcodeMiscPrefix, op), miscImmediateOf(op))
+		}
+		if name := wasm.VectorInstructionName(op); name != "" {
+			addInstruction(name, prefixedOpcode(wasm.OpcodeVecPrefix, op), vectorImmediateOf(op))
+		}
+		if name := wasm.AtomicInstructionName(op); name != "" {
+			imm := immMemarg
+			if op == wasm.OpcodeAtomicFence {
+				imm = immZeroByte
+			}
+			addInstruction(name, prefixedOpcode(wasm.OpcodeAtomicPrefix, op), imm)
+		}
+	}
+}
+
+func addInstruction(name string, opcode []byte, imm immediate) {
+	in := &instruction{opcode: opcode, imm: imm}
+	if imm == immMemarg || imm == immMemargLane {
+		in.align = naturalAlignment(name)
+	}
+	instructions[name] = in
+}
+
+// prefixedOpcode returns the encoding of an opcode after a prefix, which is a LEB128 encoded u32.
+func prefixedOpcode(prefix, op byte) []byte {
+	return append([]byte{prefix}, leb128.EncodeUint32(uint32(op))...)
+}
+
+func immediateOf(op wasm.Opcode) immediate {
+	switch {
+	case wasm.OpcodeI32Load <= op && op <= wasm.OpcodeI64Store32:
+		return immMemarg
+	}
+	switch op {
+	case wasm.OpcodeBr, wasm.OpcodeBrIf, wasm.OpcodeBrOnNull, wasm.OpcodeBrOnNonNull:
+		return immLabel
+	case wasm.OpcodeBrTable:
+		return immBrTable
+	case wasm.OpcodeCall, wasm.OpcodeTailCallReturnCall, wasm.OpcodeRefFunc:
+		return immFunc
+	case wasm.OpcodeCallIndirect, wasm.OpcodeTailCallReturnCallIndirect:
+		return immCallIndirect
+	case wasm.OpcodeCallRef, wasm.OpcodeReturnCallRef:
+		return immType
+	case wasm.OpcodeThrow:
+		return immTag
+	case wasm.OpcodeSelect:
+		return immSelect
+	case wasm.OpcodeLocalGet, wasm.OpcodeLocalSet, wasm.OpcodeLocalTee:
+		return immLocal
+	case wasm.OpcodeGlobalGet, wasm.OpcodeGlobalSet:
+		return immGlobal
+	case wasm.OpcodeTableGet, wasm.OpcodeTableSet:
+		return immTable
+	case wasm.OpcodeMemorySize, wasm.OpcodeMemoryGrow:
+		return immMemory
+	case wasm.OpcodeI32Const:
+		return immI32
+	case wasm.OpcodeI64Const:
+		return immI64
+	case wasm.OpcodeF32Const:
+		return immF32
+	case wasm.OpcodeF64Const:
+		return immF64
+	case wasm.OpcodeRefNull:
+		return immHeapType
+	}
+	return immNone
+}
+
+func miscImmediateOf(op wasm.OpcodeMisc) immediate {
+	switch op {
+	case wasm.OpcodeMiscMemoryInit:
+		return immMemoryInit
+	case wasm.OpcodeMiscDataDrop:
+		return immData
+	case wasm.OpcodeMiscMemoryCopy:
+		return immMemoryCopy
+	case wasm.OpcodeMiscMemoryFill:
+		return immMemory
+	case wasm.OpcodeMiscTableInit:
+		return immTableInit
+	case wasm.OpcodeMiscElemDrop:
+		return immElem
+	case wasm.OpcodeMiscTableCopy:
+		return immTableCopy
+	case wasm.OpcodeMiscTableGrow, wasm.OpcodeMiscTableSize, wasm.OpcodeMiscTableFill:
+		return immTable
+	}
+	return immNone
+}
+
+func vectorImmediateOf(op wasm.OpcodeVec) immediate {
+	switch {
+	case op <= wasm.OpcodeVecV128Store, op == wasm.OpcodeVecV128Load32zero, op == wasm.OpcodeVecV128Load64zero:
+		return immMemarg
+	case wasm.OpcodeVecV128Load8Lane <= op && op <= wasm.OpcodeVecV128Store64Lane:
+		return immMemargLane
+	case wasm.OpcodeVecI8x16ExtractLaneS <= op && op <= wasm.OpcodeVecF64x2ReplaceLane:
+		return immLane
+	case op == wasm.OpcodeVecV128Const:
+		return immV128
+	case op == wasm.OpcodeVecV128i8x16Shuffle:
+		return immShuffle
+	}
+	return immNone
+}
+
+// naturalAlignment returns the log2 of the number of bytes accessed by a memory instruction, which is the default
+// alignment of its memarg. For example, this is 2 for "i32.load" and 0 for "i64.atomic.rmw8.add_u".
+func naturalAlignment(name string) uint32 {
+	switch name {
+	case wasm.OpcodeAtomicMemoryNotifyName, wasm.OpcodeAtomicMemoryWait32Name:
+		return 2
+	case wasm.OpcodeAtomicMemoryWait64Name:
+		return 3
+	}
+	typ, op, _ := strings.Cut(name, ".")
+	if typ == "v128" && strings.Contains(op, "x") { // v128.load8x8_s, etc.
+		return 3
+	}
+	op = strings.TrimPrefix(op, "atomic.")
+	for _, prefix := range []string{"load", "store", "rmw"} {
+		op = strings.TrimPrefix(op, prefix)
+	}
+	switch {
+	case strings.HasPrefix(op, "8"):
+		return 0
+	case strings.HasPrefix(op, "16"):
+		return 1
+	case strings.HasPrefix(op, "32"):
+		return 2
+	case strings.HasPrefix(op, "64"):
+		return 3
+	case typ == "i32" || typ == "f32":
+		return 2
+	case typ == "v128":
+		return 4
+	}
+	return 3
+}
@@ -0,0 +1,1158 @@
+package wat
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/tetratelabs/wazero/internal/leb128"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// moduleParser parses the fields of a text module into a wasm.Module.
+//
+// Identifiers can be used before the field defining them, so fields are parsed in passes: the first assigns an index
+// to each identifier, the second defines explicit types, which are referenced by other fields, and the last parses
+// the remaining fields in order.
+type moduleParser struct {
+	m *wasm.Module
+
+	// names are identifiers of the index space of each wasm.ExternType.
+	names [wasm.ExternTypeTag + 1]map[string]wasm.Index
+
+	typeNames, elemNames, dataNames map[string]wasm.Index
+
+	// indexes are the index of each field defining or importing an item, in the index space of its kind.
+	indexes map[*sexpr]wasm.Index
+
+	// elemCount and dataCount are the number of segments parsed so far, including those defined inline by tables
+	// and memories.
+	elemCount, dataCount wasm.Index
+
+	funcNames  wasm.NameMap
+	localNames wasm.IndirectNameMap
+
+	// usesDataCount is true when code refers to data segments, which requires the data count section.
+	usesDataCount bool
+}
+
+// parseModule parses the items of a module S-expression which follow the module keyword.
+func parseModule(items []*sexpr) (*wasm.Module, error) {
+	p := &moduleParser{
+		m:         &wasm.Module{},
+		typeNames: map[string]wasm.Index{},
+		elemNames: map[string]wasm.Index{},
+		dataNames: map[string]wasm.Index{},
+		indexes:   map[*sexpr]wasm.Index{},
+	}
+	for i := range p.names {
+		p.names[i] = map[string]wasm.Index{}
+	}
+
+	var moduleName string
+	if len(items) > 0 && items[0].isID() {
+		moduleName, items = items[0].atom[1:], items[1:]
+	}
+
+	if err := p.declare(items); err != nil {
+		return nil, err
+	}
+	if err := p.defineTypes(items); err != nil {
+		return nil, err
+	}
+	for _, field := range items {
+		if err := p.parseField(field); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.usesDataCount && len(p.m.DataSection) > 0 {
+		count := uint32(len(p.m.DataSection))
+		p.m.DataCountSection = &count
+	}
+	if moduleName != "" || len(p.funcNames) > 0 || len(p.localNames) > 0 {
+		sort.Slice(p.funcNames, func(i, j int) bool { return p.funcNames[i].Index < p.funcNames[j].Index })
+		sort.Slice(p.localNames, func(i, j int) bool { return p.localNames[i].Index < p.localNames[j].Index })
+		p.m.NameSection = &wasm.NameSection{ModuleName: moduleName, FunctionNames: p.funcNames, LocalNames: p.localNames}
+	}
+	return p.m, nil
+}
+
+// declare assigns an index to each type, function, table, memory, global, tag and segment, and records their
+// identifiers. Imports are indexed before definitions of the same kind, as in the binary format.
+func (p *moduleParser) declare(fields []*sexpr) error {
+	var typeCount wasm.Index
+	var importCounts [wasm.ExternTypeTag + 1]wasm.Index
+	var definitions []*sexpr
+	segments := map[*sexpr]wasm.Index{} // data and elem fields, named after memories and tables are
+	var defined *sexpr                  // the first definition, which imports can't follow
+
+	for _, field := range fields {
+		if !field.isList {
+			return field.errorf("unexpected %s: expected a module field", field)
+		}
+		switch k := field.keyword(); k {
+		case "type":
+			if err := declareName(p.typeNames, field, typeCount, "type"); err != nil {
+				return err
+			}
+			typeCount++
+		case "rec":
+			for _, t := range field.list[1:] {
+				if t.keyword() != "type" {
+					return t.errorf("unexpected %s: expected a type", t)
+				}
+				if err := declareName(p.typeNames, t, typeCount, "type"); err != nil {
+					return err
+				}
+				typeCount++
+			}
+		case "import":
+			if defined != nil {
+				return field.errorf("import after %s", defined.keyword())
+			}
+			if len(field.list) != 4 || !field.list[1].isString || !field.list[2].isString {
+				return field.errorf("invalid import: expected (import \"module\" \"name\" (desc))")
+			}
+			if err := validNames(field.list[1], field.list[2]); err != nil {
+				return err
+			}
+			desc := field.list[3]
+			et, ok := externTypeOf(desc.keyword())
+			if !ok {
+				return desc.errorf("unexpected %s: expected an import description", desc)
+			}
+			if err := p.declareItem(desc, et, importCounts[et]); err != nil {
+				return err
+			}
+			p.indexes[field] = importCounts[et]
+			importCounts[et]++
+		case "func", "table", "memory", "global", "tag":
+			et, _ := externTypeOf(k)
+			if hasInlineImport(field) {
+				if defined != nil {
+					return field.errorf("import after %s", defined.keyword())
+				}
+				if err := p.declareItem(field, et, importCounts[et]); err != nil {
+					return err
+				}
+				importCounts[et]++
+			} else {
+				definitions = append(definitions, field)
+				if defined == nil {
+					defined = field
+				}
+			}
+			p.declareSegment(field)
+		case "elem":
+			segments[field] = p.elemCount
+			p.elemCount++
+		case "data":
+			segments[field] = p.dataCount
+			p.dataCount++
+		case "export", "start":
+		default:
+			return field.errorf("unknown module field %s", field)
+		}
+	}
+
+	counts := importCounts
+	for _, field := range definitions {
+		et, _ := externTypeOf(field.keyword())
+		if err := p.declareItem(field, et, counts[et]); err != nil {
+			return err
+		}
+		counts[et]++
+	}
+	for _, field := range fields {
+		index, ok := segments[field]
+		if !ok || p.isLegacySegmentIndex(field) {
+			continue
+		}
+		var err error
+		if field.keyword() == "elem" {
+			err = declareName(p.elemNames, field, index, "elem segment")
+		} else {
+			err = declareName(p.dataNames, field, index, "data segment")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	p.elemCount, p.dataCount = 0, 0
+	return nil
+}
+
+// isLegacySegmentIndex returns true if a data or elem field begins with the index of its memory or table, as in the
+// WebAssembly 1.0 (20191205) text format, such as (data 0 (i32.const 0)) or (elem $t (i32.const 0) $f). An identifier
+// is an index, rather than the name of the segment, when it names a memory or table.
+func (p *moduleParser) isLegacySegmentIndex(field *sexpr) bool {
+	items := field.list
+	if len(items) < 3 || items[1].isList || items[1].isString || !items[2].isList || isRefType(items[2]) {
+		return false
+	}
+	switch items[2].keyword() {
+	case "memory", "table", "item":
+		return false
+	}
+	if items[1].isID() {
+		et := wasm.ExternTypeTable
+		if field.keyword() == "data" {
+			et = wasm.ExternTypeMemory
+		}
+		_, ok := p.names[et][items[1].atom]
+		return ok
+	}
+	_, ok := parseUint(items[1].atom, 32)
+	return ok
+}
+
+// declareSegment counts the segment defined inline by a table or memory field, so that the indexes of explicit
+// segments following it are correct.
+func (p *moduleParser) declareSegment(field *sexpr) {
+	for _, item := range field.list[1:] {
+		switch {
+		case field.keyword() == "table" && item.keyword() == "elem":
+			p.elemCount++
+		case field.keyword() == "memory" && item.keyword() == "data":
+			p.dataCount++
+		}
+	}
+}
+
+func (p *moduleParser) declareItem(field *sexpr, et wasm.ExternType, index wasm.Index) error {
+	p.indexes[field] = index
+	return declareName(p.names[et], field, index, wasm.ExternTypeName(et))
+}
+
+// declareName records the identifier of a field, if it has one.
+func declareName(names map[string]wasm.Index, field *sexpr, index wasm.Index, kind string) error {
+	if len(field.list) < 2 || !field.list[1].isID() {
+		return nil
+	}
+	id := field.list[1]
+	if _, ok := names[id.atom]; ok {
+		return id.errorf("duplicate %s %s", kind, id.atom)
+	}
+	names[id.atom] = index
+	return nil
+}
+
+func externTypeOf(keyword string) (wasm.ExternType, bool) {
+	switch keyword {
+	case wasm.ExternTypeFuncName:
+		return wasm.ExternTypeFunc, true
+	case wasm.ExternTypeTableName:
+		return wasm.ExternTypeTable, true
+	case wasm.ExternTypeMemoryName:
+		return wasm.ExternTypeMemory, true
+	case wasm.ExternTypeGlobalName:
+		return wasm.ExternTypeGlobal, true
+	case wasm.ExternTypeTagName:
+		return wasm.ExternTypeTag, true
+	}
+	return 0, false
+}
+
+// hasInlineImport returns true if a field is an abbreviated import, such as (func $f (import "m" "f")).
+func hasInlineImport(field *sexpr) bool {
+	for _, item := range field.list[1:] {
+		switch item.keyword() {
+		case "import":
+			return true
+		case "export":
+		default:
+			if !item.isID() {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// defineTypes parses the type fields, which must be known before parsing type uses of other fields.
+func (p *moduleParser) defineTypes(fields []*sexpr) error {
+	for _, field := range fields {
+		switch field.keyword() {
+		case "type":
+			t, err := p.parseType(field)
+			if err != nil {
+				return err
+			}
+			p.m.TypeSection = append(p.m.TypeSection, *t)
+		case "rec":
+			types := field.list[1:]
+			for i, f := range types {
+				t, err := p.parseType(f)
+				if err != nil {
+					return err
+				}
+				t.RecGroupSize, t.RecGroupPosition = len(types), i
+				p.m.TypeSection = append(p.m.TypeSection, *t)
+			}
+		}
+	}
+	return nil
+}
+
+// parseType parses (type $id? (func (param ...)* (result ...)*)).
+func (p *moduleParser) parseType(field *sexpr) (*wasm.FunctionType, error) {
+	_, i := optionalID(field.list, 1)
+	if i != len(field.list)-1 || field.list[i].keyword() != "func" {
+		return nil, field.errorf("invalid type: expected (type (func ...))")
+	}
+	fn := field.list[i]
+	_, j := optionalID(fn.list, 1)
+	tu, j, err := p.parseTypeUse(fn.list, j)
+	if err != nil {
+		return nil, err
+	}
+	if tu.explicit || j != len(fn.list) {
+		return nil, fn.errorf("invalid function type: expected params and results")
+	}
+	return &wasm.FunctionType{Params: tu.params, Results: tu.results}, nil
+}
+
+// parseField parses a module field other than a type.
+func (p *moduleParser) parseField(field *sexpr) error {
+	switch field.keyword() {
+	case "import":
+		return p.parseImport(field)
+	case "func":
+		return p.parseFunc(field)
+	case "table":
+		return p.parseTable(field)
+	case "memory":
+		return p.parseMemory(field)
+	case "global":
+		return p.parseGlobal(field)
+	case "tag":
+		return p.parseTag(field)
+	case "export":
+		return p.parseExport(field)
+	case "start":
+		return p.parseStart(field)
+	case "elem":
+		return p.parseElem(field)
+	case "data":
+		return p.parseData(field)
+	}
+	return nil // types were already defined
+}
+
+// parseImport parses (import "module" "name" (desc)), where desc is a function, table, memory, global or tag
+// without a body.
+func (p *moduleParser) parseImport(field *sexpr) error {
+	desc := field.list[3]
+	_, i := optionalID(desc.list, 1)
+	return p.parseImportDesc(desc, field.list[1].atom, field.list[2].atom, p.indexes[field], i)
+}
+
+// parseImportDesc parses the type of an import, from items[i] of its description, and adds it to the module.
+func (p *moduleParser) parseImportDesc(desc *sexpr, module, name string, index wasm.Index, i int) (err error) {
+	items := desc.list
+	imp := wasm.Import{Module: module, Name: name, IndexPerType: index}
+	switch desc.keyword() {
+	case "func":
+		imp.Type = wasm.ExternTypeFunc
+		var tu *typeUse
+		if tu, i, err = p.parseTypeUse(items, i); err != nil {
+			return err
+		}
+		if imp.DescFunc, err = p.typeUseIndex(tu, desc); err != nil {
+			return err
+		}
+		p.addFuncName(desc, index)
+		p.m.ImportFunctionCount++
+	case "table":
+		imp.Type = wasm.ExternTypeTable
+		if imp.DescTable, i, err = p.parseTableType(desc, i); err != nil {
+			return err
+		}
+		p.m.ImportTableCount++
+	case "memory":
+		imp.Type = wasm.ExternTypeMemory
+		if imp.DescMem, i, err = p.parseMemoryType(desc, i); err != nil {
+			return err
+		}
+		p.m.ImportMemoryCount++
+	case "global":
+		imp.Type = wasm.ExternTypeGlobal
+		if imp.DescGlobal, i, err = p.parseGlobalType(items, i, desc); err != nil {
+			return err
+		}
+		p.m.ImportGlobalCount++
+	case "tag":
+		imp.Type = wasm.ExternTypeTag
+		var tu *typeUse
+		if tu, i, err = p.parseTypeUse(items, i); err != nil {
+			return err
+		}
+		if imp.DescTag, err = p.typeUseIndex(tu, desc); err != nil {
+			return err
+		}
+		p.m.ImportTagCount++
+	}
+	if i != len(items) {
+		return items[i].errorf("unexpected %s in import", items[i])
+	}
+	p.m.ImportSection = append(p.m.ImportSection, imp)
+	return nil
+}
+
+// parseInlineExportsAndImport parses the abbreviated exports and import of a function, table, memory, global or tag,
+// which follow its optional identifier. This returns true if the field was an import, which was added to the module.
+func (p *moduleParser) parseInlineExportsAndImport(field *sexpr, et wasm.ExternType) (int, bool, error) {
+	items := field.list
+	index := p.indexes[field]
+	_, i := optionalID(items, 1)
+	for ; i < len(items) && items[i].keyword() == "export"; i++ {
+		e := items[i]
+		if len(e.list) != 2 || !e.list[1].isString {
+			return 0, false, e.errorf("invalid export: expected (export \"name\")")
+		}
+		if err := validNames(e.list[1]); err != nil {
+			return 0, false, err
+		}
+		p.m.ExportSection = append(p.m.ExportSection, wasm.Export{Type: et, Name: e.list[1].atom, Index: index})
+	}
+	if i < len(items) && items[i].keyword() == "import" {
+		imp := items[i]
+		if len(imp.list) != 3 || !imp.list[1].isString || !imp.list[2].isString {
+			return 0, false, imp.errorf("invalid import: expected (import \"module\" \"name\")")
+		}
+		if err := validNames(imp.list[1], imp.list[2]); err != nil {
+			return 0, false, err
+		}
+		return i, true, p.parseImportDesc(field, imp.list[1].atom, imp.list[2].atom, index, i+1)
+	}
+	return i, false, nil
+}
+
+// parseFunc parses (func $id? (export "name")* (import "module" "name")? typeuse (local ...)* instr*).
+func (p *moduleParser) parseFunc(field *sexpr) error {
+	i, imported, err := p.parseInlineExportsAndImport(field, wasm.ExternTypeFunc)
+	if err != nil || imported {
+		return err
+	}
+	index := p.indexes[field]
+	items := field.list
+	tu, i, err := p.parseTypeUse(items, i)
+	if err != nil {
+		return err
+	}
+	typeIndex, err := p.typeUseIndex(tu, field)
+	if err != nil {
+		return err
+	}
+	p.addFuncName(field, index)
+
+	fp := &funcParser{p: p, locals: map[string]wasm.Index{}}
+	var localNames wasm.NameMap
+	addLocal := func(id *sexpr) error {
+		idx := wasm.Index(len(localNames))
+		if id != nil {
+			if _, ok := fp.locals[id.atom]; ok {
+				return id.errorf("duplicate local %s", id.atom)
+			}
+			fp.locals[id.atom] = idx
+		}
+		name := ""
+		if id != nil {
+			name = id.atom[1:]
+		}
+		localNames = append(localNames, wasm.NameAssoc{Index: idx, Name: name})
+		return nil
+	}
+
+	params := tu.params
+	if int(typeIndex) < len(p.m.TypeSection) {
+		params = p.m.TypeSection[typeIndex].Params
+	}
+	for j := range params {
+		var id *sexpr
+		if j < len(tu.paramIDs) {
+			id = tu.paramIDs[j]
+		}
+		if err = addLocal(id); err != nil {
+			return err
+		}
+	}
+
+	var code wasm.Code
+	for ; i < len(items) && items[i].keyword() == "local"; i++ {
+		local := items[i]
+		ids, types, err := p.parseValueTypes(local)
+		if err != nil {
+			return err
+		}
+		for j, t := range types {
+			if err = addLocal(ids[j]); err != nil {
+				return err
+			}
+			code.LocalTypes = append(code.LocalTypes, t)
+		}
+	}
+
+	if code.Body, err = fp.parseBody(items[i:], field); err != nil {
+		return err
+	}
+
+	var named wasm.NameMap
+	for _, n := range localNames {
+		if n.Name != "" {
+			named = append(named, n)
+		}
+	}
+	if len(named) > 0 {
+		p.localNames = append(p.localNames, wasm.NameMapAssoc{Index: index, NameMap: named})
+	}
+	p.m.FunctionSection = append(p.m.FunctionSection, typeIndex)
+	p.m.CodeSection = append(p.m.CodeSection, code)
+	return nil
+}
+
+func (p *moduleParser) addFuncName(field *sexpr, index wasm.Index) {
+	if len(field.list) > 1 && field.list[1].isID() {
+		p.funcNames = append(p.funcNames, wasm.NameAssoc{Index: index, Name: field.list[1].atom[1:]})
+	}
+}
+
+// parseTable parses a table, which is either
+// (table $id? (export "name")* (import "module" "name")? limits reftype expr?), or
+// (table $id? (export "name")* reftype (elem ...)), which defines an element segment of the size of the table.
+func (p *moduleParser) parseTable(field *sexpr) error {
+	i, imported, err := p.parseInlineExportsAndImport(field, wasm.ExternTypeTable)
+	if err != nil || imported {
+		return err
+	}
+	items := field.list
+	index := p.indexes[field]
+
+	if i+2 == len(items) && items[i+1].keyword() == "elem" {
+		refType, err := p.parseValueType(items[i])
+		if err != nil {
+			return err
+		}
+		seg := wasm.ElementSegment{
+			Mode:       wasm.ElementModeActive,
+			TableIndex: index,
+			OffsetExpr: wasm.NewConstantExpressionFromI32(0),
+		}
+		if err = p.parseElemList(items[i+1].list, 1, &seg, refType, false); err != nil {
+			return err
+		}
+		size := uint32(len(seg.Init))
+		p.m.TableSection = append(p.m.TableSection, wasm.Table{Min: size, Max: &size, Type: refType})
+		p.m.ElementSection = append(p.m.ElementSection, seg)
+		p.elemCount++
+		return nil
+	}
+
+	t, i, err := p.parseTableType(field, i)
+	if err != nil {
+		return err
+	}
+	if i < len(items) {
+		expr, err := p.parseConstExpr(items[i:], field)
+		if err != nil {
+			return err
+		}
+		t.InitExpr = &expr
+	}
+	p.m.TableSection = append(p.m.TableSection, t)
+	return nil
+}
+
+// parseTableType parses the limits and reference type of a table, starting at items[i].
+func (p *moduleParser) parseTableType(field *sexpr, i int) (t wasm.Table, next int, err error) {
+	var max *uint32
+	if t.Min, max, i, err = parseLimits(field, i); err != nil {
+		return
+	}
+	t.Max = max
+	if i >= len(field.list) {
+		err = field.errorf("missing table reference type")
+		return
+	}
+	if t.Type, err = p.parseValueType(field.list[i]); err != nil {
+		return
+	}
+	return t, i + 1, nil
+}
+
+// parseMemory parses a memory, which is either
+// (memory $id? (export "name")* (import "module" "name")? limits shared?), or
+// (memory $id? (export "name")* (data "bytes"*)), which defines a data segment of the size of the memory.
+func (p *moduleParser) parseMemory(field *sexpr) error {
+	i, imported, err := p.parseInlineExportsAndImport(field, wasm.ExternTypeMemory)
+	if err != nil || imported {
+		return err
+	}
+	if p.m.MemorySection != nil || p.m.ImportMemoryCount > 0 {
+		return field.errorf("multiple memories")
+	}
+	items := field.list
+	if i+1 == len(items) && items[i].keyword() == "data" {
+		init, err := parseStrings(items[i].list[1:])
+		if err != nil {
+			return err
+		}
+		pages := (uint32(len(init)) + wasm.MemoryPageSize - 1) / wasm.MemoryPageSize
+		p.m.MemorySection = &wasm.Memory{Min: pages, Cap: pages, Max: pages, IsMaxEncoded: true}
+		p.m.DataSection = append(p.m.DataSection, wasm.DataSegment{OffsetExpression: wasm.NewConstantExpressionFromI32(0), Init: init})
+		p.dataCount++
+		return nil
+	}
+
+	mem, i, err := p.parseMemoryType(field, i)
+	if err != nil {
+		return err
+	}
+	if i != len(items) {
+		return items[i].errorf("unexpected %s in memory", items[i])
+	}
+	p.m.MemorySection = mem
+	return nil
+}
+
+// parseMemoryType parses the limits of a memory and whether it is shared, starting at items[i].
+func (p *moduleParser) parseMemoryType(field *sexpr, i int) (*wasm.Memory, int, error) {
+	if i < len(field.list) && field.list[i].isKeyword("i32") {
+		i++ // the default index type
+	}
+	min, max, i, err := parseLimits(field, i)
+	if err != nil {
+		return nil, 0, err
+	}
+	mem := &wasm.Memory{Min: min, Cap: min, Max: wasm.MemoryLimitPages}
+	if max != nil {
+		mem.Max, mem.IsMaxEncoded = *max, true
+	}
+	if i < len(field.list) && field.list[i].isKeyword("shared") {
+		mem.IsShared = true
+		i++
+	}
+	return mem, i, nil
+}
+
+// parseLimits parses the minimum and optional maximum of a table or memory, starting at items[i].
+func parseLimits(field *sexpr, i int) (min uint32, max *uint32, next int, err error) {
+	items := field.list
+	if i >= len(items) || items[i].isList || items[i].isString {
+		err = field.errorf("missing limits")
+		return
+	}
+	v, ok := parseUint(items[i].atom, 32)
+	if !ok {
+		err = items[i].errorf("invalid limit %s", items[i])
+		return
+	}
+	min, i = uint32(v), i+1
+	if i < len(items) && !items[i].isList && !items[i].isString {
+		if v, ok = parseUint(items[i].atom, 32); ok {
+			m := uint32(v)
+			max, i = &m, i+1
+		}
+	}
+	return min, max, i, nil
+}
+
+// parseGlobal parses (global $id? (export "name")* (import "module" "name")? globaltype expr).
+func (p *moduleParser) parseGlobal(field *sexpr) error {
+	i, imported, err := p.parseInlineExportsAndImport(field, wasm.ExternTypeGlobal)
+	if err != nil || imported {
+		return err
+	}
+	gt, i, err := p.parseGlobalType(field.list, i, field)
+	if err != nil {
+		return err
+	}
+	init, err := p.parseConstExpr(field.list[i:], field)
+	if err != nil {
+		return err
+	}
+	p.m.GlobalSection = append(p.m.GlobalSection, wasm.Global{Type: gt, Init: init})
+	return nil
+}
+
+// parseGlobalType parses valtype or (mut valtype) at items[i].
+func (p *moduleParser) parseGlobalType(items []*sexpr, i int, field *sexpr) (gt wasm.GlobalType, next int, err error) {
+	if i >= len(items) {
+		err = field.errorf("missing global type")
+		return
+	}
+	t := items[i]
+	if t.keyword() == "mut" {
+		if len(t.list) != 2 {
+			err = t.errorf("invalid global type: expected (mut valtype)")
+			return
+		}
+		gt.Mutable, t = true, t.list[1]
+	}
+	gt.ValType, err = p.parseValueType(t)
+	return gt, i + 1, err
+}
+
+// parseTag parses (tag $id? (export "name")* (import "module" "name")? typeuse).
+func (p *moduleParser) parseTag(field *sexpr) error {
+	i, imported, err := p.parseInlineExportsAndImport(field, wasm.ExternTypeTag)
+	if err != nil || imported {
+		return err
+	}
+	tu, i, err := p.parseTypeUse(field.list, i)
+	if err != nil {
+		return err
+	}
+	if i != len(field.list) {
+		return field.list[i].errorf("unexpected %s in tag", field.list[i])
+	}
+	typeIndex, err := p.typeUseIndex(tu, field)
+	if err != nil {
+		return err
+	}
+	p.m.TagSection = append(p.m.TagSection, wasm.Tag{Type: typeIndex})
+	return nil
+}
+
+// parseExport parses (export "name" (kind index)).
+func (p *moduleParser) parseExport(field *sexpr) error {
+	items := field.list
+	if len(items) != 3 || !items[1].isString || len(items[2].list) != 2 {
+		return field.errorf("invalid export: expected (export \"name\" (kind index))")
+	}
+	if err := validNames(items[1]); err != nil {
+		return err
+	}
+	desc := items[2]
+	et, ok := externTypeOf(desc.keyword())
+	if !ok {
+		return desc.errorf("unexpected %s: expected an export description", desc)
+	}
+	index, err := resolveIndex(p.names[et], desc.list[1], wasm.ExternTypeName(et))
+	if err != nil {
+		return err
+	}
+	p.m.ExportSection = append(p.m.ExportSection, wasm.Export{Type: et, Name: items[1].atom, Index: index})
+	return nil
+}
+
+// parseStart parses (start funcidx).
+func (p *moduleParser) parseStart(field *sexpr) error {
+	if len(field.list) != 2 {
+		return field.errorf("invalid start: expected (start funcidx)")
+	}
+	if p.m.StartSection != nil {
+		return field.errorf("multiple start sections")
+	}
+	index, err := resolveIndex(p.names[wasm.ExternTypeFunc], field.list[1], "function")
+	if err != nil {
+		return err
+	}
+	p.m.StartSection = &index
+	return nil
+}
+
+// parseElem parses an element segment, which is one of
+// (elem $id? elemlist), which is passive,
+// (elem $id? declare elemlist),
+// (elem $id? (table tableidx)? offset elemlist), where offset is (offset expr) or a folded instruction, or
+// (elem $id? offset funcidx*).
+//
+// elemlist is either func funcidx*, or reftype followed by expressions, each (item expr) or a folded instruction.
+func (p *moduleParser) parseElem(field *sexpr) error {
+	items := field.list
+	_, i := optionalID(items, 1)
+	seg := wasm.ElementSegment{Mode: wasm.ElementModePassive}
+	legacy := false
+	switch {
+	case p.isLegacySegmentIndex(field):
+		index, err := resolveIndex(p.names[wasm.ExternTypeTable], items[1], "table")
+		if err != nil {
+			return err
+		}
+		offset, err := p.parseOffset(items[2], field)
+		if err != nil {
+			return err
+		}
+		seg.Mode, seg.TableIndex, seg.OffsetExpr, legacy = wasm.ElementModeActive, index, offset, true
+		i = 3
+	case i < len(items) && items[i].isKeyword("declare"):
+		seg.Mode = wasm.ElementModeDeclarative
+		i++
+	case i < len(items) && (items[i].keyword() == "table" || (items[i].isList && !isRefType(items[i]))):
+		seg.Mode = wasm.ElementModeActive
+		legacy = items[i].keyword() != "table"
+		if !legacy {
+			if len(items[i].list) != 2 {
+				return items[i].errorf("invalid table use: expected (table tableidx)")
+			}
+			index, err := resolveIndex(p.names[wasm.ExternTypeTable], items[i].list[1], "table")
+			if err != nil {
+				return err
+			}
+			seg.TableIndex = index
+			i++
+		}
+		if i >= len(items) || !items[i].isList {
+			return field.errorf("missing element segment offset")
+		}
+		offset, err := p.parseOffset(items[i], field)
+		if err != nil {
+			return err
+		}
+		seg.OffsetExpr = offset
+		i++
+	}
+	if err := p.parseElemList(items, i, &seg, 0, legacy); err != nil {
+		return err
+	}
+	p.m.ElementSection = append(p.m.ElementSection, seg)
+	p.elemCount++
+	return nil
+}
+
+// parseElemList parses the type and initializers of an element segment starting at items[i]. When refType is
+// non-zero, it is the type of a table with an inline segment, and the list is either function indexes or
+// expressions, without a leading keyword or type. When legacy is true, func can be omitted before function indexes.
+func (p *moduleParser) parseElemList(items []*sexpr, i int, seg *wasm.ElementSegment, refType wasm.RefType, legacy bool) error {
+	funcIndexes := legacy
+	switch {
+	case refType != 0:
+		seg.Type = refType
+		funcIndexes = i == len(items) || !items[i].isList
+	case i < len(items) && items[i].isKeyword("func"):
+		funcIndexes = true
+		i++
+	case i < len(items) && isRefType(items[i]):
+		t, err := p.parseValueType(items[i])
+		if err != nil {
+			return err
+		}
+		seg.Type, funcIndexes = t, false
+		i++
+	case i == len(items) || !items[i].isList:
+		funcIndexes = true
+	}
+
+	if funcIndexes {
+		// Function indexes are non-null references, unless inline in a table of a concrete type, whose type they have.
+		seg.Type = wasm.RefTypeFuncref.AsNonNullable()
+		if refType != 0 && refType != wasm.RefTypeFuncref {
+			seg.Type = refType
+		}
+		for ; i < len(items); i++ {
+			index, err := resolveIndex(p.names[wasm.ExternTypeFunc], items[i], "function")
+			if err != nil {
+				return err
+			}
+			seg.Init = append(seg.Init, wasm.NewConstantExpressionFromOpcode(wasm.OpcodeRefFunc, leb128.EncodeUint32(index)))
+		}
+		return nil
+	}
+
+	for ; i < len(items); i++ {
+		item := items[i]
+		if !item.isList {
+			return item.errorf("unexpected %s: expected an element expression", item)
+		}
+		exprItems := []*sexpr{item}
+		if item.keyword() == "item" {
+			exprItems = item.list[1:]
+		}
+		expr, err := p.parseConstExpr(exprItems, item)
+		if err != nil {
+			return err
+		}
+		seg.Init = append(seg.Init, expr)
+	}
+	return nil
+}
+
+// isRefType returns true if s is a reference type, such as funcref or (ref null $t).
+func isRefType(s *sexpr) bool {
+	switch {
+	case s.isList:
+		return s.keyword() == "ref"
+	case s.isString:
+		return false
+	}
+	switch s.atom {
+	case "funcref", "externref", "exnref":
+		return true
+	}
+	return false
+}
+
+// parseData parses a data segment, which is either (data $id? "bytes"*), which is passive, or
+// (data $id? (memory memidx)? offset "bytes"*), where offset is (offset expr) or a folded instruction.
+func (p *moduleParser) parseData(field *sexpr) error {
+	items := field.list
+	_, i := optionalID(items, 1)
+	seg := wasm.DataSegment{Passive: true}
+	var memory *sexpr
+	if p.isLegacySegmentIndex(field) {
+		memory, i = items[1], 2
+	} else if i < len(items) && items[i].keyword() == "memory" {
+		if len(items[i].list) != 2 {
+			return items[i].errorf("invalid memory use: expected (memory memidx)")
+		}
+		memory = items[i].list[1]
+		i++
+	}
+	if memory != nil {
+		if index, err := resolveIndex(p.names[wasm.ExternTypeMemory], memory, "memory"); err != nil {
+			return err
+		} else if index != 0 {
+			return memory.errorf("unknown memory %d", index)
+		}
+		if i >= len(items) || !items[i].isList {
+			return field.errorf("missing data segment offset")
+		}
+	}
+	if i < len(items) && items[i].isList {
+		offset, err := p.parseOffset(items[i], field)
+		if err != nil {
+			return err
+		}
+		seg.Passive, seg.OffsetExpression = false, offset
+		i++
+	}
+	init, err := parseStrings(items[i:])
+	if err != nil {
+		return err
+	}
+	seg.Init = init
+	p.m.DataSection = append(p.m.DataSection, seg)
+	p.dataCount++
+	return nil
+}
+
+// parseOffset parses the offset of an active segment: (offset expr) or a folded instruction.
+func (p *moduleParser) parseOffset(offset, field *sexpr) (wasm.ConstantExpression, error) {
+	if offset.keyword() == "offset" {
+		return p.parseConstExpr(offset.list[1:], offset)
+	}
+	return p.parseConstExpr([]*sexpr{offset}, field)
+}
+
+// parseStrings returns the concatenated bytes of strings, such as the content of a data segment.
+func parseStrings(items []*sexpr) ([]byte, error) {
+	var b []byte
+	for _, s := range items {
+		if !s.isString {
+			return nil, s.errorf("unexpected %s: expected a string", s)
+		}
+		b = append(b, s.atom...)
+	}
+	return b, nil
+}
+
+// typeUse is a reference to a function type, by index, inline parameters and results, or both.
+type typeUse struct {
+	index    wasm.Index
+	explicit bool
+
+	params  []wasm.ValueType
+	results []wasm.ValueType
+	// paramIDs are the identifiers of params, or nil for those without.
+	paramIDs []*sexpr
+}
+
+// parseTypeUse parses (type typeidx)? (param ...)* (result ...)* starting at items[i].
+func (p *moduleParser) parseTypeUse(items []*sexpr, i int) (*typeUse, int, error) {
+	tu := &typeUse{}
+	if i < len(items) && items[i].keyword() == "type" {
+		t := items[i]
+		if len(t.list) != 2 {
+			return nil, 0, t.errorf("invalid type use: expected (type typeidx)")
+		}
+		index, err := p.typeIndex(t.list[1])
+		if err != nil {
+			return nil, 0, err
+		}
+		tu.index, tu.explicit = index, true
+		i++
+	}
+	for ; i < len(items) && items[i].keyword() == "param"; i++ {
+		ids, types, err := p.parseValueTypes(items[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		tu.params = append(tu.params, types...)
+		tu.paramIDs = append(tu.paramIDs, ids...)
+	}
+	for ; i < len(items) && items[i].keyword() == "result"; i++ {
+		ids, types, err := p.parseValueTypes(items[i])
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(ids) > 0 && ids[0] != nil {
+			return nil, 0, ids[0].errorf("unexpected identifier in result")
+		}
+		tu.results = append(tu.results, types...)
+	}
+	return tu, i, nil
+}
+
+// parseValueTypes parses the types of (param ...), (result ...) or (local ...), which are either an identifier
+// followed by a single type, or a list of types. The identifiers are nil for types without.
+func (p *moduleParser) parseValueTypes(s *sexpr) (ids []*sexpr, types []wasm.ValueType, err error) {
+	items := s.list[1:]
+	if len(items) > 0 && items[0].isID() {
+		if len(items) != 2 {
+			return nil, nil, s.errorf("invalid %s: expected an identifier and a single type", s.keyword())
+		}
+		t, err := p.parseValueType(items[1])
+		return []*sexpr{items[0]}, []wasm.ValueType{t}, err
+	}
+	for _, item := range items {
+		t, err := p.parseValueType(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		ids, types = append(ids, nil), append(types, t)
+	}
+	return ids, types, nil
+}
+
+// typeUseIndex returns the index of the function type of a type use. Inline types without an index are matched
+// against existing types, and added to the module if none matches.
+func (p *moduleParser) typeUseIndex(tu *typeUse, s *sexpr) (wasm.Index, error) {
+	if tu.explicit {
+		if int(tu.index) >= len(p.m.TypeSection) {
+			if len(tu.params) > 0 || len(tu.results) > 0 {
+				return 0, s.errorf("unknown type %d", tu.index)
+			}
+			return tu.index, nil // unknown types are reported by validation, like other unknown indexes
+		}
+		t := &p.m.TypeSection[tu.index]
+		if (len(tu.params) > 0 || len(tu.results) > 0) && !t.EqualsSignature(tu.params, tu.results) {
+			return 0, s.errorf("inline function type doesn't match type %d", tu.index)
+		}
+		return tu.index, nil
+	}
+	for i := range p.m.TypeSection {
+		t := &p.m.TypeSection[i]
+		if t.RecGroupSize <= 1 && t.EqualsSignature(tu.params, tu.results) {
+			return wasm.Index(i), nil
+		}
+	}
+	p.m.TypeSection = append(p.m.TypeSection, wasm.FunctionType{Params: tu.params, Results: tu.results})
+	return wasm.Index(len(p.m.TypeSection) - 1), nil
+}
+
+// typeIndex resolves the index of a type, by identifier or number.
+func (p *moduleParser) typeIndex(s *sexpr) (wasm.Index, error) {
+	return resolveIndex(p.typeNames, s, "type")
+}
+
+// parseValueType parses a value type, such as i32, funcref, or (ref null $t).
+func (p *moduleParser) parseValueType(s *sexpr) (wasm.ValueType, error) {
+	if !s.isList && !s.isString {
+		switch s.atom {
+		case "i32":
+			return wasm.ValueTypeI32, nil
+		case "i64":
+			return wasm.ValueTypeI64, nil
+		case "f32":
+			return wasm.ValueTypeF32, nil
+		case "f64":
+			return wasm.ValueTypeF64, nil
+		case "v128":
+			return wasm.ValueTypeV128, nil
+		case "funcref":
+			return wasm.ValueTypeFuncref, nil
+		case "externref":
+			return wasm.ValueTypeExternref, nil
+		case "exnref":
+			return wasm.ValueTypeExnref, nil
+		}
+	}
+	if s.keyword() != "ref" {
+		return 0, s.errorf("unknown value type %s", s)
+	}
+	items := s.list[1:]
+	nullable := len(items) > 0 && items[0].isKeyword("null")
+	if nullable {
+		items = items[1:]
+	}
+	if len(items) != 1 {
+		return 0, s.errorf("invalid reference type: expected (ref null? heaptype)")
+	}
+	var t wasm.ValueType
+	switch {
+	case items[0].isKeyword("func"):
+		t = wasm.ValueTypeFuncref
+	case items[0].isKeyword("extern"):
+		t = wasm.ValueTypeExternref
+	case items[0].isKeyword("exn"):
+		t = wasm.ValueTypeExnref
+	default:
+		index, err := p.typeIndex(items[0])
+		if err != nil {
+			return 0, err
+		}
+		return wasm.ValueTypeConcreteRef(index, nullable), nil
+	}
+	if !nullable {
+		t = t.AsNonNullable()
+	}
+	return t, nil
+}
+
+// parseHeapType parses the heap type of ref.null, returning its signed LEB128 encoding.
+func (p *moduleParser) parseHeapType(s *sexpr) ([]byte, error) {
+	var ht int64
+	switch {
+	case s.isKeyword("func"):
+		ht = wasm.HeapTypeFunc
+	case s.isKeyword("extern"):
+		ht = wasm.HeapTypeExtern
+	case s.isKeyword("exn"):
+		ht = wasm.HeapTypeExn
+	default:
+		index, err := p.typeIndex(s)
+		if err != nil {
+			return nil, err
+		}
+		ht = int64(index)
+	}
+	return leb128.EncodeInt64(ht), nil
+}
+
+// resolveIndex resolves an index by identifier, using names, or number.
+func resolveIndex(names map[string]wasm.Index, s *sexpr, kind string) (wasm.Index, error) {
+	if s.isID() {
+		index, ok := names[s.atom]
+		if !ok {
+			return 0, s.errorf("unknown %s %s", kind, s.atom)
+		}
+		return index, nil
+	}
+	if s.isList || s.isString {
+		return 0, s.errorf("unexpected %s: expected a %s index", s, kind)
+	}
+	v, ok := parseUint(s.atom, 32)
+	if !ok {
+		return 0, s.errorf("invalid %s index %s", kind, s.atom)
+	}
+	return wasm.Index(v), nil
+}
+
+// validNames returns an error if any of the strings of import or export names isn't valid UTF-8.
+func validNames(names ...*sexpr) error {
+	for _, s := range names {
+		if !utf8.ValidString(s.atom) {
+			return s.errorf("malformed UTF-8 encoding in name %q", s.atom)
+		}
+	}
+	return nil
+}
+
+// optionalID returns the identifier at items[i], if any, and the index after it.
+func optionalID(items []*sexpr, i int) (string, int) {
+	if i < len(items) && items[i].isID() {
+		return items[i].atom, i + 1
+	}
+	return "", i
+}
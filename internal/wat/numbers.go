@@ -0,0 +1,192 @@
+package wat
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseUint parses an unsigned integer literal of at most the given number of bits, in decimal or hexadecimal with
+// a 0x prefix, with optional underscores between digits.
+// See https://webassembly.github.io/spec/core/text/values.html#integers
+func parseUint(s string, bits int) (uint64, bool) {
+	base := 10
+	if strings.HasPrefix(s, "0x") {
+		s, base = s[2:], 16
+	}
+	digits, ok := stripUnderscores(s, base == 16)
+	if !ok {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(digits, base, bits)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+// parseInt parses the literal of an integer of the given number of bits, which is either unsigned or signed with a
+// leading + or -, returning its two's complement bits.
+func parseInt(s string, bits int) (uint64, bool) {
+	if s == "" {
+		return 0, false
+	}
+	neg := s[0] == '-'
+	if !neg && s[0] != '+' {
+		return parseUint(s, bits)
+	}
+	mag, ok := parseUint(s[1:], bits)
+	if !ok {
+		return 0, false
+	}
+	limit := uint64(1) << (bits - 1)
+	if neg {
+		if mag > limit {
+			return 0, false
+		}
+		v := -mag
+		if bits < 64 {
+			v &= 1<<bits - 1
+		}
+		return v, true
+	}
+	if mag >= limit {
+		return 0, false
+	}
+	return mag, true
+}
+
+// stripUnderscores returns s without underscores, or false if s isn't a non-empty sequence of digits where each
+// underscore is between two digits.
+func stripUnderscores(s string, hex bool) (string, bool) {
+	isDigit := func(c byte) bool {
+		if hex {
+			_, ok := hexDigit(c)
+			return ok
+		}
+		return '0' <= c && c <= '9'
+	}
+	if s == "" || !isDigit(s[0]) || !isDigit(s[len(s)-1]) {
+		return "", false
+	}
+	for i := 1; i < len(s)-1; i++ {
+		if c := s[i]; c == '_' && (!isDigit(s[i-1]) || !isDigit(s[i+1])) {
+			return "", false
+		} else if c != '_' && !isDigit(c) {
+			return "", false
+		}
+	}
+	return strings.ReplaceAll(s, "_", ""), true
+}
+
+// parseF32 parses a float literal into the bits of a float32.
+func parseF32(s string) (uint32, bool) {
+	v, ok := parseFloat(s, 32)
+	return uint32(v), ok
+}
+
+// parseF64 parses a float literal into the bits of a float64.
+func parseF64(s string) (uint64, bool) {
+	return parseFloat(s, 64)
+}
+
+// parseFloat parses a float literal into the bits of a float of the given size, which is 32 or 64.
+// See https://webassembly.github.io/spec/core/text/values.html#floating-point
+func parseFloat(s string, bits int) (uint64, bool) {
+	var sign uint64
+	body := s
+	if body != "" && (body[0] == '+' || body[0] == '-') {
+		if body[0] == '-' {
+			sign = 1 << (bits - 1)
+		}
+		body = body[1:]
+	}
+
+	mantissaBits := 52
+	if bits == 32 {
+		mantissaBits = 23
+	}
+	expMask := uint64(1)<<(bits-1) - 1 ^ (uint64(1)<<mantissaBits - 1)
+	switch {
+	case body == "inf":
+		return sign | expMask, true
+	case body == "nan":
+		return sign | expMask | 1<<(mantissaBits-1), true
+	case strings.HasPrefix(body, "nan:0x"):
+		payload, ok := parseUint(body[4:], mantissaBits)
+		if !ok || payload == 0 {
+			return 0, false
+		}
+		return sign | expMask | payload, true
+	}
+
+	normalized, ok := normalizeFloat(body)
+	if !ok {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(normalized, bits)
+	if err != nil {
+		return 0, false
+	}
+	if bits == 32 {
+		return sign | uint64(math.Float32bits(float32(f))), true
+	}
+	return sign | math.Float64bits(f), true
+}
+
+// normalizeFloat validates an unsigned float literal other than inf or nan, and returns it in a form accepted by
+// strconv.ParseFloat.
+func normalizeFloat(s string) (string, bool) {
+	hex := strings.HasPrefix(s, "0x")
+	if hex {
+		s = s[2:]
+	}
+	expChars := "eE"
+	if hex {
+		expChars = "pP"
+	}
+
+	mantissa, exp := s, ""
+	if i := strings.IndexAny(s, expChars); i >= 0 {
+		mantissa, exp = s[:i], s[i+1:]
+		expSign := ""
+		if exp != "" && (exp[0] == '+' || exp[0] == '-') {
+			expSign, exp = exp[:1], exp[1:]
+		}
+		digits, ok := stripUnderscores(exp, false)
+		if !ok {
+			return "", false
+		}
+		exp = expSign + digits
+	}
+
+	intPart, fracPart, hasDot := strings.Cut(mantissa, ".")
+	intPart, ok := stripUnderscores(intPart, hex)
+	if !ok {
+		return "", false
+	}
+	if hasDot && fracPart != "" {
+		if fracPart, ok = stripUnderscores(fracPart, hex); !ok {
+			return "", false
+		}
+	}
+
+	var b strings.Builder
+	if hex {
+		b.WriteString("0x")
+	}
+	b.WriteString(intPart)
+	if fracPart != "" {
+		b.WriteByte('.')
+		b.WriteString(fracPart)
+	}
+	switch {
+	case exp != "" && hex:
+		b.WriteString("p" + exp)
+	case exp != "":
+		b.WriteString("e" + exp)
+	case hex: // strconv.ParseFloat requires an exponent in hexadecimal.
+		b.WriteString("p0")
+	}
+	return b.String(), true
+}
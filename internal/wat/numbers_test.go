@@ -0,0 +1,114 @@
+package wat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestParseInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		bits     int
+		expected uint64
+		ok       bool
+	}{
+		{input: "0", bits: 32, expected: 0, ok: true},
+		{input: "42", bits: 32, expected: 42, ok: true},
+		{input: "+42", bits: 32, expected: 42, ok: true},
+		{input: "-1", bits: 32, expected: 0xffffffff, ok: true},
+		{input: "-1", bits: 64, expected: math.MaxUint64, ok: true},
+		{input: "0xffff_ffff", bits: 32, expected: 0xffffffff, ok: true},
+		{input: "1_000_000", bits: 32, expected: 1000000, ok: true},
+		{input: "-0x8000_0000", bits: 32, expected: 0x80000000, ok: true},
+		{input: "4294967295", bits: 32, expected: 0xffffffff, ok: true},
+		{input: "4294967296", bits: 32},
+		{input: "+2147483648", bits: 32},
+		{input: "-2147483649", bits: 32},
+		{input: "1__0", bits: 32},
+		{input: "_1", bits: 32},
+		{input: "1_", bits: 32},
+		{input: "0x", bits: 32},
+		{input: "", bits: 32},
+		{input: "-", bits: 32},
+		{input: "1a", bits: 32},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.input, func(t *testing.T) {
+			v, ok := parseInt(tc.input, tc.bits)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestParseF32(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint32
+		ok       bool
+	}{
+		{input: "0", expected: 0, ok: true},
+		{input: "-0", expected: 0x80000000, ok: true},
+		{input: "1.5", expected: math.Float32bits(1.5), ok: true},
+		{input: "1e10", expected: math.Float32bits(1e10), ok: true},
+		{input: "1_000.000_1", expected: math.Float32bits(1000.0001), ok: true},
+		{input: "0x1p-1", expected: math.Float32bits(0.5), ok: true},
+		{input: "0x1.8", expected: math.Float32bits(1.5), ok: true},
+		{input: "-0x1.fffffep127", expected: math.Float32bits(-math.MaxFloat32), ok: true},
+		{input: "inf", expected: 0x7f800000, ok: true},
+		{input: "-inf", expected: 0xff800000, ok: true},
+		{input: "nan", expected: 0x7fc00000, ok: true},
+		{input: "-nan", expected: 0xffc00000, ok: true},
+		{input: "nan:0x200000", expected: 0x7fa00000, ok: true},
+		{input: "-nan:0x7f_ffff", expected: 0xffffffff, ok: true},
+		{input: "nan:0x0"},
+		{input: "nan:0x800000"},
+		{input: "1e"},
+		{input: "1._5"},
+		{input: "0x1p"},
+		{input: "."},
+		{input: "infinity"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.input, func(t *testing.T) {
+			v, ok := parseF32(tc.input)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, v)
+		})
+	}
+}
+
+func TestParseF64(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected uint64
+		ok       bool
+	}{
+		{input: "0.1", expected: math.Float64bits(0.1), ok: true},
+		{input: "-1.", expected: math.Float64bits(-1), ok: true},
+		{input: "0x1P+1", expected: math.Float64bits(2), ok: true},
+		{input: "1e-1", expected: math.Float64bits(0.1), ok: true},
+		{input: "nan", expected: 0x7ff8000000000000, ok: true},
+		{input: "nan:0xc000000000001", expected: 0x7ffc000000000001, ok: true},
+		{input: "nan:0x10000000000000"},
+		{input: "0x"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.input, func(t *testing.T) {
+			v, ok := parseF64(tc.input)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.expected, v)
+		})
+	}
+}
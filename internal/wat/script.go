@@ -0,0 +1,464 @@
+package wat
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+)
+
+// Script is a WebAssembly script, the superset of the text format used by the specification tests (.wast files). It
+// defines modules, and actions and assertions on them.
+//
+// See https://github.com/WebAssembly/spec/tree/main/interpreter#scripts
+type Script struct {
+	Commands []*Command
+}
+
+// Command types, which are the keyword of each command, except for actions.
+const (
+	CommandModule               = "module"
+	CommandRegister             = "register"
+	CommandAction               = "action"
+	CommandAssertReturn         = "assert_return"
+	CommandAssertTrap           = "assert_trap"
+	CommandAssertExhaustion     = "assert_exhaustion"
+	CommandAssertException      = "assert_exception"
+	CommandAssertMalformed      = "assert_malformed"
+	CommandAssertInvalid        = "assert_invalid"
+	CommandAssertUnlinkable     = "assert_unlinkable"
+	CommandAssertUninstantiable = "assert_uninstantiable"
+)
+
+// Command is a command of a script.
+type Command struct {
+	// Line is the line of the command in the script, starting at one.
+	Line int
+
+	// Type is one of the Command constants, such as CommandAssertReturn.
+	Type string
+
+	// Module is the module defined by CommandModule, or asserted on by commands such as CommandAssertInvalid. This
+	// is also set for CommandAssertTrap on a module, whose start function traps.
+	Module *Module
+
+	// Name is the identifier of the module registered by CommandRegister, or empty for the last module.
+	Name string
+
+	// As is the name CommandRegister registers a module as, for imports of later modules.
+	As string
+
+	// Action is the action of CommandAction, CommandAssertReturn and assertions on actions, such as
+	// CommandAssertTrap.
+	Action *Action
+
+	// Expected are the results of CommandAssertReturn.
+	Expected []Value
+
+	// Text is the expected error of assertions, such as "integer divide by zero".
+	Text string
+}
+
+// Module is a module of a script, in the text or binary format.
+type Module struct {
+	// Line is the line of the module in the script, starting at one.
+	Line int
+
+	// ID is the identifier of the module, such as $M, used by actions and CommandRegister, or empty.
+	ID string
+
+	// Binary is the module defined with (module binary "..."), or nil.
+	Binary []byte
+
+	// Quote is the source of the module defined with (module quote "..."), which is only parsed by Encode, so that
+	// CommandAssertMalformed can assert its syntax error. This is nil for other modules.
+	Quote []byte
+
+	// fields are the fields of a module in the text format.
+	fields []*sexpr
+}
+
+// Encode returns the module in the binary format, parsing it if it's in the text format.
+func (m *Module) Encode() ([]byte, error) {
+	switch {
+	case m.Binary != nil:
+		return m.Binary, nil
+	case m.Quote != nil:
+		return Compile(m.Quote)
+	}
+	parsed, err := parseModule(m.fields)
+	if err != nil {
+		return nil, err
+	}
+	return binaryencoding.EncodeModule(parsed), nil
+}
+
+// Action invokes an exported function or gets the value of an exported global.
+type Action struct {
+	// Type is either "invoke" or "get".
+	Type string
+
+	// Module is the identifier of the module, or empty for the last module defined.
+	Module string
+
+	// Field is the name of the export.
+	Field string
+
+	// Args are the arguments of an invoked function.
+	Args []Value
+}
+
+// NaN is a pattern matching NaN results, which can't be represented by constants.
+type NaN byte
+
+const (
+	// NaNNone is a value which isn't a NaN pattern.
+	NaNNone NaN = iota
+	// NaNCanonical matches a NaN whose payload only has its most significant bit set, with either sign.
+	NaNCanonical
+	// NaNArithmetic matches a NaN whose payload has its most significant bit set, with either sign.
+	NaNArithmetic
+)
+
+// Value is a constant argument or expected result of an action.
+type Value struct {
+	// Type is the type of the value. This is the abstract reference type for references, such as
+	// wasm.ValueTypeExternref, or zero for a (ref.null) result, which matches a null reference of any type.
+	Type wasm.ValueType
+
+	// Lo are the bits of the value. Hi are the high 64 bits of a v128.
+	Lo, Hi uint64
+
+	// Shape is the lane shape of a v128, such as "f32x4". Results with a float shape compare each lane as a float,
+	// and may have NaN patterns.
+	Shape string
+
+	// NaN are the NaN patterns of a float result, with one per lane for a v128. These are nil if there are none.
+	NaN []NaN
+
+	// Null is true for a null reference.
+	Null bool
+
+	// AnyRef is true for a result which matches any non-null reference of Type, such as (ref.func).
+	AnyRef bool
+
+	// Either are the alternatives of an (either ...) result, which matches if any alternative does.
+	Either []Value
+}
+
+// ParseScript parses a WebAssembly script, such as a .wast file of the specification tests.
+func ParseScript(source []byte) (*Script, error) {
+	top, err := parseSExprs(source)
+	if err != nil {
+		return nil, err
+	}
+	script := &Script{}
+	if len(top) > 0 && isModuleField(top[0]) { // a script can be the fields of a single module
+		m := &Module{Line: top[0].line, fields: top}
+		script.Commands = append(script.Commands, &Command{Line: m.Line, Type: CommandModule, Module: m})
+		return script, nil
+	}
+	for _, s := range top {
+		cmd, err := parseCommand(s)
+		if err != nil {
+			return nil, err
+		}
+		script.Commands = append(script.Commands, cmd)
+	}
+	return script, nil
+}
+
+// isModuleField returns true if s is a field of a module, such as (func ...).
+func isModuleField(s *sexpr) bool {
+	switch s.keyword() {
+	case "type", "rec", "import", "func", "table", "memory", "global", "tag", "export", "start", "elem", "data":
+		return true
+	}
+	return false
+}
+
+func parseCommand(s *sexpr) (*Command, error) {
+	cmd := &Command{Line: s.line, Type: s.keyword()}
+	items := s.list
+	var err error
+	switch cmd.Type {
+	case CommandModule:
+		cmd.Module, err = parseScriptModule(s)
+	case CommandRegister:
+		if len(items) < 2 || len(items) > 3 || !items[1].isString || (len(items) == 3 && !items[2].isID()) {
+			return nil, s.errorf("invalid register: expected (register \"name\" $module?)")
+		}
+		cmd.As = items[1].atom
+		if len(items) == 3 {
+			cmd.Name = items[2].atom
+		}
+	case "invoke", "get":
+		cmd.Type = CommandAction
+		cmd.Action, err = parseAction(s)
+	case CommandAssertReturn:
+		if len(items) < 2 {
+			return nil, s.errorf("missing action")
+		}
+		if cmd.Action, err = parseAction(items[1]); err != nil {
+			return nil, err
+		}
+		for _, r := range items[2:] {
+			v, err := parseValue(r, true)
+			if err != nil {
+				return nil, err
+			}
+			cmd.Expected = append(cmd.Expected, v)
+		}
+	case CommandAssertTrap, CommandAssertExhaustion, CommandAssertException:
+		if len(items) < 2 {
+			return nil, s.errorf("missing action")
+		}
+		if cmd.Type == CommandAssertTrap && items[1].keyword() == "module" {
+			cmd.Module, err = parseScriptModule(items[1])
+		} else {
+			cmd.Action, err = parseAction(items[1])
+		}
+		if err == nil && cmd.Type != CommandAssertException {
+			cmd.Text, err = parseText(s, items)
+		}
+	case CommandAssertMalformed, CommandAssertInvalid, CommandAssertUnlinkable, CommandAssertUninstantiable:
+		if len(items) < 2 || items[1].keyword() != "module" {
+			return nil, s.errorf("missing module")
+		}
+		if cmd.Module, err = parseScriptModule(items[1]); err == nil {
+			cmd.Text, err = parseText(s, items)
+		}
+	default:
+		return nil, s.errorf("unknown command %s", s)
+	}
+	return cmd, err
+}
+
+// parseText returns the expected error, which is the third item of an assertion.
+func parseText(s *sexpr, items []*sexpr) (string, error) {
+	if len(items) != 3 || !items[2].isString {
+		return "", s.errorf("invalid %s: expected a failure message", s.keyword())
+	}
+	return items[2].atom, nil
+}
+
+// parseScriptModule parses (module $id? field*), (module $id? binary "..."*) or (module $id? quote "..."*).
+func parseScriptModule(s *sexpr) (*Module, error) {
+	m := &Module{Line: s.line}
+	items := s.list[1:]
+	if len(items) > 0 && items[0].isID() {
+		m.ID, items = items[0].atom, items[1:]
+	}
+	if len(items) == 0 || (!items[0].isKeyword("binary") && !items[0].isKeyword("quote")) {
+		m.fields = items
+		return m, nil
+	}
+	b, err := parseStrings(items[1:])
+	if err != nil {
+		return nil, err
+	}
+	if b == nil { // so that empty modules aren't mistaken for text
+		b = []byte{}
+	}
+	if items[0].atom == "binary" {
+		m.Binary = b
+	} else {
+		m.Quote = b
+	}
+	return m, nil
+}
+
+// parseAction parses (invoke $module? "name" const*) or (get $module? "name").
+func parseAction(s *sexpr) (*Action, error) {
+	a := &Action{Type: s.keyword()}
+	if a.Type != "invoke" && a.Type != "get" {
+		return nil, s.errorf("unexpected %s: expected an action", s)
+	}
+	items := s.list[1:]
+	if len(items) > 0 && items[0].isID() {
+		a.Module, items = items[0].atom, items[1:]
+	}
+	if len(items) == 0 || !items[0].isString {
+		return nil, s.errorf("missing export name")
+	}
+	a.Field = items[0].atom
+	for _, arg := range items[1:] {
+		v, err := parseValue(arg, false)
+		if err != nil {
+			return nil, err
+		}
+		a.Args = append(a.Args, v)
+	}
+	if a.Type == "get" && len(a.Args) > 0 {
+		return nil, s.errorf("unexpected arguments of get")
+	}
+	return a, nil
+}
+
+// parseValue parses a constant, such as (i32.const 1). Results can also be NaN patterns, such as
+// (f32.const nan:canonical), references of any value, such as (ref.extern), or (either ...).
+func parseValue(s *sexpr, result bool) (v Value, err error) {
+	items := s.list
+	op := s.keyword()
+	if op == "" {
+		return v, s.errorf("unexpected %s: expected a constant", s)
+	}
+	args := items[1:]
+	arg := func() (*sexpr, error) {
+		if len(args) != 1 || args[0].isList || args[0].isString {
+			return nil, s.errorf("invalid %s: expected a single value", op)
+		}
+		return args[0], nil
+	}
+
+	var a *sexpr
+	switch op {
+	case "i32.const", "i64.const":
+		v.Type, v.Shape = wasm.ValueTypeI32, "i32"
+		bits := 32
+		if op == "i64.const" {
+			v.Type, v.Shape, bits = wasm.ValueTypeI64, "i64", 64
+		}
+		if a, err = arg(); err == nil {
+			var ok bool
+			if v.Lo, ok = parseInt(a.atom, bits); !ok {
+				err = a.errorf("invalid %s %s", v.Shape, a.atom)
+			}
+		}
+	case "f32.const", "f64.const":
+		v.Type, v.Shape = wasm.ValueTypeF32, "f32"
+		if op == "f64.const" {
+			v.Type, v.Shape = wasm.ValueTypeF64, "f64"
+		}
+		if a, err = arg(); err == nil {
+			var nan NaN
+			v.Lo, nan, err = parseFloatValue(a, v.Shape, result)
+			if nan != NaNNone {
+				v.NaN = []NaN{nan}
+			}
+		}
+	case "v128.const":
+		v.Type = wasm.ValueTypeV128
+		err = parseV128Value(s, args, result, &v)
+	case "ref.null":
+		v.Null = true
+		if result && len(args) == 0 {
+			break // matches a null reference of any type
+		}
+		if a, err = arg(); err == nil {
+			switch a.atom {
+			case "func":
+				v.Type = wasm.ValueTypeFuncref
+			case "extern":
+				v.Type = wasm.ValueTypeExternref
+			case "exn":
+				v.Type = wasm.ValueTypeExnref
+			default:
+				err = a.errorf("unknown heap type %s", a.atom)
+			}
+		}
+	case "ref.extern", "ref.host", "ref.func":
+		v.Type = wasm.ValueTypeExternref
+		if op == "ref.func" {
+			v.Type = wasm.ValueTypeFuncref
+		}
+		if result && len(args) == 0 {
+			v.AnyRef = true
+		} else if a, err = arg(); err == nil {
+			var ok bool
+			if v.Lo, ok = parseUint(a.atom, 32); !ok {
+				err = a.errorf("invalid reference %s", a.atom)
+			}
+		}
+	case "either":
+		if !result {
+			return v, s.errorf("unexpected either in arguments")
+		}
+		for _, alt := range args {
+			e, err := parseValue(alt, true)
+			if err != nil {
+				return v, err
+			}
+			v.Either = append(v.Either, e)
+		}
+		if len(v.Either) == 0 {
+			return v, s.errorf("missing alternatives of either")
+		}
+		v.Type = v.Either[0].Type
+	default:
+		return v, s.errorf("unknown constant %s", op)
+	}
+	return v, err
+}
+
+// parseFloatValue parses a float, or a NaN pattern if result is true.
+func parseFloatValue(s *sexpr, shape string, result bool) (uint64, NaN, error) {
+	if result {
+		switch s.atom {
+		case "nan:canonical":
+			return 0, NaNCanonical, nil
+		case "nan:arithmetic":
+			return 0, NaNArithmetic, nil
+		}
+	}
+	bits := 64
+	if shape == "f32" {
+		bits = 32
+	}
+	v, ok := parseFloat(s.atom, bits)
+	if !ok {
+		return 0, NaNNone, s.errorf("invalid %s %s", shape, s.atom)
+	}
+	return v, NaNNone, nil
+}
+
+// parseV128Value parses the shape and lanes of (v128.const shape lane*).
+func parseV128Value(s *sexpr, args []*sexpr, result bool, v *Value) error {
+	if len(args) == 0 || args[0].isList || args[0].isString {
+		return s.errorf("missing shape of v128.const")
+	}
+	v.Shape = args[0].atom
+	if !strings.HasPrefix(v.Shape, "f") {
+		b, _, err := parseV128(s.list[0], args, 0)
+		if err != nil {
+			return err
+		}
+		v.Lo, v.Hi = binary.LittleEndian.Uint64(b[:8]), binary.LittleEndian.Uint64(b[8:])
+		return nil
+	}
+
+	var laneShape string
+	var lanes int
+	switch v.Shape {
+	case "f32x4":
+		laneShape, lanes = "f32", 4
+	case "f64x2":
+		laneShape, lanes = "f64", 2
+	default:
+		return args[0].errorf("unknown vector shape %s", v.Shape)
+	}
+	if len(args) != lanes+1 {
+		return s.errorf("expected %d lanes of %s", lanes, v.Shape)
+	}
+	laneBits := 128 / lanes
+	hasNaN := false
+	nans := make([]NaN, lanes)
+	for i, lane := range args[1:] {
+		bits, nan, err := parseFloatValue(lane, laneShape, result)
+		if err != nil {
+			return err
+		}
+		nans[i], hasNaN = nan, hasNaN || nan != NaNNone
+		offset := i * laneBits
+		if offset < 64 {
+			v.Lo |= bits << offset
+		} else {
+			v.Hi |= bits << (offset - 64)
+		}
+	}
+	if hasNaN {
+		v.NaN = nans
+	}
+	return nil
+}
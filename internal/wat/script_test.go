@@ -0,0 +1,120 @@
+package wat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+func TestParseScript(t *testing.T) {
+	script, err := ParseScript([]byte(`
+(module $M (func (export "add") (param i32 i32) (result i32) (i32.add (local.get 0) (local.get 1))))
+(register "math" $M)
+(module binary "\00asm" "\01\00\00\00")
+(assert_return (invoke $M "add" (i32.const 1) (i32.const -1)) (i32.const 0))
+(assert_return (invoke "f") (f32.const nan:canonical) (either (i64.const 1) (i64.const 2)))
+(assert_return (invoke "g") (v128.const f64x2 nan:arithmetic 1.0) (ref.null func) (ref.extern))
+(assert_trap (invoke "div" (i32.const 1) (i32.const 0)) "integer divide by zero")
+(assert_malformed (module quote "(func") "unclosed parenthesis")
+(assert_invalid (module (func (result i32))) "type mismatch")
+(get "global")
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, 10, len(script.Commands))
+	types := make([]string, 0, len(script.Commands))
+	for _, c := range script.Commands {
+		types = append(types, c.Type)
+	}
+	require.Equal(t, []string{
+		CommandModule, CommandRegister, CommandModule, CommandAssertReturn, CommandAssertReturn, CommandAssertReturn,
+		CommandAssertTrap, CommandAssertMalformed, CommandAssertInvalid, CommandAction,
+	}, types)
+
+	t.Run("text module", func(t *testing.T) {
+		c := script.Commands[0]
+		require.Equal(t, 2, c.Line)
+		require.Equal(t, "$M", c.Module.ID)
+		bin, err := c.Module.Encode()
+		require.NoError(t, err)
+		expected, err := Compile([]byte(`(func (export "add") (param i32 i32) (result i32) (i32.add (local.get 0) (local.get 1)))`))
+		require.NoError(t, err)
+		require.Equal(t, expected, bin)
+	})
+
+	t.Run("register", func(t *testing.T) {
+		c := script.Commands[1]
+		require.Equal(t, "math", c.As)
+		require.Equal(t, "$M", c.Name)
+	})
+
+	t.Run("binary module", func(t *testing.T) {
+		bin, err := script.Commands[2].Module.Encode()
+		require.NoError(t, err)
+		require.Equal(t, []byte("\x00asm\x01\x00\x00\x00"), bin)
+	})
+
+	t.Run("assert_return", func(t *testing.T) {
+		c := script.Commands[3]
+		require.Equal(t, &Action{Type: "invoke", Module: "$M", Field: "add", Args: []Value{
+			{Type: wasm.ValueTypeI32, Shape: "i32", Lo: 1},
+			{Type: wasm.ValueTypeI32, Shape: "i32", Lo: 0xffffffff},
+		}}, c.Action)
+		require.Equal(t, []Value{{Type: wasm.ValueTypeI32, Shape: "i32"}}, c.Expected)
+	})
+
+	t.Run("assert_return patterns", func(t *testing.T) {
+		require.Equal(t, []Value{
+			{Type: wasm.ValueTypeF32, Shape: "f32", NaN: []NaN{NaNCanonical}},
+			{Type: wasm.ValueTypeI64, Either: []Value{
+				{Type: wasm.ValueTypeI64, Shape: "i64", Lo: 1},
+				{Type: wasm.ValueTypeI64, Shape: "i64", Lo: 2},
+			}},
+		}, script.Commands[4].Expected)
+		require.Equal(t, []Value{
+			{Type: wasm.ValueTypeV128, Shape: "f64x2", Hi: math.Float64bits(1), NaN: []NaN{NaNArithmetic, NaNNone}},
+			{Type: wasm.ValueTypeFuncref, Null: true},
+			{Type: wasm.ValueTypeExternref, AnyRef: true},
+		}, script.Commands[5].Expected)
+	})
+
+	t.Run("assert_trap", func(t *testing.T) {
+		c := script.Commands[6]
+		require.Equal(t, "div", c.Action.Field)
+		require.Equal(t, "integer divide by zero", c.Text)
+	})
+
+	t.Run("assert_malformed", func(t *testing.T) {
+		c := script.Commands[7]
+		require.Equal(t, "unclosed parenthesis", c.Text)
+		_, err := c.Module.Encode()
+		require.EqualError(t, err, "1:1: unclosed parenthesis")
+	})
+
+	t.Run("get", func(t *testing.T) {
+		require.Equal(t, &Action{Type: "get", Field: "global"}, script.Commands[9].Action)
+	})
+}
+
+func TestParseScript_Errors(t *testing.T) {
+	tests := []struct {
+		name, input, expectedErr string
+	}{
+		{name: "unknown command", input: "(assert_foo)", expectedErr: "1:1: unknown command (assert_foo ...)"},
+		{name: "invalid register", input: "(register $M)", expectedErr: `1:1: invalid register: expected (register "name" $module?)`},
+		{name: "missing export", input: "(invoke)", expectedErr: "1:1: missing export name"},
+		{name: "invalid argument", input: `(invoke "f" (i32.const x))`, expectedErr: "1:24: invalid i32 x"},
+		{name: "pattern argument", input: `(invoke "f" (f32.const nan:canonical))`, expectedErr: "1:24: invalid f32 nan:canonical"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseScript([]byte(tc.input))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
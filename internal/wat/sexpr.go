@@ -0,0 +1,316 @@
+package wat
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// sexpr is a node of the S-expression tree of a text module or script: either an atom, such as a keyword, number or
+// identifier, a string, or a parenthesized list.
+type sexpr struct {
+	line, col int
+
+	// atom is the text of an atom, or the decoded bytes of a string.
+	atom string
+
+	// isString is true when atom was a quoted string.
+	isString bool
+
+	// isList is true for a parenthesized list, whose items are in list.
+	isList bool
+	list   []*sexpr
+}
+
+// keyword returns the leading keyword of a list, or the empty string.
+func (s *sexpr) keyword() string {
+	if !s.isList || len(s.list) == 0 || s.list[0].isList || s.list[0].isString {
+		return ""
+	}
+	return s.list[0].atom
+}
+
+// isKeyword returns true if s is an atom with the given text.
+func (s *sexpr) isKeyword(k string) bool {
+	return !s.isList && !s.isString && s.atom == k
+}
+
+// isID returns true if s is an identifier, such as $x.
+func (s *sexpr) isID() bool {
+	return !s.isList && !s.isString && len(s.atom) > 1 && s.atom[0] == '$'
+}
+
+// String implements fmt.Stringer for error messages.
+func (s *sexpr) String() string {
+	switch {
+	case s.isList:
+		if k := s.keyword(); k != "" {
+			return "(" + k + " ...)"
+		}
+		return "(...)"
+	case s.isString:
+		return strconv.Quote(s.atom)
+	default:
+		return s.atom
+	}
+}
+
+// errorf returns an error prefixed with the position of s in the source.
+func (s *sexpr) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Line: s.line, Col: s.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// SyntaxError is an error in the text format, at the given line and column of the source, both starting at one.
+type SyntaxError struct {
+	Line, Col int
+	Msg       string
+}
+
+// Error implements error.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+}
+
+// parseSExprs parses the source into a list of top-level S-expressions, skipping comments and annotations.
+func parseSExprs(source []byte) ([]*sexpr, error) {
+	l := &lexer{src: source, line: 1, col: 1}
+	var stack [][]*sexpr
+	var open []*sexpr
+	var top []*sexpr
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case tok == nil:
+			if len(open) > 0 {
+				o := open[len(open)-1]
+				return nil, o.errorf("unclosed parenthesis")
+			}
+			return top, nil
+		case tok.isList: // open parenthesis
+			stack = append(stack, top)
+			open = append(open, tok)
+			top = nil
+		case tok.atom == ")" && !tok.isString:
+			if len(open) == 0 {
+				return nil, tok.errorf("unexpected closing parenthesis")
+			}
+			list := open[len(open)-1]
+			list.list = top
+			open = open[:len(open)-1]
+			top = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			// Annotations, such as (@custom ...), have no meaning to the module and are skipped.
+			if k := list.keyword(); !strings.HasPrefix(k, "@") {
+				top = append(top, list)
+			}
+		default:
+			top = append(top, tok)
+		}
+	}
+}
+
+// lexer splits the source into tokens: an open parenthesis is returned as an empty list and a closing one as an atom.
+type lexer struct {
+	src       []byte
+	pos       int
+	line, col int
+}
+
+func (l *lexer) advance(n int) {
+	for _, c := range l.src[l.pos : l.pos+n] {
+		if c == '\n' {
+			l.line++
+			l.col = 1
+		} else {
+			l.col++
+		}
+	}
+	l.pos += n
+}
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return &SyntaxError{Line: l.line, Col: l.col, Msg: fmt.Sprintf(format, args...)}
+}
+
+// next returns the next token, or nil at the end of the source.
+func (l *lexer) next() (*sexpr, error) {
+	if err := l.skipSpace(); err != nil {
+		return nil, err
+	}
+	if l.pos >= len(l.src) {
+		return nil, nil
+	}
+	tok := &sexpr{line: l.line, col: l.col}
+	switch c := l.src[l.pos]; c {
+	case '(':
+		tok.isList = true
+		l.advance(1)
+	case ')':
+		tok.atom = ")"
+		l.advance(1)
+	case '"':
+		s, err := l.string()
+		if err != nil {
+			return nil, err
+		}
+		tok.atom, tok.isString = s, true
+	default:
+		start := l.pos
+		for l.pos < len(l.src) && isIDChar(l.src[l.pos]) {
+			l.advance(1)
+		}
+		if l.pos == start {
+			return nil, l.errorf("unexpected character %q", c)
+		}
+		tok.atom = string(l.src[start:l.pos])
+	}
+	// Strings and atoms must be separated from the next token, for example "a""b" is malformed.
+	if tok.isString || (!tok.isList && tok.atom != ")") {
+		if c := l.peek(0); l.pos < len(l.src) && c != ' ' && c != '\t' && c != '\n' && c != '\r' && c != '(' && c != ')' && c != ';' {
+			return nil, l.errorf("unexpected character %q after %s", c, tok)
+		}
+	}
+	return tok, nil
+}
+
+// isIDChar returns true if c can be part of a keyword, number or identifier.
+// See https://webassembly.github.io/spec/core/text/values.html#text-idchar
+func isIDChar(c byte) bool {
+	if c <= ' ' || c >= 0x7f {
+		return false
+	}
+	switch c {
+	case '"', ',', ';', '(', ')', '[', ']', '{', '}':
+		return false
+	}
+	return true
+}
+
+// skipSpace skips white space, line comments and block comments, which can be nested.
+func (l *lexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			l.advance(1)
+		case c == ';' && l.peek(1) == ';':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.advance(1)
+			}
+		case c == '(' && l.peek(1) == ';':
+			line, col := l.line, l.col
+			l.advance(2)
+			for depth := 1; depth > 0; {
+				switch {
+				case l.pos >= len(l.src):
+					return &SyntaxError{Line: line, Col: col, Msg: "unclosed block comment"}
+				case l.src[l.pos] == '(' && l.peek(1) == ';':
+					depth++
+					l.advance(2)
+				case l.src[l.pos] == ';' && l.peek(1) == ')':
+					depth--
+					l.advance(2)
+				default:
+					l.advance(1)
+				}
+			}
+		case c == ';':
+			return l.errorf("unexpected character ';'")
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func (l *lexer) peek(n int) byte {
+	if l.pos+n < len(l.src) {
+		return l.src[l.pos+n]
+	}
+	return 0
+}
+
+// string reads a quoted string, decoding its escapes.
+// See https://webassembly.github.io/spec/core/text/values.html#strings
+func (l *lexer) string() (string, error) {
+	line, col := l.line, l.col
+	l.advance(1) // opening quote
+	var b strings.Builder
+	for {
+		if l.pos >= len(l.src) || l.src[l.pos] == '\n' {
+			return "", &SyntaxError{Line: line, Col: col, Msg: "unclosed string"}
+		}
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.advance(1)
+			return b.String(), nil
+		case c == '\\':
+			if err := l.escape(&b); err != nil {
+				return "", err
+			}
+		case c < ' ' || c == 0x7f:
+			return "", l.errorf("illegal control character in string")
+		default:
+			l.advance(1)
+			b.WriteByte(c)
+		}
+	}
+}
+
+func (l *lexer) escape(b *strings.Builder) error {
+	switch c := l.peek(1); c {
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case '"', '\'', '\\':
+		b.WriteByte(c)
+	case 'u':
+		if l.peek(2) != '{' {
+			return l.errorf("illegal escape in string")
+		}
+		end := l.pos + 3
+		for end < len(l.src) && l.src[end] != '}' {
+			end++
+		}
+		if end >= len(l.src) {
+			return l.errorf("illegal escape in string")
+		}
+		r, err := strconv.ParseUint(strings.ReplaceAll(string(l.src[l.pos+3:end]), "_", ""), 16, 32)
+		if err != nil || r > utf8.MaxRune || (r >= 0xd800 && r < 0xe000) {
+			return l.errorf("illegal unicode escape in string")
+		}
+		b.WriteRune(rune(r))
+		l.advance(end + 1 - l.pos)
+		return nil
+	default:
+		hi, ok1 := hexDigit(c)
+		lo, ok2 := hexDigit(l.peek(2))
+		if !ok1 || !ok2 {
+			return l.errorf("illegal escape in string")
+		}
+		b.WriteByte(hi<<4 | lo)
+		l.advance(3)
+		return nil
+	}
+	l.advance(2)
+	return nil
+}
+
+func hexDigit(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
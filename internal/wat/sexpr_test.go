@@ -0,0 +1,84 @@
+package wat
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/tetratelabs/wazero/internal/testing/require"
+)
+
+func TestParseSExprs(t *testing.T) {
+	tests := []struct {
+		name, input, expected string
+	}{
+		{name: "empty", input: "", expected: ""},
+		{name: "atoms", input: "a $b 1.5 -0x1", expected: `a $b 1.5 -0x1`},
+		{name: "lists", input: "(module (func $f (nop)))", expected: `(module (func $f (nop)))`},
+		{name: "empty list", input: "()", expected: `()`},
+		{name: "line comment", input: "(a ;; comment\n b)", expected: `(a b)`},
+		{name: "line comment ending with carriage return", input: "(a ;; comment\rb)", expected: `(a b)`},
+		{name: "block comment", input: "(a (; comment ;) b)", expected: `(a b)`},
+		{name: "nested block comment", input: "(a (; outer (; inner ;) ;)b)", expected: `(a b)`},
+		{name: "annotation", input: "(module (@custom \"x\" (after func)) (func))", expected: `(module (func))`},
+		{name: "string", input: `"hello"`, expected: `"hello"`},
+		{name: "escapes", input: `"\t\n\r\"\'\\\00\ff"`, expected: strconv.Quote("\t\n\r\"'\\\x00\xff")},
+		{name: "unicode escape", input: `"\u{1F600}\u{41}"`, expected: strconv.Quote("\U0001F600A")},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			top, err := parseSExprs([]byte(tc.input))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, dumpSExprs(top))
+		})
+	}
+}
+
+func TestParseSExprs_Errors(t *testing.T) {
+	tests := []struct {
+		name, input, expectedErr string
+	}{
+		{name: "unclosed list", input: "(module\n  (func)", expectedErr: "1:1: unclosed parenthesis"},
+		{name: "unexpected close", input: "(a))", expectedErr: "1:4: unexpected closing parenthesis"},
+		{name: "unclosed string", input: `(a "b)`, expectedErr: "1:4: unclosed string"},
+		{name: "unclosed block comment", input: "(; a", expectedErr: "1:1: unclosed block comment"},
+		{name: "illegal escape", input: `"\q"`, expectedErr: "1:2: illegal escape in string"},
+		{name: "illegal unicode escape", input: `"\u{d800}"`, expectedErr: "1:2: illegal unicode escape in string"},
+		{name: "control character", input: "\"a\tb\"", expectedErr: "1:3: illegal control character in string"},
+		{name: "unseparated strings", input: `(data "a""b")`, expectedErr: `1:10: unexpected character '"' after "a"`},
+		{name: "unseparated atom", input: `(data $l"a")`, expectedErr: `1:9: unexpected character '"' after $l`},
+		{name: "semicolon", input: "(a ;b)", expectedErr: "1:4: unexpected character ';'"},
+		{name: "unexpected character", input: "(a {)", expectedErr: "1:4: unexpected character '{'"},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseSExprs([]byte(tc.input))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+// dumpSExprs formats S-expressions as text, so that tests can compare them.
+func dumpSExprs(list []*sexpr) string {
+	var b strings.Builder
+	for i, s := range list {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		switch {
+		case s.isList:
+			b.WriteString("(" + dumpSExprs(s.list) + ")")
+		case s.isString:
+			b.WriteString(strconv.Quote(s.atom))
+		default:
+			b.WriteString(s.atom)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,39 @@
+// Package wat parses the WebAssembly text format, including scripts used by the specification tests.
+//
+// The text format supports the instructions and syntax of the features supported by wazero, such as SIMD, threads,
+// exception handling, tail calls and typed function references. Modules are parsed into a wasm.Module, which is not
+// validated: encode it with binaryencoding.EncodeModule and compile the result to validate it.
+//
+// See https://webassembly.github.io/spec/core/text/index.html
+package wat
+
+import (
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+)
+
+// ParseModule parses a module in the text format, such as "(module (func (export \"f\")))". The module keyword can be
+// omitted, in which case the source is the fields of the module.
+func ParseModule(source []byte) (*wasm.Module, error) {
+	top, err := parseSExprs(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(top) == 1 && top[0].keyword() == "module" {
+		items := top[0].list[1:]
+		if _, i := optionalID(items, 0); i < len(items) && (items[i].isKeyword("binary") || items[i].isKeyword("quote")) {
+			return nil, items[i].errorf("unexpected %s module: expected a text module", items[i].atom)
+		}
+		return parseModule(items)
+	}
+	return parseModule(top)
+}
+
+// Compile parses a module in the text format and encodes it in the binary format.
+func Compile(source []byte) ([]byte, error) {
+	m, err := ParseModule(source)
+	if err != nil {
+		return nil, err
+	}
+	return binaryencoding.EncodeModule(m), nil
+}
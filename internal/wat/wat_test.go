@@ -0,0 +1,239 @@
+package wat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binary"
+)
+
+func TestParseModule(t *testing.T) {
+	i32, i64 := wasm.ValueTypeI32, wasm.ValueTypeI64
+	one := uint32(1)
+	two := uint32(2)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected *wasm.Module
+	}{
+		{
+			name:     "empty",
+			input:    "(module)",
+			expected: &wasm.Module{},
+		},
+		{
+			name:     "fields without module",
+			input:    "(memory 1)",
+			expected: &wasm.Module{MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: wasm.MemoryLimitPages}},
+		},
+		{
+			name: "function",
+			input: `(module $math
+  (func $add (export "add") (param $x i32) (param $y i32) (result i32) (local $tmp i64)
+    (i32.add (local.get $x) (local.get $y))))`,
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32, i32}, Results: []wasm.ValueType{i32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection: []wasm.Code{{
+					LocalTypes: []wasm.ValueType{i64},
+					Body: []byte{
+						wasm.OpcodeLocalGet, 0, wasm.OpcodeLocalGet, 1, wasm.OpcodeI32Add, wasm.OpcodeEnd,
+					},
+				}},
+				ExportSection: []wasm.Export{{Type: wasm.ExternTypeFunc, Name: "add", Index: 0}},
+				NameSection: &wasm.NameSection{
+					ModuleName:    "math",
+					FunctionNames: wasm.NameMap{{Index: 0, Name: "add"}},
+					LocalNames: wasm.IndirectNameMap{{Index: 0, NameMap: wasm.NameMap{
+						{Index: 0, Name: "x"}, {Index: 1, Name: "y"}, {Index: 2, Name: "tmp"},
+					}}},
+				},
+			},
+		},
+		{
+			name: "imports are indexed first",
+			input: `(module
+  (import "env" "log" (func $log (param i32)))
+  (func $main (call $log (i32.const 42)))
+  (start $main))`,
+			expected: &wasm.Module{
+				TypeSection: []wasm.FunctionType{{Params: []wasm.ValueType{i32}}, {}},
+				ImportSection: []wasm.Import{
+					{Type: wasm.ExternTypeFunc, Module: "env", Name: "log", DescFunc: 0},
+				},
+				ImportFunctionCount: 1,
+				FunctionSection:     []wasm.Index{1},
+				CodeSection: []wasm.Code{{
+					Body: []byte{wasm.OpcodeI32Const, 42, wasm.OpcodeCall, 0, wasm.OpcodeEnd},
+				}},
+				StartSection: &one,
+				NameSection: &wasm.NameSection{
+					FunctionNames: wasm.NameMap{{Index: 0, Name: "log"}, {Index: 1, Name: "main"}},
+				},
+			},
+		},
+		{
+			name: "blocks",
+			input: `(func (param i32) (result i32)
+  (block $done (result i32)
+    (if (local.get 0) (then (br $done (i32.const 1))))
+    loop $l
+      (br_if $l (local.get 0))
+    end
+    i32.const 0))`,
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{Params: []wasm.ValueType{i32}, Results: []wasm.ValueType{i32}}},
+				FunctionSection: []wasm.Index{0},
+				CodeSection: []wasm.Code{{
+					Body: []byte{
+						wasm.OpcodeBlock, 0x7f,
+						wasm.OpcodeLocalGet, 0, wasm.OpcodeIf, 0x40, wasm.OpcodeI32Const, 1, wasm.OpcodeBr, 1, wasm.OpcodeEnd,
+						wasm.OpcodeLoop, 0x40, wasm.OpcodeLocalGet, 0, wasm.OpcodeBrIf, 0, wasm.OpcodeEnd,
+						wasm.OpcodeI32Const, 0,
+						wasm.OpcodeEnd,
+						wasm.OpcodeEnd,
+					},
+				}},
+			},
+		},
+		{
+			name: "memory and data",
+			input: `(module
+  (memory (export "mem") 1 2)
+  (data (i32.const 8) "hi" "\00")
+  (data $passive "abc")
+  (func (memory.init $passive (i32.const 0) (i32.const 0) (i32.const 3)) (data.drop 1)))`,
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				MemorySection:   &wasm.Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true},
+				ExportSection:   []wasm.Export{{Type: wasm.ExternTypeMemory, Name: "mem", Index: 0}},
+				CodeSection: []wasm.Code{{
+					Body: []byte{
+						wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 0, wasm.OpcodeI32Const, 3,
+						wasm.OpcodeMiscPrefix, wasm.OpcodeMiscMemoryInit, 1, 0,
+						wasm.OpcodeMiscPrefix, wasm.OpcodeMiscDataDrop, 1,
+						wasm.OpcodeEnd,
+					},
+				}},
+				DataSection: []wasm.DataSegment{
+					{OffsetExpression: wasm.NewConstantExpressionFromI32(8), Init: []byte("hi\x00")},
+					{Passive: true, Init: []byte("abc")},
+				},
+				DataCountSection: &two,
+			},
+		},
+		{
+			name:  "table with inline elements",
+			input: `(module (table $t funcref (elem $f $f)) (func $f))`,
+			expected: &wasm.Module{
+				TypeSection:     []wasm.FunctionType{{}},
+				FunctionSection: []wasm.Index{0},
+				TableSection:    []wasm.Table{{Min: 2, Max: &two, Type: wasm.RefTypeFuncref}},
+				ElementSection: []wasm.ElementSegment{{
+					OffsetExpr: wasm.NewConstantExpressionFromI32(0),
+					Init: []wasm.ConstantExpression{
+						wasm.NewConstantExpressionFromOpcode(wasm.OpcodeRefFunc, []byte{0}),
+						wasm.NewConstantExpressionFromOpcode(wasm.OpcodeRefFunc, []byte{0}),
+					},
+					Type: wasm.RefTypeFuncref.AsNonNullable(),
+					Mode: wasm.ElementModeActive,
+				}},
+				CodeSection: []wasm.Code{{Body: []byte{wasm.OpcodeEnd}}},
+				NameSection: &wasm.NameSection{FunctionNames: wasm.NameMap{{Index: 0, Name: "f"}}},
+			},
+		},
+		{
+			name:  "globals",
+			input: `(module (global $g (mut i32) (i32.const -1)) (global i64 (global.get $g)))`,
+			expected: &wasm.Module{
+				GlobalSection: []wasm.Global{
+					{Type: wasm.GlobalType{ValType: i32, Mutable: true}, Init: wasm.NewConstantExpressionFromI32(-1)},
+					{Type: wasm.GlobalType{ValType: i64}, Init: wasm.NewConstantExpressionFromOpcode(wasm.OpcodeGlobalGet, []byte{0})},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := ParseModule([]byte(tc.input))
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, m)
+		})
+	}
+}
+
+func TestParseModule_Errors(t *testing.T) {
+	tests := []struct {
+		name, input, expectedErr string
+	}{
+		{name: "binary module", input: `(module binary "\00asm")`, expectedErr: "1:9: unexpected binary module: expected a text module"},
+		{name: "unknown field", input: "(module (fun))", expectedErr: "1:9: unknown module field (fun ...)"},
+		{name: "unknown instruction", input: "(func i32.foo)", expectedErr: "1:7: unknown instruction i32.foo"},
+		{name: "unknown local", input: "(func (local.get $x))", expectedErr: "1:18: unknown local $x"},
+		{name: "unknown function", input: "(func (call $f))", expectedErr: "1:13: unknown function $f"},
+		{name: "unknown label", input: "(func (br $l))", expectedErr: "1:11: unknown label $l"},
+		{name: "duplicate function", input: "(func $f) (func $f)", expectedErr: "1:17: duplicate func $f"},
+		{name: "import after function", input: `(func) (import "" "" (func))`, expectedErr: "1:8: import after func"},
+		{name: "invalid i32", input: "(func (i32.const 4294967296))", expectedErr: "1:18: invalid i32 4294967296"},
+		{name: "unclosed block", input: "(func block)", expectedErr: "1:1: unclosed block"},
+		{name: "mismatched label", input: "(func block $a end $b)", expectedErr: "1:20: mismatching label $b"},
+		{name: "multiple memories", input: "(memory 1) (memory 1)", expectedErr: "1:12: multiple memories"},
+		{name: "invalid name", input: `(func (export "\ff"))`, expectedErr: `1:15: malformed UTF-8 encoding in name "\xff"`},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseModule([]byte(tc.input))
+			require.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+// TestCompile_spectest ensures every text module of the specification tests compiles, unless it is asserted to be
+// malformed, and that valid modules decode.
+func TestCompile_spectest(t *testing.T) {
+	files, err := filepath.Glob("../integration_test/spectest/*/testdata/*.wast")
+	require.NoError(t, err)
+	require.NotEqual(t, 0, len(files))
+
+	features := api.CoreFeaturesV2 | experimental.CoreFeaturesThreads | experimental.CoreFeaturesTailCall |
+		experimental.CoreFeaturesExtendedConst | experimental.CoreFeaturesExceptionHandling |
+		experimental.CoreFeaturesTypedFunctionReferences
+	for _, file := range files {
+		file := file
+		name, _ := filepath.Rel("../integration_test/spectest", file)
+		t.Run(name, func(t *testing.T) {
+			source, err := os.ReadFile(file)
+			require.NoError(t, err)
+			script, err := ParseScript(source)
+			require.NoError(t, err)
+
+			for _, c := range script.Commands {
+				if c.Module == nil || c.Module.Binary != nil {
+					continue
+				}
+				bin, err := c.Module.Encode()
+				switch c.Type {
+				case CommandAssertMalformed:
+					require.Error(t, err, "line %d", c.Line)
+				case CommandModule:
+					require.NoError(t, err, "line %d", c.Line)
+					_, err = binary.DecodeModule(bin, features, wasm.MemoryLimitPages, false, false, false)
+					require.NoError(t, err, "line %d", c.Line)
+				}
+			}
+		})
+	}
+}
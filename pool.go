@@ -0,0 +1,271 @@
+package wazero
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/experimental"
+	"github.com/tetratelabs/wazero/internal/expctxkeys"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/wasm"
+)
+
+// ModulePool hands out instances of the same CompiledModule, resetting them
+// to their state just after instantiation when returned, as opposed to
+// instantiating a new module per use.
+//
+// Resetting restores memory, globals, tables and dropped segments captured
+// after start functions ran, and replaces the system state (e.g. open files)
+// with a new one built from the ModuleConfig. This is typically much cheaper
+// than InstantiateModule when start functions do non-trivial work, such as
+// initializing a language runtime.
+//
+// On Linux, memory is reset by mapping its state after instantiation
+// copy-on-write, so Put costs time proportional to the pages written since
+// Get, not to the size of memory. Elsewhere, or when the context has an
+// experimental.MemoryAllocator, Put copies memory in full. In either case,
+// each instance retains a copy of its memory as of instantiation, excluding
+// trailing zeros.
+//
+// Here's an example:
+//
+//	pool, _ := wazero.NewModulePool(ctx, r, compiled,
+//		wazero.NewModuleConfig().WithStartFunctions("_initialize"), 0)
+//	defer pool.Close(ctx)
+//
+//	mod, _ := pool.Get(ctx)
+//	defer pool.Put(ctx, mod)
+//	_, err := mod.ExportedFunction("handle").Call(ctx)
+//
+// # Notes
+//
+//   - This is an interface for decoupling, not third-party implementations.
+//     All implementations are in wazero.
+//   - Instances are anonymous, so cannot be imported by other modules.
+//   - State outside the module is not reset, such as imported memories,
+//     globals or tables, or anything held by host functions.
+//   - A module that exits during instantiation, such as a WASI command
+//     calling proc_exit from "_start", cannot be pooled. Use
+//     ModuleConfig.WithStartFunctions to run a reactor's "_initialize"
+//     instead.
+type ModulePool interface {
+	// Get returns an idle instance, or instantiates a new one if there are
+	// none. The instance must be returned with Put, or closed, when no longer
+	// used.
+	//
+	// Note: Instances are not safe for concurrent use, so the same instance is
+	// never returned by Get until it is passed to Put.
+	Get(ctx context.Context) (api.Module, error)
+
+	// Put resets an instance returned by Get and makes it available for reuse.
+	//
+	// The instance is closed instead when it was closed already, for example
+	// by the guest calling proc_exit, when the pool has maxIdle instances or
+	// when the pool is closed.
+	//
+	// Note: The instance must not be executing any function.
+	Put(ctx context.Context, mod api.Module) error
+
+	// Stats returns a snapshot of counters describing the use of this pool.
+	Stats() ModulePoolStats
+
+	// Close closes all idle instances. Instances subsequently passed to Put
+	// are closed instead of pooled.
+	api.Closer
+}
+
+// ModulePoolStats are counters maintained by a ModulePool.
+type ModulePoolStats struct {
+	// Instantiated is the count of instances created by Get.
+	Instantiated uint64
+	// Reused is the count of Get calls returning a reset instance.
+	Reused uint64
+	// Discarded is the count of instances closed by Put.
+	Discarded uint64
+	// Idle is the count of instances currently available to Get.
+	Idle int
+}
+
+// NewModulePool returns a ModulePool of instances of the given module.
+//
+// Parameters
+//
+//   - r: the runtime which compiled the module.
+//   - compiled: the module to instantiate, which must outlive the pool.
+//   - config: the configuration of each instance. The name is ignored.
+//   - maxIdle: the number of instances to retain for reuse. Zero is
+//     unbounded.
+func NewModulePool(_ context.Context, r Runtime, compiled CompiledModule, config ModuleConfig, maxIdle int) (ModulePool, error) {
+	if maxIdle < 0 {
+		return nil, errors.New("maxIdle must not be negative")
+	}
+	if c, ok := compiled.(*compiledModule); !ok {
+		return nil, errors.New("compiled module must be from Runtime.CompileModule")
+	} else if c.module.IsHostModule {
+		return nil, errors.New("host modules cannot be pooled")
+	}
+	return &modulePool{
+		runtime:   r,
+		compiled:  compiled,
+		config:    config.WithName("").(*moduleConfig),
+		maxIdle:   maxIdle,
+		instances: map[*wasm.ModuleInstance]*pooledModule{},
+	}, nil
+}
+
+// modulePool implements ModulePool
+type modulePool struct {
+	runtime  Runtime
+	compiled CompiledModule
+	config   *moduleConfig
+	maxIdle  int
+
+	mux sync.Mutex
+	// idle are reset instances, used last in first out to favor warm caches.
+	idle []*wasm.ModuleInstance
+	// instances are all instances created by this pool which aren't closed.
+	instances map[*wasm.ModuleInstance]*pooledModule
+	stats     ModulePoolStats
+	closed    bool
+}
+
+// Get implements ModulePool.Get
+func (p *modulePool) Get(ctx context.Context) (api.Module, error) {
+	p.mux.Lock()
+	if p.closed {
+		p.mux.Unlock()
+		return nil, errors.New("module pool closed")
+	}
+	if n := len(p.idle); n > 0 {
+		m := p.idle[n-1]
+		p.idle[n-1] = nil
+		p.idle = p.idle[:n-1]
+		p.stats.Reused++
+		p.mux.Unlock()
+		return m, nil
+	}
+	p.mux.Unlock()
+
+	// Clone the config, as instantiation retains socket configuration from
+	// the context, which is needed to reset the instance later.
+	config := p.config.clone()
+	if _, ok := ctx.Value(expctxkeys.MemoryAllocatorKey{}).(experimental.MemoryAllocator); !ok && platform.MemoryImageSupported {
+		ctx = experimental.WithMemoryAllocator(ctx, wasm.SnapshotMemoryAllocator)
+	}
+	mod, err := p.runtime.InstantiateModule(ctx, p.compiled, config)
+	if err != nil {
+		return nil, err
+	}
+	m := mod.(*wasm.ModuleInstance)
+	if m.IsClosed() {
+		return nil, errors.New("module closed during instantiation, so cannot be pooled")
+	}
+	pm := &pooledModule{config: config, snapshot: m.Snapshot()}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.instances[m] = pm
+	p.stats.Instantiated++
+	return m, nil
+}
+
+// Put implements ModulePool.Put
+func (p *modulePool) Put(ctx context.Context, mod api.Module) error {
+	m, ok := mod.(*wasm.ModuleInstance)
+	if !ok {
+		return errors.New("module not from this pool")
+	}
+
+	p.mux.Lock()
+	pm, ok := p.instances[m]
+	if !ok {
+		p.mux.Unlock()
+		return errors.New("module not from this pool")
+	}
+	if p.closed || m.IsClosed() || (p.maxIdle > 0 && len(p.idle) >= p.maxIdle) {
+		p.discard(m)
+		p.mux.Unlock()
+		return m.Close(ctx)
+	}
+	p.mux.Unlock()
+
+	if err := pm.reset(m); err != nil {
+		p.mux.Lock()
+		p.discard(m)
+		p.mux.Unlock()
+		_ = m.Close(ctx) // Don't overwrite the error
+		return err
+	}
+
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.closed { // closed concurrently
+		p.discard(m)
+		return m.Close(ctx)
+	}
+	p.idle = append(p.idle, m)
+	return nil
+}
+
+// pooledModule is the state needed to reset an instance created by a
+// modulePool.
+type pooledModule struct {
+	config   *moduleConfig
+	snapshot *wasm.InstanceSnapshot
+}
+
+// reset restores the snapshot taken after instantiating m, and replaces its
+// system state with a new one.
+func (pm *pooledModule) reset(m *wasm.ModuleInstance) error {
+	if err := m.Restore(pm.snapshot); err != nil {
+		return err
+	}
+	if err := m.Sys.FS().Close(); err != nil {
+		return err
+	}
+	sysCtx, err := pm.config.toSysContext()
+	if err != nil {
+		return err
+	}
+	m.Sys = sysCtx
+	return nil
+}
+
+// discard forgets m, which the caller closes. This must be called under the
+// lock.
+func (p *modulePool) discard(m *wasm.ModuleInstance) {
+	_ = p.instances[m].snapshot.Close()
+	delete(p.instances, m)
+	p.stats.Discarded++
+}
+
+// Stats implements ModulePool.Stats
+func (p *modulePool) Stats() ModulePoolStats {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	ret := p.stats
+	ret.Idle = len(p.idle)
+	return ret
+}
+
+// Close implements api.Closer embedded in ModulePool.
+func (p *modulePool) Close(ctx context.Context) (err error) {
+	p.mux.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	for _, m := range idle {
+		_ = p.instances[m].snapshot.Close()
+		delete(p.instances, m)
+	}
+	p.mux.Unlock()
+
+	for _, m := range idle {
+		if e := m.Close(ctx); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
+}
@@ -0,0 +1,159 @@
+package wazero
+
+import (
+	"testing"
+
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/internal/platform"
+	"github.com/tetratelabs/wazero/internal/testing/require"
+	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
+)
+
+// poolWasm has a memory initialized to "hi" and a global set to 42 by
+// "_initialize", both exported.
+var poolWasm = binaryencoding.EncodeModule(&wasm.Module{
+	TypeSection:     []wasm.FunctionType{{}},
+	FunctionSection: []wasm.Index{0},
+	CodeSection: []wasm.Code{{Body: []byte{
+		wasm.OpcodeI32Const, 42,
+		wasm.OpcodeGlobalSet, 0,
+		wasm.OpcodeEnd,
+	}}},
+	MemorySection: &wasm.Memory{Min: 1, Cap: 1, Max: 2, IsMaxEncoded: true},
+	GlobalSection: []wasm.Global{{
+		Type: wasm.GlobalType{ValType: wasm.ValueTypeI32, Mutable: true},
+		Init: wasm.NewConstantExpressionFromI32(7),
+	}},
+	DataSection: []wasm.DataSegment{{
+		OffsetExpression: wasm.NewConstantExpressionFromI32(0),
+		Init:             []byte("hi"),
+	}},
+	ExportSection: []wasm.Export{
+		{Name: "_initialize", Type: wasm.ExternTypeFunc, Index: 0},
+		{Name: "memory", Type: wasm.ExternTypeMemory, Index: 0},
+		{Name: "global", Type: wasm.ExternTypeGlobal, Index: 0},
+	},
+})
+
+func TestModulePool_Reset(t *testing.T) {
+	t.Run("interpreter", func(t *testing.T) {
+		testModulePoolReset(t, NewRuntimeConfigInterpreter())
+	})
+	if platform.CompilerSupported() {
+		t.Run("compiler", func(t *testing.T) {
+			testModulePoolReset(t, NewRuntimeConfigCompiler())
+		})
+	}
+}
+
+func testModulePoolReset(t *testing.T, config RuntimeConfig) {
+	r := NewRuntimeWithConfig(testCtx, config)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, poolWasm)
+	require.NoError(t, err)
+
+	pool, err := NewModulePool(testCtx, r, compiled, NewModuleConfig().WithStartFunctions("_initialize"), 0)
+	require.NoError(t, err)
+	defer pool.Close(testCtx)
+
+	requireInitialState := func(mod api.Module) {
+		require.Equal(t, uint64(42), mod.ExportedGlobal("global").Get())
+		mem := mod.ExportedMemory("memory")
+		require.Equal(t, uint32(wasm.MemoryPageSize), mem.Size())
+		b, ok := mem.Read(0, mem.Size())
+		require.True(t, ok)
+		require.Equal(t, []byte("hi"), b[:2])
+		require.Equal(t, make([]byte, len(b)-2), b[2:])
+	}
+
+	mod, err := pool.Get(testCtx)
+	require.NoError(t, err)
+	requireInitialState(mod)
+
+	// Mutate the instance, including growing memory.
+	mod.ExportedGlobal("global").(api.MutableGlobal).Set(1)
+	mem := mod.ExportedMemory("memory")
+	require.True(t, mem.Write(0, []byte("bye")))
+	_, ok := mem.Grow(1)
+	require.True(t, ok)
+	require.True(t, mem.WriteByte(wasm.MemoryPageSize+1, 1))
+
+	require.NoError(t, pool.Put(testCtx, mod))
+
+	reused, err := pool.Get(testCtx)
+	require.NoError(t, err)
+	require.Equal(t, mod, reused)
+	requireInitialState(reused)
+
+	// Growing again must not expose bytes written before the reset.
+	_, ok = mem.Grow(1)
+	require.True(t, ok)
+	b, ok := mem.ReadByte(wasm.MemoryPageSize + 1)
+	require.True(t, ok)
+	require.Equal(t, byte(0), b)
+
+	require.NoError(t, pool.Put(testCtx, reused))
+	require.Equal(t, ModulePoolStats{Instantiated: 1, Reused: 1, Idle: 1}, pool.Stats())
+}
+
+func TestModulePool_Discard(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, poolWasm)
+	require.NoError(t, err)
+
+	pool, err := NewModulePool(testCtx, r, compiled, NewModuleConfig(), 1)
+	require.NoError(t, err)
+
+	mod1, err := pool.Get(testCtx)
+	require.NoError(t, err)
+	mod2, err := pool.Get(testCtx)
+	require.NoError(t, err)
+	mod3, err := pool.Get(testCtx)
+	require.NoError(t, err)
+
+	// A closed module isn't pooled.
+	require.NoError(t, mod1.Close(testCtx))
+	require.NoError(t, pool.Put(testCtx, mod1))
+	require.Equal(t, ModulePoolStats{Instantiated: 3, Discarded: 1}, pool.Stats())
+
+	// Modules beyond maxIdle are closed.
+	require.NoError(t, pool.Put(testCtx, mod2))
+	require.NoError(t, pool.Put(testCtx, mod3))
+	require.False(t, mod2.IsClosed())
+	require.True(t, mod3.IsClosed())
+	require.Equal(t, ModulePoolStats{Instantiated: 3, Discarded: 2, Idle: 1}, pool.Stats())
+
+	// Modules not from this pool are rejected.
+	other, err := r.InstantiateModule(testCtx, compiled, NewModuleConfig().WithName("other"))
+	require.NoError(t, err)
+	require.EqualError(t, pool.Put(testCtx, other), "module not from this pool")
+
+	// Closing the pool closes idle modules.
+	require.NoError(t, pool.Close(testCtx))
+	require.True(t, mod2.IsClosed())
+	_, err = pool.Get(testCtx)
+	require.EqualError(t, err, "module pool closed")
+}
+
+func TestNewModulePool_Errors(t *testing.T) {
+	r := NewRuntime(testCtx)
+	defer r.Close(testCtx)
+
+	compiled, err := r.CompileModule(testCtx, poolWasm)
+	require.NoError(t, err)
+	_, err = NewModulePool(testCtx, r, compiled, NewModuleConfig(), -1)
+	require.EqualError(t, err, "maxIdle must not be negative")
+
+	host, err := r.NewHostModuleBuilder("host").Compile(testCtx)
+	require.NoError(t, err)
+	_, err = NewModulePool(testCtx, r, host, NewModuleConfig(), 0)
+	require.EqualError(t, err, "host modules cannot be pooled")
+
+	// A CompiledModule implemented outside wazero can't be instantiated.
+	_, err = NewModulePool(testCtx, r, struct{ CompiledModule }{compiled}, NewModuleConfig(), 0)
+	require.EqualError(t, err, "compiled module must be from Runtime.CompileModule")
+}
@@ -102,6 +102,28 @@ type Runtime interface {
 	// See https://www.w3.org/TR/2019/REC-wasm-core-1-20191205/#name-section%E2%91%A0
 	CompileModule(ctx context.Context, binary []byte) (CompiledModule, error)
 
+	// LoadCompiledModule is like CompileModule, except it reads a module
+	// previously serialized with CompiledModule.MarshalBinary, skipping
+	// compilation to native code.
+	//
+	// Here's an example of loading a module compiled ahead of time:
+	//	data, _ := os.ReadFile("module.wzc")
+	//	compiled, _ := r.LoadCompiledModule(ctx, data)
+	//	mod, _ := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
+	//
+	// # Errors
+	//
+	// An error is returned when data is corrupt, or was produced on an
+	// incompatible host. Notably, this errs when:
+	//   - data was produced by a different version of wazero.
+	//   - data was produced for a different operating system or architecture.
+	//   - this CPU lacks features available where data was produced.
+	//   - this runtime is not compiling to native code, for example when
+	//     using NewRuntimeConfigInterpreter.
+	//   - this runtime disables any api.CoreFeatures the module was compiled
+	//     with, or differs in RuntimeConfig.WithCloseOnContextDone.
+	LoadCompiledModule(ctx context.Context, data []byte) (CompiledModule, error)
+
 	// InstantiateModule instantiates the module or errs for reasons including
 	// exit or validation.
 	//
@@ -151,10 +173,15 @@ func NewRuntime(ctx context.Context) Runtime {
 // NewRuntimeWithConfig returns a runtime with the given configuration.
 func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 	config := rConfig.(*runtimeConfig)
+	enabledFeatures := config.enabledFeatures
+	if config.deterministic {
+		// Shared memory results depend on thread scheduling.
+		enabledFeatures = enabledFeatures.SetEnabled(experimentalapi.CoreFeaturesThreads, false)
+	}
 	configKind := config.engineKind
 	configEngine := config.newEngine
 	if configKind == engineKindAuto {
-		if platform.CompilerSupports(config.enabledFeatures) {
+		if platform.CompilerSupports(enabledFeatures) {
 			configKind = engineKindCompiler
 		} else {
 			configKind = engineKindInterpreter
@@ -172,21 +199,22 @@ func NewRuntimeWithConfig(ctx context.Context, rConfig RuntimeConfig) Runtime {
 	if c := config.cache; c != nil {
 		// If the Cache is configured, we share the engine.
 		cacheImpl = c.(*cache)
-		engine = cacheImpl.initEngine(configKind, configEngine, ctx, config.enabledFeatures)
+		engine = cacheImpl.initEngine(configKind, configEngine, ctx, enabledFeatures)
 	} else {
 		// Otherwise, we create a new engine.
-		engine = configEngine(ctx, config.enabledFeatures, nil)
+		engine = configEngine(ctx, enabledFeatures, nil)
 	}
-	store := wasm.NewStore(config.enabledFeatures, engine)
+	store := wasm.NewStore(enabledFeatures, engine)
 	return &runtime{
 		cache:                 cacheImpl,
 		store:                 store,
-		enabledFeatures:       config.enabledFeatures,
+		enabledFeatures:       enabledFeatures,
 		memoryLimitPages:      config.memoryLimitPages,
 		memoryCapacityFromMax: config.memoryCapacityFromMax,
 		dwarfDisabled:         config.dwarfDisabled,
 		storeCustomSections:   config.storeCustomSections,
 		ensureTermination:     config.ensureTermination,
+		deterministic:         config.deterministic,
 	}
 }
 
@@ -209,6 +237,7 @@ type runtime struct {
 	closed atomic.Uint64
 
 	ensureTermination bool
+	deterministic     bool
 }
 
 // Module implements Runtime.Module.
@@ -227,42 +256,59 @@ func (r *runtime) Module(moduleName string) api.Module {
 
 // CompileModule implements Runtime.CompileModule
 func (r *runtime) CompileModule(ctx context.Context, binary []byte) (CompiledModule, error) {
-	if err := r.failIfClosed(); err != nil {
+	c, listeners, err := r.decodeModule(ctx, binary)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.store.Engine.CompileModule(ctx, c.module, listeners, r.ensureTermination); err != nil {
 		return nil, err
 	}
+	return c, nil
+}
+
+// decodeModule decodes and validates the binary, returning a compiledModule
+// ready to be compiled by the engine.
+func (r *runtime) decodeModule(ctx context.Context, binary []byte) (*compiledModule, []experimentalapi.FunctionListener, error) {
+	if err := r.failIfClosed(); err != nil {
+		return nil, nil, err
+	}
 
 	internal, err := binaryformat.DecodeModule(binary, r.enabledFeatures,
 		r.memoryLimitPages, r.memoryCapacityFromMax, !r.dwarfDisabled, r.storeCustomSections)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	} else if err = internal.Validate(r.enabledFeatures); err != nil {
 		// TODO: decoders should validate before returning, as that allows
 		// them to err with the correct position in the wasm binary.
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Now that the module is validated, cache the memory definitions.
 	// TODO: lazy initialization of memory definition.
 	internal.BuildMemoryDefinitions()
 
-	c := &compiledModule{module: internal, compiledEngine: r.store.Engine}
+	c := &compiledModule{
+		module:            internal,
+		compiledEngine:    r.store.Engine,
+		enabledFeatures:   r.enabledFeatures,
+		ensureTermination: r.ensureTermination,
+		canonicalizeNaN:   r.deterministic,
+	}
 
 	// typeIDs are static and compile-time known.
 	typeIDs, err := r.store.GetFunctionTypeIDs(internal.TypeSection)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	c.typeIDs = typeIDs
 
 	listeners, err := buildFunctionListeners(ctx, internal)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	internal.CanonicalizeNaN = r.deterministic
 	internal.AssignModuleID(binary, listeners, r.ensureTermination)
-	if err = r.store.Engine.CompileModule(ctx, internal, listeners, r.ensureTermination); err != nil {
-		return nil, err
-	}
-	return c, nil
+	return c, listeners, nil
 }
 
 func buildFunctionListeners(ctx context.Context, internal *wasm.Module) ([]experimentalapi.FunctionListener, error) {
@@ -318,6 +364,11 @@ func (r *runtime) InstantiateModule(
 
 	// Only add guest module configuration to guests.
 	if !code.module.IsHostModule {
+		if r.deterministic {
+			if err = config.requireDeterministic(); err != nil {
+				return nil, err
+			}
+		}
 		if sockConfig, ok := ctx.Value(internalsock.ConfigKey{}).(*internalsock.Config); ok {
 			config.sockConfig = sockConfig
 		}
@@ -328,6 +379,22 @@ func (r *runtime) InstantiateModule(
 		return nil, err
 	}
 
+	if trace, ok := ctx.Value(internalsys.TraceKey{}).(*internalsys.Trace); ok && !code.module.IsHostModule {
+		if err = sysCtx.SetTrace(trace); err != nil {
+			return nil, err
+		}
+	}
+
+	var vclock *internalsys.VirtualClock
+	if !code.module.IsHostModule {
+		if audit, ok := ctx.Value(internalsys.AuditKey{}).(*internalsys.Audit); ok {
+			sysCtx.SetAudit(audit)
+		}
+		if vclock, _ = ctx.Value(internalsys.VirtualClockKey{}).(*internalsys.VirtualClock); vclock != nil {
+			sysCtx.SetVirtualClock(vclock)
+		}
+	}
+
 	name := config.name
 	if !config.nameSet && code.module.NameSection != nil && code.module.NameSection.ModuleName != "" {
 		name = code.module.NameSection.ModuleName
@@ -343,6 +410,10 @@ func (r *runtime) InstantiateModule(
 		return nil, err
 	}
 
+	if vclock != nil {
+		vclock.Attach(mod.(*wasm.ModuleInstance).Done())
+	}
+
 	if closeNotifier, ok := ctx.Value(expctxkeys.CloseNotifierKey{}).(experimentalapi.CloseNotifier); ok {
 		mod.(*wasm.ModuleInstance).CloseNotifier = closeNotifier
 	}
@@ -2,8 +2,10 @@ package wazero
 
 import (
 	"context"
+	crand "crypto/rand"
 	_ "embed"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -12,9 +14,9 @@ import (
 	"github.com/tetratelabs/wazero/experimental"
 	"github.com/tetratelabs/wazero/internal/filecache"
 	"github.com/tetratelabs/wazero/internal/platform"
-	"github.com/tetratelabs/wazero/internal/testing/binaryencoding"
 	"github.com/tetratelabs/wazero/internal/testing/require"
 	"github.com/tetratelabs/wazero/internal/wasm"
+	"github.com/tetratelabs/wazero/internal/wasm/binaryencoding"
 	"github.com/tetratelabs/wazero/sys"
 )
 
@@ -367,6 +369,68 @@ func TestRuntime_InstantiateModule_UsesContext(t *testing.T) {
 	require.Equal(t, uint32(2), r.(*runtime).store.Engine.CompiledModuleCount())
 }
 
+func TestRuntime_Deterministic(t *testing.T) {
+	r := NewRuntimeWithConfig(testCtx, NewRuntimeConfig().
+		WithCoreFeatures(api.CoreFeaturesV2|experimental.CoreFeaturesThreads).
+		WithDeterministic(true))
+	defer r.Close(testCtx)
+
+	// Threads are disabled, even if enabled in the config.
+	require.False(t, r.(*runtime).enabledFeatures.IsEnabled(experimental.CoreFeaturesThreads))
+
+	code, err := r.CompileModule(testCtx, binaryNamedZero)
+	require.NoError(t, err)
+	require.True(t, code.(*compiledModule).module.CanonicalizeNaN)
+
+	tests := []struct {
+		name        string
+		config      ModuleConfig
+		expectedErr string
+	}{
+		{
+			name:   "default clocks",
+			config: NewModuleConfig(),
+		},
+		{
+			name:   "custom clock",
+			config: NewModuleConfig().WithSysWalltime().WithWalltime(func() (int64, int32) { return 1, 0 }, 1),
+		},
+		{
+			name:        "WithSysWalltime",
+			config:      NewModuleConfig().WithSysWalltime(),
+			expectedErr: "deterministic runtime doesn't support ModuleConfig.WithSysWalltime",
+		},
+		{
+			name:        "all host clocks",
+			config:      NewModuleConfig().WithSysWalltime().WithSysNanotime().WithSysNanosleep(),
+			expectedErr: "deterministic runtime doesn't support ModuleConfig.WithSysWalltime, ModuleConfig.WithSysNanotime, ModuleConfig.WithSysNanosleep",
+		},
+		{
+			name:   "custom rand source",
+			config: NewModuleConfig().WithRandSource(strings.NewReader("seeded")),
+		},
+		{
+			name:        "crypto/rand.Reader",
+			config:      NewModuleConfig().WithRandSource(crand.Reader),
+			expectedErr: "deterministic runtime doesn't support ModuleConfig.WithRandSource(crypto/rand.Reader)",
+		},
+	}
+
+	for _, tt := range tests {
+		tc := tt
+
+		t.Run(tc.name, func(t *testing.T) {
+			mod, err := r.InstantiateModule(testCtx, code, tc.config.WithName(""))
+			if tc.expectedErr != "" {
+				require.EqualError(t, err, tc.expectedErr)
+			} else {
+				require.NoError(t, err)
+				require.NoError(t, mod.Close(testCtx))
+			}
+		})
+	}
+}
+
 // TestRuntime_Instantiate_DoesntEnforce_Start ensures wapc-go work when modules import WASI, but don't
 // export "_start".
 func TestRuntime_Instantiate_DoesntEnforce_Start(t *testing.T) {